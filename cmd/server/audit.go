@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	internal_server "github.com/SedlarDavid/localdb-mcp/internal/server"
+)
+
+// Actions of localdb-mcp audit.
+const (
+	auditTail  = "tail"
+	auditQuery = "query"
+)
+
+// Default number of entries audit query prints, and audit tail prints
+// before following.
+const (
+	defaultAuditQueryLimit = 100
+	defaultAuditTailLimit  = 10
+)
+
+// auditOptions is the parsed command line of localdb-mcp audit.
+type auditOptions struct {
+	action   string
+	dbPath   string // the audit database; audit_db of the config if empty
+	filter   internal_server.AuditFilter
+	interval time.Duration
+	json     bool
+}
+
+// parseAuditFlags parses the arguments of localdb-mcp audit, the action
+// first. now resolves --since and --until given as durations.
+func parseAuditFlags(args []string, getenv func(string) string, now time.Time, out io.Writer) (*auditOptions, error) {
+	fail := func(err error) (*auditOptions, error) {
+		fmt.Fprintln(out, err)
+		return nil, err
+	}
+	usage := "usage: localdb-mcp audit tail|query [flags]"
+	var o auditOptions
+	fs := flag.NewFlagSet("localdb-mcp audit", flag.ContinueOnError)
+	fs.SetOutput(out)
+	fs.Usage = func() {
+		fmt.Fprintln(out, usage)
+		fs.PrintDefaults()
+	}
+	configPath := fs.String("config", getenv(config.EnvConfigFile), "config file whose audit_db to read (env "+config.EnvConfigFile+")")
+	fs.StringVar(&o.dbPath, "db", "", "audit database to read instead of the config's audit_db")
+	fs.StringVar(&o.filter.Tool, "tool", "", "only calls of this tool")
+	fs.StringVar(&o.filter.ConnectionID, "connection", "", "only calls on this connection_id")
+	fs.StringVar(&o.filter.Table, "table", "", "only calls that touched this table, with or without schema")
+	fs.BoolVar(&o.filter.FailedOnly, "failed", false, "only failed calls")
+	since := fs.String("since", "", "only calls from this RFC 3339 time, or this long ago (e.g. 15m)")
+	until := fs.String("until", "", "with query: only calls before this RFC 3339 time, or this long ago")
+	fs.IntVar(&o.filter.Limit, "limit", 0, fmt.Sprintf("entries to print: the newest %d with query, the last %d before following with tail", defaultAuditQueryLimit, defaultAuditTailLimit))
+	fs.DurationVar(&o.interval, "interval", 500*time.Millisecond, "with tail: how often to look for new calls")
+	fs.BoolVar(&o.json, "json", false, "print the entries as JSON lines")
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		o.action, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if o.action != auditTail && o.action != auditQuery {
+		return fail(errors.New(usage))
+	}
+	if fs.NArg() > 0 {
+		return fail(fmt.Errorf("unexpected arguments: %v", fs.Args()))
+	}
+	for _, t := range []struct {
+		flag, value string
+		dst         *time.Time
+	}{{"since", *since, &o.filter.Since}, {"until", *until, &o.filter.Until}} {
+		if t.value == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(t.value); err == nil {
+			*t.dst = now.Add(-d)
+		} else if *t.dst, err = time.Parse(time.RFC3339, t.value); err != nil {
+			return fail(fmt.Errorf("--%s: want an RFC 3339 time such as 2024-05-01T12:00:00Z or a duration such as 15m", t.flag))
+		}
+	}
+	if o.action == auditTail && *until != "" {
+		return fail(errors.New("--until only applies to audit query"))
+	}
+	if o.filter.Limit < 0 || o.interval <= 0 {
+		return fail(errors.New("--limit must not be negative and --interval must be positive"))
+	}
+	if o.filter.Limit == 0 {
+		o.filter.Limit = defaultAuditQueryLimit
+		if o.action == auditTail {
+			o.filter.Limit = defaultAuditTailLimit
+		}
+	}
+	if o.dbPath == "" {
+		cfg, err := config.LoadFrom(*configPath)
+		if err != nil {
+			return fail(fmt.Errorf("config: %w", err))
+		}
+		if o.dbPath = cfg.AuditDB(); o.dbPath == "" {
+			return fail(fmt.Errorf("auditing is off: set audit_db in the config file (or %s) and restart the server, or name a database with --db", config.EnvAuditDB))
+		}
+	}
+	return &o, nil
+}
+
+// audit prints the calls in the audit database: query the newest ones
+// matching the filters, tail the last few and then every new one as the
+// server records it, until interrupted.
+func audit(args []string, w io.Writer) int {
+	o, err := parseAuditFlags(args, os.Getenv, time.Now(), os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		return 2
+	}
+	r, err := internal_server.OpenAuditReader(o.dbPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "audit: %v\n", err)
+		return 1
+	}
+	defer r.Close()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if o.action == auditQuery {
+		entries, err := r.Query(ctx, o.filter)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "audit: %v\n", err)
+			return 1
+		}
+		for _, e := range entries {
+			writeAuditEntry(w, e, o.json)
+		}
+		return 0
+	}
+	if err := tailAudit(ctx, r, o.filter, o.interval, w, o.json); err != nil && ctx.Err() == nil {
+		fmt.Fprintf(os.Stderr, "audit: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// tailAudit prints the last f.Limit entries matching f, oldest first, and
+// then polls every interval for newer ones until ctx is done.
+func tailAudit(ctx context.Context, r *internal_server.AuditReader, f internal_server.AuditFilter, interval time.Duration, w io.Writer, asJSON bool) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		entries, err := r.Query(ctx, f)
+		if err != nil {
+			return err
+		}
+		// Entries come newest first.
+		for _, e := range slices.Backward(entries) {
+			writeAuditEntry(w, e, asJSON)
+		}
+		if len(entries) > 0 {
+			f.AfterID = entries[0].ID
+		} else if f.AfterID == 0 {
+			// Nothing matches yet: follow from the newest entry of all, so
+			// a filtered tail does not print history on its first match.
+			all, err := r.Query(ctx, internal_server.AuditFilter{Limit: 1})
+			if err != nil {
+				return err
+			}
+			if len(all) > 0 {
+				f.AfterID = all[0].ID
+			}
+		}
+		// After the backlog, every entry since the last one is new.
+		f.Limit = 1 << 20
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// writeAuditEntry prints e on one line: as JSON, or its local time, tool,
+// connection, duration, outcome and what it touched.
+func writeAuditEntry(w io.Writer, e internal_server.AuditEntry, asJSON bool) {
+	if asJSON {
+		b, _ := json.Marshal(e)
+		fmt.Fprintf(w, "%s\n", b)
+		return
+	}
+	ts := e.Time
+	if t, err := time.Parse(time.RFC3339Nano, e.Time); err == nil {
+		ts = t.Local().Format("2006-01-02 15:04:05.000")
+	}
+	outcome := "ok"
+	if e.ErrorCode != "" {
+		outcome = e.ErrorCode
+	}
+	what := strings.Join(strings.Fields(e.SQL), " ")
+	if what == "" {
+		what = strings.Join(e.Tables, ", ")
+	}
+	conn := e.ConnectionID
+	if conn == "" {
+		conn = "-"
+	}
+	line := fmt.Sprintf("%s  %-20s %-12s %7s  %-16s %s", ts, e.Tool, conn, fmt.Sprintf("%dms", e.DurationMS), outcome, what)
+	fmt.Fprintln(w, strings.TrimRight(line, " "))
+}
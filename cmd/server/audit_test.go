@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	internal_server "github.com/SedlarDavid/localdb-mcp/internal/server"
+)
+
+func TestParseAuditFlags(t *testing.T) {
+	getenv := func(string) string { return "" }
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	o, err := parseAuditFlags([]string{"query", "--db", "audit.db", "--since", "15m", "--until", "2026-05-01T11:55:00Z", "--failed"}, getenv, now, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.action != auditQuery || !o.filter.Since.Equal(now.Add(-15*time.Minute)) || o.filter.Until.Minute() != 55 || !o.filter.FailedOnly || o.filter.Limit != defaultAuditQueryLimit {
+		t.Errorf("options = %+v", o)
+	}
+	if o, err := parseAuditFlags([]string{"tail", "--db", "audit.db"}, getenv, now, io.Discard); err != nil || o.filter.Limit != defaultAuditTailLimit {
+		t.Errorf("tail: %+v, %v", o, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("connections:\n  demo: demo\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(config.EnvAuditDB, "")
+	for _, args := range [][]string{
+		{"--db", "audit.db"},
+		{"follow", "--db", "audit.db"},
+		{"query", "--db", "audit.db", "--since", "yesterday"},
+		{"tail", "--db", "audit.db", "--until", "1h"},
+		{"query", "--config", path},
+	} {
+		if _, err := parseAuditFlags(args, getenv, now, io.Discard); err == nil {
+			t.Errorf("%v: no error", args)
+		}
+	}
+}
+
+func TestTailAudit(t *testing.T) {
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	dir := t.TempDir()
+	auditPath := filepath.Join(dir, "audit.db")
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte("connections:\n  demo: demo\naudit_db: "+auditPath+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for _, env := range []string{config.EnvPostgresURI, config.EnvSQLServerURI, config.EnvSQLiteURI, config.EnvMySQLURI, config.EnvAuditDB} {
+		t.Setenv(env, "")
+	}
+	ctx := context.Background()
+	c, closeSession, err := localSession(ctx, &options{configPath: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeSession()
+	query := func(sql string) {
+		t.Helper()
+		var out internal_server.RunQueryOutput
+		_ = callLocal(ctx, c, "run_query", map[string]any{"connection_id": "demo", "sql": sql}, &out)
+	}
+	query("SELECT 1 AS old")
+	query("SELECT 2 AS backlog")
+
+	r, err := internal_server.OpenAuditReader(auditPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	var mu sync.Mutex
+	var out strings.Builder
+	w := writerFunc(func(p []byte) (int, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return out.Write(p)
+	})
+	tailCtx, cancel := context.WithCancel(ctx)
+	done := make(chan error)
+	go func() {
+		done <- tailAudit(tailCtx, r, internal_server.AuditFilter{Tool: "run_query", Limit: 1}, 10*time.Millisecond, w, false)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	query("SELECT 3 AS live")
+	query("SELEC")
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		got := out.String()
+		mu.Unlock()
+		if strings.Count(got, "\n") >= 3 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || !strings.HasSuffix(lines[0], "ok               SELECT 2 AS backlog") ||
+		!strings.HasSuffix(lines[1], "SELECT 3 AS live") || !strings.Contains(lines[2], "database_error") {
+		t.Errorf("tail output:\n%s", out.String())
+	}
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	"github.com/SedlarDavid/localdb-mcp/internal/mcpclient"
+	internal_server "github.com/SedlarDavid/localdb-mcp/internal/server"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// benchOptions is the parsed command line of localdb-mcp bench.
+type benchOptions struct {
+	configPath  string
+	url         string // a running server to load instead of an in-process one
+	token       string
+	connection  string
+	tools       []string
+	sql         string
+	table       string
+	requests    int
+	concurrency int
+	timeout     time.Duration
+}
+
+// benchTools are the tools bench knows arguments for.
+var benchTools = []string{"list_tables", "run_query", "describe_table", "ping"}
+
+// parseBenchFlags parses the arguments of localdb-mcp bench.
+func parseBenchFlags(args []string, getenv func(string) string, out io.Writer) (*benchOptions, error) {
+	fail := func(err error) (*benchOptions, error) {
+		fmt.Fprintln(out, err)
+		return nil, err
+	}
+	var o benchOptions
+	fs := flag.NewFlagSet("localdb-mcp bench", flag.ContinueOnError)
+	fs.SetOutput(out)
+	fs.Usage = func() {
+		fmt.Fprintln(out, "usage: localdb-mcp bench [flags]")
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&o.configPath, "config", getenv(config.EnvConfigFile), "config file for the in-process server (env "+config.EnvConfigFile+")")
+	fs.StringVar(&o.url, "url", "", "endpoint of a running server to load instead of an in-process one, e.g. http://localhost:8089/mcp")
+	fs.StringVar(&o.token, "token", getenv(config.EnvAuthToken), "bearer token for --url (env "+config.EnvAuthToken+")")
+	fs.StringVar(&o.connection, "connection", "", "connection_id to call the tools on (default the first connection)")
+	tools := fs.String("tools", "list_tables,run_query", "comma-separated tools to call in turn: "+strings.Join(benchTools, ", "))
+	fs.StringVar(&o.sql, "sql", "SELECT 1", "statement for run_query")
+	fs.StringVar(&o.table, "table", "", "table for describe_table")
+	fs.IntVar(&o.requests, "requests", 200, "number of calls in total")
+	fs.IntVar(&o.concurrency, "concurrency", 4, "number of calls in flight at once")
+	fs.DurationVar(&o.timeout, "timeout", 30*time.Second, "deadline of each call")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return fail(fmt.Errorf("unexpected arguments: %v", fs.Args()))
+	}
+	for _, t := range strings.Split(*tools, ",") {
+		t = strings.TrimSpace(t)
+		if !slices.Contains(benchTools, t) {
+			return fail(fmt.Errorf("--tools: %q is not one of %s", t, strings.Join(benchTools, ", ")))
+		}
+		o.tools = append(o.tools, t)
+	}
+	if slices.Contains(o.tools, "describe_table") && o.table == "" {
+		return fail(errors.New("--tools describe_table needs --table"))
+	}
+	if o.requests < 1 || o.concurrency < 1 || o.timeout <= 0 {
+		return fail(errors.New("--requests, --concurrency and --timeout must be positive"))
+	}
+	if o.url != "" && o.configPath != "" {
+		return fail(errors.New("--url and --config cannot be used together: the running server has its own config"))
+	}
+	return &o, nil
+}
+
+// benchCall is the outcome of one call.
+type benchCall struct {
+	tool    string
+	elapsed time.Duration
+	code    string // error code of a failed call, "" if it succeeded
+}
+
+// bench fires o.requests calls at a server, o.concurrency at a time, and
+// reports their latency per tool.
+func bench(args []string, w io.Writer) int {
+	o, err := parseBenchFlags(args, os.Getenv, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		return 2
+	}
+	ctx := context.Background()
+	var c *client.Client
+	if o.url != "" {
+		if c, err = mcpclient.DialURL(ctx, o.url, o.token); err == nil {
+			defer c.Close()
+			initReq := mcp.InitializeRequest{}
+			initReq.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+			initReq.Params.ClientInfo = mcp.Implementation{Name: "localdb-mcp bench", Version: internal_server.ServerVersion}
+			_, err = c.Initialize(ctx, initReq)
+		}
+	} else {
+		var closeSession func()
+		if c, closeSession, err = localSession(ctx, &options{configPath: o.configPath}); err == nil {
+			defer closeSession()
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "bench: %v\n", err)
+		return 1
+	}
+	if o.connection == "" {
+		var conns internal_server.ListConnectionsOutput
+		if err := callLocal(ctx, c, "list_connections", nil, &conns); err != nil || len(conns.Connections) == 0 {
+			fmt.Fprintf(os.Stderr, "bench: no connection to call the tools on (%v)\n", err)
+			return 1
+		}
+		o.connection = conns.Connections[0].ID
+	}
+	argsFor := func(tool string) map[string]any {
+		switch tool {
+		case "run_query":
+			return map[string]any{"connection_id": o.connection, "sql": o.sql}
+		case "describe_table":
+			return map[string]any{"connection_id": o.connection, "table": o.table}
+		case "ping":
+			return nil
+		}
+		return map[string]any{"connection_id": o.connection}
+	}
+	once := func(tool string) benchCall {
+		ctx, cancel := context.WithTimeout(ctx, o.timeout)
+		defer cancel()
+		start := time.Now()
+		res, err := c.CallTool(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{Name: tool, Arguments: argsFor(tool)}})
+		call := benchCall{tool: tool, elapsed: time.Since(start)}
+		switch {
+		case err != nil:
+			call.code = "transport"
+		case res.IsError:
+			call.code = "error"
+			if m, ok := res.StructuredContent.(map[string]any); ok {
+				if code, ok := m["code"].(string); ok {
+					call.code = code
+				}
+			}
+		}
+		return call
+	}
+
+	// One unmeasured call per tool opens the connection and fills caches.
+	for _, tool := range o.tools {
+		if call := once(tool); call.code != "" {
+			fmt.Fprintf(os.Stderr, "bench: warm-up call to %s failed (%s); check the connection and arguments with localdb-mcp call\n", tool, call.code)
+			return 1
+		}
+	}
+
+	jobs := make(chan string)
+	results := make(chan benchCall, o.requests)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for range o.concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for tool := range jobs {
+				results <- once(tool)
+			}
+		}()
+	}
+	for i := range o.requests {
+		jobs <- o.tools[i%len(o.tools)]
+	}
+	close(jobs)
+	wg.Wait()
+	elapsed := time.Since(start)
+	close(results)
+	var calls []benchCall
+	for call := range results {
+		calls = append(calls, call)
+	}
+
+	fmt.Fprintf(w, "%d calls on connection %q, %d at a time, in %s (%.1f calls/s)\n\n",
+		len(calls), o.connection, o.concurrency, elapsed.Round(time.Millisecond), float64(len(calls))/elapsed.Seconds())
+	failed := writeBenchReport(w, calls, o.tools)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// writeBenchReport writes the latency percentiles of calls per tool, in
+// the order of tools, and the failures by error code. It returns the
+// number of failed calls.
+func writeBenchReport(w io.Writer, calls []benchCall, tools []string) int {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "tool\tcalls\terrors\tp50\tp90\tp99\tmax\tmean")
+	failed := 0
+	codes := make(map[string]map[string]int)
+	for _, tool := range tools {
+		var ok []time.Duration
+		n := 0
+		for _, c := range calls {
+			if c.tool != tool {
+				continue
+			}
+			n++
+			if c.code != "" {
+				if codes[tool] == nil {
+					codes[tool] = make(map[string]int)
+				}
+				codes[tool][c.code]++
+				continue
+			}
+			ok = append(ok, c.elapsed)
+		}
+		failed += n - len(ok)
+		if len(ok) == 0 {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t-\t-\t-\t-\t-\n", tool, n, n)
+			continue
+		}
+		slices.Sort(ok)
+		var sum time.Duration
+		for _, d := range ok {
+			sum += d
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%s\t%s\t%s\t%s\n", tool, n, n-len(ok),
+			fmtLatency(percentile(ok, 50)), fmtLatency(percentile(ok, 90)), fmtLatency(percentile(ok, 99)),
+			fmtLatency(ok[len(ok)-1]), fmtLatency(sum/time.Duration(len(ok))))
+	}
+	tw.Flush()
+	if failed == 0 {
+		return 0
+	}
+	fmt.Fprintln(w, "\nFailed calls (latencies above are of the successful ones):")
+	for _, tool := range tools {
+		var list []string
+		for code, n := range codes[tool] {
+			list = append(list, fmt.Sprintf("%d %s", n, code))
+		}
+		sort.Strings(list)
+		if len(list) > 0 {
+			fmt.Fprintf(w, "  %s: %s\n", tool, strings.Join(list, ", "))
+		}
+		if codes[tool][internal_server.CodeRateLimited] > 0 {
+			fmt.Fprintln(w, "    rate limited: set rate_limits: { read: { rate: 0 } } in config.yaml to measure past the limit")
+		}
+	}
+	return failed
+}
+
+// percentile returns the p-th percentile of sorted, by nearest rank.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	i := (p*len(sorted)+99)/100 - 1
+	return sorted[max(i, 0)]
+}
+
+func fmtLatency(d time.Duration) string {
+	return fmt.Sprintf("%.2fms", float64(d)/float64(time.Millisecond))
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+)
+
+func TestPercentile(t *testing.T) {
+	var d []time.Duration
+	for i := 1; i <= 10; i++ {
+		d = append(d, time.Duration(i)*time.Millisecond)
+	}
+	for _, tc := range []struct {
+		p    int
+		want time.Duration
+	}{{50, 5 * time.Millisecond}, {90, 9 * time.Millisecond}, {99, 10 * time.Millisecond}, {0, time.Millisecond}} {
+		if got := percentile(d, tc.p); got != tc.want {
+			t.Errorf("p%d = %v, want %v", tc.p, got, tc.want)
+		}
+	}
+}
+
+func TestParseBenchFlags(t *testing.T) {
+	getenv := func(string) string { return "" }
+	o, err := parseBenchFlags([]string{"--tools", "ping, run_query", "--requests", "10"}, getenv, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(o.tools, ",") != "ping,run_query" || o.requests != 10 || o.concurrency != 4 || o.sql != "SELECT 1" {
+		t.Errorf("options = %+v", o)
+	}
+	for _, args := range [][]string{
+		{"--tools", "insert_test_row"},
+		{"--tools", "describe_table"},
+		{"--concurrency", "0"},
+		{"--url", "http://localhost:8089/mcp", "--config", "x.yaml"},
+		{"extra"},
+	} {
+		if _, err := parseBenchFlags(args, getenv, io.Discard); err == nil {
+			t.Errorf("%v: no error", args)
+		}
+	}
+}
+
+func TestWriteBenchReport(t *testing.T) {
+	calls := []benchCall{
+		{tool: "run_query", elapsed: time.Millisecond},
+		{tool: "run_query", elapsed: 3 * time.Millisecond},
+		{tool: "run_query", code: "rate_limited"},
+		{tool: "ping", code: "transport"},
+	}
+	var out strings.Builder
+	if failed := writeBenchReport(&out, calls, []string{"run_query", "ping"}); failed != 2 {
+		t.Errorf("failed = %d, want 2", failed)
+	}
+	for _, want := range []string{"run_query  3      1       1.00ms  3.00ms", "ping       1      1       -", "run_query: 1 rate_limited", "rate_limits:", "ping: 1 transport"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("report lacks %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestBench(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("connections:\n  demo: demo\nrate_limits: { read: { rate: 0 } }\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for _, env := range []string{config.EnvPostgresURI, config.EnvSQLServerURI, config.EnvSQLiteURI, config.EnvMySQLURI} {
+		t.Setenv(env, "")
+	}
+	var out strings.Builder
+	if code := bench([]string{"--config", path, "--requests", "20", "--concurrency", "3"}, &out); code != 0 {
+		t.Fatalf("bench: exit %d\n%s", code, out.String())
+	}
+	if !strings.Contains(out.String(), `20 calls on connection "demo", 3 at a time`) || !strings.Contains(out.String(), "list_tables  10") {
+		t.Errorf("bench output:\n%s", out.String())
+	}
+}
@@ -0,0 +1,429 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mark3labs/mcp-go/client"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
+	internal_server "github.com/SedlarDavid/localdb-mcp/internal/server"
+)
+
+// browseSampleRows is how many rows browse shows of a table.
+const browseSampleRows = 50
+
+// browseTimeout bounds each lookup of browse.
+const browseTimeout = 30 * time.Second
+
+// browseLevel is what the browser lists.
+type browseLevel int
+
+const (
+	levelSchemas browseLevel = iota
+	levelTables
+	levelColumns
+	levelRows
+	levelRecord // one row, a line per column
+)
+
+// browseSource looks up what browse shows.
+type browseSource interface {
+	schemas(ctx context.Context) ([]string, error)
+	tables(ctx context.Context, schema string) ([]string, error)
+	columns(ctx context.Context, schema, table string) ([]db.ColumnInfo, error)
+	rows(ctx context.Context, schema, table string) ([]map[string]any, error)
+}
+
+// toolSource looks up tables, columns and rows with the tools of an
+// in-process server, so browse shows what an agent would see, masking and
+// row filters included. No tool lists schemas; those come from the driver.
+type toolSource struct {
+	c      *client.Client
+	mgr    *db.Manager
+	connID string
+	typ    string
+}
+
+func (s toolSource) schemas(ctx context.Context) ([]string, error) {
+	d, err := s.mgr.Driver(ctx, s.connID)
+	if err != nil {
+		return nil, err
+	}
+	return db.ListSchemas(ctx, d, s.typ)
+}
+
+func (s toolSource) tables(ctx context.Context, schema string) ([]string, error) {
+	var tables []string
+	args := map[string]any{"connection_id": s.connID, "schema": schema}
+	for {
+		var out internal_server.ListTablesOutput
+		if err := callLocal(ctx, s.c, "list_tables", args, &out); err != nil {
+			return nil, err
+		}
+		tables = append(tables, out.Tables...)
+		if out.NextCursor == "" {
+			return tables, nil
+		}
+		args["cursor"] = out.NextCursor
+	}
+}
+
+func (s toolSource) columns(ctx context.Context, schema, table string) ([]db.ColumnInfo, error) {
+	var out internal_server.DescribeTableOutput
+	err := callLocal(ctx, s.c, "describe_table", map[string]any{"connection_id": s.connID, "schema": schema, "table": table}, &out)
+	return out.Columns, err
+}
+
+func (s toolSource) rows(ctx context.Context, schema, table string) ([]map[string]any, error) {
+	var out internal_server.RunQueryOutput
+	sql := db.SampleQuery(s.typ, schema, table, browseSampleRows)
+	err := callLocal(ctx, s.c, "run_query", map[string]any{"connection_id": s.connID, "sql": sql}, &out)
+	return out.Rows, err
+}
+
+// browser is the bubbletea model of localdb-mcp browse.
+type browser struct {
+	src    browseSource
+	title  string // the connection, as "id (type)"
+	top    browseLevel
+	level  browseLevel
+	schema string
+	table  string
+
+	schemaList []string
+	tableList  []string
+	columnList []db.ColumnInfo
+	rowList    []map[string]any
+	record     int // the row levelRecord shows
+
+	cursor  [levelRecord + 1]int
+	offset  int // first line shown of the list
+	loading bool
+	err     string
+	width   int
+	height  int
+}
+
+// loadedMsg carries the result of a lookup for level.
+type loadedMsg struct {
+	level   browseLevel
+	schemas []string
+	tables  []string
+	columns []db.ColumnInfo
+	rows    []map[string]any
+	err     error
+}
+
+// newBrowser returns a browser of a connection; with schemas it starts
+// at the list of schemas, otherwise at the tables.
+func newBrowser(src browseSource, connID, typ string, schemas bool) *browser {
+	b := &browser{src: src, title: fmt.Sprintf("%s (%s)", connID, typ), width: 80, height: 24}
+	if !schemas {
+		b.top, b.level = levelTables, levelTables
+	}
+	return b
+}
+
+func (b *browser) Init() tea.Cmd {
+	return b.load(b.level)
+}
+
+// load looks up the list of level in the background.
+func (b *browser) load(level browseLevel) tea.Cmd {
+	b.loading, b.err = true, ""
+	src, schema, table := b.src, b.schema, b.table
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), browseTimeout)
+		defer cancel()
+		msg := loadedMsg{level: level}
+		switch level {
+		case levelSchemas:
+			msg.schemas, msg.err = src.schemas(ctx)
+		case levelTables:
+			msg.tables, msg.err = src.tables(ctx, schema)
+		case levelColumns:
+			msg.columns, msg.err = src.columns(ctx, schema, table)
+		case levelRows:
+			msg.rows, msg.err = src.rows(ctx, schema, table)
+		}
+		return msg
+	}
+}
+
+func (b *browser) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		b.width, b.height = msg.Width, msg.Height
+	case loadedMsg:
+		b.loading = false
+		if msg.err != nil {
+			b.err = msg.err.Error()
+			return b, nil
+		}
+		b.level = msg.level
+		b.cursor[b.level], b.offset = 0, 0
+		switch msg.level {
+		case levelSchemas:
+			b.schemaList = msg.schemas
+		case levelTables:
+			b.tableList = msg.tables
+		case levelColumns:
+			b.columnList = msg.columns
+		case levelRows:
+			b.rowList = msg.rows
+		}
+	case tea.KeyMsg:
+		return b, b.key(msg.String())
+	}
+	return b, nil
+}
+
+// key handles a key press.
+func (b *browser) key(k string) tea.Cmd {
+	switch k {
+	case "q", "ctrl+c":
+		return tea.Quit
+	}
+	if b.loading {
+		return nil
+	}
+	n := b.length()
+	c := &b.cursor[b.level]
+	page := max(b.listHeight()-1, 1)
+	switch k {
+	case "up", "k":
+		*c = max(*c-1, 0)
+	case "down", "j":
+		*c = min(*c+1, max(n-1, 0))
+	case "pgup":
+		*c = max(*c-page, 0)
+	case "pgdown", " ":
+		*c = min(*c+page, max(n-1, 0))
+	case "home", "g":
+		*c = 0
+	case "end", "G":
+		*c = max(n-1, 0)
+	case "r":
+		if b.level != levelRecord {
+			return b.load(b.level)
+		}
+	case "enter", "right", "l":
+		if n == 0 {
+			return nil
+		}
+		switch b.level {
+		case levelSchemas:
+			b.schema = b.schemaList[*c]
+			return b.load(levelTables)
+		case levelTables:
+			b.table = b.tableList[*c]
+			return b.load(levelColumns)
+		case levelColumns:
+			return b.load(levelRows)
+		case levelRows:
+			b.record, b.level, b.offset = *c, levelRecord, 0
+			b.cursor[levelRecord] = 0
+		}
+	case "esc", "left", "h", "backspace":
+		if b.level > b.top {
+			b.level--
+			b.err, b.offset = "", 0
+		}
+	}
+	b.scroll()
+	return nil
+}
+
+// length returns the number of lines of the current list.
+func (b *browser) length() int {
+	switch b.level {
+	case levelSchemas:
+		return len(b.schemaList)
+	case levelTables:
+		return len(b.tableList)
+	case levelColumns:
+		return len(b.columnList)
+	case levelRows:
+		return len(b.rowList)
+	}
+	return len(b.columnList)
+}
+
+// listHeight is the number of list lines that fit between the header and
+// the footer.
+func (b *browser) listHeight() int {
+	return max(b.height-4, 1)
+}
+
+// scroll moves the window so the cursor is in it.
+func (b *browser) scroll() {
+	c, h := b.cursor[b.level], b.listHeight()
+	if c < b.offset {
+		b.offset = c
+	}
+	if c >= b.offset+h {
+		b.offset = c - h + 1
+	}
+}
+
+func (b *browser) View() string {
+	var sb strings.Builder
+	crumbs := []string{"localdb-mcp browse", b.title}
+	if b.level > levelSchemas && b.schema != "" {
+		crumbs = append(crumbs, b.schema)
+	}
+	if b.level >= levelColumns {
+		crumbs = append(crumbs, b.table)
+	}
+	switch b.level {
+	case levelSchemas:
+		crumbs = append(crumbs, "schemas")
+	case levelTables:
+		crumbs = append(crumbs, "tables")
+	case levelColumns:
+		crumbs = append(crumbs, "columns")
+	case levelRows:
+		crumbs = append(crumbs, fmt.Sprintf("first %d rows", browseSampleRows))
+	case levelRecord:
+		crumbs = append(crumbs, fmt.Sprintf("row %d", b.record+1))
+	}
+	sb.WriteString(b.fit(strings.Join(crumbs, " › ")) + "\n")
+	sb.WriteString(strings.Repeat("─", max(b.width, 1)) + "\n")
+
+	lines := b.lines()
+	h := b.listHeight()
+	for i := b.offset; i < b.offset+h; i++ {
+		switch {
+		case i < len(lines) && i == b.cursor[b.level] && b.level != levelRecord:
+			sb.WriteString("\x1b[7m" + b.fit("> "+lines[i]) + "\x1b[0m")
+		case i < len(lines):
+			sb.WriteString(b.fit("  " + lines[i]))
+		case i == 0 && !b.loading:
+			sb.WriteString("  (none)")
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString(strings.Repeat("─", max(b.width, 1)) + "\n")
+	status := "↑/↓ move  enter open  ← back  r reload  q quit"
+	switch {
+	case b.loading:
+		status = "loading..."
+	case b.err != "":
+		status = "error: " + b.err
+	}
+	sb.WriteString(b.fit(status))
+	return sb.String()
+}
+
+// lines returns the lines of the current list.
+func (b *browser) lines() []string {
+	switch b.level {
+	case levelSchemas:
+		return b.schemaList
+	case levelTables:
+		return b.tableList
+	case levelColumns:
+		width := 0
+		for _, c := range b.columnList {
+			width = max(width, len(c.Name))
+		}
+		lines := make([]string, len(b.columnList))
+		for i, c := range b.columnList {
+			var flags []string
+			if c.IsPK {
+				flags = append(flags, "primary key")
+			}
+			if !c.Nullable {
+				flags = append(flags, "not null")
+			}
+			lines[i] = strings.TrimRight(fmt.Sprintf("%-*s  %-16s %s", width, c.Name, c.Type, strings.Join(flags, ", ")), " ")
+		}
+		return lines
+	case levelRows:
+		lines := make([]string, len(b.rowList))
+		for i, r := range b.rowList {
+			cells := make([]string, 0, len(b.columnList))
+			for _, c := range b.columnList {
+				cells = append(cells, browseCell(r[c.Name], 24))
+			}
+			lines[i] = strings.Join(cells, " │ ")
+		}
+		return lines
+	case levelRecord:
+		if b.record >= len(b.rowList) {
+			return nil
+		}
+		width := 0
+		for _, c := range b.columnList {
+			width = max(width, len(c.Name))
+		}
+		r := b.rowList[b.record]
+		lines := make([]string, len(b.columnList))
+		for i, c := range b.columnList {
+			lines[i] = fmt.Sprintf("%-*s  %s", width, c.Name, browseCell(r[c.Name], 0))
+		}
+		return lines
+	}
+	return nil
+}
+
+// fit cuts line to the width of the terminal.
+func (b *browser) fit(line string) string {
+	if r := []rune(line); b.width > 0 && len(r) > b.width {
+		return string(r[:b.width-1]) + "…"
+	}
+	return line
+}
+
+// browseCell formats a value of a row on one line, cut to n runes if n is
+// positive.
+func browseCell(v any, n int) string {
+	var s string
+	switch v := v.(type) {
+	case nil:
+		s = "NULL"
+	case string:
+		s = v
+	default:
+		b, _ := json.Marshal(v)
+		s = string(b)
+	}
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); n > 0 && len(r) > n {
+		s = string(r[:n-1]) + "…"
+	}
+	return s
+}
+
+// browse runs the schema browser of opts.connID until the human quits.
+func browse(opts *options) int {
+	// Log lines would tear the screen; errors are shown in the status line.
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx := context.Background()
+	c, cfg, mgr, closeServer, err := localServer(ctx, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "browse: %v\n", err)
+		return 1
+	}
+	defer closeServer()
+	typ, ok := cfg.Type(opts.connID)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "browse: unknown connection %q (configured: %s)\n", opts.connID, strings.Join(configuredConnections(opts.configPath), ", "))
+		return 1
+	}
+	caps, _ := db.CapabilitiesFor(typ)
+	b := newBrowser(toolSource{c: c, mgr: mgr, connID: opts.connID, typ: typ}, opts.connID, typ, caps.Schemas)
+	if _, err := tea.NewProgram(b, tea.WithAltScreen()).Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "browse: %v\n", err)
+		return 1
+	}
+	return 0
+}
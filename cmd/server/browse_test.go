@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
+)
+
+type fakeBrowseSource struct{}
+
+func (fakeBrowseSource) schemas(context.Context) ([]string, error) {
+	return []string{"public", "sales"}, nil
+}
+
+func (fakeBrowseSource) tables(_ context.Context, schema string) ([]string, error) {
+	if schema == "sales" {
+		return []string{"orders"}, nil
+	}
+	return []string{"users", "broken"}, nil
+}
+
+func (fakeBrowseSource) columns(_ context.Context, schema, table string) ([]db.ColumnInfo, error) {
+	if table == "broken" {
+		return nil, errors.New("permission denied")
+	}
+	return []db.ColumnInfo{{Name: "id", Type: "integer", IsPK: true}, {Name: "note", Type: "text", Nullable: true}}, nil
+}
+
+func (fakeBrowseSource) rows(context.Context, string, string) ([]map[string]any, error) {
+	return []map[string]any{{"id": 1, "note": "first\nline"}, {"id": 2, "note": nil}}, nil
+}
+
+// press sends keys to b, running the command each returns the way the
+// bubbletea runtime would.
+func press(t *testing.T, b *browser, keys ...string) {
+	t.Helper()
+	for _, k := range keys {
+		var msg tea.Msg
+		switch k {
+		case "enter":
+			msg = tea.KeyMsg{Type: tea.KeyEnter}
+		case "esc":
+			msg = tea.KeyMsg{Type: tea.KeyEsc}
+		case "down":
+			msg = tea.KeyMsg{Type: tea.KeyDown}
+		default:
+			msg = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+		}
+		run(b, msg)
+	}
+}
+
+func run(b *browser, msg tea.Msg) {
+	for msg != nil {
+		_, cmd := b.Update(msg)
+		if cmd == nil {
+			return
+		}
+		msg = cmd()
+	}
+}
+
+func TestBrowser(t *testing.T) {
+	b := newBrowser(fakeBrowseSource{}, "app", "postgres", true)
+	run(b, b.Init()())
+	if !strings.Contains(b.View(), "> public") || !strings.Contains(b.View(), "app (postgres) › schemas") {
+		t.Fatalf("schemas view:\n%s", b.View())
+	}
+	press(t, b, "down", "enter")
+	if b.level != levelTables || b.schema != "sales" || !strings.Contains(b.View(), "> orders") {
+		t.Fatalf("tables of sales: level %d schema %q\n%s", b.level, b.schema, b.View())
+	}
+	press(t, b, "esc", "k", "enter", "enter")
+	if b.level != levelColumns || b.table != "users" {
+		t.Fatalf("columns: level %d table %q", b.level, b.table)
+	}
+	if v := b.View(); !strings.Contains(v, "public › users › columns") || !strings.Contains(v, "id    integer          primary key, not null") {
+		t.Errorf("columns view:\n%s", v)
+	}
+	press(t, b, "enter")
+	if v := b.View(); !strings.Contains(v, "1 │ first line") || !strings.Contains(v, "2 │ NULL") {
+		t.Errorf("rows view:\n%s", v)
+	}
+	press(t, b, "j", "enter")
+	if v := b.View(); b.level != levelRecord || !strings.Contains(v, "row 2") || !strings.Contains(v, "note  NULL") {
+		t.Errorf("record view:\n%s", v)
+	}
+
+	// A failed lookup stays on the current list and shows the error.
+	press(t, b, "esc", "esc", "esc", "down", "enter")
+	if b.level != levelTables || !strings.Contains(b.View(), "error: permission denied") {
+		t.Errorf("failed lookup: level %d\n%s", b.level, b.View())
+	}
+	// Back stops at the top list.
+	press(t, b, "esc", "esc")
+	if b.level != levelSchemas {
+		t.Errorf("level %d after going back past the top", b.level)
+	}
+}
+
+func TestBrowserScroll(t *testing.T) {
+	b := newBrowser(fakeBrowseSource{}, "app", "sqlite", false)
+	b.Update(tea.WindowSizeMsg{Width: 40, Height: 5})
+	b.Update(loadedMsg{level: levelTables, tables: []string{"a", "b", "c", "d"}})
+	press(t, b, "down", "down")
+	if v := b.View(); !strings.Contains(v, "> c") || strings.Contains(v, "  a") {
+		t.Errorf("scrolled view:\n%s", v)
+	}
+	press(t, b, "esc")
+	if b.level != levelTables {
+		t.Errorf("went back past the tables of a connection without schemas")
+	}
+}
+
+func TestToolSourceDemo(t *testing.T) {
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("connections:\n  demo: demo\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for _, env := range []string{config.EnvPostgresURI, config.EnvSQLServerURI, config.EnvSQLiteURI, config.EnvMySQLURI} {
+		t.Setenv(env, "")
+	}
+	ctx := context.Background()
+	c, cfg, mgr, closeServer, err := localServer(ctx, &options{configPath: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeServer()
+	typ, _ := cfg.Type("demo")
+	src := toolSource{c: c, mgr: mgr, connID: "demo", typ: typ}
+	if schemas, err := src.schemas(ctx); err != nil || schemas != nil {
+		t.Errorf("schemas = %v, %v; want none", schemas, err)
+	}
+	tables, err := src.tables(ctx, "")
+	if err != nil || len(tables) == 0 {
+		t.Fatalf("tables = %v, %v", tables, err)
+	}
+	cols, err := src.columns(ctx, "", tables[0])
+	if err != nil || len(cols) == 0 {
+		t.Fatalf("columns of %s = %v, %v", tables[0], cols, err)
+	}
+	rows, err := src.rows(ctx, "", tables[0])
+	if err != nil || len(rows) == 0 {
+		t.Fatalf("rows of %s = %v, %v", tables[0], rows, err)
+	}
+	if _, ok := rows[0][cols[0].Name]; !ok {
+		t.Errorf("row %v lacks column %s", rows[0], cols[0].Name)
+	}
+}
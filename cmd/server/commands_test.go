@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+)
+
+func TestInitConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "config.yaml")
+	var out strings.Builder
+	if code := initConfig(&options{configPath: path}, &out); code != 0 {
+		t.Fatalf("init: exit %d", code)
+	}
+	cfg, err := config.LoadFrom(path)
+	if err != nil {
+		t.Fatalf("starter config does not load: %v", err)
+	}
+	if !cfg.HasConnection("demo") {
+		t.Error("starter config has no demo connection")
+	}
+	if err := os.WriteFile(path, []byte("connections: {}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if code := initConfig(&options{configPath: path}, &out); code == 0 {
+		t.Error("init overwrote an existing config")
+	}
+	if b, _ := os.ReadFile(path); string(b) != "connections: {}\n" {
+		t.Errorf("config changed: %q", b)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	content := "connections:\n  demo: demo\n  broken: " + filepath.Join(dir, "missing", "app.db") + "\n"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for _, env := range []string{config.EnvPostgresURI, config.EnvSQLServerURI, config.EnvSQLiteURI, config.EnvMySQLURI} {
+		t.Setenv(env, "")
+	}
+	var out strings.Builder
+	if code := check(&options{configPath: path}, &out); code != 1 {
+		t.Errorf("check: exit %d, want 1", code)
+	}
+	if !strings.Contains(out.String(), "ok    demo (demo)") || !strings.Contains(out.String(), "FAIL  broken (") {
+		t.Errorf("check output:\n%s", out.String())
+	}
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	"github.com/SedlarDavid/localdb-mcp/internal/mcpclient"
+	internal_server "github.com/SedlarDavid/localdb-mcp/internal/server"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// completeTimeout bounds looking up tool names for a completion, so a slow
+// config does not hang the shell.
+const completeTimeout = 3 * time.Second
+
+// subcommands are the subcommands completion offers.
+var subcommands = []string{cmdServe, cmdInit, cmdCheck, cmdDoctor, cmdBrowse, cmdCall, cmdBench, cmdReplay, cmdAudit, cmdExport, cmdImport, cmdAttach, cmdSecure, cmdCompletion}
+
+// completionShells are the shells localdb-mcp completion writes scripts for.
+var completionShells = []string{"bash", "zsh", "fish"}
+
+// flagValues are the values completion offers for flags with a fixed set.
+var flagValues = map[string][]string{
+	"transport": {internal_server.TransportStdio, internal_server.TransportSSE, internal_server.TransportHTTP, internal_server.TransportUnix},
+	"log-level": {"debug", "info", "warn", "error"},
+	"output":    {"table", "json", "csv", "raw"},
+}
+
+// completionSource looks up what completion offers beyond fixed words.
+type completionSource struct {
+	connections func(configPath string) []string
+	tools       func(configPath string) []string
+}
+
+// complete returns the candidates for the last of words, the command line
+// after the program name up to the word being completed. Flags and their
+// names come from the subcommand's own usage, so they cannot drift from
+// it. An empty result lets the shell fall back to file names.
+func complete(words []string, src completionSource) []string {
+	if len(words) == 0 {
+		return nil
+	}
+	cur := words[len(words)-1]
+	if len(words) == 1 {
+		return withPrefix(subcommands, cur)
+	}
+	sub, done := words[0], words[1:len(words)-1]
+	flags := usageFlags(sub)
+	if strings.HasPrefix(cur, "-") {
+		names := make([]string, 0, len(flags))
+		for name := range flags {
+			names = append(names, "--"+name)
+		}
+		slices.Sort(names)
+		return withPrefix(names, cur)
+	}
+
+	// Walk the words before cur, skipping flags and their values, to find
+	// what cur is: a flag value or the n-th positional argument.
+	configPath, pos, valueOf := "", 0, ""
+	for i := 0; i < len(done); i++ {
+		w := done[i]
+		if !strings.HasPrefix(w, "-") || w == "-" {
+			pos++
+			continue
+		}
+		name, value, inline := strings.Cut(strings.TrimLeft(w, "-"), "=")
+		if !flags[name] || inline {
+			if name == "config" && inline {
+				configPath = value
+			}
+			continue
+		}
+		if i+1 < len(done) {
+			if name == "config" {
+				configPath = done[i+1]
+			}
+			i++
+			continue
+		}
+		valueOf = name
+	}
+	if valueOf != "" {
+		switch valueOf {
+		case "connection":
+			return withPrefix(src.connections(configPath), cur)
+		default:
+			return withPrefix(flagValues[valueOf], cur)
+		}
+	}
+	switch {
+	case sub == cmdCall && pos == 0:
+		return withPrefix(append(src.tools(configPath), "run"), cur)
+	case (sub == cmdExport || sub == cmdImport || sub == cmdBrowse) && pos == 0:
+		return withPrefix(src.connections(configPath), cur)
+	case sub == cmdAudit && pos == 0:
+		return withPrefix([]string{auditQuery, auditTail}, cur)
+	case sub == cmdCompletion && pos == 0:
+		return withPrefix(completionShells, cur)
+	}
+	return nil
+}
+
+func withPrefix(words []string, prefix string) []string {
+	var out []string
+	for _, w := range words {
+		if strings.HasPrefix(w, prefix) {
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+// usageFlags returns the flags of subcommand sub, read from its usage, and
+// whether each takes a value.
+func usageFlags(sub string) map[string]bool {
+	var usage strings.Builder
+	switch sub {
+	case cmdCall:
+		mcpclient.PrintUsage("localdb-mcp call", &usage)
+	case cmdBench:
+		_, _ = parseBenchFlags([]string{"-h"}, func(string) string { return "" }, &usage)
+	case cmdReplay:
+		_, _ = parseReplayFlags([]string{"-h"}, func(string) string { return "" }, &usage)
+	case cmdAudit:
+		_, _ = parseAuditFlags([]string{auditTail, "-h"}, func(string) string { return "" }, time.Now(), &usage)
+	default:
+		_, _ = parseFlags([]string{sub, "-h"}, func(string) string { return "" }, &usage)
+	}
+	flags := make(map[string]bool)
+	sc := bufio.NewScanner(strings.NewReader(usage.String()))
+	for sc.Scan() {
+		line, ok := strings.CutPrefix(sc.Text(), "  -")
+		if !ok {
+			continue
+		}
+		// "  -name type" for a flag with a value, "  -name" for a bool.
+		name, typ, _ := strings.Cut(line, " ")
+		flags[name] = typ != ""
+	}
+	return flags
+}
+
+// configuredConnections returns the connection IDs of the config at path
+// (or the default config), or none if it does not load.
+func configuredConnections(path string) []string {
+	cfg, err := config.LoadFrom(path)
+	if err != nil {
+		return nil
+	}
+	ids := cfg.ConnectionIDs()
+	slices.Sort(ids)
+	return ids
+}
+
+// serverTools returns the names of the tools a server with the config at
+// path offers, asking an in-process server.
+func serverTools(path string) []string {
+	ctx, cancel := context.WithTimeout(context.Background(), completeTimeout)
+	defer cancel()
+	c, closeSession, err := localSession(ctx, &options{configPath: path})
+	if err != nil {
+		return nil
+	}
+	defer closeSession()
+	res, err := c.ListTools(ctx, mcp.ListToolsRequest{})
+	if err != nil {
+		return nil
+	}
+	names := make([]string, len(res.Tools))
+	for i, t := range res.Tools {
+		names[i] = t.Name
+	}
+	slices.Sort(names)
+	return names
+}
+
+// completeWords prints the completion candidates for words, one per line,
+// for the completion scripts.
+func completeWords(words []string, w io.Writer) {
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	for _, c := range complete(words, completionSource{connections: configuredConnections, tools: serverTools}) {
+		fmt.Fprintln(w, c)
+	}
+}
+
+// completionScripts are the scripts localdb-mcp completion prints. Each
+// asks localdb-mcp __complete for the candidates of the word being
+// completed, and falls back to file names when there are none.
+var completionScripts = map[string]string{
+	"bash": `# bash completion for localdb-mcp; add to ~/.bashrc:
+#   source <(localdb-mcp completion bash)
+_localdb_mcp() {
+    local IFS=$'\n'
+    COMPREPLY=($(localdb-mcp __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+}
+complete -o default -F _localdb_mcp localdb-mcp
+`,
+	"zsh": `#compdef localdb-mcp
+# zsh completion for localdb-mcp; add to ~/.zshrc:
+#   source <(localdb-mcp completion zsh)
+_localdb_mcp() {
+    local -a candidates
+    candidates=("${(@f)$(localdb-mcp __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+    if (( ${#candidates[@]} )) && [[ -n ${candidates[1]} ]]; then
+        compadd -a candidates
+    else
+        _files
+    fi
+}
+compdef _localdb_mcp localdb-mcp
+`,
+	"fish": `# fish completion for localdb-mcp; save as
+#   ~/.config/fish/completions/localdb-mcp.fish
+function __localdb_mcp_complete
+    set -l words (commandline -opc)
+    set -l cur (commandline -ct)
+    localdb-mcp __complete $words[2..-1] "$cur" 2>/dev/null
+end
+complete -c localdb-mcp -f -a '(__localdb_mcp_complete)'
+complete -c localdb-mcp -n 'not __localdb_mcp_complete | string length -q' -F
+`,
+}
+
+// completion prints the completion script for shell.
+func completion(shell string, w io.Writer) int {
+	script, ok := completionScripts[shell]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "completion: unknown shell %q (want %s)\n", shell, strings.Join(completionShells, ", "))
+		return 1
+	}
+	fmt.Fprint(w, script)
+	return 0
+}
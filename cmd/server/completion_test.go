@@ -0,0 +1,78 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestComplete(t *testing.T) {
+	var gotConfig string
+	src := completionSource{
+		connections: func(path string) []string { gotConfig = path; return []string{"demo", "postgres"} },
+		tools:       func(path string) []string { gotConfig = path; return []string{"list_tables", "run_query"} },
+	}
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"", subcommands},
+		{"ex", []string{"export"}},
+		{"call ", []string{"list_tables", "run_query", "run"}},
+		{"call --output json ru", []string{"run_query", "run"}},
+		{"call --output ", []string{"table", "json", "csv", "raw"}},
+		{"call --connection p", []string{"postgres"}},
+		{"call --json list_tables ", nil},
+		{"call --ser", []string{"--server-cmd"}},
+		{"export ", []string{"demo", "postgres"}},
+		{"import --yes demo ", nil},
+		{"serve --transport h", []string{"http"}},
+		{"serve --log-level=debug --rea", []string{"--read-only"}},
+		{"check --con", []string{"--config"}},
+		{"completion ", []string{"bash", "zsh", "fish"}},
+	}
+	for _, tt := range tests {
+		words := strings.Split(tt.line, " ")
+		if got := complete(words, src); !slices.Equal(got, tt.want) {
+			t.Errorf("complete(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+
+	complete(strings.Split("export --config /tmp/c.yaml ", " "), src)
+	if gotConfig != "/tmp/c.yaml" {
+		t.Errorf("--config: looked up %q", gotConfig)
+	}
+	complete(strings.Split("call --config=/tmp/d.yaml ", " "), src)
+	if gotConfig != "/tmp/d.yaml" {
+		t.Errorf("--config=: looked up %q", gotConfig)
+	}
+}
+
+func TestUsageFlags(t *testing.T) {
+	serve := usageFlags(cmdServe)
+	if !serve["transport"] || serve["read-only"] || !contains(serve, "read-only") {
+		t.Errorf("serve flags: %v", serve)
+	}
+	call := usageFlags(cmdCall)
+	if !call["connection"] || call["json"] || !contains(call, "json") || contains(call, "transport") {
+		t.Errorf("call flags: %v", call)
+	}
+}
+
+func contains(flags map[string]bool, name string) bool {
+	_, ok := flags[name]
+	return ok
+}
+
+func TestCompletion(t *testing.T) {
+	for _, shell := range completionShells {
+		var b strings.Builder
+		if completion(shell, &b) != 0 || !strings.Contains(b.String(), "localdb-mcp __complete") {
+			t.Errorf("%s: %q", shell, b.String())
+		}
+	}
+	var b strings.Builder
+	if completion("tcsh", &b) == 0 {
+		t.Error("tcsh: no error")
+	}
+}
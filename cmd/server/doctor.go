@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
+	internal_server "github.com/SedlarDavid/localdb-mcp/internal/server"
+)
+
+// doctorQuery is the statement doctor runs on every connection that
+// answers, through run_query like an agent would.
+const doctorQuery = "SELECT 1"
+
+// toolHints say how to install a missing CLI tool.
+var toolHints = map[string]string{
+	"pg_dump":   "install the PostgreSQL client tools: brew install libpq, apt install postgresql-client",
+	"psql":      "install the PostgreSQL client tools: brew install libpq, apt install postgresql-client",
+	"mysqldump": "install the MySQL client tools: brew install mysql-client, apt install mysql-client",
+	"mysql":     "install the MySQL client tools: brew install mysql-client, apt install mysql-client",
+	"sqlite3":   "install the sqlite3 shell: brew install sqlite, apt install sqlite3",
+	"sqlcmd":    "install sqlcmd: brew install sqlcmd, or see https://learn.microsoft.com/sql/tools/sqlcmd/sqlcmd-utility",
+}
+
+// connectionHint returns the hint for connection id, which failed: what
+// db.Diagnose finds out about its URI, or generic advice.
+func connectionHint(ctx context.Context, cfg *config.Config, id, typ string) string {
+	if uri, ok := cfg.URI(id); ok {
+		if hint := db.Diagnose(ctx, typ, uri); hint != "" {
+			return hint
+		}
+	}
+	return "check the connection URI and that the database server is reachable from this machine"
+}
+
+// doctor connects to every connection, runs doctorQuery on the ones that
+// answer and looks for the CLI tools export and import need for the
+// database types in use, printing a pass/fail line per check with a hint
+// for each failure. find looks up a CLI tool, as db.FindCLITool. It fails
+// if the config does not load or a connection or its query fails; a
+// missing CLI tool is only a warning, since it is needed for export and
+// import alone.
+func doctor(opts *options, w io.Writer, find func(string) (string, error)) int {
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+	c, closeSession, err := localSession(ctx, opts)
+	if err != nil {
+		fmt.Fprintf(w, "FAIL  %v\n      hint: fix the config file, or run localdb-mcp init for a starter one\n", err)
+		return 1
+	}
+	defer closeSession()
+	cfg, err := config.LoadFrom(opts.configPath)
+	if err != nil {
+		fmt.Fprintf(w, "FAIL  config: %v\n", err)
+		return 1
+	}
+
+	fmt.Fprintln(w, "Connections")
+	var health internal_server.HealthOutput
+	if err := callLocal(ctx, c, "health", map[string]any{"connect": true}, &health); err != nil {
+		fmt.Fprintf(w, "  FAIL  health: %v\n", err)
+		return 1
+	}
+	if len(health.Connections) == 0 {
+		fmt.Fprintln(w, "  FAIL  no connections configured\n        hint: add one to the config file or set MCP_DB_POSTGRES_URI and the like")
+		return 1
+	}
+	failed, checks := 0, 0
+	var types []string
+	for _, h := range health.Connections {
+		checks++
+		if !slices.Contains(types, h.Type) {
+			types = append(types, h.Type)
+		}
+		if !h.OK {
+			failed++
+			fmt.Fprintf(w, "  FAIL  %s (%s): %s\n        hint: %s\n", h.ID, h.Type, h.Error, connectionHint(ctx, cfg, h.ID, h.Type))
+			continue
+		}
+		var out internal_server.RunQueryOutput
+		if err := callLocal(ctx, c, "run_query", map[string]any{"connection_id": h.ID, "sql": doctorQuery}, &out); err != nil {
+			failed++
+			fmt.Fprintf(w, "  FAIL  %s (%s): connected, but %s failed: %v\n        hint: check that the user may run queries on the database\n", h.ID, h.Type, doctorQuery, err)
+			continue
+		}
+		latency := ""
+		if h.LatencyMS != nil {
+			latency = fmt.Sprintf(" in %.1fms", *h.LatencyMS)
+		}
+		fmt.Fprintf(w, "  PASS  %s (%s): connected%s, %s ok\n", h.ID, h.Type, latency, doctorQuery)
+	}
+
+	fmt.Fprintln(w, "Export/import tools")
+	slices.Sort(types)
+	warned, needed := 0, 0
+	for _, typ := range types {
+		for _, tool := range db.CLITools(typ) {
+			needed++
+			if path, err := find(tool); err != nil {
+				warned++
+				fmt.Fprintf(w, "  WARN  %s: not found; export and import on %s connections need it\n        hint: %s\n", tool, typ, toolHints[tool])
+			} else {
+				fmt.Fprintf(w, "  PASS  %s: %s\n", tool, path)
+			}
+		}
+	}
+	if needed == 0 {
+		fmt.Fprintln(w, "  PASS  none needed for the configured connections")
+	}
+
+	switch {
+	case failed > 0:
+		fmt.Fprintf(w, "%d of %d connections failed\n", failed, checks)
+		return 1
+	case warned > 0:
+		fmt.Fprintf(w, "All connections ok; %d of %d export/import tools missing\n", warned, needed)
+	default:
+		fmt.Fprintln(w, "All checks passed")
+	}
+	return 0
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+)
+
+func TestDoctor(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	content := "connections:\n  demo: demo\n  sqlite: " + filepath.Join(dir, "missing", "app.db") + "\n"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for _, env := range []string{config.EnvPostgresURI, config.EnvSQLServerURI, config.EnvSQLiteURI, config.EnvMySQLURI} {
+		t.Setenv(env, "")
+	}
+	find := func(name string) (string, error) { return "", errors.New("not found") }
+	var out strings.Builder
+	if code := doctor(&options{configPath: path}, &out, find); code != 1 {
+		t.Errorf("doctor: exit %d, want 1", code)
+	}
+	for _, want := range []string{
+		"PASS  demo (demo): connected",
+		"SELECT 1 ok",
+		"FAIL  sqlite (sqlite):",
+		"hint: directory " + filepath.Join(dir, "missing") + " does not exist",
+		"WARN  sqlite3: not found",
+		"hint: install the sqlite3 shell",
+		"1 of 2 connections failed",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("doctor output lacks %q:\n%s", want, out.String())
+		}
+	}
+
+	if err := os.WriteFile(path, []byte("connections:\n  demo: demo\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if code := doctor(&options{configPath: path}, &out, find); code != 0 || !strings.Contains(out.String(), "All checks passed") {
+		t.Errorf("doctor: exit %d\n%s", code, out.String())
+	}
+}
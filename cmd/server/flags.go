@@ -0,0 +1,195 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	internal_server "github.com/SedlarDavid/localdb-mcp/internal/server"
+)
+
+// Env var equivalents of the command-line flags. A flag given on the command
+// line wins over its env var.
+const (
+	envTransport    = "MCP_TRANSPORT"
+	envAddr         = "MCP_ADDR"
+	envAllowRemote  = "MCP_ALLOW_REMOTE"
+	envDrainTimeout = "MCP_DRAIN_TIMEOUT"
+	envLogLevel     = "MCP_LOG_LEVEL"
+)
+
+// Subcommands, the first argument. Without one the server is run, as with
+// serve.
+const (
+	cmdServe  = "serve"  // run the MCP server
+	cmdAttach = "attach" // bridge stdio to the daemon at serve.Addr
+	cmdSecure = "secure" // move plaintext credentials to the keychain
+	cmdCall   = "call"   // call a tool, like cmd/mcpclient
+	cmdBench  = "bench"  // load a server with concurrent tool calls
+	cmdReplay = "replay" // re-send the tool calls of a recording
+	cmdAudit  = "audit"  // print or follow the audit log
+	cmdBrowse = "browse" // walk a connection's schemas, tables and rows
+	cmdCheck  = "check"  // check the config and every connection
+	cmdDoctor = "doctor" // check connections, a query and the CLI tools, with hints
+	cmdExport = "export" // export a connection's database to a dump file
+	cmdImport = "import" // import a dump file into a connection's database
+	cmdInit   = "init"   // write a starter config file
+	// cmdCompletion prints a shell completion script; cmdComplete, which
+	// the scripts call, prints the candidates for a command line.
+	cmdCompletion = "completion"
+	cmdComplete   = "__complete"
+)
+
+// options is the parsed command line.
+type options struct {
+	command    string // one of the cmd constants; "" runs the server
+	serve      internal_server.ServeOptions
+	configPath string
+	logLevel   slog.Level
+	readOnly   *bool // nil unless --read-only was given; config.Load reads the env var
+	version    bool
+	dryRun     bool     // secure --dry-run: only report what would move
+	recordFile string   // serve --record: the file to record tool calls to
+	demoOnly   bool     // replay --demo: only the demo connection
+	yes        bool     // import --yes: do not ask before importing
+	callArgs   []string // call, bench, replay, audit and __complete: the arguments, parsed elsewhere
+	shell      string   // completion: the shell to write a script for
+	connID     string   // export, import and browse: the connection
+	dumpPath   string   // export and import: the dump file
+}
+
+// parseFlags parses args (without the program name), taking defaults from
+// the env vars looked up with getenv. Usage and errors are reported on out.
+// A leading subcommand selects something other than serving (see the cmd
+// constants): "attach" the stdio bridge to a unix-socket daemon, with
+// --addr naming the socket; "secure" the credential migration, with
+// --config naming the file to migrate; "call" the MCP client, which parses
+// the rest of args itself, as do "bench", "replay" and "audit"; "export" and "import" take a connection ID and
+// a dump path after the flags.
+func parseFlags(args []string, getenv func(string) string, out io.Writer) (*options, error) {
+	fail := func(err error) (*options, error) {
+		fmt.Fprintln(out, err)
+		return nil, err
+	}
+	var o options
+	if len(args) > 0 {
+		switch args[0] {
+		case cmdServe, cmdAttach, cmdSecure, cmdCheck, cmdDoctor, cmdBrowse, cmdExport, cmdImport, cmdInit, cmdCompletion:
+			o.command = args[0]
+			args = args[1:]
+		case cmdCall, cmdBench, cmdReplay, cmdAudit, cmdComplete:
+			// mcpclient, bench, replay and audit parse their own flags; a command line to
+			// complete is incomplete.
+			o.command = args[0]
+			o.callArgs = args[1:]
+			return &o, nil
+		}
+	}
+	if o.command == cmdServe {
+		o.command = ""
+	}
+	allowRemote, err := envBool(getenv, envAllowRemote)
+	if err != nil {
+		return fail(err)
+	}
+	drain := internal_server.DefaultDrainTimeout
+	if v := getenv(envDrainTimeout); v != "" {
+		if drain, err = time.ParseDuration(v); err != nil {
+			return fail(fmt.Errorf("%s: %w", envDrainTimeout, err))
+		}
+	}
+
+	fs := flag.NewFlagSet("localdb-mcp", flag.ContinueOnError)
+	fs.SetOutput(out)
+	fs.Usage = func() {
+		fmt.Fprintln(out, `usage: localdb-mcp [serve] [flags]
+       localdb-mcp init [--config path]
+       localdb-mcp check [flags]
+       localdb-mcp doctor [flags]
+       localdb-mcp call [mcpclient flags] <tool_name> [json_arguments]
+       localdb-mcp bench [bench flags]
+       localdb-mcp replay [replay flags] <recording.jsonl>
+       localdb-mcp audit tail|query [audit flags]
+       localdb-mcp browse [flags] <connection_id>
+       localdb-mcp export [flags] <connection_id> <path>
+       localdb-mcp import [--yes] [flags] <connection_id> <path>
+       localdb-mcp attach [--addr socket]
+       localdb-mcp secure [--config path] [--dry-run]
+       localdb-mcp completion bash|zsh|fish`)
+		fs.PrintDefaults()
+	}
+	fs.BoolVar(&o.version, "version", false, "print the version and exit")
+	fs.StringVar(&o.configPath, "config", getenv(config.EnvConfigFile), "config file to load instead of ~/.localdb-mcp/config.yaml (env "+config.EnvConfigFile+")")
+	fs.StringVar(&o.serve.Transport, "transport", envOr(getenv, envTransport, internal_server.TransportStdio), "MCP transport: stdio, sse, http (streamable HTTP) or unix (daemon for localdb-mcp attach) (env "+envTransport+")")
+	fs.StringVar(&o.serve.Addr, "addr", envOr(getenv, envAddr, internal_server.DefaultAddr), "listen address for the sse and http transports, or socket path for unix and attach (default ~/.localdb-mcp/localdb-mcp.sock) (env "+envAddr+")")
+	fs.BoolVar(&o.serve.AllowRemote, "allow-remote", allowRemote, "allow the sse/http transports to listen on non-loopback interfaces (env "+envAllowRemote+")")
+	fs.DurationVar(&o.serve.DrainTimeout, "drain-timeout", drain, "how long in-flight tool calls may finish after SIGINT/SIGTERM (env "+envDrainTimeout+")")
+	logLevel := fs.String("log-level", envOr(getenv, envLogLevel, "info"), "log level: debug, info, warn or error (env "+envLogLevel+")")
+	readOnly := fs.Bool("read-only", false, "do not offer tools that write to a database (env "+config.EnvReadOnly+")")
+	fs.BoolVar(&o.dryRun, "dry-run", false, "with secure: list the credentials that would be moved without changing anything")
+	fs.StringVar(&o.recordFile, "record", "", "append every tool call and its result to this JSONL file, for localdb-mcp replay (env "+config.EnvRecordFile+")")
+	fs.BoolVar(&o.yes, "yes", false, "with import: import without asking for confirmation")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "read-only" {
+			o.readOnly = readOnly
+		}
+	})
+	switch {
+	case o.command == cmdExport || o.command == cmdImport:
+		if fs.NArg() != 2 {
+			return fail(fmt.Errorf("usage: localdb-mcp %s [flags] <connection_id> <path>", o.command))
+		}
+		o.connID, o.dumpPath = fs.Arg(0), fs.Arg(1)
+	case o.command == cmdBrowse:
+		if fs.NArg() != 1 {
+			return fail(fmt.Errorf("usage: localdb-mcp browse [flags] <connection_id>"))
+		}
+		o.connID = fs.Arg(0)
+	case o.command == cmdCompletion:
+		if fs.NArg() != 1 {
+			return fail(fmt.Errorf("usage: localdb-mcp completion bash|zsh|fish"))
+		}
+		o.shell = fs.Arg(0)
+	case fs.NArg() > 0:
+		return fail(fmt.Errorf("unexpected arguments: %v", fs.Args()))
+	}
+	if o.dryRun && o.command != cmdSecure {
+		return fail(fmt.Errorf("--dry-run only applies to localdb-mcp secure"))
+	}
+	if o.recordFile != "" && o.command != "" {
+		return fail(fmt.Errorf("--record only applies to localdb-mcp serve"))
+	}
+	if o.yes && o.command != cmdImport {
+		return fail(fmt.Errorf("--yes only applies to localdb-mcp import"))
+	}
+	if err := o.logLevel.UnmarshalText([]byte(*logLevel)); err != nil {
+		return fail(fmt.Errorf("invalid log level %q (want debug, info, warn or error)", *logLevel))
+	}
+	return &o, nil
+}
+
+func envOr(getenv func(string) string, name, def string) string {
+	if v := getenv(name); v != "" {
+		return v
+	}
+	return def
+}
+
+func envBool(getenv func(string) string, name string) (bool, error) {
+	v := getenv(name)
+	if v == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", name, err)
+	}
+	return b, nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	internal_server "github.com/SedlarDavid/localdb-mcp/internal/server"
+)
+
+func getenvFrom(env map[string]string) func(string) string {
+	return func(name string) string { return env[name] }
+}
+
+func TestParseFlags_defaults(t *testing.T) {
+	o, err := parseFlags(nil, getenvFrom(nil), io.Discard)
+	if err != nil {
+		t.Fatalf("parseFlags: %v", err)
+	}
+	if o.serve.Transport != internal_server.TransportStdio || o.serve.Addr != internal_server.DefaultAddr {
+		t.Errorf("unexpected serve defaults: %+v", o.serve)
+	}
+	if o.logLevel != slog.LevelInfo || o.readOnly != nil || o.version || o.configPath != "" {
+		t.Errorf("unexpected defaults: %+v", o)
+	}
+}
+
+func TestParseFlags_envAndOverride(t *testing.T) {
+	env := getenvFrom(map[string]string{
+		envTransport:         "sse",
+		envAddr:              ":9000",
+		envAllowRemote:       "true",
+		envDrainTimeout:      "3s",
+		envLogLevel:          "debug",
+		config.EnvConfigFile: "/etc/localdb-mcp.yaml",
+	})
+	o, err := parseFlags(nil, env, io.Discard)
+	if err != nil {
+		t.Fatalf("parseFlags: %v", err)
+	}
+	want := internal_server.ServeOptions{Transport: "sse", Addr: ":9000", AllowRemote: true, DrainTimeout: 3 * time.Second}
+	if o.serve != want {
+		t.Errorf("serve = %+v, want %+v", o.serve, want)
+	}
+	if o.logLevel != slog.LevelDebug || o.configPath != "/etc/localdb-mcp.yaml" {
+		t.Errorf("unexpected options from env: %+v", o)
+	}
+
+	o, err = parseFlags([]string{"--transport", "http", "--log-level", "warn", "--read-only"}, env, io.Discard)
+	if err != nil {
+		t.Fatalf("parseFlags: %v", err)
+	}
+	if o.serve.Transport != "http" || o.logLevel != slog.LevelWarn {
+		t.Errorf("flags should override env, got %+v", o)
+	}
+	if o.readOnly == nil || !*o.readOnly {
+		t.Error("expected --read-only to be recorded")
+	}
+}
+
+func TestParseFlags_errors(t *testing.T) {
+	tests := []struct {
+		args []string
+		env  map[string]string
+	}{
+		{[]string{"--log-level", "loud"}, nil},
+		{[]string{"--no-such-flag"}, nil},
+		{[]string{"extra"}, nil},
+		{nil, map[string]string{envAllowRemote: "maybe"}},
+		{nil, map[string]string{envDrainTimeout: "soon"}},
+	}
+	for _, tt := range tests {
+		if _, err := parseFlags(tt.args, getenvFrom(tt.env), io.Discard); err == nil {
+			t.Errorf("parseFlags(%v) with env %v: expected error", tt.args, tt.env)
+		}
+	}
+}
+
+func TestParseFlags_attach(t *testing.T) {
+	o, err := parseFlags([]string{"attach", "--addr", "/tmp/d.sock"}, getenvFrom(nil), io.Discard)
+	if err != nil {
+		t.Fatalf("parseFlags: %v", err)
+	}
+	if o.command != cmdAttach || o.serve.Addr != "/tmp/d.sock" {
+		t.Errorf("attach options: %+v", o)
+	}
+	if o, _ := parseFlags(nil, getenvFrom(nil), io.Discard); o.command != "" {
+		t.Error("attach should only be set by the subcommand")
+	}
+}
+
+func TestParseFlags_secure(t *testing.T) {
+	o, err := parseFlags([]string{"secure", "--config", "/tmp/c.yaml", "--dry-run"}, getenvFrom(nil), io.Discard)
+	if err != nil {
+		t.Fatalf("parseFlags: %v", err)
+	}
+	if o.command != cmdSecure || !o.dryRun || o.configPath != "/tmp/c.yaml" {
+		t.Errorf("unexpected options: %+v", o)
+	}
+	if _, err := parseFlags([]string{"--dry-run"}, getenvFrom(nil), io.Discard); err == nil {
+		t.Error("--dry-run without secure should fail")
+	}
+}
+
+func TestParseFlags_subcommands(t *testing.T) {
+	o, err := parseFlags([]string{"call", "--output", "csv", "run_query", "{}"}, getenvFrom(nil), io.Discard)
+	if err != nil {
+		t.Fatalf("parseFlags: %v", err)
+	}
+	if o.command != cmdCall || len(o.callArgs) != 4 || o.callArgs[0] != "--output" {
+		t.Errorf("call: %+v", o)
+	}
+	o, err = parseFlags([]string{"import", "--yes", "--config", "/tmp/c.yaml", "sqlite", "dump.sql"}, getenvFrom(nil), io.Discard)
+	if err != nil {
+		t.Fatalf("parseFlags: %v", err)
+	}
+	if o.command != cmdImport || !o.yes || o.connID != "sqlite" || o.dumpPath != "dump.sql" || o.configPath != "/tmp/c.yaml" {
+		t.Errorf("import: %+v", o)
+	}
+	if o, err = parseFlags([]string{"serve", "--transport", "http"}, getenvFrom(nil), io.Discard); err != nil || o.command != "" || o.serve.Transport != "http" {
+		t.Errorf("serve: %+v, %v", o, err)
+	}
+	for _, args := range [][]string{
+		{"export", "sqlite"},
+		{"import", "sqlite", "dump.sql", "extra"},
+		{"check", "sqlite"},
+		{"export", "--yes", "sqlite", "dump.sql"},
+	} {
+		if _, err := parseFlags(args, getenvFrom(nil), io.Discard); err == nil {
+			t.Errorf("%q: no error", args)
+		}
+	}
+}
+
+func TestParseFlags_completion(t *testing.T) {
+	o, err := parseFlags([]string{"completion", "zsh"}, getenvFrom(nil), io.Discard)
+	if err != nil || o.command != cmdCompletion || o.shell != "zsh" {
+		t.Errorf("completion: %+v, %v", o, err)
+	}
+	o, err = parseFlags([]string{"__complete", "call", "--"}, getenvFrom(nil), io.Discard)
+	if err != nil || o.command != cmdComplete || len(o.callArgs) != 2 {
+		t.Errorf("__complete: %+v, %v", o, err)
+	}
+	if _, err := parseFlags([]string{"completion"}, getenvFrom(nil), io.Discard); err == nil {
+		t.Error("completion without a shell: no error")
+	}
+}
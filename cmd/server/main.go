@@ -1,31 +1,124 @@
 // Package main runs the localdb-mcp server: an MCP server that exposes
 // read-only and controlled-write database tools to agents (e.g. Cursor)
-// without exposing credentials.
+// without exposing credentials. Its subcommands cover the rest of the
+// tool's life:
+//
+//	localdb-mcp [serve] [flags]                        # the MCP server
+//	localdb-mcp init [--config path]                   # write a starter config
+//	localdb-mcp check                                  # connect to every connection
+//	localdb-mcp call <tool_name> [json_arguments]      # call one tool
+//	localdb-mcp export <connection_id> <path>          # dump a database
+//	localdb-mcp import [--yes] <connection_id> <path>  # load a dump
+//	localdb-mcp attach | secure                        # see the README
+//	localdb-mcp completion bash|zsh|fish               # shell completion
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
 	"log"
+	"log/slog"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
 
 	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
 	internal_server "github.com/SedlarDavid/localdb-mcp/internal/server"
 	"github.com/mark3labs/mcp-go/server"
 )
 
 func main() {
-	// Redirect logs to file for debugging if MCP_DEBUG is set
+	opts, err := parseFlags(os.Args[1:], os.Getenv, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		os.Exit(2)
+	}
+	if opts.version {
+		fmt.Printf("%s %s\n", internal_server.ServerName, internal_server.ServerVersion)
+		return
+	}
+	switch opts.command {
+	case cmdAttach:
+		attach(opts)
+		return
+	case cmdSecure:
+		secure(opts)
+		return
+	case cmdCall:
+		os.Exit(call(opts))
+	case cmdBench, cmdReplay, cmdCheck, cmdDoctor, cmdExport, cmdImport:
+		// Failed calls are reported by the subcommand, so the in-process
+		// server only logs errors of its own.
+		slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: max(opts.logLevel, slog.LevelError)})))
+		switch opts.command {
+		case cmdBench:
+			os.Exit(bench(opts.callArgs, os.Stdout))
+		case cmdReplay:
+			os.Exit(replay(opts.callArgs, os.Stdout))
+		case cmdCheck:
+			os.Exit(check(opts, os.Stdout))
+		case cmdDoctor:
+			os.Exit(doctor(opts, os.Stdout, db.FindCLITool))
+		case cmdExport:
+			os.Exit(export(opts, os.Stdout))
+		default:
+			os.Exit(importDump(opts, os.Stdin, os.Stdout))
+		}
+	case cmdAudit:
+		os.Exit(audit(opts.callArgs, os.Stdout))
+	case cmdBrowse:
+		os.Exit(browse(opts))
+	case cmdInit:
+		os.Exit(initConfig(opts, os.Stdout))
+	case cmdCompletion:
+		os.Exit(completion(opts.shell, os.Stdout))
+	case cmdComplete:
+		completeWords(opts.callArgs, os.Stdout)
+		return
+	}
+
+	// Logs go to stderr (stdout carries the stdio transport), or to a file
+	// for debugging if MCP_DEBUG is set. log.Printf output is logged at info.
+	var logOut io.Writer = os.Stderr
 	if os.Getenv("MCP_DEBUG") != "" {
 		f, err := os.OpenFile("/tmp/localdb-mcp.log", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
 		if err == nil {
-			log.SetOutput(f)
+			logOut = f
 			defer f.Close()
 		}
 	}
-	
-	cfg, err := config.Load()
+	slog.SetDefault(slog.New(slog.NewTextHandler(logOut, &slog.HandlerOptions{Level: opts.logLevel})))
+
+	cfg, err := config.LoadFrom(opts.configPath)
 	if err != nil {
 		log.Fatalf("config: %v", err)
 	}
+	// From here on log lines may carry driver or dump tool output.
+	slog.SetDefault(slog.New(internal_server.RedactingHandler(slog.Default().Handler(), cfg)))
+	if opts.readOnly != nil {
+		cfg.SetReadOnly(*opts.readOnly)
+	}
+	if opts.recordFile != "" {
+		path, err := filepath.Abs(opts.recordFile)
+		if err != nil {
+			log.Fatalf("record: %v", err)
+		}
+		cfg.SetRecordFile(path)
+	}
+	opts.serve.AuthToken = cfg.AuthToken()
+	switch {
+	case cfg.GlobalReadOnly():
+		slog.Info("global read-only lock: write tools are disabled")
+	case cfg.ReadOnly():
+		slog.Info("read-only mode: write tools are disabled")
+	}
 
 	// Create MCP server
 	s := server.NewMCPServer(
@@ -34,9 +127,86 @@ func main() {
 	)
 
 	// Register tools
-	internal_server.Register(s, cfg)
+	mgr := internal_server.Register(s, cfg)
+	if mgr != nil {
+		opts.serve.Metrics = internal_server.MetricsHandler(cfg, mgr)
+	}
+
+	// SIGINT/SIGTERM start a graceful shutdown: Serve stops accepting
+	// requests and returns once in-flight tool calls have drained.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// SIGHUP reloads the connections from the config file; clients are told
+	// to refresh their tool lists.
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	go func() {
+		for range hup {
+			next, err := config.LoadFrom(opts.configPath)
+			if err != nil {
+				slog.Error("reload config", "err", err)
+				continue
+			}
+			changed, err := internal_server.Reload(s, mgr, cfg, next)
+			if err != nil {
+				slog.Warn("reload config: close connections", "err", err)
+			}
+			slog.Info("reloaded config", "changed_connections", changed)
+		}
+	}()
 
-	if err := server.ServeStdio(s); err != nil {
+	if err := internal_server.Serve(ctx, s, opts.serve); err != nil {
 		log.Printf("server error: %v", err)
 	}
+	if mgr != nil {
+		if err := mgr.Close(); err != nil {
+			log.Printf("close connections: %v", err)
+		}
+	}
+}
+
+// attach bridges this process's stdio to a daemon started with
+// --transport=unix, so an editor restart keeps the daemon's database
+// connections warm.
+func attach(opts *options) {
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: opts.logLevel})))
+	socket := opts.serve.Addr
+	if socket == internal_server.DefaultAddr {
+		socket = ""
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := internal_server.Attach(ctx, socket, os.Stdin, os.Stdout); err != nil {
+		log.Fatalf("attach: %v", err)
+	}
+}
+
+// secure moves the plaintext credentials of the config file and of .env in
+// the working directory into the OS keychain, rewriting both files to
+// reference them.
+func secure(opts *options) {
+	path := opts.configPath
+	if path == "" {
+		var err error
+		if path, err = config.DefaultConfigPath(); err != nil {
+			log.Fatalf("config path: %v", err)
+		}
+	}
+	moved, err := config.Secure(path, ".env", opts.dryRun)
+	if err != nil {
+		log.Fatalf("secure: %v", err)
+	}
+	if len(moved) == 0 {
+		fmt.Println("No plaintext credentials found.")
+		return
+	}
+	verb := "Moved"
+	if opts.dryRun {
+		verb = "Would move"
+	}
+	for _, m := range moved {
+		fmt.Printf("%s %s in %s to the keychain (service %s, account %s)\n", verb, m.Key, m.File, config.KeychainService, m.Account)
+	}
 }
@@ -0,0 +1,367 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	"github.com/SedlarDavid/localdb-mcp/internal/mcpclient"
+	internal_server "github.com/SedlarDavid/localdb-mcp/internal/server"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// replayOptions is the parsed command line of localdb-mcp replay.
+type replayOptions struct {
+	path       string
+	configPath string
+	url        string
+	token      string
+	demo       bool   // replay against the demo database only
+	connection string // connection_id every call is sent to instead of its own
+	compare    bool
+	ignore     []string
+	timeout    time.Duration
+}
+
+// defaultReplayIgnore are the result keys that differ from run to run.
+const defaultReplayIgnore = "request_id,latency_ms,last_ping,unlocked_until,expires_at"
+
+// issuedKeys are the result keys whose values a later call passes back as
+// an argument of the same name. Replay maps the recorded values to the
+// ones the server issues now.
+var issuedKeys = []string{"confirmation_token", "transaction_id"}
+
+// parseReplayFlags parses the arguments of localdb-mcp replay.
+func parseReplayFlags(args []string, getenv func(string) string, out io.Writer) (*replayOptions, error) {
+	fail := func(err error) (*replayOptions, error) {
+		fmt.Fprintln(out, err)
+		return nil, err
+	}
+	var o replayOptions
+	fs := flag.NewFlagSet("localdb-mcp replay", flag.ContinueOnError)
+	fs.SetOutput(out)
+	fs.Usage = func() {
+		fmt.Fprintln(out, "usage: localdb-mcp replay [flags] <recording.jsonl>")
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&o.configPath, "config", getenv(config.EnvConfigFile), "config file for the in-process server (env "+config.EnvConfigFile+")")
+	fs.StringVar(&o.url, "url", "", "endpoint of a running server to replay against instead of an in-process one")
+	fs.StringVar(&o.token, "token", getenv(config.EnvAuthToken), "bearer token for --url (env "+config.EnvAuthToken+")")
+	fs.BoolVar(&o.demo, "demo", false, "replay against the built-in demo database, sending every call to it")
+	fs.StringVar(&o.connection, "connection", "", "connection_id to send every call to instead of the recorded one")
+	fs.BoolVar(&o.compare, "compare", true, "fail calls whose result differs from the recorded one; without it only calls that fail now and did not then are reported")
+	ignore := fs.String("ignore", defaultReplayIgnore, "comma-separated result keys to leave out of the comparison, at any depth")
+	fs.DurationVar(&o.timeout, "timeout", 30*time.Second, "deadline of each call")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() != 1 {
+		return fail(errors.New("usage: localdb-mcp replay [flags] <recording.jsonl>"))
+	}
+	o.path = fs.Arg(0)
+	for _, k := range strings.Split(*ignore, ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			o.ignore = append(o.ignore, k)
+		}
+	}
+	switch {
+	case o.demo && o.url != "":
+		return fail(errors.New("--demo and --url cannot be used together: --demo replays in-process"))
+	case o.demo && o.connection != "":
+		return fail(errors.New("--demo sends every call to the demo connection; leave out --connection"))
+	case o.url != "" && o.configPath != "":
+		return fail(errors.New("--url and --config cannot be used together: the running server has its own config"))
+	}
+	if o.demo {
+		o.connection = config.DemoConnectionID
+	}
+	return &o, nil
+}
+
+// loadRecording reads the calls of the recording at path, one JSON object
+// per line; blank lines and lines starting with # are skipped.
+func loadRecording(path string) ([]internal_server.RecordedCall, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var calls []internal_server.RecordedCall
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(nil, 64<<20)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var c internal_server.RecordedCall
+		if err := json.Unmarshal([]byte(line), &c); err != nil {
+			return nil, fmt.Errorf("%s: line %d: %w", path, n, err)
+		}
+		if c.Tool == "" {
+			return nil, fmt.Errorf("%s: line %d: no tool", path, n)
+		}
+		calls = append(calls, c)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(calls) == 0 {
+		return nil, fmt.Errorf("%s: no calls", path)
+	}
+	return calls, nil
+}
+
+// replay re-sends the calls of a recording, in order, on one session and
+// compares each result with the recorded one.
+func replay(args []string, w io.Writer) int {
+	o, err := parseReplayFlags(args, os.Getenv, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		return 2
+	}
+	calls, err := loadRecording(o.path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "replay: %v\n", err)
+		return 1
+	}
+	ctx := context.Background()
+	var c *client.Client
+	if o.url != "" {
+		if c, err = mcpclient.DialURL(ctx, o.url, o.token); err == nil {
+			defer c.Close()
+			initReq := mcp.InitializeRequest{}
+			initReq.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+			initReq.Params.ClientInfo = mcp.Implementation{Name: "localdb-mcp replay", Version: internal_server.ServerVersion}
+			_, err = c.Initialize(ctx, initReq)
+		}
+	} else {
+		var closeSession func()
+		if c, closeSession, err = localSession(ctx, &options{configPath: o.configPath, demoOnly: o.demo}); err == nil {
+			defer closeSession()
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "replay: %v\n", err)
+		return 1
+	}
+	return replayCalls(ctx, c, calls, o, w)
+}
+
+// replayCalls sends calls on c and prints one line per call. It returns 1
+// if any call did not match.
+func replayCalls(ctx context.Context, c *client.Client, calls []internal_server.RecordedCall, o *replayOptions, w io.Writer) int {
+	issued := make(map[string]any) // recorded value of an issuedKeys key -> the one issued now
+	failed := 0
+	for i, rc := range calls {
+		args := maps.Clone(rc.Arguments)
+		if _, ok := args["connection_id"]; ok && o.connection != "" {
+			args["connection_id"] = o.connection
+		}
+		for _, k := range issuedKeys {
+			if v, ok := args[k].(string); ok {
+				if now, ok := issued[v]; ok {
+					args[k] = now
+				}
+			}
+		}
+		callCtx, cancel := context.WithTimeout(ctx, o.timeout)
+		res, err := c.CallTool(callCtx, mcp.CallToolRequest{Params: mcp.CallToolParams{Name: rc.Tool, Arguments: args}})
+		cancel()
+		var got any
+		gotErr := false
+		switch {
+		case err != nil:
+			got, gotErr = map[string]any{"code": "transport", "message": err.Error()}, true
+		default:
+			got, gotErr = resultValue(res), res.IsError
+		}
+		var want any
+		if rc.Error != nil {
+			_ = json.Unmarshal(rc.Error, &want)
+		} else if rc.Result != nil {
+			_ = json.Unmarshal(rc.Result, &want)
+		}
+		if gm, ok := got.(map[string]any); ok && !gotErr {
+			if wm, ok := want.(map[string]any); ok {
+				for _, k := range issuedKeys {
+					if v, ok := wm[k].(string); ok && gm[k] != nil {
+						issued[v] = gm[k]
+					}
+				}
+			}
+		}
+
+		verdict := "ok"
+		switch {
+		case gotErr && rc.Error == nil:
+			verdict = "FAILED: " + errorSummary(got)
+		case !gotErr && rc.Error != nil:
+			verdict = "FAILED: succeeded, but the recorded call failed with " + errorSummary(want)
+		case gotErr:
+			// Both failed: the code is what should match; messages carry
+			// details such as request IDs and server versions.
+			if o.compare && errorCode(got) != errorCode(want) {
+				verdict = fmt.Sprintf("FAILED: error %s, recorded %s", errorSummary(got), errorSummary(want))
+			}
+		case o.compare:
+			if path, diff := firstDiff("", strip(want, o.ignore), strip(got, o.ignore)); diff != "" {
+				if path == "" {
+					path = "result"
+				}
+				verdict = fmt.Sprintf("FAILED: %s differs: %s", path, diff)
+			}
+		}
+		if verdict != "ok" {
+			failed++
+		}
+		fmt.Fprintf(w, "[%d/%d] %s: %s\n", i+1, len(calls), rc.Tool, verdict)
+	}
+	fmt.Fprintf(w, "%d of %d calls matched\n", len(calls)-failed, len(calls))
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// resultValue returns the structured content of res decoded like the
+// recorded one, or its text, decoded if it is JSON.
+func resultValue(res *mcp.CallToolResult) any {
+	var raw []byte
+	if res.StructuredContent != nil {
+		raw, _ = json.Marshal(res.StructuredContent)
+	} else {
+		for _, content := range res.Content {
+			if tc, ok := mcp.AsTextContent(content); ok {
+				raw = []byte(tc.Text)
+				break
+			}
+		}
+		if !json.Valid(raw) {
+			return string(raw)
+		}
+	}
+	var v any
+	_ = json.Unmarshal(raw, &v)
+	return v
+}
+
+func errorCode(v any) string {
+	if m, ok := v.(map[string]any); ok {
+		if code, ok := m["code"].(string); ok {
+			return code
+		}
+	}
+	return ""
+}
+
+func errorSummary(v any) string {
+	code := errorCode(v)
+	if m, ok := v.(map[string]any); ok {
+		if msg, ok := m["message"].(string); ok {
+			return fmt.Sprintf("%s (%s)", code, msg)
+		}
+	}
+	if code == "" {
+		return fmt.Sprint(v)
+	}
+	return code
+}
+
+// strip returns v without the object keys in ignore, at any depth.
+func strip(v any, ignore []string) any {
+	switch v := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, e := range v {
+			if !slices.Contains(ignore, k) {
+				out[k] = strip(e, ignore)
+			}
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = strip(e, ignore)
+		}
+		return out
+	}
+	return v
+}
+
+// firstDiff returns where want and got first differ, as a path such as
+// rows[2].name, and how; diff is "" if they are equal.
+func firstDiff(path string, want, got any) (at, diff string) {
+	switch w := want.(type) {
+	case map[string]any:
+		g, ok := got.(map[string]any)
+		if !ok {
+			break
+		}
+		keys := slices.Sorted(maps.Keys(w))
+		for k := range g {
+			if _, ok := w[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		for _, k := range keys {
+			p := k
+			if path != "" {
+				p = path + "." + k
+			}
+			wv, inWant := w[k]
+			gv, inGot := g[k]
+			switch {
+			case !inWant:
+				return p, "not in the recording"
+			case !inGot:
+				return p, "missing"
+			}
+			if at, diff := firstDiff(p, wv, gv); diff != "" {
+				return at, diff
+			}
+		}
+		return "", ""
+	case []any:
+		g, ok := got.([]any)
+		if !ok {
+			break
+		}
+		for i := range min(len(w), len(g)) {
+			if at, diff := firstDiff(fmt.Sprintf("%s[%d]", path, i), w[i], g[i]); diff != "" {
+				return at, diff
+			}
+		}
+		if len(w) != len(g) {
+			return path, fmt.Sprintf("%d elements, recorded %d", len(g), len(w))
+		}
+		return "", ""
+	}
+	if reflect.DeepEqual(want, got) {
+		return "", ""
+	}
+	return path, fmt.Sprintf("got %s, recorded %s", compactJSON(got), compactJSON(want))
+}
+
+// compactJSON returns v as JSON, cut to a readable length.
+func compactJSON(v any) string {
+	b, _ := json.Marshal(v)
+	if len(b) > 80 {
+		return string(b[:77]) + "..."
+	}
+	return string(b)
+}
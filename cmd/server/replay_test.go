@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+)
+
+func TestFirstDiff(t *testing.T) {
+	for _, tc := range []struct {
+		want, got any
+		at, diff  string
+	}{
+		{map[string]any{"a": 1.0}, map[string]any{"a": 1.0}, "", ""},
+		{map[string]any{"rows": []any{map[string]any{"x": 1.0}}}, map[string]any{"rows": []any{map[string]any{"x": 2.0}}}, "rows[0].x", "got 2, recorded 1"},
+		{map[string]any{"rows": []any{1.0}}, map[string]any{"rows": []any{1.0, 2.0}}, "rows", "2 elements, recorded 1"},
+		{map[string]any{"a": 1.0}, map[string]any{"a": 1.0, "b": 2.0}, "b", "not in the recording"},
+		{map[string]any{"a": 1.0, "b": 2.0}, map[string]any{"a": 1.0}, "b", "missing"},
+	} {
+		at, diff := firstDiff("", tc.want, tc.got)
+		if at != tc.at || diff != tc.diff {
+			t.Errorf("firstDiff(%v, %v) = %q, %q; want %q, %q", tc.want, tc.got, at, diff, tc.at, tc.diff)
+		}
+	}
+	stripped := strip(map[string]any{"request_id": "x", "rows": []any{map[string]any{"request_id": "y", "n": 1.0}}}, []string{"request_id"})
+	if _, diff := firstDiff("", map[string]any{"rows": []any{map[string]any{"n": 1.0}}}, stripped); diff != "" {
+		t.Errorf("strip left %v", stripped)
+	}
+}
+
+func TestParseReplayFlags(t *testing.T) {
+	getenv := func(string) string { return "" }
+	o, err := parseReplayFlags([]string{"--demo", "session.jsonl"}, getenv, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.path != "session.jsonl" || o.connection != config.DemoConnectionID || !o.compare || !strings.Contains(strings.Join(o.ignore, ","), "request_id") {
+		t.Errorf("options = %+v", o)
+	}
+	for _, args := range [][]string{
+		nil,
+		{"a.jsonl", "b.jsonl"},
+		{"--demo", "--url", "http://localhost:8089/mcp", "a.jsonl"},
+		{"--demo", "--connection", "pg", "a.jsonl"},
+	} {
+		if _, err := parseReplayFlags(args, getenv, io.Discard); err == nil {
+			t.Errorf("%v: no error", args)
+		}
+	}
+}
+
+func TestReplay(t *testing.T) {
+	for _, env := range []string{config.EnvPostgresURI, config.EnvSQLServerURI, config.EnvSQLiteURI, config.EnvMySQLURI, config.EnvRecordFile} {
+		t.Setenv(env, "")
+	}
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	recording := `# recorded against postgres
+{"tool":"run_query","arguments":{"connection_id":"postgres","sql":"SELECT 1 AS x"},"result":{"rows":[{"x":1}]},"request_id":"abc"}
+{"tool":"run_query","arguments":{"connection_id":"postgres","sql":"SELECT 2 AS x"},"result":{"rows":[{"x":3}]}}
+{"tool":"describe_table","arguments":{"connection_id":"postgres"},"error":{"code":"validation_failed","message":"table is required"}}
+{"tool":"ping","result":{"message":"pong"}}
+`
+	if err := os.WriteFile(path, []byte(recording), 0600); err != nil {
+		t.Fatal(err)
+	}
+	calls, err := loadRecording(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := parseReplayFlags([]string{"--demo", path}, func(string) string { return "" }, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// main installs a handler before localSession wraps it; wrapping the
+	// default one would deadlock when a failed call is logged.
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx := context.Background()
+	c, closeSession, err := localSession(ctx, &options{demoOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeSession()
+	var out strings.Builder
+	if code := replayCalls(ctx, c, calls, o, &out); code != 1 {
+		t.Errorf("replay: exit %d, want 1", code)
+	}
+	for _, want := range []string{
+		"[1/4] run_query: ok",
+		"[2/4] run_query: FAILED: rows[0].x differs: got 2, recorded 3",
+		"[3/4] describe_table: ok",
+		"3 of 4 calls matched",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("replay output lacks %q:\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package codegen
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
+)
+
+// mermaidInvalid matches what Mermaid's entity, attribute and type names
+// cannot hold.
+var mermaidInvalid = regexp.MustCompile(`[^A-Za-z0-9_\-()\[\]]+`)
+
+// ERD returns a Mermaid erDiagram of tables and the foreign keys fks
+// between them: an entity per table, named after it, with its columns and
+// their types unless columns is false, and a relationship per foreign key,
+// labelled with its columns. Keys are marked PK, FK and UK (a
+// single-column unique index in idxs). A relationship is identifying (a
+// solid line) when the foreign key is part of the primary key, and
+// one-to-one when its columns are unique.
+func ERD(tables []Table, fks []db.ForeignKey, idxs []db.Index, columns bool) string {
+	included := make(map[string]Table, len(tables))
+	for _, t := range tables {
+		included[t.Name] = t
+	}
+	var b strings.Builder
+	b.WriteString("erDiagram\n")
+	for _, t := range tables {
+		if !columns {
+			fmt.Fprintf(&b, "    %s\n", mermaidName(t.Name))
+			continue
+		}
+		fkCols := map[string]bool{}
+		for _, fk := range fks {
+			if fk.Table == t.Name {
+				for _, c := range fk.Columns {
+					fkCols[c] = true
+				}
+			}
+		}
+		fmt.Fprintf(&b, "    %s {\n", mermaidName(t.Name))
+		for _, c := range t.Columns {
+			var keys []string
+			if c.IsPK {
+				keys = append(keys, "PK")
+			}
+			if fkCols[c.Name] {
+				keys = append(keys, "FK")
+			}
+			if !c.IsPK && isUniqueKey(t, idxs, []string{c.Name}) {
+				keys = append(keys, "UK")
+			}
+			typ := c.Type
+			if typ == "" {
+				typ = "unknown"
+			}
+			line := fmt.Sprintf("        %s %s", mermaidName(typ), mermaidName(c.Name))
+			if len(keys) > 0 {
+				line += " " + strings.Join(keys, ", ")
+			}
+			if c.Nullable {
+				line += ` "nullable"`
+			}
+			fmt.Fprintf(&b, "%s\n", line)
+		}
+		b.WriteString("    }\n")
+	}
+	for _, fk := range fks {
+		child, ok := included[fk.Table]
+		if _, parent := included[fk.RefTable]; !ok || !parent {
+			continue
+		}
+		// The parent's side: exactly one, or zero or one if the foreign
+		// key may be NULL.
+		left := "||"
+		for _, col := range fk.Columns {
+			for _, c := range child.Columns {
+				if c.Name == col && c.Nullable {
+					left = "|o"
+				}
+			}
+		}
+		right := "o{"
+		if isUniqueKey(child, idxs, fk.Columns) {
+			right = "o|"
+		}
+		line := ".."
+		pk := primaryKey(child)
+		if len(pk) > 0 && !slices.ContainsFunc(fk.Columns, func(c string) bool { return !slices.Contains(pk, c) }) {
+			line = "--"
+		}
+		fmt.Fprintf(&b, "    %s %s%s%s %s : %q\n", mermaidName(fk.RefTable), left, line, right,
+			mermaidName(fk.Table), strings.Join(fk.Columns, ", "))
+	}
+	return b.String()
+}
+
+// Related returns table and the tables within depth foreign keys of it,
+// in either direction, sorted by name.
+func Related(fks []db.ForeignKey, table string, depth int) []string {
+	seen := map[string]bool{table: true}
+	frontier := []string{table}
+	for range depth {
+		var next []string
+		for _, fk := range fks {
+			for _, pair := range [][2]string{{fk.Table, fk.RefTable}, {fk.RefTable, fk.Table}} {
+				if slices.Contains(frontier, pair[0]) && !seen[pair[1]] {
+					seen[pair[1]] = true
+					next = append(next, pair[1])
+				}
+			}
+		}
+		frontier = next
+	}
+	out := make([]string, 0, len(seen))
+	for t := range seen {
+		out = append(out, t)
+	}
+	slices.Sort(out)
+	return out
+}
+
+// mermaidName returns name with the runs of characters Mermaid does not
+// allow in names replaced by underscores, and an underscore before a
+// leading digit.
+func mermaidName(name string) string {
+	s := mermaidInvalid.ReplaceAllString(name, "_")
+	if s == "" || s[0] >= '0' && s[0] <= '9' {
+		s = "_" + s
+	}
+	return s
+}
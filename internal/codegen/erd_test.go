@@ -0,0 +1,59 @@
+package codegen
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
+)
+
+func TestERD(t *testing.T) {
+	idxs := []db.Index{{Table: "customers", Columns: []string{"email"}, Unique: true}}
+	got := ERD(graphQLTables, graphQLKeys, idxs, true)
+	want := `erDiagram
+    customers {
+        integer id PK
+        text email UK
+        integer referred_by FK "nullable"
+    }
+    order_items {
+        bigint order_id PK, FK
+        smallint line PK
+        numeric price
+    }
+    orders {
+        bigint id PK
+        integer customer_id FK
+        timestamp_with_time_zone placed_at
+        text _2fa_code "nullable"
+    }
+    customers |o..o{ customers : "referred_by"
+    orders ||--o{ order_items : "order_id"
+    customers ||..o{ orders : "customer_id"
+`
+	if got != want {
+		t.Errorf("ERD =\n%s\nwant\n%s", got, want)
+	}
+
+	got = ERD(graphQLTables[1:], graphQLKeys, nil, false)
+	want = `erDiagram
+    order_items
+    orders
+    orders ||--o{ order_items : "order_id"
+`
+	if got != want {
+		t.Errorf("ERD without columns =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRelated(t *testing.T) {
+	for depth, want := range [][]string{
+		{"order_items"},
+		{"order_items", "orders"},
+		{"customers", "order_items", "orders"},
+	} {
+		if got := Related(graphQLKeys, "order_items", depth); !slices.Equal(got, want) {
+			t.Errorf("Related(order_items, %d) = %v, want %v", depth, got, want)
+		}
+	}
+}
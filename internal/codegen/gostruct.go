@@ -0,0 +1,264 @@
+package codegen
+
+import (
+	"fmt"
+	"go/format"
+	"go/token"
+	"slices"
+	"strings"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
+)
+
+// Ways GoStruct declares nullable columns.
+const (
+	NullableSQL     = "sql"     // database/sql's Null types: sql.NullString
+	NullablePointer = "pointer" // pointers: *string
+)
+
+// Go data layers GoStruct targets.
+const (
+	StylePlain = "plain" // a struct and its tags
+	StyleGORM  = "gorm"  // a GORM model, with a TableName method and associations
+	StyleSQLC  = "sqlc"  // sqlc input files, generated by SQLC rather than GoStruct
+)
+
+// GoOptions adjust the code GoStruct generates.
+type GoOptions struct {
+	// Package is the package clause; "models" if empty.
+	Package string
+	// StructName names the struct; TypeName of the table if empty.
+	StructName string
+	// Tags are the struct tag keys to give every field, in order; json
+	// and db if nil. A tag's value is the column name, except for gorm,
+	// which also marks primary keys and NOT NULL columns.
+	Tags []string
+	// Nullable is NullableSQL (the default) or NullablePointer.
+	Nullable string
+	// Style is StylePlain (the default) or StyleGORM, which adds the gorm
+	// tag if Tags lack it.
+	Style string
+	// ForeignKeys are the foreign keys of the table's schema, for
+	// StyleGORM: those of the table become belongs-to associations, and
+	// those referencing it has-many associations, named after the other
+	// table.
+	ForeignKeys []db.ForeignKey
+}
+
+// GoStruct returns a Go source file declaring a struct for a row of table,
+// whose columns cols are of connection type engine: a field per column, in
+// column order, named after it and typed from MapType. Nullable columns
+// get a sql.Null type or a pointer, as opts.Nullable says; []byte,
+// json.RawMessage and any hold NULL as nil. Decimals and UUIDs are strings,
+// so no precision or driver-specific type is involved. A StyleGORM model
+// also has a pointer field per belongs-to association, a slice per
+// has-many one, and a TableName method.
+func GoStruct(engine, table string, cols []db.ColumnInfo, opts GoOptions) (string, error) {
+	if opts.Package == "" {
+		opts.Package = "models"
+	}
+	if !token.IsIdentifier(opts.Package) {
+		return "", fmt.Errorf("package %q is not a Go identifier", opts.Package)
+	}
+	if opts.StructName == "" {
+		opts.StructName = TypeName(table)
+	}
+	if !token.IsIdentifier(opts.StructName) {
+		return "", fmt.Errorf("struct name %q is not a Go identifier", opts.StructName)
+	}
+	if opts.Tags == nil {
+		opts.Tags = []string{"json", "db"}
+	}
+	for _, tag := range opts.Tags {
+		if !token.IsIdentifier(tag) {
+			return "", fmt.Errorf("tag %q is not a valid struct tag key", tag)
+		}
+	}
+	switch opts.Style {
+	case "":
+		opts.Style = StylePlain
+	case StylePlain:
+	case StyleGORM:
+		if !slices.Contains(opts.Tags, "gorm") {
+			opts.Tags = append(slices.Clip(opts.Tags), "gorm")
+		}
+	case StyleSQLC:
+		return "", fmt.Errorf("style %q generates sqlc input files; use SQLC", StyleSQLC)
+	default:
+		return "", fmt.Errorf("style must be %q, %q or %q, not %q", StylePlain, StyleGORM, StyleSQLC, opts.Style)
+	}
+	switch opts.Nullable {
+	case "":
+		opts.Nullable = NullableSQL
+	case NullableSQL, NullablePointer:
+	default:
+		return "", fmt.Errorf("nullable must be %q or %q, not %q", NullableSQL, NullablePointer, opts.Nullable)
+	}
+
+	var body strings.Builder
+	imports := map[string]bool{}
+	used := map[string]int{}
+	for _, c := range cols {
+		typ, pkgs := goType(MapType(engine, c.Type), c.Nullable, opts.Nullable)
+		for _, p := range pkgs {
+			imports[p] = true
+		}
+		name := GoName(c.Name)
+		// Columns that differ only in case or separators get a suffix.
+		if used[name]++; used[name] > 1 {
+			name = fmt.Sprintf("%s%d", name, used[name])
+		}
+		fmt.Fprintf(&body, "\t%s %s", name, typ)
+		if tags := goTags(opts.Tags, c); tags != "" {
+			fmt.Fprintf(&body, " `%s`", tags)
+		}
+		if c.Type != "" {
+			fmt.Fprintf(&body, " // %s", c.Type)
+		}
+		body.WriteByte('\n')
+	}
+	if opts.Style == StyleGORM {
+		fields := map[string]string{}
+		for _, c := range cols {
+			fields[c.Name] = GoName(c.Name)
+		}
+		for _, fk := range opts.ForeignKeys {
+			if fk.Table != table {
+				continue
+			}
+			name := GoName(relationName(fk.Columns, fk.RefTable))
+			if used[name]++; used[name] > 1 {
+				name = fmt.Sprintf("%s%d", name, used[name])
+			}
+			keys := make([]string, len(fk.Columns))
+			for i, c := range fk.Columns {
+				keys[i] = fields[c]
+			}
+			refs := make([]string, len(fk.RefColumns))
+			for i, c := range fk.RefColumns {
+				refs[i] = GoName(c)
+			}
+			gorm := fmt.Sprintf("foreignKey:%s;references:%s", strings.Join(keys, ","), strings.Join(refs, ","))
+			fmt.Fprintf(&body, "\t%s *%s `%s`\n", name, TypeName(fk.RefTable),
+				associationTags(opts.Tags, relationName(fk.Columns, fk.RefTable), gorm))
+		}
+		for _, fk := range opts.ForeignKeys {
+			if fk.RefTable != table {
+				continue
+			}
+			name := GoName(fk.Table)
+			if used[name]++; used[name] > 1 {
+				name = fmt.Sprintf("%s%d", name, used[name])
+			}
+			keys := make([]string, len(fk.Columns))
+			for i, c := range fk.Columns {
+				keys[i] = GoName(c)
+			}
+			refs := make([]string, len(fk.RefColumns))
+			for i, c := range fk.RefColumns {
+				refs[i] = fields[c]
+			}
+			gorm := fmt.Sprintf("foreignKey:%s;references:%s", strings.Join(keys, ","), strings.Join(refs, ","))
+			fmt.Fprintf(&body, "\t%s []%s `%s`\n", name, TypeName(fk.Table), associationTags(opts.Tags, fk.Table, gorm))
+		}
+	}
+
+	var src strings.Builder
+	fmt.Fprintf(&src, "package %s\n\n", opts.Package)
+	if len(imports) > 0 {
+		pkgs := make([]string, 0, len(imports))
+		for p := range imports {
+			pkgs = append(pkgs, p)
+		}
+		slices.Sort(pkgs)
+		src.WriteString("import (\n")
+		for _, p := range pkgs {
+			fmt.Fprintf(&src, "\t%q\n", p)
+		}
+		src.WriteString(")\n\n")
+	}
+	fmt.Fprintf(&src, "// %s is a row of %s.\ntype %s struct {\n%s}\n", opts.StructName, table, opts.StructName, body.String())
+	if opts.Style == StyleGORM {
+		fmt.Fprintf(&src, "\n// TableName names the table of %s for GORM.\nfunc (%s) TableName() string {\n\treturn %q\n}\n",
+			opts.StructName, opts.StructName, table)
+	}
+	out, err := format.Source([]byte(src.String()))
+	if err != nil {
+		return "", fmt.Errorf("format generated code: %w", err)
+	}
+	return string(out), nil
+}
+
+// goType returns the Go type of a column of type t and the packages it
+// needs. nullable says how a nullable column is declared.
+func goType(t Type, isNullable bool, nullable string) (typ string, pkgs []string) {
+	var base, null string
+	switch t.Kind {
+	case KindString, KindDecimal, KindUUID, KindTime:
+		base, null = "string", "sql.NullString"
+	case KindInteger:
+		switch {
+		case t.Bits <= 16:
+			base, null = "int16", "sql.NullInt16"
+		case t.Bits <= 32:
+			base, null = "int32", "sql.NullInt32"
+		default:
+			base, null = "int64", "sql.NullInt64"
+		}
+	case KindFloat:
+		base, null = "float64", "sql.NullFloat64"
+	case KindBool:
+		base, null = "bool", "sql.NullBool"
+	case KindTimestamp, KindDate:
+		base, null = "time.Time", "sql.NullTime"
+		pkgs = []string{"time"}
+	case KindJSON:
+		return "json.RawMessage", []string{"encoding/json"}
+	case KindBytes:
+		return "[]byte", nil
+	default:
+		return "any", nil
+	}
+	if !isNullable {
+		return base, pkgs
+	}
+	if nullable == NullablePointer {
+		return "*" + base, pkgs
+	}
+	return null, []string{"database/sql"}
+}
+
+// associationTags returns the struct tags of an association field: json
+// names it, omitted when empty; gorm holds its keys; other tags are left
+// out.
+func associationTags(keys []string, name, gorm string) string {
+	var tags []string
+	for _, k := range keys {
+		switch k {
+		case "json":
+			tags = append(tags, fmt.Sprintf("json:%q", name+",omitempty"))
+		case "gorm":
+			tags = append(tags, fmt.Sprintf("gorm:%q", gorm))
+		}
+	}
+	return strings.Join(tags, " ")
+}
+
+// goTags returns the struct tags of the field for column c.
+func goTags(keys []string, c db.ColumnInfo) string {
+	tags := make([]string, len(keys))
+	for i, k := range keys {
+		v := c.Name
+		if k == "gorm" {
+			v = "column:" + c.Name
+			if c.IsPK {
+				v += ";primaryKey"
+			}
+			if !c.Nullable && !c.IsPK {
+				v += ";not null"
+			}
+		}
+		tags[i] = fmt.Sprintf("%s:%q", k, v)
+	}
+	return strings.Join(tags, " ")
+}
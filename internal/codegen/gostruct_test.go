@@ -0,0 +1,115 @@
+package codegen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
+)
+
+var goStructColumns = []db.ColumnInfo{
+	{Name: "id", Type: "bigint", IsPK: true},
+	{Name: "customer_id", Type: "integer"},
+	{Name: "total", Type: "numeric"},
+	{Name: "note", Type: "text", Nullable: true},
+	{Name: "placed_at", Type: "timestamp with time zone"},
+	{Name: "shipped_at", Type: "timestamp with time zone", Nullable: true},
+	{Name: "meta", Type: "jsonb", Nullable: true},
+}
+
+func TestGoStruct(t *testing.T) {
+	got, err := GoStruct("postgres", "orders", goStructColumns, GoOptions{})
+	if err != nil {
+		t.Fatalf("GoStruct: %v", err)
+	}
+	want := `package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// Order is a row of orders.
+type Order struct {
+	ID         int64           ` + "`json:\"id\" db:\"id\"`" + `                   // bigint
+	CustomerID int32           ` + "`json:\"customer_id\" db:\"customer_id\"`" + ` // integer
+	Total      string          ` + "`json:\"total\" db:\"total\"`" + `             // numeric
+	Note       sql.NullString  ` + "`json:\"note\" db:\"note\"`" + `               // text
+	PlacedAt   time.Time       ` + "`json:\"placed_at\" db:\"placed_at\"`" + `     // timestamp with time zone
+	ShippedAt  sql.NullTime    ` + "`json:\"shipped_at\" db:\"shipped_at\"`" + `   // timestamp with time zone
+	Meta       json.RawMessage ` + "`json:\"meta\" db:\"meta\"`" + `               // jsonb
+}
+`
+	if got != want {
+		t.Errorf("GoStruct =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestGoStruct_options(t *testing.T) {
+	got, err := GoStruct("postgres", "orders", goStructColumns, GoOptions{
+		Package: "store", StructName: "Purchase", Tags: []string{"gorm"}, Nullable: NullablePointer,
+	})
+	if err != nil {
+		t.Fatalf("GoStruct: %v", err)
+	}
+	for _, want := range []string{
+		"package store\n",
+		"type Purchase struct",
+		`ID         int64           ` + "`gorm:\"column:id;primaryKey\"`",
+		`CustomerID int32           ` + "`gorm:\"column:customer_id;not null\"`",
+		`Note       *string         ` + "`gorm:\"column:note\"`",
+		`ShippedAt  *time.Time`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("GoStruct lacks %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "database/sql") {
+		t.Errorf("GoStruct with pointers imports database/sql:\n%s", got)
+	}
+
+	for _, opts := range []GoOptions{
+		{Package: "my-models"},
+		{StructName: "1Order"},
+		{Tags: []string{"bad tag"}},
+		{Nullable: "maybe"},
+	} {
+		if _, err := GoStruct("postgres", "orders", goStructColumns, opts); err == nil {
+			t.Errorf("GoStruct with %+v succeeded", opts)
+		}
+	}
+}
+
+func TestGoStruct_gorm(t *testing.T) {
+	fks := []db.ForeignKey{
+		{Table: "orders", Columns: []string{"customer_id"}, RefTable: "customers", RefColumns: []string{"id"}},
+		{Table: "order_items", Columns: []string{"order_id"}, RefTable: "orders", RefColumns: []string{"id"}},
+		{Table: "order_items", Columns: []string{"sku"}, RefTable: "products", RefColumns: []string{"sku"}},
+	}
+	got, err := GoStruct("postgres", "orders", goStructColumns[:2], GoOptions{Style: StyleGORM, Tags: []string{"json"}, ForeignKeys: fks})
+	if err != nil {
+		t.Fatalf("GoStruct: %v", err)
+	}
+	want := `package models
+
+// Order is a row of orders.
+type Order struct {
+	ID         int64       ` + "`json:\"id\" gorm:\"column:id;primaryKey\"`" + `                 // bigint
+	CustomerID int32       ` + "`json:\"customer_id\" gorm:\"column:customer_id;not null\"`" + ` // integer
+	Customer   *Customer   ` + "`json:\"customer,omitempty\" gorm:\"foreignKey:CustomerID;references:ID\"`" + `
+	OrderItems []OrderItem ` + "`json:\"order_items,omitempty\" gorm:\"foreignKey:OrderID;references:ID\"`" + `
+}
+
+// TableName names the table of Order for GORM.
+func (Order) TableName() string {
+	return "orders"
+}
+`
+	if got != want {
+		t.Errorf("GoStruct =\n%s\nwant\n%s", got, want)
+	}
+	if _, err := GoStruct("postgres", "orders", goStructColumns, GoOptions{Style: "ent"}); err == nil {
+		t.Error("GoStruct with style ent succeeded")
+	}
+}
@@ -0,0 +1,201 @@
+package codegen
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
+)
+
+// How GraphQL names fields.
+const (
+	CaseCamel    = "camel"    // customerId, orderItems
+	CaseSnake    = "snake"    // customer_id, order_items
+	CasePreserve = "preserve" // the column and table names as they are
+)
+
+// graphQLScalars are the custom scalars GraphQL generates, with their
+// descriptions, in the order they are declared.
+var graphQLScalars = []struct{ name, doc string }{
+	{"BigInt", "A 64-bit integer."},
+	{"Decimal", "An exact decimal number, as a string."},
+	{"DateTime", "A date and time, in RFC 3339."},
+	{"Date", "A calendar date: 2006-01-02."},
+	{"Time", "A time of day: 15:04:05."},
+	{"JSON", "A JSON document."},
+	{"Bytes", "Binary data, base64-encoded."},
+}
+
+var graphQLInvalid = regexp.MustCompile(`[^_0-9A-Za-z]`)
+
+// graphQLType is an object type being generated.
+type graphQLType struct {
+	name   string
+	table  Table
+	fields [][2]string // name, type
+	// columns maps a column to its field name.
+	columns map[string]string
+	used    map[string]bool
+}
+
+func (t *graphQLType) field(name, typ string) {
+	t.fields = append(t.fields, [2]string{uniqueName(t.used, name), typ})
+}
+
+// GraphQL returns a GraphQL schema (SDL) for tables, whose columns are of
+// connection type engine: an object type per table, named after it in
+// singular, with a field per column, a field per foreign key in fks
+// holding the referenced row, and one on the other side listing the rows
+// that reference it. fieldCase is CaseCamel (the default), CaseSnake or
+// CasePreserve. A Query type lists each table's rows, and looks one up by
+// primary key. Columns of a single-column primary key are IDs; types
+// GraphQL lacks are custom scalars, declared when used.
+func GraphQL(engine string, tables []Table, fks []db.ForeignKey, idxs []db.Index, fieldCase string) (string, error) {
+	var name func(string) string
+	switch fieldCase {
+	case "", CaseCamel:
+		name = camelName
+	case CaseSnake:
+		name = func(s string) string { return graphQLName(strings.Join(words(s), "_")) }
+	case CasePreserve:
+		name = graphQLName
+	default:
+		return "", fmt.Errorf("field case must be %q, %q or %q, not %q", CaseCamel, CaseSnake, CasePreserve, fieldCase)
+	}
+
+	types := make([]*graphQLType, len(tables))
+	byTable := make(map[string]*graphQLType, len(tables))
+	typeNames := map[string]bool{"Query": true}
+	scalars := map[string]bool{}
+	for i, tbl := range tables {
+		t := &graphQLType{
+			name:    uniqueName(typeNames, TypeName(tbl.Name)),
+			table:   tbl,
+			columns: map[string]string{},
+			used:    map[string]bool{},
+		}
+		types[i], byTable[tbl.Name] = t, t
+		pk := primaryKey(tbl)
+		for _, c := range tbl.Columns {
+			typ := graphQLScalar(engine, c.Type)
+			if len(pk) == 1 && c.IsPK {
+				typ = "ID"
+			}
+			if !isBuiltinGraphQLScalar(typ) {
+				scalars[typ] = true
+			}
+			if !c.Nullable {
+				typ += "!"
+			}
+			t.field(name(c.Name), typ)
+			t.columns[c.Name] = t.fields[len(t.fields)-1][0]
+		}
+	}
+
+	for _, fk := range fks {
+		from, to := byTable[fk.Table], byTable[fk.RefTable]
+		if from == nil || to == nil {
+			continue
+		}
+		typ := to.name + "!"
+		for _, col := range fk.Columns {
+			for _, c := range from.table.Columns {
+				if c.Name == col && c.Nullable {
+					typ = to.name
+				}
+			}
+		}
+		from.field(name(relationName(fk.Columns, fk.RefTable)), typ)
+		if isUniqueKey(from.table, idxs, fk.Columns) {
+			to.field(name(Singular(fk.Table)), from.name)
+		} else {
+			to.field(name(fk.Table), "["+from.name+"!]!")
+		}
+	}
+
+	var b strings.Builder
+	for _, s := range graphQLScalars {
+		if scalars[s.name] {
+			fmt.Fprintf(&b, "%q\nscalar %s\n\n", s.doc, s.name)
+		}
+	}
+	for _, t := range types {
+		fmt.Fprintf(&b, "\"A row of %s.\"\ntype %s {\n", t.table.Name, t.name)
+		for _, f := range t.fields {
+			fmt.Fprintf(&b, "  %s: %s\n", f[0], f[1])
+		}
+		b.WriteString("}\n\n")
+	}
+
+	b.WriteString("type Query {\n")
+	queries := map[string]bool{}
+	for _, t := range types {
+		list, one := name(t.table.Name), name(Singular(t.table.Name))
+		if list == one {
+			list = name("all_" + t.table.Name)
+		}
+		fmt.Fprintf(&b, "  %s: [%s!]!\n", uniqueName(queries, list), t.name)
+		pk := primaryKey(t.table)
+		if len(pk) == 0 {
+			continue
+		}
+		args := make([]string, len(pk))
+		for i, col := range pk {
+			for _, f := range t.fields {
+				if f[0] == t.columns[col] {
+					args[i] = f[0] + ": " + f[1]
+				}
+			}
+		}
+		fmt.Fprintf(&b, "  %s(%s): %s\n", uniqueName(queries, one), strings.Join(args, ", "), t.name)
+	}
+	b.WriteString("}\n")
+	return b.String(), nil
+}
+
+// graphQLScalar returns the GraphQL scalar of a column of type typ.
+func graphQLScalar(engine, typ string) string {
+	t := MapType(engine, typ)
+	switch t.Kind {
+	case KindInteger:
+		// GraphQL's Int has 32 bits. SQLite's integers have 64, but hold
+		// small numbers in practice.
+		if t.Bits > 32 && engine != "sqlite" && engine != "demo" {
+			return "BigInt"
+		}
+		return "Int"
+	case KindFloat:
+		return "Float"
+	case KindDecimal:
+		return "Decimal"
+	case KindBool:
+		return "Boolean"
+	case KindTimestamp:
+		return "DateTime"
+	case KindDate:
+		return "Date"
+	case KindTime:
+		return "Time"
+	case KindJSON:
+		return "JSON"
+	case KindBytes:
+		return "Bytes"
+	}
+	return "String"
+}
+
+func isBuiltinGraphQLScalar(typ string) bool {
+	return slices.Contains([]string{"Int", "Float", "String", "Boolean", "ID"}, typ)
+}
+
+// graphQLName returns name with the characters GraphQL names cannot hold
+// replaced by underscores.
+func graphQLName(name string) string {
+	s := graphQLInvalid.ReplaceAllString(name, "_")
+	if s == "" || s[0] >= '0' && s[0] <= '9' {
+		s = "_" + s
+	}
+	return s
+}
@@ -0,0 +1,109 @@
+package codegen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
+)
+
+var graphQLTables = []Table{
+	{Name: "customers", Columns: []db.ColumnInfo{
+		{Name: "id", Type: "integer", IsPK: true},
+		{Name: "email", Type: "text"},
+		{Name: "referred_by", Type: "integer", Nullable: true},
+	}},
+	{Name: "order_items", Columns: []db.ColumnInfo{
+		{Name: "order_id", Type: "bigint", IsPK: true},
+		{Name: "line", Type: "smallint", IsPK: true},
+		{Name: "price", Type: "numeric"},
+	}},
+	{Name: "orders", Columns: []db.ColumnInfo{
+		{Name: "id", Type: "bigint", IsPK: true},
+		{Name: "customer_id", Type: "integer"},
+		{Name: "placed_at", Type: "timestamp with time zone"},
+		{Name: "2fa code", Type: "text", Nullable: true},
+	}},
+}
+
+var graphQLKeys = []db.ForeignKey{
+	{Table: "customers", Columns: []string{"referred_by"}, RefTable: "customers", RefColumns: []string{"id"}},
+	{Table: "order_items", Columns: []string{"order_id"}, RefTable: "orders", RefColumns: []string{"id"}},
+	{Table: "orders", Columns: []string{"customer_id"}, RefTable: "customers", RefColumns: []string{"id"}},
+}
+
+func TestGraphQL(t *testing.T) {
+	got, err := GraphQL("postgres", graphQLTables, graphQLKeys, nil, "")
+	if err != nil {
+		t.Fatalf("GraphQL: %v", err)
+	}
+	want := `"A 64-bit integer."
+scalar BigInt
+
+"An exact decimal number, as a string."
+scalar Decimal
+
+"A date and time, in RFC 3339."
+scalar DateTime
+
+"A row of customers."
+type Customer {
+  id: ID!
+  email: String!
+  referredBy: Int
+  customer: Customer
+  customers: [Customer!]!
+  orders: [Order!]!
+}
+
+"A row of order_items."
+type OrderItem {
+  orderId: BigInt!
+  line: Int!
+  price: Decimal!
+  order: Order!
+}
+
+"A row of orders."
+type Order {
+  id: ID!
+  customerId: Int!
+  placedAt: DateTime!
+  x2faCode: String
+  orderItems: [OrderItem!]!
+  customer: Customer!
+}
+
+type Query {
+  customers: [Customer!]!
+  customer(id: ID!): Customer
+  orderItems: [OrderItem!]!
+  orderItem(orderId: BigInt!, line: Int!): OrderItem
+  orders: [Order!]!
+  order(id: ID!): Order
+}
+`
+	if got != want {
+		t.Errorf("GraphQL =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestGraphQL_fieldCase(t *testing.T) {
+	for fieldCase, want := range map[string][]string{
+		CaseSnake:    {"  customer_id: Int!", "  order_items: [OrderItem!]!", "  _2fa_code: String", "  order_item(order_id: BigInt!, line: Int!): OrderItem"},
+		CasePreserve: {"  customer_id: Int!", "  order_items: [OrderItem!]!", "  _2fa_code: String"},
+	} {
+		got, err := GraphQL("postgres", graphQLTables, graphQLKeys, nil, fieldCase)
+		if err != nil {
+			t.Fatalf("GraphQL %s: %v", fieldCase, err)
+		}
+		for _, w := range want {
+			if !strings.Contains(got, w+"\n") {
+				t.Errorf("GraphQL %s lacks %q:\n%s", fieldCase, w, got)
+			}
+		}
+	}
+	if _, err := GraphQL("postgres", graphQLTables, nil, nil, "kebab"); err == nil {
+		t.Error("GraphQL with case kebab succeeded")
+	}
+}
@@ -0,0 +1,107 @@
+package codegen
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
+)
+
+// JSONSchemaDialect is the JSON Schema draft of the documents JSONSchema
+// returns.
+const JSONSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchema returns a JSON Schema document describing a row of table, whose
+// columns cols are of connection type engine, as run_query returns it: an
+// object with a property per column, in column order, required unless the
+// column is nullable. Nullable columns also accept null. Dates, times and
+// UUIDs are strings with the matching format, binary data base64 strings
+// and JSON columns any value.
+func JSONSchema(engine, table string, cols []db.ColumnInfo) json.RawMessage {
+	props := make([]property, len(cols))
+	required := []string{}
+	for i, c := range cols {
+		props[i] = property{name: c.Name, schema: columnSchema(MapType(engine, c.Type), c.Nullable)}
+		if !c.Nullable {
+			required = append(required, c.Name)
+		}
+	}
+	doc := struct {
+		Schema               string     `json:"$schema"`
+		Title                string     `json:"title"`
+		Type                 string     `json:"type"`
+		Properties           properties `json:"properties"`
+		Required             []string   `json:"required"`
+		AdditionalProperties bool       `json:"additionalProperties"`
+	}{JSONSchemaDialect, table, "object", props, required, false}
+	b, _ := json.Marshal(doc)
+	return b
+}
+
+// columnSchema returns the schema of a column value of type t.
+func columnSchema(t Type, nullable bool) map[string]any {
+	s := map[string]any{}
+	var typ string
+	switch t.Kind {
+	case KindString:
+		typ = "string"
+	case KindInteger:
+		typ = "integer"
+	case KindFloat, KindDecimal:
+		typ = "number"
+	case KindBool:
+		typ = "boolean"
+	case KindTimestamp:
+		typ, s["format"] = "string", "date-time"
+	case KindDate:
+		typ, s["format"] = "string", "date"
+	case KindTime:
+		typ, s["format"] = "string", "time"
+	case KindUUID:
+		typ, s["format"] = "string", "uuid"
+	case KindBytes:
+		typ, s["contentEncoding"] = "string", "base64"
+	default:
+		// JSON documents and unknown types: any value, null included.
+		return s
+	}
+	if nullable {
+		s["type"] = []string{typ, "null"}
+	} else {
+		s["type"] = typ
+	}
+	return s
+}
+
+// property is one entry of a schema's properties.
+type property struct {
+	name   string
+	schema map[string]any
+}
+
+// properties marshals as a JSON object that keeps its entries in order,
+// so a table's columns read in their own order.
+type properties []property
+
+func (p properties) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, prop := range p {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(prop.name)
+		if err != nil {
+			return nil, err
+		}
+		schema, err := json.Marshal(prop.schema)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(schema)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
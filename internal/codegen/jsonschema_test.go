@@ -0,0 +1,69 @@
+package codegen
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
+)
+
+func TestJSONSchema(t *testing.T) {
+	cols := []db.ColumnInfo{
+		{Name: "id", Type: "integer", IsPK: true},
+		{Name: "name", Type: "character varying"},
+		{Name: "born", Type: "date", Nullable: true},
+		{Name: "uid", Type: "uuid"},
+		{Name: "avatar", Type: "bytea", Nullable: true},
+		{Name: "prefs", Type: "jsonb", Nullable: true},
+	}
+	doc := JSONSchema("postgres", "people", cols)
+	want := `{"$schema":"https://json-schema.org/draft/2020-12/schema","title":"people","type":"object",` +
+		`"properties":{"id":{"type":"integer"},"name":{"type":"string"},"born":{"format":"date","type":["string","null"]},` +
+		`"uid":{"format":"uuid","type":"string"},"avatar":{"contentEncoding":"base64","type":["string","null"]},"prefs":{}},` +
+		`"required":["id","name","uid"],"additionalProperties":false}`
+	if string(doc) != want {
+		t.Errorf("JSONSchema =\n%s\nwant\n%s", doc, want)
+	}
+	if !json.Valid(doc) {
+		t.Error("JSONSchema is not valid JSON")
+	}
+
+	// A table whose columns are all nullable still lists required.
+	doc = JSONSchema("sqlite", "t", []db.ColumnInfo{{Name: "a", Type: "", Nullable: true}})
+	if !strings.Contains(string(doc), `"required":[]`) {
+		t.Errorf("JSONSchema = %s, want an empty required list", doc)
+	}
+}
+
+func TestMapType(t *testing.T) {
+	tests := []struct {
+		engine, typ string
+		want        Type
+	}{
+		{"postgres", "integer", Type{Kind: KindInteger, Bits: 32}},
+		{"postgres", "timestamp with time zone", Type{Kind: KindTimestamp}},
+		{"postgres", "ARRAY", Type{Kind: KindJSON}},
+		{"postgres", "USER-DEFINED", Type{Kind: KindString}},
+		{"mysql", "tinyint", Type{Kind: KindInteger, Bits: 8}},
+		{"mysql", "bit", Type{Kind: KindBytes}},
+		{"mysql", "timestamp", Type{Kind: KindTimestamp}},
+		{"mysql", "enum", Type{Kind: KindString}},
+		{"sqlserver", "bit", Type{Kind: KindBool}},
+		{"sqlserver", "timestamp", Type{Kind: KindBytes}},
+		{"sqlserver", "uniqueidentifier", Type{Kind: KindUUID}},
+		{"sqlserver", "datetimeoffset", Type{Kind: KindTimestamp}},
+		{"sqlite", "INTEGER", Type{Kind: KindInteger, Bits: 64}},
+		{"sqlite", "VARCHAR(255)", Type{Kind: KindString}},
+		{"sqlite", "UNSIGNED BIG INT", Type{Kind: KindInteger, Bits: 64}},
+		{"sqlite", "DOUBLE PRECISION", Type{Kind: KindFloat, Bits: 64}},
+		{"sqlite", "NUMERIC(10, 2)", Type{Kind: KindDecimal}},
+		{"sqlite", "", Type{Kind: KindUnknown}},
+		{"demo", "TEXT", Type{Kind: KindString}},
+	}
+	for _, tt := range tests {
+		if got := MapType(tt.engine, tt.typ); got != tt.want {
+			t.Errorf("MapType(%q, %q) = %+v, want %+v", tt.engine, tt.typ, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package codegen
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// initialisms are the words Go spells in capitals, as golint does.
+var initialisms = map[string]bool{
+	"acl": true, "api": true, "ascii": true, "cpu": true, "css": true, "dns": true, "eof": true,
+	"guid": true, "html": true, "http": true, "https": true, "id": true, "ip": true, "json": true,
+	"lhs": true, "qps": true, "ram": true, "rhs": true, "rpc": true, "sku": true, "sla": true,
+	"smtp": true, "sql": true, "ssh": true, "tcp": true, "tls": true, "ttl": true, "udp": true,
+	"ui": true, "uid": true, "uri": true, "url": true, "utf8": true, "uuid": true, "vm": true,
+	"xml": true, "xmpp": true, "xsrf": true, "xss": true,
+}
+
+// words splits a database name into lower-case words at underscores,
+// hyphens, spaces and lower-to-upper case changes: "order_items",
+// "OrderItems" and "order-items" all give order, items.
+func words(name string) []string {
+	var out []string
+	var cur []rune
+	flush := func() {
+		if len(cur) > 0 {
+			out = append(out, strings.ToLower(string(cur)))
+			cur = cur[:0]
+		}
+	}
+	runes := []rune(name)
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+		case unicode.IsUpper(r) && i > 0 && (unicode.IsLower(runes[i-1]) ||
+			i+1 < len(runes) && unicode.IsUpper(runes[i-1]) && unicode.IsLower(runes[i+1])):
+			// orderID → order, id; HTTPServer → http, server.
+			flush()
+			cur = append(cur, r)
+		default:
+			cur = append(cur, r)
+		}
+	}
+	flush()
+	return out
+}
+
+// GoName returns name as an exported Go identifier: customer_id gives
+// CustomerID, and a name starting with a digit gets an X prefix.
+func GoName(name string) string {
+	var b strings.Builder
+	for _, w := range words(name) {
+		if initialisms[w] {
+			b.WriteString(strings.ToUpper(w))
+			continue
+		}
+		r := []rune(w)
+		b.WriteString(string(unicode.ToUpper(r[0])) + string(r[1:]))
+	}
+	s := b.String()
+	if s == "" || unicode.IsDigit([]rune(s)[0]) {
+		s = "X" + s
+	}
+	return s
+}
+
+// Singular returns the singular of the English plural word ends with, for
+// naming a type after its table: customers gives customer, categories
+// category, addresses address. Words that do not look plural are returned
+// as they are.
+func Singular(word string) string {
+	lower := strings.ToLower(word)
+	switch {
+	case strings.HasSuffix(lower, "ies") && len(word) > 3:
+		return word[:len(word)-3] + matchCase(word[len(word)-3:], "y")
+	case strings.HasSuffix(lower, "sses"), strings.HasSuffix(lower, "shes"),
+		strings.HasSuffix(lower, "ches"), strings.HasSuffix(lower, "xes"):
+		return word[:len(word)-2]
+	case strings.HasSuffix(lower, "ss"), strings.HasSuffix(lower, "us"), strings.HasSuffix(lower, "is"):
+		return word
+	case strings.HasSuffix(lower, "s") && len(word) > 1:
+		return word[:len(word)-1]
+	}
+	return word
+}
+
+// matchCase returns s in upper case if like is.
+func matchCase(like, s string) string {
+	if strings.ToUpper(like) == like {
+		return strings.ToUpper(s)
+	}
+	return s
+}
+
+// TypeName returns the exported name of the type for a row of table:
+// order_items gives OrderItem.
+func TypeName(table string) string {
+	return GoName(Singular(table))
+}
+
+// camelName returns a column or table name in camelCase, as JavaScript
+// names fields: customer_id gives customerId.
+func camelName(name string) string {
+	var b strings.Builder
+	for i, w := range words(name) {
+		r := []rune(w)
+		if i > 0 {
+			r[0] = unicode.ToUpper(r[0])
+		}
+		b.WriteString(string(r))
+	}
+	s := b.String()
+	if s == "" || !unicode.IsLetter([]rune(s)[0]) {
+		s = "x" + s
+	}
+	return s
+}
+
+// uniqueName returns name, with a number appended if used already has it,
+// and records it in used.
+func uniqueName(used map[string]bool, name string) string {
+	out := name
+	for i := 2; used[out]; i++ {
+		out = fmt.Sprintf("%s%d", name, i)
+	}
+	used[out] = true
+	return out
+}
@@ -0,0 +1,43 @@
+package codegen
+
+import "testing"
+
+func TestGoName(t *testing.T) {
+	tests := map[string]string{
+		"customer_id": "CustomerID",
+		"order_items": "OrderItems",
+		"OrderItems":  "OrderItems",
+		"orderID":     "OrderID",
+		"HTTPServer":  "HTTPServer",
+		"api-key":     "APIKey",
+		"sku":         "SKU",
+		"2fa_enabled": "X2faEnabled",
+		"created at":  "CreatedAt",
+		"__weird__":   "Weird",
+		"":            "X",
+	}
+	for in, want := range tests {
+		if got := GoName(in); got != want {
+			t.Errorf("GoName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestTypeName(t *testing.T) {
+	tests := map[string]string{
+		"customers":   "Customer",
+		"order_items": "OrderItem",
+		"categories":  "Category",
+		"addresses":   "Address",
+		"boxes":       "Box",
+		"status":      "Status",
+		"analysis":    "Analysis",
+		"data":        "Data",
+		"PEOPLES":     "People",
+	}
+	for in, want := range tests {
+		if got := TypeName(in); got != want {
+			t.Errorf("TypeName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package codegen
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
+)
+
+// prismaProviders are the Prisma datasource providers of the connection
+// types.
+var prismaProviders = map[string]string{
+	"postgres":  "postgresql",
+	"mysql":     "mysql",
+	"sqlserver": "sqlserver",
+	"sqlite":    "sqlite",
+	"demo":      "sqlite",
+}
+
+// prismaActions are the Prisma spellings of referential actions.
+var prismaActions = map[string]string{
+	"CASCADE":     "Cascade",
+	"RESTRICT":    "Restrict",
+	"NO ACTION":   "NoAction",
+	"SET NULL":    "SetNull",
+	"SET DEFAULT": "SetDefault",
+}
+
+// prismaModel is a model being generated: its fields, and its block
+// attributes.
+type prismaModel struct {
+	name   string
+	table  Table
+	fields [][3]string // name, type, attributes
+	attrs  []string
+	// columns maps a column to its field name.
+	columns map[string]string
+	used    map[string]bool
+}
+
+// field adds a field, renaming it if the model has one by that name.
+func (m *prismaModel) field(name, typ, attrs string) string {
+	name = uniqueName(m.used, name)
+	m.fields = append(m.fields, [3]string{name, typ, attrs})
+	return name
+}
+
+// Prisma returns a Prisma schema for tables, whose columns are of
+// connection type engine: a model per table, named after it in singular
+// with @@map to the table, and a field per column, in camelCase with @map
+// to the column. Primary keys, unique and other indexes become @id,
+// @unique and @@index attributes; partial and expression indexes, which
+// Prisma cannot declare, are left out. Each of fks that references a
+// table of tables becomes a relation field on both models. A table
+// without a primary key or unique index gets @@ignore, as prisma db pull
+// does.
+func Prisma(engine string, tables []Table, fks []db.ForeignKey, idxs []db.Index) (string, error) {
+	provider, ok := prismaProviders[engine]
+	if !ok {
+		return "", fmt.Errorf("no Prisma provider for connection type %q", engine)
+	}
+
+	models := make([]*prismaModel, len(tables))
+	byTable := make(map[string]*prismaModel, len(tables))
+	modelNames := map[string]bool{}
+	for i, t := range tables {
+		m := &prismaModel{
+			name:    uniqueName(modelNames, TypeName(t.Name)),
+			table:   t,
+			columns: map[string]string{},
+			used:    map[string]bool{},
+		}
+		models[i], byTable[t.Name] = m, m
+	}
+
+	for _, m := range models {
+		pk := primaryKey(m.table)
+		// Single-column unique indexes are field attributes, the others
+		// block attributes.
+		unique := map[string]string{}
+		keyed := len(pk) > 0
+		for _, ix := range idxs {
+			if ix.Table != m.table.Name || ix.Primary || ix.Partial || slices.Contains(ix.Columns, "") {
+				continue
+			}
+			if ix.Unique {
+				keyed = true
+				if len(ix.Columns) == 1 {
+					unique[ix.Columns[0]] = ix.Name
+				}
+			}
+		}
+		for _, c := range m.table.Columns {
+			typ := prismaType(engine, c.Type)
+			if c.Nullable {
+				typ += "?"
+			}
+			var attrs []string
+			if len(pk) == 1 && c.IsPK {
+				attrs = append(attrs, "@id")
+			}
+			if name, ok := unique[c.Name]; ok && !(len(pk) == 1 && c.IsPK) {
+				attrs = append(attrs, "@unique"+prismaMap(name))
+			}
+			name := m.field(camelName(c.Name), typ, "")
+			if name != c.Name {
+				attrs = append(attrs, fmt.Sprintf("@map(%q)", c.Name))
+			}
+			m.fields[len(m.fields)-1][2] = strings.Join(attrs, " ")
+			m.columns[c.Name] = name
+		}
+		if len(pk) > 1 {
+			m.attrs = append(m.attrs, fmt.Sprintf("@@id([%s])", m.fieldList(pk)))
+		}
+		for _, ix := range idxs {
+			if ix.Table != m.table.Name || ix.Primary || ix.Partial || slices.Contains(ix.Columns, "") {
+				continue
+			}
+			switch {
+			case ix.Unique && len(ix.Columns) > 1:
+				m.attrs = append(m.attrs, fmt.Sprintf("@@unique([%s]%s)", m.fieldList(ix.Columns), prismaMapArg(ix.Name)))
+			case !ix.Unique:
+				m.attrs = append(m.attrs, fmt.Sprintf("@@index([%s]%s)", m.fieldList(ix.Columns), prismaMapArg(ix.Name)))
+			}
+		}
+		if !keyed {
+			m.attrs = append(m.attrs, "@@ignore")
+		}
+		if m.name != m.table.Name {
+			m.attrs = append(m.attrs, fmt.Sprintf("@@map(%q)", m.table.Name))
+		}
+	}
+
+	// Relations between two models need a name when there are several,
+	// or the model references itself.
+	pairs := map[[2]string]int{}
+	for _, fk := range fks {
+		pairs[[2]string{fk.Table, fk.RefTable}]++
+		if fk.Table != fk.RefTable {
+			pairs[[2]string{fk.RefTable, fk.Table}]++
+		}
+	}
+	for _, fk := range fks {
+		from, to := byTable[fk.Table], byTable[fk.RefTable]
+		if from == nil || to == nil || len(fk.Columns) != len(fk.RefColumns) {
+			continue
+		}
+		var relName string
+		if pairs[[2]string{fk.Table, fk.RefTable}] > 1 || fk.Table == fk.RefTable {
+			relName = fk.Table + "_" + strings.Join(fk.Columns, "_")
+			if fk.Name != "" {
+				relName = fk.Name
+			}
+		}
+		optional := ""
+		for _, col := range fk.Columns {
+			for _, c := range from.table.Columns {
+				if c.Name == col && c.Nullable {
+					optional = "?"
+				}
+			}
+		}
+		args := []string{
+			fmt.Sprintf("fields: [%s]", from.fieldList(fk.Columns)),
+			fmt.Sprintf("references: [%s]", to.fieldList(fk.RefColumns)),
+		}
+		if a, ok := prismaActions[fk.OnDelete]; ok {
+			args = append(args, "onDelete: "+a)
+		}
+		if a, ok := prismaActions[fk.OnUpdate]; ok {
+			args = append(args, "onUpdate: "+a)
+		}
+		if relName != "" {
+			args = append([]string{fmt.Sprintf("%q", relName)}, args...)
+		}
+		from.field(camelName(relationName(fk.Columns, fk.RefTable)), to.name+optional,
+			fmt.Sprintf("@relation(%s)", strings.Join(args, ", ")))
+
+		// The other side is a list, unless the columns are unique.
+		back := from.name + "[]"
+		if isUniqueKey(from.table, idxs, fk.Columns) {
+			back = from.name + "?"
+		}
+		attrs := ""
+		if relName != "" {
+			attrs = fmt.Sprintf("@relation(%q)", relName)
+		}
+		backName := camelName(fk.Table)
+		if back[len(back)-1] == '?' {
+			backName = camelName(Singular(fk.Table))
+		}
+		to.field(backName, back, attrs)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "generator client {\n  provider = \"prisma-client-js\"\n}\n\n")
+	fmt.Fprintf(&b, "datasource db {\n  provider = %q\n  url      = env(\"DATABASE_URL\")\n}\n", provider)
+	for _, m := range models {
+		b.WriteByte('\n')
+		if slices.Contains(m.attrs, "@@ignore") {
+			b.WriteString("/// The table has no primary key or unique index, so Prisma Client cannot use it.\n")
+		}
+		fmt.Fprintf(&b, "model %s {\n", m.name)
+		var w [2]int
+		for _, f := range m.fields {
+			w[0], w[1] = max(w[0], len(f[0])), max(w[1], len(f[1]))
+		}
+		for _, f := range m.fields {
+			line := fmt.Sprintf("  %-*s %-*s %s", w[0], f[0], w[1], f[1], f[2])
+			b.WriteString(strings.TrimRight(line, " ") + "\n")
+		}
+		if len(m.attrs) > 0 {
+			b.WriteByte('\n')
+			for _, a := range m.attrs {
+				fmt.Fprintf(&b, "  %s\n", a)
+			}
+		}
+		b.WriteString("}\n")
+	}
+	return b.String(), nil
+}
+
+// fieldList returns the fields of columns, comma-separated.
+func (m *prismaModel) fieldList(columns []string) string {
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = m.columns[c]
+		if names[i] == "" {
+			names[i] = camelName(c)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// prismaType returns the Prisma scalar type of a column of type typ.
+func prismaType(engine, typ string) string {
+	t := MapType(engine, typ)
+	switch t.Kind {
+	case KindString, KindUUID:
+		return "String"
+	case KindInteger:
+		// SQLite's integers are 64-bit, but Prisma reads them as Int.
+		if t.Bits > 32 && engine != "sqlite" && engine != "demo" {
+			return "BigInt"
+		}
+		return "Int"
+	case KindFloat:
+		return "Float"
+	case KindDecimal:
+		return "Decimal"
+	case KindBool:
+		return "Boolean"
+	case KindTimestamp, KindDate, KindTime:
+		return "DateTime"
+	case KindJSON:
+		return "Json"
+	case KindBytes:
+		return "Bytes"
+	}
+	return fmt.Sprintf("Unsupported(%q)", typ)
+}
+
+// prismaMap returns the (map: name) argument of a field attribute. The
+// names SQLite gives the indexes of constraints are reserved, so none is
+// returned for them.
+func prismaMap(name string) string {
+	if name == "" || strings.HasPrefix(name, "sqlite_autoindex_") {
+		return ""
+	}
+	return fmt.Sprintf("(map: %q)", name)
+}
+
+// prismaMapArg returns the map argument of a block attribute.
+func prismaMapArg(name string) string {
+	if name == "" || strings.HasPrefix(name, "sqlite_autoindex_") {
+		return ""
+	}
+	return fmt.Sprintf(", map: %q", name)
+}
@@ -0,0 +1,106 @@
+package codegen
+
+import (
+	"testing"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
+)
+
+func TestPrisma(t *testing.T) {
+	tables := []Table{
+		{Name: "customers", Columns: []db.ColumnInfo{
+			{Name: "id", Type: "integer", IsPK: true},
+			{Name: "email", Type: "varchar(200)"},
+			{Name: "referred_by", Type: "integer", Nullable: true},
+		}},
+		{Name: "events", Columns: []db.ColumnInfo{
+			{Name: "at", Type: "timestamp"},
+			{Name: "kind", Type: "point", Nullable: true},
+		}},
+		{Name: "order_items", Columns: []db.ColumnInfo{
+			{Name: "order_id", Type: "integer", IsPK: true},
+			{Name: "line", Type: "integer", IsPK: true},
+			{Name: "price", Type: "numeric(10,2)"},
+		}},
+		{Name: "orders", Columns: []db.ColumnInfo{
+			{Name: "id", Type: "bigint", IsPK: true},
+			{Name: "customer_id", Type: "integer"},
+			{Name: "placed_at", Type: "timestamp with time zone"},
+			{Name: "meta", Type: "jsonb", Nullable: true},
+		}},
+	}
+	fks := []db.ForeignKey{
+		{Name: "customers_referred_by_fkey", Table: "customers", Columns: []string{"referred_by"}, RefTable: "customers", RefColumns: []string{"id"}},
+		{Name: "order_items_order_id_fkey", Table: "order_items", Columns: []string{"order_id"}, RefTable: "orders", RefColumns: []string{"id"}, OnDelete: "CASCADE", OnUpdate: "NO ACTION"},
+		{Name: "orders_customer_id_fkey", Table: "orders", Columns: []string{"customer_id"}, RefTable: "customers", RefColumns: []string{"id"}},
+		{Name: "elsewhere", Table: "orders", Columns: []string{"id"}, RefTable: "audit", RefColumns: []string{"id"}},
+	}
+	idxs := []db.Index{
+		{Name: "customers_pkey", Table: "customers", Columns: []string{"id"}, Unique: true, Primary: true},
+		{Name: "customers_email_key", Table: "customers", Columns: []string{"email"}, Unique: true},
+		{Name: "orders_lower_idx", Table: "orders", Columns: []string{""}},
+		{Name: "orders_placed_at", Table: "orders", Columns: []string{"customer_id", "placed_at"}},
+		{Name: "orders_recent", Table: "orders", Columns: []string{"placed_at"}, Partial: true},
+	}
+	got, err := Prisma("postgres", tables, fks, idxs)
+	if err != nil {
+		t.Fatalf("Prisma: %v", err)
+	}
+	want := `generator client {
+  provider = "prisma-client-js"
+}
+
+datasource db {
+  provider = "postgresql"
+  url      = env("DATABASE_URL")
+}
+
+model Customer {
+  id         Int        @id
+  email      String     @unique(map: "customers_email_key")
+  referredBy Int?       @map("referred_by")
+  customer   Customer?  @relation("customers_referred_by_fkey", fields: [referredBy], references: [id])
+  customers  Customer[] @relation("customers_referred_by_fkey")
+  orders     Order[]
+
+  @@map("customers")
+}
+
+/// The table has no primary key or unique index, so Prisma Client cannot use it.
+model Event {
+  at   DateTime
+  kind Unsupported("point")?
+
+  @@ignore
+  @@map("events")
+}
+
+model OrderItem {
+  orderId Int     @map("order_id")
+  line    Int
+  price   Decimal
+  order   Order   @relation(fields: [orderId], references: [id], onDelete: Cascade, onUpdate: NoAction)
+
+  @@id([orderId, line])
+  @@map("order_items")
+}
+
+model Order {
+  id         BigInt      @id
+  customerId Int         @map("customer_id")
+  placedAt   DateTime    @map("placed_at")
+  meta       Json?
+  orderItems OrderItem[]
+  customer   Customer    @relation(fields: [customerId], references: [id])
+
+  @@index([customerId, placedAt], map: "orders_placed_at")
+  @@map("orders")
+}
+`
+	if got != want {
+		t.Errorf("Prisma =\n%s\nwant\n%s", got, want)
+	}
+	if _, err := Prisma("oracle", tables, nil, nil); err == nil {
+		t.Error("Prisma for oracle succeeded")
+	}
+}
@@ -0,0 +1,59 @@
+package codegen
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
+)
+
+// relationName names the field holding the row that columns reference, as
+// a database name for the generators to case: customer_id gives customer;
+// other columns give the referenced table in singular.
+func relationName(columns []string, refTable string) string {
+	if len(columns) == 1 {
+		w := words(columns[0])
+		if len(w) > 1 && w[len(w)-1] == "id" {
+			return strings.Join(w[:len(w)-1], "_")
+		}
+	}
+	return Singular(refTable)
+}
+
+// primaryKey returns the primary key columns of t.
+func primaryKey(t Table) []string {
+	var pk []string
+	for _, c := range t.Columns {
+		if c.IsPK {
+			pk = append(pk, c.Name)
+		}
+	}
+	return pk
+}
+
+// isUniqueKey reports whether columns are the primary key or a unique
+// index of t.
+func isUniqueKey(t Table, idxs []db.Index, columns []string) bool {
+	if sameSet(primaryKey(t), columns) {
+		return true
+	}
+	for _, ix := range idxs {
+		if ix.Table == t.Name && ix.Unique && !ix.Partial && sameSet(ix.Columns, columns) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameSet reports whether a and b hold the same strings, in any order.
+func sameSet(a, b []string) bool {
+	if len(a) != len(b) || len(a) == 0 {
+		return false
+	}
+	for _, s := range a {
+		if !slices.Contains(b, s) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,187 @@
+package codegen
+
+import (
+	"fmt"
+	"go/token"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
+)
+
+// sqlcEngines are sqlc's names for the connection types it supports.
+var sqlcEngines = map[string]string{
+	"postgres": "postgresql",
+	"mysql":    "mysql",
+	"sqlite":   "sqlite",
+	"demo":     "sqlite",
+}
+
+var sqlIdentifier = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// sqlReserved are the reserved words tables and columns are commonly named
+// after, which must be quoted.
+var sqlReserved = map[string]bool{
+	"check": true, "column": true, "default": true, "desc": true, "from": true, "group": true,
+	"index": true, "key": true, "limit": true, "order": true, "select": true, "table": true,
+	"to": true, "user": true, "where": true,
+}
+
+// SQLC returns the input files of sqlc for table, whose columns cols are
+// of connection type engine: schema.sql creating the table, query.sql with
+// queries to get, list, create, update and delete its rows (get, update
+// and delete by primary key, if it has one), and sqlc.yaml generating Go
+// package pkg ("models" if empty) from them. A lone integer primary key is
+// left to the database on create.
+func SQLC(engine, table string, cols []db.ColumnInfo, pkg string) (map[string]string, error) {
+	sqlcEngine, ok := sqlcEngines[engine]
+	if !ok {
+		return nil, fmt.Errorf("sqlc does not support connection type %q", engine)
+	}
+	if pkg == "" {
+		pkg = "models"
+	}
+	if !token.IsIdentifier(pkg) {
+		return nil, fmt.Errorf("package %q is not a Go identifier", pkg)
+	}
+	q := func(name string) string { return sqlcIdent(engine, name) }
+	n := 0
+	param := func() string {
+		n++
+		if engine == "mysql" {
+			return "?"
+		}
+		return fmt.Sprintf("$%d", n)
+	}
+
+	var pk, rest []string
+	var schema strings.Builder
+	fmt.Fprintf(&schema, "CREATE TABLE %s (\n", q(table))
+	for i, c := range cols {
+		typ := c.Type
+		if typ == "" || strings.EqualFold(typ, "USER-DEFINED") || strings.EqualFold(typ, "ARRAY") {
+			typ = "text"
+		}
+		fmt.Fprintf(&schema, "  %s %s", q(c.Name), typ)
+		if !c.Nullable {
+			schema.WriteString(" NOT NULL")
+		}
+		if i < len(cols)-1 || slices.ContainsFunc(cols, func(c db.ColumnInfo) bool { return c.IsPK }) {
+			schema.WriteByte(',')
+		}
+		schema.WriteByte('\n')
+		if c.IsPK {
+			pk = append(pk, c.Name)
+		} else {
+			rest = append(rest, c.Name)
+		}
+	}
+	if len(pk) > 0 {
+		fmt.Fprintf(&schema, "  PRIMARY KEY (%s)\n", joinMapped(pk, ", ", q))
+	}
+	schema.WriteString(");\n")
+
+	one, many := TypeName(table), GoName(table)
+	if many == one {
+		many = "All" + many
+	}
+	where := func() string {
+		conds := make([]string, len(pk))
+		for i, c := range pk {
+			conds[i] = q(c) + " = " + param()
+		}
+		return strings.Join(conds, " AND ")
+	}
+	var queries strings.Builder
+	if len(pk) > 0 {
+		n = 0
+		fmt.Fprintf(&queries, "-- name: Get%s :one\nSELECT * FROM %s\nWHERE %s;\n\n", one, q(table), where())
+	}
+	fmt.Fprintf(&queries, "-- name: List%s :many\nSELECT * FROM %s", many, q(table))
+	if len(pk) > 0 {
+		fmt.Fprintf(&queries, "\nORDER BY %s", joinMapped(pk, ", ", q))
+	}
+	queries.WriteString(";\n")
+
+	insert := slices.Clone(rest)
+	if len(pk) != 1 || MapType(engine, typeOf(cols, pk[0])).Kind != KindInteger {
+		insert = nil
+		for _, c := range cols {
+			insert = append(insert, c.Name)
+		}
+	}
+	if len(insert) > 0 {
+		n = 0
+		params := make([]string, len(insert))
+		for i := range insert {
+			params[i] = param()
+		}
+		if engine == "mysql" {
+			fmt.Fprintf(&queries, "\n-- name: Create%s :execresult\n", one)
+		} else {
+			fmt.Fprintf(&queries, "\n-- name: Create%s :one\n", one)
+		}
+		fmt.Fprintf(&queries, "INSERT INTO %s (%s)\nVALUES (%s)", q(table), joinMapped(insert, ", ", q), strings.Join(params, ", "))
+		if engine != "mysql" {
+			queries.WriteString("\nRETURNING *")
+		}
+		queries.WriteString(";\n")
+	}
+
+	if len(pk) > 0 && len(rest) > 0 {
+		n = 0
+		sets := make([]string, len(rest))
+		for i, c := range rest {
+			sets[i] = q(c) + " = " + param()
+		}
+		fmt.Fprintf(&queries, "\n-- name: Update%s :exec\nUPDATE %s\nSET %s\nWHERE %s;\n", one, q(table), strings.Join(sets, ", "), where())
+	}
+	if len(pk) > 0 {
+		n = 0
+		fmt.Fprintf(&queries, "\n-- name: Delete%s :exec\nDELETE FROM %s\nWHERE %s;\n", one, q(table), where())
+	}
+
+	config := fmt.Sprintf(`version: "2"
+sql:
+  - engine: %q
+    schema: "schema.sql"
+    queries: "query.sql"
+    gen:
+      go:
+        package: %q
+        out: %q
+`, sqlcEngine, pkg, pkg)
+	return map[string]string{"schema.sql": schema.String(), "query.sql": queries.String(), "sqlc.yaml": config}, nil
+}
+
+// sqlcIdent returns name as an identifier of engine, quoted unless it is a
+// plain lower-case name.
+func sqlcIdent(engine, name string) string {
+	if sqlIdentifier.MatchString(name) && !sqlReserved[name] {
+		return name
+	}
+	if engine == "mysql" {
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// joinMapped joins names after applying f to each.
+func joinMapped(names []string, sep string, f func(string) string) string {
+	out := make([]string, len(names))
+	for i, n := range names {
+		out[i] = f(n)
+	}
+	return strings.Join(out, sep)
+}
+
+// typeOf returns the type of column name in cols.
+func typeOf(cols []db.ColumnInfo, name string) string {
+	for _, c := range cols {
+		if c.Name == name {
+			return c.Type
+		}
+	}
+	return ""
+}
@@ -0,0 +1,85 @@
+package codegen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
+)
+
+func TestSQLC(t *testing.T) {
+	files, err := SQLC("postgres", "orders", []db.ColumnInfo{
+		{Name: "id", Type: "bigint", IsPK: true},
+		{Name: "customer_id", Type: "integer"},
+		{Name: "Note", Type: "text", Nullable: true},
+		{Name: "tags", Type: "ARRAY", Nullable: true},
+	}, "store")
+	if err != nil {
+		t.Fatalf("SQLC: %v", err)
+	}
+	wantSchema := `CREATE TABLE orders (
+  id bigint NOT NULL,
+  customer_id integer NOT NULL,
+  "Note" text,
+  tags text,
+  PRIMARY KEY (id)
+);
+`
+	if files["schema.sql"] != wantSchema {
+		t.Errorf("schema.sql =\n%s\nwant\n%s", files["schema.sql"], wantSchema)
+	}
+	wantQueries := `-- name: GetOrder :one
+SELECT * FROM orders
+WHERE id = $1;
+
+-- name: ListOrders :many
+SELECT * FROM orders
+ORDER BY id;
+
+-- name: CreateOrder :one
+INSERT INTO orders (customer_id, "Note", tags)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: UpdateOrder :exec
+UPDATE orders
+SET customer_id = $1, "Note" = $2, tags = $3
+WHERE id = $4;
+
+-- name: DeleteOrder :exec
+DELETE FROM orders
+WHERE id = $1;
+`
+	if files["query.sql"] != wantQueries {
+		t.Errorf("query.sql =\n%s\nwant\n%s", files["query.sql"], wantQueries)
+	}
+	if !strings.Contains(files["sqlc.yaml"], `engine: "postgresql"`) || !strings.Contains(files["sqlc.yaml"], `package: "store"`) {
+		t.Errorf("sqlc.yaml =\n%s", files["sqlc.yaml"])
+	}
+}
+
+func TestSQLC_mysql(t *testing.T) {
+	files, err := SQLC("mysql", "order", []db.ColumnInfo{
+		{Name: "order_id", Type: "int", IsPK: true},
+		{Name: "line", Type: "int", IsPK: true},
+	}, "")
+	if err != nil {
+		t.Fatalf("SQLC: %v", err)
+	}
+	for _, want := range []string{
+		"CREATE TABLE `order` (\n",
+		"WHERE order_id = ? AND line = ?;",
+		"-- name: CreateOrder :execresult\nINSERT INTO `order` (order_id, line)\nVALUES (?, ?);\n",
+		"-- name: ListAllOrder :many",
+	} {
+		if !strings.Contains(files["schema.sql"]+files["query.sql"], want) {
+			t.Errorf("files lack %q:\n%s%s", want, files["schema.sql"], files["query.sql"])
+		}
+	}
+	if strings.Contains(files["query.sql"], "UpdateOrder") {
+		t.Errorf("query.sql updates a table of key columns only:\n%s", files["query.sql"])
+	}
+	if _, err := SQLC("sqlserver", "orders", nil, ""); err == nil {
+		t.Error("SQLC for sqlserver succeeded")
+	}
+}
@@ -0,0 +1,156 @@
+// Package codegen turns table metadata from the drivers into artifacts for
+// other tools: JSON Schema documents, and the type definitions of code
+// generators. Column types are first mapped to a Type, a dialect-free
+// description every generator shares, so they all agree on what a column
+// holds.
+package codegen
+
+import "strings"
+
+// Kind is what a column holds, whatever the engine calls its type.
+type Kind int
+
+const (
+	KindUnknown   Kind = iota // anything else; generators fall back to their widest type
+	KindString                // text of any length
+	KindInteger               // whole numbers; see Type.Bits
+	KindFloat                 // binary floating point
+	KindDecimal               // exact numbers: numeric, decimal, money
+	KindBool                  // true or false
+	KindTimestamp             // a date and time of day, with or without zone
+	KindDate                  // a calendar date
+	KindTime                  // a time of day
+	KindUUID                  // a UUID
+	KindJSON                  // a JSON document, or an array
+	KindBytes                 // binary data
+)
+
+var kindNames = [...]string{"unknown", "string", "integer", "float", "decimal", "bool", "timestamp", "date", "time", "uuid", "json", "bytes"}
+
+// String returns the kind's name, as used in tool output.
+func (k Kind) String() string {
+	if int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+	return "unknown"
+}
+
+// Type is a column type mapped from a database type.
+type Type struct {
+	Kind Kind
+	// Bits is the size in bits of a KindInteger or KindFloat.
+	Bits int
+}
+
+// MapType maps typ, a column type as the driver of connection type engine
+// reports it in DescribeTable, to a Type. The demo connection type maps
+// like sqlite.
+func MapType(engine, typ string) Type {
+	t := strings.ToLower(strings.TrimSpace(typ))
+	// Drop a length or precision: varchar(255), decimal(10, 2).
+	if i := strings.IndexByte(t, '('); i >= 0 {
+		t = strings.TrimSpace(t[:i])
+	}
+	switch engine {
+	case "sqlite", "demo":
+		return sqliteType(t)
+	case "sqlserver":
+		switch t {
+		case "bit":
+			return Type{Kind: KindBool}
+		case "tinyint":
+			// Unsigned, so it needs more than 8 signed bits.
+			return Type{Kind: KindInteger, Bits: 16}
+		case "timestamp", "rowversion":
+			return Type{Kind: KindBytes}
+		case "money", "smallmoney":
+			return Type{Kind: KindDecimal}
+		case "xml", "sql_variant", "hierarchyid", "geography", "geometry":
+			return Type{Kind: KindString}
+		}
+	case "mysql":
+		switch t {
+		case "bit":
+			return Type{Kind: KindBytes}
+		case "tinyint":
+			return Type{Kind: KindInteger, Bits: 8}
+		case "mediumint":
+			return Type{Kind: KindInteger, Bits: 32}
+		case "year":
+			return Type{Kind: KindInteger, Bits: 16}
+		case "enum", "set":
+			return Type{Kind: KindString}
+		}
+	case "postgres":
+		switch t {
+		case "array", "json", "jsonb":
+			return Type{Kind: KindJSON}
+		case "interval", "inet", "cidr", "macaddr", "tsvector", "user-defined", "xml", "money":
+			return Type{Kind: KindString}
+		}
+	}
+	return commonType(t)
+}
+
+// commonType maps the type names the engines share, lower-cased and
+// without length.
+func commonType(t string) Type {
+	switch t {
+	case "smallint", "int2", "smallserial":
+		return Type{Kind: KindInteger, Bits: 16}
+	case "integer", "int", "int4", "serial", "mediumint":
+		return Type{Kind: KindInteger, Bits: 32}
+	case "bigint", "int8", "bigserial":
+		return Type{Kind: KindInteger, Bits: 64}
+	case "real", "float4":
+		return Type{Kind: KindFloat, Bits: 32}
+	case "double precision", "double", "float", "float8":
+		return Type{Kind: KindFloat, Bits: 64}
+	case "numeric", "decimal", "money":
+		return Type{Kind: KindDecimal}
+	case "boolean", "bool":
+		return Type{Kind: KindBool}
+	case "text", "character varying", "varchar", "character", "char", "nvarchar", "nchar", "ntext",
+		"tinytext", "mediumtext", "longtext", "citext", "name", "clob":
+		return Type{Kind: KindString}
+	case "timestamp", "timestamp without time zone", "timestamp with time zone", "timestamptz",
+		"datetime", "datetime2", "datetimeoffset", "smalldatetime":
+		return Type{Kind: KindTimestamp}
+	case "date":
+		return Type{Kind: KindDate}
+	case "time", "time without time zone", "time with time zone", "timetz":
+		return Type{Kind: KindTime}
+	case "uuid", "uniqueidentifier":
+		return Type{Kind: KindUUID}
+	case "json", "jsonb":
+		return Type{Kind: KindJSON}
+	case "bytea", "blob", "tinyblob", "mediumblob", "longblob", "binary", "varbinary", "image":
+		return Type{Kind: KindBytes}
+	}
+	return Type{Kind: KindUnknown}
+}
+
+// sqliteType maps a declared SQLite type: the names the other engines use
+// first, then SQLite's own affinity rules, under which any declared type
+// is valid.
+func sqliteType(t string) Type {
+	if typ := commonType(t); typ.Kind != KindUnknown {
+		if typ.Kind == KindInteger {
+			// Every SQLite integer is stored in up to 64 bits.
+			typ.Bits = 64
+		}
+		return typ
+	}
+	switch {
+	case strings.Contains(t, "int"):
+		return Type{Kind: KindInteger, Bits: 64}
+	case strings.Contains(t, "char"), strings.Contains(t, "clob"), strings.Contains(t, "text"):
+		return Type{Kind: KindString}
+	case strings.Contains(t, "blob"):
+		return Type{Kind: KindBytes}
+	case strings.Contains(t, "real"), strings.Contains(t, "floa"), strings.Contains(t, "doub"):
+		return Type{Kind: KindFloat, Bits: 64}
+	}
+	// No declared type holds anything.
+	return Type{Kind: KindUnknown}
+}
@@ -0,0 +1,122 @@
+package codegen
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
+)
+
+// Table is a table's name and columns, for the generators that take
+// several.
+type Table struct {
+	Name    string
+	Columns []db.ColumnInfo
+}
+
+// TypeScript output formats.
+const (
+	TSInterface = "interface" // export interface Customer { ... }
+	TSZod       = "zod"       // export const Customer = z.object({ ... })
+)
+
+// TypeScript returns a TypeScript module declaring the type of a row of
+// each table, as run_query returns it in JSON, whose columns are of
+// connection type engine. format is TSInterface (the default) or TSZod,
+// which also exports the inferred type. Properties keep the column names;
+// nullable columns are unions with null. Dates and times, decimals, UUIDs
+// and base64-encoded binary data are strings, and JSON columns unknown.
+func TypeScript(engine string, tables []Table, format string) (string, error) {
+	switch format {
+	case "":
+		format = TSInterface
+	case TSInterface, TSZod:
+	default:
+		return "", fmt.Errorf("format must be %q or %q, not %q", TSInterface, TSZod, format)
+	}
+	var b strings.Builder
+	if format == TSZod {
+		b.WriteString("import { z } from \"zod\";\n")
+	}
+	for i, t := range tables {
+		if i > 0 || format == TSZod {
+			b.WriteByte('\n')
+		}
+		name := TypeName(t.Name)
+		fmt.Fprintf(&b, "/** A row of %s. */\n", t.Name)
+		if format == TSZod {
+			fmt.Fprintf(&b, "export const %s = z.object({\n", name)
+		} else {
+			fmt.Fprintf(&b, "export interface %s {\n", name)
+		}
+		for _, c := range t.Columns {
+			typ := MapType(engine, c.Type)
+			if format == TSZod {
+				fmt.Fprintf(&b, "  %s: %s,\n", tsKey(c.Name), zodType(typ, c.Nullable))
+			} else {
+				fmt.Fprintf(&b, "  %s: %s;\n", tsKey(c.Name), tsType(typ, c.Nullable))
+			}
+		}
+		if format == TSZod {
+			fmt.Fprintf(&b, "});\nexport type %s = z.infer<typeof %s>;\n", name, name)
+		} else {
+			b.WriteString("}\n")
+		}
+	}
+	return b.String(), nil
+}
+
+// tsType returns the TypeScript type of a column of type t.
+func tsType(t Type, nullable bool) string {
+	var typ string
+	switch t.Kind {
+	case KindInteger, KindFloat:
+		typ = "number"
+	case KindBool:
+		typ = "boolean"
+	case KindJSON, KindUnknown:
+		// unknown includes null.
+		return "unknown"
+	default:
+		typ = "string"
+	}
+	if nullable {
+		typ += " | null"
+	}
+	return typ
+}
+
+// zodType returns the zod schema of a column of type t.
+func zodType(t Type, nullable bool) string {
+	var typ string
+	switch t.Kind {
+	case KindInteger:
+		typ = "z.number().int()"
+	case KindFloat:
+		typ = "z.number()"
+	case KindBool:
+		typ = "z.boolean()"
+	case KindUUID:
+		typ = "z.string().uuid()"
+	case KindJSON, KindUnknown:
+		return "z.unknown()"
+	default:
+		typ = "z.string()"
+	}
+	if nullable {
+		typ += ".nullable()"
+	}
+	return typ
+}
+
+var tsIdentifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// tsKey returns name as a property key, quoted unless it is an identifier.
+func tsKey(name string) string {
+	if tsIdentifier.MatchString(name) {
+		return name
+	}
+	return strconv.Quote(name)
+}
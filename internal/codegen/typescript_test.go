@@ -0,0 +1,71 @@
+package codegen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
+)
+
+var tsTables = []Table{
+	{Name: "customers", Columns: []db.ColumnInfo{
+		{Name: "id", Type: "uniqueidentifier", IsPK: true},
+		{Name: "name", Type: "nvarchar"},
+		{Name: "vip", Type: "bit"},
+		{Name: "born", Type: "date", Nullable: true},
+	}},
+	{Name: "order_items", Columns: []db.ColumnInfo{
+		{Name: "order_id", Type: "int", IsPK: true},
+		{Name: "unit price", Type: "decimal"},
+		{Name: "extra", Type: "nvarchar(max)", Nullable: true},
+	}},
+}
+
+func TestTypeScript(t *testing.T) {
+	got, err := TypeScript("sqlserver", tsTables, "")
+	if err != nil {
+		t.Fatalf("TypeScript: %v", err)
+	}
+	want := `/** A row of customers. */
+export interface Customer {
+  id: string;
+  name: string;
+  vip: boolean;
+  born: string | null;
+}
+
+/** A row of order_items. */
+export interface OrderItem {
+  order_id: number;
+  "unit price": string;
+  extra: string | null;
+}
+`
+	if got != want {
+		t.Errorf("TypeScript =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestTypeScript_zod(t *testing.T) {
+	got, err := TypeScript("sqlserver", tsTables[:1], TSZod)
+	if err != nil {
+		t.Fatalf("TypeScript: %v", err)
+	}
+	want := `import { z } from "zod";
+
+/** A row of customers. */
+export const Customer = z.object({
+  id: z.string().uuid(),
+  name: z.string(),
+  vip: z.boolean(),
+  born: z.string().nullable(),
+});
+export type Customer = z.infer<typeof Customer>;
+`
+	if got != want {
+		t.Errorf("TypeScript =\n%s\nwant\n%s", got, want)
+	}
+	if _, err := TypeScript("sqlserver", tsTables, "flow"); err == nil || !strings.Contains(err.Error(), "format") {
+		t.Errorf("TypeScript with format flow: %v", err)
+	}
+}
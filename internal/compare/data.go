@@ -0,0 +1,188 @@
+package compare
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Statuses of a RowDiff.
+const (
+	StatusOnlyInA = "only_in_a"
+	StatusOnlyInB = "only_in_b"
+	StatusDiffers = "differs"
+)
+
+// DataDiff is the difference between the rows of tables A and B.
+type DataDiff struct {
+	// Identical is set if both tables hold the same rows.
+	Identical bool `json:"identical"`
+	RowsA     int  `json:"rows_a"`
+	RowsB     int  `json:"rows_b"`
+	// Matching counts the rows in both tables with equal values.
+	Matching int `json:"matching"`
+	// OnlyInA and OnlyInB count the rows whose key is missing from the
+	// other table; Differing the rows in both with different values.
+	OnlyInA   int `json:"only_in_a"`
+	OnlyInB   int `json:"only_in_b"`
+	Differing int `json:"differing"`
+	// Sample holds the first rows that differ.
+	Sample []RowDiff `json:"sample"`
+}
+
+// RowDiff is a row that differs between the tables.
+type RowDiff struct {
+	// Key holds the row's key columns and values.
+	Key    map[string]any `json:"key"`
+	Status string         `json:"status"`
+	// Columns are the columns whose values differ, for StatusDiffers.
+	Columns []string `json:"columns,omitempty"`
+	// A and B are the row as each table holds it: all its columns if it is
+	// only in one table, the differing columns otherwise.
+	A map[string]any `json:"a,omitempty"`
+	B map[string]any `json:"b,omitempty"`
+}
+
+// Rows compares rows a and b of two tables, matched by the values of their
+// key columns, by the values of columns. Column names are as A names them
+// and match B's case-insensitively. Values match when they hold the same
+// thing, whatever the driver's Go type: integers and floats by number,
+// booleans as 0 and 1, bytes and times (in UTC, RFC 3339) as strings. At
+// most sample differing rows are returned, in the order of a and then of b.
+// Keys must be unique in each table.
+func Rows(a, b []map[string]any, key, columns []string, sample int) (DataDiff, error) {
+	diff := DataDiff{RowsA: len(a), RowsB: len(b), Sample: []RowDiff{}}
+	add := func(d RowDiff) {
+		if len(diff.Sample) < sample {
+			diff.Sample = append(diff.Sample, d)
+		}
+	}
+	bRows := make(map[string]map[string]any, len(b))
+	for _, row := range b {
+		row = lowered(row)
+		k := rowKey(row, key)
+		if _, dup := bRows[k]; dup {
+			return DataDiff{}, fmt.Errorf("key (%s) is not unique in table B", strings.Join(key, ", "))
+		}
+		bRows[k] = row
+	}
+	seen := make(map[string]bool, len(a))
+	for _, row := range a {
+		row = lowered(row)
+		k := rowKey(row, key)
+		if seen[k] {
+			return DataDiff{}, fmt.Errorf("key (%s) is not unique in table A", strings.Join(key, ", "))
+		}
+		seen[k] = true
+		other, ok := bRows[k]
+		if !ok {
+			diff.OnlyInA++
+			add(RowDiff{Key: pick(row, key), Status: StatusOnlyInA, A: pick(row, columns)})
+			continue
+		}
+		var differing []string
+		for _, c := range columns {
+			if normalize(row[strings.ToLower(c)]) != normalize(other[strings.ToLower(c)]) {
+				differing = append(differing, c)
+			}
+		}
+		if len(differing) == 0 {
+			diff.Matching++
+			continue
+		}
+		diff.Differing++
+		add(RowDiff{Key: pick(row, key), Status: StatusDiffers, Columns: differing, A: pick(row, differing), B: pick(other, differing)})
+	}
+	for _, row := range b {
+		row = lowered(row)
+		if !seen[rowKey(row, key)] {
+			diff.OnlyInB++
+			add(RowDiff{Key: pick(row, key), Status: StatusOnlyInB, B: pick(row, columns)})
+		}
+	}
+	diff.Identical = diff.OnlyInA == 0 && diff.OnlyInB == 0 && diff.Differing == 0
+	return diff, nil
+}
+
+// lowered returns row with its column names in lower case.
+func lowered(row map[string]any) map[string]any {
+	out := make(map[string]any, len(row))
+	for name, v := range row {
+		out[strings.ToLower(name)] = v
+	}
+	return out
+}
+
+// pick returns the values of columns in a lowered row, under their names.
+func pick(row map[string]any, columns []string) map[string]any {
+	out := make(map[string]any, len(columns))
+	for _, c := range columns {
+		out[c] = row[strings.ToLower(c)]
+	}
+	return out
+}
+
+// rowKey identifies a lowered row by the values of its key columns.
+func rowKey(row map[string]any, key []string) string {
+	parts := make([]string, len(key))
+	for i, c := range key {
+		parts[i] = strconv.Quote(normalize(row[strings.ToLower(c)]))
+	}
+	return strings.Join(parts, ",")
+}
+
+// normalize returns a value as a string that equals that of another value
+// holding the same thing, tagged with its kind so the number 1 and the
+// string "1" differ.
+func normalize(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "s:" + v
+	case []byte:
+		return "s:" + string(v)
+	case time.Time:
+		return "s:" + v.UTC().Format(time.RFC3339Nano)
+	case bool:
+		if v {
+			return "n:1"
+		}
+		return "n:0"
+	case int:
+		return "n:" + strconv.FormatInt(int64(v), 10)
+	case int8:
+		return "n:" + strconv.FormatInt(int64(v), 10)
+	case int16:
+		return "n:" + strconv.FormatInt(int64(v), 10)
+	case int32:
+		return "n:" + strconv.FormatInt(int64(v), 10)
+	case int64:
+		return "n:" + strconv.FormatInt(v, 10)
+	case uint:
+		return "n:" + strconv.FormatUint(uint64(v), 10)
+	case uint8:
+		return "n:" + strconv.FormatUint(uint64(v), 10)
+	case uint16:
+		return "n:" + strconv.FormatUint(uint64(v), 10)
+	case uint32:
+		return "n:" + strconv.FormatUint(uint64(v), 10)
+	case uint64:
+		return "n:" + strconv.FormatUint(v, 10)
+	case float32:
+		return normalizeFloat(float64(v))
+	case float64:
+		return normalizeFloat(v)
+	}
+	return fmt.Sprintf("%T:%v", v, v)
+}
+
+// normalizeFloat formats integral floats as integers, so 2.0 equals 2.
+func normalizeFloat(f float64) string {
+	if f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+		return "n:" + strconv.FormatInt(int64(f), 10)
+	}
+	return "n:" + strconv.FormatFloat(f, 'g', -1, 64)
+}
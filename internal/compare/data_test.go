@@ -0,0 +1,45 @@
+package compare
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestRows(t *testing.T) {
+	a := []map[string]any{
+		{"id": int64(1), "name": "Ada", "score": 2.0, "active": int64(1), "seen": "2024-01-05T09:12:00Z"},
+		{"id": int64(2), "name": "Ben", "score": 1.5, "active": int64(0), "seen": nil},
+		{"id": int64(3), "name": "Cy", "score": 1.0, "active": int64(1), "seen": nil},
+	}
+	b := []map[string]any{
+		{"ID": int32(1), "Name": []byte("Ada"), "Score": int64(2), "Active": true, "Seen": time.Date(2024, 1, 5, 10, 12, 0, 0, time.FixedZone("CET", 3600))},
+		{"ID": 2.0, "Name": "Ben", "Score": "1.5", "Active": false, "Seen": nil},
+		{"ID": int64(4), "Name": "Dee", "Score": 0.5, "Active": true, "Seen": nil},
+	}
+	diff, err := Rows(a, b, []string{"id"}, []string{"id", "name", "score", "active", "seen"}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := json.Marshal(diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"identical":false,"rows_a":3,"rows_b":3,"matching":1,"only_in_a":1,"only_in_b":1,"differing":1,"sample":[` +
+		`{"key":{"id":2},"status":"differs","columns":["score"],"a":{"score":1.5},"b":{"score":"1.5"}},` +
+		`{"key":{"id":3},"status":"only_in_a","a":{"active":1,"id":3,"name":"Cy","score":1,"seen":null}},` +
+		`{"key":{"id":4},"status":"only_in_b","b":{"active":true,"id":4,"name":"Dee","score":0.5,"seen":null}}]}`
+	if string(got) != want {
+		t.Errorf("Rows =\n%s\nwant\n%s", got, want)
+	}
+
+	if diff, err := Rows(a, b, []string{"id"}, []string{"id"}, 1); err != nil || len(diff.Sample) != 1 || diff.OnlyInB != 1 {
+		t.Errorf("Rows with a sample of 1 = %+v, %v", diff, err)
+	}
+	if diff, err := Rows(a, a, []string{"id"}, []string{"id", "name"}, 10); err != nil || !diff.Identical || diff.Matching != 3 {
+		t.Errorf("Rows of a table with itself = %+v, %v", diff, err)
+	}
+	if _, err := Rows(a, b, []string{"active"}, []string{"name"}, 10); err == nil {
+		t.Error("Rows with a key that is not unique succeeded")
+	}
+}
@@ -0,0 +1,224 @@
+// Package compare diffs what two connections hold: the tables, columns and
+// indexes of their schemas, and the rows of their tables, so environments
+// can be kept in sync. The connections may be of different types; names
+// match case-insensitively, and types and values of different engines are
+// compared by what they hold.
+package compare
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/codegen"
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
+)
+
+// Schema is one side of a comparison: the tables of a schema, with its
+// indexes, on a connection of type Engine.
+type Schema struct {
+	Engine  string
+	Tables  []codegen.Table
+	Indexes []db.Index
+}
+
+// SchemaDiff is the difference between schemas A and B.
+type SchemaDiff struct {
+	// Identical is set if the schemas hold the same tables, columns and
+	// indexes.
+	Identical bool `json:"identical"`
+	// OnlyInA and OnlyInB are the tables missing from the other schema.
+	OnlyInA []string `json:"only_in_a"`
+	OnlyInB []string `json:"only_in_b"`
+	// Tables are the tables in both schemas that differ, by name.
+	Tables []TableDiff `json:"tables"`
+}
+
+// TableDiff is the difference between a table in both schemas.
+type TableDiff struct {
+	// Table is the table's name in A.
+	Table string `json:"table"`
+	// ColumnsOnlyInA and ColumnsOnlyInB are the columns missing from the
+	// other table.
+	ColumnsOnlyInA []string `json:"columns_only_in_a,omitempty"`
+	ColumnsOnlyInB []string `json:"columns_only_in_b,omitempty"`
+	// Columns are the columns in both tables that differ, in A's order.
+	Columns []ColumnDiff `json:"columns,omitempty"`
+	// PrimaryKeyA and PrimaryKeyB are set if the primary keys differ.
+	PrimaryKeyA []string `json:"primary_key_a,omitempty"`
+	PrimaryKeyB []string `json:"primary_key_b,omitempty"`
+	// IndexesOnlyInA and IndexesOnlyInB are the indexes missing from the
+	// other table, described by their columns: "unique (email)". Names are
+	// not compared, as engines name constraints' indexes their own way.
+	IndexesOnlyInA []string `json:"indexes_only_in_a,omitempty"`
+	IndexesOnlyInB []string `json:"indexes_only_in_b,omitempty"`
+}
+
+// ColumnDiff is a column that differs between the tables.
+type ColumnDiff struct {
+	Column string `json:"column"`
+	// Differences lists what differs: "type", "nullable".
+	Differences []string   `json:"differences"`
+	A           ColumnSide `json:"a"`
+	B           ColumnSide `json:"b"`
+}
+
+// ColumnSide is a column as one schema declares it.
+type ColumnSide struct {
+	Type string `json:"type"`
+	// Kind is what the type holds, as codegen maps it.
+	Kind     string `json:"kind"`
+	Nullable bool   `json:"nullable"`
+}
+
+// Schemas returns the difference between a and b. Types of the same engine
+// must match by name, ignoring case; types of different engines by the
+// codegen.Kind they map to.
+func Schemas(a, b Schema) SchemaDiff {
+	diff := SchemaDiff{OnlyInA: []string{}, OnlyInB: []string{}, Tables: []TableDiff{}}
+	bTables := make(map[string]codegen.Table, len(b.Tables))
+	for _, t := range b.Tables {
+		bTables[strings.ToLower(t.Name)] = t
+	}
+	seen := map[string]bool{}
+	for _, ta := range a.Tables {
+		key := strings.ToLower(ta.Name)
+		seen[key] = true
+		tb, ok := bTables[key]
+		if !ok {
+			diff.OnlyInA = append(diff.OnlyInA, ta.Name)
+			continue
+		}
+		if td, differs := tables(a, b, ta, tb); differs {
+			diff.Tables = append(diff.Tables, td)
+		}
+	}
+	for _, t := range b.Tables {
+		if !seen[strings.ToLower(t.Name)] {
+			diff.OnlyInB = append(diff.OnlyInB, t.Name)
+		}
+	}
+	diff.Identical = len(diff.OnlyInA) == 0 && len(diff.OnlyInB) == 0 && len(diff.Tables) == 0
+	return diff
+}
+
+// tables compares table ta of a with tb of b.
+func tables(a, b Schema, ta, tb codegen.Table) (TableDiff, bool) {
+	td := TableDiff{Table: ta.Name}
+	bCols := make(map[string]db.ColumnInfo, len(tb.Columns))
+	for _, c := range tb.Columns {
+		bCols[strings.ToLower(c.Name)] = c
+	}
+	sameEngine := engine(a.Engine) == engine(b.Engine)
+	seen := map[string]bool{}
+	for _, ca := range ta.Columns {
+		key := strings.ToLower(ca.Name)
+		seen[key] = true
+		cb, ok := bCols[key]
+		if !ok {
+			td.ColumnsOnlyInA = append(td.ColumnsOnlyInA, ca.Name)
+			continue
+		}
+		sa, sb := side(a.Engine, ca), side(b.Engine, cb)
+		var differences []string
+		if (sameEngine && !strings.EqualFold(sa.Type, sb.Type)) || (!sameEngine && sa.Kind != sb.Kind) {
+			differences = append(differences, "type")
+		}
+		if sa.Nullable != sb.Nullable {
+			differences = append(differences, "nullable")
+		}
+		if len(differences) > 0 {
+			td.Columns = append(td.Columns, ColumnDiff{Column: ca.Name, Differences: differences, A: sa, B: sb})
+		}
+	}
+	for _, c := range tb.Columns {
+		if !seen[strings.ToLower(c.Name)] {
+			td.ColumnsOnlyInB = append(td.ColumnsOnlyInB, c.Name)
+		}
+	}
+
+	pkA, pkB := primaryKey(ta), primaryKey(tb)
+	if !slices.Equal(lower(pkA), lower(pkB)) {
+		td.PrimaryKeyA, td.PrimaryKeyB = pkA, pkB
+		if td.PrimaryKeyA == nil {
+			td.PrimaryKeyA = []string{}
+		}
+		if td.PrimaryKeyB == nil {
+			td.PrimaryKeyB = []string{}
+		}
+	}
+
+	ia, ib := indexes(a.Indexes, ta.Name), indexes(b.Indexes, tb.Name)
+	for _, ix := range ia {
+		if !slices.Contains(ib, ix) {
+			td.IndexesOnlyInA = append(td.IndexesOnlyInA, ix)
+		}
+	}
+	for _, ix := range ib {
+		if !slices.Contains(ia, ix) {
+			td.IndexesOnlyInB = append(td.IndexesOnlyInB, ix)
+		}
+	}
+
+	differs := len(td.ColumnsOnlyInA)+len(td.ColumnsOnlyInB)+len(td.Columns)+
+		len(td.IndexesOnlyInA)+len(td.IndexesOnlyInB) > 0 || td.PrimaryKeyA != nil
+	return td, differs
+}
+
+// engine maps the demo connection type to sqlite, which it is.
+func engine(typ string) string {
+	if typ == "demo" {
+		return "sqlite"
+	}
+	return typ
+}
+
+func side(engine string, c db.ColumnInfo) ColumnSide {
+	return ColumnSide{Type: c.Type, Kind: codegen.MapType(engine, c.Type).Kind.String(), Nullable: c.Nullable}
+}
+
+func primaryKey(t codegen.Table) []string {
+	var pk []string
+	for _, c := range t.Columns {
+		if c.IsPK {
+			pk = append(pk, c.Name)
+		}
+	}
+	return pk
+}
+
+// indexes describes the indexes of table other than its primary key,
+// sorted.
+func indexes(idxs []db.Index, table string) []string {
+	var out []string
+	for _, ix := range idxs {
+		if ix.Primary || !strings.EqualFold(ix.Table, table) {
+			continue
+		}
+		cols := make([]string, len(ix.Columns))
+		for i, c := range ix.Columns {
+			cols[i] = strings.ToLower(c)
+			if c == "" {
+				cols[i] = "<expression>"
+			}
+		}
+		s := fmt.Sprintf("(%s)", strings.Join(cols, ", "))
+		if ix.Unique {
+			s = "unique " + s
+		}
+		if ix.Partial {
+			s += " partial"
+		}
+		out = append(out, s)
+	}
+	slices.Sort(out)
+	return out
+}
+
+func lower(names []string) []string {
+	out := make([]string, len(names))
+	for i, n := range names {
+		out[i] = strings.ToLower(n)
+	}
+	return out
+}
@@ -0,0 +1,79 @@
+package compare
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/codegen"
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
+)
+
+func TestSchemas(t *testing.T) {
+	a := Schema{
+		Engine: "sqlite",
+		Tables: []codegen.Table{
+			{Name: "customers", Columns: []db.ColumnInfo{
+				{Name: "id", Type: "INTEGER", IsPK: true},
+				{Name: "email", Type: "TEXT"},
+				{Name: "city", Type: "TEXT", Nullable: true},
+				{Name: "score", Type: "REAL"},
+			}},
+			{Name: "notes", Columns: []db.ColumnInfo{{Name: "body", Type: "TEXT"}}},
+			{Name: "orders", Columns: []db.ColumnInfo{{Name: "id", Type: "INTEGER", IsPK: true}}},
+		},
+		Indexes: []db.Index{
+			{Name: "sqlite_autoindex_customers_1", Table: "customers", Columns: []string{"email"}, Unique: true},
+			{Table: "customers", Columns: []string{"id"}, Unique: true, Primary: true},
+		},
+	}
+	b := Schema{
+		Engine: "postgres",
+		Tables: []codegen.Table{
+			{Name: "Customers", Columns: []db.ColumnInfo{
+				{Name: "id", Type: "bigint", IsPK: true},
+				{Name: "email", Type: "character varying"},
+				{Name: "city", Type: "text"},
+				{Name: "score", Type: "numeric"},
+				{Name: "created_at", Type: "timestamp with time zone"},
+			}},
+			{Name: "orders", Columns: []db.ColumnInfo{{Name: "id", Type: "integer", IsPK: true}}},
+			{Name: "products", Columns: []db.ColumnInfo{{Name: "id", Type: "integer", IsPK: true}}},
+		},
+		Indexes: []db.Index{
+			{Name: "customers_pkey", Table: "Customers", Columns: []string{"id"}, Unique: true, Primary: true},
+			{Name: "customers_email_idx", Table: "Customers", Columns: []string{"email"}},
+		},
+	}
+	got, err := json.Marshal(Schemas(a, b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"identical":false,"only_in_a":["notes"],"only_in_b":["products"],"tables":[{"table":"customers",` +
+		`"columns_only_in_b":["created_at"],` +
+		`"columns":[{"column":"city","differences":["nullable"],"a":{"type":"TEXT","kind":"string","nullable":true},"b":{"type":"text","kind":"string","nullable":false}},` +
+		`{"column":"score","differences":["type"],"a":{"type":"REAL","kind":"float","nullable":false},"b":{"type":"numeric","kind":"decimal","nullable":false}}],` +
+		`"indexes_only_in_a":["unique (email)"],"indexes_only_in_b":["(email)"]}]}`
+	if string(got) != want {
+		t.Errorf("Schemas =\n%s\nwant\n%s", got, want)
+	}
+
+	if d := Schemas(a, a); !d.Identical || len(d.Tables) != 0 {
+		t.Errorf("Schemas(a, a) = %+v, want identical", d)
+	}
+}
+
+func TestSchemas_sameEngine(t *testing.T) {
+	a := Schema{Engine: "postgres", Tables: []codegen.Table{{Name: "t", Columns: []db.ColumnInfo{
+		{Name: "id", Type: "integer", IsPK: true},
+	}}}}
+	b := Schema{Engine: "postgres", Tables: []codegen.Table{{Name: "t", Columns: []db.ColumnInfo{
+		{Name: "id", Type: "bigint"},
+	}}}}
+	d := Schemas(a, b)
+	if len(d.Tables) != 1 || len(d.Tables[0].Columns) != 1 || d.Tables[0].Columns[0].Differences[0] != "type" {
+		t.Fatalf("Schemas = %+v, want integer and bigint to differ", d)
+	}
+	if pk := d.Tables[0]; len(pk.PrimaryKeyA) != 1 || pk.PrimaryKeyB == nil || len(pk.PrimaryKeyB) != 0 {
+		t.Errorf("primary keys = %v, %v", pk.PrimaryKeyA, pk.PrimaryKeyB)
+	}
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+// This file decrypts files in the age format (https://age-encryption.org/v1)
+// with filippo.io/age, for the recipient types the age CLI creates: X25519
+// and hybrid post-quantum keys (age-keygen) and passphrases (age -p).
+
+// ageIntro starts every binary age file.
+const ageIntro = "age-encryption.org/v1\n"
+
+// ageMaxWorkFactor bounds the scrypt cost a file can ask for (2^22 takes
+// about a second), so a crafted file cannot stall startup.
+const ageMaxWorkFactor = 22
+
+// errAgeNoIdentity means none of the identities given opens the file.
+var errAgeNoIdentity = errors.New("no identity matches any of the file's recipients")
+
+// isAge reports whether data is an age file, binary or armored.
+func isAge(data []byte) bool {
+	data = bytes.TrimLeft(data, " \t\r\n")
+	return bytes.HasPrefix(data, []byte(ageIntro)) || bytes.HasPrefix(data, []byte(armor.Header))
+}
+
+// ageDecrypt decrypts an age file with identities (AGE-SECRET-KEY-1...)
+// or, for a passphrase-encrypted file, passphrase.
+func ageDecrypt(data []byte, identities []string, passphrase string) ([]byte, error) {
+	var ids []age.Identity
+	if len(identities) > 0 {
+		parsed, err := age.ParseIdentities(strings.NewReader(strings.Join(identities, "\n")))
+		if err != nil {
+			return nil, fmt.Errorf("malformed age identity: %w", err)
+		}
+		ids = parsed
+	}
+	if passphrase != "" {
+		id, err := age.NewScryptIdentity(passphrase)
+		if err != nil {
+			return nil, err
+		}
+		id.SetMaxWorkFactor(ageMaxWorkFactor)
+		ids = append(ids, id)
+	}
+	var src io.Reader = bytes.NewReader(data)
+	if bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte(armor.Header)) {
+		src = armor.NewReader(src)
+	}
+	r, err := age.Decrypt(src, ids...)
+	var noMatch *age.NoIdentityMatchError
+	if errors.As(err, &noMatch) {
+		return nil, errAgeNoIdentity
+	}
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+// ageIdentities returns the identities in text, the contents of an identity
+// file as age-keygen writes it: one per line, # starting a comment.
+func ageIdentities(text string) []string {
+	var ids []string
+	for line := range strings.Lines(text) {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			ids = append(ids, line)
+		}
+	}
+	return ids
+}
@@ -8,7 +8,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -22,20 +27,265 @@ const (
 	EnvMySQLURI     = "MCP_DB_MYSQL_URI"
 )
 
+// EnvExportDirs lists the directories export_database may write to and
+// import_database may read from, separated by the OS path list separator
+// (":" on Unix). It overrides export_dirs from the config file.
+const EnvExportDirs = "MCP_EXPORT_DIRS"
+
+// EnvConfigFile names a config file to load instead of
+// ~/.localdb-mcp/config.yaml.
+const EnvConfigFile = "MCP_CONFIG"
+
+// DemoConnectionID is the connection configured when no other is: a built-in
+// in-memory e-commerce database (type "demo"), so the tools work before any
+// database is set up. A config file can also list it as demo: demo.
+const DemoConnectionID = "demo"
+
+// EnvAuthToken is the bearer token clients of the sse and http transports
+// must send. It overrides auth_token from the config file; like connection
+// URIs it is never logged.
+const EnvAuthToken = "MCP_AUTH_TOKEN"
+
+// EnvConfirmWrites, when set to a true value, makes every write tool ask the
+// human for confirmation through the client (MCP elicitation) before it
+// runs. It overrides confirm_writes from the config file.
+const EnvConfirmWrites = "MCP_CONFIRM_WRITES"
+
+// EnvWriteUnlock, when set to a true value, keeps the write tools locked
+// until the human enables them for a while through enable_writes. It
+// overrides write_unlock from the config file.
+const EnvWriteUnlock = "MCP_WRITE_UNLOCK"
+
+// EnvReadOnly, when set to a true value ("1", "true", ...), runs the server
+// without any tool that writes to a database. It overrides read_only from the
+// config file.
+const EnvReadOnly = "MCP_READ_ONLY"
+
+// EnvGlobalReadOnly, when set to a true value, locks the server in read-only
+// mode: unlike EnvReadOnly it cannot be turned off by the config file, a
+// flag or another variable. global_read_only in the config file does the
+// same.
+const EnvGlobalReadOnly = "MCP_DB_GLOBAL_READ_ONLY"
+
+// EnvAuditDB names the SQLite file the audit trail of tool calls is
+// written to. It overrides audit_db from the config file.
+const EnvAuditDB = "MCP_AUDIT_DB"
+
+// EnvRecordFile names the file every tool call and its result are recorded
+// to, for localdb-mcp replay. It overrides record_file from the config file.
+const EnvRecordFile = "MCP_RECORD_FILE"
+
+// EnvEnvironment names the environment the server runs in (e.g. dev,
+// staging), which policies can match on. It overrides environment from the
+// config file.
+const EnvEnvironment = "MCP_ENVIRONMENT"
+
 // DefaultConfigDir is the directory for the optional config file.
 // Config file path: ~/.localdb-mcp/config.yaml
 const DefaultConfigDir = ".localdb-mcp"
 const ConfigFileName = "config.yaml"
 
+// DefaultExportsDir is the directory, relative to the home directory, that is
+// always allowed for dumps when no export directories are configured.
+const DefaultExportsDir = ".localdb-mcp/exports"
+
+// Tool classes group the database tools for rate limiting.
+const (
+	ToolClassRead   = "read"   // list_tables, describe_table, run_query
+	ToolClassWrite  = "write"  // insert_test_row, update_test_row, begin_transaction, import_database
+	ToolClassExport = "export" // export_database
+)
+
+// RateLimit is a token bucket: Rate calls per second on average, with bursts
+// of up to Burst calls. A Rate of zero or less disables the limit.
+type RateLimit struct {
+	Rate  float64 `yaml:"rate" json:"rate"`
+	Burst int     `yaml:"burst" json:"burst"`
+}
+
+// DefaultRateLimits apply per tool class and connection unless the config
+// file overrides them. They are loose enough for interactive use but stop a
+// looping agent from hammering a shared database; writes, which a runaway
+// loop can do the most damage with, get far fewer than reads.
+var DefaultRateLimits = map[string]RateLimit{
+	ToolClassRead:   {Rate: 20, Burst: 40},
+	ToolClassWrite:  {Rate: 10.0 / 60, Burst: 3},
+	ToolClassExport: {Rate: 0.1, Burst: 2},
+}
+
+// Timeout categories group the tools for server-side deadlines.
+const (
+	TimeoutMetadata = "metadata" // list_tables, describe_table
+	TimeoutQuery    = "query"    // run_query, insert_test_row, update_test_row
+	TimeoutExport   = "export"   // export_database, import_database
+)
+
+// DefaultTimeouts bound each tool call by category, whatever timeout the
+// client uses, unless the config file overrides them.
+var DefaultTimeouts = map[string]time.Duration{
+	TimeoutMetadata: 5 * time.Second,
+	TimeoutQuery:    30 * time.Second,
+	TimeoutExport:   10 * time.Minute,
+}
+
+// DefaultMaxResultBytes caps the serialized size of a tool result unless the
+// config file sets max_result_bytes. Larger results are truncated.
+const DefaultMaxResultBytes = 1 << 20
+
+// EgressBudget caps the data run_query may return to one MCP session over
+// its lifetime. Zero leaves that measure unlimited.
+type EgressBudget struct {
+	Bytes int64 `yaml:"bytes" json:"bytes,omitempty"`
+	Rows  int64 `yaml:"rows" json:"rows,omitempty"`
+}
+
+// DefaultMaxRowsAffected is how many rows an update_test_row call may change
+// unless the config file sets max_rows_affected. An update over the limit is
+// rolled back.
+const DefaultMaxRowsAffected = 1
+
+// DefaultIdleTimeout is how long an unused database connection stays open
+// unless the config file sets idle_timeout. It is longer than the default
+// export deadline, so a connection is not closed under a running call.
+const DefaultIdleTimeout = 15 * time.Minute
+
+// DefaultHealthCheckInterval is how often open database connections are
+// pinged in the background unless the config file sets
+// health_check_interval. Broken connections are reopened.
+const DefaultHealthCheckInterval = 30 * time.Second
+
+// DefaultConnectTimeout bounds opening a database connection, retries
+// included, unless the config file sets connect_timeout or a per-connection
+// value in connect_timeouts.
+const DefaultConnectTimeout = 15 * time.Second
+
+// DefaultMaxConcurrentQueries is how many database tool calls may run at
+// once on one connection unless the config file sets max_concurrent_queries
+// or a per-connection value in max_concurrent_queries_by_connection. Further
+// calls wait for a slot.
+const DefaultMaxConcurrentQueries = 8
+
+// DefaultSchemaCacheTTL is how long table metadata (describe_table, and the
+// primary key check of update_test_row) is reused unless the config file
+// sets schema_cache_ttl.
+const DefaultSchemaCacheTTL = 5 * time.Minute
+
+// DefaultSlowQueryThreshold is how long a database call may take before the
+// server logs it as slow, unless the config file sets slow_query_threshold.
+const DefaultSlowQueryThreshold = time.Second
+
+// Masks applied to sensitive columns by MaskRule.
+const (
+	MaskRedact  = "redact"  // replace the value with [REDACTED]
+	MaskHash    = "hash"    // replace the value with a keyed hash, stable while the server runs
+	MaskPartial = "partial" // keep only the last four characters
+)
+
+// MaskRule masks a column in every query result before it leaves the
+// server. Connection, Table and Column are case-insensitive glob patterns
+// (*, ? and [...]); an empty Connection or Table matches any.
+type MaskRule struct {
+	Connection string `yaml:"connection"`
+	Table      string `yaml:"table"`
+	Column     string `yaml:"column"`
+	Mask       string `yaml:"mask"`
+}
+
+// Policy actions.
+const (
+	PolicyAllow   = "allow"   // run the call, subject to the other safety settings
+	PolicyDeny    = "deny"    // refuse the call
+	PolicyConfirm = "confirm" // ask the human through MCP elicitation first
+)
+
+// PolicyRule decides what happens to the tool calls it matches. Tool,
+// Connection, Table and Environment are case-insensitive glob patterns (*,
+// ? and [...]); an empty one matches any. Table matches the table argument,
+// with or without schema, and the tables a run_query statement names; a rule
+// with a table pattern does not match calls without tables.
+type PolicyRule struct {
+	Tool        string `yaml:"tool" json:"tool,omitempty"`
+	Connection  string `yaml:"connection" json:"connection,omitempty"`
+	Table       string `yaml:"table" json:"table,omitempty"`
+	Environment string `yaml:"environment" json:"environment,omitempty"`
+	Action      string `yaml:"action" json:"action"`
+	// Reason is shown to the agent and the human with the decision.
+	Reason string `yaml:"reason" json:"reason,omitempty"`
+}
+
+// Operations a connection's permissions entry can allow.
+const (
+	OpSelect = "select" // run_query
+	OpInsert = "insert" // insert_test_row
+	OpUpdate = "update" // update_test_row
+	OpExport = "export" // export_database
+	OpImport = "import" // import_database
+)
+
+// Permission limits the operations the tools may run on one connection.
+// Listing, describing and refreshing tables are always allowed.
+type Permission struct {
+	Allow []string `yaml:"allow"`
+	// Tables, if set, limits insert and update to the tables matching one
+	// of these case-insensitive glob patterns, with or without schema.
+	Tables []string `yaml:"tables"`
+}
+
 // Config holds loaded connection configuration. URIs are stored but never
 // included in logs or tool output.
 type Config struct {
-	connections map[string]connectionEntry
+	mu              sync.RWMutex // guards connections, which ReplaceConnections swaps at runtime
+	connections     map[string]connectionEntry
+	exportDirs      []string
+	defaultDirs     bool // exportDirs are the defaults, not configured
+	readOnly        bool
+	globalReadOnly  bool // read-only mode that SetReadOnly cannot turn off
+	rateLimits      map[string]RateLimit
+	timeouts        map[string]time.Duration
+	maxResult       int
+	maxRows         int
+	idleTimeout     time.Duration
+	healthCheck     time.Duration
+	connectTimeout  time.Duration
+	connectTimeouts map[string]time.Duration // by connection ID
+	readOnlyConns   []string                 // read_only_connections, applied once connections are known
+	allowSystem     []string                 // allow_system_schemas: connection IDs
+	maxConcurrent   int
+	maxConcurrentBy map[string]int // by connection ID
+	schemaCacheTTL  time.Duration
+	slowQuery       time.Duration
+	masking         []MaskRule
+	permissions     map[string]Permission           // by connection ID; none means everything is allowed
+	writeSchemas    map[string][]string             // write_schemas: by connection ID
+	rateLimitsBy    map[string]map[string]RateLimit // rate_limits_by_connection: by connection ID, then tool class
+	egressBudget    EgressBudget
+	rowFilters      map[string]map[string]string // row_filters: by connection ID, then table
+	defaultSchemas  map[string]string            // default_schemas: by connection ID
+	schemaLock      []string                     // schema_lock: connection IDs
+	sessionSchemas  []string                     // session_schemas: connection IDs
+	auditDB         string                       // audit_db: SQLite file of the audit trail, "" for none
+	recordFile      string                       // record_file: JSONL recording of the tool calls, "" for none
+	noExport        bool                         // features.export: false
+	deniedFuncs     []string                     // denied_functions: patterns added to the built-in deny-list
+	allowedFuncs    []string                     // allowed_functions: patterns lifted from it
+	environment     string
+	policies        []PolicyRule
+	authToken       string
+	confirmWrites   bool
+	writeUnlock     bool
 }
 
 type connectionEntry struct {
-	Type string // "postgres" or "sqlserver"
-	uri  string
+	Type      string // "postgres" or "sqlserver"
+	uri       string
+	fallbacks []string // further URIs to try, in order, if uri fails
+	readOnly  bool     // listed in read_only_connections
+}
+
+// same reports whether e and o configure the same connection.
+func (e connectionEntry) same(o connectionEntry) bool {
+	return e.Type == o.Type && e.uri == o.uri && e.readOnly == o.readOnly &&
+		slices.Equal(e.fallbacks, o.fallbacks)
 }
 
 // ConnectionInfo is safe to log or return to tools: no credentials.
@@ -45,18 +295,31 @@ type ConnectionInfo struct {
 }
 
 // Load reads configuration from the environment and, if present,
-// a .env file in the current directory and ~/.localdb-mcp/config.yaml.
+// a .env file in the current directory and ~/.localdb-mcp/config.yaml
+// (or the file named by MCP_CONFIG).
 // Env vars override .env and file values for the same connection ID.
 func Load() (*Config, error) {
+	return LoadFrom("")
+}
+
+// LoadFrom is like Load but reads the config file at path, which must exist.
+// An empty path falls back to MCP_CONFIG and then the default location.
+func LoadFrom(path string) (*Config, error) {
 	// 0) Optional .env in cwd (so server sees MCP_DB_* when run via mcpclient or from project root)
 	loadEnvFile(".")
 
 	c := &Config{connections: make(map[string]connectionEntry)}
 
 	// 1) Optional config file (base)
-	configPath, err := configFilePath()
-	if err != nil {
-		return nil, fmt.Errorf("config path: %w", err)
+	if path == "" {
+		path = os.Getenv(EnvConfigFile)
+	}
+	configPath := path
+	if configPath == "" {
+		var err error
+		if configPath, err = configFilePath(); err != nil {
+			return nil, fmt.Errorf("config path: %w", err)
+		}
 	}
 	if configPath != "" {
 		if err := c.loadFile(configPath); err != nil {
@@ -78,18 +341,165 @@ func Load() (*Config, error) {
 		c.connections["mysql"] = connectionEntry{Type: "mysql", uri: v}
 	}
 
+	if v := os.Getenv(EnvExportDirs); v != "" {
+		c.exportDirs = filepath.SplitList(v)
+	}
+	if v := os.Getenv(EnvAuditDB); v != "" {
+		c.auditDB = v
+	}
+	if v := os.Getenv(EnvRecordFile); v != "" {
+		c.recordFile = v
+	}
+	if v := os.Getenv(EnvEnvironment); v != "" {
+		c.environment = v
+	}
+	if v := os.Getenv(EnvAuthToken); v != "" {
+		c.authToken = v
+	}
+	if v := os.Getenv(EnvReadOnly); v != "" {
+		ro, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", EnvReadOnly, err)
+		}
+		c.readOnly = ro
+	}
+	if v := os.Getenv(EnvGlobalReadOnly); v != "" {
+		ro, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", EnvGlobalReadOnly, err)
+		}
+		// Either the file or the environment can lock the server; neither
+		// unlocks what the other locked.
+		c.globalReadOnly = c.globalReadOnly || ro
+	}
+	if v := os.Getenv(EnvWriteUnlock); v != "" {
+		unlock, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", EnvWriteUnlock, err)
+		}
+		c.writeUnlock = unlock
+	}
+	if v := os.Getenv(EnvConfirmWrites); v != "" {
+		confirm, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", EnvConfirmWrites, err)
+		}
+		c.confirmWrites = confirm
+	}
+	if err := c.resolveSecrets(); err != nil {
+		return nil, err
+	}
+	if err := c.resolveExportDirs(); err != nil {
+		return nil, fmt.Errorf("export dirs: %w", err)
+	}
+	if c.auditDB != "" {
+		p, err := expandPath(c.auditDB)
+		if err != nil {
+			return nil, fmt.Errorf("audit_db: %w", err)
+		}
+		c.auditDB = p
+	}
+	if c.recordFile != "" {
+		p, err := expandPath(c.recordFile)
+		if err != nil {
+			return nil, fmt.Errorf("record_file: %w", err)
+		}
+		c.recordFile = p
+	}
 	if len(c.connections) == 0 {
-		return c, nil
+		c.connections[DemoConnectionID] = connectionEntry{Type: DemoConnectionID, uri: DemoConnectionID}
+	}
+	for _, id := range c.readOnlyConns {
+		e, ok := c.connections[id]
+		if !ok {
+			return nil, fmt.Errorf("read_only_connections: unknown connection %q", id)
+		}
+		e.readOnly = true
+		c.connections[id] = e
+	}
+	for id := range c.permissions {
+		if _, ok := c.connections[id]; !ok {
+			return nil, fmt.Errorf("permissions: unknown connection %q", id)
+		}
+	}
+	for id, schemas := range c.writeSchemas {
+		e, ok := c.connections[id]
+		if !ok {
+			return nil, fmt.Errorf("write_schemas: unknown connection %q", id)
+		}
+		if e.Type == "sqlite" || e.Type == DemoConnectionID {
+			return nil, fmt.Errorf("write_schemas: connection %q (%s) has no schemas; use read_only_connections or permissions instead", id, e.Type)
+		}
+		if len(schemas) == 0 {
+			return nil, fmt.Errorf("write_schemas: connection %q lists no schemas; list at least one, or use read_only_connections", id)
+		}
+	}
+	for _, id := range c.allowSystem {
+		if _, ok := c.connections[id]; !ok {
+			return nil, fmt.Errorf("allow_system_schemas: unknown connection %q", id)
+		}
+	}
+	for _, id := range c.sessionSchemas {
+		if _, ok := c.connections[id]; !ok {
+			return nil, fmt.Errorf("session_schemas: unknown connection %q", id)
+		}
+	}
+	for id, schema := range c.defaultSchemas {
+		e, ok := c.connections[id]
+		if !ok {
+			return nil, fmt.Errorf("default_schemas: unknown connection %q", id)
+		}
+		if e.Type == "sqlite" || e.Type == DemoConnectionID {
+			return nil, fmt.Errorf("default_schemas: connection %q (%s) has no schemas", id, e.Type)
+		}
+		if strings.TrimSpace(schema) == "" {
+			return nil, fmt.Errorf("default_schemas: connection %q: empty schema", id)
+		}
+	}
+	for _, id := range c.schemaLock {
+		if _, ok := c.connections[id]; !ok {
+			return nil, fmt.Errorf("schema_lock: unknown connection %q", id)
+		}
+		if c.defaultSchemas[id] == "" {
+			return nil, fmt.Errorf("schema_lock: connection %q has no entry in default_schemas to lock it to", id)
+		}
+	}
+	for id, filters := range c.rowFilters {
+		if _, ok := c.connections[id]; !ok {
+			return nil, fmt.Errorf("row_filters: unknown connection %q", id)
+		}
+		for table, predicate := range filters {
+			if strings.TrimSpace(table) == "" {
+				return nil, fmt.Errorf("row_filters: connection %q: empty table name", id)
+			}
+			// The predicate is pasted into SQL; a statement separator or a
+			// comment would let it end the WHERE clause it is added to.
+			if strings.TrimSpace(predicate) == "" || strings.ContainsAny(predicate, ";") ||
+				strings.Contains(predicate, "--") || strings.Contains(predicate, "/*") {
+				return nil, fmt.Errorf("row_filters: connection %q, table %q: predicate must be a non-empty SQL condition without ; or comments", id, table)
+			}
+		}
+	}
+	for id := range c.rateLimitsBy {
+		if _, ok := c.connections[id]; !ok {
+			return nil, fmt.Errorf("rate_limits_by_connection: unknown connection %q", id)
+		}
 	}
+
 	return c, nil
 }
 
 // loadEnvFile reads .env from dir and sets env vars for any key not already set.
 func loadEnvFile(dir string) {
-	path := filepath.Join(dir, ".env")
+	_ = LoadEnvFile(filepath.Join(dir, ".env"))
+}
+
+// LoadEnvFile reads the KEY=value lines of the .env file at path, which may
+// start with export, and sets env vars for any key not already set.
+func LoadEnvFile(path string) error {
 	f, err := os.Open(path)
 	if err != nil {
-		return
+		return err
 	}
 	defer f.Close()
 	sc := bufio.NewScanner(f)
@@ -102,7 +512,7 @@ func loadEnvFile(dir string) {
 		if idx <= 0 {
 			continue
 		}
-		key := strings.TrimSpace(line[:idx])
+		key, _ := cutEnvExport(strings.TrimSpace(line[:idx]))
 		val := strings.TrimSpace(line[idx+1:])
 		if key == "" {
 			continue
@@ -116,14 +526,34 @@ func loadEnvFile(dir string) {
 			_ = os.Setenv(key, val)
 		}
 	}
+	return sc.Err()
 }
 
-func configFilePath() (string, error) {
+// cutEnvExport returns the variable name of key, the part of a .env line
+// before "=", without the shell's export prefix, and whether it had one.
+func cutEnvExport(key string) (string, bool) {
+	rest, ok := strings.CutPrefix(key, "export")
+	if !ok || rest == strings.TrimLeft(rest, " \t") {
+		return key, false
+	}
+	return strings.TrimSpace(rest), true
+}
+
+// DefaultConfigPath returns ~/.localdb-mcp/config.yaml, whether or not it
+// exists.
+func DefaultConfigPath() (string, error) {
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return "", err
 	}
-	p := filepath.Join(home, DefaultConfigDir, ConfigFileName)
+	return filepath.Join(home, DefaultConfigDir, ConfigFileName), nil
+}
+
+func configFilePath() (string, error) {
+	p, err := DefaultConfigPath()
+	if err != nil {
+		return "", err
+	}
 	_, err = os.Stat(p)
 	if os.IsNotExist(err) {
 		return "", nil
@@ -135,7 +565,75 @@ func configFilePath() (string, error) {
 }
 
 type fileFormat struct {
-	Connections map[string]string `yaml:"connections"`
+	Connections     map[string]uriList              `yaml:"connections"`
+	ExportDirs      []string                        `yaml:"export_dirs"`
+	ReadOnly        bool                            `yaml:"read_only"`
+	GlobalReadOnly  bool                            `yaml:"global_read_only"`
+	RateLimits      map[string]RateLimit            `yaml:"rate_limits"`
+	Timeouts        map[string]time.Duration        `yaml:"timeouts"`
+	MaxResult       int                             `yaml:"max_result_bytes"`
+	MaxRows         int                             `yaml:"max_rows_affected"`
+	IdleTimeout     time.Duration                   `yaml:"idle_timeout"`
+	HealthCheck     time.Duration                   `yaml:"health_check_interval"`
+	ConnectTimeout  time.Duration                   `yaml:"connect_timeout"`
+	ConnectTimeouts map[string]time.Duration        `yaml:"connect_timeouts"`
+	ReadOnlyConns   []string                        `yaml:"read_only_connections"`
+	AllowSystem     []string                        `yaml:"allow_system_schemas"`
+	MaxConcurrent   int                             `yaml:"max_concurrent_queries"`
+	MaxConcurrentBy map[string]int                  `yaml:"max_concurrent_queries_by_connection"`
+	SchemaCacheTTL  time.Duration                   `yaml:"schema_cache_ttl"`
+	SlowQuery       time.Duration                   `yaml:"slow_query_threshold"`
+	Masking         []MaskRule                      `yaml:"masking"`
+	Permissions     map[string]Permission           `yaml:"permissions"`
+	WriteSchemas    map[string][]string             `yaml:"write_schemas"`
+	RateLimitsBy    map[string]map[string]RateLimit `yaml:"rate_limits_by_connection"`
+	EgressBudget    EgressBudget                    `yaml:"egress_budget"`
+	RowFilters      map[string]map[string]string    `yaml:"row_filters"`
+	DefaultSchemas  map[string]string               `yaml:"default_schemas"`
+	Environment     string                          `yaml:"environment"`
+	Policies        []PolicyRule                    `yaml:"policies"`
+	SchemaLock      []string                        `yaml:"schema_lock"`
+	SessionSchemas  []string                        `yaml:"session_schemas"`
+	AuditDB         string                          `yaml:"audit_db"`
+	RecordFile      string                          `yaml:"record_file"`
+	AuthToken       string                          `yaml:"auth_token"`
+	ConfirmWrites   bool                            `yaml:"confirm_writes"`
+	WriteUnlock     bool                            `yaml:"write_unlock"`
+	Features        Features                        `yaml:"features"`
+	DeniedFuncs     []string                        `yaml:"denied_functions"`
+	AllowedFuncs    []string                        `yaml:"allowed_functions"`
+}
+
+// Features switches whole groups of tools off. A feature left out of the
+// config file is on.
+type Features struct {
+	// Export, when false, removes export_database and import_database, for
+	// setups where SQL dumps on disk are a compliance problem.
+	Export *bool `yaml:"export"`
+}
+
+// uriList is a connection's URIs in the config file: a single URI, or a
+// list of candidates tried in order until one connects.
+type uriList []string
+
+func (l *uriList) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		var uri string
+		if err := value.Decode(&uri); err != nil {
+			return err
+		}
+		*l = uriList{uri}
+		return nil
+	case yaml.SequenceNode:
+		var uris []string
+		if err := value.Decode(&uris); err != nil {
+			return err
+		}
+		*l = uris
+		return nil
+	}
+	return fmt.Errorf("line %d: want a connection URI or a list of URIs", value.Line)
 }
 
 func (c *Config) loadFile(path string) error {
@@ -143,23 +641,188 @@ func (c *Config) loadFile(path string) error {
 	if err != nil {
 		return err
 	}
+	if data, err = decryptConfig(path, data); err != nil {
+		return err
+	}
 	var f fileFormat
 	if err := yaml.Unmarshal(data, &f); err != nil {
 		return err
 	}
-	for id, uri := range f.Connections {
-		if uri == "" {
+	for id, list := range f.Connections {
+		uris := slices.DeleteFunc(list, func(uri string) bool { return uri == "" })
+		if len(uris) == 0 {
 			continue
 		}
 		typ := idToType(id)
-		c.connections[id] = connectionEntry{Type: typ, uri: uri}
+		c.connections[id] = connectionEntry{Type: typ, uri: uris[0], fallbacks: uris[1:]}
+	}
+	c.exportDirs = f.ExportDirs
+	c.readOnly = f.ReadOnly
+	c.globalReadOnly = f.GlobalReadOnly
+	c.authToken = f.AuthToken
+	c.auditDB = f.AuditDB
+	c.recordFile = f.RecordFile
+	c.confirmWrites = f.ConfirmWrites
+	c.writeUnlock = f.WriteUnlock
+	c.noExport = f.Features.Export != nil && !*f.Features.Export
+	c.maxResult = f.MaxResult
+	c.maxRows = f.MaxRows
+	c.idleTimeout = f.IdleTimeout
+	c.healthCheck = f.HealthCheck
+	c.connectTimeout = f.ConnectTimeout
+	c.connectTimeouts = f.ConnectTimeouts
+	c.readOnlyConns = f.ReadOnlyConns
+	c.allowSystem = f.AllowSystem
+	c.sessionSchemas = f.SessionSchemas
+	c.maxConcurrent = f.MaxConcurrent
+	c.maxConcurrentBy = f.MaxConcurrentBy
+	c.schemaCacheTTL = f.SchemaCacheTTL
+	c.slowQuery = f.SlowQuery
+	if f.EgressBudget.Bytes < 0 || f.EgressBudget.Rows < 0 {
+		return fmt.Errorf("egress_budget: bytes and rows must not be negative")
+	}
+	c.egressBudget = f.EgressBudget
+	limits, err := rateLimitsFrom("rate_limits", f.RateLimits)
+	if err != nil {
+		return err
+	}
+	c.rateLimits = limits
+	c.rateLimitsBy = nil
+	for id, byClass := range f.RateLimitsBy {
+		limits, err := rateLimitsFrom("rate_limits_by_connection: "+id, byClass)
+		if err != nil {
+			return err
+		}
+		if c.rateLimitsBy == nil {
+			c.rateLimitsBy = make(map[string]map[string]RateLimit)
+		}
+		c.rateLimitsBy[id] = limits
+	}
+	for i, r := range f.Masking {
+		if r.Column == "" {
+			return fmt.Errorf("masking[%d]: column is required", i)
+		}
+		for _, pattern := range []string{r.Connection, r.Table, r.Column} {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return fmt.Errorf("masking[%d]: bad pattern %q", i, pattern)
+			}
+		}
+		switch r.Mask {
+		case MaskRedact, MaskHash, MaskPartial:
+		default:
+			return fmt.Errorf("masking[%d]: unknown mask %q (want %s, %s or %s)", i, r.Mask, MaskRedact, MaskHash, MaskPartial)
+		}
+	}
+	c.masking = f.Masking
+	for key, patterns := range map[string][]string{"denied_functions": f.DeniedFuncs, "allowed_functions": f.AllowedFuncs} {
+		for _, pattern := range patterns {
+			if _, err := filepath.Match(pattern, ""); err != nil || strings.TrimSpace(pattern) == "" {
+				return fmt.Errorf("%s: bad pattern %q", key, pattern)
+			}
+		}
+	}
+	c.deniedFuncs = f.DeniedFuncs
+	c.allowedFuncs = f.AllowedFuncs
+	for i, r := range f.Policies {
+		for _, pattern := range []string{r.Tool, r.Connection, r.Table, r.Environment} {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return fmt.Errorf("policies[%d]: bad pattern %q", i, pattern)
+			}
+		}
+		switch r.Action {
+		case PolicyAllow, PolicyDeny, PolicyConfirm:
+		default:
+			return fmt.Errorf("policies[%d]: unknown action %q (want %s, %s or %s)", i, r.Action, PolicyAllow, PolicyDeny, PolicyConfirm)
+		}
+	}
+	c.policies = f.Policies
+	c.environment = f.Environment
+	for id, p := range f.Permissions {
+		for _, op := range p.Allow {
+			switch op {
+			case OpSelect, OpInsert, OpUpdate, OpExport, OpImport:
+			default:
+				return fmt.Errorf("permissions.%s: unknown operation %q (want %s, %s, %s, %s or %s)",
+					id, op, OpSelect, OpInsert, OpUpdate, OpExport, OpImport)
+			}
+		}
+		for _, pattern := range p.Tables {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return fmt.Errorf("permissions.%s: bad table pattern %q", id, pattern)
+			}
+		}
 	}
+	c.permissions = f.Permissions
+	c.writeSchemas = f.WriteSchemas
+	c.rowFilters = f.RowFilters
+	c.defaultSchemas = f.DefaultSchemas
+	c.schemaLock = f.SchemaLock
+	for category, d := range f.Timeouts {
+		if _, ok := DefaultTimeouts[category]; !ok {
+			return fmt.Errorf("timeouts: unknown tool category %q (want %s, %s or %s)",
+				category, TimeoutMetadata, TimeoutQuery, TimeoutExport)
+		}
+		if c.timeouts == nil {
+			c.timeouts = make(map[string]time.Duration)
+		}
+		c.timeouts[category] = d
+	}
+	return nil
+}
+
+// resolveExportDirs makes the configured export directories absolute,
+// expanding a leading "~/", or fills in the defaults (the working directory
+// and ~/.localdb-mcp/exports) when none are configured. A configured list
+// whose entries are all empty is an error.
+func (c *Config) resolveExportDirs() error {
+	home, _ := os.UserHomeDir()
+	dirs := c.exportDirs
+	c.defaultDirs = len(dirs) == 0
+	if c.defaultDirs {
+		wd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		dirs = []string{wd}
+		if home != "" {
+			dirs = append(dirs, filepath.Join(home, DefaultExportsDir))
+		}
+	}
+	resolved := make([]string, 0, len(dirs))
+	for _, d := range dirs {
+		if d == "" {
+			continue
+		}
+		abs, err := expandPath(d)
+		if err != nil {
+			return err
+		}
+		resolved = append(resolved, abs)
+	}
+	if len(resolved) == 0 {
+		// An empty list would allow no path at all; refuse it rather than
+		// start a server whose dump tools can never succeed.
+		return fmt.Errorf("every entry is empty")
+	}
+	c.exportDirs = resolved
 	return nil
 }
 
+// expandPath makes p absolute, expanding a leading "~/".
+func expandPath(p string) (string, error) {
+	if p == "~" || strings.HasPrefix(p, "~/") {
+		home, _ := os.UserHomeDir()
+		if home == "" {
+			return "", fmt.Errorf("cannot expand %q: home directory unknown", p)
+		}
+		p = filepath.Join(home, strings.TrimPrefix(p, "~"))
+	}
+	return filepath.Abs(p)
+}
+
 func idToType(id string) string {
 	switch id {
-	case "postgres", "sqlserver", "sqlite", "mysql":
+	case "postgres", "sqlserver", "sqlite", "mysql", DemoConnectionID:
 		return id
 	default:
 		return "postgres"
@@ -168,6 +831,8 @@ func idToType(id string) string {
 
 // ConnectionIDs returns all configured connection IDs. Safe to log.
 func (c *Config) ConnectionIDs() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	ids := make([]string, 0, len(c.connections))
 	for id := range c.connections {
 		ids = append(ids, id)
@@ -177,6 +842,8 @@ func (c *Config) ConnectionIDs() []string {
 
 // ConnectionInfos returns connection id and type for each connection. Safe to return from tools.
 func (c *Config) ConnectionInfos() []ConnectionInfo {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	infos := make([]ConnectionInfo, 0, len(c.connections))
 	for id, e := range c.connections {
 		infos = append(infos, ConnectionInfo{ID: id, Type: e.Type})
@@ -186,6 +853,8 @@ func (c *Config) ConnectionInfos() []ConnectionInfo {
 
 // URI returns the connection URI for the given ID. For use only by the db layer; never log the result.
 func (c *Config) URI(id string) (uri string, ok bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	e, ok := c.connections[id]
 	if !ok {
 		return "", false
@@ -193,17 +862,446 @@ func (c *Config) URI(id string) (uri string, ok bool) {
 	return e.uri, true
 }
 
+// URIs returns every URI configured for the given ID, in the order they
+// should be tried: URI first, then the fallbacks from a list in the config
+// file. For use only by the db layer; never log the result.
+func (c *Config) URIs(id string) (uris []string, ok bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	e, ok := c.connections[id]
+	if !ok {
+		return nil, false
+	}
+	return append([]string{e.uri}, e.fallbacks...), true
+}
+
+// ExportDirs returns the absolute directories that export_database may
+// write to and import_database may read from.
+func (c *Config) ExportDirs() []string {
+	return c.exportDirs
+}
+
+// RootExportDirs returns the directories export_database and import_database
+// may use for a client that declared the filesystem roots roots (absolute
+// paths): the roots, plus the configured export directories. When none are
+// configured, the roots take the place of the working directory default and
+// only ~/.localdb-mcp/exports is added.
+func (c *Config) RootExportDirs(roots []string) []string {
+	dirs := append([]string(nil), roots...)
+	if !c.defaultDirs {
+		return append(dirs, c.exportDirs...)
+	}
+	if home, err := os.UserHomeDir(); err == nil && home != "" {
+		dirs = append(dirs, filepath.Join(home, DefaultExportsDir))
+	}
+	return dirs
+}
+
+// ReadOnly reports whether the server runs without write tools.
+func (c *Config) ReadOnly() bool {
+	return c.readOnly || c.globalReadOnly
+}
+
+// ExportEnabled reports whether export_database and import_database may be
+// used; features.export: false in the config file turns them off.
+func (c *Config) ExportEnabled() bool {
+	return !c.noExport
+}
+
+// AuditDB returns the absolute path of the SQLite file the audit trail is
+// written to, or "" if auditing is off.
+func (c *Config) AuditDB() string {
+	return c.auditDB
+}
+
+// RecordFile returns the absolute path of the file tool calls are recorded
+// to, or "" if recording is off.
+func (c *Config) RecordFile() string {
+	return c.recordFile
+}
+
+// SetRecordFile turns recording on to path, e.g. from a command-line flag.
+func (c *Config) SetRecordFile(path string) {
+	c.recordFile = path
+}
+
+// UseDemoOnly replaces the connections with the built-in demo database, so
+// a recording can be replayed without touching a real one.
+func (c *Config) UseDemoOnly() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.connections = map[string]connectionEntry{DemoConnectionID: {Type: DemoConnectionID, uri: DemoConnectionID}}
+}
+
+// GlobalReadOnly reports whether read-only mode is locked on by
+// global_read_only or MCP_DB_GLOBAL_READ_ONLY.
+func (c *Config) GlobalReadOnly() bool {
+	return c.globalReadOnly
+}
+
+// MaxConcurrentQueries returns how many database tool calls may run at once
+// on connection id: its entry in max_concurrent_queries_by_connection, else
+// max_concurrent_queries from the config file, else
+// DefaultMaxConcurrentQueries. Zero means no limit; a negative value in the
+// config file disables it.
+func (c *Config) MaxConcurrentQueries(id string) int {
+	n, ok := c.maxConcurrentBy[id]
+	if !ok || n == 0 {
+		n = c.maxConcurrent
+	}
+	switch {
+	case n == 0:
+		return DefaultMaxConcurrentQueries
+	case n < 0:
+		return 0
+	}
+	return n
+}
+
+// SchemaCacheTTL returns how long table metadata is cached: schema_cache_ttl
+// from the config file, or DefaultSchemaCacheTTL when unset. Zero means no
+// caching.
+func (c *Config) SchemaCacheTTL() time.Duration {
+	switch {
+	case c.schemaCacheTTL == 0:
+		return DefaultSchemaCacheTTL
+	case c.schemaCacheTTL < 0:
+		return 0
+	}
+	return c.schemaCacheTTL
+}
+
+// SlowQueryThreshold returns how long a database call may take before it is
+// logged as slow: slow_query_threshold from the config file, or
+// DefaultSlowQueryThreshold when unset. Zero means no slow-query logging.
+func (c *Config) SlowQueryThreshold() time.Duration {
+	switch {
+	case c.slowQuery == 0:
+		return DefaultSlowQueryThreshold
+	case c.slowQuery < 0:
+		return 0
+	}
+	return c.slowQuery
+}
+
+// ConnectionReadOnly reports whether connection id must not be written to:
+// it is listed in read_only_connections or the whole server is read-only.
+// Such connections are opened with a read-only database session.
+func (c *Config) ConnectionReadOnly(id string) bool {
+	if c.ReadOnly() {
+		return true
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.connections[id].readOnly
+}
+
+// MaskRules returns the masking rules of the config file, in order.
+func (c *Config) MaskRules() []MaskRule {
+	return c.masking
+}
+
+// Permits reports whether the permissions of connection id allow op on
+// schema.table. An empty table asks whether op is allowed on any table.
+// Connections without a permissions entry allow every operation.
+func (c *Config) Permits(id, op, schema, table string) bool {
+	p, ok := c.permissions[id]
+	if !ok {
+		return true
+	}
+	if !slices.Contains(p.Allow, op) {
+		return false
+	}
+	if table == "" || len(p.Tables) == 0 || (op != OpInsert && op != OpUpdate) {
+		return true
+	}
+	names := []string{strings.ToLower(table)}
+	if schema != "" {
+		names = append(names, strings.ToLower(schema+"."+table))
+	}
+	for _, pattern := range p.Tables {
+		for _, name := range names {
+			if ok, _ := filepath.Match(strings.ToLower(pattern), name); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Environment returns the environment the server runs in, from
+// EnvEnvironment or environment in the config file, or "" if unset.
+func (c *Config) Environment() string {
+	return c.environment
+}
+
+// Policies returns the policy rules from the config file, in order: the
+// first rule matching a tool call decides it. Calls no rule matches are
+// allowed.
+func (c *Config) Policies() []PolicyRule {
+	return c.policies
+}
+
+// DefaultSchema returns the schema tools use on connection id when the
+// schema argument is omitted, from default_schemas in the config file, or
+// "" for the database's own default.
+func (c *Config) DefaultSchema(id string) string {
+	return c.defaultSchemas[id]
+}
+
+// SchemaLocked reports whether connection id is listed in schema_lock:
+// tools and queries may only use its DefaultSchema.
+func (c *Config) SchemaLocked(id string) bool {
+	return slices.Contains(c.schemaLock, id)
+}
+
+// RowFilters returns the predicates forced on tables of connection id, by
+// table name as written in row_filters (optionally schema-qualified), or
+// nil if it has none.
+func (c *Config) RowFilters(id string) map[string]string {
+	return c.rowFilters[id]
+}
+
+// WriteSchemas returns the schemas write tools are confined to on
+// connection id, from write_schemas in the config file, or nil if writes
+// may target any schema.
+func (c *Config) WriteSchemas(id string) []string {
+	return c.writeSchemas[id]
+}
+
+// Permission returns the permissions entry of connection id, if it has one.
+func (c *Config) Permission(id string) (Permission, bool) {
+	p, ok := c.permissions[id]
+	return p, ok
+}
+
+// SystemSchemasAllowed reports whether the tools may read connection id's
+// system schemas and catalog tables (pg_catalog, information_schema,
+// sqlite_master, ...), i.e. whether it is listed in allow_system_schemas.
+func (c *Config) SystemSchemasAllowed(id string) bool {
+	return slices.Contains(c.allowSystem, id)
+}
+
+// SessionSchemas reports whether connection id gives every MCP session a
+// scratch schema of its own (an attached database on SQLite), dropped when
+// the session ends, i.e. whether it is listed in session_schemas.
+func (c *Config) SessionSchemas(id string) bool {
+	return slices.Contains(c.sessionSchemas, id)
+}
+
+// AnySessionSchemas reports whether session_schemas lists any connection.
+func (c *Config) AnySessionSchemas() bool {
+	return len(c.sessionSchemas) > 0
+}
+
+// DeniedFunctions returns the function name patterns of denied_functions,
+// which run_query refuses to call in addition to the built-in deny-list.
+func (c *Config) DeniedFunctions() []string {
+	return c.deniedFuncs
+}
+
+// AllowedFunctions returns the function name patterns of allowed_functions:
+// built-in deny-list entries they match may be called after all.
+func (c *Config) AllowedFunctions() []string {
+	return c.allowedFuncs
+}
+
+// SetReadOnly overrides the read-only setting, e.g. from a command-line flag.
+// It cannot turn off a global read-only lock.
+func (c *Config) SetReadOnly(readOnly bool) {
+	c.readOnly = readOnly
+}
+
+// ConfirmWrites reports whether write tools must be confirmed by the human
+// through the client before they run.
+func (c *Config) ConfirmWrites() bool {
+	return c.confirmWrites
+}
+
+// WriteUnlock reports whether the write tools stay locked until the human
+// enables them for a limited time through enable_writes.
+func (c *Config) WriteUnlock() bool {
+	return c.writeUnlock
+}
+
+// AuthToken returns the configured bearer token for network transports, or
+// "" if none is configured. Never log the result.
+func (c *Config) AuthToken() string {
+	return c.authToken
+}
+
+// rateLimitsFrom checks the tool classes of a rate_limits map read from the
+// config file, key naming it in errors, and gives an enabled limit a burst
+// of at least one.
+func rateLimitsFrom(key string, m map[string]RateLimit) (map[string]RateLimit, error) {
+	var limits map[string]RateLimit
+	for class, rl := range m {
+		if _, ok := DefaultRateLimits[class]; !ok {
+			return nil, fmt.Errorf("%s: unknown tool class %q (want %s, %s or %s)",
+				key, class, ToolClassRead, ToolClassWrite, ToolClassExport)
+		}
+		if rl.Rate > 0 && rl.Burst < 1 {
+			rl.Burst = 1
+		}
+		if limits == nil {
+			limits = make(map[string]RateLimit)
+		}
+		limits[class] = rl
+	}
+	return limits, nil
+}
+
+// RateLimits returns the rate limit for each tool class: the defaults,
+// overridden per class by rate_limits in the config file.
+func (c *Config) RateLimits() map[string]RateLimit {
+	limits := make(map[string]RateLimit, len(DefaultRateLimits))
+	for class, rl := range DefaultRateLimits {
+		limits[class] = rl
+	}
+	for class, rl := range c.rateLimits {
+		limits[class] = rl
+	}
+	return limits
+}
+
+// ConnectionRateLimits returns the rate limits set per connection by
+// rate_limits_by_connection, by connection ID and then tool class. They
+// replace RateLimits for that class on that connection.
+func (c *Config) ConnectionRateLimits() map[string]map[string]RateLimit {
+	return c.rateLimitsBy
+}
+
+// Timeouts returns the deadline for each tool category: the defaults,
+// overridden per category by timeouts in the config file. Zero means no
+// server-side deadline.
+func (c *Config) Timeouts() map[string]time.Duration {
+	timeouts := make(map[string]time.Duration, len(DefaultTimeouts))
+	for category, d := range DefaultTimeouts {
+		timeouts[category] = d
+	}
+	for category, d := range c.timeouts {
+		timeouts[category] = d
+	}
+	return timeouts
+}
+
+// EgressBudget returns egress_budget from the config file: how much data
+// run_query may return to one session in total. The zero value means no
+// budget.
+func (c *Config) EgressBudget() EgressBudget {
+	return c.egressBudget
+}
+
+// MaxResultBytes returns the largest tool result, in bytes, the server sends
+// before truncating it: max_result_bytes from the config file, or
+// DefaultMaxResultBytes when unset. Zero or less means no limit.
+func (c *Config) MaxResultBytes() int {
+	switch {
+	case c.maxResult == 0:
+		return DefaultMaxResultBytes
+	case c.maxResult < 0:
+		return 0
+	}
+	return c.maxResult
+}
+
+// MaxRowsAffected returns the most rows a single update may change, or
+// DefaultMaxRowsAffected when unset. Zero means no limit (set a negative
+// max_rows_affected).
+func (c *Config) MaxRowsAffected() int {
+	switch {
+	case c.maxRows == 0:
+		return DefaultMaxRowsAffected
+	case c.maxRows < 0:
+		return 0
+	}
+	return c.maxRows
+}
+
+// IdleTimeout returns how long a database connection may go unused before
+// it is closed: idle_timeout from the config file, or DefaultIdleTimeout when
+// unset. Zero means connections stay open until shutdown.
+func (c *Config) IdleTimeout() time.Duration {
+	switch {
+	case c.idleTimeout == 0:
+		return DefaultIdleTimeout
+	case c.idleTimeout < 0:
+		return 0
+	}
+	return c.idleTimeout
+}
+
+// HealthCheckInterval returns how often open database connections are pinged
+// in the background: health_check_interval from the config file, or
+// DefaultHealthCheckInterval when unset. Zero means no background checks.
+func (c *Config) HealthCheckInterval() time.Duration {
+	switch {
+	case c.healthCheck == 0:
+		return DefaultHealthCheckInterval
+	case c.healthCheck < 0:
+		return 0
+	}
+	return c.healthCheck
+}
+
+// ConnectTimeout returns how long opening connection id may take, retries
+// included: its entry in connect_timeouts, else connect_timeout from the
+// config file, else DefaultConnectTimeout. The deadline is independent of
+// the tool call that needs the connection.
+func (c *Config) ConnectTimeout(id string) time.Duration {
+	if d := c.connectTimeouts[id]; d > 0 {
+		return d
+	}
+	if c.connectTimeout > 0 {
+		return c.connectTimeout
+	}
+	return DefaultConnectTimeout
+}
+
 // HasConnection returns whether the given connection ID is configured.
 func (c *Config) HasConnection(id string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	_, ok := c.connections[id]
 	return ok
 }
 
 // Type returns the database type for the connection ID ("postgres" or "sqlserver"). ok is false if ID is not configured.
 func (c *Config) Type(id string) (typ string, ok bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	e, ok := c.connections[id]
 	if !ok {
 		return "", false
 	}
 	return e.Type, true
 }
+
+// ReplaceConnections swaps in the connections of next, e.g. after the config
+// file was edited, and returns the sorted IDs that were added, removed or
+// changed. Other settings keep the values they were loaded with.
+func (c *Config) ReplaceConnections(next *Config) []string {
+	next.mu.RLock()
+	conns := make(map[string]connectionEntry, len(next.connections))
+	for id, e := range next.connections {
+		conns[id] = e
+	}
+	next.mu.RUnlock()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var changed []string
+	for id, e := range conns {
+		if old, ok := c.connections[id]; !ok || !old.same(e) {
+			changed = append(changed, id)
+		}
+	}
+	for id := range c.connections {
+		if _, ok := conns[id]; !ok {
+			changed = append(changed, id)
+		}
+	}
+	c.connections = conns
+	sort.Strings(changed)
+	return changed
+}
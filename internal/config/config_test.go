@@ -4,7 +4,9 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"slices"
 	"testing"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -88,12 +90,12 @@ connections:
 	if err := yaml.Unmarshal(data, &f); err != nil {
 		t.Fatalf("unmarshal: %v", err)
 	}
-	for id, uri := range f.Connections {
-		if uri == "" {
+	for id, uris := range f.Connections {
+		if len(uris) == 0 || uris[0] == "" {
 			continue
 		}
 		typ := idToType(id)
-		c.connections[id] = connectionEntry{Type: typ, uri: uri}
+		c.connections[id] = connectionEntry{Type: typ, uri: uris[0]}
 	}
 
 	infos := c.ConnectionInfos()
@@ -151,3 +153,761 @@ func TestURI(t *testing.T) {
 		t.Error("URI(missing) should be !ok")
 	}
 }
+
+func TestLoadFile_uriList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ConfigFileName)
+	if err := os.WriteFile(path, []byte(`
+connections:
+  postgres:
+    - "postgres://u@db:5432/app"
+    - ""
+    - "postgres://u@localhost:5432/app"
+  mysql: "root@tcp(localhost:3306)/app"
+  sqlite: []
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := &Config{connections: make(map[string]connectionEntry)}
+	if err := c.loadFile(path); err != nil {
+		t.Fatalf("loadFile: %v", err)
+	}
+	uris, ok := c.URIs("postgres")
+	want := []string{"postgres://u@db:5432/app", "postgres://u@localhost:5432/app"}
+	if !ok || !reflect.DeepEqual(uris, want) {
+		t.Errorf("URIs(postgres) = %q, %v; want %q", uris, ok, want)
+	}
+	if uri, _ := c.URI("postgres"); uri != want[0] {
+		t.Errorf("URI(postgres) = %q, want the first candidate", uri)
+	}
+	if uris, _ := c.URIs("mysql"); len(uris) != 1 {
+		t.Errorf("URIs(mysql) = %q, want the single URI", uris)
+	}
+	if c.HasConnection("sqlite") {
+		t.Error("a connection with no URIs should be skipped")
+	}
+
+	if err := os.WriteFile(path, []byte("connections:\n  postgres: {host: db}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.loadFile(path); err == nil {
+		t.Error("expected error for a mapping as connection URI")
+	}
+}
+
+func TestResolveExportDirs(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &Config{}
+	if err := c.resolveExportDirs(); err != nil {
+		t.Fatalf("resolveExportDirs: %v", err)
+	}
+	want := []string{wd, filepath.Join(home, DefaultExportsDir)}
+	if !reflect.DeepEqual(c.ExportDirs(), want) {
+		t.Errorf("default ExportDirs = %v, want %v", c.ExportDirs(), want)
+	}
+
+	c = &Config{exportDirs: []string{"~/dumps", "rel", ""}}
+	if err := c.resolveExportDirs(); err != nil {
+		t.Fatalf("resolveExportDirs: %v", err)
+	}
+	want = []string{filepath.Join(home, "dumps"), filepath.Join(wd, "rel")}
+	if !reflect.DeepEqual(c.ExportDirs(), want) {
+		t.Errorf("configured ExportDirs = %v, want %v", c.ExportDirs(), want)
+	}
+
+	// export_dirs: [""] or MCP_EXPORT_DIRS=":" configures no directory.
+	c = &Config{exportDirs: []string{"", ""}}
+	if err := c.resolveExportDirs(); err == nil {
+		t.Errorf("resolveExportDirs(%q) = nil, want an error", []string{"", ""})
+	}
+}
+
+func TestLoadFrom(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "custom.yaml")
+	if err := os.WriteFile(path, []byte(`
+connections:
+  sqlite: ":memory:"
+read_only: true
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(EnvReadOnly, "")
+
+	cfg, err := LoadFrom(path)
+	if err != nil {
+		t.Fatalf("LoadFrom: %v", err)
+	}
+	if typ, ok := cfg.Type("sqlite"); !ok || typ != "sqlite" {
+		t.Errorf("expected sqlite connection from %s, got %q, %v", path, typ, ok)
+	}
+	if !cfg.ReadOnly() {
+		t.Error("expected read_only from the config file")
+	}
+
+	t.Setenv(EnvReadOnly, "false")
+	if cfg, err = LoadFrom(path); err != nil || cfg.ReadOnly() {
+		t.Errorf("%s=false should override the file, got ReadOnly=%v, err=%v", EnvReadOnly, cfg != nil && cfg.ReadOnly(), err)
+	}
+
+	t.Setenv(EnvReadOnly, "maybe")
+	if _, err := LoadFrom(path); err == nil {
+		t.Errorf("expected error for invalid %s", EnvReadOnly)
+	}
+
+	t.Setenv(EnvReadOnly, "")
+	if _, err := LoadFrom(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("expected error for a missing explicit config file")
+	}
+}
+
+func TestLoadFile_rateLimits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ConfigFileName)
+	if err := os.WriteFile(path, []byte(`
+rate_limits:
+  write: {rate: 1}
+  export: {rate: 0}
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := &Config{connections: make(map[string]connectionEntry)}
+	if err := c.loadFile(path); err != nil {
+		t.Fatalf("loadFile: %v", err)
+	}
+	limits := c.RateLimits()
+	if got := limits[ToolClassWrite]; got != (RateLimit{Rate: 1, Burst: 1}) {
+		t.Errorf("write limit = %+v, want rate 1 burst 1", got)
+	}
+	if got := limits[ToolClassExport]; got.Rate != 0 {
+		t.Errorf("export limit = %+v, want disabled", got)
+	}
+	if got := limits[ToolClassRead]; got != DefaultRateLimits[ToolClassRead] {
+		t.Errorf("read limit = %+v, want default", got)
+	}
+
+	if err := os.WriteFile(path, []byte("rate_limits:\n  bulk: {rate: 1}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.loadFile(path); err == nil {
+		t.Error("expected error for unknown tool class")
+	}
+}
+
+func TestLoadFile_egressBudget(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ConfigFileName)
+	c := &Config{connections: make(map[string]connectionEntry)}
+	if got := c.EgressBudget(); got != (EgressBudget{}) {
+		t.Errorf("default budget = %+v, want none", got)
+	}
+	if err := os.WriteFile(path, []byte("egress_budget: {bytes: 1000000, rows: 5000}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.loadFile(path); err != nil {
+		t.Fatalf("loadFile: %v", err)
+	}
+	if got := c.EgressBudget(); got != (EgressBudget{Bytes: 1000000, Rows: 5000}) {
+		t.Errorf("budget = %+v", got)
+	}
+	if err := os.WriteFile(path, []byte("egress_budget: {rows: -1}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.loadFile(path); err == nil {
+		t.Error("expected error for a negative budget")
+	}
+}
+
+func TestReplaceConnections(t *testing.T) {
+	c := &Config{connections: map[string]connectionEntry{
+		"keep":   {Type: "postgres", uri: "postgres://a"},
+		"change": {Type: "postgres", uri: "postgres://b"},
+		"drop":   {Type: "sqlite", uri: "/tmp/x.db"},
+		"list":   {Type: "postgres", uri: "postgres://d", fallbacks: []string{"postgres://e"}},
+	}}
+	next := &Config{connections: map[string]connectionEntry{
+		"keep":   {Type: "postgres", uri: "postgres://a"},
+		"change": {Type: "postgres", uri: "postgres://c"},
+		"add":    {Type: "mysql", uri: "root@tcp(localhost)/db"},
+		"list":   {Type: "postgres", uri: "postgres://d", fallbacks: []string{"postgres://f"}},
+	}}
+	got := c.ReplaceConnections(next)
+	want := []string{"add", "change", "drop", "list"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReplaceConnections = %v, want %v", got, want)
+	}
+	if c.HasConnection("drop") || !c.HasConnection("add") {
+		t.Errorf("connections not replaced: %v", c.ConnectionIDs())
+	}
+	if uri, _ := c.URI("change"); uri != "postgres://c" {
+		t.Errorf("changed URI not applied: %q", uri)
+	}
+}
+
+func TestLoadFile_timeouts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ConfigFileName)
+	if err := os.WriteFile(path, []byte("timeouts:\n  query: 1m\n  export: 0s\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := &Config{connections: make(map[string]connectionEntry)}
+	if err := c.loadFile(path); err != nil {
+		t.Fatalf("loadFile: %v", err)
+	}
+	timeouts := c.Timeouts()
+	if got := timeouts[TimeoutQuery]; got != time.Minute {
+		t.Errorf("query timeout = %v, want 1m", got)
+	}
+	if got := timeouts[TimeoutExport]; got != 0 {
+		t.Errorf("export timeout = %v, want disabled", got)
+	}
+	if got := timeouts[TimeoutMetadata]; got != DefaultTimeouts[TimeoutMetadata] {
+		t.Errorf("metadata timeout = %v, want default", got)
+	}
+
+	if err := os.WriteFile(path, []byte("timeouts:\n  bulk: 1s\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.loadFile(path); err == nil {
+		t.Error("expected error for unknown tool category")
+	}
+}
+
+func TestMaxResultBytes(t *testing.T) {
+	for _, tt := range []struct{ set, want int }{
+		{0, DefaultMaxResultBytes},
+		{4096, 4096},
+		{-1, 0},
+	} {
+		c := &Config{maxResult: tt.set}
+		if got := c.MaxResultBytes(); got != tt.want {
+			t.Errorf("max_result_bytes %d: MaxResultBytes() = %d, want %d", tt.set, got, tt.want)
+		}
+	}
+}
+
+func TestMaxRowsAffected(t *testing.T) {
+	for _, tt := range []struct{ set, want int }{
+		{0, DefaultMaxRowsAffected},
+		{10, 10},
+		{-1, 0},
+	} {
+		c := &Config{maxRows: tt.set}
+		if got := c.MaxRowsAffected(); got != tt.want {
+			t.Errorf("max_rows_affected %d: MaxRowsAffected() = %d, want %d", tt.set, got, tt.want)
+		}
+	}
+}
+
+func TestIdleTimeout(t *testing.T) {
+	for _, tt := range []struct{ set, want time.Duration }{
+		{0, DefaultIdleTimeout},
+		{time.Minute, time.Minute},
+		{-1, 0},
+	} {
+		c := &Config{idleTimeout: tt.set}
+		if got := c.IdleTimeout(); got != tt.want {
+			t.Errorf("idle_timeout %v: IdleTimeout() = %v, want %v", tt.set, got, tt.want)
+		}
+	}
+}
+
+func TestHealthCheckInterval(t *testing.T) {
+	for _, tt := range []struct{ set, want time.Duration }{
+		{0, DefaultHealthCheckInterval},
+		{time.Minute, time.Minute},
+		{-1, 0},
+	} {
+		c := &Config{healthCheck: tt.set}
+		if got := c.HealthCheckInterval(); got != tt.want {
+			t.Errorf("health_check_interval %v: HealthCheckInterval() = %v, want %v", tt.set, got, tt.want)
+		}
+	}
+}
+
+func TestLoadFile_connectTimeout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := "connect_timeout: 5s\nconnect_timeouts:\n  warehouse: 1m\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := &Config{connections: make(map[string]connectionEntry)}
+	if err := c.loadFile(path); err != nil {
+		t.Fatalf("loadFile: %v", err)
+	}
+	if got := c.ConnectTimeout("warehouse"); got != time.Minute {
+		t.Errorf("ConnectTimeout(warehouse) = %v, want 1m", got)
+	}
+	if got := c.ConnectTimeout("postgres"); got != 5*time.Second {
+		t.Errorf("ConnectTimeout(postgres) = %v, want 5s", got)
+	}
+	if got := (&Config{}).ConnectTimeout("postgres"); got != DefaultConnectTimeout {
+		t.Errorf("default ConnectTimeout = %v, want %v", got, DefaultConnectTimeout)
+	}
+}
+
+func TestLoadFrom_readOnlyConnections(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(`
+connections:
+  sqlite: ":memory:"
+  reporting: "postgres://localhost/reporting"
+read_only_connections: [reporting]
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(EnvReadOnly, "")
+	cfg, err := LoadFrom(path)
+	if err != nil {
+		t.Fatalf("LoadFrom: %v", err)
+	}
+	if !cfg.ConnectionReadOnly("reporting") || cfg.ConnectionReadOnly("sqlite") {
+		t.Errorf("ConnectionReadOnly: reporting=%v sqlite=%v, want true, false",
+			cfg.ConnectionReadOnly("reporting"), cfg.ConnectionReadOnly("sqlite"))
+	}
+	cfg.SetReadOnly(true)
+	if !cfg.ConnectionReadOnly("sqlite") {
+		t.Error("a read-only server should make every connection read-only")
+	}
+
+	if err := os.WriteFile(path, []byte("read_only_connections: [missing]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFrom(path); err == nil {
+		t.Error("expected error for an unknown connection in read_only_connections")
+	}
+}
+
+func TestMaxConcurrentQueries(t *testing.T) {
+	c := &Config{}
+	if got := c.MaxConcurrentQueries("sqlite"); got != DefaultMaxConcurrentQueries {
+		t.Errorf("default = %d, want %d", got, DefaultMaxConcurrentQueries)
+	}
+	c = &Config{maxConcurrent: 4, maxConcurrentBy: map[string]int{"sqlite": 1, "scratch": -1}}
+	for id, want := range map[string]int{"sqlite": 1, "scratch": 0, "postgres": 4} {
+		if got := c.MaxConcurrentQueries(id); got != want {
+			t.Errorf("MaxConcurrentQueries(%q) = %d, want %d", id, got, want)
+		}
+	}
+	c = &Config{maxConcurrent: -1}
+	if got := c.MaxConcurrentQueries("postgres"); got != 0 {
+		t.Errorf("disabled = %d, want 0", got)
+	}
+}
+
+func TestSchemaCacheTTL(t *testing.T) {
+	for _, tt := range []struct{ set, want time.Duration }{
+		{0, DefaultSchemaCacheTTL},
+		{time.Minute, time.Minute},
+		{-1, 0},
+	} {
+		c := &Config{schemaCacheTTL: tt.set}
+		if got := c.SchemaCacheTTL(); got != tt.want {
+			t.Errorf("schema_cache_ttl %v: SchemaCacheTTL() = %v, want %v", tt.set, got, tt.want)
+		}
+	}
+}
+
+func TestSlowQueryThreshold(t *testing.T) {
+	for _, tt := range []struct{ set, want time.Duration }{
+		{0, DefaultSlowQueryThreshold},
+		{500 * time.Millisecond, 500 * time.Millisecond},
+		{-1, 0},
+	} {
+		c := &Config{slowQuery: tt.set}
+		if got := c.SlowQueryThreshold(); got != tt.want {
+			t.Errorf("slow_query_threshold %v: SlowQueryThreshold() = %v, want %v", tt.set, got, tt.want)
+		}
+	}
+}
+
+func TestLoadFrom_globalReadOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	write := func(body string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv(EnvReadOnly, "false")
+	t.Setenv(EnvGlobalReadOnly, "")
+
+	write("global_read_only: true\nread_only: false\n")
+	cfg, err := LoadFrom(path)
+	if err != nil {
+		t.Fatalf("LoadFrom: %v", err)
+	}
+	cfg.SetReadOnly(false)
+	if !cfg.ReadOnly() || !cfg.GlobalReadOnly() {
+		t.Error("global_read_only should keep the server read-only whatever else is set")
+	}
+
+	write("read_only: false\n")
+	t.Setenv(EnvGlobalReadOnly, "true")
+	if cfg, err = LoadFrom(path); err != nil || !cfg.ReadOnly() {
+		t.Errorf("%s=true: ReadOnly=%v, err=%v", EnvGlobalReadOnly, cfg != nil && cfg.ReadOnly(), err)
+	}
+	write("global_read_only: true\n")
+	t.Setenv(EnvGlobalReadOnly, "false")
+	if cfg, err = LoadFrom(path); err != nil || !cfg.GlobalReadOnly() {
+		t.Errorf("%s=false should not unlock the file's global_read_only", EnvGlobalReadOnly)
+	}
+
+	t.Setenv(EnvGlobalReadOnly, "sometimes")
+	if _, err := LoadFrom(path); err == nil {
+		t.Errorf("expected error for invalid %s", EnvGlobalReadOnly)
+	}
+}
+
+func TestLoadFrom_allowSystemSchemas(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(`
+connections:
+  sqlite: ":memory:"
+  admin: "postgres://localhost/postgres"
+allow_system_schemas: [admin]
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadFrom(path)
+	if err != nil {
+		t.Fatalf("LoadFrom: %v", err)
+	}
+	if !cfg.SystemSchemasAllowed("admin") || cfg.SystemSchemasAllowed("sqlite") {
+		t.Errorf("SystemSchemasAllowed: admin=%v sqlite=%v, want true, false",
+			cfg.SystemSchemasAllowed("admin"), cfg.SystemSchemasAllowed("sqlite"))
+	}
+
+	if err := os.WriteFile(path, []byte("allow_system_schemas: [missing]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFrom(path); err == nil {
+		t.Error("expected error for an unknown connection in allow_system_schemas")
+	}
+}
+
+func TestLoadFile_masking(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ConfigFileName)
+	if err := os.WriteFile(path, []byte(`
+masking:
+  - column: "password*"
+    mask: redact
+  - connection: app
+    table: users
+    column: ssn
+    mask: partial
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := &Config{connections: make(map[string]connectionEntry)}
+	if err := c.loadFile(path); err != nil {
+		t.Fatalf("loadFile: %v", err)
+	}
+	want := []MaskRule{
+		{Column: "password*", Mask: MaskRedact},
+		{Connection: "app", Table: "users", Column: "ssn", Mask: MaskPartial},
+	}
+	if got := c.MaskRules(); !reflect.DeepEqual(got, want) {
+		t.Errorf("MaskRules = %+v, want %+v", got, want)
+	}
+
+	for _, bad := range []string{
+		"masking:\n  - {column: ssn, mask: scramble}\n",
+		"masking:\n  - {table: users, mask: redact}\n",
+		"masking:\n  - {column: \"[ssn\", mask: redact}\n",
+	} {
+		if err := os.WriteFile(path, []byte(bad), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.loadFile(path); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestLoadFrom_demoWhenEmpty(t *testing.T) {
+	for _, env := range []string{EnvPostgresURI, EnvSQLServerURI, EnvSQLiteURI, EnvMySQLURI} {
+		t.Setenv(env, "")
+	}
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("read_only: false\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := LoadFrom(path)
+	if err != nil {
+		t.Fatalf("LoadFrom: %v", err)
+	}
+	if typ, ok := c.Type(DemoConnectionID); !ok || typ != "demo" || len(c.ConnectionIDs()) != 1 {
+		t.Errorf("without connections: %v, type %q", c.ConnectionIDs(), typ)
+	}
+
+	t.Setenv(EnvSQLiteURI, ":memory:")
+	if c, err = LoadFrom(path); err != nil {
+		t.Fatalf("LoadFrom: %v", err)
+	}
+	if c.HasConnection(DemoConnectionID) {
+		t.Errorf("demo added next to configured connections: %v", c.ConnectionIDs())
+	}
+}
+
+func TestLoadFrom_auditDB(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("audit_db: ~/.localdb-mcp/audit.db\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(EnvAuditDB, "")
+	cfg, err := LoadFrom(path)
+	if err != nil {
+		t.Fatalf("LoadFrom: %v", err)
+	}
+	if want := filepath.Join(home, ".localdb-mcp", "audit.db"); cfg.AuditDB() != want {
+		t.Errorf("AuditDB() = %q, want %q", cfg.AuditDB(), want)
+	}
+
+	t.Setenv(EnvAuditDB, "/var/log/localdb-mcp.db")
+	if cfg, err = LoadFrom(path); err != nil || cfg.AuditDB() != "/var/log/localdb-mcp.db" {
+		t.Errorf("%s should override audit_db: %v", EnvAuditDB, err)
+	}
+}
+
+func TestLoadFrom_permissions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	write := func(body string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, env := range []string{EnvPostgresURI, EnvSQLServerURI, EnvSQLiteURI, EnvMySQLURI} {
+		t.Setenv(env, "")
+	}
+	write(`
+connections:
+  mysql: "root@tcp(localhost:3306)/app"
+  sqlite: ":memory:"
+permissions:
+  mysql:
+    allow: [select, insert]
+    tables: ["*_test"]
+`)
+	cfg, err := LoadFrom(path)
+	if err != nil {
+		t.Fatalf("LoadFrom: %v", err)
+	}
+	tests := []struct {
+		id, op, schema, table string
+		want                  bool
+	}{
+		{"mysql", OpSelect, "", "", true},
+		{"mysql", OpInsert, "", "", true},
+		{"mysql", OpInsert, "", "Orders_TEST", true},
+		{"mysql", OpInsert, "app", "orders_test", true},
+		{"mysql", OpInsert, "", "orders", false},
+		{"mysql", OpUpdate, "", "orders_test", false},
+		{"mysql", OpExport, "", "", false},
+		{"sqlite", OpImport, "", "", true},
+		{"sqlite", OpUpdate, "", "orders", true},
+	}
+	for _, tt := range tests {
+		if got := cfg.Permits(tt.id, tt.op, tt.schema, tt.table); got != tt.want {
+			t.Errorf("Permits(%s, %s, %q, %q) = %v, want %v", tt.id, tt.op, tt.schema, tt.table, got, tt.want)
+		}
+	}
+
+	for _, body := range []string{
+		"connections: {sqlite: \":memory:\"}\npermissions: {sqlite: {allow: [delete]}}\n",
+		"connections: {sqlite: \":memory:\"}\npermissions: {sqlite: {allow: [insert], tables: [\"[\"]}}\n",
+		"connections: {sqlite: \":memory:\"}\npermissions: {mysql: {allow: [select]}}\n",
+	} {
+		write(body)
+		if _, err := LoadFrom(path); err == nil {
+			t.Errorf("expected an error for %q", body)
+		}
+	}
+}
+
+func TestLoadFrom_writeSchemas(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	for _, env := range []string{EnvPostgresURI, EnvSQLServerURI, EnvSQLiteURI, EnvMySQLURI} {
+		t.Setenv(env, "")
+	}
+	for body, wantErr := range map[string]bool{
+		"connections: {postgres: \"postgres://localhost/app\"}\nwrite_schemas: {postgres: [test]}\n": false,
+		"connections: {postgres: \"postgres://localhost/app\"}\nwrite_schemas: {mysql: [test]}\n":    true,
+		"connections: {postgres: \"postgres://localhost/app\"}\nwrite_schemas: {postgres: []}\n":     true,
+		"connections: {sqlite: \":memory:\"}\nwrite_schemas: {sqlite: [main]}\n":                     true,
+	} {
+		if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := LoadFrom(path)
+		if (err != nil) != wantErr {
+			t.Errorf("%q: err = %v, want error %v", body, err, wantErr)
+		}
+		if err == nil && !slices.Equal(cfg.WriteSchemas("postgres"), []string{"test"}) {
+			t.Errorf("WriteSchemas(postgres) = %v", cfg.WriteSchemas("postgres"))
+		}
+	}
+}
+
+func TestLoadFrom_connectionRateLimits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	for _, env := range []string{EnvPostgresURI, EnvSQLServerURI, EnvSQLiteURI, EnvMySQLURI} {
+		t.Setenv(env, "")
+	}
+	for body, wantErr := range map[string]bool{
+		"connections: {sqlite: \":memory:\"}\nrate_limits_by_connection: {sqlite: {write: {rate: 0.5}}}\n": false,
+		"connections: {sqlite: \":memory:\"}\nrate_limits_by_connection: {mysql: {write: {rate: 0.5}}}\n":  true,
+		"connections: {sqlite: \":memory:\"}\nrate_limits_by_connection: {sqlite: {bulk: {rate: 0.5}}}\n":  true,
+	} {
+		if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := LoadFrom(path)
+		if (err != nil) != wantErr {
+			t.Errorf("%q: err = %v, want error %v", body, err, wantErr)
+		}
+		if err != nil {
+			continue
+		}
+		if got := cfg.ConnectionRateLimits()["sqlite"][ToolClassWrite]; got != (RateLimit{Rate: 0.5, Burst: 1}) {
+			t.Errorf("sqlite write limit = %+v, want rate 0.5 burst 1", got)
+		}
+		if got := cfg.RateLimits()[ToolClassWrite]; got != DefaultRateLimits[ToolClassWrite] {
+			t.Errorf("global write limit = %+v, want default", got)
+		}
+	}
+}
+
+func TestLoadFrom_rowFilters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	for _, env := range []string{EnvPostgresURI, EnvSQLServerURI, EnvSQLiteURI, EnvMySQLURI} {
+		t.Setenv(env, "")
+	}
+	for body, wantErr := range map[string]bool{
+		"connections: {sqlite: \":memory:\"}\nrow_filters: {sqlite: {orders: \"tenant_id = 42\"}}\n":           false,
+		"connections: {sqlite: \":memory:\"}\nrow_filters: {mysql: {orders: \"tenant_id = 42\"}}\n":            true,
+		"connections: {sqlite: \":memory:\"}\nrow_filters: {sqlite: {orders: \"\"}}\n":                         true,
+		"connections: {sqlite: \":memory:\"}\nrow_filters: {sqlite: {orders: \"tenant_id = 42; DELETE x\"}}\n": true,
+		"connections: {sqlite: \":memory:\"}\nrow_filters: {sqlite: {orders: \"tenant_id = 42 --\"}}\n":        true,
+	} {
+		if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := LoadFrom(path)
+		if (err != nil) != wantErr {
+			t.Errorf("%q: err = %v, want error %v", body, err, wantErr)
+		}
+		if err == nil && cfg.RowFilters("sqlite")["orders"] != "tenant_id = 42" {
+			t.Errorf("RowFilters(sqlite) = %v", cfg.RowFilters("sqlite"))
+		}
+	}
+}
+
+func TestLoadFrom_schemaLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	for _, env := range []string{EnvPostgresURI, EnvSQLServerURI, EnvSQLiteURI, EnvMySQLURI} {
+		t.Setenv(env, "")
+	}
+	const conns = "connections: {postgres: \"postgres://localhost/app\", sqlite: \":memory:\"}\n"
+	for body, wantErr := range map[string]bool{
+		conns + "default_schemas: {postgres: app}\nschema_lock: [postgres]\n": false,
+		conns + "default_schemas: {mysql: app}\n":                             true,
+		conns + "default_schemas: {sqlite: main}\n":                           true,
+		conns + "schema_lock: [postgres]\n":                                   true,
+		conns + "default_schemas: {postgres: app}\nschema_lock: [mysql]\n":    true,
+	} {
+		if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := LoadFrom(path)
+		if (err != nil) != wantErr {
+			t.Errorf("%q: err = %v, want error %v", body, err, wantErr)
+		}
+		if err == nil && (cfg.DefaultSchema("postgres") != "app" || !cfg.SchemaLocked("postgres") || cfg.SchemaLocked("sqlite")) {
+			t.Errorf("DefaultSchema = %q, SchemaLocked = %v", cfg.DefaultSchema("postgres"), cfg.SchemaLocked("postgres"))
+		}
+	}
+}
+
+func TestLoadFile_policies(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ConfigFileName)
+	c := &Config{connections: make(map[string]connectionEntry)}
+	if err := os.WriteFile(path, []byte(`
+environment: staging
+policies:
+  - {tool: "*_test_row", table: orders, action: confirm}
+  - {connection: "prod*", action: deny, reason: read the replica instead}
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.loadFile(path); err != nil {
+		t.Fatalf("loadFile: %v", err)
+	}
+	if c.Environment() != "staging" || len(c.Policies()) != 2 || c.Policies()[1].Reason != "read the replica instead" {
+		t.Errorf("environment %q, policies %+v", c.Environment(), c.Policies())
+	}
+	for _, body := range []string{
+		"policies: [{tool: run_query, action: block}]\n",
+		"policies: [{tool: run_query}]\n",
+		"policies: [{table: \"[\", action: deny}]\n",
+	} {
+		if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.loadFile(path); err == nil {
+			t.Errorf("%q: expected an error", body)
+		}
+	}
+}
+
+func TestLoadFile_functionPatterns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ConfigFileName)
+	c := &Config{connections: make(map[string]connectionEntry)}
+	if err := os.WriteFile(path, []byte("denied_functions: [\"my_*\"]\nallowed_functions: [dblink]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.loadFile(path); err != nil {
+		t.Fatalf("loadFile: %v", err)
+	}
+	if len(c.DeniedFunctions()) != 1 || c.AllowedFunctions()[0] != "dblink" {
+		t.Errorf("denied %v, allowed %v", c.DeniedFunctions(), c.AllowedFunctions())
+	}
+	if err := os.WriteFile(path, []byte("denied_functions: [\"[\"]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.loadFile(path); err == nil {
+		t.Error("bad pattern: expected an error")
+	}
+}
+
+func TestLoadFrom_sessionSchemas(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(`
+connections:
+  sqlite: ":memory:"
+  pg: "postgres://localhost/app"
+session_schemas: [pg]
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadFrom(path)
+	if err != nil {
+		t.Fatalf("LoadFrom: %v", err)
+	}
+	if !cfg.SessionSchemas("pg") || cfg.SessionSchemas("sqlite") || !cfg.AnySessionSchemas() {
+		t.Errorf("SessionSchemas: pg=%v sqlite=%v", cfg.SessionSchemas("pg"), cfg.SessionSchemas("sqlite"))
+	}
+
+	if err := os.WriteFile(path, []byte("session_schemas: [missing]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFrom(path); err == nil {
+		t.Error("expected error for an unknown connection in session_schemas")
+	}
+}
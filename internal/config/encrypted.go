@@ -0,0 +1,106 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvConfigKey holds the age identities (AGE-SECRET-KEY-1..., one per line)
+// that decrypt an encrypted config file. EnvConfigKeyFile names a file
+// holding them instead, as age-keygen writes it. Without either, the key is
+// looked up in the OS keychain under KeychainAccount.
+const (
+	EnvConfigKey     = "MCP_CONFIG_KEY"
+	EnvConfigKeyFile = "MCP_CONFIG_KEY_FILE"
+)
+
+// EnvConfigPassphrase is the passphrase of a config file encrypted with
+// age -p.
+const EnvConfigPassphrase = "MCP_CONFIG_PASSPHRASE"
+
+// decryptConfig returns the plaintext of a config file: data itself, or
+// for a file encrypted with age (binary or armored) or sops, the decrypted
+// YAML. Encrypted files are decrypted in memory only.
+func decryptConfig(path string, data []byte) ([]byte, error) {
+	switch {
+	case isAge(data):
+		ids, err := configIdentities()
+		if err != nil {
+			return nil, err
+		}
+		passphrase := os.Getenv(EnvConfigPassphrase)
+		if len(ids) == 0 && passphrase == "" {
+			return nil, fmt.Errorf("the file is encrypted with age; set %s, %s or %s, or store the key in the keychain (service %q, account %q)",
+				EnvConfigKey, EnvConfigKeyFile, EnvConfigPassphrase, KeychainService, KeychainAccount)
+		}
+		plain, err := ageDecrypt(data, ids, passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("decrypt: %w", err)
+		}
+		return plain, nil
+	case isSops(data):
+		return sopsDecrypt(path)
+	}
+	return data, nil
+}
+
+// configIdentities returns the age identities from EnvConfigKey,
+// EnvConfigKeyFile or the keychain, in that order of preference.
+func configIdentities() ([]string, error) {
+	if v := os.Getenv(EnvConfigKey); v != "" {
+		return ageIdentities(v), nil
+	}
+	if p := os.Getenv(EnvConfigKeyFile); p != "" {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", EnvConfigKeyFile, err)
+		}
+		return ageIdentities(string(data)), nil
+	}
+	key, err := keychain.Get(KeychainAccount)
+	if err != nil {
+		return nil, err
+	}
+	return ageIdentities(key), nil
+}
+
+// isSops reports whether data is a YAML file encrypted with sops, which
+// keeps its metadata under a top-level sops key.
+func isSops(data []byte) bool {
+	var doc struct {
+		Sops struct {
+			MAC string `yaml:"mac"`
+		} `yaml:"sops"`
+	}
+	return yaml.Unmarshal(data, &doc) == nil && doc.Sops.MAC != ""
+}
+
+// sopsDecrypt decrypts a sops-encrypted YAML file with the sops CLI, which
+// finds its keys (age, PGP, cloud KMS) as usual. An age key configured for
+// localdb-mcp is passed on as SOPS_AGE_KEY.
+func sopsDecrypt(path string) ([]byte, error) {
+	p, err := exec.LookPath("sops")
+	if err != nil {
+		return nil, errors.New("the file is encrypted with sops, which is not installed or not in PATH")
+	}
+	cmd := exec.Command(p, "--decrypt", "--input-type", "yaml", "--output-type", "yaml", path)
+	cmd.Env = os.Environ()
+	if os.Getenv("SOPS_AGE_KEY") == "" && os.Getenv("SOPS_AGE_KEY_FILE") == "" {
+		if ids, err := configIdentities(); err == nil && len(ids) > 0 {
+			cmd.Env = append(cmd.Env, "SOPS_AGE_KEY="+strings.Join(ids, "\n"))
+		}
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("sops --decrypt failed: %s", strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
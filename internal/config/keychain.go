@@ -0,0 +1,163 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+)
+
+// KeychainService is the service the server's secrets are stored under in
+// the OS keychain: the macOS login keychain, or the Secret Service (GNOME
+// Keyring, KWallet) on Linux. KeychainAccount is the account of the age key
+// of an encrypted config file.
+const (
+	KeychainService = "localdb-mcp"
+	KeychainAccount = "config-key"
+)
+
+// KeychainRefPrefix starts a config value stored in the keychain instead:
+// a connection URI or auth token of keychain:<account>, in config.yaml or
+// the environment, is replaced by the secret stored for that account when
+// the config is loaded. localdb-mcp secure writes such references.
+const KeychainRefPrefix = "keychain:"
+
+// keychainTimeout bounds a keychain call, which may prompt to unlock.
+const keychainTimeout = 30 * time.Second
+
+// secretStore stores secrets by account under KeychainService.
+type secretStore interface {
+	// Get returns the secret of account, or "" if there is none.
+	Get(account string) (string, error)
+	Set(account, secret string) error
+}
+
+// keychain is the store keychain: references are resolved from; tests
+// replace it.
+var keychain secretStore = osKeychain{}
+
+// osKeychain is the OS keychain, through its command-line tools:
+//
+//	security find-generic-password / add-generic-password    (macOS)
+//	secret-tool lookup / store                               (Linux, BSD)
+//
+// Without a keychain tool, Get finds nothing and Set fails.
+type osKeychain struct{}
+
+func (osKeychain) Get(account string) (string, error) {
+	var name string
+	var args []string
+	switch runtime.GOOS {
+	case "darwin":
+		name, args = "security", []string{"find-generic-password", "-s", KeychainService, "-a", account, "-w"}
+	case "linux", "freebsd", "openbsd":
+		name, args = "secret-tool", []string{"lookup", "service", KeychainService, "account", account}
+	default:
+		return "", nil
+	}
+	p, err := exec.LookPath(name)
+	if err != nil {
+		return "", nil
+	}
+	out, msg, err := runKeychainTool(p, nil, args...)
+	if err != nil {
+		// Both tools fail when there is no such item: security with a
+		// message, secret-tool silently.
+		if msg == "" || strings.Contains(msg, "could not be found") {
+			return "", nil
+		}
+		return "", fmt.Errorf("keychain: %s: %s", name, msg)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// Set stores secret for account, replacing any secret stored before. On
+// macOS security takes the secret as an argument, so it is briefly visible
+// to other processes of the same user; secret-tool reads it from stdin.
+func (osKeychain) Set(account, secret string) error {
+	var name string
+	var args []string
+	var stdin []byte
+	switch runtime.GOOS {
+	case "darwin":
+		name, args = "security", []string{"add-generic-password", "-U", "-s", KeychainService, "-a", account, "-w", secret}
+	case "linux", "freebsd", "openbsd":
+		name, args = "secret-tool", []string{"store", "--label", KeychainService + " " + account, "service", KeychainService, "account", account}
+		stdin = []byte(secret)
+	default:
+		return fmt.Errorf("keychain: not supported on %s", runtime.GOOS)
+	}
+	p, err := exec.LookPath(name)
+	if err != nil {
+		return fmt.Errorf("keychain: %s is not installed or not in PATH", name)
+	}
+	if _, msg, err := runKeychainTool(p, stdin, args...); err != nil {
+		return fmt.Errorf("keychain: %s: %s", name, msg)
+	}
+	return nil
+}
+
+// runKeychainTool runs a keychain tool, returning its stdout and trimmed
+// stderr. The arguments are never logged: they may hold the secret.
+func runKeychainTool(path string, stdin []byte, args ...string) (string, string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), keychainTimeout)
+	defer cancel()
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, args...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err := cmd.Run()
+	return stdout.String(), strings.TrimSpace(stderr.String()), err
+}
+
+// resolveSecret returns v, or the secret v refers to if it is a keychain:
+// reference.
+func resolveSecret(v string) (string, error) {
+	account, ok := strings.CutPrefix(v, KeychainRefPrefix)
+	if !ok {
+		return v, nil
+	}
+	if account == "" {
+		return "", errors.New("empty keychain account")
+	}
+	secret, err := keychain.Get(account)
+	if err != nil {
+		return "", err
+	}
+	if secret == "" {
+		return "", fmt.Errorf("no secret in the keychain for service %q, account %q", KeychainService, account)
+	}
+	return secret, nil
+}
+
+// resolveSecrets replaces the keychain: references among the connection
+// URIs and the auth token by their secrets.
+func (c *Config) resolveSecrets() error {
+	for id, e := range c.connections {
+		uri, err := resolveSecret(e.uri)
+		if err != nil {
+			return fmt.Errorf("connection %q: %w", id, err)
+		}
+		e.uri = uri
+		e.fallbacks = slices.Clone(e.fallbacks)
+		for i, f := range e.fallbacks {
+			if e.fallbacks[i], err = resolveSecret(f); err != nil {
+				return fmt.Errorf("connection %q: %w", id, err)
+			}
+		}
+		c.connections[id] = e
+	}
+	token, err := resolveSecret(c.authToken)
+	if err != nil {
+		return fmt.Errorf("auth_token: %w", err)
+	}
+	c.authToken = token
+	return nil
+}
@@ -0,0 +1,278 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// secureEnvVars are the variables of a .env file Secure moves to the
+// keychain.
+var secureEnvVars = []string{EnvPostgresURI, EnvSQLServerURI, EnvMySQLURI, EnvAuthToken}
+
+// SecuredSecret is one credential Secure moved: where it was and the
+// keychain account it is stored under now.
+type SecuredSecret struct {
+	File    string // config.yaml or .env the value was in
+	Key     string // connections.<id>[<n>], auth_token or the variable name
+	Account string
+}
+
+// Secure moves the plaintext credentials of a config file and a .env file
+// into the OS keychain: connection URIs (other than SQLite paths) and the
+// auth token. Each is stored under its own account, read back to check it
+// was stored, and replaced in its file by a keychain:<account> reference;
+// the rewritten file replaces the old one atomically, and the old one is
+// then overwritten with random bytes, so the plaintext does not linger in
+// its blocks (on most file systems; copy-on-write file systems and SSDs
+// may keep old blocks regardless).
+//
+// configPath and envPath may be "" or name files that do not exist, which
+// are skipped; an encrypted config file is refused. With dryRun set, Secure
+// only reports what it would move.
+func Secure(configPath, envPath string, dryRun bool) ([]SecuredSecret, error) {
+	var moved []SecuredSecret
+	type rewrite struct {
+		path string
+		data []byte
+	}
+	var rewrites []rewrite
+	secrets := make(map[string]string) // account -> secret
+
+	if data, err := readIfExists(configPath); err != nil {
+		return nil, err
+	} else if data != nil {
+		if isAge(data) || isSops(data) {
+			return nil, fmt.Errorf("%s is encrypted; decrypt it first or leave it as it is", configPath)
+		}
+		out, found, err := secureConfigFile(data, secrets)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", configPath, err)
+		}
+		for _, s := range found {
+			s.File = configPath
+			moved = append(moved, s)
+		}
+		if len(found) > 0 {
+			rewrites = append(rewrites, rewrite{configPath, out})
+		}
+	}
+	if data, err := readIfExists(envPath); err != nil {
+		return nil, err
+	} else if data != nil {
+		out, found, err := secureEnvFile(data, secrets)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", envPath, err)
+		}
+		for _, s := range found {
+			s.File = envPath
+			moved = append(moved, s)
+		}
+		if len(found) > 0 {
+			rewrites = append(rewrites, rewrite{envPath, out})
+		}
+	}
+	if dryRun {
+		return moved, nil
+	}
+
+	// Every secret is in the keychain before any file loses it.
+	for _, s := range moved {
+		if err := keychain.Set(s.Account, secrets[s.Account]); err != nil {
+			return nil, err
+		}
+		if got, err := keychain.Get(s.Account); err != nil || got != secrets[s.Account] {
+			return nil, fmt.Errorf("keychain: account %q did not read back as stored (%v); no file was changed", s.Account, err)
+		}
+	}
+	for _, r := range rewrites {
+		if err := shredAndWrite(r.path, r.data); err != nil {
+			return nil, fmt.Errorf("%s: %w", r.path, err)
+		}
+	}
+	return moved, nil
+}
+
+func readIfExists(path string) ([]byte, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// secureConfigFile replaces the credentials in config file data by keychain
+// references, keeping its comments and layout as far as yaml.v3 does. The
+// secrets found are added to secrets by account.
+func secureConfigFile(data []byte, secrets map[string]string) ([]byte, []SecuredSecret, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, err
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return data, nil, nil
+	}
+	var found []SecuredSecret
+	move := func(n *yaml.Node, key, account string) {
+		if n.Kind != yaml.ScalarNode || n.Value == "" || strings.HasPrefix(n.Value, KeychainRefPrefix) {
+			return
+		}
+		secrets[account] = n.Value
+		n.Value, n.Style, n.Tag = KeychainRefPrefix+account, 0, "!!str"
+		found = append(found, SecuredSecret{Key: key, Account: account})
+	}
+	root := doc.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		switch key, value := root.Content[i].Value, root.Content[i+1]; key {
+		case "auth_token":
+			move(value, key, "auth-token")
+		case "connections":
+			for j := 0; j+1 < len(value.Content); j += 2 {
+				id, uris := value.Content[j].Value, value.Content[j+1]
+				if typ := idToType(id); typ == "sqlite" || typ == DemoConnectionID {
+					continue // file paths, not credentials
+				}
+				if uris.Kind == yaml.SequenceNode {
+					for k, n := range uris.Content {
+						move(n, fmt.Sprintf("connections.%s[%d]", id, k), fmt.Sprintf("connection/%s/%d", id, k))
+					}
+				} else {
+					move(uris, "connections."+id, "connection/"+id)
+				}
+			}
+		}
+	}
+	if len(found) == 0 {
+		return data, nil, nil
+	}
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, nil, err
+	}
+	return b.Bytes(), found, enc.Close()
+}
+
+// secureEnvFile replaces the credentials among the variables of .env file
+// data by keychain references, leaving every other line as it is. A
+// variable may be written with a shell export prefix, which is kept.
+func secureEnvFile(data []byte, secrets map[string]string) ([]byte, []SecuredSecret, error) {
+	var found []SecuredSecret
+	var b bytes.Buffer
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := sc.Text()
+		key, val, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		key, export := cutEnvExport(key)
+		val = strings.Trim(strings.TrimSpace(val), `"'`)
+		if ok && val != "" && !strings.HasPrefix(val, KeychainRefPrefix) && slices.Contains(secureEnvVars, key) {
+			account := "env/" + key
+			secrets[account] = val
+			found = append(found, SecuredSecret{Key: key, Account: account})
+			line = key + "=" + KeychainRefPrefix + account
+			if export {
+				line = "export " + line
+			}
+		}
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	if err := sc.Err(); err != nil {
+		return nil, nil, err
+	}
+	return b.Bytes(), found, nil
+}
+
+// shredAndWrite replaces the file at path with data without a moment in
+// which it is missing or half written: data goes to a temporary file in the
+// same directory, which is flushed to disk and renamed over path. Only then
+// is the old copy, kept under a second name until the rename, overwritten
+// with random bytes, flushed and removed.
+func shredAndWrite(path string, data []byte) error {
+	path = filepath.Clean(path)
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	dir, base := filepath.Split(path)
+	tmp, err := os.CreateTemp(dir, "."+base+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := writeSynced(tmp, info.Mode().Perm(), data); err != nil {
+		return err
+	}
+	old, err := keepOldCopy(dir, base, path)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		if rerr := os.Rename(old, path); rerr != nil {
+			return fmt.Errorf("%w; the old contents are in %s", err, old)
+		}
+		os.Remove(old)
+		return err
+	}
+	f, err := os.OpenFile(old, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	noise := make([]byte, info.Size())
+	rand.Read(noise)
+	err = writeSynced(f, 0, noise)
+	if rerr := os.Remove(old); err == nil {
+		err = rerr
+	}
+	return err
+}
+
+// writeSynced writes data at the start of f, flushes it to disk and closes
+// f. A non-zero perm is set as f's permissions first.
+func writeSynced(f *os.File, perm os.FileMode, data []byte) error {
+	defer f.Close()
+	if perm != 0 {
+		if err := f.Chmod(perm); err != nil {
+			return err
+		}
+	}
+	if _, err := f.WriteAt(data, 0); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// keepOldCopy gives the file at path, base in dir, a second name the old
+// contents stay reachable by once path is replaced: a hard link, or where
+// the file system has none, the file itself moved aside.
+func keepOldCopy(dir, base, path string) (string, error) {
+	f, err := os.CreateTemp(dir, "."+base+".*.old")
+	if err != nil {
+		return "", err
+	}
+	old := f.Name()
+	f.Close()
+	if err := os.Remove(old); err != nil {
+		return "", err
+	}
+	if err := os.Link(path, old); err == nil {
+		return old, nil
+	}
+	return old, os.Rename(path, old)
+}
@@ -0,0 +1,103 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// schemaQueries list the user schemas of each connection type that has
+// them (databases for MySQL), leaving out the system ones.
+var schemaQueries = map[string]string{
+	"postgres": `SELECT schema_name AS schema_name FROM information_schema.schemata
+		WHERE schema_name NOT IN ('pg_catalog', 'information_schema', 'pg_toast')
+		AND schema_name NOT LIKE 'pg\_temp\_%' AND schema_name NOT LIKE 'pg\_toast\_temp\_%'
+		ORDER BY schema_name`,
+	"mysql": `SELECT schema_name AS schema_name FROM information_schema.schemata
+		WHERE schema_name NOT IN ('mysql', 'information_schema', 'performance_schema', 'sys')
+		ORDER BY schema_name`,
+	"sqlserver": `SELECT name AS schema_name FROM sys.schemas
+		WHERE name NOT IN ('sys', 'INFORMATION_SCHEMA', 'guest') AND name NOT LIKE 'db[_]%'
+		ORDER BY name`,
+}
+
+// ListSchemas returns the user schemas of d, a driver of connection type
+// typ, sorted by name; nil for types without schemas (SQLite, the demo).
+func ListSchemas(ctx context.Context, d Driver, typ string) ([]string, error) {
+	q, ok := schemaQueries[typ]
+	if !ok {
+		return nil, nil
+	}
+	rows, err := d.RunReadOnlyQuery(ctx, q, nil)
+	if err != nil {
+		return nil, err
+	}
+	schemas := make([]string, 0, len(rows))
+	for _, r := range rows {
+		switch v := r["schema_name"].(type) {
+		case string:
+			schemas = append(schemas, v)
+		case []byte:
+			schemas = append(schemas, string(v))
+		}
+	}
+	return schemas, nil
+}
+
+// SampleQuery returns a statement selecting the first n rows of table, in
+// the dialect of connection type typ. schema may be empty for the
+// connection's default.
+func SampleQuery(typ, schema, table string, n int) string {
+	return OrderedQuery(typ, schema, table, nil, n)
+}
+
+// OrderedQuery is SampleQuery with the rows ordered by the columns orderBy,
+// if any.
+func OrderedQuery(typ, schema, table string, orderBy []string, n int) string {
+	quote, name := quoteFor(typ), quoteTableFor(typ, schema, table)
+	var order string
+	if len(orderBy) > 0 {
+		cols := make([]string, len(orderBy))
+		for i, c := range orderBy {
+			cols[i] = quote(c)
+		}
+		order = " ORDER BY " + strings.Join(cols, ", ")
+	}
+	if typ == "sqlserver" {
+		return fmt.Sprintf("SELECT TOP (%d) * FROM %s%s", n, name, order)
+	}
+	return fmt.Sprintf("SELECT * FROM %s%s LIMIT %d", name, order, n)
+}
+
+// quoteFor returns the identifier quoting of connection type typ.
+func quoteFor(typ string) func(string) string {
+	switch typ {
+	case "sqlserver":
+		return quoteMSSQLIdentifier
+	case "mysql":
+		return quoteMySQLIdentifier
+	case "postgres":
+		return quotePGIdentifier
+	}
+	return quoteSQLiteIdentifier
+}
+
+// quoteTableFor returns table, qualified by schema if it is not empty, as
+// connection type typ quotes it.
+func quoteTableFor(typ, schema, table string) string {
+	switch typ {
+	case "sqlserver":
+		if schema == "" {
+			return quoteMSSQLIdentifier(table)
+		}
+		return quoteMSSQLTable(schema, table)
+	case "mysql":
+		return quoteMySQLTable(schema, table)
+	case "postgres":
+		if schema == "" {
+			return quotePGIdentifier(table)
+		}
+		return quotePGIdentifier(schema) + "." + quotePGIdentifier(table)
+	}
+	return quoteSQLiteTable(schema, table)
+}
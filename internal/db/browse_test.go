@@ -0,0 +1,35 @@
+package db
+
+import "testing"
+
+func TestSampleQuery(t *testing.T) {
+	for _, tt := range []struct {
+		typ, schema, table, want string
+	}{
+		{"postgres", "public", "users", `SELECT * FROM "public"."users" LIMIT 5`},
+		{"postgres", "", "users", `SELECT * FROM "users" LIMIT 5`},
+		{"mysql", "shop", "orders", "SELECT * FROM `shop`.`orders` LIMIT 5"},
+		{"sqlserver", "dbo", "orders", "SELECT TOP (5) * FROM [dbo].[orders]"},
+		{"sqlserver", "", "orders", "SELECT TOP (5) * FROM [orders]"},
+		{"sqlite", "", `we"ird`, `SELECT * FROM "we""ird" LIMIT 5`},
+	} {
+		if got := SampleQuery(tt.typ, tt.schema, tt.table, 5); got != tt.want {
+			t.Errorf("SampleQuery(%q, %q, %q) = %s, want %s", tt.typ, tt.schema, tt.table, got, tt.want)
+		}
+	}
+}
+
+func TestOrderedQuery(t *testing.T) {
+	for _, tt := range []struct {
+		typ, want string
+	}{
+		{"postgres", `SELECT * FROM "public"."order_items" ORDER BY "order_id", "line" LIMIT 5`},
+		{"mysql", "SELECT * FROM `public`.`order_items` ORDER BY `order_id`, `line` LIMIT 5"},
+		{"sqlserver", "SELECT TOP (5) * FROM [public].[order_items] ORDER BY [order_id], [line]"},
+		{"sqlite", `SELECT * FROM "public"."order_items" ORDER BY "order_id", "line" LIMIT 5`},
+	} {
+		if got := OrderedQuery(tt.typ, "public", "order_items", []string{"order_id", "line"}, 5); got != tt.want {
+			t.Errorf("OrderedQuery(%q) = %s, want %s", tt.typ, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package db
+
+// Capabilities describes what a database backend supports, so tools can be
+// offered and SQL shaped per backend without checking connection types.
+type Capabilities struct {
+	// Schemas reports whether tables are namespaced by a schema (or, for
+	// MySQL, a database) that tools accept as their schema argument.
+	Schemas bool `json:"schemas"`
+	// Returning reports whether INSERT ... RETURNING is supported.
+	Returning bool `json:"returning"`
+	// Transactions reports whether statements can be grouped in a
+	// transaction.
+	Transactions bool `json:"transactions"`
+	// ReadOnlySessions reports whether the database itself can enforce that
+	// a session or transaction only reads.
+	ReadOnlySessions bool `json:"read_only_sessions"`
+	// Export reports whether the driver implements Exporter.
+	Export bool `json:"export"`
+}
+
+// capabilities by connection type.
+var capabilities = map[string]Capabilities{
+	"postgres":  {Schemas: true, Returning: true, Transactions: true, ReadOnlySessions: true, Export: true},
+	"sqlserver": {Schemas: true, Transactions: true, Export: true},
+	"sqlite":    {Returning: true, Transactions: true, ReadOnlySessions: true, Export: true},
+	"mysql":     {Schemas: true, Transactions: true, ReadOnlySessions: true, Export: true},
+	"demo":      {Returning: true, Transactions: true, ReadOnlySessions: true},
+}
+
+// CapabilitiesFor returns the capabilities of connection type typ without
+// connecting. ok is false for an unsupported type.
+func CapabilitiesFor(typ string) (caps Capabilities, ok bool) {
+	caps, ok = capabilities[typ]
+	return caps, ok
+}
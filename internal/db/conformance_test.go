@@ -0,0 +1,41 @@
+package db_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
+	"github.com/SedlarDavid/localdb-mcp/internal/db/dbtest"
+)
+
+func TestSQLite_conformance(t *testing.T) {
+	dbtest.Run(t, dbtest.Backend{Type: "sqlite", Open: openSQLiteFixture})
+}
+
+// openSQLiteFixture returns a driver on a new database file seeded with the
+// SQLite fixture.
+func openSQLiteFixture(t *testing.T) db.Driver {
+	t.Helper()
+	fixture, err := dbtest.Fixture("sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "conformance.db")
+	seed, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = seed.Exec(fixture)
+	seed.Close()
+	if err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	d, err := db.NewSQLiteDriver(context.Background(), path, db.ConnectOptions{})
+	if err != nil {
+		t.Fatalf("NewSQLiteDriver: %v", err)
+	}
+	t.Cleanup(func() { d.Close() })
+	return d
+}
@@ -0,0 +1,405 @@
+// Package dbtest is a conformance suite for db.Driver implementations: the
+// behaviour every backend must share, so tools work the same whatever the
+// connection type. Run it against a database seeded with the backend's
+// Fixture:
+//
+//	func TestConformance(t *testing.T) {
+//		dbtest.Run(t, dbtest.Backend{Type: "postgres", Open: openSeededPostgres, Schema: "public", Other: "inventory"})
+//	}
+//
+// A new backend adds its fixture to fixtures/, with the same tables and rows
+// as the others, and a Run of its own.
+package dbtest
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
+)
+
+//go:embed fixtures/*.sql
+var fixtures embed.FS
+
+// Fixture returns the SQL script that seeds a database of connection type
+// typ for the suite. SQL Server's separates batches with GO lines, for
+// sqlcmd.
+func Fixture(typ string) (string, error) {
+	b, err := fixtures.ReadFile("fixtures/" + typ + ".sql")
+	if err != nil {
+		return "", fmt.Errorf("no fixture for %s", typ)
+	}
+	return string(b), nil
+}
+
+// Backend is a database the suite runs against.
+type Backend struct {
+	// Type is the connection type, e.g. "postgres".
+	Type string
+	// Open returns a driver on a database seeded with Fixture(Type). Each
+	// case opens its own; the suite does not close it. Rows the cases
+	// write carry unique values, so the database may be shared between
+	// runs.
+	Open func(t *testing.T) db.Driver
+	// Schema names the schema the fixture's tables are in, for backends
+	// where calls may name it; Other the one holding inventory.products
+	// (a database on MySQL). Both are empty for backends without schemas.
+	Schema, Other string
+}
+
+// cases are the conformance checks, each run on a driver of its own.
+var cases = []struct {
+	name string
+	run  func(t *testing.T, b Backend, d db.Driver)
+}{
+	{"Ping", testPing},
+	{"Capabilities", testCapabilities},
+	{"ListTables", testListTables},
+	{"DescribeTable", testDescribeTable},
+	{"DescribeTable/unknown", testDescribeUnknown},
+	{"ForeignKeys", testForeignKeys},
+	{"Indexes", testIndexes},
+	{"RunReadOnlyQuery", testRunReadOnlyQuery},
+	{"RunReadOnlyQuery/placeholders", testPlaceholders},
+	{"RunReadOnlyQuery/types", testQueryTypes},
+	{"InsertRow", testInsertRow},
+	{"InsertRow/invalid", testInsertInvalid},
+	{"UpdateRow", testUpdateRow},
+	{"UpdateRow/compositeKey", testUpdateCompositeKey},
+	{"UpdateRow/noop", testUpdateNoop},
+	{"UpdateRow/invalid", testUpdateInvalid},
+}
+
+// Run runs the conformance suite against b, a subtest per case.
+func Run(t *testing.T, b Backend) {
+	t.Helper()
+	if _, err := Fixture(b.Type); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.run(t, b, b.Open(t))
+		})
+	}
+}
+
+func testPing(t *testing.T, _ Backend, d db.Driver) {
+	if err := d.Ping(context.Background()); err != nil {
+		t.Errorf("Ping: %v", err)
+	}
+}
+
+func testCapabilities(t *testing.T, b Backend, d db.Driver) {
+	want, ok := db.CapabilitiesFor(b.Type)
+	if !ok {
+		t.Fatalf("no capabilities for %s", b.Type)
+	}
+	if got := d.Capabilities(); got != want {
+		t.Errorf("Capabilities = %+v, want %+v as CapabilitiesFor reports", got, want)
+	}
+	if got := d.Capabilities().Schemas; got != (b.Other != "") {
+		t.Errorf("Capabilities.Schemas = %t, but the backend has another schema: %q", got, b.Other)
+	}
+}
+
+func testListTables(t *testing.T, b Backend, d db.Driver) {
+	ctx := context.Background()
+	schemas := []string{""}
+	if b.Schema != "" {
+		schemas = append(schemas, b.Schema)
+	}
+	for _, schema := range schemas {
+		tables, err := d.ListTables(ctx, schema)
+		if err != nil {
+			t.Fatalf("ListTables(%q): %v", schema, err)
+		}
+		for _, want := range []string{"customers", "events", "kinds", "notes", "order_items", "orders"} {
+			if !slices.Contains(tables, want) {
+				t.Errorf("ListTables(%q) = %v, lacks %s", schema, tables, want)
+			}
+		}
+		if slices.Contains(tables, "products") {
+			t.Errorf("ListTables(%q) = %v, has products of another schema", schema, tables)
+		}
+		if !slices.IsSorted(tables) {
+			t.Errorf("ListTables(%q) = %v, not sorted by name", schema, tables)
+		}
+	}
+	if b.Other != "" {
+		if tables, err := d.ListTables(ctx, b.Other); err != nil || !slices.Equal(tables, []string{"products"}) {
+			t.Errorf("ListTables(%q) = %v, %v; want [products]", b.Other, tables, err)
+		}
+	}
+}
+
+func testDescribeTable(t *testing.T, b Backend, d db.Driver) {
+	tests := []struct {
+		schema, table string
+		want          []string
+	}{
+		{"", "customers", []string{"id pk", "name", "email", "city null"}},
+		{b.Schema, "customers", []string{"id pk", "name", "email", "city null"}},
+		{"", "order_items", []string{"order_id pk", "line pk", "sku", "quantity"}},
+		{"", "events", []string{"at", "kind"}},
+		{"", "kinds", []string{"id pk", "flag", "amount null", "created null", "payload null", "raw null", "uid null"}},
+	}
+	if b.Other != "" {
+		tests = append(tests, struct {
+			schema, table string
+			want          []string
+		}{b.Other, "products", []string{"sku pk", "title", "price null"}})
+	}
+	for _, tt := range tests {
+		cols, err := d.DescribeTable(context.Background(), tt.schema, tt.table)
+		if err != nil {
+			t.Errorf("DescribeTable(%q, %q): %v", tt.schema, tt.table, err)
+			continue
+		}
+		if got := Columns(cols); !slices.Equal(got, tt.want) {
+			t.Errorf("DescribeTable(%q, %q) = %v, want %v", tt.schema, tt.table, got, tt.want)
+		}
+		for _, c := range cols {
+			if c.Type == "" {
+				t.Errorf("DescribeTable(%q, %q): column %s has no type", tt.schema, tt.table, c.Name)
+			}
+		}
+	}
+}
+
+func testDescribeUnknown(t *testing.T, _ Backend, d db.Driver) {
+	cols, err := d.DescribeTable(context.Background(), "", "no_such_table")
+	if err == nil && len(cols) > 0 {
+		t.Errorf("DescribeTable of a missing table = %v", cols)
+	}
+}
+
+func testForeignKeys(t *testing.T, _ Backend, d db.Driver) {
+	l, ok := db.Unwrap(d).(db.ForeignKeyLister)
+	if !ok {
+		t.Skip("driver does not list foreign keys")
+	}
+	fks, err := l.ForeignKeys(context.Background(), "")
+	if err != nil {
+		t.Fatalf("ForeignKeys: %v", err)
+	}
+	var got []string
+	for _, fk := range fks {
+		got = append(got, fmt.Sprintf("%s%v -> %s%v", fk.Table, fk.Columns, fk.RefTable, fk.RefColumns))
+	}
+	want := []string{"order_items[order_id] -> orders[id]", "orders[customer_id] -> customers[id]"}
+	if !slices.Equal(got, want) {
+		t.Errorf("ForeignKeys = %v, want %v", got, want)
+	}
+}
+
+func testIndexes(t *testing.T, _ Backend, d db.Driver) {
+	l, ok := db.Unwrap(d).(db.IndexLister)
+	if !ok {
+		t.Skip("driver does not list indexes")
+	}
+	idxs, err := l.Indexes(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Indexes: %v", err)
+	}
+	// Names of primary and unique keys differ between engines.
+	var got []string
+	for _, ix := range idxs {
+		if ix.Table == "events" {
+			t.Errorf("Indexes has %+v on events, which has none", ix)
+		}
+		got = append(got, fmt.Sprintf("%s%v unique=%t primary=%t", ix.Table, ix.Columns, ix.Unique, ix.Primary))
+	}
+	for _, want := range []string{
+		"customers[id] unique=true primary=true",
+		"customers[email] unique=true primary=false",
+		"order_items[order_id line] unique=true primary=true",
+		"orders[placed_at] unique=false primary=false",
+	} {
+		if !slices.Contains(got, want) {
+			t.Errorf("Indexes = %v, lacks %s", got, want)
+		}
+	}
+	if !slices.IsSortedFunc(idxs, func(a, b db.Index) int {
+		if a.Table != b.Table {
+			return strings.Compare(a.Table, b.Table)
+		}
+		return strings.Compare(a.Name, b.Name)
+	}) {
+		t.Errorf("Indexes = %v, not sorted by table and name", got)
+	}
+}
+
+func testRunReadOnlyQuery(t *testing.T, _ Backend, d db.Driver) {
+	ctx := context.Background()
+	rows, err := d.RunReadOnlyQuery(ctx, "SELECT name, city FROM customers WHERE id = $1 OR email = $2 ORDER BY id", []any{2, "linus@example.com"})
+	if err != nil {
+		t.Fatalf("RunReadOnlyQuery: %v", err)
+	}
+	if len(rows) != 2 || rows[0]["name"] != "Grace Example" || rows[0]["city"] != "Paris" || rows[1]["city"] != nil {
+		t.Errorf("rows = %v, want Grace Example in Paris and Linus Example with a NULL city", rows)
+	}
+	rows, err = d.RunReadOnlyQuery(ctx, "SELECT COUNT(*) AS n FROM order_items", nil)
+	if err != nil || len(rows) != 1 || fmt.Sprint(rows[0]["n"]) != "3" {
+		t.Errorf("COUNT(*) of order_items = %v, %v; want 3", rows, err)
+	}
+	rows, err = d.RunReadOnlyQuery(ctx, "SELECT name FROM customers WHERE id = $1", []any{-1})
+	if err != nil || len(rows) != 0 {
+		t.Errorf("query matching nothing = %v, %v; want no rows", rows, err)
+	}
+	if _, err := d.RunReadOnlyQuery(ctx, "SELECT no_such_column FROM customers", nil); err == nil {
+		t.Error("query of an unknown column succeeded")
+	}
+}
+
+func testPlaceholders(t *testing.T, _ Backend, d db.Driver) {
+	// Ten parameters, so $1 is not taken for the start of $10.
+	params := make([]any, 10)
+	for i := range params {
+		params[i] = i + 1
+	}
+	rows, err := d.RunReadOnlyQuery(context.Background(),
+		"SELECT COUNT(*) AS n FROM customers WHERE id IN ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)", params)
+	if err != nil || len(rows) != 1 || fmt.Sprint(rows[0]["n"]) != "3" {
+		t.Errorf("query with $1 to $10 = %v, %v; want 3", rows, err)
+	}
+}
+
+func testQueryTypes(t *testing.T, _ Backend, d db.Driver) {
+	rows, err := d.RunReadOnlyQuery(context.Background(), "SELECT raw, uid FROM kinds WHERE id = $1", []any{1})
+	if err != nil || len(rows) != 1 {
+		t.Fatalf("kinds = %v, %v", rows, err)
+	}
+	if raw, ok := rows[0]["raw"].([]byte); !ok || !slices.Equal(raw, []byte{0x00, 0xff}) {
+		t.Errorf("binary column = %#v, want bytes 00 ff", rows[0]["raw"])
+	}
+	if rows[0]["uid"] == nil {
+		t.Error("uuid column is NULL")
+	}
+}
+
+func testInsertRow(t *testing.T, _ Backend, d db.Driver) {
+	ctx := context.Background()
+	body := unique(t)
+	id, err := d.InsertRow(ctx, "", "notes", map[string]any{"body": body})
+	if err != nil {
+		t.Fatalf("InsertRow: %v", err)
+	}
+	if id == nil {
+		t.Fatal("InsertRow returned no ID for an identity column")
+	}
+	rows, err := d.RunReadOnlyQuery(ctx, "SELECT body FROM notes WHERE id = $1", []any{id})
+	if err != nil || len(rows) != 1 || rows[0]["body"] != body {
+		t.Errorf("inserted row %v = %v, %v", id, rows, err)
+	}
+}
+
+func testInsertInvalid(t *testing.T, _ Backend, d db.Driver) {
+	ctx := context.Background()
+	for _, tt := range []struct {
+		name  string
+		table string
+		row   map[string]any
+	}{
+		{"no columns", "notes", map[string]any{}},
+		{"unknown column", "notes", map[string]any{"nope": 1}},
+		{"unknown table", "no_such_table", map[string]any{"body": "x"}},
+	} {
+		if _, err := d.InsertRow(ctx, "", tt.table, tt.row); !errors.Is(err, db.ErrInvalidInput) {
+			t.Errorf("InsertRow with %s: %v, want invalid input", tt.name, err)
+		}
+	}
+}
+
+func testUpdateRow(t *testing.T, _ Backend, d db.Driver) {
+	ctx := context.Background()
+	body := unique(t)
+	id, err := d.InsertRow(ctx, "", "notes", map[string]any{"body": body})
+	if err != nil {
+		t.Fatalf("InsertRow: %v", err)
+	}
+	n, err := d.UpdateRow(ctx, "", "notes", map[string]any{"id": id}, map[string]any{"body": body + " updated"})
+	if err != nil || n != 1 {
+		t.Fatalf("UpdateRow = %d, %v; want 1", n, err)
+	}
+	rows, err := d.RunReadOnlyQuery(ctx, "SELECT body FROM notes WHERE id = $1", []any{id})
+	if err != nil || len(rows) != 1 || rows[0]["body"] != body+" updated" {
+		t.Errorf("updated row = %v, %v", rows, err)
+	}
+}
+
+func testUpdateCompositeKey(t *testing.T, _ Backend, d db.Driver) {
+	ctx := context.Background()
+	// Order 1 has two lines: only the one named by both key columns changes.
+	key := map[string]any{"order_id": 1, "line": 2}
+	quantity := time.Now().Nanosecond()%1000 + 10
+	if _, err := d.UpdateRow(ctx, "", "order_items", key, map[string]any{"quantity": quantity}); err != nil {
+		t.Fatalf("UpdateRow: %v", err)
+	}
+	rows, err := d.RunReadOnlyQuery(ctx, "SELECT line, quantity FROM order_items WHERE order_id = $1 ORDER BY line", []any{1})
+	if err != nil || len(rows) != 2 {
+		t.Fatalf("lines of order 1 = %v, %v", rows, err)
+	}
+	if fmt.Sprint(rows[0]["quantity"]) != "2" || fmt.Sprint(rows[1]["quantity"]) != fmt.Sprint(quantity) {
+		t.Errorf("lines of order 1 = %v, want line 1 left at 2 and line 2 set to %d", rows, quantity)
+	}
+	if _, err := d.UpdateRow(ctx, "", "order_items", map[string]any{"order_id": 1}, map[string]any{"quantity": 1}); !errors.Is(err, db.ErrInvalidInput) {
+		t.Errorf("UpdateRow keyed by part of the primary key: %v, want invalid input", err)
+	}
+}
+
+func testUpdateNoop(t *testing.T, _ Backend, d db.Driver) {
+	// Setting the values a row already has is not a missing row, although
+	// MySQL reports no rows changed.
+	n, err := d.UpdateRow(context.Background(), "", "customers", map[string]any{"id": 1}, map[string]any{"city": "London"})
+	if err != nil || n > 1 {
+		t.Errorf("no-op UpdateRow = %d, %v; want no error", n, err)
+	}
+}
+
+func testUpdateInvalid(t *testing.T, _ Backend, d db.Driver) {
+	ctx := context.Background()
+	for _, tt := range []struct {
+		name     string
+		table    string
+		key, set map[string]any
+		want     error
+	}{
+		{"a missing row", "notes", map[string]any{"id": -1}, map[string]any{"body": "x"}, db.ErrNotFound},
+		{"a non-key column as key", "notes", map[string]any{"body": "x"}, map[string]any{"body": "y"}, db.ErrInvalidInput},
+		{"no key", "notes", map[string]any{}, map[string]any{"body": "x"}, db.ErrInvalidInput},
+		{"nothing to set", "notes", map[string]any{"id": 1}, map[string]any{}, db.ErrInvalidInput},
+		{"an unknown column to set", "customers", map[string]any{"id": 1}, map[string]any{"nope": 1}, db.ErrInvalidInput},
+		{"a table without primary key", "events", map[string]any{"kind": "signup"}, map[string]any{"kind": "x"}, db.ErrInvalidInput},
+	} {
+		if _, err := d.UpdateRow(ctx, "", tt.table, tt.key, tt.set); !errors.Is(err, tt.want) {
+			t.Errorf("UpdateRow of %s: %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+// Columns returns cols as "name", with " pk" for primary key columns and
+// " null" for nullable ones, for comparing descriptions.
+func Columns(cols []db.ColumnInfo) []string {
+	out := make([]string, len(cols))
+	for i, c := range cols {
+		out[i] = c.Name
+		if c.IsPK {
+			out[i] += " pk"
+		}
+		if c.Nullable {
+			out[i] += " null"
+		}
+	}
+	return out
+}
+
+// unique returns a value no earlier run has written.
+func unique(t *testing.T) string {
+	return fmt.Sprintf("%s %d", t.Name(), time.Now().UnixNano())
+}
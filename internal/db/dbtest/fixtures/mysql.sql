@@ -0,0 +1,81 @@
+-- Conformance fixture for MySQL, also the seed of the integration server,
+-- run in the localdb database. The fixtures of the other engines hold the
+-- same tables and rows; keep them in step. MySQL has no schemas inside a
+-- database, so inventory is a database.
+
+CREATE TABLE customers (
+	id int AUTO_INCREMENT PRIMARY KEY,
+	name varchar(200) NOT NULL,
+	email varchar(200) NOT NULL UNIQUE,
+	city varchar(200)
+);
+
+CREATE TABLE orders (
+	id int AUTO_INCREMENT PRIMARY KEY,
+	customer_id int NOT NULL,
+	total decimal(10, 2) NOT NULL,
+	placed_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (customer_id) REFERENCES customers (id)
+);
+
+CREATE INDEX orders_placed_at ON orders (placed_at);
+
+CREATE TABLE order_items (
+	order_id int NOT NULL,
+	line int NOT NULL,
+	sku varchar(40) NOT NULL,
+	quantity int NOT NULL,
+	PRIMARY KEY (order_id, line),
+	FOREIGN KEY (order_id) REFERENCES orders (id)
+);
+
+-- Written by the tests.
+CREATE TABLE notes (
+	id int AUTO_INCREMENT PRIMARY KEY,
+	body text NOT NULL
+);
+
+-- Types beyond text and integers.
+CREATE TABLE kinds (
+	id int PRIMARY KEY,
+	flag boolean NOT NULL,
+	amount decimal(12, 4),
+	created datetime(6),
+	payload json,
+	raw varbinary(16),
+	uid char(36)
+);
+
+-- No primary key, so no row can be updated.
+CREATE TABLE events (
+	at datetime NOT NULL,
+	kind varchar(40) NOT NULL
+);
+
+CREATE DATABASE inventory;
+
+CREATE TABLE inventory.products (
+	sku varchar(40) PRIMARY KEY,
+	title varchar(200) NOT NULL,
+	price decimal(10, 2)
+);
+
+INSERT INTO customers (name, email, city) VALUES
+	('Ada Example', 'ada@example.com', 'London'),
+	('Grace Example', 'grace@example.com', 'Paris'),
+	('Linus Example', 'linus@example.com', NULL);
+
+INSERT INTO orders (customer_id, total) VALUES (1, 19.99), (1, 5.00), (2, 42.50);
+
+INSERT INTO order_items (order_id, line, sku, quantity) VALUES
+	(1, 1, 'SKU-1', 2),
+	(1, 2, 'SKU-2', 1),
+	(3, 1, 'SKU-1', 5);
+
+INSERT INTO kinds VALUES (1, true, 12.5, '2024-05-01 12:00:00', '{"a": 1}', X'00FF', '6f1c2b4e-8a3d-4c5e-9f60-7a8b9c0d1e2f');
+
+INSERT INTO events VALUES ('2024-05-01 12:00:00', 'signup');
+
+INSERT INTO inventory.products (sku, title, price) VALUES
+	('SKU-1', 'Widget', 9.99),
+	('SKU-2', 'Gadget', NULL);
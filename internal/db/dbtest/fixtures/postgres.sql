@@ -0,0 +1,78 @@
+-- Conformance fixture for PostgreSQL, also the seed of the integration
+-- server. The fixtures of the other engines hold the same tables and rows;
+-- keep them in step.
+
+CREATE TABLE customers (
+	id serial PRIMARY KEY,
+	name text NOT NULL,
+	email varchar(200) NOT NULL UNIQUE,
+	city text
+);
+
+CREATE TABLE orders (
+	id serial PRIMARY KEY,
+	customer_id integer NOT NULL REFERENCES customers (id),
+	total numeric(10, 2) NOT NULL,
+	placed_at timestamp NOT NULL DEFAULT now()
+);
+
+CREATE INDEX orders_placed_at ON orders (placed_at);
+
+CREATE TABLE order_items (
+	order_id integer NOT NULL REFERENCES orders (id),
+	line integer NOT NULL,
+	sku varchar(40) NOT NULL,
+	quantity integer NOT NULL,
+	PRIMARY KEY (order_id, line)
+);
+
+-- Written by the tests.
+CREATE TABLE notes (
+	id serial PRIMARY KEY,
+	body text NOT NULL
+);
+
+-- Types beyond text and integers.
+CREATE TABLE kinds (
+	id integer PRIMARY KEY,
+	flag boolean NOT NULL,
+	amount numeric(12, 4),
+	created timestamptz,
+	payload jsonb,
+	raw bytea,
+	uid uuid
+);
+
+-- No primary key, so no row can be updated.
+CREATE TABLE events (
+	at timestamp NOT NULL,
+	kind text NOT NULL
+);
+
+CREATE SCHEMA inventory;
+
+CREATE TABLE inventory.products (
+	sku varchar(40) PRIMARY KEY,
+	title text NOT NULL,
+	price numeric(10, 2)
+);
+
+INSERT INTO customers (name, email, city) VALUES
+	('Ada Example', 'ada@example.com', 'London'),
+	('Grace Example', 'grace@example.com', 'Paris'),
+	('Linus Example', 'linus@example.com', NULL);
+
+INSERT INTO orders (customer_id, total) VALUES (1, 19.99), (1, 5.00), (2, 42.50);
+
+INSERT INTO order_items (order_id, line, sku, quantity) VALUES
+	(1, 1, 'SKU-1', 2),
+	(1, 2, 'SKU-2', 1),
+	(3, 1, 'SKU-1', 5);
+
+INSERT INTO kinds VALUES (1, true, 12.5, '2024-05-01 12:00:00+00', '{"a": 1}', '\x00ff', '6f1c2b4e-8a3d-4c5e-9f60-7a8b9c0d1e2f');
+
+INSERT INTO events VALUES ('2024-05-01 12:00:00', 'signup');
+
+INSERT INTO inventory.products (sku, title, price) VALUES
+	('SKU-1', 'Widget', 9.99),
+	('SKU-2', 'Gadget', NULL);
@@ -0,0 +1,66 @@
+-- Conformance fixture for SQLite. The fixtures of the other engines hold the
+-- same tables and rows; keep them in step. SQLite has no schemas, so there
+-- is no inventory.products.
+
+CREATE TABLE customers (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	email TEXT NOT NULL UNIQUE,
+	city TEXT
+);
+
+CREATE TABLE orders (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	customer_id INTEGER NOT NULL REFERENCES customers (id),
+	total NUMERIC NOT NULL,
+	placed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX orders_placed_at ON orders (placed_at);
+
+CREATE TABLE order_items (
+	order_id INTEGER NOT NULL REFERENCES orders (id),
+	line INTEGER NOT NULL,
+	sku TEXT NOT NULL,
+	quantity INTEGER NOT NULL,
+	PRIMARY KEY (order_id, line)
+);
+
+-- Written by the tests.
+CREATE TABLE notes (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	body TEXT NOT NULL
+);
+
+-- Types beyond text and integers.
+CREATE TABLE kinds (
+	id INTEGER PRIMARY KEY,
+	flag BOOLEAN NOT NULL,
+	amount NUMERIC,
+	created TIMESTAMP,
+	payload JSON,
+	raw BLOB,
+	uid TEXT
+);
+
+-- No primary key, so no row can be updated.
+CREATE TABLE events (
+	at TIMESTAMP NOT NULL,
+	kind TEXT NOT NULL
+);
+
+INSERT INTO customers (name, email, city) VALUES
+	('Ada Example', 'ada@example.com', 'London'),
+	('Grace Example', 'grace@example.com', 'Paris'),
+	('Linus Example', 'linus@example.com', NULL);
+
+INSERT INTO orders (customer_id, total) VALUES (1, 19.99), (1, 5.00), (2, 42.50);
+
+INSERT INTO order_items (order_id, line, sku, quantity) VALUES
+	(1, 1, 'SKU-1', 2),
+	(1, 2, 'SKU-2', 1),
+	(3, 1, 'SKU-1', 5);
+
+INSERT INTO kinds VALUES (1, 1, 12.5, '2024-05-01 12:00:00', '{"a": 1}', X'00FF', '6f1c2b4e-8a3d-4c5e-9f60-7a8b9c0d1e2f');
+
+INSERT INTO events VALUES ('2024-05-01 12:00:00', 'signup');
@@ -0,0 +1,87 @@
+-- Conformance fixture for SQL Server, also the seed of the integration
+-- server, run with sqlcmd. The fixtures of the other engines hold the same
+-- tables and rows; keep them in step.
+
+CREATE DATABASE localdb;
+GO
+
+USE localdb;
+GO
+
+CREATE TABLE customers (
+	id int IDENTITY(1, 1) PRIMARY KEY,
+	name nvarchar(200) NOT NULL,
+	email nvarchar(200) NOT NULL UNIQUE,
+	city nvarchar(200)
+);
+
+CREATE TABLE orders (
+	id int IDENTITY(1, 1) PRIMARY KEY,
+	customer_id int NOT NULL REFERENCES customers (id),
+	total decimal(10, 2) NOT NULL,
+	placed_at datetime2 NOT NULL DEFAULT SYSUTCDATETIME()
+);
+
+CREATE INDEX orders_placed_at ON orders (placed_at);
+
+CREATE TABLE order_items (
+	order_id int NOT NULL REFERENCES orders (id),
+	line int NOT NULL,
+	sku nvarchar(40) NOT NULL,
+	quantity int NOT NULL,
+	PRIMARY KEY (order_id, line)
+);
+
+-- Written by the tests.
+CREATE TABLE notes (
+	id int IDENTITY(1, 1) PRIMARY KEY,
+	body nvarchar(max) NOT NULL
+);
+
+-- Types beyond text and integers.
+CREATE TABLE kinds (
+	id int PRIMARY KEY,
+	flag bit NOT NULL,
+	amount decimal(12, 4),
+	created datetimeoffset,
+	payload nvarchar(max),
+	raw varbinary(16),
+	uid uniqueidentifier
+);
+
+-- No primary key, so no row can be updated.
+CREATE TABLE events (
+	at datetime2 NOT NULL,
+	kind nvarchar(40) NOT NULL
+);
+GO
+
+CREATE SCHEMA inventory;
+GO
+
+CREATE TABLE inventory.products (
+	sku nvarchar(40) PRIMARY KEY,
+	title nvarchar(200) NOT NULL,
+	price decimal(10, 2)
+);
+
+INSERT INTO customers (name, email, city) VALUES
+	('Ada Example', 'ada@example.com', 'London'),
+	('Grace Example', 'grace@example.com', 'Paris'),
+	('Linus Example', 'linus@example.com', NULL);
+
+INSERT INTO orders (customer_id, total) VALUES (1, 19.99), (1, 5.00), (2, 42.50);
+
+INSERT INTO order_items (order_id, line, sku, quantity) VALUES
+	(1, 1, 'SKU-1', 2),
+	(1, 2, 'SKU-2', 1),
+	(3, 1, 'SKU-1', 5);
+
+INSERT INTO kinds VALUES (1, 1, 12.5, '2024-05-01T12:00:00+00:00', N'{"a": 1}', 0x00FF, '6f1c2b4e-8a3d-4c5e-9f60-7a8b9c0d1e2f');
+
+INSERT INTO events VALUES ('2024-05-01T12:00:00', 'signup');
+
+INSERT INTO inventory.products (sku, title, price) VALUES
+	('SKU-1', 'Widget', 9.99),
+	('SKU-2', 'Gadget', NULL);
+GO
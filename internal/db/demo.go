@@ -0,0 +1,111 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// DemoDriver implements Driver for the "demo" connection type: an
+// in-memory SQLite database holding a small e-commerce dataset, so every
+// tool can be tried without a real database. Each driver starts from a
+// fresh copy; writes last until it is closed.
+type DemoDriver struct {
+	*SQLiteDriver
+}
+
+// demoSchema creates and fills the demo tables: customers place orders,
+// whose order_items reference products.
+const demoSchema = `
+CREATE TABLE customers (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	email TEXT NOT NULL UNIQUE,
+	city TEXT,
+	created_at TEXT NOT NULL
+);
+CREATE TABLE products (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	sku TEXT NOT NULL UNIQUE,
+	name TEXT NOT NULL,
+	category TEXT NOT NULL,
+	price_cents INTEGER NOT NULL,
+	stock INTEGER NOT NULL DEFAULT 0
+);
+CREATE TABLE orders (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	customer_id INTEGER NOT NULL REFERENCES customers(id),
+	status TEXT NOT NULL,
+	ordered_at TEXT NOT NULL
+);
+CREATE TABLE order_items (
+	order_id INTEGER NOT NULL REFERENCES orders(id),
+	product_id INTEGER NOT NULL REFERENCES products(id),
+	quantity INTEGER NOT NULL,
+	unit_price_cents INTEGER NOT NULL,
+	PRIMARY KEY (order_id, product_id)
+);
+
+INSERT INTO customers (name, email, city, created_at) VALUES
+	('Ada Example', 'ada@example.com', 'London', '2024-01-05T09:12:00Z'),
+	('Ben Sample', 'ben@example.com', 'Prague', '2024-02-11T14:30:00Z'),
+	('Chloe Test', 'chloe@example.com', 'Berlin', '2024-03-02T08:45:00Z'),
+	('Dev Null', 'dev@example.com', NULL, '2024-03-20T17:05:00Z'),
+	('Eva Mock', 'eva@example.com', 'Prague', '2024-04-01T11:00:00Z');
+
+INSERT INTO products (sku, name, category, price_cents, stock) VALUES
+	('MUG-01', 'Coffee mug', 'kitchen', 1200, 40),
+	('TEA-02', 'Green tea, 100 g', 'groceries', 850, 120),
+	('KBD-03', 'Mechanical keyboard', 'electronics', 8900, 7),
+	('LMP-04', 'Desk lamp', 'home', 3450, 0),
+	('NTB-05', 'Dotted notebook', 'stationery', 600, 250),
+	('PEN-06', 'Fountain pen', 'stationery', 2500, 18);
+
+INSERT INTO orders (customer_id, status, ordered_at) VALUES
+	(1, 'delivered', '2024-04-02T10:00:00Z'),
+	(1, 'shipped', '2024-05-14T16:20:00Z'),
+	(2, 'delivered', '2024-04-18T09:40:00Z'),
+	(3, 'cancelled', '2024-05-01T12:00:00Z'),
+	(5, 'pending', '2024-05-20T19:15:00Z'),
+	(2, 'pending', '2024-05-21T07:55:00Z');
+
+INSERT INTO order_items (order_id, product_id, quantity, unit_price_cents) VALUES
+	(1, 1, 2, 1200),
+	(1, 2, 1, 850),
+	(2, 3, 1, 8900),
+	(3, 5, 3, 600),
+	(3, 6, 1, 2500),
+	(4, 4, 1, 3450),
+	(5, 2, 4, 850),
+	(5, 1, 1, 1200),
+	(6, 6, 2, 2500),
+	(6, 5, 1, 600);
+`
+
+// NewDemoDriver opens a fresh demo database. With opts.ReadOnly the
+// dataset is loaded first and the connection then set to PRAGMA query_only.
+func NewDemoDriver(ctx context.Context, opts ConnectOptions) (*DemoDriver, error) {
+	d, err := NewSQLiteDriver(ctx, ":memory:", ConnectOptions{SchemaCacheTTL: opts.SchemaCacheTTL, Masker: opts.Masker})
+	if err != nil {
+		return nil, err
+	}
+	// The in-memory database has a single connection, so these apply to
+	// every later call.
+	if _, err := d.db.ExecContext(ctx, demoSchema); err != nil {
+		d.Close()
+		return nil, fmt.Errorf("demo: load dataset: %w", err)
+	}
+	if opts.ReadOnly {
+		if _, err := d.db.ExecContext(ctx, "PRAGMA query_only = 1"); err != nil {
+			d.Close()
+			return nil, fmt.Errorf("demo: %w", err)
+		}
+	}
+	return &DemoDriver{SQLiteDriver: d}, nil
+}
+
+// Capabilities implements Driver. The demo database cannot be exported:
+// the dump tools work on database files.
+func (d *DemoDriver) Capabilities() Capabilities {
+	caps, _ := CapabilitiesFor("demo")
+	return caps
+}
@@ -0,0 +1,58 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestDemoDriver(t *testing.T) {
+	ctx := context.Background()
+	d, err := NewDemoDriver(ctx, ConnectOptions{})
+	if err != nil {
+		t.Fatalf("NewDemoDriver: %v", err)
+	}
+	defer d.Close()
+
+	tables, err := d.ListTables(ctx, "")
+	if err != nil {
+		t.Fatalf("ListTables: %v", err)
+	}
+	for _, want := range []string{"customers", "order_items", "orders", "products"} {
+		if !slices.Contains(tables, want) {
+			t.Errorf("tables %v lack %s", tables, want)
+		}
+	}
+	rows, err := d.RunReadOnlyQuery(ctx, `SELECT c.name, SUM(i.quantity * i.unit_price_cents) AS total
+		FROM orders o JOIN customers c ON c.id = o.customer_id JOIN order_items i ON i.order_id = o.id
+		WHERE o.id = $1 GROUP BY c.name`, []any{1})
+	if err != nil {
+		t.Fatalf("RunReadOnlyQuery: %v", err)
+	}
+	if len(rows) != 1 || rows[0]["name"] != "Ada Example" || rows[0]["total"] != int64(3250) {
+		t.Errorf("order 1: %v", rows)
+	}
+	if _, err := d.UpdateRow(ctx, "", "order_items", map[string]any{"order_id": 1, "product_id": 1}, map[string]any{"quantity": 3}); err != nil {
+		t.Errorf("UpdateRow with a composite key: %v", err)
+	}
+	if d.Capabilities().Export {
+		t.Error("demo database should not offer export")
+	}
+
+	// Each driver starts from the original dataset.
+	ro, err := NewDemoDriver(ctx, ConnectOptions{ReadOnly: true})
+	if err != nil {
+		t.Fatalf("NewDemoDriver read-only: %v", err)
+	}
+	defer ro.Close()
+	rows, err = ro.RunReadOnlyQuery(ctx, "SELECT quantity FROM order_items WHERE order_id = 1 AND product_id = 1", nil)
+	if err != nil || len(rows) != 1 || rows[0]["quantity"] != int64(2) {
+		t.Errorf("fresh demo database: %v, %v", rows, err)
+	}
+	if _, err := ro.InsertRow(ctx, "", "customers", map[string]any{"name": "x", "email": "x@example.com", "created_at": "now"}); err == nil {
+		t.Error("insert into a read-only demo database succeeded")
+	} else if errors.Is(err, ErrInvalidInput) {
+		t.Errorf("insert refused by the server, not the database: %v", err)
+	}
+}
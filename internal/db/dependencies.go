@@ -0,0 +1,172 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"sort"
+)
+
+// ForeignKeyLister is an optional interface for drivers that can list the
+// foreign keys of a schema.
+type ForeignKeyLister interface {
+	// ForeignKeys returns the foreign keys declared on tables in schema
+	// (the connection's default schema if empty) that reference tables of
+	// the same schema, one entry per constraint.
+	ForeignKeys(ctx context.Context, schema string) ([]ForeignKey, error)
+}
+
+// ForeignKey describes a single foreign-key constraint. Columns and RefColumns
+// are parallel slices in constraint ordinal order. OnDelete and OnUpdate hold
+// the referential action (e.g. "CASCADE", "NO ACTION") when known.
+type ForeignKey struct {
+	Name       string   `json:"name"`
+	Table      string   `json:"table"`
+	Columns    []string `json:"columns"`
+	RefTable   string   `json:"ref_table"`
+	RefColumns []string `json:"ref_columns"`
+	OnDelete   string   `json:"on_delete,omitempty"`
+	OnUpdate   string   `json:"on_update,omitempty"`
+}
+
+// DependencyLister is an optional interface for drivers that can list the
+// objects depending on a table.
+type DependencyLister interface {
+	// Dependents returns the views, routines and triggers that depend on
+	// table of schema (the connection's default schema if empty), in any
+	// schema, ordered by kind, schema and name. Foreign keys are left to
+	// ForeignKeyLister.
+	Dependents(ctx context.Context, schema, table string) ([]Dependent, error)
+}
+
+// Kinds of Dependent.
+const (
+	DependentView             = "view"
+	DependentMaterializedView = "materialized view"
+	DependentFunction         = "function"
+	DependentProcedure        = "procedure"
+	DependentTrigger          = "trigger"
+)
+
+// Dependent is an object that depends on a table. Inferred is set if the
+// database records no dependency and the object was found by the table's
+// name in its definition, which may also match a column or another
+// schema's table of that name.
+type Dependent struct {
+	Kind     string `json:"kind"`
+	Schema   string `json:"schema,omitempty"`
+	Name     string `json:"name"`
+	Inferred bool   `json:"inferred,omitempty"`
+}
+
+// scanDependents reads rows of kind, schema, name and inferred.
+func scanDependents(rows *sql.Rows) ([]Dependent, error) {
+	defer rows.Close()
+	var deps []Dependent
+	for rows.Next() {
+		var dep Dependent
+		if err := rows.Scan(&dep.Kind, &dep.Schema, &dep.Name, &dep.Inferred); err != nil {
+			return nil, err
+		}
+		deps = append(deps, dep)
+	}
+	return deps, rows.Err()
+}
+
+// mentionsTable returns a regular expression matching the name of table
+// as a word, optionally quoted, in a definition.
+func mentionsTable(table string) *regexp.Regexp {
+	return regexp.MustCompile(`(?i)(^|[^\w$])["\x60\[]?` + regexp.QuoteMeta(table) + `["\x60\]]?([^\w$]|$)`)
+}
+
+// appendForeignKeyColumn adds the column pair col → refCol to the last
+// foreign key of fks if it is the constraint fk names, and appends fk with
+// that pair otherwise. Catalog queries return a row per column, ordered by
+// table, constraint and position.
+func appendForeignKeyColumn(fks []ForeignKey, fk ForeignKey, col, refCol string) []ForeignKey {
+	if n := len(fks); n > 0 && fks[n-1].Name == fk.Name && fks[n-1].Table == fk.Table {
+		fks[n-1].Columns = append(fks[n-1].Columns, col)
+		fks[n-1].RefColumns = append(fks[n-1].RefColumns, refCol)
+		return fks
+	}
+	fk.Columns, fk.RefColumns = []string{col}, []string{refCol}
+	return append(fks, fk)
+}
+
+// sortTablesByDependencies orders tables so that every table comes after the
+// tables it references through foreign keys, which is the order rows must be
+// inserted in when restoring a dump with constraints enabled. Ties are broken
+// alphabetically so the output is deterministic.
+//
+// Tables that take part in a reference cycle (including self-references)
+// cannot be ordered; they are appended at the end in alphabetical order and
+// cyclic is reported as true so the caller can disable constraint checking
+// around the data load instead.
+func sortTablesByDependencies(tables []string, fks []ForeignKey) (ordered []string, cyclic bool) {
+	known := make(map[string]bool, len(tables))
+	for _, t := range tables {
+		known[t] = true
+	}
+
+	// deps[t] = set of tables t references; dependents[r] = tables referencing r.
+	deps := make(map[string]map[string]bool, len(tables))
+	dependents := make(map[string][]string, len(tables))
+	for _, fk := range fks {
+		if !known[fk.Table] || !known[fk.RefTable] {
+			continue
+		}
+		if fk.Table == fk.RefTable {
+			cyclic = true
+			continue
+		}
+		if deps[fk.Table] == nil {
+			deps[fk.Table] = make(map[string]bool)
+		}
+		if deps[fk.Table][fk.RefTable] {
+			continue
+		}
+		deps[fk.Table][fk.RefTable] = true
+		dependents[fk.RefTable] = append(dependents[fk.RefTable], fk.Table)
+	}
+
+	var ready []string
+	for _, t := range tables {
+		if len(deps[t]) == 0 {
+			ready = append(ready, t)
+		}
+	}
+	sort.Strings(ready)
+
+	done := make(map[string]bool, len(tables))
+	for len(ready) > 0 {
+		t := ready[0]
+		ready = ready[1:]
+		ordered = append(ordered, t)
+		done[t] = true
+
+		var unlocked []string
+		for _, child := range dependents[t] {
+			delete(deps[child], t)
+			if len(deps[child]) == 0 && !done[child] {
+				unlocked = append(unlocked, child)
+			}
+		}
+		if len(unlocked) > 0 {
+			ready = append(ready, unlocked...)
+			sort.Strings(ready)
+		}
+	}
+
+	if len(ordered) < len(tables) {
+		cyclic = true
+		var rest []string
+		for _, t := range tables {
+			if !done[t] {
+				rest = append(rest, t)
+			}
+		}
+		sort.Strings(rest)
+		ordered = append(ordered, rest...)
+	}
+	return ordered, cyclic
+}
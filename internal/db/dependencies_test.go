@@ -0,0 +1,67 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSortTablesByDependencies(t *testing.T) {
+	tests := []struct {
+		name       string
+		tables     []string
+		fks        []ForeignKey
+		want       []string
+		wantCyclic bool
+	}{
+		{
+			name:   "no foreign keys keeps alphabetical order",
+			tables: []string{"a", "b", "c"},
+			want:   []string{"a", "b", "c"},
+		},
+		{
+			name:   "parents before children",
+			tables: []string{"order_items", "orders", "products", "users"},
+			fks: []ForeignKey{
+				{Table: "orders", RefTable: "users"},
+				{Table: "order_items", RefTable: "orders"},
+				{Table: "order_items", RefTable: "products"},
+			},
+			want: []string{"products", "users", "orders", "order_items"},
+		},
+		{
+			name:   "reference to unknown table is ignored",
+			tables: []string{"a", "b"},
+			fks:    []ForeignKey{{Table: "a", RefTable: "elsewhere"}},
+			want:   []string{"a", "b"},
+		},
+		{
+			name:       "self reference is reported as cyclic",
+			tables:     []string{"employees"},
+			fks:        []ForeignKey{{Table: "employees", RefTable: "employees"}},
+			want:       []string{"employees"},
+			wantCyclic: true,
+		},
+		{
+			name:   "cycle members are appended last",
+			tables: []string{"a", "b", "c", "root"},
+			fks: []ForeignKey{
+				{Table: "a", RefTable: "b"},
+				{Table: "b", RefTable: "a"},
+				{Table: "c", RefTable: "root"},
+			},
+			want:       []string{"root", "c", "a", "b"},
+			wantCyclic: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, cyclic := sortTablesByDependencies(tt.tables, tt.fks)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("order = %v, want %v", got, tt.want)
+			}
+			if cyclic != tt.wantCyclic {
+				t.Errorf("cyclic = %v, want %v", cyclic, tt.wantCyclic)
+			}
+		})
+	}
+}
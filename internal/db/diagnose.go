@@ -0,0 +1,127 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// diagnoseDialTimeout bounds the TCP probe of Diagnose.
+const diagnoseDialTimeout = 3 * time.Second
+
+// Diagnose looks for why a connection of type typ to uri fails, without
+// the driver: whether a sqlite file and its directory exist, or whether
+// anything accepts TCP connections at a server's host and port. It returns
+// a remediation hint naming at most the host and port or the file, never
+// credentials, or "" if it cannot tell.
+func Diagnose(ctx context.Context, typ, uri string) string {
+	if typ == "sqlite" {
+		return diagnoseSQLite(uri)
+	}
+	addr := serverAddr(typ, uri)
+	if addr == "" {
+		return ""
+	}
+	conn, err := (&net.Dialer{Timeout: diagnoseDialTimeout}).DialContext(ctx, "tcp", addr)
+	if err == nil {
+		conn.Close()
+		return fmt.Sprintf("%s accepts connections, so the server is up; check the user, password, database name and TLS settings in the URI", addr)
+	}
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &dnsErr):
+		return fmt.Sprintf("host %s does not resolve; check the host name in the URI", dnsErr.Name)
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return fmt.Sprintf("nothing is listening on %s; start the database server or fix the port in the URI", addr)
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return fmt.Sprintf("%s did not answer within %s; check the host, and any firewall or VPN in between", addr, diagnoseDialTimeout)
+	}
+	return fmt.Sprintf("cannot reach %s; check the host and port in the URI", addr)
+}
+
+func diagnoseSQLite(uri string) string {
+	path, err := sqliteFilePath(uri)
+	if err != nil {
+		return ""
+	}
+	if _, err := os.Stat(filepath.Dir(path)); err != nil {
+		return fmt.Sprintf("directory %s does not exist; create it or fix the path", filepath.Dir(path))
+	}
+	f, err := os.Open(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return fmt.Sprintf("%s does not exist; fix the path, or create the database with sqlite3", path)
+	case errors.Is(err, os.ErrPermission):
+		return fmt.Sprintf("%s is not readable; check its permissions", path)
+	case err != nil:
+		return ""
+	}
+	f.Close()
+	return fmt.Sprintf("%s exists; check that it is a SQLite database and not locked by another process", path)
+}
+
+// serverAddr returns the host:port a connection of type typ connects to,
+// or "" if uri does not say (a unix socket or an unparsable URI).
+func serverAddr(typ, uri string) string {
+	var host, port string
+	switch typ {
+	case "postgres":
+		host, port = postgresHostPort(uri)
+		if port == "" {
+			port = "5432"
+		}
+	case "mysql":
+		if !strings.Contains(uri, "tcp(") {
+			return ""
+		}
+		info, err := parseMySQLDSN(uri)
+		if err != nil {
+			return ""
+		}
+		host, port = info.Host, info.Port
+	case "sqlserver":
+		info, err := parseSQLServerURI(uri)
+		if err != nil {
+			return ""
+		}
+		host, port = info.Host, info.Port
+	}
+	if host == "" || strings.HasPrefix(host, "/") {
+		return ""
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// postgresHostPort returns the host and port of a postgres URL or
+// key=value connection string; the first of several hosts.
+func postgresHostPort(uri string) (host, port string) {
+	if strings.HasPrefix(uri, "postgres://") || strings.HasPrefix(uri, "postgresql://") {
+		u, err := url.Parse(uri)
+		if err != nil {
+			return "", ""
+		}
+		hostport, _, _ := strings.Cut(u.Host, ",")
+		if h, p, err := net.SplitHostPort(hostport); err == nil {
+			return h, p
+		}
+		return hostport, ""
+	}
+	for _, field := range strings.Fields(uri) {
+		k, v, _ := strings.Cut(field, "=")
+		switch k {
+		case "host":
+			host, _, _ = strings.Cut(v, ",")
+		case "port":
+			port, _, _ = strings.Cut(v, ",")
+		}
+	}
+	return host, port
+}
@@ -0,0 +1,57 @@
+package db
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestServerAddr(t *testing.T) {
+	for _, tc := range []struct{ typ, uri, want string }{
+		{"postgres", "postgres://u:p@db.local/app", "db.local:5432"},
+		{"postgres", "postgresql://u:p@h1:6432,h2:6433/app", "h1:6432"},
+		{"postgres", "host=db.local port=5433 user=u password=p dbname=app", "db.local:5433"},
+		{"postgres", "host=/var/run/postgresql dbname=app", ""},
+		{"mysql", "u:p@tcp(db.local:3307)/app", "db.local:3307"},
+		{"mysql", "u:p@unix(/tmp/mysql.sock)/app", ""},
+		{"sqlserver", "sqlserver://sa:p@db.local?database=app", "db.local:1433"},
+		{"demo", "demo", ""},
+	} {
+		if got := serverAddr(tc.typ, tc.uri); got != tc.want {
+			t.Errorf("serverAddr(%s, %s) = %q, want %q", tc.typ, tc.uri, got, tc.want)
+		}
+	}
+}
+
+func TestDiagnose(t *testing.T) {
+	ctx := context.Background()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	if got := Diagnose(ctx, "postgres", "postgres://u:secret@"+addr+"/app"); !strings.Contains(got, "accepts connections") || strings.Contains(got, "secret") {
+		t.Errorf("listening: %q", got)
+	}
+	ln.Close()
+	if got := Diagnose(ctx, "postgres", "postgres://u:secret@"+addr+"/app"); !strings.Contains(got, "nothing is listening on "+addr) {
+		t.Errorf("closed: %q", got)
+	}
+
+	dir := t.TempDir()
+	if got := Diagnose(ctx, "sqlite", filepath.Join(dir, "missing", "app.db")); !strings.Contains(got, "does not exist; create it") {
+		t.Errorf("missing dir: %q", got)
+	}
+	if got := Diagnose(ctx, "sqlite", filepath.Join(dir, "app.db")); !strings.Contains(got, "create the database") {
+		t.Errorf("missing file: %q", got)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "app.db"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if got := Diagnose(ctx, "sqlite", "file:"+filepath.Join(dir, "app.db")+"?mode=rw"); !strings.Contains(got, "exists") {
+		t.Errorf("existing file: %q", got)
+	}
+}
@@ -8,10 +8,17 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 )
 
 // Driver is the interface for database operations used by MCP tools.
 // Implementations are backend-specific (Postgres, SQL Server).
+//
+// When ctx ends, a call must stop its statement on the database server, not
+// just stop waiting for it: a cancelled tool call or timeout should not leave
+// a query running. SQLite (interrupt) and SQL Server (attention) do this in
+// the driver; PostgresDriver sends a cancel request and MySQLDriver a KILL
+// QUERY.
 type Driver interface {
 	// Ping verifies the connection is alive.
 	Ping(ctx context.Context) error
@@ -31,17 +38,82 @@ type Driver interface {
 	// The implementation must verify that key columns match the table's actual PK
 	// and return an error if they don't or if no row is found.
 	UpdateRow(ctx context.Context, schema, table string, key map[string]any, set map[string]any) (rowsAffected int64, err error)
+	// Capabilities reports what the backend supports.
+	Capabilities() Capabilities
 	// Close releases the connection. Caller should call once when done.
 	Close() error
 }
 
-// validatePKColumns fetches the real primary key columns of a table via
-// DescribeTable and verifies that the caller-provided key map matches them
+// checkColumns verifies, via DescribeTable, that table exists and has every
+// column named by the keys of fields, before they are quoted into a
+// statement, and returns its columns. op prefixes the error. Names must
+// match the catalog exactly, as the drivers quote them; a name that only
+// differs in case is suggested. If d caches metadata, a miss is checked
+// again against the catalog, so a column added since it was cached is found.
+func checkColumns(ctx context.Context, d tableDescriber, op, schema, table string, fields ...map[string]any) ([]ColumnInfo, error) {
+	cols, unknown, err := describeColumns(ctx, d, schema, table, fields)
+	if err == nil && (len(cols) == 0 || unknown != "") {
+		if inv, ok := d.(SchemaInvalidator); ok && inv.InvalidateSchema(schema, table) > 0 {
+			cols, unknown, err = describeColumns(ctx, d, schema, table, fields)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to describe table: %w", op, err)
+	}
+	name := table
+	if schema != "" {
+		name = schema + "." + table
+	}
+	if len(cols) == 0 {
+		return nil, classify(ErrInvalidInput, "%s: unknown table %q", op, name)
+	}
+	if unknown != "" {
+		names := make([]string, len(cols))
+		for i, c := range cols {
+			names[i] = c.Name
+			if strings.EqualFold(c.Name, unknown) {
+				return nil, classify(ErrInvalidInput, "%s: unknown column %q in table %q; did you mean %q?", op, unknown, name, c.Name)
+			}
+		}
+		return nil, classify(ErrInvalidInput, "%s: unknown column %q in table %q (columns: %s)", op, unknown, name, strings.Join(names, ", "))
+	}
+	return cols, nil
+}
+
+// describeColumns describes table and returns the first name in fields, in
+// sorted order, that is not one of its columns.
+func describeColumns(ctx context.Context, d tableDescriber, schema, table string, fields []map[string]any) (cols []ColumnInfo, unknown string, err error) {
+	cols, err = d.DescribeTable(ctx, schema, table)
+	if err != nil || len(cols) == 0 {
+		return cols, "", err
+	}
+	known := make(map[string]bool, len(cols))
+	for _, c := range cols {
+		known[c.Name] = true
+	}
+	var missing []string
+	for _, f := range fields {
+		for name := range f {
+			if !known[name] {
+				missing = append(missing, name)
+			}
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		unknown = missing[0]
+	}
+	return cols, unknown, nil
+}
+
+// validatePKColumns checks the columns of an update, including those of its
+// expected values (see WithExpected), with checkColumns and verifies that
+// the caller-provided key map matches the table's real primary key columns
 // exactly (same column names, no extra, no missing).
-func validatePKColumns(ctx context.Context, d Driver, schema, table string, key map[string]any) error {
-	cols, err := d.DescribeTable(ctx, schema, table)
+func validatePKColumns(ctx context.Context, d tableDescriber, schema, table string, key, set map[string]any) error {
+	cols, err := checkColumns(ctx, d, "update row", schema, table, key, set, expectedValues(ctx))
 	if err != nil {
-		return fmt.Errorf("update row: failed to describe table: %w", err)
+		return err
 	}
 
 	var pkCols []string
@@ -51,7 +123,7 @@ func validatePKColumns(ctx context.Context, d Driver, schema, table string, key
 		}
 	}
 	if len(pkCols) == 0 {
-		return fmt.Errorf("update row: table %q has no primary key; update_test_row requires one", table)
+		return classify(ErrInvalidInput, "update row: table %q has no primary key; update_test_row requires one", table)
 	}
 
 	// Collect provided key column names.
@@ -65,7 +137,7 @@ func validatePKColumns(ctx context.Context, d Driver, schema, table string, key
 	sort.Strings(provided)
 
 	if strings.Join(provided, ",") != strings.Join(pkCols, ",") {
-		return fmt.Errorf(
+		return classify(ErrInvalidInput,
 			"update row: key columns {%s} do not match primary key {%s}",
 			strings.Join(provided, ", "),
 			strings.Join(pkCols, ", "),
@@ -93,7 +165,8 @@ func rowExistsByPK(ctx context.Context, db sqlQueryer, table string, keyCols []s
 	return rows.Next(), rows.Err()
 }
 
-// sqlQueryer is the subset of *sql.DB needed by rowExistsByPK.
+// sqlQueryer is the subset of *sql.DB needed by rowExistsByPK; *sql.Tx
+// has it too.
 type sqlQueryer interface {
 	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
 }
@@ -102,13 +175,55 @@ type sqlQueryer interface {
 // database export and import via engine-native CLI tools.
 type Exporter interface {
 	// ExportDatabase dumps the database to the given file path using
-	// the engine-native CLI tool (pg_dump, mysqldump, sqlite3, sqlcmd).
-	ExportDatabase(ctx context.Context, path string) error
+	// the engine-native CLI tool (pg_dump, mysqldump, sqlite3) or, for
+	// SQL Server, SQL generated in Go.
+	ExportDatabase(ctx context.Context, path string, opts ExportOptions) error
 
 	// ImportDatabase loads a dump file into the database using the
 	// engine-native CLI tool (psql, mysql, sqlite3, sqlcmd).
 	// This is a destructive operation that may overwrite existing data.
-	ImportDatabase(ctx context.Context, path string) error
+	ImportDatabase(ctx context.Context, path string, opts ImportOptions) error
+}
+
+// WritePreviewer is an optional interface for drivers that can show the
+// statement InsertRow or UpdateRow would run, with its positional params,
+// without executing it.
+type WritePreviewer interface {
+	InsertSQL(schema, table string, row map[string]any) (query string, params []any)
+	UpdateSQL(schema, table string, key, set map[string]any) (query string, params []any)
+}
+
+// ConnectOptions adjust how a driver connects.
+type ConnectOptions struct {
+	// ReadOnly opens every session read-only where the database supports it
+	// (see Capabilities.ReadOnlySessions), so a statement that slips past the
+	// server's own checks still cannot write.
+	ReadOnly bool
+	// SchemaCacheTTL is how long DescribeTable results are reused; zero
+	// disables the cache.
+	SchemaCacheTTL time.Duration
+	// Masker masks sensitive columns in RunReadOnlyQuery results; nil masks
+	// nothing.
+	Masker *Masker
+}
+
+// PoolStatter is an optional interface for drivers backed by a connection
+// pool.
+type PoolStatter interface {
+	PoolStats() PoolStats
+}
+
+// PoolStats is a snapshot of a driver's connection pool.
+type PoolStats struct {
+	Open    int `json:"open"`     // connections open to the database
+	InUse   int `json:"in_use"`   // connections running a statement
+	Idle    int `json:"idle"`     // open connections waiting for work
+	MaxOpen int `json:"max_open"` // pool size limit; 0 means unlimited
+}
+
+// sqlPoolStats converts database/sql pool statistics.
+func sqlPoolStats(s sql.DBStats) PoolStats {
+	return PoolStats{Open: s.OpenConnections, InUse: s.InUse, Idle: s.Idle, MaxOpen: s.MaxOpenConnections}
 }
 
 // ColumnInfo describes one column for describe_table.
@@ -0,0 +1,73 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DuplicatesQuery returns a statement selecting the groups of rows of table
+// that share the values of columns, none of them NULL, in the dialect of
+// connection type typ: the values, named after their columns so masking
+// rules apply to them, and the number of rows as countColumn, the biggest
+// groups first, then by values; at most limit groups.
+func DuplicatesQuery(typ, schema, table string, columns []string, limit int) (query, countColumn string) {
+	quote := quoteFor(typ)
+	countColumn = freeAlias("duplicate_count", columns)
+	cols := make([]string, len(columns))
+	exprs := make([]string, len(columns))
+	for i, c := range columns {
+		cols[i] = quote(c)
+		exprs[i] = cols[i] + " AS " + cols[i]
+	}
+	top, limitClause := "", fmt.Sprintf(" LIMIT %d", limit)
+	if typ == "sqlserver" {
+		top, limitClause = fmt.Sprintf("TOP (%d) ", limit), ""
+	}
+	query = fmt.Sprintf("SELECT %s%s, COUNT(*) AS %s FROM %s%s ORDER BY %s DESC, %s%s",
+		top, strings.Join(exprs, ", "), quote(countColumn), quoteTableFor(typ, schema, table),
+		duplicateGroups(cols), quote(countColumn), strings.Join(cols, ", "), limitClause)
+	return query, countColumn
+}
+
+// DuplicatesSummaryQuery returns a statement counting the groups
+// DuplicatesQuery selects, as "groups", and the rows in them, as "rows".
+func DuplicatesSummaryQuery(typ, schema, table string, columns []string) string {
+	quote := quoteFor(typ)
+	cols := make([]string, len(columns))
+	for i, c := range columns {
+		cols[i] = quote(c)
+	}
+	return fmt.Sprintf("SELECT COUNT(*) AS %s, SUM(n) AS %s FROM (SELECT COUNT(*) AS n FROM %s%s) AS duplicates",
+		quote("groups"), quote("rows"), quoteTableFor(typ, schema, table), duplicateGroups(cols))
+}
+
+// duplicateGroups returns the clauses grouping the rows with no NULL in the
+// quoted columns cols by their values, keeping the groups of several rows.
+func duplicateGroups(cols []string) string {
+	conds := make([]string, len(cols))
+	for i, c := range cols {
+		conds[i] = c + " IS NOT NULL"
+	}
+	return fmt.Sprintf(" WHERE %s GROUP BY %s HAVING COUNT(*) > 1", strings.Join(conds, " AND "), strings.Join(cols, ", "))
+}
+
+// GroupKeysQuery returns a statement selecting the columns key of the
+// first limit rows of table, by key, whose columns equal the parameters
+// $1, $2, ... in order.
+func GroupKeysQuery(typ, schema, table string, columns, key []string, limit int) string {
+	quote := quoteFor(typ)
+	conds := make([]string, len(columns))
+	for i, c := range columns {
+		conds[i] = fmt.Sprintf("%s = $%d", quote(c), i+1)
+	}
+	keys := make([]string, len(key))
+	for i, c := range key {
+		keys[i] = quote(c)
+	}
+	top, limitClause := "", fmt.Sprintf(" LIMIT %d", limit)
+	if typ == "sqlserver" {
+		top, limitClause = fmt.Sprintf("TOP (%d) ", limit), ""
+	}
+	return fmt.Sprintf("SELECT %s%s FROM %s WHERE %s ORDER BY %s%s", top, strings.Join(keys, ", "),
+		quoteTableFor(typ, schema, table), strings.Join(conds, " AND "), strings.Join(keys, ", "), limitClause)
+}
@@ -0,0 +1,35 @@
+package db
+
+import "testing"
+
+func TestDuplicatesQuery(t *testing.T) {
+	for _, tt := range []struct {
+		typ, want string
+	}{
+		{"postgres", `SELECT "email" AS "email", "city" AS "city", COUNT(*) AS "duplicate_count" FROM "public"."users" ` +
+			`WHERE "email" IS NOT NULL AND "city" IS NOT NULL GROUP BY "email", "city" HAVING COUNT(*) > 1 ` +
+			`ORDER BY "duplicate_count" DESC, "email", "city" LIMIT 10`},
+		{"sqlserver", "SELECT TOP (10) [email] AS [email], [city] AS [city], COUNT(*) AS [duplicate_count] FROM [public].[users] " +
+			"WHERE [email] IS NOT NULL AND [city] IS NOT NULL GROUP BY [email], [city] HAVING COUNT(*) > 1 " +
+			"ORDER BY [duplicate_count] DESC, [email], [city]"},
+	} {
+		got, count := DuplicatesQuery(tt.typ, "public", "users", []string{"email", "city"}, 10)
+		if got != tt.want || count != "duplicate_count" {
+			t.Errorf("DuplicatesQuery(%q) = %s, %s\nwant %s", tt.typ, got, count, tt.want)
+		}
+	}
+	if _, count := DuplicatesQuery("sqlite", "", "t", []string{"Duplicate_Count", "duplicate_count_"}, 10); count != "duplicate_count__" {
+		t.Errorf("count column = %q", count)
+	}
+
+	if got, want := DuplicatesSummaryQuery("mysql", "", "users", []string{"email"}),
+		"SELECT COUNT(*) AS `groups`, SUM(n) AS `rows` FROM (SELECT COUNT(*) AS n FROM `users` "+
+			"WHERE `email` IS NOT NULL GROUP BY `email` HAVING COUNT(*) > 1) AS duplicates"; got != want {
+		t.Errorf("DuplicatesSummaryQuery = %s\nwant %s", got, want)
+	}
+
+	if got, want := GroupKeysQuery("sqlite", "", "users", []string{"email", "city"}, []string{"id"}, 5),
+		`SELECT "id" FROM "users" WHERE "email" = $1 AND "city" = $2 ORDER BY "id" LIMIT 5`; got != want {
+		t.Errorf("GroupKeysQuery = %s\nwant %s", got, want)
+	}
+}
@@ -0,0 +1,33 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Sentinel errors that classify failures for errors.Is. Errors returned by
+// this package keep their own messages and wrap at most one of these (or
+// ErrManagerClosed); anything else is an error from the database itself.
+var (
+	ErrUnknownConnection = errors.New("unknown connection")
+	ErrConnectFailed     = errors.New("connection failed")
+	ErrNotSupported      = errors.New("not supported")
+	ErrPathNotAllowed    = errors.New("path not allowed")
+	ErrInvalidInput      = errors.New("invalid input")
+	ErrNotFound          = errors.New("not found")
+	ErrConflict          = errors.New("conflict")
+)
+
+// classifiedError carries a message of its own and matches kind.
+type classifiedError struct {
+	kind error
+	err  error
+}
+
+func (e *classifiedError) Error() string   { return e.err.Error() }
+func (e *classifiedError) Unwrap() []error { return []error{e.kind, e.err} }
+
+// classify formats an error like fmt.Errorf and marks it as kind.
+func classify(kind error, format string, args ...any) error {
+	return &classifiedError{kind: kind, err: fmt.Errorf(format, args...)}
+}
@@ -0,0 +1,103 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+type expectedKey struct{}
+
+// WithExpected returns a copy of ctx carrying expected, column values the
+// row UpdateRow targets must still have. They are added to the WHERE clause
+// of its UPDATE, so the check and the write are one statement; an update
+// they exclude fails with a *ConflictError holding the row as it is now.
+// A nil value expects NULL.
+func WithExpected(ctx context.Context, expected map[string]any) context.Context {
+	return context.WithValue(ctx, expectedKey{}, expected)
+}
+
+// expectedValues returns the expected values of ctx, if any.
+func expectedValues(ctx context.Context) map[string]any {
+	expected, _ := ctx.Value(expectedKey{}).(map[string]any)
+	return expected
+}
+
+// ConflictError is returned by UpdateRow when the row exists but no longer
+// has the values of WithExpected: it was changed since the caller read it.
+// It matches ErrConflict.
+type ConflictError struct {
+	// Current is the row as it is now, masked like a run_query result.
+	Current map[string]any
+}
+
+func (e *ConflictError) Error() string {
+	return "update row: the row no longer has the expected values; it was changed since it was read"
+}
+
+func (e *ConflictError) Unwrap() error { return ErrConflict }
+
+// expectUpdate adds the conditions of ctx's expected values to query, an
+// UPDATE built by UpdateSQL and so ending in its WHERE clause, binding them
+// after params. placeholder returns the driver's placeholder for the n-th
+// param, counting from 1.
+func expectUpdate(ctx context.Context, query string, params []any, quote func(string) string, placeholder func(n int) string) (string, []any) {
+	cols, vals := mapsToColumnsAndValues(expectedValues(ctx))
+	for i, c := range cols {
+		if vals[i] == nil {
+			query += " AND " + quote(c) + " IS NULL"
+			continue
+		}
+		params = append(params, vals[i])
+		query += fmt.Sprintf(" AND %s = %s", quote(c), placeholder(len(params)))
+	}
+	return query, params
+}
+
+// rowReader runs a query for expectedConflict and returns its rows.
+type rowReader func(query string, params []any) ([]map[string]any, error)
+
+// sqlRowReader is a rowReader for the database/sql drivers, reading through
+// q and masking like RunReadOnlyQuery.
+func sqlRowReader(ctx context.Context, q sqlQueryer, m *Masker) rowReader {
+	return func(query string, params []any) ([]map[string]any, error) {
+		rows, err := q.QueryContext(ctx, query, params...)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		return sqlRowsToMaps(rows, m.forQuery(query))
+	}
+}
+
+// expectedConflict works out why an update with expected values changed no
+// row, reading the row key names in table (quoted) through read. It returns
+// ErrNotFound if there is no such row (within the row filter), a
+// *ConflictError if the row no longer has the expected values, and nil if
+// it has them. Only drivers whose RowsAffected counts changed rather than
+// matched rows (MySQL) can get nil: set held the row's current values.
+func expectedConflict(ctx context.Context, read rowReader, table string, key map[string]any, quote func(string) string, placeholder func(n int) string) error {
+	keyCols, params := mapsToColumnsAndValues(key)
+	wheres := make([]string, len(keyCols))
+	for i, c := range keyCols {
+		wheres[i] = quote(c) + " = " + placeholder(i+1)
+	}
+	query := filterUpdate(ctx, "SELECT * FROM "+table+" WHERE "+strings.Join(wheres, " AND "))
+
+	matching, matchParams := expectUpdate(ctx, query, params, quote, placeholder)
+	rows, err := read(matching, matchParams)
+	if err != nil {
+		return fmt.Errorf("update row: read current row: %w", err)
+	}
+	if len(rows) > 0 {
+		return nil
+	}
+	rows, err = read(query, params)
+	if err != nil {
+		return fmt.Errorf("update row: read current row: %w", err)
+	}
+	if len(rows) == 0 {
+		return classify(ErrNotFound, "update row: no row found with the given key")
+	}
+	return &ConflictError{Current: rows[0]}
+}
@@ -20,11 +20,26 @@ var brewPrefixes = map[string]string{
 	"psql":    "postgresql@",
 }
 
-// findCLITool returns the absolute path to the best available version of a CLI
+// cliTools are the CLI tools ExportDatabase and ImportDatabase run, by
+// database type. SQL Server exports natively and only imports with sqlcmd.
+var cliTools = map[string][]string{
+	"postgres":  {"pg_dump", "psql"},
+	"mysql":     {"mysqldump", "mysql"},
+	"sqlite":    {"sqlite3"},
+	"sqlserver": {"sqlcmd"},
+}
+
+// CLITools returns the CLI tools export and import need for a database
+// type, or none for a type that needs none (the demo).
+func CLITools(dbType string) []string {
+	return cliTools[dbType]
+}
+
+// FindCLITool returns the absolute path to the best available version of a CLI
 // tool. On macOS it inspects Homebrew versioned formula directories so that the
 // newest installed version is used regardless of PATH ordering. Falls back to
 // exec.LookPath.
-func findCLITool(name string) (string, error) {
+func FindCLITool(name string) (string, error) {
 	if runtime.GOOS == "darwin" {
 		if prefix, ok := brewPrefixes[name]; ok {
 			if p := findNewestBrewBinary(prefix, name); p != "" {
@@ -34,7 +49,7 @@ func findCLITool(name string) (string, error) {
 	}
 	p, err := exec.LookPath(name)
 	if err != nil {
-		return "", fmt.Errorf("%s is not installed or not in PATH; install it to use export/import", name)
+		return "", classify(ErrNotSupported, "%s is not installed or not in PATH; install it to use export/import", name)
 	}
 	return p, nil
 }
@@ -83,10 +98,12 @@ func findNewestBrewBinary(formulaPrefix, binary string) string {
 }
 
 // validateExportPath validates and normalizes the output file path for export.
-// It ensures the parent directory exists.
-func validateExportPath(path string) (string, error) {
+// It ensures the parent directory exists, that the path is not a symbolic
+// link or a directory, and that the file lands inside one of the allowed
+// directories; an empty allowed list allows no path.
+func validateExportPath(path string, allowed []string) (string, error) {
 	if path == "" {
-		return "", fmt.Errorf("path is required")
+		return "", classify(ErrInvalidInput, "path is required")
 	}
 	abs, err := filepath.Abs(path)
 	if err != nil {
@@ -95,18 +112,52 @@ func validateExportPath(path string) (string, error) {
 	dir := filepath.Dir(abs)
 	info, err := os.Stat(dir)
 	if err != nil {
-		return "", fmt.Errorf("parent directory does not exist: %s", dir)
+		return "", classify(ErrInvalidInput, "parent directory does not exist: %s", dir)
 	}
 	if !info.IsDir() {
-		return "", fmt.Errorf("parent path is not a directory: %s", dir)
+		return "", classify(ErrInvalidInput, "parent path is not a directory: %s", dir)
+	}
+	if info, err := os.Lstat(abs); err == nil {
+		if info.Mode()&os.ModeSymlink != 0 {
+			return "", classify(ErrPathNotAllowed, "export path %s is a symbolic link", abs)
+		}
+		if info.IsDir() {
+			return "", classify(ErrInvalidInput, "path is a directory, not a file: %s", abs)
+		}
+	}
+	if len(allowed) == 0 {
+		return "", classify(ErrPathNotAllowed, "export path %s is not allowed: no export directories are configured", abs)
+	}
+	// Resolve symlinks in the directory so a link inside an allowed
+	// directory cannot point the dump somewhere else.
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", fmt.Errorf("invalid path: %w", err)
+	}
+	if !withinDirs(filepath.Join(realDir, filepath.Base(abs)), allowed) {
+		return "", classify(ErrPathNotAllowed, "export path %s is outside the allowed directories (%s)", abs, strings.Join(allowed, ", "))
 	}
 	return abs, nil
 }
 
-// validateImportPath validates the import file path exists and is readable.
-func validateImportPath(path string) (string, error) {
+// exportTempFile creates an empty file next to path for a dump to be
+// written to. The dump is renamed onto path once complete, which replaces
+// whatever is at path instead of following a link created there after
+// validateExportPath, and leaves no partial dump behind on failure.
+func exportTempFile(path string) (*os.File, error) {
+	f, err := os.CreateTemp(filepath.Dir(path), ".localdb-mcp-export-*")
+	if err != nil {
+		return nil, fmt.Errorf("export: create file: %w", err)
+	}
+	return f, nil
+}
+
+// validateImportPath validates the import file path exists and is readable
+// and that it is inside one of the allowed directories; an empty allowed
+// list allows no path.
+func validateImportPath(path string, allowed []string) (string, error) {
 	if path == "" {
-		return "", fmt.Errorf("path is required")
+		return "", classify(ErrInvalidInput, "path is required")
 	}
 	abs, err := filepath.Abs(path)
 	if err != nil {
@@ -114,14 +165,51 @@ func validateImportPath(path string) (string, error) {
 	}
 	info, err := os.Stat(abs)
 	if err != nil {
-		return "", fmt.Errorf("file does not exist: %s", abs)
+		return "", classify(ErrInvalidInput, "file does not exist: %s", abs)
 	}
 	if info.IsDir() {
-		return "", fmt.Errorf("path is a directory, not a file: %s", abs)
+		return "", classify(ErrInvalidInput, "path is a directory, not a file: %s", abs)
+	}
+	if len(allowed) == 0 {
+		return "", classify(ErrPathNotAllowed, "import path %s is not allowed: no export directories are configured", abs)
+	}
+	real, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return "", fmt.Errorf("invalid path: %w", err)
+	}
+	if !withinDirs(real, allowed) {
+		return "", classify(ErrPathNotAllowed, "import path %s is outside the allowed directories (%s)", abs, strings.Join(allowed, ", "))
 	}
 	return abs, nil
 }
 
+// CheckImportPath returns the error ImportDatabase would return for path
+// and allowed before touching the database, so a caller can refuse an
+// import early.
+func CheckImportPath(path string, allowed []string) error {
+	_, err := validateImportPath(path, allowed)
+	return err
+}
+
+// withinDirs reports whether the symlink-resolved path p is inside one of
+// dirs. Directories that do not exist are skipped.
+func withinDirs(p string, dirs []string) bool {
+	for _, d := range dirs {
+		real, err := filepath.EvalSymlinks(d)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(real, p)
+		if err != nil {
+			continue
+		}
+		if rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel) {
+			return true
+		}
+	}
+	return false
+}
+
 // truncateMsg truncates a string to maxLen characters for safe error reporting.
 func truncateMsg(s string, maxLen int) string {
 	if len(s) > maxLen {
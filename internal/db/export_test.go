@@ -0,0 +1,111 @@
+package db
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateExportPath_allowedDirs(t *testing.T) {
+	allowed := t.TempDir()
+	other := t.TempDir()
+	if err := os.Symlink(other, filepath.Join(allowed, "escape")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{"inside", filepath.Join(allowed, "dump.sql"), false},
+		{"outside", filepath.Join(other, "dump.sql"), true},
+		{"dot-dot", filepath.Join(allowed, "..", filepath.Base(other), "dump.sql"), true},
+		{"symlink escape", filepath.Join(allowed, "escape", "dump.sql"), true},
+	}
+	for _, tt := range tests {
+		_, err := validateExportPath(tt.path, []string{allowed})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: validateExportPath(%s) err = %v, wantErr %v", tt.name, tt.path, err, tt.wantErr)
+		}
+		if err != nil && !strings.Contains(err.Error(), "outside the allowed directories") {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+		}
+	}
+
+	// No allowed directories allows no path.
+	if _, err := validateExportPath(filepath.Join(allowed, "dump.sql"), nil); err == nil {
+		t.Error("export with no allowed directories should be rejected")
+	}
+}
+
+func TestValidateExportPath_symlinkTarget(t *testing.T) {
+	allowed := t.TempDir()
+	target := filepath.Join(t.TempDir(), "authorized_keys")
+	if err := os.WriteFile(target, []byte("keep\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(allowed, "dump.sql")
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := validateExportPath(link, []string{allowed}); err == nil || !strings.Contains(err.Error(), "symbolic link") {
+		t.Errorf("validateExportPath(symlink) err = %v, want a symbolic link error", err)
+	}
+
+	// A link created after validation is replaced, not written through.
+	f, err := exportTempFile(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("dump\n")
+	f.Close()
+	if err := prependManifest(f.Name(), link, &Manifest{Engine: "sqlite"}); err != nil {
+		t.Fatalf("prependManifest: %v", err)
+	}
+	if b, _ := os.ReadFile(target); string(b) != "keep\n" {
+		t.Errorf("link target overwritten: %q", b)
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink != 0 {
+		t.Errorf("dump should have replaced the link: %v", err)
+	}
+}
+
+func TestValidateImportPath_allowedDirs(t *testing.T) {
+	allowed := t.TempDir()
+	other := t.TempDir()
+	inside := filepath.Join(allowed, "dump.sql")
+	outside := filepath.Join(other, "secret.txt")
+	for _, p := range []string{inside, outside} {
+		if err := os.WriteFile(p, []byte("SELECT 1;\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	link := filepath.Join(allowed, "link.sql")
+	if err := os.Symlink(outside, link); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := validateImportPath(inside, []string{allowed}); err != nil {
+		t.Errorf("inside: %v", err)
+	}
+	for _, p := range []string{outside, link} {
+		if _, err := validateImportPath(p, []string{allowed}); err == nil {
+			t.Errorf("validateImportPath(%s) should be rejected", p)
+		}
+	}
+}
+
+func TestWithinDirs(t *testing.T) {
+	root := t.TempDir()
+	if !withinDirs(root, []string{root}) {
+		t.Error("a directory should be within itself")
+	}
+	if withinDirs(root+"-sibling", []string{root}) {
+		t.Error("a sibling sharing the prefix must not match")
+	}
+	if withinDirs(filepath.Join(root, "x"), []string{filepath.Join(root, "missing")}) {
+		t.Error("non-existent allowed dirs must be skipped")
+	}
+}
@@ -0,0 +1,99 @@
+package db
+
+import (
+	"bufio"
+	"strings"
+)
+
+// DefaultExportBatchSize is the number of rows per multi-row INSERT used by
+// Go-generated exports when ExportOptions.BatchSize is zero.
+const DefaultExportBatchSize = 100
+
+// maxExportBatchBytes bounds a single buffered INSERT statement so tables
+// with wide rows flush early instead of growing a batch without limit.
+const maxExportBatchBytes = 1 << 20
+
+// ExportOptions tunes how a dump is produced. The zero value uses defaults.
+type ExportOptions struct {
+	// BatchSize is the number of rows per multi-row INSERT statement for
+	// exporters that generate SQL in Go (SQL Server). CLI-based exporters
+	// use the engine tool's own batching and ignore it.
+	BatchSize int
+	// ToolVersion is the localdb-mcp version recorded in the dump manifest.
+	ToolVersion string
+	// AllowedDirs restricts where the dump may be written. Empty allows
+	// no path; the MCP server sets it from the configuration.
+	AllowedDirs []string
+}
+
+// ImportOptions tunes how a dump is loaded. The zero value uses defaults.
+type ImportOptions struct {
+	// AllowedDirs restricts which files may be imported. Empty allows no
+	// path; the MCP server sets it from the configuration.
+	AllowedDirs []string
+}
+
+// batchSize returns the effective batch size, clamped to [1, limit].
+func (o ExportOptions) batchSize(limit int) int {
+	n := o.BatchSize
+	if n <= 0 {
+		n = DefaultExportBatchSize
+	}
+	if limit > 0 && n > limit {
+		n = limit
+	}
+	return n
+}
+
+// insertBatcher streams rows of one table to w as multi-row INSERT
+// statements. At most one batch is held in memory; each completed batch is
+// written and the underlying buffered writer flushed, so memory stays flat
+// regardless of table size.
+type insertBatcher struct {
+	w          *bufio.Writer
+	prefix     string // e.g. "INSERT INTO [dbo].[t] ([a], [b]) VALUES"
+	terminator string // written after each statement, e.g. ";\nGO\n"
+	batchSize  int
+	buf        strings.Builder
+	rows       int
+}
+
+func newInsertBatcher(w *bufio.Writer, prefix, terminator string, batchSize int) *insertBatcher {
+	return &insertBatcher{w: w, prefix: prefix, terminator: terminator, batchSize: batchSize}
+}
+
+// add appends one row of already-formatted SQL literals to the current batch,
+// writing the batch out once it is full.
+func (b *insertBatcher) add(values []string) error {
+	if b.rows == 0 {
+		b.buf.WriteString(b.prefix)
+		b.buf.WriteString("\n(")
+	} else {
+		b.buf.WriteString(",\n(")
+	}
+	for i, v := range values {
+		if i > 0 {
+			b.buf.WriteString(", ")
+		}
+		b.buf.WriteString(v)
+	}
+	b.buf.WriteByte(')')
+	b.rows++
+	if b.rows >= b.batchSize || b.buf.Len() >= maxExportBatchBytes {
+		return b.flush()
+	}
+	return nil
+}
+
+// flush writes any pending batch and flushes the underlying writer.
+func (b *insertBatcher) flush() error {
+	if b.rows > 0 {
+		b.buf.WriteString(b.terminator)
+		if _, err := b.w.WriteString(b.buf.String()); err != nil {
+			return err
+		}
+		b.buf.Reset()
+		b.rows = 0
+	}
+	return b.w.Flush()
+}
@@ -0,0 +1,58 @@
+package db
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+)
+
+func TestInsertBatcher(t *testing.T) {
+	var out bytes.Buffer
+	w := bufio.NewWriter(&out)
+	b := newInsertBatcher(w, "INSERT INTO t (a, b) VALUES", ";\nGO\n", 2)
+
+	for _, row := range [][]string{{"1", "'x'"}, {"2", "'y'"}, {"3", "NULL"}} {
+		if err := b.add(row); err != nil {
+			t.Fatalf("add: %v", err)
+		}
+	}
+	// The first two rows form a full batch and must already be on the wire.
+	wantFirst := "INSERT INTO t (a, b) VALUES\n(1, 'x'),\n(2, 'y');\nGO\n"
+	if out.String() != wantFirst {
+		t.Errorf("after full batch:\n got %q\nwant %q", out.String(), wantFirst)
+	}
+
+	if err := b.flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	want := wantFirst + "INSERT INTO t (a, b) VALUES\n(3, NULL);\nGO\n"
+	if out.String() != want {
+		t.Errorf("after flush:\n got %q\nwant %q", out.String(), want)
+	}
+
+	// Flushing with nothing pending writes nothing.
+	if err := b.flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	if out.String() != want {
+		t.Errorf("empty flush changed output: %q", out.String())
+	}
+}
+
+func TestExportOptionsBatchSize(t *testing.T) {
+	tests := []struct {
+		opts  ExportOptions
+		limit int
+		want  int
+	}{
+		{ExportOptions{}, 1000, DefaultExportBatchSize},
+		{ExportOptions{BatchSize: 10}, 1000, 10},
+		{ExportOptions{BatchSize: 5000}, 1000, 1000},
+		{ExportOptions{BatchSize: 5000}, 0, 5000},
+	}
+	for _, tt := range tests {
+		if got := tt.opts.batchSize(tt.limit); got != tt.want {
+			t.Errorf("%+v.batchSize(%d) = %d, want %d", tt.opts, tt.limit, got, tt.want)
+		}
+	}
+}
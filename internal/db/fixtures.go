@@ -0,0 +1,230 @@
+package db
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"math"
+	"path"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fixtureFile is one table's rows read from a fixtures directory.
+type fixtureFile struct {
+	name          string // file name, for errors
+	schema, table string
+	rows          []map[string]any
+}
+
+// LoadFixtures inserts the rows of the fixture files at the root of fsys
+// into the database d is connected to. Each file holds the rows of one
+// table as a YAML or JSON list of column → value mappings, and is named
+// after the table: customers.yaml, orders.json, or inventory.products.yml
+// for a table of another schema.
+//
+// Tables are loaded parents first, in the order of their foreign keys when
+// d lists them (see ForeignKeyLister), and rows in file order. Values are
+// converted to suit their column: mappings and lists become JSON text,
+// strings for binary columns become bytes (hex with a 0x or \x prefix;
+// quote it in YAML, which reads 0x00ff as a number), and whole numbers for
+// integer columns become integers. When d supports transactions (see
+// Transactor) everything is loaded in one, so a failing row leaves the
+// database as it was.
+//
+// It is meant for the test suites of other programs, to seed a database
+// the way localdb-mcp's tools write to it:
+//
+//	err := db.LoadFixtures(ctx, driver, os.DirFS("testdata/fixtures"))
+func LoadFixtures(ctx context.Context, d Driver, fsys fs.FS) error {
+	files, err := readFixtures(fsys)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return nil
+	}
+	files, err = orderFixtures(ctx, d, files)
+	if err != nil {
+		return err
+	}
+
+	// Columns are described up front: on in-memory SQLite a transaction
+	// holds the only connection.
+	cols := make([]map[string]ColumnInfo, len(files))
+	for i, f := range files {
+		described, err := d.DescribeTable(ctx, f.schema, f.table)
+		if err != nil {
+			return fmt.Errorf("fixtures %s: %w", f.name, err)
+		}
+		if len(described) == 0 {
+			return classify(ErrInvalidInput, "fixtures %s: table %s does not exist", f.name, f.table)
+		}
+		cols[i] = make(map[string]ColumnInfo, len(described))
+		for _, c := range described {
+			cols[i][c.Name] = c
+		}
+	}
+
+	var w interface {
+		InsertRow(ctx context.Context, schema, table string, row map[string]any) (any, error)
+	} = d
+	var tx Tx
+	if t, ok := Unwrap(d).(Transactor); ok && d.Capabilities().Transactions {
+		if tx, err = t.BeginTx(ctx); err != nil {
+			return fmt.Errorf("fixtures: %w", err)
+		}
+		defer tx.Rollback(context.WithoutCancel(ctx))
+		w = tx
+	}
+	for i, f := range files {
+		for n, row := range f.rows {
+			row, err := coerceFixtureRow(row, cols[i])
+			if err == nil {
+				_, err = w.InsertRow(ctx, f.schema, f.table, row)
+			}
+			if err != nil {
+				return fmt.Errorf("fixtures %s, row %d: %w", f.name, n+1, err)
+			}
+		}
+	}
+	if tx != nil {
+		if err := tx.Commit(ctx); err != nil {
+			return fmt.Errorf("fixtures: %w", err)
+		}
+	}
+	return nil
+}
+
+// readFixtures reads the fixture files at the root of fsys, in name order.
+// Other files are ignored.
+func readFixtures(fsys fs.FS) ([]fixtureFile, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("fixtures: %w", err)
+	}
+	var files []fixtureFile
+	for _, e := range entries {
+		ext := path.Ext(e.Name())
+		if e.IsDir() || (ext != ".yaml" && ext != ".yml" && ext != ".json") {
+			continue
+		}
+		b, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, fmt.Errorf("fixtures: %w", err)
+		}
+		f := fixtureFile{name: e.Name(), table: strings.TrimSuffix(e.Name(), ext)}
+		if schema, table, ok := strings.Cut(f.table, "."); ok {
+			f.schema, f.table = schema, table
+		}
+		// JSON is YAML, so one decoder reads both.
+		if err := yaml.Unmarshal(b, &f.rows); err != nil {
+			return nil, classify(ErrInvalidInput, "fixtures %s: want a list of rows: %v", e.Name(), err)
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// orderFixtures returns files ordered so that every table comes after the
+// tables of its schema it references. Schemas are loaded in name order,
+// the default schema first. Without a ForeignKeyLister, or for tables in a
+// reference cycle, files stay in name order; the database then reports a
+// row whose parent is not loaded yet.
+func orderFixtures(ctx context.Context, d Driver, files []fixtureFile) ([]fixtureFile, error) {
+	bySchema := make(map[string]map[string]fixtureFile)
+	for _, f := range files {
+		if bySchema[f.schema] == nil {
+			bySchema[f.schema] = make(map[string]fixtureFile)
+		}
+		if _, dup := bySchema[f.schema][f.table]; dup {
+			return nil, classify(ErrInvalidInput, "fixtures: more than one file for table %s", f.table)
+		}
+		bySchema[f.schema][f.table] = f
+	}
+	schemas := make([]string, 0, len(bySchema))
+	for s := range bySchema {
+		schemas = append(schemas, s)
+	}
+	sort.Strings(schemas)
+
+	lister, _ := Unwrap(d).(ForeignKeyLister)
+	ordered := make([]fixtureFile, 0, len(files))
+	for _, s := range schemas {
+		tables := make([]string, 0, len(bySchema[s]))
+		for t := range bySchema[s] {
+			tables = append(tables, t)
+		}
+		sort.Strings(tables)
+		if lister != nil {
+			fks, err := lister.ForeignKeys(ctx, s)
+			if err != nil {
+				return nil, fmt.Errorf("fixtures: list foreign keys: %w", err)
+			}
+			tables, _ = sortTablesByDependencies(tables, fks)
+		}
+		for _, t := range tables {
+			ordered = append(ordered, bySchema[s][t])
+		}
+	}
+	return ordered, nil
+}
+
+// coerceFixtureRow converts the values of row to suit cols, its table's
+// columns. Unknown columns are left to InsertRow to reject.
+func coerceFixtureRow(row map[string]any, cols map[string]ColumnInfo) (map[string]any, error) {
+	out := make(map[string]any, len(row))
+	for name, v := range row {
+		typ := strings.ToLower(cols[name].Type)
+		switch val := v.(type) {
+		case map[string]any, []any:
+			b, err := json.Marshal(val)
+			if err != nil {
+				return nil, classify(ErrInvalidInput, "column %s: %v", name, err)
+			}
+			v = string(b)
+		case string:
+			if fixtureBinaryType(typ) {
+				b, err := decodeFixtureBytes(val)
+				if err != nil {
+					return nil, classify(ErrInvalidInput, "column %s: %v", name, err)
+				}
+				v = b
+			}
+		case float64:
+			if fixtureIntegerType(typ) && val == math.Trunc(val) {
+				v = int64(val)
+			}
+		}
+		out[name] = v
+	}
+	return out, nil
+}
+
+// decodeFixtureBytes returns s as bytes: hex-decoded if it starts with 0x
+// or \x, as SQL Server and PostgreSQL write binary literals, and as is
+// otherwise.
+func decodeFixtureBytes(s string) ([]byte, error) {
+	for _, prefix := range []string{"0x", `\x`} {
+		if rest, ok := strings.CutPrefix(s, prefix); ok {
+			return hex.DecodeString(rest)
+		}
+	}
+	return []byte(s), nil
+}
+
+// fixtureBinaryType reports whether typ, a lower-cased column type as
+// DescribeTable reports it, holds bytes.
+func fixtureBinaryType(typ string) bool {
+	return strings.Contains(typ, "binary") || strings.Contains(typ, "blob") || typ == "bytea" || typ == "image"
+}
+
+// fixtureIntegerType reports whether typ, a lower-cased column type as
+// DescribeTable reports it, holds integers.
+func fixtureIntegerType(typ string) bool {
+	return strings.Contains(typ, "int") && !strings.Contains(typ, "interval") && !strings.Contains(typ, "point")
+}
@@ -0,0 +1,116 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+// newFixturesDriver returns an in-memory SQLite driver, with foreign keys
+// enforced, whose order_items reference orders, which reference customers.
+func newFixturesDriver(t *testing.T) *SQLiteDriver {
+	t.Helper()
+	d, err := NewSQLiteDriver(context.Background(), ":memory:", ConnectOptions{})
+	if err != nil {
+		t.Fatalf("NewSQLiteDriver: %v", err)
+	}
+	t.Cleanup(func() { d.Close() })
+	_, err = d.db.Exec(`PRAGMA foreign_keys = ON;
+		CREATE TABLE customers (id INTEGER PRIMARY KEY, name TEXT NOT NULL, prefs JSON, avatar BLOB);
+		CREATE TABLE orders (id INTEGER PRIMARY KEY, customer_id INTEGER NOT NULL REFERENCES customers);
+		CREATE TABLE order_items (
+			order_id INTEGER NOT NULL REFERENCES orders (id),
+			line INTEGER NOT NULL,
+			PRIMARY KEY (order_id, line)
+		)`)
+	if err != nil {
+		t.Fatalf("create tables: %v", err)
+	}
+	return d
+}
+
+func TestSQLite_ForeignKeys(t *testing.T) {
+	d := newFixturesDriver(t)
+	fks, err := d.ForeignKeys(context.Background(), "")
+	if err != nil {
+		t.Fatalf("ForeignKeys: %v", err)
+	}
+	if len(fks) != 2 {
+		t.Fatalf("ForeignKeys = %+v, want 2", fks)
+	}
+	// order_items names its parent column; orders leaves it to the
+	// primary key.
+	if fk := fks[0]; fk.Table != "order_items" || fk.RefTable != "orders" || !slices.Equal(fk.Columns, []string{"order_id"}) || !slices.Equal(fk.RefColumns, []string{"id"}) {
+		t.Errorf("fks[0] = %+v", fk)
+	}
+	if fk := fks[1]; fk.Table != "orders" || fk.RefTable != "customers" || !slices.Equal(fk.Columns, []string{"customer_id"}) || !slices.Equal(fk.RefColumns, []string{"id"}) || fk.OnDelete != "NO ACTION" {
+		t.Errorf("fks[1] = %+v", fk)
+	}
+}
+
+func TestLoadFixtures(t *testing.T) {
+	d := newFixturesDriver(t)
+	ctx := context.Background()
+	// In name order order_items would come first, before the orders it
+	// references.
+	fsys := fstest.MapFS{
+		"order_items.yaml": {Data: []byte("- {order_id: 1, line: 1}\n- {order_id: 1, line: 2}\n")},
+		"orders.json":      {Data: []byte(`[{"id": 1, "customer_id": 7.0}]`)},
+		"customers.yml":    {Data: []byte("- id: 7\n  name: Ada\n  prefs: {theme: dark, tags: [a, b]}\n  avatar: \"0x00ff\"\n")},
+		"README.md":        {Data: []byte("not a fixture")},
+	}
+	if err := LoadFixtures(ctx, d, fsys); err != nil {
+		t.Fatalf("LoadFixtures: %v", err)
+	}
+	rows, err := d.RunReadOnlyQuery(ctx, "SELECT name, prefs, avatar, typeof(avatar) AS t FROM customers", nil)
+	if err != nil || len(rows) != 1 {
+		t.Fatalf("customers = %v, %v", rows, err)
+	}
+	if rows[0]["prefs"] != `{"tags":["a","b"],"theme":"dark"}` {
+		t.Errorf("prefs = %v, want JSON text", rows[0]["prefs"])
+	}
+	if rows[0]["t"] != "blob" || !slices.Equal(rows[0]["avatar"].([]byte), []byte{0x00, 0xff}) {
+		t.Errorf("avatar = %v (%v), want bytes 00 ff", rows[0]["avatar"], rows[0]["t"])
+	}
+	rows, err = d.RunReadOnlyQuery(ctx, "SELECT typeof(customer_id) AS t FROM orders", nil)
+	if err != nil || len(rows) != 1 || rows[0]["t"] != "integer" {
+		t.Errorf("orders.customer_id = %v, %v; want an integer", rows, err)
+	}
+	rows, err = d.RunReadOnlyQuery(ctx, "SELECT COUNT(*) AS n FROM order_items", nil)
+	if err != nil || rows[0]["n"] != int64(2) {
+		t.Errorf("order_items = %v, %v; want 2 rows", rows, err)
+	}
+}
+
+func TestLoadFixtures_rollsBack(t *testing.T) {
+	d := newFixturesDriver(t)
+	ctx := context.Background()
+	fsys := fstest.MapFS{
+		"customers.yaml": {Data: []byte("- {id: 1, name: Ada}\n")},
+		"orders.yaml":    {Data: []byte("- {id: 1, customer_id: 1}\n- {id: 2, nope: 1}\n")},
+	}
+	err := LoadFixtures(ctx, d, fsys)
+	if !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("LoadFixtures with an unknown column: %v, want invalid input", err)
+	}
+	rows, err := d.RunReadOnlyQuery(ctx, "SELECT (SELECT COUNT(*) FROM customers) + (SELECT COUNT(*) FROM orders) AS n", nil)
+	if err != nil || rows[0]["n"] != int64(0) {
+		t.Errorf("rows left after a failed load = %v, %v; want none", rows, err)
+	}
+}
+
+func TestLoadFixtures_invalid(t *testing.T) {
+	d := newFixturesDriver(t)
+	for name, fsys := range map[string]fstest.MapFS{
+		"unknown table":     {"nope.yaml": {Data: []byte("- {id: 1}\n")}},
+		"not a list":        {"customers.yaml": {Data: []byte("id: 1\n")}},
+		"bad hex":           {"customers.yaml": {Data: []byte("- {id: 1, name: a, avatar: 0xzz}\n")}},
+		"two files a table": {"customers.yaml": {Data: []byte("[]")}, "customers.json": {Data: []byte("[]")}},
+	} {
+		if err := LoadFixtures(context.Background(), d, fsys); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("%s: %v, want invalid input", name, err)
+		}
+	}
+}
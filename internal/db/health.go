@@ -0,0 +1,223 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HealthPingTimeout bounds each ping made by Manager.Health.
+const HealthPingTimeout = 5 * time.Second
+
+// ConnectionHealth is the status of one configured connection. Like
+// config.ConnectionInfo it never contains the connection URI.
+type ConnectionHealth struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	// Connected reports whether a driver for the connection is cached.
+	Connected bool `json:"connected"`
+	// OK reports whether the connection answered a ping just now.
+	OK bool `json:"ok"`
+	// LastPing is the time of the last successful ping, possibly from an
+	// earlier check.
+	LastPing *time.Time `json:"last_ping,omitempty"`
+	// LatencyMS is the round trip of that ping in milliseconds.
+	LatencyMS *float64 `json:"latency_ms,omitempty"`
+	// LastFailure is when a background check last found the connection
+	// broken, and Reconnects how often it was reopened after that.
+	LastFailure *time.Time `json:"last_failure,omitempty"`
+	Reconnects  int        `json:"reconnects,omitempty"`
+	Error       string     `json:"error,omitempty"`
+}
+
+type pingRecord struct {
+	at      time.Time
+	latency time.Duration
+}
+
+// checkRecord is the history of background checks of one connection.
+type checkRecord struct {
+	failed     time.Time
+	reconnects int
+}
+
+// Health pings every configured connection that has a cached driver and
+// reports the result per connection, sorted by ID. Connections without a
+// driver are not opened unless connect is set, so a health check stays cheap
+// and never dials a database the agent has not used yet. A cached driver that
+// fails its ping is dropped, so the next tool call reconnects.
+func (m *Manager) Health(ctx context.Context, connect bool) []ConnectionHealth {
+	if m.cfg == nil {
+		return nil
+	}
+	infos := m.cfg.ConnectionInfos()
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ID < infos[j].ID })
+
+	out := make([]ConnectionHealth, len(infos))
+	var wg sync.WaitGroup
+	for i, info := range infos {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			out[i] = m.checkHealth(ctx, info.ID, info.Type, connect)
+		}()
+	}
+	wg.Wait()
+	return out
+}
+
+func (m *Manager) checkHealth(ctx context.Context, id, typ string, connect bool) ConnectionHealth {
+	h := ConnectionHealth{ID: id, Type: typ}
+	m.mu.Lock()
+	d, cached := m.drivers[id]
+	m.mu.Unlock()
+
+	if !cached && connect {
+		var err error
+		if d, err = m.driver(ctx, id); err != nil {
+			h.Error = err.Error()
+			return m.withLastPing(h)
+		}
+		cached = true
+	}
+	h.Connected = cached
+	if !cached {
+		return m.withLastPing(h)
+	}
+
+	pingCtx, cancel := context.WithTimeout(ctx, HealthPingTimeout)
+	defer cancel()
+	start := time.Now()
+	if err := d.Ping(pingCtx); err != nil {
+		// Driver errors may echo parts of the DSN; report only the kind of failure.
+		if errors.Is(err, context.DeadlineExceeded) {
+			h.Error = fmt.Sprintf("ping timed out after %s", HealthPingTimeout)
+		} else {
+			h.Error = "ping failed"
+		}
+		if ctx.Err() == nil {
+			// The connection was dropped; the next Driver call reconnects.
+			m.drop(id, d)
+		}
+		return m.withLastPing(h)
+	}
+	h.OK = true
+	m.mu.Lock()
+	m.pings[id] = pingRecord{at: start, latency: time.Since(start)}
+	m.mu.Unlock()
+	return m.withLastPing(h)
+}
+
+// withLastPing fills in the last successful ping and the background check
+// history recorded for h.ID.
+func (m *Manager) withLastPing(h ConnectionHealth) ConnectionHealth {
+	m.mu.Lock()
+	rec, ok := m.pings[h.ID]
+	check := m.checks[h.ID]
+	m.mu.Unlock()
+	if ok {
+		at := rec.at.UTC()
+		ms := float64(rec.latency.Microseconds()) / 1000
+		h.LastPing, h.LatencyMS = &at, &ms
+	}
+	if !check.failed.IsZero() {
+		failed := check.failed.UTC()
+		h.LastFailure = &failed
+	}
+	h.Reconnects = check.reconnects
+	return h
+}
+
+// watchHealth pings the cached drivers every interval until Close is called.
+func (m *Manager) watchHealth(every time.Duration) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-m.stop
+		cancel()
+	}()
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			m.checkCached(ctx)
+		}
+	}
+}
+
+// checkCached pings every cached driver and reconnects those that fail, so
+// a dropped connection is replaced before a tool call runs into it. The
+// connection's idle time is kept, so the reaper still closes it on schedule.
+func (m *Manager) checkCached(ctx context.Context) {
+	m.mu.Lock()
+	drivers := make(map[string]Driver, len(m.drivers))
+	for id, d := range m.drivers {
+		drivers[id] = d
+	}
+	m.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for id, d := range drivers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pingCtx, cancel := context.WithTimeout(ctx, HealthPingTimeout)
+			start := time.Now()
+			err := d.Ping(pingCtx)
+			cancel()
+			if ctx.Err() != nil {
+				return
+			}
+			if err == nil {
+				m.mu.Lock()
+				m.pings[id] = pingRecord{at: start, latency: time.Since(start)}
+				m.mu.Unlock()
+				return
+			}
+			m.mu.Lock()
+			rec := m.checks[id]
+			rec.failed = start
+			m.checks[id] = rec
+			m.mu.Unlock()
+			m.reconnect(ctx, id, d)
+		}()
+	}
+	wg.Wait()
+}
+
+// reconnect replaces the broken driver old for id with a new connection. If
+// connecting fails, old is dropped and the next Driver call tries again.
+func (m *Manager) reconnect(ctx context.Context, id string, old Driver) {
+	typ, _ := m.cfg.Type(id)
+	uris, ok := m.cfg.URIs(id)
+	var d Driver
+	err := errors.New("connection removed")
+	if ok {
+		ctx, cancel := context.WithTimeout(ctx, m.cfg.ConnectTimeout(id))
+		d, err = m.connect(ctx, id, typ, uris)
+		cancel()
+	}
+	if err != nil {
+		m.drop(id, old)
+		return
+	}
+	m.mu.Lock()
+	if m.closed || m.drivers[id] != old {
+		// Closed, reaped or replaced while reconnecting.
+		m.mu.Unlock()
+		d.Close()
+		return
+	}
+	m.drivers[id] = d
+	rec := m.checks[id]
+	rec.reconnects++
+	m.checks[id] = rec
+	m.mu.Unlock()
+	old.Close()
+}
@@ -0,0 +1,101 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+)
+
+func TestManager_Health(t *testing.T) {
+	t.Setenv(config.EnvSQLiteURI, ":memory:")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+	m := NewManager(cfg)
+	defer m.Close()
+	ctx := context.Background()
+
+	find := func(hs []ConnectionHealth) ConnectionHealth {
+		t.Helper()
+		for _, h := range hs {
+			if h.ID == "sqlite" {
+				return h
+			}
+		}
+		t.Fatalf("sqlite missing from health report %+v", hs)
+		return ConnectionHealth{}
+	}
+
+	h := find(m.Health(ctx, false))
+	if h.Connected || h.OK || h.LastPing != nil {
+		t.Errorf("unused connection should not be opened: %+v", h)
+	}
+	m.mu.Lock()
+	_, opened := m.drivers["sqlite"]
+	m.mu.Unlock()
+	if opened {
+		t.Fatal("Health(connect=false) created a driver")
+	}
+
+	h = find(m.Health(ctx, true))
+	if !h.Connected || !h.OK || h.LastPing == nil || h.LatencyMS == nil || h.Error != "" {
+		t.Errorf("expected a successful ping after connecting: %+v", h)
+	}
+
+	// A cached driver is pinged without connect.
+	h = find(m.Health(ctx, false))
+	if !h.Connected || !h.OK {
+		t.Errorf("expected cached driver to be pinged: %+v", h)
+	}
+}
+
+func TestManager_Health_nilConfig(t *testing.T) {
+	if hs := NewManager(nil).Health(context.Background(), true); hs != nil {
+		t.Errorf("expected no connections, got %+v", hs)
+	}
+}
+
+func TestManager_checkCached(t *testing.T) {
+	t.Setenv(config.EnvSQLiteURI, ":memory:")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+	m := NewManager(cfg)
+	defer m.Close()
+	m.open = func(context.Context, string, string, ConnectOptions) (Driver, error) { return &flakyDriver{}, nil }
+	ctx := context.Background()
+	first, err := m.Driver(ctx, "sqlite")
+	if err != nil {
+		t.Fatalf("Driver: %v", err)
+	}
+	m.mu.Lock()
+	used := m.used["sqlite"]
+	m.mu.Unlock()
+
+	m.checkCached(ctx)
+	if h := m.Health(ctx, false)[0]; h.LastPing == nil || h.LastFailure != nil || h.Reconnects != 0 {
+		t.Errorf("after a passing check: %+v", h)
+	}
+
+	Unwrap(first).(*flakyDriver).down = true
+	m.checkCached(ctx)
+	if !Unwrap(first).(*flakyDriver).closed {
+		t.Error("broken driver should be closed")
+	}
+	h := m.Health(ctx, false)[0]
+	if !h.OK || h.LastFailure == nil || h.Reconnects != 1 {
+		t.Errorf("after a reconnect: %+v", h)
+	}
+	m.mu.Lock()
+	second, reused := m.drivers["sqlite"], m.used["sqlite"]
+	m.mu.Unlock()
+	if second == Unwrap(first) {
+		t.Error("expected the broken driver to be replaced")
+	}
+	if !reused.Equal(used) {
+		t.Error("a reconnect should not count as use of the connection")
+	}
+}
@@ -0,0 +1,36 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxIndexNameLength is the shortest limit on index names of the
+// connection types, PostgreSQL's.
+const maxIndexNameLength = 63
+
+// IndexName returns a name for an index on columns of table.
+func IndexName(table string, columns []string) string {
+	name := "ix_" + table + "_" + strings.Join(columns, "_")
+	if len(name) > maxIndexNameLength {
+		name = name[:maxIndexNameLength]
+	}
+	return name
+}
+
+// CreateIndexDDL returns the CREATE INDEX statement for an index named
+// IndexName on columns of table, qualified by schema if it is not empty,
+// for connection type typ.
+func CreateIndexDDL(typ, schema, table string, columns []string) string {
+	quote := quoteFor(typ)
+	quoted := make([]string, len(columns))
+	for i, c := range columns {
+		quoted[i] = quote(c)
+	}
+	name, on := quote(IndexName(table, columns)), quoteTableFor(typ, schema, table)
+	if (typ == "sqlite" || typ == "demo") && schema != "" {
+		// SQLite qualifies the index, which lives in its table's schema.
+		name, on = quote(schema)+"."+name, quote(table)
+	}
+	return fmt.Sprintf("CREATE INDEX %s ON %s (%s)", name, on, strings.Join(quoted, ", "))
+}
@@ -0,0 +1,20 @@
+package db
+
+import "testing"
+
+func TestCreateIndexDDL(t *testing.T) {
+	cols := []string{"customer_id", "status"}
+	for _, tc := range []struct{ typ, schema, want string }{
+		{"postgres", "public", `CREATE INDEX "ix_orders_customer_id_status" ON "public"."orders" ("customer_id", "status")`},
+		{"mysql", "", "CREATE INDEX `ix_orders_customer_id_status` ON `orders` (`customer_id`, `status`)"},
+		{"sqlserver", "dbo", "CREATE INDEX [ix_orders_customer_id_status] ON [dbo].[orders] ([customer_id], [status])"},
+		{"sqlite", "main", `CREATE INDEX "main"."ix_orders_customer_id_status" ON "orders" ("customer_id", "status")`},
+	} {
+		if got := CreateIndexDDL(tc.typ, tc.schema, "orders", cols); got != tc.want {
+			t.Errorf("CreateIndexDDL(%s) =\n%s\nwant\n%s", tc.typ, got, tc.want)
+		}
+	}
+	if name := IndexName("t", []string{"a_very_long_column_name", "another_very_long_column_name", "third"}); len(name) != maxIndexNameLength {
+		t.Errorf("IndexName = %q, longer than %d", name, maxIndexNameLength)
+	}
+}
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"slices"
+)
+
+// IndexLister is an optional interface for drivers that can list the
+// indexes of a schema.
+type IndexLister interface {
+	// Indexes returns the indexes on tables in schema (the connection's
+	// default schema if empty), including those backing primary keys and
+	// unique constraints, ordered by table and index name.
+	Indexes(ctx context.Context, schema string) ([]Index, error)
+}
+
+// Index describes an index. Columns are its key columns in index order; an
+// expression is an empty name. Included (non-key) columns are left out.
+type Index struct {
+	Name    string   `json:"name"`
+	Table   string   `json:"table"`
+	Columns []string `json:"columns"`
+	Unique  bool     `json:"unique"`
+	Primary bool     `json:"primary,omitempty"`
+	// Partial is set for an index on the rows matching a predicate.
+	Partial bool `json:"partial,omitempty"`
+}
+
+// appendIndexColumn adds col to the last index of idxs if it is the index
+// ix names, and appends ix with col otherwise. Catalog queries return a row
+// per column, ordered by table, index and position.
+func appendIndexColumn(idxs []Index, ix Index, col string) []Index {
+	if n := len(idxs); n > 0 && idxs[n-1].Name == ix.Name && idxs[n-1].Table == ix.Table {
+		idxs[n-1].Columns = append(idxs[n-1].Columns, col)
+		return idxs
+	}
+	ix.Columns = []string{col}
+	return append(idxs, ix)
+}
+
+// sortIndexes orders idxs by table and index name, byte-wise: catalogs
+// sort by their collation, which may ignore case.
+func sortIndexes(idxs []Index) []Index {
+	slices.SortStableFunc(idxs, func(a, b Index) int {
+		return cmp.Or(cmp.Compare(a.Table, b.Table), cmp.Compare(a.Name, b.Name))
+	})
+	return idxs
+}
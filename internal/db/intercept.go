@@ -0,0 +1,209 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// Driver operations reported to an Interceptor in Call.Op.
+const (
+	OpPing          = "ping"
+	OpListTables    = "list_tables"
+	OpDescribeTable = "describe_table"
+	OpQuery         = "query"
+	OpInsert        = "insert"
+	OpUpdate        = "update"
+	OpCommit        = "commit"
+	OpRollback      = "rollback"
+)
+
+// Call describes one Driver or Tx call made through a Manager.
+type Call struct {
+	ConnectionID string
+	Op           string
+	// SQL is the statement run by OpQuery, OpInsert and OpUpdate, when the
+	// driver can show it; Params counts its positional parameters.
+	SQL    string
+	Params int
+	// InTx is set for the calls of a transaction from Manager.BeginTx.
+	InTx bool
+	// Duration and Err are set when After is called.
+	Duration time.Duration
+	Err      error
+}
+
+// Interceptor observes the calls made on the drivers and transactions a
+// Manager hands out, e.g. for auditing, metrics or slow-query warnings.
+// Both methods run on the calling goroutine and should return quickly.
+type Interceptor interface {
+	// Before is called as call starts.
+	Before(ctx context.Context, call *Call)
+	// After is called once the driver returned, with Duration and Err set.
+	After(ctx context.Context, call *Call)
+}
+
+// Use adds interceptors to the calls made on the drivers m hands out from
+// now on. Call it before the first Driver call.
+func (m *Manager) Use(interceptors ...Interceptor) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.interceptors = append(m.interceptors, interceptors...)
+}
+
+// Unwrap returns the driver behind d if d was wrapped by a Manager's
+// interceptors, and d otherwise. Use it to reach the optional interfaces of
+// a driver from Manager.Driver, such as WritePreviewer.
+func Unwrap(d Driver) Driver {
+	if i, ok := d.(*interceptedDriver); ok {
+		return i.Driver
+	}
+	return d
+}
+
+// intercept wraps d in m's interceptors, if it has any.
+func (m *Manager) intercept(id string, d Driver) Driver {
+	m.mu.Lock()
+	ics := m.interceptors
+	m.mu.Unlock()
+	if len(ics) == 0 {
+		return d
+	}
+	return &interceptedDriver{Driver: d, id: id, ics: ics}
+}
+
+// interceptTx wraps tx, begun on d, in m's interceptors, if it has any.
+func (m *Manager) interceptTx(id string, d Driver, tx Tx) Tx {
+	m.mu.Lock()
+	ics := m.interceptors
+	m.mu.Unlock()
+	if len(ics) == 0 {
+		return tx
+	}
+	return &interceptedTx{Tx: tx, d: d, id: id, ics: ics}
+}
+
+// observe runs fn as call, reporting it to ics.
+func observe(ctx context.Context, ics []Interceptor, call *Call, fn func() error) {
+	for _, ic := range ics {
+		ic.Before(ctx, call)
+	}
+	start := time.Now()
+	call.Err = fn()
+	call.Duration = time.Since(start)
+	for _, ic := range ics {
+		ic.After(ctx, call)
+	}
+}
+
+// writeCall describes an insert or update on d, with its SQL if d can
+// preview it.
+func writeCall(d Driver, id, op string, preview func(WritePreviewer) (string, []any)) *Call {
+	call := &Call{ConnectionID: id, Op: op}
+	if p, ok := d.(WritePreviewer); ok {
+		var params []any
+		call.SQL, params = preview(p)
+		call.Params = len(params)
+	}
+	return call
+}
+
+// interceptedDriver reports the calls on Driver to ics.
+type interceptedDriver struct {
+	Driver
+	id  string
+	ics []Interceptor
+}
+
+func (i *interceptedDriver) Ping(ctx context.Context) (err error) {
+	observe(ctx, i.ics, &Call{ConnectionID: i.id, Op: OpPing}, func() error {
+		err = i.Driver.Ping(ctx)
+		return err
+	})
+	return err
+}
+
+func (i *interceptedDriver) ListTables(ctx context.Context, schema string) (tables []string, err error) {
+	observe(ctx, i.ics, &Call{ConnectionID: i.id, Op: OpListTables}, func() error {
+		tables, err = i.Driver.ListTables(ctx, schema)
+		return err
+	})
+	return tables, err
+}
+
+func (i *interceptedDriver) DescribeTable(ctx context.Context, schema, table string) (cols []ColumnInfo, err error) {
+	observe(ctx, i.ics, &Call{ConnectionID: i.id, Op: OpDescribeTable}, func() error {
+		cols, err = i.Driver.DescribeTable(ctx, schema, table)
+		return err
+	})
+	return cols, err
+}
+
+func (i *interceptedDriver) RunReadOnlyQuery(ctx context.Context, sql string, params []any) (rows []map[string]any, err error) {
+	observe(ctx, i.ics, &Call{ConnectionID: i.id, Op: OpQuery, SQL: sql, Params: len(params)}, func() error {
+		rows, err = i.Driver.RunReadOnlyQuery(ctx, sql, params)
+		return err
+	})
+	return rows, err
+}
+
+func (i *interceptedDriver) InsertRow(ctx context.Context, schema, table string, row map[string]any) (id any, err error) {
+	call := writeCall(i.Driver, i.id, OpInsert, func(p WritePreviewer) (string, []any) { return p.InsertSQL(schema, table, row) })
+	observe(ctx, i.ics, call, func() error {
+		id, err = i.Driver.InsertRow(ctx, schema, table, row)
+		return err
+	})
+	return id, err
+}
+
+func (i *interceptedDriver) UpdateRow(ctx context.Context, schema, table string, key, set map[string]any) (n int64, err error) {
+	call := writeCall(i.Driver, i.id, OpUpdate, func(p WritePreviewer) (string, []any) { return p.UpdateSQL(schema, table, key, set) })
+	observe(ctx, i.ics, call, func() error {
+		n, err = i.Driver.UpdateRow(ctx, schema, table, key, set)
+		return err
+	})
+	return n, err
+}
+
+// interceptedTx reports the calls on a transaction begun on d to ics.
+type interceptedTx struct {
+	Tx
+	d   Driver
+	id  string
+	ics []Interceptor
+}
+
+func (i *interceptedTx) InsertRow(ctx context.Context, schema, table string, row map[string]any) (id any, err error) {
+	call := writeCall(i.d, i.id, OpInsert, func(p WritePreviewer) (string, []any) { return p.InsertSQL(schema, table, row) })
+	call.InTx = true
+	observe(ctx, i.ics, call, func() error {
+		id, err = i.Tx.InsertRow(ctx, schema, table, row)
+		return err
+	})
+	return id, err
+}
+
+func (i *interceptedTx) UpdateRow(ctx context.Context, schema, table string, key, set map[string]any) (n int64, err error) {
+	call := writeCall(i.d, i.id, OpUpdate, func(p WritePreviewer) (string, []any) { return p.UpdateSQL(schema, table, key, set) })
+	call.InTx = true
+	observe(ctx, i.ics, call, func() error {
+		n, err = i.Tx.UpdateRow(ctx, schema, table, key, set)
+		return err
+	})
+	return n, err
+}
+
+func (i *interceptedTx) Commit(ctx context.Context) (err error) {
+	observe(ctx, i.ics, &Call{ConnectionID: i.id, Op: OpCommit, InTx: true}, func() error {
+		err = i.Tx.Commit(ctx)
+		return err
+	})
+	return err
+}
+
+func (i *interceptedTx) Rollback(ctx context.Context) (err error) {
+	observe(ctx, i.ics, &Call{ConnectionID: i.id, Op: OpRollback, InTx: true}, func() error {
+		err = i.Tx.Rollback(ctx)
+		return err
+	})
+	return err
+}
@@ -0,0 +1,82 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+)
+
+// recorder is an Interceptor that keeps the calls it saw.
+type recorder struct {
+	before int
+	calls  []Call
+}
+
+func (r *recorder) Before(context.Context, *Call)       { r.before++ }
+func (r *recorder) After(_ context.Context, call *Call) { r.calls = append(r.calls, *call) }
+
+func TestManager_Use(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv(config.EnvSQLiteURI, ":memory:")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+	m := NewManager(cfg)
+	defer m.Close()
+	r := &recorder{}
+	m.Use(r)
+
+	d, err := m.Driver(ctx, "sqlite")
+	if err != nil {
+		t.Fatalf("Driver: %v", err)
+	}
+	raw, ok := Unwrap(d).(*SQLiteDriver)
+	if !ok {
+		t.Fatalf("Unwrap = %T, want *SQLiteDriver", Unwrap(d))
+	}
+	if _, err := raw.db.Exec("CREATE TABLE t (id INTEGER PRIMARY KEY, v TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := d.RunReadOnlyQuery(ctx, "SELECT * FROM t WHERE id = $1", []any{1}); err != nil {
+		t.Fatalf("RunReadOnlyQuery: %v", err)
+	}
+	if _, err := d.DescribeTable(ctx, "", "missing"); err != nil {
+		t.Fatalf("DescribeTable: %v", err)
+	}
+	if _, err := d.RunReadOnlyQuery(ctx, "SELECT * FROM missing", nil); err == nil {
+		t.Fatal("query on a missing table succeeded")
+	}
+	tx, err := m.BeginTx(ctx, "sqlite")
+	if err != nil {
+		t.Fatalf("BeginTx: %v", err)
+	}
+	if _, err := tx.InsertRow(ctx, "", "t", map[string]any{"v": "a"}); err != nil {
+		t.Fatalf("InsertRow: %v", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	want := []Call{
+		{Op: OpQuery, SQL: "SELECT * FROM t WHERE id = $1", Params: 1},
+		{Op: OpDescribeTable},
+		{Op: OpQuery, SQL: "SELECT * FROM missing"},
+		{Op: OpInsert, SQL: `INSERT INTO "t" ("v") VALUES (?1)`, Params: 1, InTx: true},
+		{Op: OpCommit, InTx: true},
+	}
+	if len(r.calls) != len(want) || r.before != len(want) {
+		t.Fatalf("saw %d calls (%d Before), want %d: %+v", len(r.calls), r.before, len(want), r.calls)
+	}
+	for i, w := range want {
+		got := r.calls[i]
+		if got.ConnectionID != "sqlite" || got.Op != w.Op || got.SQL != w.SQL || got.Params != w.Params || got.InTx != w.InTx {
+			t.Errorf("call %d = %+v, want %+v", i, got, w)
+		}
+		if (got.Err != nil) != (i == 2) {
+			t.Errorf("call %d: err = %v", i, got.Err)
+		}
+	}
+}
@@ -2,97 +2,485 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math/rand/v2"
+	"slices"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/SedlarDavid/localdb-mcp/internal/config"
 )
 
+// Connection attempts made by Manager.Driver before giving up. Waits between
+// attempts start at ConnectBackoff and double up to ConnectMaxBackoff, each
+// jittered so that clients retrying together do not hit a recovering
+// database in lockstep.
+const (
+	ConnectAttempts   = 4
+	ConnectBackoff    = 100 * time.Millisecond
+	ConnectMaxBackoff = 2 * time.Second
+)
+
 // Manager holds configuration and caches drivers by connection ID.
 type Manager struct {
 	cfg    *config.Config
 	mu     sync.Mutex
 	drivers map[string]Driver
+	pings   map[string]pingRecord // last successful ping per connection ID
+	used    map[string]time.Time  // last Driver call per cached connection ID
+	checks  map[string]checkRecord
+	dialing map[string]*dial // connection attempts in progress
+	working map[string]string // URI that last connected, for IDs with several
+	started bool          // the background loops are running
+	stop    chan struct{} // closed by Close to stop the background loops
+	closed  bool
+
+	interceptors []Interceptor // see Use; metrics comes first
+	closers      []func() error // see OnClose
+	metrics      *metrics
+
+	open    func(ctx context.Context, typ, uri string, opts ConnectOptions) (Driver, error)
+	backoff time.Duration // first wait between connection attempts
 }
 
+// ErrManagerClosed is returned by Driver after Close has been called.
+var ErrManagerClosed = errors.New("database connections are closed (server is shutting down)")
+
 // NewManager returns a manager that will create drivers from cfg.
 func NewManager(cfg *config.Config) *Manager {
+	mt := newMetrics()
 	return &Manager{
 		cfg:    cfg,
 		drivers: make(map[string]Driver),
+		pings:   make(map[string]pingRecord),
+		used:    make(map[string]time.Time),
+		checks:  make(map[string]checkRecord),
+		dialing: make(map[string]*dial),
+		working: make(map[string]string),
+		stop:    make(chan struct{}),
+		open:    openDriver,
+		backoff: ConnectBackoff,
+
+		interceptors: []Interceptor{mt},
+		metrics:      mt,
 	}
 }
 
 // Driver returns a Driver for the given connection ID, creating and caching it if needed.
+// Connecting is retried with backoff, so a database that is briefly down at
+// first use does not fail the call; a connection that still fails is not
+// cached, and the next call tries again. Drivers left unused for the
+// config's idle timeout are closed, and reopened on the next call; cached
+// drivers are pinged in the background and reconnected if they broke.
+// Connecting has its own deadline (the config's connect timeout for the
+// connection); if ctx ends first, Driver returns ctx's error and the
+// connection is still cached for the next call.
 func (m *Manager) Driver(ctx context.Context, connectionID string) (Driver, error) {
-	uri, ok := m.cfg.URI(connectionID)
+	d, err := m.driver(ctx, connectionID)
+	if err != nil {
+		return nil, err
+	}
+	return m.intercept(connectionID, d), nil
+}
+
+// driver is Driver without the interceptors, for the Manager's own use of
+// the optional driver interfaces.
+func (m *Manager) driver(ctx context.Context, connectionID string) (Driver, error) {
+	uris, ok := m.cfg.URIs(connectionID)
 	if !ok {
-		return nil, fmt.Errorf("unknown connection: %q", connectionID)
+		return nil, classify(ErrUnknownConnection, "unknown connection: %q", connectionID)
 	}
 	typ, _ := m.cfg.Type(connectionID)
 
 	m.mu.Lock()
 	d, cached := m.drivers[connectionID]
+	closed := m.closed
+	if cached && !closed {
+		m.used[connectionID] = time.Now()
+	}
 	m.mu.Unlock()
 
+	if closed {
+		return nil, ErrManagerClosed
+	}
 	if cached {
 		return d, nil
 	}
 
-	var newDriver Driver
+	if !supportedType(typ) {
+		return nil, classify(ErrNotSupported, "unsupported connection type %q for %q", typ, connectionID)
+	}
+
+	// Connect in the background under the connection's own deadline, so a
+	// caller that gives up early (e.g. a client with a short timeout) does
+	// not abort the connection for everyone else; it is cached for the next
+	// call. Concurrent callers share one attempt.
+	m.mu.Lock()
+	p, dialing := m.dialing[connectionID]
+	if !dialing {
+		p = &dial{done: make(chan struct{})}
+		m.dialing[connectionID] = p
+		go m.dial(connectionID, typ, uris, p)
+	}
+	m.mu.Unlock()
+
+	select {
+	case <-p.done:
+		return p.d, p.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// dial is a connection attempt in progress; d and err are set before done
+// is closed.
+type dial struct {
+	done chan struct{}
+	d    Driver
+	err  error
+}
+
+// dial connects to id within the config's connect timeout and caches the
+// driver, unless the manager was closed or id forgotten in the meantime.
+func (m *Manager) dial(id, typ string, uris []string, p *dial) {
+	defer close(p.done)
+	timeout := m.cfg.ConnectTimeout(id)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	go func() {
+		select {
+		case <-m.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	d, err := m.connect(ctx, id, typ, uris)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	forgotten := m.dialing[id] != p
+	if !forgotten {
+		delete(m.dialing, id)
+	}
+	switch {
+	case m.closed:
+		p.err = ErrManagerClosed
+	case err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded):
+		p.err = classify(ErrConnectFailed, "timed out connecting to %q (%s) after %s; verify the database is running, or raise connect_timeout in config.yaml", id, typ, timeout)
+	case err != nil:
+		// Return only a safe message — the raw error from the driver may
+		// contain the full DSN/URI (with credentials), so we must NOT
+		// log it.  Callers who need to debug connection issues should
+		// test the URI outside of the MCP server (e.g. psql, mysql CLI).
+		p.err = classify(ErrConnectFailed, "failed to connect to %q (%s) after %d attempts; verify the connection URI is correct and the database is running", id, typ, ConnectAttempts)
+		if len(uris) > 1 {
+			p.err = classify(ErrConnectFailed, "failed to connect to %q (%s) with any of its %d URIs after %d attempts; verify the connection URIs are correct and the database is running", id, typ, len(uris), ConnectAttempts)
+		}
+	case forgotten:
+		p.err = classify(ErrConnectFailed, "connection %q was reconfigured while connecting; try again", id)
+	}
+	if p.err != nil {
+		if d != nil {
+			d.Close()
+		}
+		return
+	}
+	p.d = d
+	m.drivers[id] = d
+	m.used[id] = time.Now()
+	if !m.started {
+		m.started = true
+		if ttl := m.cfg.IdleTimeout(); ttl > 0 {
+			go m.reap(ttl)
+		}
+		if every := m.cfg.HealthCheckInterval(); every > 0 {
+			go m.watchHealth(every)
+		}
+	}
+}
+
+// connect opens a driver for id, making up to ConnectAttempts attempts with
+// jittered exponential backoff between them. It stops early if ctx is done.
+// Each attempt tries uris in order, starting with the one that connected
+// last time, and the first that connects is remembered for the next.
+func (m *Manager) connect(ctx context.Context, id, typ string, uris []string) (Driver, error) {
+	opts := ConnectOptions{
+		ReadOnly:       m.cfg.ConnectionReadOnly(id),
+		SchemaCacheTTL: m.cfg.SchemaCacheTTL(),
+		Masker:         NewMasker(id, m.cfg.MaskRules()),
+	}
+	m.mu.Lock()
+	uris = preferURI(uris, m.working[id])
+	m.mu.Unlock()
+	wait := m.backoff
 	var err error
+	for attempt := 1; ; attempt++ {
+		for _, uri := range uris {
+			var d Driver
+			if d, err = m.open(ctx, typ, uri, opts); err == nil {
+				if len(uris) > 1 {
+					m.mu.Lock()
+					m.working[id] = uri
+					m.mu.Unlock()
+				}
+				return d, nil
+			}
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		if attempt == ConnectAttempts {
+			return nil, err
+		}
+		select {
+		case <-time.After(jitter(wait)):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		wait = min(2*wait, ConnectMaxBackoff)
+	}
+}
+
+// preferURI returns uris with first moved to the front, if it is one of
+// them.
+func preferURI(uris []string, first string) []string {
+	i := slices.Index(uris, first)
+	if i <= 0 {
+		return uris
+	}
+	out := append([]string{first}, uris[:i]...)
+	return append(out, uris[i+1:]...)
+}
+
+// jitter returns a random duration between d/2 and d.
+func jitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// supportedType reports whether openDriver knows the connection type.
+func supportedType(typ string) bool {
+	_, ok := CapabilitiesFor(typ)
+	return ok
+}
+
+// openDriver makes one attempt to connect to a database of type typ.
+func openDriver(ctx context.Context, typ, uri string, opts ConnectOptions) (Driver, error) {
 	switch typ {
 	case "postgres":
-		newDriver, err = NewPostgresDriver(ctx, uri)
+		return NewPostgresDriver(ctx, uri, opts)
 	case "sqlserver":
-		newDriver, err = NewSQLServerDriver(ctx, uri)
+		return NewSQLServerDriver(ctx, uri, opts)
 	case "sqlite":
-		newDriver, err = NewSQLiteDriver(ctx, uri)
+		return NewSQLiteDriver(ctx, uri, opts)
 	case "mysql":
-		newDriver, err = NewMySQLDriver(ctx, uri)
-	default:
-		return nil, fmt.Errorf("unsupported connection type %q for %q", typ, connectionID)
-	}
-	if err != nil {
-		// Return only a safe message — the raw error from the driver may
-		// contain the full DSN/URI (with credentials), so we must NOT
-		// log it.  Callers who need to debug connection issues should
-		// test the URI outside of the MCP server (e.g. psql, mysql CLI).
-		return nil, fmt.Errorf("failed to connect to %q (%s); verify the connection URI is correct", connectionID, typ)
+		return NewMySQLDriver(ctx, uri, opts)
+	case "demo":
+		return NewDemoDriver(ctx, opts)
 	}
+	return nil, fmt.Errorf("unsupported connection type %q", typ)
+}
 
+// drop closes d and removes it from the cache if it is still the driver for
+// id, so the next Driver call reconnects. It is used when d has stopped
+// answering, e.g. after the database restarted.
+func (m *Manager) drop(id string, d Driver) {
 	m.mu.Lock()
-	if existing, ok := m.drivers[connectionID]; ok {
+	if m.drivers[id] != d {
 		m.mu.Unlock()
-		newDriver.Close()
-		return existing, nil
+		return
 	}
-	m.drivers[connectionID] = newDriver
+	delete(m.drivers, id)
+	delete(m.used, id)
 	m.mu.Unlock()
+	d.Close()
+}
+
+// reap closes drivers that have been idle for ttl, checking every ttl/2
+// until Close is called.
+func (m *Manager) reap(ttl time.Duration) {
+	t := time.NewTicker(max(ttl/2, time.Second))
+	defer t.Stop()
+	for {
+		select {
+		case <-m.stop:
+			return
+		case now := <-t.C:
+			m.closeIdle(now.Add(-ttl))
+		}
+	}
+}
 
-	return newDriver, nil
+// closeIdle closes and drops the drivers last used before cutoff and returns
+// their IDs, sorted. The next Driver call for one of them reconnects.
+func (m *Manager) closeIdle(cutoff time.Time) []string {
+	m.mu.Lock()
+	var ids []string
+	var idle []Driver
+	for id, at := range m.used {
+		if d, ok := m.drivers[id]; ok && at.Before(cutoff) {
+			ids = append(ids, id)
+			idle = append(idle, d)
+			delete(m.drivers, id)
+			delete(m.used, id)
+		}
+	}
+	m.mu.Unlock()
+	for _, d := range idle {
+		d.Close()
+	}
+	sort.Strings(ids)
+	return ids
 }
 
-// Exporter returns an Exporter for the given connection ID, if the driver supports it.
+// Exporter returns an Exporter for the given connection ID, if the driver
+// supports it and export is not disabled in the config.
 func (m *Manager) Exporter(ctx context.Context, connectionID string) (Exporter, error) {
-	d, err := m.Driver(ctx, connectionID)
+	if m.cfg != nil && !m.cfg.ExportEnabled() {
+		return nil, classify(ErrNotSupported, "export and import are disabled by features.export in config.yaml")
+	}
+	d, err := m.driver(ctx, connectionID)
 	if err != nil {
 		return nil, err
 	}
 	exp, ok := d.(Exporter)
-	if !ok {
-		return nil, fmt.Errorf("driver for %q does not support export/import", connectionID)
+	if !ok || !d.Capabilities().Export {
+		return nil, classify(ErrNotSupported, "driver for %q does not support export/import", connectionID)
 	}
 	return exp, nil
 }
 
-// Close closes all cached drivers. Call when shutting down.
+// BeginTx starts a write transaction on connectionID. The transaction ends
+// with the driver: closing or forgetting the connection aborts it.
+func (m *Manager) BeginTx(ctx context.Context, connectionID string) (Tx, error) {
+	d, err := m.driver(ctx, connectionID)
+	if err != nil {
+		return nil, err
+	}
+	t, ok := d.(Transactor)
+	if !ok || !d.Capabilities().Transactions {
+		return nil, classify(ErrNotSupported, "driver for %q does not support transactions", connectionID)
+	}
+	tx, err := t.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return m.interceptTx(connectionID, d, tx), nil
+}
+
+// Forget closes and drops the cached drivers for ids, e.g. after their
+// connections were removed or changed by a config reload. The next Driver
+// call for an ID that is still configured connects afresh.
+func (m *Manager) Forget(ids ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var errs []error
+	for _, id := range ids {
+		delete(m.pings, id)
+		delete(m.used, id)
+		delete(m.checks, id)
+		delete(m.dialing, id)
+		delete(m.working, id)
+		d, ok := m.drivers[id]
+		if !ok {
+			continue
+		}
+		delete(m.drivers, id)
+		if err := d.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("close %q: %w", id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// PoolStats returns the pool statistics of id's cached driver. ok is false
+// if the connection is not open or its driver has no pool.
+func (m *Manager) PoolStats(id string) (stats PoolStats, ok bool) {
+	m.mu.Lock()
+	d, cached := m.drivers[id]
+	m.mu.Unlock()
+	p, isPool := d.(PoolStatter)
+	if !cached || !isPool {
+		return PoolStats{}, false
+	}
+	return p.PoolStats(), true
+}
+
+// InvalidateSchema drops the cached metadata of id's tables, e.g. after DDL
+// changed them: table in schema, every table in schema if table is empty,
+// or everything if both are empty. It returns the number of tables dropped,
+// zero if the connection is not open.
+func (m *Manager) InvalidateSchema(id, schema, table string) (int, error) {
+	if !m.cfg.HasConnection(id) {
+		return 0, classify(ErrUnknownConnection, "unknown connection: %q", id)
+	}
+	m.mu.Lock()
+	d := m.drivers[id]
+	m.mu.Unlock()
+	inv, ok := d.(SchemaInvalidator)
+	if !ok {
+		return 0, nil
+	}
+	return inv.InvalidateSchema(schema, table), nil
+}
+
+// Evict closes and drops the cached driver for id, so the next Driver call
+// connects afresh, e.g. after the database was restarted. Unlike Forget it
+// keeps the connection's ping history. It reports whether a driver was
+// cached.
+func (m *Manager) Evict(id string) (bool, error) {
+	m.mu.Lock()
+	d, ok := m.drivers[id]
+	delete(m.drivers, id)
+	delete(m.used, id)
+	m.mu.Unlock()
+	if !ok {
+		if !m.cfg.HasConnection(id) {
+			return false, classify(ErrUnknownConnection, "unknown connection: %q", id)
+		}
+		return false, nil
+	}
+	if err := d.Close(); err != nil {
+		return true, fmt.Errorf("close %q: %w", id, err)
+	}
+	return true, nil
+}
+
+// Close closes all cached drivers. Call when shutting down; afterwards
+// Driver returns ErrManagerClosed.
 func (m *Manager) Close() error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	if !m.closed {
+		close(m.stop)
+	}
+	m.closed = true
+	var errs []error
 	for id, d := range m.drivers {
-		_ = d.Close()
+		if err := d.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("close %q: %w", id, err))
+		}
 		delete(m.drivers, id)
+		delete(m.used, id)
+	}
+	for _, fn := range m.closers {
+		if err := fn(); err != nil {
+			errs = append(errs, err)
+		}
 	}
-	return nil
+	m.closers = nil
+	return errors.Join(errs...)
+}
+
+// OnClose registers fn to run when m is closed, after its drivers, for
+// resources that live as long as m, such as the interceptors' own files.
+func (m *Manager) OnClose(fn func() error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closers = append(m.closers, fn)
 }
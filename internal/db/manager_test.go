@@ -2,7 +2,13 @@ package db
 
 import (
 	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/SedlarDavid/localdb-mcp/internal/config"
 )
@@ -27,3 +33,320 @@ func TestManager_Close_idempotent(t *testing.T) {
 		t.Errorf("Close again: %v", err)
 	}
 }
+
+func TestManager_Driver_afterClose(t *testing.T) {
+	t.Setenv(config.EnvSQLiteURI, ":memory:")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+	m := NewManager(cfg)
+	ctx := context.Background()
+	if _, err := m.Driver(ctx, "sqlite"); err != nil {
+		t.Fatalf("Driver: %v", err)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if _, err := m.Driver(ctx, "sqlite"); !errors.Is(err, ErrManagerClosed) {
+		t.Errorf("Driver after Close: got %v, want ErrManagerClosed", err)
+	}
+}
+
+func TestManager_Forget(t *testing.T) {
+	t.Setenv(config.EnvSQLiteURI, ":memory:")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+	m := NewManager(cfg)
+	defer m.Close()
+	ctx := context.Background()
+	first, err := m.Driver(ctx, "sqlite")
+	if err != nil {
+		t.Fatalf("Driver: %v", err)
+	}
+	if err := m.Forget("sqlite", "unknown"); err != nil {
+		t.Fatalf("Forget: %v", err)
+	}
+	if err := first.Ping(ctx); err == nil {
+		t.Error("forgotten driver should be closed")
+	}
+	second, err := m.Driver(ctx, "sqlite")
+	if err != nil {
+		t.Fatalf("Driver after Forget: %v", err)
+	}
+	if second == Unwrap(first) {
+		t.Error("expected a new driver after Forget")
+	}
+}
+
+// flakyDriver is a Driver whose Ping fails once down is set.
+type flakyDriver struct {
+	Driver
+	down   bool
+	closed bool
+}
+
+func (d *flakyDriver) Ping(context.Context) error {
+	if d.down {
+		return errors.New("connection reset")
+	}
+	return nil
+}
+
+func (d *flakyDriver) Close() error { d.closed = true; return nil }
+
+func TestManager_Driver_retry(t *testing.T) {
+	t.Setenv(config.EnvSQLiteURI, ":memory:")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+	m := NewManager(cfg)
+	m.backoff = time.Millisecond
+	ctx := context.Background()
+
+	attempts, failures := 0, ConnectAttempts+2
+	m.open = func(context.Context, string, string, ConnectOptions) (Driver, error) {
+		attempts++
+		if attempts <= failures {
+			return nil, errors.New("connection refused")
+		}
+		return &flakyDriver{}, nil
+	}
+
+	// Down for longer than one call's attempts: the call fails, nothing is
+	// cached, and the next call retries and succeeds.
+	if _, err := m.Driver(ctx, "sqlite"); !errors.Is(err, ErrConnectFailed) {
+		t.Fatalf("Driver while down: got %v, want ErrConnectFailed", err)
+	}
+	if attempts != ConnectAttempts {
+		t.Errorf("attempts: got %d, want %d", attempts, ConnectAttempts)
+	}
+	d, err := m.Driver(ctx, "sqlite")
+	if err != nil {
+		t.Fatalf("Driver after recovery: %v", err)
+	}
+	if attempts != failures+1 {
+		t.Errorf("attempts: got %d, want %d", attempts, failures+1)
+	}
+
+	// A dropped connection is noticed by the health check and replaced on
+	// the next call.
+	Unwrap(d).(*flakyDriver).down = true
+	if h := m.Health(ctx, false); h[0].OK {
+		t.Errorf("health of a dropped connection: %+v", h[0])
+	}
+	if !Unwrap(d).(*flakyDriver).closed {
+		t.Error("dropped driver should be closed")
+	}
+	if again, err := m.Driver(ctx, "sqlite"); err != nil || Unwrap(again) == Unwrap(d) {
+		t.Errorf("Driver after drop: got %v, %v; want a new driver", again, err)
+	}
+}
+
+func TestManager_Driver_retryCancelled(t *testing.T) {
+	t.Setenv(config.EnvSQLiteURI, ":memory:")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+	m := NewManager(cfg)
+	defer m.Close()
+	m.backoff = time.Hour
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	m.open = func(context.Context, string, string, ConnectOptions) (Driver, error) {
+		return nil, errors.New("connection refused")
+	}
+	if _, err := m.Driver(ctx, "sqlite"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Driver: got %v, want the context's error", err)
+	}
+}
+
+func TestJitter(t *testing.T) {
+	for range 100 {
+		if d := jitter(time.Second); d < time.Second/2 || d > time.Second {
+			t.Fatalf("jitter(1s) = %v, want between 500ms and 1s", d)
+		}
+	}
+}
+
+func TestManager_closeIdle(t *testing.T) {
+	t.Setenv(config.EnvSQLiteURI, ":memory:")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+	m := NewManager(cfg)
+	defer m.Close()
+	m.open = func(context.Context, string, string, ConnectOptions) (Driver, error) { return &flakyDriver{}, nil }
+	ctx := context.Background()
+	first, err := m.Driver(ctx, "sqlite")
+	if err != nil {
+		t.Fatalf("Driver: %v", err)
+	}
+
+	if ids := m.closeIdle(time.Now().Add(-time.Minute)); len(ids) != 0 {
+		t.Errorf("recently used driver reaped: %v", ids)
+	}
+	if ids := m.closeIdle(time.Now().Add(time.Minute)); len(ids) != 1 || ids[0] != "sqlite" {
+		t.Errorf("closeIdle: got %v, want [sqlite]", ids)
+	}
+	if !Unwrap(first).(*flakyDriver).closed {
+		t.Error("idle driver should be closed")
+	}
+	if h := m.Health(ctx, false); h[0].Connected {
+		t.Errorf("reaped connection reported as connected: %+v", h[0])
+	}
+	if second, err := m.Driver(ctx, "sqlite"); err != nil || second == Unwrap(first) {
+		t.Errorf("Driver after reaping: got %v, %v; want a new driver", second, err)
+	}
+}
+
+func TestManager_Driver_connectOutlivesCaller(t *testing.T) {
+	t.Setenv(config.EnvSQLiteURI, ":memory:")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+	m := NewManager(cfg)
+	defer m.Close()
+	release := make(chan struct{})
+	opened := 0
+	m.open = func(context.Context, string, string, ConnectOptions) (Driver, error) {
+		opened++
+		<-release
+		return &flakyDriver{}, nil
+	}
+
+	// A caller with a short timeout gives up, but the connection it started
+	// is finished and cached for the next call.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := m.Driver(ctx, "sqlite"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Driver: got %v, want the caller's deadline", err)
+	}
+	close(release)
+	if _, err := m.Driver(context.Background(), "sqlite"); err != nil {
+		t.Fatalf("Driver: %v", err)
+	}
+	if opened != 1 {
+		t.Errorf("opened %d connections, want 1", opened)
+	}
+}
+
+func TestManager_Driver_connectTimeout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("connect_timeouts:\n  sqlite: 20ms\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(config.EnvConfigFile, path)
+	t.Setenv(config.EnvSQLiteURI, ":memory:")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+	m := NewManager(cfg)
+	defer m.Close()
+	m.open = func(ctx context.Context, _, _ string, _ ConnectOptions) (Driver, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	_, err = m.Driver(context.Background(), "sqlite")
+	if !errors.Is(err, ErrConnectFailed) || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("Driver: got %v, want a connect timeout", err)
+	}
+}
+
+func TestManager_Driver_failover(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(`
+connections:
+  sqlite: ["/docker/app.db", "/native/app.db", "/other/app.db"]
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(config.EnvConfigFile, path)
+	t.Setenv(config.EnvSQLiteURI, "")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+	m := NewManager(cfg)
+	defer m.Close()
+	m.backoff = time.Millisecond
+	var tried []string
+	up := map[string]bool{"/native/app.db": true, "/other/app.db": true}
+	m.open = func(_ context.Context, _, uri string, _ ConnectOptions) (Driver, error) {
+		tried = append(tried, uri)
+		if !up[uri] {
+			return nil, errors.New("no such file")
+		}
+		return &flakyDriver{}, nil
+	}
+	ctx := context.Background()
+
+	// The first candidate fails, the second connects.
+	if _, err := m.Driver(ctx, "sqlite"); err != nil {
+		t.Fatalf("Driver: %v", err)
+	}
+	if want := []string{"/docker/app.db", "/native/app.db"}; !slices.Equal(tried, want) {
+		t.Errorf("tried %q, want %q", tried, want)
+	}
+
+	// Reconnecting starts with the URI that worked.
+	tried = nil
+	if _, err := m.Evict("sqlite"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Driver(ctx, "sqlite"); err != nil {
+		t.Fatalf("Driver: %v", err)
+	}
+	if want := []string{"/native/app.db"}; !slices.Equal(tried, want) {
+		t.Errorf("tried %q after evict, want %q", tried, want)
+	}
+
+	// When none connects, every candidate is tried on every attempt.
+	tried = nil
+	up = nil
+	m.Evict("sqlite")
+	_, err = m.Driver(ctx, "sqlite")
+	if !errors.Is(err, ErrConnectFailed) || !strings.Contains(err.Error(), "any of its 3 URIs") {
+		t.Errorf("Driver: got %v, want a failure naming the URI count", err)
+	}
+	if len(tried) != 3*ConnectAttempts {
+		t.Errorf("tried %d URIs, want %d", len(tried), 3*ConnectAttempts)
+	}
+}
+
+func TestManager_Evict(t *testing.T) {
+	t.Setenv(config.EnvSQLiteURI, ":memory:")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+	m := NewManager(cfg)
+	defer m.Close()
+	ctx := context.Background()
+	first, err := m.Driver(ctx, "sqlite")
+	if err != nil {
+		t.Fatalf("Driver: %v", err)
+	}
+	if closed, err := m.Evict("sqlite"); !closed || err != nil {
+		t.Fatalf("Evict: %v, %v; want true", closed, err)
+	}
+	if err := first.Ping(ctx); err == nil {
+		t.Error("evicted driver should be closed")
+	}
+	if closed, err := m.Evict("sqlite"); closed || err != nil {
+		t.Errorf("Evict without a cached driver: %v, %v; want false", closed, err)
+	}
+	if _, err := m.Evict("nonexistent"); !errors.Is(err, ErrUnknownConnection) {
+		t.Errorf("Evict unknown: got %v, want ErrUnknownConnection", err)
+	}
+	if second, err := m.Driver(ctx, "sqlite"); err != nil || second == Unwrap(first) {
+		t.Errorf("Driver after Evict: got %v, %v; want a new driver", second, err)
+	}
+}
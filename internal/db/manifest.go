@@ -0,0 +1,184 @@
+package db
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// manifestPrefix starts the first line of every dump written by
+// ExportDatabase. The rest of the line is the JSON-encoded Manifest; being a
+// SQL comment, it is ignored by every engine's import tool.
+const manifestPrefix = "-- localdb-mcp-manifest: "
+
+// manifestFormat is the manifest layout version written by this build.
+// Importers refuse manifests with a newer format.
+const manifestFormat = 1
+
+// Manifest describes a dump: which engine and server produced it, which
+// tables it contains, and how many rows each had at export time. Row counts
+// are taken alongside the dump, not inside the same snapshot, so they are
+// approximate if the database was being written to concurrently.
+type Manifest struct {
+	Format        int             `json:"format"`
+	Engine        string          `json:"engine"`
+	ServerVersion string          `json:"server_version,omitempty"`
+	ToolVersion   string          `json:"tool_version,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	Tables        []ManifestTable `json:"tables"`
+}
+
+// ManifestTable is one table entry in a Manifest.
+type ManifestTable struct {
+	Name string `json:"name"`
+	Rows int64  `json:"rows"`
+}
+
+// manifestSource is implemented by drivers that can describe themselves in
+// a dump manifest.
+type manifestSource interface {
+	Driver
+	// engine returns the connection type ("postgres", "mysql", ...).
+	engine() string
+	// serverVersion returns the database server's version string.
+	serverVersion(ctx context.Context) (string, error)
+	// countRows returns the number of rows in a table of the default schema.
+	countRows(ctx context.Context, table string) (int64, error)
+}
+
+// buildManifest collects the manifest for d's default schema.
+func buildManifest(ctx context.Context, d manifestSource, toolVersion string) (*Manifest, error) {
+	version, err := d.serverVersion(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("manifest: server version: %w", err)
+	}
+	tables, err := d.ListTables(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("manifest: list tables: %w", err)
+	}
+	m := &Manifest{
+		Format:        manifestFormat,
+		Engine:        d.engine(),
+		ServerVersion: version,
+		ToolVersion:   toolVersion,
+		CreatedAt:     time.Now().UTC().Truncate(time.Second),
+		Tables:        make([]ManifestTable, 0, len(tables)),
+	}
+	for _, t := range tables {
+		n, err := d.countRows(ctx, t)
+		if err != nil {
+			return nil, fmt.Errorf("manifest: count rows in %s: %w", t, err)
+		}
+		m.Tables = append(m.Tables, ManifestTable{Name: t, Rows: n})
+	}
+	return m, nil
+}
+
+// manifestLine renders m as the dump's first line, including the newline.
+func (m *Manifest) manifestLine() (string, error) {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+	return manifestPrefix + string(b) + "\n", nil
+}
+
+// prependManifest writes the dump at src to dst, starting with the manifest
+// line, through a temp file renamed into place. src may be dst. Used for
+// dumps written by external CLI tools.
+func prependManifest(src, dst string, m *Manifest) error {
+	line, err := m.manifestLine()
+	if err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".localdb-mcp-export-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	if _, err := w.WriteString(line); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := io.Copy(w, in); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
+
+// writeManifestedExport builds d's manifest and writes it, followed by the
+// dump a CLI tool has just written to tmp, to path.
+func writeManifestedExport(ctx context.Context, d manifestSource, tmp, path string, opts ExportOptions) error {
+	m, err := buildManifest(ctx, d, opts.ToolVersion)
+	if err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	if err := prependManifest(tmp, path, m); err != nil {
+		return fmt.Errorf("export: write manifest: %w", err)
+	}
+	return nil
+}
+
+// ReadManifest returns the manifest at the start of the dump at path, or
+// nil if the dump has none (e.g. it was produced by another tool).
+func ReadManifest(path string) (*Manifest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	line, err := bufio.NewReader(f).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, manifestPrefix) {
+		return nil, nil
+	}
+	var m Manifest
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(line, manifestPrefix)), &m); err != nil {
+		return nil, fmt.Errorf("malformed dump manifest: %w", err)
+	}
+	return &m, nil
+}
+
+// checkManifest validates the dump at path against the engine it is about
+// to be imported into. Dumps without a manifest are accepted unchanged.
+func checkManifest(path, engine string) error {
+	m, err := ReadManifest(path)
+	if err != nil {
+		return fmt.Errorf("import: %w", err)
+	}
+	if m == nil {
+		return nil
+	}
+	if m.Format > manifestFormat {
+		return classify(ErrInvalidInput, "import: dump manifest format %d is newer than supported (%d); upgrade localdb-mcp", m.Format, manifestFormat)
+	}
+	if m.Engine != engine {
+		return classify(ErrInvalidInput, "import: dump was exported from %s but the target connection is %s; refusing to import", m.Engine, engine)
+	}
+	return nil
+}
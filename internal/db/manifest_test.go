@@ -0,0 +1,113 @@
+package db
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuildManifest_SQLite(t *testing.T) {
+	d := newTestSQLiteDriver(t)
+	defer d.Close()
+	ctx := context.Background()
+	if _, err := d.db.Exec(`INSERT INTO users (name) VALUES ('Alice'), ('Bob')`); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := buildManifest(ctx, d, "9.9.9")
+	if err != nil {
+		t.Fatalf("buildManifest: %v", err)
+	}
+	if m.Engine != "sqlite" || m.ToolVersion != "9.9.9" || m.ServerVersion == "" {
+		t.Errorf("unexpected manifest header: %+v", m)
+	}
+	if len(m.Tables) != 1 || m.Tables[0] != (ManifestTable{Name: "users", Rows: 2}) {
+		t.Errorf("unexpected tables: %+v", m.Tables)
+	}
+}
+
+func TestPrependAndReadManifest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dump.sql")
+	body := "CREATE TABLE t (id INTEGER);\n"
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	want := &Manifest{Format: manifestFormat, Engine: "postgres", ServerVersion: "16.2",
+		Tables: []ManifestTable{{Name: "t", Rows: 0}}}
+	if err := prependManifest(path, path, want); err != nil {
+		t.Fatalf("prependManifest: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), manifestPrefix) || !strings.HasSuffix(string(data), "\n"+body) {
+		t.Errorf("dump not rewritten as manifest + body:\n%s", data)
+	}
+
+	got, err := ReadManifest(path)
+	if err != nil {
+		t.Fatalf("ReadManifest: %v", err)
+	}
+	if got == nil || got.Engine != "postgres" || got.ServerVersion != "16.2" || len(got.Tables) != 1 {
+		t.Errorf("ReadManifest = %+v", got)
+	}
+}
+
+func TestCheckManifest(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	plain := write("plain.sql", "CREATE TABLE t (id INTEGER);\n")
+	pg := write("pg.sql", manifestPrefix+`{"format":1,"engine":"postgres","tables":[]}`+"\n")
+	future := write("future.sql", manifestPrefix+`{"format":99,"engine":"mysql","tables":[]}`+"\n")
+	broken := write("broken.sql", manifestPrefix+"{not json\n")
+
+	tests := []struct {
+		path    string
+		engine  string
+		wantErr string
+	}{
+		{plain, "mysql", ""},
+		{pg, "postgres", ""},
+		{pg, "mysql", "exported from postgres"},
+		{future, "mysql", "newer than supported"},
+		{broken, "mysql", "malformed dump manifest"},
+	}
+	for _, tt := range tests {
+		err := checkManifest(tt.path, tt.engine)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("checkManifest(%s, %s): unexpected error %v", filepath.Base(tt.path), tt.engine, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("checkManifest(%s, %s) = %v, want error containing %q", filepath.Base(tt.path), tt.engine, err, tt.wantErr)
+		}
+	}
+}
+
+func TestSQLite_ImportDatabase_rejectsForeignEngine(t *testing.T) {
+	d := newTestSQLiteDriver(t)
+	defer d.Close()
+
+	path := filepath.Join(t.TempDir(), "dump.sql")
+	script := manifestPrefix + `{"format":1,"engine":"mysql","tables":[]}` + "\n" +
+		"INSERT INTO users (name) VALUES ('Alice');\n"
+	if err := os.WriteFile(path, []byte(script), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	err := d.ImportDatabase(context.Background(), path, ImportOptions{AllowedDirs: []string{filepath.Dir(path)}})
+	if err == nil || !strings.Contains(err.Error(), "refusing to import") {
+		t.Fatalf("expected engine mismatch error, got %v", err)
+	}
+}
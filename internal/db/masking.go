@@ -0,0 +1,179 @@
+package db
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+)
+
+// Masker masks sensitive columns in the query results of one connection,
+// following the masking rules of the config file. A nil *Masker masks
+// nothing.
+//
+// A result column does not say which table it came from, so a rule applies
+// to every result column its column pattern matches, provided the statement
+// names a table its table pattern matches. Rules without a table pattern
+// apply to every statement; prefer them for columns that must never leak,
+// as a view or function hides the table it reads.
+type Masker struct {
+	rules []config.MaskRule // of this connection, patterns lower-cased
+}
+
+// maskHashKey keys the hash mask, so hashed values can be compared within
+// one run of the server but not looked up in a precomputed table.
+var maskHashKey = func() []byte {
+	key := make([]byte, 32)
+	rand.Read(key)
+	return key
+}()
+
+// sqlWord matches the identifiers of a statement that a table pattern is
+// tested against.
+var sqlWord = regexp.MustCompile(`[A-Za-z_][\w$]*`)
+
+// NewMasker returns the Masker for connection id, or nil if no rule applies
+// to it.
+func NewMasker(id string, rules []config.MaskRule) *Masker {
+	var m Masker
+	for _, r := range rules {
+		if r.Connection != "" && !globMatch(r.Connection, id) {
+			continue
+		}
+		m.rules = append(m.rules, config.MaskRule{
+			Table:  strings.ToLower(r.Table),
+			Column: strings.ToLower(r.Column),
+			Mask:   r.Mask,
+		})
+	}
+	if len(m.rules) == 0 {
+		return nil
+	}
+	return &m
+}
+
+// columnMask returns the mask for a result column, or nil to leave it as
+// it is.
+type columnMask func(column string) func(any) any
+
+// forQuery returns the columnMask for the results of statement sql, or nil
+// if no rule applies to it.
+func (m *Masker) forQuery(sql string) columnMask {
+	rules := m.rulesFor(sql)
+	if len(rules) == 0 {
+		return nil
+	}
+	return func(column string) func(any) any {
+		column = strings.ToLower(column)
+		for _, r := range rules {
+			if globMatch(r.Column, column) {
+				return maskFunc(r.Mask)
+			}
+		}
+		return nil
+	}
+}
+
+// ColumnPatterns returns the column patterns of the rules that apply to
+// statement sql, lower-cased.
+func (m *Masker) ColumnPatterns(sql string) []string {
+	var patterns []string
+	for _, r := range m.rulesFor(sql) {
+		patterns = append(patterns, r.Column)
+	}
+	return patterns
+}
+
+// MasksTable reports whether a rule that applies to statement sql covers
+// table, a lower-case name; rules without a table cover every table.
+func (m *Masker) MasksTable(sql, table string) bool {
+	for _, r := range m.rulesFor(sql) {
+		if r.Table == "" || globMatch(r.Table, table) {
+			return true
+		}
+	}
+	return false
+}
+
+// rulesFor returns the rules that apply to statement sql.
+func (m *Masker) rulesFor(sql string) []config.MaskRule {
+	if m == nil {
+		return nil
+	}
+	var words []string
+	var rules []config.MaskRule
+	for _, r := range m.rules {
+		if r.Table != "" {
+			if words == nil {
+				words = sqlWord.FindAllString(strings.ToLower(sql), -1)
+			}
+			if !anyGlobMatch(r.Table, words) {
+				continue
+			}
+		}
+		rules = append(rules, r)
+	}
+	return rules
+}
+
+// MatchesColumn reports whether name matches one of patterns, as returned
+// by ColumnPatterns.
+func MatchesColumn(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if globMatch(p, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// maskFunc returns the function applying mask to a value. NULL stays NULL.
+func maskFunc(mask string) func(any) any {
+	return func(v any) any {
+		if v == nil {
+			return nil
+		}
+		switch mask {
+		case config.MaskHash:
+			h := hmac.New(sha256.New, maskHashKey)
+			h.Write([]byte(maskString(v)))
+			return "hash:" + hex.EncodeToString(h.Sum(nil)[:8])
+		case config.MaskPartial:
+			s := []rune(maskString(v))
+			keep := 4
+			if len(s) <= 2*keep {
+				keep = len(s) / 4
+			}
+			return strings.Repeat("*", len(s)-keep) + string(s[len(s)-keep:])
+		}
+		return "[REDACTED]"
+	}
+}
+
+func maskString(v any) string {
+	if b, ok := v.([]byte); ok {
+		return string(b)
+	}
+	return fmt.Sprint(v)
+}
+
+// globMatch reports whether name matches pattern, case-insensitively.
+func globMatch(pattern, name string) bool {
+	ok, _ := filepath.Match(strings.ToLower(pattern), strings.ToLower(name))
+	return ok
+}
+
+func anyGlobMatch(pattern string, names []string) bool {
+	for _, n := range names {
+		if globMatch(pattern, n) {
+			return true
+		}
+	}
+	return false
+}
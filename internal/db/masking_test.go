@@ -0,0 +1,110 @@
+package db
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+)
+
+func TestMaskFunc(t *testing.T) {
+	tests := []struct {
+		mask string
+		in   any
+		want string
+	}{
+		{config.MaskRedact, "s3cret", "[REDACTED]"},
+		{config.MaskRedact, 42, "[REDACTED]"},
+		{config.MaskPartial, "123-45-6789", "*******6789"},
+		{config.MaskPartial, []byte("tok_abcdefgh"), "********efgh"},
+		{config.MaskPartial, "abcdefgh", "******gh"},
+		{config.MaskPartial, "abc", "***"},
+	}
+	for _, tt := range tests {
+		if got := maskFunc(tt.mask)(tt.in); got != tt.want {
+			t.Errorf("%s(%v) = %v, want %q", tt.mask, tt.in, got, tt.want)
+		}
+	}
+	if got := maskFunc(config.MaskRedact)(nil); got != nil {
+		t.Errorf("NULL masked to %v, want NULL", got)
+	}
+
+	hash := maskFunc(config.MaskHash)
+	a, b := hash("alice@example.com"), hash("alice@example.com")
+	if a != b || !strings.HasPrefix(a.(string), "hash:") || strings.Contains(a.(string), "alice") {
+		t.Errorf("hash = %v, %v; want equal hashes of the value", a, b)
+	}
+	if hash("bob@example.com") == a {
+		t.Error("different values hashed alike")
+	}
+}
+
+func TestMasker_forQuery(t *testing.T) {
+	m := NewMasker("app", []config.MaskRule{
+		{Connection: "other", Column: "*", Mask: config.MaskRedact},
+		{Column: "password*", Mask: config.MaskRedact},
+		{Connection: "app", Table: "Users", Column: "email", Mask: config.MaskHash},
+	})
+	if NewMasker("none", []config.MaskRule{{Connection: "app", Column: "*", Mask: config.MaskRedact}}) != nil {
+		t.Error("masker for a connection no rule applies to should be nil")
+	}
+	var nilMasker *Masker
+	if nilMasker.forQuery("SELECT 1") != nil {
+		t.Error("nil masker should mask nothing")
+	}
+
+	mask := m.forQuery("SELECT u.email, u.PASSWORD_HASH, o.total FROM users u JOIN orders o ON o.user_id = u.id")
+	if mask == nil {
+		t.Fatal("forQuery: want a mask")
+	}
+	if f := mask("PASSWORD_HASH"); f == nil || f("x") != "[REDACTED]" {
+		t.Error("password_hash should be redacted")
+	}
+	if f := mask("email"); f == nil || !strings.HasPrefix(f("a@b.c").(string), "hash:") {
+		t.Error("email should be hashed when users is queried")
+	}
+	if mask("total") != nil {
+		t.Error("total should not be masked")
+	}
+
+	// The table rule only applies to statements naming the table.
+	mask = m.forQuery("SELECT email, password FROM customers")
+	if mask("email") != nil {
+		t.Error("email of customers should not be masked")
+	}
+	if mask("password") == nil {
+		t.Error("a rule without table applies to every statement")
+	}
+
+	users := NewMasker("app", []config.MaskRule{{Table: "Users", Column: "email", Mask: config.MaskHash}})
+	if !users.MasksTable("SELECT u FROM users u", "users") || users.MasksTable("SELECT u FROM users u", "orders") {
+		t.Error("MasksTable should cover users only")
+	}
+	if !m.MasksTable("SELECT * FROM orders", "orders") {
+		t.Error("a rule without table covers every table")
+	}
+}
+
+func TestSQLite_RunReadOnlyQuery_masked(t *testing.T) {
+	ctx := context.Background()
+	masker := NewMasker("sqlite", []config.MaskRule{{Table: "users", Column: "email", Mask: config.MaskPartial}})
+	d, err := NewSQLiteDriver(ctx, ":memory:", ConnectOptions{Masker: masker})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	if _, err := d.db.ExecContext(ctx, `CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT)`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.InsertRow(ctx, "", "users", map[string]any{"email": "ada@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := d.RunReadOnlyQuery(ctx, "SELECT id, email FROM users", nil)
+	if err != nil {
+		t.Fatalf("RunReadOnlyQuery: %v", err)
+	}
+	if len(rows) != 1 || rows[0]["email"] != "***********.com" || rows[0]["id"] != int64(1) {
+		t.Errorf("rows = %v, want email masked and id unchanged", rows)
+	}
+}
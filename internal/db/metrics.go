@@ -0,0 +1,139 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// LatencyBuckets are the upper bounds of the latency histogram kept per
+// connection; slower calls are only counted in CallMetrics.Calls.
+var LatencyBuckets = []time.Duration{
+	time.Millisecond, 5 * time.Millisecond, 10 * time.Millisecond, 25 * time.Millisecond,
+	50 * time.Millisecond, 100 * time.Millisecond, 250 * time.Millisecond, 500 * time.Millisecond,
+	time.Second, 2500 * time.Millisecond, 5 * time.Second, 10 * time.Second,
+}
+
+// Error classes counted in CallMetrics.Errors.
+const (
+	ErrorClassInvalidInput = "invalid_input"
+	ErrorClassNotFound     = "not_found"
+	ErrorClassNotSupported = "not_supported"
+	ErrorClassConnection   = "connection"
+	ErrorClassTimeout      = "timeout"
+	ErrorClassCancelled    = "cancelled"
+	ErrorClassDatabase     = "database" // reported by the database itself
+)
+
+// ErrorClass returns the class a failed driver call is counted under.
+func ErrorClass(err error) string {
+	switch {
+	case errors.Is(err, ErrInvalidInput):
+		return ErrorClassInvalidInput
+	case errors.Is(err, ErrNotFound):
+		return ErrorClassNotFound
+	case errors.Is(err, ErrNotSupported):
+		return ErrorClassNotSupported
+	case errors.Is(err, ErrConnectFailed), errors.Is(err, ErrManagerClosed):
+		return ErrorClassConnection
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorClassTimeout
+	case errors.Is(err, context.Canceled):
+		return ErrorClassCancelled
+	}
+	return ErrorClassDatabase
+}
+
+// CallMetrics summarizes the driver calls made on one connection since the
+// Manager was created: how long they took, as seen by the server, and how
+// they failed.
+type CallMetrics struct {
+	Calls int64 `json:"calls"`
+	// Latency holds the cumulative histogram over LatencyBuckets.
+	Latency []LatencyBucket `json:"latency"`
+	TotalMS float64         `json:"total_ms"`
+	// Errors counts failed calls by error class (see ErrorClass).
+	Errors map[string]int64 `json:"errors,omitempty"`
+}
+
+// LatencyBucket counts the calls that took at most LEMS milliseconds.
+type LatencyBucket struct {
+	LEMS  float64 `json:"le_ms"`
+	Count int64   `json:"count"`
+}
+
+// metrics is the Interceptor behind Manager.CallMetrics.
+type metrics struct {
+	mu     sync.Mutex
+	byConn map[string]*connMetrics
+}
+
+type connMetrics struct {
+	calls   int64
+	buckets []int64 // per LatencyBuckets entry, not cumulative
+	total   time.Duration
+	errors  map[string]int64
+}
+
+func newMetrics() *metrics {
+	return &metrics{byConn: make(map[string]*connMetrics)}
+}
+
+func (*metrics) Before(context.Context, *Call) {}
+
+func (m *metrics) After(_ context.Context, call *Call) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.byConn[call.ConnectionID]
+	if !ok {
+		c = &connMetrics{buckets: make([]int64, len(LatencyBuckets)), errors: make(map[string]int64)}
+		m.byConn[call.ConnectionID] = c
+	}
+	c.calls++
+	c.total += call.Duration
+	for i, le := range LatencyBuckets {
+		if call.Duration <= le {
+			c.buckets[i]++
+			break
+		}
+	}
+	if call.Err != nil {
+		c.errors[ErrorClass(call.Err)]++
+	}
+}
+
+// snapshot returns the metrics of connection id; ok is false if no call was
+// made on it yet.
+func (m *metrics) snapshot(id string) (out CallMetrics, ok bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.byConn[id]
+	if !ok {
+		return CallMetrics{}, false
+	}
+	out = CallMetrics{
+		Calls:   c.calls,
+		Latency: make([]LatencyBucket, len(LatencyBuckets)),
+		TotalMS: float64(c.total) / float64(time.Millisecond),
+	}
+	var n int64
+	for i, le := range LatencyBuckets {
+		n += c.buckets[i]
+		out.Latency[i] = LatencyBucket{LEMS: float64(le) / float64(time.Millisecond), Count: n}
+	}
+	if len(c.errors) > 0 {
+		out.Errors = make(map[string]int64, len(c.errors))
+		for class, n := range c.errors {
+			out.Errors[class] = n
+		}
+	}
+	return out, true
+}
+
+// CallMetrics returns the latency histogram and error counts of the calls
+// made on connection id's drivers and transactions. ok is false if none
+// was made yet.
+func (m *Manager) CallMetrics(id string) (CallMetrics, bool) {
+	return m.metrics.snapshot(id)
+}
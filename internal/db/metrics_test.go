@@ -0,0 +1,66 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestErrorClass(t *testing.T) {
+	for _, tt := range []struct {
+		err  error
+		want string
+	}{
+		{classify(ErrInvalidInput, "bad"), ErrorClassInvalidInput},
+		{classify(ErrNotFound, "gone"), ErrorClassNotFound},
+		{classify(ErrNotSupported, "no"), ErrorClassNotSupported},
+		{classify(ErrConnectFailed, "down"), ErrorClassConnection},
+		{fmt.Errorf("query: %w", context.DeadlineExceeded), ErrorClassTimeout},
+		{context.Canceled, ErrorClassCancelled},
+		{errors.New("syntax error"), ErrorClassDatabase},
+	} {
+		if got := ErrorClass(tt.err); got != tt.want {
+			t.Errorf("ErrorClass(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	m := newMetrics()
+	if _, ok := m.snapshot("sqlite"); ok {
+		t.Error("metrics before any call")
+	}
+	ctx := context.Background()
+	for _, call := range []*Call{
+		{ConnectionID: "sqlite", Duration: 3 * time.Millisecond},
+		{ConnectionID: "sqlite", Duration: 200 * time.Millisecond, Err: errors.New("syntax error")},
+		{ConnectionID: "sqlite", Duration: time.Minute, Err: context.DeadlineExceeded},
+		{ConnectionID: "other", Duration: time.Millisecond},
+	} {
+		m.After(ctx, call)
+	}
+	got, ok := m.snapshot("sqlite")
+	if !ok || got.Calls != 3 {
+		t.Fatalf("snapshot = %+v, %v", got, ok)
+	}
+	if got.TotalMS != 60203 {
+		t.Errorf("TotalMS = %v, want 60203", got.TotalMS)
+	}
+	for _, b := range got.Latency {
+		want := int64(0)
+		switch {
+		case b.LEMS >= 250:
+			want = 2
+		case b.LEMS >= 5:
+			want = 1
+		}
+		if b.Count != want {
+			t.Errorf("bucket le %vms = %d, want %d", b.LEMS, b.Count, want)
+		}
+	}
+	if got.Errors[ErrorClassDatabase] != 1 || got.Errors[ErrorClassTimeout] != 1 || len(got.Errors) != 2 {
+		t.Errorf("Errors = %v", got.Errors)
+	}
+}
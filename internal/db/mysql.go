@@ -3,22 +3,41 @@ package db
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
+	"regexp"
 	"strings"
+	"time"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 )
 
 // MySQLDriver implements Driver for MySQL using go-sql-driver/mysql.
 type MySQLDriver struct {
 	db  *sql.DB
 	dsn string
+
+	schemaCache // DescribeTable results
+	masker      *Masker
 }
 
 // NewMySQLDriver connects to MySQL using the given DSN
-// (e.g. "user:password@tcp(localhost:3306)/dbname").
-func NewMySQLDriver(ctx context.Context, dsn string) (*MySQLDriver, error) {
-	db, err := sql.Open("mysql", dsn)
+// (e.g. "user:password@tcp(localhost:3306)/dbname"). With opts.ReadOnly
+// every session sets transaction_read_only.
+func NewMySQLDriver(ctx context.Context, dsn string, opts ConnectOptions) (*MySQLDriver, error) {
+	openDSN := dsn
+	if opts.ReadOnly {
+		cfg, err := mysql.ParseDSN(dsn)
+		if err != nil {
+			return nil, fmt.Errorf("mysql open: %w", err)
+		}
+		if cfg.Params == nil {
+			cfg.Params = make(map[string]string)
+		}
+		cfg.Params["transaction_read_only"] = "1"
+		openDSN = cfg.FormatDSN()
+	}
+	db, err := sql.Open("mysql", openDSN)
 	if err != nil {
 		return nil, fmt.Errorf("mysql open: %w", err)
 	}
@@ -26,7 +45,7 @@ func NewMySQLDriver(ctx context.Context, dsn string) (*MySQLDriver, error) {
 		db.Close()
 		return nil, fmt.Errorf("mysql ping: %w", err)
 	}
-	return &MySQLDriver{db: db, dsn: dsn}, nil
+	return &MySQLDriver{db: db, dsn: dsn, schemaCache: newSchemaCache(opts.SchemaCacheTTL), masker: opts.Masker}, nil
 }
 
 // Ping implements Driver.
@@ -34,6 +53,17 @@ func (d *MySQLDriver) Ping(ctx context.Context) error {
 	return d.db.PingContext(ctx)
 }
 
+// Capabilities implements Driver.
+func (d *MySQLDriver) Capabilities() Capabilities {
+	caps, _ := CapabilitiesFor(d.engine())
+	return caps
+}
+
+// PoolStats implements PoolStatter.
+func (d *MySQLDriver) PoolStats() PoolStats {
+	return sqlPoolStats(d.db.Stats())
+}
+
 // ListTables implements Driver. Schema maps to the MySQL database; if empty
 // the current database (from the DSN) is used.
 func (d *MySQLDriver) ListTables(ctx context.Context, schema string) ([]string, error) {
@@ -65,9 +95,15 @@ func (d *MySQLDriver) ListTables(ctx context.Context, schema string) ([]string,
 	return names, rows.Err()
 }
 
-// DescribeTable implements Driver. Schema maps to MySQL database; if empty
-// the current database is used.
+// DescribeTable implements Driver. Results are cached per table for the
+// connection's schema cache TTL; see SchemaInvalidator.
 func (d *MySQLDriver) DescribeTable(ctx context.Context, schema, table string) ([]ColumnInfo, error) {
+	return d.describe(ctx, schema, table, d.describeTable)
+}
+
+// describeTable queries the catalog for DescribeTable. Schema maps to
+// MySQL database; if empty the current database is used.
+func (d *MySQLDriver) describeTable(ctx context.Context, schema, table string) ([]ColumnInfo, error) {
 	var query string
 	var args []any
 	if schema == "" {
@@ -108,16 +144,140 @@ func (d *MySQLDriver) DescribeTable(ctx context.Context, schema, table string) (
 	return cols, rows.Err()
 }
 
+// ForeignKeys implements ForeignKeyLister. Schema maps to the MySQL
+// database; if empty the current database is used.
+func (d *MySQLDriver) ForeignKeys(ctx context.Context, schema string) ([]ForeignKey, error) {
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT k.CONSTRAINT_NAME, k.TABLE_NAME, k.COLUMN_NAME,
+		       k.REFERENCED_TABLE_NAME, k.REFERENCED_COLUMN_NAME,
+		       r.DELETE_RULE, r.UPDATE_RULE
+		FROM INFORMATION_SCHEMA.KEY_COLUMN_USAGE k
+		JOIN INFORMATION_SCHEMA.REFERENTIAL_CONSTRAINTS r
+		  ON r.CONSTRAINT_SCHEMA = k.CONSTRAINT_SCHEMA AND r.CONSTRAINT_NAME = k.CONSTRAINT_NAME
+		 AND r.TABLE_NAME = k.TABLE_NAME
+		WHERE k.TABLE_SCHEMA = COALESCE(NULLIF(?, ''), DATABASE())
+		  AND k.REFERENCED_TABLE_SCHEMA = k.TABLE_SCHEMA
+		ORDER BY k.TABLE_NAME, k.CONSTRAINT_NAME, k.ORDINAL_POSITION`,
+		schema)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var fks []ForeignKey
+	for rows.Next() {
+		var fk ForeignKey
+		var col, refCol string
+		if err := rows.Scan(&fk.Name, &fk.Table, &col, &fk.RefTable, &refCol, &fk.OnDelete, &fk.OnUpdate); err != nil {
+			return nil, err
+		}
+		fks = appendForeignKeyColumn(fks, fk, col, refCol)
+	}
+	return fks, rows.Err()
+}
+
+// Dependents implements DependencyLister. Schema maps to the MySQL
+// database; if empty the current database is used. MySQL records which
+// tables views use (8.0.13 and later) and which table a trigger is on;
+// routines of the database are found by the table's name in their body.
+func (d *MySQLDriver) Dependents(ctx context.Context, schema, table string) ([]Dependent, error) {
+	mentions := `(^|[^[:alnum:]_$])` + "`?" + regexp.QuoteMeta(table) + "`?" + `([^[:alnum:]_$]|$)`
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT 'view', VIEW_SCHEMA, VIEW_NAME, FALSE
+		FROM INFORMATION_SCHEMA.VIEW_TABLE_USAGE
+		WHERE TABLE_SCHEMA = COALESCE(NULLIF(?, ''), DATABASE()) AND TABLE_NAME = ?
+		UNION
+		SELECT LOWER(ROUTINE_TYPE), ROUTINE_SCHEMA, ROUTINE_NAME, TRUE
+		FROM INFORMATION_SCHEMA.ROUTINES
+		WHERE ROUTINE_SCHEMA = COALESCE(NULLIF(?, ''), DATABASE()) AND ROUTINE_DEFINITION REGEXP ?
+		UNION
+		SELECT 'trigger', TRIGGER_SCHEMA, TRIGGER_NAME, FALSE
+		FROM INFORMATION_SCHEMA.TRIGGERS
+		WHERE EVENT_OBJECT_SCHEMA = COALESCE(NULLIF(?, ''), DATABASE()) AND EVENT_OBJECT_TABLE = ?
+		ORDER BY 1, 2, 3`,
+		schema, table, schema, mentions, schema, table)
+	if err != nil {
+		return nil, err
+	}
+	return scanDependents(rows)
+}
+
+// Indexes implements IndexLister. Schema maps to the MySQL database; if
+// empty the current database is used. MySQL has no partial indexes.
+func (d *MySQLDriver) Indexes(ctx context.Context, schema string) ([]Index, error) {
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT INDEX_NAME, TABLE_NAME, COALESCE(COLUMN_NAME, ''), NON_UNIQUE = 0, INDEX_NAME = 'PRIMARY'
+		FROM INFORMATION_SCHEMA.STATISTICS
+		WHERE TABLE_SCHEMA = COALESCE(NULLIF(?, ''), DATABASE())
+		ORDER BY TABLE_NAME, INDEX_NAME, SEQ_IN_INDEX`,
+		schema)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var idxs []Index
+	for rows.Next() {
+		var ix Index
+		var col string
+		if err := rows.Scan(&ix.Name, &ix.Table, &col, &ix.Unique, &ix.Primary); err != nil {
+			return nil, err
+		}
+		idxs = appendIndexColumn(idxs, ix, col)
+	}
+	return sortIndexes(idxs), rows.Err()
+}
+
 // RunReadOnlyQuery implements Driver. Converts $1, $2 placeholders to MySQL's
 // positional ? syntax.
 func (d *MySQLDriver) RunReadOnlyQuery(ctx context.Context, query string, params []any) ([]map[string]any, error) {
 	query = convertPlaceholdersToMySQL(query)
-	rows, err := d.db.QueryContext(ctx, query, params...)
+	var out []map[string]any
+	err := d.killOnCancel(ctx, func(conn *sql.Conn) error {
+		rows, err := conn.QueryContext(ctx, query, params...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		out, err = sqlRowsToMaps(rows, d.masker.forQuery(query))
+		return err
+	})
+	return out, err
+}
+
+// mysqlKillTimeout bounds the KILL QUERY sent for a cancelled statement.
+const mysqlKillTimeout = 5 * time.Second
+
+// killOnCancel runs fn on a connection of its own and, if ctx ends first,
+// stops the statement fn is running with KILL QUERY. go-sql-driver/mysql
+// only closes the connection when a context ends, and MySQL keeps running
+// the statement until it next writes to the client.
+func (d *MySQLDriver) killOnCancel(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := d.db.Conn(ctx)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer rows.Close()
-	return sqlRowsToMaps(rows)
+	defer conn.Close()
+	var id int64
+	if err := conn.QueryRowContext(ctx, "SELECT CONNECTION_ID()").Scan(&id); err != nil {
+		return err
+	}
+
+	killed := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		defer close(killed)
+		kctx, cancel := context.WithTimeout(context.Background(), mysqlKillTimeout)
+		defer cancel()
+		// Best effort: if this fails the statement runs to completion, as
+		// it did before.
+		d.db.ExecContext(kctx, fmt.Sprintf("KILL QUERY %d", id))
+	})
+	err = fn(conn)
+	if !stop() {
+		// The kill may have landed after fn finished; don't hand a
+		// connection with a pending kill back to the pool.
+		<-killed
+		conn.Raw(func(any) error { return driver.ErrBadConn })
+	}
+	return err
 }
 
 // convertPlaceholdersToMySQL replaces $1, $2, ... with ? for go-sql-driver/mysql.
@@ -128,20 +288,25 @@ func convertPlaceholdersToMySQL(s string) string {
 
 // InsertRow implements Driver.
 func (d *MySQLDriver) InsertRow(ctx context.Context, schema, table string, row map[string]any) (any, error) {
+	var id any
+	err := d.killOnCancel(ctx, func(conn *sql.Conn) error {
+		var err error
+		id, err = d.insertRow(ctx, conn, d, schema, table, row)
+		return err
+	})
+	return id, err
+}
+
+// insertRow runs InsertRow on q, the pool or a transaction.
+func (d *MySQLDriver) insertRow(ctx context.Context, q sqlConn, desc tableDescriber, schema, table string, row map[string]any) (any, error) {
 	if len(row) == 0 {
-		return nil, fmt.Errorf("insert row: no columns")
+		return nil, classify(ErrInvalidInput, "insert row: no columns")
 	}
-	cols, vals := mapsToColumnsAndValues(row)
-	placeholders := makeMySQLPlaceholders(len(cols))
-	quotedTable := quoteMySQLTable(schema, table)
-	quotedCols := make([]string, len(cols))
-	for i, c := range cols {
-		quotedCols[i] = quoteMySQLIdentifier(c)
+	if _, err := checkColumns(ctx, desc, "insert row", schema, table, row); err != nil {
+		return nil, err
 	}
-	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
-		quotedTable, joinQuoted(quotedCols), placeholders)
-
-	result, err := d.db.ExecContext(ctx, query, vals...)
+	query, vals := d.InsertSQL(schema, table, row)
+	result, err := q.ExecContext(ctx, query, vals...)
 	if err != nil {
 		return nil, err
 	}
@@ -154,18 +319,83 @@ func (d *MySQLDriver) InsertRow(ctx context.Context, schema, table string, row m
 
 // UpdateRow implements Driver. Validates key matches actual PK, then updates a single row.
 func (d *MySQLDriver) UpdateRow(ctx context.Context, schema, table string, key map[string]any, set map[string]any) (int64, error) {
+	var n int64
+	err := d.killOnCancel(ctx, func(conn *sql.Conn) error {
+		var err error
+		n, err = d.updateRow(ctx, conn, d, schema, table, key, set)
+		return err
+	})
+	return n, err
+}
+
+// updateRow runs UpdateRow on q, the pool or a transaction.
+func (d *MySQLDriver) updateRow(ctx context.Context, q sqlConn, desc tableDescriber, schema, table string, key map[string]any, set map[string]any) (int64, error) {
 	if len(key) == 0 {
-		return 0, fmt.Errorf("update row: key must contain at least one column")
+		return 0, classify(ErrInvalidInput, "update row: key must contain at least one column")
 	}
 	if len(set) == 0 {
-		return 0, fmt.Errorf("update row: set must contain at least one column")
+		return 0, classify(ErrInvalidInput, "update row: set must contain at least one column")
 	}
 
 	// Fetch actual PK columns and validate the provided key matches.
-	if err := validatePKColumns(ctx, d, schema, table, key); err != nil {
+	if err := validatePKColumns(ctx, desc, schema, table, key, set); err != nil {
+		return 0, err
+	}
+
+	query, params := d.UpdateSQL(schema, table, key, set)
+	query = filterUpdate(ctx, query)
+	query, params = expectUpdate(ctx, query, params, quoteMySQLIdentifier, mysqlPlaceholder)
+	result, err := q.ExecContext(ctx, query, params...)
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
 		return 0, err
 	}
+	if n == 0 && expectedValues(ctx) != nil {
+		// As below, 0 may mean the row already had the new values; the
+		// expected values then still match.
+		return 0, expectedConflict(ctx, sqlRowReader(ctx, q, d.masker), quoteMySQLTable(schema, table), key, quoteMySQLIdentifier, mysqlPlaceholder)
+	}
+	if n == 0 {
+		// MySQL's RowsAffected reports *changed* rows, not *matched* rows.
+		// A no-op UPDATE (SET values identical to current) returns 0 even
+		// when the row exists. Check existence before reporting "not found".
+		keyCols, keyVals := mapsToColumnsAndValues(key)
+		exists, existErr := rowExistsByPK(ctx, q, table, keyCols, keyVals, quoteMySQLIdentifier)
+		if existErr != nil {
+			return 0, fmt.Errorf("update row: existence check: %w", existErr)
+		}
+		if !exists {
+			return 0, classify(ErrNotFound, "update row: no row found with the given key")
+		}
+		// Row exists but values were already identical — success, 0 changed.
+	}
+	return n, nil
+}
+
+// BeginTx implements Transactor.
+func (d *MySQLDriver) BeginTx(ctx context.Context) (Tx, error) {
+	return beginSQLTx(ctx, d.db, d, d)
+}
+
+// InsertSQL implements WritePreviewer.
+func (d *MySQLDriver) InsertSQL(schema, table string, row map[string]any) (string, []any) {
+	cols, vals := mapsToColumnsAndValues(row)
+	placeholders := makeMySQLPlaceholders(len(cols))
+	quotedTable := quoteMySQLTable(schema, table)
+	quotedCols := make([]string, len(cols))
+	for i, c := range cols {
+		quotedCols[i] = quoteMySQLIdentifier(c)
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		quotedTable, joinQuoted(quotedCols), placeholders)
+	return query, vals
+}
 
+// UpdateSQL implements WritePreviewer.
+func (d *MySQLDriver) UpdateSQL(schema, table string, key, set map[string]any) (string, []any) {
 	// Build SET clause: `col1` = ?, `col2` = ?, ...
 	setCols, setVals := mapsToColumnsAndValues(set)
 	quotedSets := make([]string, len(setCols))
@@ -180,9 +410,8 @@ func (d *MySQLDriver) UpdateRow(ctx context.Context, schema, table string, key m
 		quotedWheres[i] = fmt.Sprintf("%s = ?", quoteMySQLIdentifier(c))
 	}
 
-	quotedT := quoteMySQLTable(schema, table)
 	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s",
-		quotedT,
+		quoteMySQLTable(schema, table),
 		strings.Join(quotedSets, ", "),
 		strings.Join(quotedWheres, " AND "),
 	)
@@ -190,30 +419,7 @@ func (d *MySQLDriver) UpdateRow(ctx context.Context, schema, table string, key m
 	params := make([]any, 0, len(setVals)+len(keyVals))
 	params = append(params, setVals...)
 	params = append(params, keyVals...)
-
-	result, err := d.db.ExecContext(ctx, query, params...)
-	if err != nil {
-		return 0, err
-	}
-	n, err := result.RowsAffected()
-	if err != nil {
-		return 0, err
-	}
-	if n == 0 {
-		// MySQL's RowsAffected reports *changed* rows, not *matched* rows.
-		// A no-op UPDATE (SET values identical to current) returns 0 even
-		// when the row exists. Check existence before reporting "not found".
-		keyCols, keyVals := mapsToColumnsAndValues(key)
-		exists, existErr := rowExistsByPK(ctx, d.db, table, keyCols, keyVals, quoteMySQLIdentifier)
-		if existErr != nil {
-			return 0, fmt.Errorf("update row: existence check: %w", existErr)
-		}
-		if !exists {
-			return 0, fmt.Errorf("update row: no row found with the given key")
-		}
-		// Row exists but values were already identical — success, 0 changed.
-	}
-	return n, nil
+	return query, params
 }
 
 func makeMySQLPlaceholders(n int) string {
@@ -229,6 +435,8 @@ func quoteMySQLIdentifier(name string) string {
 	return "`" + mysqlIdentReplacer.Replace(name) + "`"
 }
 
+func mysqlPlaceholder(int) string { return "?" }
+
 // quoteMySQLTable returns `schema`.`table` if schema is non-empty, otherwise `table`.
 func quoteMySQLTable(schema, table string) string {
 	if schema == "" {
@@ -237,9 +445,43 @@ func quoteMySQLTable(schema, table string) string {
 	return quoteMySQLIdentifier(schema) + "." + quoteMySQLIdentifier(table)
 }
 
+// CreateScratchSchema implements ScratchSchemas. MySQL's schemas are
+// databases.
+func (d *MySQLDriver) CreateScratchSchema(ctx context.Context, name string) error {
+	return d.exec(ctx, "CREATE DATABASE IF NOT EXISTS "+quoteMySQLIdentifier(name))
+}
+
+// DropScratchSchema implements ScratchSchemas.
+func (d *MySQLDriver) DropScratchSchema(ctx context.Context, name string) error {
+	err := d.exec(ctx, "DROP DATABASE IF EXISTS "+quoteMySQLIdentifier(name))
+	d.InvalidateSchema(name, "")
+	return err
+}
+
+// CreateTable implements ScratchSchemas.
+func (d *MySQLDriver) CreateTable(ctx context.Context, schema, table string, cols []ColumnDef) error {
+	query, err := createTableSQL(quoteMySQLTable(schema, table), cols, quoteMySQLIdentifier)
+	if err != nil {
+		return err
+	}
+	err = d.exec(ctx, query)
+	d.InvalidateSchema(schema, table)
+	return err
+}
+
+// exec runs a statement without results under killOnCancel.
+func (d *MySQLDriver) exec(ctx context.Context, query string) error {
+	return d.killOnCancel(ctx, func(conn *sql.Conn) error {
+		_, err := conn.ExecContext(ctx, query)
+		return err
+	})
+}
+
 // Close implements Driver.
 func (d *MySQLDriver) Close() error {
 	return d.db.Close()
 }
 
 var _ Driver = (*MySQLDriver)(nil)
+var _ WritePreviewer = (*MySQLDriver)(nil)
+var _ ScratchSchemas = (*MySQLDriver)(nil)
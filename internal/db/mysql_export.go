@@ -94,12 +94,12 @@ func (info *mysqlConnInfo) env() []string {
 }
 
 // ExportDatabase dumps the MySQL database to a SQL file using mysqldump.
-func (d *MySQLDriver) ExportDatabase(ctx context.Context, path string) error {
-	mysqldump, err := findCLITool("mysqldump")
+func (d *MySQLDriver) ExportDatabase(ctx context.Context, path string, opts ExportOptions) error {
+	mysqldump, err := FindCLITool("mysqldump")
 	if err != nil {
 		return err
 	}
-	absPath, err := validateExportPath(path)
+	absPath, err := validateExportPath(path, opts.AllowedDirs)
 	if err != nil {
 		return err
 	}
@@ -108,27 +108,40 @@ func (d *MySQLDriver) ExportDatabase(ctx context.Context, path string) error {
 		return fmt.Errorf("export: %w", err)
 	}
 
+	tmp, err := exportTempFile(absPath)
+	if err != nil {
+		return err
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
 	args := info.cliArgs()
 	args = append(args,
-		"--result-file", absPath,
+		"--result-file", tmp.Name(),
 		"--single-transaction",
 		"--routines",
 		"--triggers",
 		info.Database,
 	)
-	return runCLIWithEnv(ctx, info.env(), mysqldump, args...)
+	if err := runCLIWithEnv(ctx, info.env(), mysqldump, args...); err != nil {
+		return err
+	}
+	return writeManifestedExport(ctx, d, tmp.Name(), absPath, opts)
 }
 
 // ImportDatabase loads a SQL dump file into the MySQL database using mysql CLI.
-func (d *MySQLDriver) ImportDatabase(ctx context.Context, path string) error {
-	mysqlBin, err := findCLITool("mysql")
+func (d *MySQLDriver) ImportDatabase(ctx context.Context, path string, opts ImportOptions) error {
+	mysqlBin, err := FindCLITool("mysql")
 	if err != nil {
 		return err
 	}
-	absPath, err := validateImportPath(path)
+	absPath, err := validateImportPath(path, opts.AllowedDirs)
 	if err != nil {
 		return err
 	}
+	if err := checkManifest(absPath, d.engine()); err != nil {
+		return err
+	}
 	info, err := parseMySQLDSN(d.dsn)
 	if err != nil {
 		return fmt.Errorf("import: %w", err)
@@ -145,5 +158,19 @@ func (d *MySQLDriver) ImportDatabase(ctx context.Context, path string) error {
 	return runCLIWithStdin(ctx, info.env(), f, mysqlBin, args...)
 }
 
+func (d *MySQLDriver) engine() string { return "mysql" }
+
+func (d *MySQLDriver) serverVersion(ctx context.Context) (string, error) {
+	var v string
+	err := d.db.QueryRowContext(ctx, "SELECT VERSION()").Scan(&v)
+	return v, err
+}
+
+func (d *MySQLDriver) countRows(ctx context.Context, table string) (int64, error) {
+	var n int64
+	err := d.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+quoteMySQLIdentifier(table)).Scan(&n)
+	return n, err
+}
+
 // Ensure MySQLDriver implements Exporter.
 var _ Exporter = (*MySQLDriver)(nil)
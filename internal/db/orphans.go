@@ -0,0 +1,48 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+)
+
+// OrphansCountQuery returns a statement counting, as "orphans", the rows of
+// fk.Table in schema whose foreign key fk references no row of
+// fk.RefTable, in the dialect of connection type typ. Keys with a NULL
+// reference nothing and are not counted. If the parent table is missing,
+// as SQLite allows with foreign keys off, every key is counted.
+func OrphansCountQuery(typ, schema string, fk ForeignKey, parentMissing bool) string {
+	return fmt.Sprintf("SELECT COUNT(*) AS %s FROM %s", quoteFor(typ)("orphans"), orphans(typ, schema, fk, parentMissing))
+}
+
+// OrphansQuery returns a statement selecting the columns of the first limit
+// rows OrphansCountQuery counts, ordered by those columns.
+func OrphansQuery(typ, schema string, fk ForeignKey, parentMissing bool, columns []string, limit int) string {
+	quote := quoteFor(typ)
+	cols := make([]string, len(columns))
+	for i, c := range columns {
+		cols[i] = "child." + quote(c)
+	}
+	list := strings.Join(cols, ", ")
+	if typ == "sqlserver" {
+		return fmt.Sprintf("SELECT TOP (%d) %s FROM %s ORDER BY %s", limit, list, orphans(typ, schema, fk, parentMissing), list)
+	}
+	return fmt.Sprintf("SELECT %s FROM %s ORDER BY %s LIMIT %d", list, orphans(typ, schema, fk, parentMissing), list, limit)
+}
+
+// orphans returns the FROM and WHERE clauses of the orphans queries: an
+// anti-join of the child table, aliased child, against its parent.
+func orphans(typ, schema string, fk ForeignKey, parentMissing bool) string {
+	quote := quoteFor(typ)
+	var conds, match []string
+	for i, c := range fk.Columns {
+		conds = append(conds, "child."+quote(c)+" IS NOT NULL")
+		if i < len(fk.RefColumns) {
+			match = append(match, "parent."+quote(fk.RefColumns[i])+" = child."+quote(c))
+		}
+	}
+	if !parentMissing {
+		conds = append(conds, fmt.Sprintf("NOT EXISTS (SELECT 1 FROM %s AS parent WHERE %s)",
+			quoteTableFor(typ, schema, fk.RefTable), strings.Join(match, " AND ")))
+	}
+	return fmt.Sprintf("%s AS child WHERE %s", quoteTableFor(typ, schema, fk.Table), strings.Join(conds, " AND "))
+}
@@ -0,0 +1,23 @@
+package db
+
+import "testing"
+
+func TestOrphansQuery(t *testing.T) {
+	fk := ForeignKey{Table: "order_items", Columns: []string{"order_id", "line"}, RefTable: "orders", RefColumns: []string{"id", "line"}}
+	if got, want := OrphansCountQuery("postgres", "public", fk, false),
+		`SELECT COUNT(*) AS "orphans" FROM "public"."order_items" AS child WHERE child."order_id" IS NOT NULL AND child."line" IS NOT NULL `+
+			`AND NOT EXISTS (SELECT 1 FROM "public"."orders" AS parent WHERE parent."id" = child."order_id" AND parent."line" = child."line")`; got != want {
+		t.Errorf("OrphansCountQuery =\n%s\nwant\n%s", got, want)
+	}
+	if got, want := OrphansQuery("sqlserver", "", fk, true, []string{"id", "order_id"}, 5),
+		"SELECT TOP (5) child.[id], child.[order_id] FROM [order_items] AS child WHERE child.[order_id] IS NOT NULL AND child.[line] IS NOT NULL "+
+			"ORDER BY child.[id], child.[order_id]"; got != want {
+		t.Errorf("OrphansQuery =\n%s\nwant\n%s", got, want)
+	}
+	if got, want := OrphansQuery("mysql", "", fk, false, []string{"order_id"}, 5),
+		"SELECT child.`order_id` FROM `order_items` AS child WHERE child.`order_id` IS NOT NULL AND child.`line` IS NOT NULL "+
+			"AND NOT EXISTS (SELECT 1 FROM `orders` AS parent WHERE parent.`id` = child.`order_id` AND parent.`line` = child.`line`) "+
+			"ORDER BY child.`order_id` LIMIT 5"; got != want {
+		t.Errorf("OrphansQuery =\n%s\nwant\n%s", got, want)
+	}
+}
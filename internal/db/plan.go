@@ -0,0 +1,384 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ShowPlanner is an optional interface for drivers whose plans come from a
+// session setting rather than a statement, as SQL Server's SHOWPLAN_XML.
+type ShowPlanner interface {
+	// ShowPlanXML returns the estimated plan of query without running it.
+	ShowPlanXML(ctx context.Context, query string, params []any) (string, error)
+}
+
+// PlanNode is a step of a query plan, with the fields every engine's
+// plans share. Operation is the engine's name for the step; FullScan is
+// set for a step reading every row of a table. Relation names the table
+// as the plan does: SQLite and MySQL name it by its alias.
+type PlanNode struct {
+	Operation     string     `json:"operation"`
+	Relation      string     `json:"relation,omitempty"`
+	Alias         string     `json:"alias,omitempty"`
+	Index         string     `json:"index,omitempty"`
+	FullScan      bool       `json:"full_scan,omitempty"`
+	EstimatedRows *float64   `json:"estimated_rows,omitempty"`
+	Cost          *float64   `json:"cost,omitempty"` // in the engine's units, including the children
+	Detail        string     `json:"detail,omitempty"`
+	Children      []PlanNode `json:"children,omitempty"`
+}
+
+// ExplainQuery returns the statement that asks connection type typ for the
+// plan of query without running it, and false for SQL Server, whose plans
+// come from ShowPlanner.
+func ExplainQuery(typ, query string) (string, bool) {
+	switch typ {
+	case "postgres":
+		return "EXPLAIN (FORMAT JSON) " + query, true
+	case "mysql":
+		return "EXPLAIN FORMAT=JSON " + query, true
+	case "sqlserver":
+		return "", false
+	}
+	return "EXPLAIN QUERY PLAN " + query, true
+}
+
+// ParsePlan returns the plan steps of plan, the result of ExplainQuery for
+// connection type typ.
+func ParsePlan(typ string, plan []map[string]any) ([]PlanNode, error) {
+	switch typ {
+	case "postgres", "mysql":
+		var nodes []PlanNode
+		for _, row := range plan {
+			for _, v := range row {
+				decoded, err := decodePlan(v)
+				if err != nil {
+					return nil, err
+				}
+				if typ == "postgres" {
+					nodes = append(nodes, postgresPlan(decoded)...)
+				} else if m, ok := decoded.(map[string]any); ok {
+					nodes = append(nodes, mysqlPlan(m)...)
+				}
+			}
+		}
+		return nodes, nil
+	}
+	return sqlitePlan(plan), nil
+}
+
+// FullScans returns the relations nodes read in full, once each.
+func FullScans(nodes []PlanNode) []string {
+	var names []string
+	var walk func([]PlanNode)
+	walk = func(nodes []PlanNode) {
+		for _, n := range nodes {
+			if n.FullScan && n.Relation != "" && !slices.Contains(names, n.Relation) {
+				names = append(names, n.Relation)
+			}
+			walk(n.Children)
+		}
+	}
+	walk(nodes)
+	return names
+}
+
+// decodePlan returns v, a JSON plan as the driver returns it, decoded.
+func decodePlan(v any) (any, error) {
+	var text []byte
+	switch v := v.(type) {
+	case string:
+		text = []byte(v)
+	case []byte:
+		text = v
+	default:
+		return v, nil
+	}
+	var decoded any
+	if err := json.Unmarshal(text, &decoded); err != nil {
+		return nil, fmt.Errorf("parse plan: %w", err)
+	}
+	return decoded, nil
+}
+
+// planNumber returns v, a JSON number or a numeric string, or nil.
+func planNumber(v any) *float64 {
+	var f float64
+	switch v := v.(type) {
+	case float64:
+		f = v
+	case int64:
+		f = float64(v)
+	case string:
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil
+		}
+		f = n
+	default:
+		return nil
+	}
+	return &f
+}
+
+// postgresPlan converts a plan of EXPLAIN (FORMAT JSON): an array of
+// objects whose Plan is the root step.
+func postgresPlan(v any) []PlanNode {
+	var nodes []PlanNode
+	switch v := v.(type) {
+	case []any:
+		for _, item := range v {
+			nodes = append(nodes, postgresPlan(item)...)
+		}
+	case map[string]any:
+		if root, ok := v["Plan"]; ok {
+			return postgresPlan(root)
+		}
+		n := PlanNode{
+			EstimatedRows: planNumber(v["Plan Rows"]),
+			Cost:          planNumber(v["Total Cost"]),
+		}
+		n.Operation, _ = v["Node Type"].(string)
+		n.Relation, _ = v["Relation Name"].(string)
+		n.Alias, _ = v["Alias"].(string)
+		n.Index, _ = v["Index Name"].(string)
+		n.FullScan = n.Operation == "Seq Scan"
+		if n.Alias == n.Relation {
+			n.Alias = ""
+		}
+		if join, ok := v["Join Type"].(string); ok {
+			n.Detail = join + " join"
+		}
+		n.Children = postgresPlan(v["Plans"])
+		nodes = append(nodes, n)
+	}
+	return nodes
+}
+
+// mysqlOperations names the steps of a MySQL plan that hold others.
+var mysqlOperations = []struct{ key, operation string }{
+	{"query_block", "Query Block"},
+	{"union_result", "Union"},
+	{"ordering_operation", "Ordering"},
+	{"grouping_operation", "Grouping"},
+	{"duplicates_removal", "Distinct"},
+	{"windowing", "Window"},
+	{"nested_loop", "Nested Loop"},
+	{"table", ""},
+}
+
+// mysqlAccess names the access types of a MySQL table step.
+var mysqlAccess = map[string]string{
+	"ALL":    "Table Scan",
+	"index":  "Index Scan",
+	"range":  "Index Range Scan",
+	"ref":    "Index Lookup",
+	"eq_ref": "Unique Index Lookup",
+	"const":  "Constant Lookup",
+	"system": "Constant Lookup",
+}
+
+// mysqlPlan converts the steps of v, an object of an EXPLAIN FORMAT=JSON
+// plan.
+func mysqlPlan(v map[string]any) []PlanNode {
+	var nodes []PlanNode
+	for _, op := range mysqlOperations {
+		child, ok := v[op.key]
+		if !ok {
+			continue
+		}
+		if op.key == "nested_loop" {
+			n := PlanNode{Operation: op.operation}
+			for _, item := range planArray(child) {
+				if m, ok := item.(map[string]any); ok {
+					n.Children = append(n.Children, mysqlPlan(m)...)
+				}
+			}
+			nodes = append(nodes, n)
+			continue
+		}
+		m, ok := child.(map[string]any)
+		if !ok {
+			continue
+		}
+		if op.key == "table" {
+			nodes = append(nodes, mysqlTable(m))
+			continue
+		}
+		n := PlanNode{Operation: op.operation, Children: mysqlPlan(m)}
+		if costs, ok := m["cost_info"].(map[string]any); ok {
+			n.Cost = planNumber(costs["query_cost"])
+		}
+		if op.key == "ordering_operation" && m["using_filesort"] == true {
+			n.Operation = "Sort"
+		}
+		for _, key := range []string{"query_specifications", "attached_subqueries", "optimized_away_subqueries"} {
+			for _, item := range planArray(m[key]) {
+				if m, ok := item.(map[string]any); ok {
+					n.Children = append(n.Children, mysqlPlan(m)...)
+				}
+			}
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes
+}
+
+// mysqlTable converts a table step of a MySQL plan.
+func mysqlTable(m map[string]any) PlanNode {
+	access, _ := m["access_type"].(string)
+	n := PlanNode{Operation: mysqlAccess[access], FullScan: access == "ALL", EstimatedRows: planNumber(m["rows_examined_per_scan"])}
+	if n.Operation == "" {
+		n.Operation = access
+	}
+	n.Relation, _ = m["table_name"].(string)
+	n.Index, _ = m["key"].(string)
+	n.Detail, _ = m["attached_condition"].(string)
+	if costs, ok := m["cost_info"].(map[string]any); ok {
+		n.Cost = planNumber(costs["prefix_cost"])
+	}
+	if sub, ok := m["materialized_from_subquery"].(map[string]any); ok {
+		n.Children = mysqlPlan(sub)
+	}
+	for _, item := range planArray(m["attached_subqueries"]) {
+		if sub, ok := item.(map[string]any); ok {
+			n.Children = append(n.Children, mysqlPlan(sub)...)
+		}
+	}
+	return n
+}
+
+func planArray(v any) []any {
+	s, _ := v.([]any)
+	return s
+}
+
+// sqliteStep matches the detail of a step of an SQLite plan that reads a
+// table. Versions before 3.36 say SCAN TABLE.
+var sqliteStep = regexp.MustCompile(`^(SCAN|SEARCH) (?:TABLE )?(\S+)(?: AS (\S+))?(?: USING (?:COVERING )?(?:INDEX (\S+)|(INTEGER PRIMARY KEY|PRIMARY KEY)))?`)
+
+// sqlitePlan converts the rows of EXPLAIN QUERY PLAN, steps with an id,
+// the id of their parent (0 at the top) and a detail, to a tree.
+func sqlitePlan(plan []map[string]any) []PlanNode {
+	type step struct {
+		id, parent int64
+		node       PlanNode
+	}
+	steps := make([]*step, 0, len(plan))
+	byID := make(map[int64]*step, len(plan))
+	for _, row := range plan {
+		s := &step{}
+		s.id, _ = planInt(row["id"])
+		s.parent, _ = planInt(row["parent"])
+		detail, _ := row["detail"].(string)
+		s.node = PlanNode{Operation: detail, Detail: detail}
+		if m := sqliteStep.FindStringSubmatch(detail); m != nil {
+			s.node.Relation, s.node.Alias = m[2], m[3]
+			switch {
+			case m[1] == "SEARCH":
+				s.node.Operation = "Index Search"
+			case m[4] != "" || m[5] != "":
+				s.node.Operation = "Index Scan"
+			default:
+				s.node.Operation, s.node.FullScan = "Table Scan", true
+			}
+			s.node.Index = m[4]
+			if m[5] != "" {
+				s.node.Index = "PRIMARY KEY"
+			}
+		} else if strings.HasPrefix(detail, "USE TEMP B-TREE FOR ") {
+			s.node.Operation = "Sort"
+		}
+		steps = append(steps, s)
+		byID[s.id] = s
+	}
+	// Attach the children, last first, so each node is complete when it
+	// is copied into its parent; SQLite lists parents before children.
+	children := make(map[int64][]PlanNode)
+	for i := len(steps) - 1; i >= 0; i-- {
+		s := steps[i]
+		s.node.Children = children[s.id]
+		if _, ok := byID[s.parent]; ok && s.parent != s.id {
+			children[s.parent] = append([]PlanNode{s.node}, children[s.parent]...)
+		} else {
+			children[0] = append([]PlanNode{s.node}, children[0]...)
+		}
+	}
+	return children[0]
+}
+
+func planInt(v any) (int64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return v, true
+	case float64:
+		return int64(v), true
+	}
+	return 0, false
+}
+
+// ParseShowPlanXML returns the steps of plan, a SQL Server SHOWPLAN_XML
+// document: its RelOp elements, with the table of the Object each reads.
+func ParseShowPlanXML(plan string) ([]PlanNode, error) {
+	dec := xml.NewDecoder(strings.NewReader(plan))
+	var roots []PlanNode
+	var stack []*PlanNode
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse plan: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			attr := func(name string) string {
+				for _, a := range t.Attr {
+					if a.Name.Local == name {
+						return strings.Trim(a.Value, "[]")
+					}
+				}
+				return ""
+			}
+			switch t.Name.Local {
+			case "RelOp":
+				n := &PlanNode{
+					Operation:     attr("PhysicalOp"),
+					EstimatedRows: planNumber(attr("EstimateRows")),
+					Cost:          planNumber(attr("EstimatedTotalSubtreeCost")),
+				}
+				if logical := attr("LogicalOp"); logical != n.Operation {
+					n.Detail = logical
+				}
+				n.FullScan = n.Operation == "Table Scan" || n.Operation == "Clustered Index Scan"
+				stack = append(stack, n)
+			case "Object":
+				if len(stack) > 0 && stack[len(stack)-1].Relation == "" {
+					n := stack[len(stack)-1]
+					n.Relation, n.Alias, n.Index = attr("Table"), attr("Alias"), attr("Index")
+				}
+			}
+		case xml.EndElement:
+			if t.Name.Local != "RelOp" || len(stack) == 0 {
+				continue
+			}
+			n := *stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				roots = append(roots, n)
+			} else {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, n)
+			}
+		}
+	}
+	return roots, nil
+}
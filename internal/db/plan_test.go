@@ -0,0 +1,106 @@
+package db
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParsePlan(t *testing.T) {
+	sqlite, err := ParsePlan("sqlite", []map[string]any{
+		{"id": int64(2), "parent": int64(0), "detail": "SCAN o"},
+		{"id": int64(5), "parent": int64(0), "detail": "SEARCH c USING INTEGER PRIMARY KEY (rowid=?)"},
+		{"id": int64(7), "parent": int64(0), "detail": "SCALAR SUBQUERY 1"},
+		{"id": int64(9), "parent": int64(7), "detail": "SCAN TABLE items AS i USING COVERING INDEX ix_items"},
+		{"id": int64(20), "parent": int64(0), "detail": "USE TEMP B-TREE FOR ORDER BY"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := planJSON(t, sqlite), `[`+
+		`{"operation":"Table Scan","relation":"o","full_scan":true,"detail":"SCAN o"},`+
+		`{"operation":"Index Search","relation":"c","index":"PRIMARY KEY","detail":"SEARCH c USING INTEGER PRIMARY KEY (rowid=?)"},`+
+		`{"operation":"SCALAR SUBQUERY 1","detail":"SCALAR SUBQUERY 1","children":[`+
+		`{"operation":"Index Scan","relation":"items","alias":"i","index":"ix_items","detail":"SCAN TABLE items AS i USING COVERING INDEX ix_items"}]},`+
+		`{"operation":"Sort","detail":"USE TEMP B-TREE FOR ORDER BY"}]`; got != want {
+		t.Errorf("sqlite =\n%s\nwant\n%s", got, want)
+	}
+
+	postgres, err := ParsePlan("postgres", []map[string]any{{"QUERY PLAN": []any{map[string]any{"Plan": map[string]any{
+		"Node Type": "Hash Join", "Join Type": "Inner", "Plan Rows": 12.0, "Total Cost": 35.5,
+		"Plans": []any{
+			map[string]any{"Node Type": "Seq Scan", "Relation Name": "orders", "Alias": "o", "Plan Rows": 1000.0, "Total Cost": 20.0},
+			map[string]any{"Node Type": "Index Scan", "Relation Name": "customers", "Alias": "customers", "Index Name": "customers_pkey"},
+		},
+	}}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := planJSON(t, postgres), `[{"operation":"Hash Join","estimated_rows":12,"cost":35.5,"detail":"Inner join","children":[`+
+		`{"operation":"Seq Scan","relation":"orders","alias":"o","full_scan":true,"estimated_rows":1000,"cost":20},`+
+		`{"operation":"Index Scan","relation":"customers","index":"customers_pkey"}]}]`; got != want {
+		t.Errorf("postgres =\n%s\nwant\n%s", got, want)
+	}
+
+	mysql, err := ParsePlan("mysql", []map[string]any{{"EXPLAIN": `{"query_block": {"cost_info": {"query_cost": "4.20"},
+		"ordering_operation": {"using_filesort": true, "nested_loop": [
+		{"table": {"table_name": "o", "access_type": "ALL", "rows_examined_per_scan": 6, "cost_info": {"prefix_cost": "0.85"}, "attached_condition": "(o.status = 'x')"}},
+		{"table": {"table_name": "c", "access_type": "eq_ref", "key": "PRIMARY", "rows_examined_per_scan": 1}}]}}}`}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := planJSON(t, mysql), `[{"operation":"Query Block","cost":4.2,"children":[{"operation":"Sort","children":[{"operation":"Nested Loop","children":[`+
+		`{"operation":"Table Scan","relation":"o","full_scan":true,"estimated_rows":6,"cost":0.85,"detail":"(o.status = 'x')"},`+
+		`{"operation":"Unique Index Lookup","relation":"c","index":"PRIMARY","estimated_rows":1}]}]}]}]`; got != want {
+		t.Errorf("mysql =\n%s\nwant\n%s", got, want)
+	}
+
+	if got, want := FullScans(mysql), []string{"o"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FullScans = %v, want %v", got, want)
+	}
+	if _, ok := ExplainQuery("sqlserver", "SELECT 1"); ok {
+		t.Error("ExplainQuery(sqlserver) ok")
+	}
+	if _, err := ParsePlan("mysql", []map[string]any{{"EXPLAIN": "{"}}); err == nil {
+		t.Error("ParsePlan of broken JSON succeeded")
+	}
+}
+
+func TestParseShowPlanXML(t *testing.T) {
+	plan := `<ShowPlanXML xmlns="http://schemas.microsoft.com/sqlserver/2004/07/showplan" Version="1.6">
+<BatchSequence><Batch><Statements><StmtSimple StatementText="SELECT ..." StatementEstRows="2">
+<QueryPlan>
+<RelOp NodeId="0" PhysicalOp="Nested Loops" LogicalOp="Inner Join" EstimateRows="2" EstimatedTotalSubtreeCost="0.0066">
+<OutputList><ColumnReference Database="[app]" Schema="[dbo]" Table="[orders]" Alias="[o]" Column="id" /></OutputList>
+<NestedLoops Optimized="false">
+<RelOp NodeId="1" PhysicalOp="Clustered Index Scan" LogicalOp="Clustered Index Scan" EstimateRows="3" EstimatedTotalSubtreeCost="0.0032">
+<IndexScan Ordered="false"><Object Database="[app]" Schema="[dbo]" Table="[orders]" Index="[PK_orders]" Alias="[o]" /></IndexScan>
+</RelOp>
+<RelOp NodeId="2" PhysicalOp="Clustered Index Seek" LogicalOp="Clustered Index Seek" EstimateRows="1" EstimatedTotalSubtreeCost="0.0032">
+<IndexScan Ordered="true"><Object Database="[app]" Schema="[dbo]" Table="[customers]" Index="[PK_customers]" /></IndexScan>
+</RelOp>
+</NestedLoops>
+</RelOp>
+</QueryPlan></StmtSimple></Statements></Batch></BatchSequence></ShowPlanXML>`
+	nodes, err := ParseShowPlanXML(plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := planJSON(t, nodes), `[{"operation":"Nested Loops","estimated_rows":2,"cost":0.0066,"detail":"Inner Join","children":[`+
+		`{"operation":"Clustered Index Scan","relation":"orders","alias":"o","index":"PK_orders","full_scan":true,"estimated_rows":3,"cost":0.0032},`+
+		`{"operation":"Clustered Index Seek","relation":"customers","index":"PK_customers","estimated_rows":1,"cost":0.0032}]}]`; got != want {
+		t.Errorf("ParseShowPlanXML =\n%s\nwant\n%s", got, want)
+	}
+	if _, err := ParseShowPlanXML("<ShowPlanXML><RelOp>"); err == nil {
+		t.Error("ParseShowPlanXML of a truncated plan succeeded")
+	}
+}
+
+func planJSON(t *testing.T, nodes []PlanNode) string {
+	t.Helper()
+	b, err := json.Marshal(nodes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
@@ -3,29 +3,87 @@ package db
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgconn/ctxwatch"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-// PostgresDriver implements Driver for PostgreSQL using pgx.
+// pgCancelGrace is how long a cancelled statement gets to stop after the
+// cancel request is sent before the connection is closed under it.
+const pgCancelGrace = 2 * time.Second
+
+// PostgresDriver implements Driver for PostgreSQL using a pgx connection
+// pool, so concurrent tool calls each get their own connection.
 type PostgresDriver struct {
-	conn *pgx.Conn
+	pool *pgxpool.Pool
 	uri  string
+
+	schemaCache // DescribeTable results
+	masker      *Masker
 }
 
-// NewPostgresDriver connects to PostgreSQL using the given URI.
-func NewPostgresDriver(ctx context.Context, uri string) (*PostgresDriver, error) {
-	conn, err := pgx.Connect(ctx, uri)
+// NewPostgresDriver connects to PostgreSQL using the given URI. With
+// opts.ReadOnly every session defaults to read-only transactions.
+func NewPostgresDriver(ctx context.Context, uri string, opts ConnectOptions) (*PostgresDriver, error) {
+	cfg, err := postgresPoolConfig(uri, opts)
+	if err != nil {
+		return nil, fmt.Errorf("postgres connect: %w", err)
+	}
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
 	if err != nil {
 		return nil, fmt.Errorf("postgres connect: %w", err)
 	}
-	return &PostgresDriver{conn: conn, uri: uri}, nil
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("postgres connect: %w", err)
+	}
+	return &PostgresDriver{pool: pool, uri: uri, schemaCache: newSchemaCache(opts.SchemaCacheTTL), masker: opts.Masker}, nil
+}
+
+// postgresPoolConfig parses uri into the pool configuration used by
+// NewPostgresDriver.
+func postgresPoolConfig(uri string, opts ConnectOptions) (*pgxpool.Config, error) {
+	cfg, err := pgxpool.ParseConfig(uri)
+	if err != nil {
+		return nil, err
+	}
+	if opts.ReadOnly {
+		cfg.ConnConfig.RuntimeParams["default_transaction_read_only"] = "on"
+	}
+	// By default pgx only closes the connection when a context ends, and the
+	// server keeps running the statement. Send a cancel request instead so
+	// a cancelled tool call or timeout stops the query on the server too.
+	cfg.ConnConfig.BuildContextWatcherHandler = func(c *pgconn.PgConn) ctxwatch.Handler {
+		return &pgconn.CancelRequestContextWatcherHandler{Conn: c, DeadlineDelay: pgCancelGrace}
+	}
+	return cfg, nil
 }
 
 // Ping implements Driver.
 func (d *PostgresDriver) Ping(ctx context.Context) error {
-	return d.conn.Ping(ctx)
+	return d.pool.Ping(ctx)
+}
+
+// Capabilities implements Driver.
+func (d *PostgresDriver) Capabilities() Capabilities {
+	caps, _ := CapabilitiesFor(d.engine())
+	return caps
+}
+
+// PoolStats implements PoolStatter.
+func (d *PostgresDriver) PoolStats() PoolStats {
+	st := d.pool.Stat()
+	return PoolStats{
+		Open:    int(st.TotalConns()),
+		InUse:   int(st.AcquiredConns()),
+		Idle:    int(st.IdleConns()),
+		MaxOpen: int(st.MaxConns()),
+	}
 }
 
 // ListTables implements Driver. Schema defaults to "public" if empty.
@@ -33,7 +91,7 @@ func (d *PostgresDriver) ListTables(ctx context.Context, schema string) ([]strin
 	if schema == "" {
 		schema = "public"
 	}
-	rows, err := d.conn.Query(ctx,
+	rows, err := d.pool.Query(ctx,
 		`SELECT table_name FROM information_schema.tables
 		 WHERE table_schema = $1 AND table_type = 'BASE TABLE'
 		 ORDER BY table_name`,
@@ -53,12 +111,18 @@ func (d *PostgresDriver) ListTables(ctx context.Context, schema string) ([]strin
 	return names, rows.Err()
 }
 
-// DescribeTable implements Driver.
+// DescribeTable implements Driver. Results are cached per table for the
+// connection's schema cache TTL; see SchemaInvalidator.
 func (d *PostgresDriver) DescribeTable(ctx context.Context, schema, table string) ([]ColumnInfo, error) {
+	return d.describe(ctx, schema, table, d.describeTable)
+}
+
+// describeTable queries the catalog for DescribeTable.
+func (d *PostgresDriver) describeTable(ctx context.Context, schema, table string) ([]ColumnInfo, error) {
 	if schema == "" {
 		schema = "public"
 	}
-	rows, err := d.conn.Query(ctx, `
+	rows, err := d.pool.Query(ctx, `
 		SELECT c.column_name, c.data_type, c.is_nullable = 'YES',
 		       EXISTS (
 		         SELECT 1 FROM information_schema.table_constraints tc
@@ -86,21 +150,169 @@ func (d *PostgresDriver) DescribeTable(ctx context.Context, schema, table string
 	return cols, rows.Err()
 }
 
+// ForeignKeys implements ForeignKeyLister. Schema defaults to "public" if
+// empty.
+func (d *PostgresDriver) ForeignKeys(ctx context.Context, schema string) ([]ForeignKey, error) {
+	if schema == "" {
+		schema = "public"
+	}
+	rows, err := d.pool.Query(ctx, `
+		SELECT con.conname, cl.relname, a.attname, rcl.relname, ra.attname,
+		       con.confdeltype::text, con.confupdtype::text
+		FROM pg_constraint con
+		JOIN pg_class cl ON cl.oid = con.conrelid
+		JOIN pg_namespace n ON n.oid = cl.relnamespace
+		JOIN pg_class rcl ON rcl.oid = con.confrelid
+		JOIN pg_namespace rn ON rn.oid = rcl.relnamespace
+		CROSS JOIN LATERAL unnest(con.conkey, con.confkey) WITH ORDINALITY AS k(attnum, refnum, ord)
+		JOIN pg_attribute a ON a.attrelid = con.conrelid AND a.attnum = k.attnum
+		JOIN pg_attribute ra ON ra.attrelid = con.confrelid AND ra.attnum = k.refnum
+		WHERE con.contype = 'f' AND n.nspname = $1 AND rn.nspname = $1
+		ORDER BY cl.relname, con.conname, k.ord`,
+		schema)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var fks []ForeignKey
+	for rows.Next() {
+		var name, table, col, refTable, refCol, onDelete, onUpdate string
+		if err := rows.Scan(&name, &table, &col, &refTable, &refCol, &onDelete, &onUpdate); err != nil {
+			return nil, err
+		}
+		fks = appendForeignKeyColumn(fks, ForeignKey{
+			Name: name, Table: table, RefTable: refTable,
+			OnDelete: pgReferentialAction(onDelete), OnUpdate: pgReferentialAction(onUpdate),
+		}, col, refCol)
+	}
+	return fks, rows.Err()
+}
+
+// Dependents implements DependencyLister. Schema defaults to "public" if
+// empty. PostgreSQL records the dependencies of views and of routines with
+// SQL-standard bodies (BEGIN ATOMIC) or on the table's row type; the
+// bodies of other routines are not tracked.
+func (d *PostgresDriver) Dependents(ctx context.Context, schema, table string) ([]Dependent, error) {
+	if schema == "" {
+		schema = "public"
+	}
+	rows, err := d.pool.Query(ctx, `
+		WITH t AS (
+			SELECT c.oid, c.reltype FROM pg_class c
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			WHERE n.nspname = $1 AND c.relname = $2
+		)
+		SELECT CASE v.relkind WHEN 'm' THEN 'materialized view' ELSE 'view' END, vn.nspname::text, v.relname::text
+		FROM t
+		JOIN pg_depend d ON d.refclassid = 'pg_class'::regclass AND d.refobjid = t.oid
+		JOIN pg_rewrite r ON d.classid = 'pg_rewrite'::regclass AND r.oid = d.objid
+		JOIN pg_class v ON v.oid = r.ev_class AND v.oid <> t.oid
+		JOIN pg_namespace vn ON vn.oid = v.relnamespace
+		UNION
+		SELECT CASE p.prokind WHEN 'p' THEN 'procedure' ELSE 'function' END, pn.nspname::text, p.proname::text
+		FROM t
+		JOIN pg_depend d ON d.refclassid = 'pg_class'::regclass AND d.refobjid = t.oid
+			OR d.refclassid = 'pg_type'::regclass AND d.refobjid = t.reltype
+		JOIN pg_proc p ON d.classid = 'pg_proc'::regclass AND p.oid = d.objid
+		JOIN pg_namespace pn ON pn.oid = p.pronamespace
+		UNION
+		SELECT 'trigger', $1::text, tg.tgname::text
+		FROM t JOIN pg_trigger tg ON tg.tgrelid = t.oid AND NOT tg.tgisinternal
+		ORDER BY 1, 2, 3`,
+		schema, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var deps []Dependent
+	for rows.Next() {
+		var dep Dependent
+		if err := rows.Scan(&dep.Kind, &dep.Schema, &dep.Name); err != nil {
+			return nil, err
+		}
+		deps = append(deps, dep)
+	}
+	return deps, rows.Err()
+}
+
+// Indexes implements IndexLister. Schema defaults to "public" if empty.
+func (d *PostgresDriver) Indexes(ctx context.Context, schema string) ([]Index, error) {
+	if schema == "" {
+		schema = "public"
+	}
+	rows, err := d.pool.Query(ctx, `
+		SELECT i.relname, t.relname, COALESCE(a.attname, ''),
+		       ix.indisunique, ix.indisprimary, ix.indpred IS NOT NULL
+		FROM pg_index ix
+		JOIN pg_class i ON i.oid = ix.indexrelid
+		JOIN pg_class t ON t.oid = ix.indrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		CROSS JOIN LATERAL generate_series(0, ix.indnkeyatts - 1) AS k(pos)
+		LEFT JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = ix.indkey[k.pos] AND ix.indkey[k.pos] <> 0
+		WHERE n.nspname = $1 AND t.relkind IN ('r', 'p')
+		ORDER BY t.relname, i.relname, k.pos`,
+		schema)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var idxs []Index
+	for rows.Next() {
+		var ix Index
+		var col string
+		if err := rows.Scan(&ix.Name, &ix.Table, &col, &ix.Unique, &ix.Primary, &ix.Partial); err != nil {
+			return nil, err
+		}
+		idxs = appendIndexColumn(idxs, ix, col)
+	}
+	return sortIndexes(idxs), rows.Err()
+}
+
+// pgReferentialAction spells out the action code of pg_constraint's
+// confdeltype and confupdtype.
+func pgReferentialAction(code string) string {
+	switch code {
+	case "r":
+		return "RESTRICT"
+	case "c":
+		return "CASCADE"
+	case "n":
+		return "SET NULL"
+	case "d":
+		return "SET DEFAULT"
+	default:
+		return "NO ACTION"
+	}
+}
+
 // RunReadOnlyQuery implements Driver. Params are positional ($1, $2, ...).
 func (d *PostgresDriver) RunReadOnlyQuery(ctx context.Context, sql string, params []any) ([]map[string]any, error) {
-	rows, err := d.conn.Query(ctx, sql, params...)
+	rows, err := d.pool.Query(ctx, sql, params...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	return rowsToMaps(rows)
+	return rowsToMaps(rows, d.masker.forQuery(sql))
 }
 
-func rowsToMaps(rows pgx.Rows) ([]map[string]any, error) {
+// rowsToMaps builds []map[string]any from pgx rows, masking columns as mask
+// says (nil masks nothing).
+func rowsToMaps(rows pgx.Rows, mask columnMask) ([]map[string]any, error) {
 	fields := rows.FieldDescriptions()
 	if len(fields) == 0 {
 		return nil, nil
 	}
+	names := make([]string, len(fields))
+	masks := make([]func(any) any, len(fields))
+	for i, f := range fields {
+		names[i] = string(f.Name)
+		if names[i] == "" {
+			names[i] = fmt.Sprintf("column_%d", i+1)
+		}
+		if mask != nil {
+			masks[i] = mask(names[i])
+		}
+	}
 	var out []map[string]any
 	for rows.Next() {
 		vals, err := rows.Values()
@@ -108,10 +320,9 @@ func rowsToMaps(rows pgx.Rows) ([]map[string]any, error) {
 			return nil, err
 		}
 		m := make(map[string]any, len(fields))
-		for i, f := range fields {
-			name := string(f.Name)
-			if name == "" {
-				name = fmt.Sprintf("column_%d", i+1)
+		for i, name := range names {
+			if masks[i] != nil {
+				vals[i] = masks[i](vals[i])
 			}
 			m[name] = vals[i]
 		}
@@ -122,23 +333,22 @@ func rowsToMaps(rows pgx.Rows) ([]map[string]any, error) {
 
 // InsertRow implements Driver. Returns the value of a single RETURNING column if present.
 func (d *PostgresDriver) InsertRow(ctx context.Context, schema, table string, row map[string]any) (any, error) {
+	return d.insertRow(ctx, d.pool, schema, table, row)
+}
+
+// insertRow runs InsertRow on q, the pool or a transaction.
+func (d *PostgresDriver) insertRow(ctx context.Context, q pgConn, schema, table string, row map[string]any) (any, error) {
 	if schema == "" {
 		schema = "public"
 	}
 	if len(row) == 0 {
-		return nil, fmt.Errorf("insert row: no columns")
+		return nil, classify(ErrInvalidInput, "insert row: no columns")
 	}
-	cols, vals := mapsToColumnsAndValues(row)
-	placeholders := makePlaceholders(len(cols))
-	quotedTable := pgx.Identifier{schema, table}.Sanitize()
-	quotedCols := make([]string, len(cols))
-	for i, c := range cols {
-		quotedCols[i] = pgx.Identifier{c}.Sanitize()
+	if _, err := checkColumns(ctx, d, "insert row", schema, table, row); err != nil {
+		return nil, err
 	}
-	sql := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) RETURNING *",
-		quotedTable, joinQuoted(quotedCols), placeholders)
-	params := append([]any(nil), vals...)
-	rows, err := d.conn.Query(ctx, sql, params...)
+	sql, params := d.InsertSQL(schema, table, row)
+	rows, err := q.Query(ctx, sql, params...)
 	if err != nil {
 		return nil, err
 	}
@@ -158,21 +368,86 @@ func (d *PostgresDriver) InsertRow(ctx context.Context, schema, table string, ro
 
 // UpdateRow implements Driver. Validates key matches actual PK, then updates a single row.
 func (d *PostgresDriver) UpdateRow(ctx context.Context, schema, table string, key map[string]any, set map[string]any) (int64, error) {
+	return d.updateRow(ctx, d.pool, schema, table, key, set)
+}
+
+// updateRow runs UpdateRow on q, the pool or a transaction.
+func (d *PostgresDriver) updateRow(ctx context.Context, q pgConn, schema, table string, key map[string]any, set map[string]any) (int64, error) {
 	if schema == "" {
 		schema = "public"
 	}
 	if len(key) == 0 {
-		return 0, fmt.Errorf("update row: key must contain at least one column")
+		return 0, classify(ErrInvalidInput, "update row: key must contain at least one column")
 	}
 	if len(set) == 0 {
-		return 0, fmt.Errorf("update row: set must contain at least one column")
+		return 0, classify(ErrInvalidInput, "update row: set must contain at least one column")
 	}
 
 	// Fetch actual PK columns and validate the provided key matches.
-	if err := validatePKColumns(ctx, d, schema, table, key); err != nil {
+	if err := validatePKColumns(ctx, d, schema, table, key, set); err != nil {
+		return 0, err
+	}
+
+	sql, params := d.UpdateSQL(schema, table, key, set)
+	sql = filterUpdate(ctx, sql)
+	sql, params = expectUpdate(ctx, sql, params, quotePGIdentifier, pgPlaceholder)
+	tag, err := q.Exec(ctx, sql, params...)
+	if err != nil {
 		return 0, err
 	}
+	n := tag.RowsAffected()
+	if n == 0 && expectedValues(ctx) != nil {
+		return 0, expectedConflict(ctx, func(query string, params []any) ([]map[string]any, error) {
+			rows, err := q.Query(ctx, query, params...)
+			if err != nil {
+				return nil, err
+			}
+			defer rows.Close()
+			return rowsToMaps(rows, d.masker.forQuery(query))
+		}, pgx.Identifier{schema, table}.Sanitize(), key, quotePGIdentifier, pgPlaceholder)
+	}
+	if n == 0 {
+		return 0, classify(ErrNotFound, "update row: no row found with the given key")
+	}
+	return n, nil
+}
+
+func quotePGIdentifier(name string) string { return pgx.Identifier{name}.Sanitize() }
+
+func pgPlaceholder(n int) string { return fmt.Sprintf("$%d", n) }
+
+// BeginTx implements Transactor.
+func (d *PostgresDriver) BeginTx(ctx context.Context) (Tx, error) {
+	// Unlike database/sql, pgx uses ctx only to start the transaction.
+	tx, err := d.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &pgTx{tx: tx, d: d}, nil
+}
+
+// InsertSQL implements WritePreviewer.
+func (d *PostgresDriver) InsertSQL(schema, table string, row map[string]any) (string, []any) {
+	if schema == "" {
+		schema = "public"
+	}
+	cols, vals := mapsToColumnsAndValues(row)
+	placeholders := makePlaceholders(len(cols))
+	quotedTable := pgx.Identifier{schema, table}.Sanitize()
+	quotedCols := make([]string, len(cols))
+	for i, c := range cols {
+		quotedCols[i] = pgx.Identifier{c}.Sanitize()
+	}
+	sql := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) RETURNING *",
+		quotedTable, joinQuoted(quotedCols), placeholders)
+	return sql, vals
+}
 
+// UpdateSQL implements WritePreviewer.
+func (d *PostgresDriver) UpdateSQL(schema, table string, key, set map[string]any) (string, []any) {
+	if schema == "" {
+		schema = "public"
+	}
 	// Build SET clause: "col1" = $1, "col2" = $2, ...
 	setCols, setVals := mapsToColumnsAndValues(set)
 	quotedSets := make([]string, len(setCols))
@@ -197,24 +472,20 @@ func (d *PostgresDriver) UpdateRow(ctx context.Context, schema, table string, ke
 	params := make([]any, 0, len(setVals)+len(keyVals))
 	params = append(params, setVals...)
 	params = append(params, keyVals...)
-
-	tag, err := d.conn.Exec(ctx, sql, params...)
-	if err != nil {
-		return 0, err
-	}
-	n := tag.RowsAffected()
-	if n == 0 {
-		return 0, fmt.Errorf("update row: no row found with the given key")
-	}
-	return n, nil
+	return sql, params
 }
 
+// mapsToColumnsAndValues splits row into column names, sorted so generated
+// statements are deterministic, and the matching values.
 func mapsToColumnsAndValues(row map[string]any) (cols []string, vals []any) {
 	cols = make([]string, 0, len(row))
-	vals = make([]any, 0, len(row))
-	for k, v := range row {
+	for k := range row {
 		cols = append(cols, k)
-		vals = append(vals, v)
+	}
+	sort.Strings(cols)
+	vals = make([]any, 0, len(row))
+	for _, k := range cols {
+		vals = append(vals, row[k])
 	}
 	return cols, vals
 }
@@ -245,10 +516,39 @@ func joinQuoted(ss []string) string {
 	return out
 }
 
+// CreateScratchSchema implements ScratchSchemas.
+func (d *PostgresDriver) CreateScratchSchema(ctx context.Context, name string) error {
+	_, err := d.pool.Exec(ctx, "CREATE SCHEMA IF NOT EXISTS "+pgx.Identifier{name}.Sanitize())
+	return err
+}
+
+// DropScratchSchema implements ScratchSchemas.
+func (d *PostgresDriver) DropScratchSchema(ctx context.Context, name string) error {
+	_, err := d.pool.Exec(ctx, "DROP SCHEMA IF EXISTS "+pgx.Identifier{name}.Sanitize()+" CASCADE")
+	d.InvalidateSchema(name, "")
+	return err
+}
+
+// CreateTable implements ScratchSchemas.
+func (d *PostgresDriver) CreateTable(ctx context.Context, schema, table string, cols []ColumnDef) error {
+	query, err := createTableSQL(pgx.Identifier{schema, table}.Sanitize(), cols, func(name string) string {
+		return pgx.Identifier{name}.Sanitize()
+	})
+	if err != nil {
+		return err
+	}
+	_, err = d.pool.Exec(ctx, query)
+	d.InvalidateSchema(schema, table)
+	return err
+}
+
 // Close implements Driver.
 func (d *PostgresDriver) Close() error {
-	return d.conn.Close(context.Background())
+	d.pool.Close()
+	return nil
 }
 
 // Ensure PostgresDriver implements Driver.
 var _ Driver = (*PostgresDriver)(nil)
+var _ WritePreviewer = (*PostgresDriver)(nil)
+var _ ScratchSchemas = (*PostgresDriver)(nil)
@@ -1,43 +1,107 @@
 package db
 
-import "context"
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+)
 
 // ExportDatabase dumps the PostgreSQL database to a SQL file using pg_dump.
-func (d *PostgresDriver) ExportDatabase(ctx context.Context, path string) error {
-	pgDump, err := findCLITool("pg_dump")
+func (d *PostgresDriver) ExportDatabase(ctx context.Context, path string, opts ExportOptions) error {
+	pgDump, err := FindCLITool("pg_dump")
+	if err != nil {
+		return err
+	}
+	absPath, err := validateExportPath(path, opts.AllowedDirs)
 	if err != nil {
 		return err
 	}
-	absPath, err := validateExportPath(path)
+	tmp, err := exportTempFile(absPath)
 	if err != nil {
 		return err
 	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
 	// pg_dump accepts the connection URI directly as a positional argument.
-	return runCLI(ctx, pgDump,
+	if err := runCLI(ctx, pgDump,
 		d.uri,
-		"--file", absPath,
+		"--file", tmp.Name(),
 		"--format", "plain",
 		"--no-owner",
 		"--no-acl",
-	)
+	); err != nil {
+		return err
+	}
+	return writeManifestedExport(ctx, d, tmp.Name(), absPath, opts)
 }
 
 // ImportDatabase loads a SQL dump file into the PostgreSQL database using psql.
-func (d *PostgresDriver) ImportDatabase(ctx context.Context, path string) error {
-	psql, err := findCLITool("psql")
+// The file runs as a single transaction (PostgreSQL DDL is transactional), so
+// a failing statement rolls back everything applied before it.
+func (d *PostgresDriver) ImportDatabase(ctx context.Context, path string, opts ImportOptions) error {
+	psql, err := FindCLITool("psql")
 	if err != nil {
 		return err
 	}
-	absPath, err := validateImportPath(path)
+	absPath, err := validateImportPath(path, opts.AllowedDirs)
 	if err != nil {
 		return err
 	}
-	return runCLI(ctx, psql,
+	if err := checkManifest(absPath, d.engine()); err != nil {
+		return err
+	}
+	out, err := exec.CommandContext(ctx, psql,
 		d.uri,
 		"--file", absPath,
 		"--quiet",
+		"--single-transaction",
 		"--set", "ON_ERROR_STOP=1",
-	)
+	).CombinedOutput()
+	if err != nil {
+		if line, msg, ok := parsePsqlError(string(out)); ok {
+			return fmt.Errorf("import failed at line %d: %s; the transaction was rolled back and no changes were applied",
+				line, truncateMsg(msg, 500))
+		}
+		return fmt.Errorf("psql failed: %s", truncateMsg(string(out), 500))
+	}
+	return nil
+}
+
+// psqlErrorLine matches psql's error prefix for script input,
+// e.g. "psql:/tmp/dump.sql:42: ERROR:  relation "x" does not exist".
+var psqlErrorLine = regexp.MustCompile(`(?m)^psql:.*:(\d+): (?:ERROR|FATAL):\s*(.*)$`)
+
+// parsePsqlError extracts the script line number and message of the first
+// error reported by psql.
+func parsePsqlError(out string) (line int, msg string, ok bool) {
+	m := psqlErrorLine.FindStringSubmatch(out)
+	if m == nil {
+		return 0, "", false
+	}
+	line, err := strconv.Atoi(m[1])
+	if err != nil {
+		return 0, "", false
+	}
+	return line, m[2], true
+}
+
+func (d *PostgresDriver) engine() string { return "postgres" }
+
+func (d *PostgresDriver) serverVersion(ctx context.Context) (string, error) {
+	var v string
+	err := d.pool.QueryRow(ctx, "SHOW server_version").Scan(&v)
+	return v, err
+}
+
+func (d *PostgresDriver) countRows(ctx context.Context, table string) (int64, error) {
+	var n int64
+	err := d.pool.QueryRow(ctx, "SELECT COUNT(*) FROM "+pgx.Identifier{"public", table}.Sanitize()).Scan(&n)
+	return n, err
 }
 
 // Ensure PostgresDriver implements Exporter.
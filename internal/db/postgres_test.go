@@ -0,0 +1,28 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestPostgresPoolConfig(t *testing.T) {
+	cfg, err := postgresPoolConfig("postgres://user@localhost:5432/app", ConnectOptions{ReadOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.ConnConfig.RuntimeParams["default_transaction_read_only"]; got != "on" {
+		t.Errorf("default_transaction_read_only = %q, want on", got)
+	}
+	h, ok := cfg.ConnConfig.BuildContextWatcherHandler(nil).(*pgconn.CancelRequestContextWatcherHandler)
+	if !ok {
+		t.Fatalf("context watcher handler = %T, want *pgconn.CancelRequestContextWatcherHandler", h)
+	}
+	if h.DeadlineDelay != pgCancelGrace {
+		t.Errorf("DeadlineDelay = %v, want %v", h.DeadlineDelay, pgCancelGrace)
+	}
+
+	if _, err := postgresPoolConfig("postgres://user@localhost:notaport/app", ConnectOptions{}); err == nil {
+		t.Error("invalid URI: want error")
+	}
+}
@@ -0,0 +1,106 @@
+package db
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ProfileColumn is a column ProfileQuery computes statistics of.
+type ProfileColumn struct {
+	Name string
+	// Distinct counts its distinct values; the type must support equality.
+	Distinct bool
+	// Length averages the length in characters of its values, which must
+	// be strings.
+	Length bool
+}
+
+// ProfileQuery returns a statement computing, over the first sample rows of
+// table (every row if sample is 0), in the dialect of connection type typ,
+// one row holding the number of rows as "rows" and, for the i-th of cols,
+// the number of its non-NULL values as "nonnull_i", of its distinct values
+// as "distinct_i" if Distinct is set, and the average length of its values
+// as "length_i" if Length is set.
+func ProfileQuery(typ, schema, table string, cols []ProfileColumn, sample int) string {
+	quote := quoteFor(typ)
+	exprs := []string{"COUNT(*) AS " + quote("rows")}
+	for i, c := range cols {
+		name := quote(c.Name)
+		exprs = append(exprs, fmt.Sprintf("COUNT(%s) AS %s", name, quote(fmt.Sprintf("nonnull_%d", i))))
+		if c.Distinct {
+			exprs = append(exprs, fmt.Sprintf("COUNT(DISTINCT %s) AS %s", name, quote(fmt.Sprintf("distinct_%d", i))))
+		}
+		if c.Length {
+			exprs = append(exprs, fmt.Sprintf("%s AS %s", averageLength(typ, name), quote(fmt.Sprintf("length_%d", i))))
+		}
+	}
+	return fmt.Sprintf("SELECT %s FROM %s", strings.Join(exprs, ", "), profileSource(typ, schema, table, sample))
+}
+
+// averageLength returns the expression averaging the length in characters
+// of the values of column, as a float.
+func averageLength(typ, column string) string {
+	switch typ {
+	case "sqlserver":
+		return "AVG(CAST(LEN(" + column + ") AS FLOAT))"
+	case "mysql":
+		return "AVG(CHAR_LENGTH(" + column + "))"
+	case "postgres":
+		return "CAST(AVG(length(" + column + ")) AS double precision)"
+	}
+	return "AVG(length(" + column + "))"
+}
+
+// ColumnAggregateQuery returns a statement applying the aggregate function
+// fn (MIN or MAX) to each of columns over the first sample rows of table
+// (every row if sample is 0), in one row. Each result is named after its
+// column, so masking rules apply to it as to the column's values.
+func ColumnAggregateQuery(typ, schema, table, fn string, columns []string, sample int) string {
+	quote := quoteFor(typ)
+	exprs := make([]string, len(columns))
+	for i, c := range columns {
+		exprs[i] = fmt.Sprintf("%s(%s) AS %s", fn, quote(c), quote(c))
+	}
+	return fmt.Sprintf("SELECT %s FROM %s", strings.Join(exprs, ", "), profileSource(typ, schema, table, sample))
+}
+
+// TopValuesQuery returns a statement selecting the k most common non-NULL
+// values of column over the first sample rows of table (every row if
+// sample is 0), most common first, then by value. The values are named
+// after the column, so masking rules apply to them, and their counts
+// countColumn.
+func TopValuesQuery(typ, schema, table, column string, k, sample int) (query, countColumn string) {
+	quote := quoteFor(typ)
+	countColumn = freeAlias("value_count", []string{column})
+	col, count := quote(column), quote(countColumn)
+	top, limit := "", fmt.Sprintf(" LIMIT %d", k)
+	if typ == "sqlserver" {
+		top, limit = fmt.Sprintf("TOP (%d) ", k), ""
+	}
+	query = fmt.Sprintf("SELECT %s%s AS %s, COUNT(*) AS %s FROM %s WHERE %s IS NOT NULL GROUP BY %s ORDER BY %s DESC, %s%s",
+		top, col, col, count, profileSource(typ, schema, table, sample), col, col, count, col, limit)
+	return query, countColumn
+}
+
+// profileSource returns what the profile queries read: table, or a
+// derived table of its first sample rows if sample is not 0.
+func profileSource(typ, schema, table string, sample int) string {
+	name := quoteTableFor(typ, schema, table)
+	if sample <= 0 {
+		return name
+	}
+	if typ == "sqlserver" {
+		return fmt.Sprintf("(SELECT TOP (%d) * FROM %s) AS profiled", sample, name)
+	}
+	return fmt.Sprintf("(SELECT * FROM %s LIMIT %d) AS profiled", name, sample)
+}
+
+// freeAlias returns alias, with underscores appended until it is not one of
+// columns.
+func freeAlias(alias string, columns []string) string {
+	for slices.ContainsFunc(columns, func(c string) bool { return strings.EqualFold(c, alias) }) {
+		alias += "_"
+	}
+	return alias
+}
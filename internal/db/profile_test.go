@@ -0,0 +1,42 @@
+package db
+
+import "testing"
+
+func TestProfileQuery(t *testing.T) {
+	cols := []ProfileColumn{{Name: "email", Distinct: true, Length: true}, {Name: "data"}}
+	for _, tt := range []struct {
+		typ    string
+		sample int
+		want   string
+	}{
+		{"postgres", 0, `SELECT COUNT(*) AS "rows", COUNT("email") AS "nonnull_0", COUNT(DISTINCT "email") AS "distinct_0", ` +
+			`CAST(AVG(length("email")) AS double precision) AS "length_0", COUNT("data") AS "nonnull_1" FROM "public"."users"`},
+		{"mysql", 100, "SELECT COUNT(*) AS `rows`, COUNT(`email`) AS `nonnull_0`, COUNT(DISTINCT `email`) AS `distinct_0`, " +
+			"AVG(CHAR_LENGTH(`email`)) AS `length_0`, COUNT(`data`) AS `nonnull_1` FROM (SELECT * FROM `public`.`users` LIMIT 100) AS profiled"},
+		{"sqlserver", 100, "SELECT COUNT(*) AS [rows], COUNT([email]) AS [nonnull_0], COUNT(DISTINCT [email]) AS [distinct_0], " +
+			"AVG(CAST(LEN([email]) AS FLOAT)) AS [length_0], COUNT([data]) AS [nonnull_1] FROM (SELECT TOP (100) * FROM [public].[users]) AS profiled"},
+	} {
+		if got := ProfileQuery(tt.typ, "public", "users", cols, tt.sample); got != tt.want {
+			t.Errorf("ProfileQuery(%q, %d) =\n%s\nwant\n%s", tt.typ, tt.sample, got, tt.want)
+		}
+	}
+
+	if got, want := ColumnAggregateQuery("sqlite", "", "users", "MAX", []string{"id", "email"}, 0),
+		`SELECT MAX("id") AS "id", MAX("email") AS "email" FROM "users"`; got != want {
+		t.Errorf("ColumnAggregateQuery = %s, want %s", got, want)
+	}
+
+	for _, tt := range []struct {
+		typ, column, want, count string
+	}{
+		{"sqlite", "city", `SELECT "city" AS "city", COUNT(*) AS "value_count" FROM "users" WHERE "city" IS NOT NULL ` +
+			`GROUP BY "city" ORDER BY "value_count" DESC, "city" LIMIT 3`, "value_count"},
+		{"sqlserver", "value_count", "SELECT TOP (3) [value_count] AS [value_count], COUNT(*) AS [value_count_] FROM [users] " +
+			"WHERE [value_count] IS NOT NULL GROUP BY [value_count] ORDER BY [value_count_] DESC, [value_count]", "value_count_"},
+	} {
+		got, count := TopValuesQuery(tt.typ, "", "users", tt.column, 3, 0)
+		if got != tt.want || count != tt.count {
+			t.Errorf("TopValuesQuery(%q, %q) = %s, %s\nwant %s, %s", tt.typ, tt.column, got, count, tt.want, tt.count)
+		}
+	}
+}
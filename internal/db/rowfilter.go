@@ -0,0 +1,22 @@
+package db
+
+import "context"
+
+type rowFilterKey struct{}
+
+// WithRowFilter returns a copy of ctx carrying predicate, an SQL condition
+// UpdateRow adds to the WHERE clause of its UPDATE, so a row the key names
+// but the predicate excludes is not found. Callers must have checked that
+// predicate is a single condition; it is pasted into the statement as is.
+func WithRowFilter(ctx context.Context, predicate string) context.Context {
+	return context.WithValue(ctx, rowFilterKey{}, predicate)
+}
+
+// filterUpdate adds the row filter of ctx, if any, to query, an UPDATE
+// built by UpdateSQL and so ending in its WHERE clause.
+func filterUpdate(ctx context.Context, query string) string {
+	if predicate, _ := ctx.Value(rowFilterKey{}).(string); predicate != "" {
+		query += " AND (" + predicate + ")"
+	}
+	return query
+}
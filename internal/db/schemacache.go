@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// SchemaInvalidator is an optional interface for drivers that cache table
+// metadata. InvalidateSchema drops the cached metadata of table in schema;
+// an empty table means every table in schema, and an empty schema every
+// schema. It returns the number of tables dropped.
+type SchemaInvalidator interface {
+	InvalidateSchema(schema, table string) int
+}
+
+// schemaCache keeps DescribeTable results for ttl, so the primary key check
+// of every update_test_row does not query the catalog again. Drivers embed
+// it; each driver serves one connection, so entries are per connection.
+type schemaCache struct {
+	ttl time.Duration // zero or less disables the cache
+
+	mu     sync.Mutex
+	tables map[tableKey]cachedColumns
+}
+
+type tableKey struct{ schema, table string }
+
+type cachedColumns struct {
+	cols []ColumnInfo
+	at   time.Time
+}
+
+func newSchemaCache(ttl time.Duration) schemaCache {
+	return schemaCache{ttl: ttl, tables: make(map[tableKey]cachedColumns)}
+}
+
+// describe returns the cached columns of schema.table, calling load on a
+// miss or once the entry is older than ttl. Errors are not cached.
+func (c *schemaCache) describe(ctx context.Context, schema, table string, load func(ctx context.Context, schema, table string) ([]ColumnInfo, error)) ([]ColumnInfo, error) {
+	if c.ttl <= 0 {
+		return load(ctx, schema, table)
+	}
+	key := tableKey{schema, table}
+	c.mu.Lock()
+	e, ok := c.tables[key]
+	c.mu.Unlock()
+	if ok && time.Since(e.at) < c.ttl {
+		return slices.Clone(e.cols), nil
+	}
+	cols, err := load(ctx, schema, table)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.tables[key] = cachedColumns{cols: slices.Clone(cols), at: time.Now()}
+	c.mu.Unlock()
+	return cols, nil
+}
+
+// InvalidateSchema implements SchemaInvalidator. Entries cached under the
+// default schema (an empty schema argument) match any schema, since the
+// cache does not know which schema the database defaulted to.
+func (c *schemaCache) InvalidateSchema(schema, table string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for key := range c.tables {
+		if (schema == "" || key.schema == "" || key.schema == schema) && (table == "" || key.table == table) {
+			delete(c.tables, key)
+			n++
+		}
+	}
+	return n
+}
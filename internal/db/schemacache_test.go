@@ -0,0 +1,88 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSchemaCache(t *testing.T) {
+	ctx := context.Background()
+	loads := 0
+	var loadErr error
+	load := func(_ context.Context, schema, table string) ([]ColumnInfo, error) {
+		loads++
+		if loadErr != nil {
+			return nil, loadErr
+		}
+		return []ColumnInfo{{Name: schema + "." + table}}, nil
+	}
+
+	c := newSchemaCache(time.Minute)
+	for range 2 {
+		if cols, err := c.describe(ctx, "", "users", load); err != nil || cols[0].Name != ".users" {
+			t.Fatalf("describe: %v, %v", cols, err)
+		}
+	}
+	if loads != 1 {
+		t.Errorf("loads = %d, want 1 (second call cached)", loads)
+	}
+
+	c.describe(ctx, "app", "orders", load)
+	c.describe(ctx, "app", "items", load)
+	if n := c.InvalidateSchema("app", "orders"); n != 1 {
+		t.Errorf("invalidate app.orders: dropped %d, want 1", n)
+	}
+	// Entries under the default schema match any schema.
+	if n := c.InvalidateSchema("public", "users"); n != 1 {
+		t.Errorf("invalidate public.users: dropped %d, want 1", n)
+	}
+	if n := c.InvalidateSchema("", ""); n != 1 {
+		t.Errorf("invalidate all: dropped %d, want 1", n)
+	}
+
+	loads, loadErr = 0, errors.New("boom")
+	for range 2 {
+		if _, err := c.describe(ctx, "", "users", load); err == nil {
+			t.Fatal("expected the load error")
+		}
+	}
+	if loads != 2 {
+		t.Errorf("loads = %d, want 2 (errors are not cached)", loads)
+	}
+
+	loads, loadErr = 0, nil
+	off := newSchemaCache(0)
+	off.describe(ctx, "", "users", load)
+	off.describe(ctx, "", "users", load)
+	if loads != 2 {
+		t.Errorf("loads with the cache off = %d, want 2", loads)
+	}
+}
+
+func TestSQLite_DescribeTable_cached(t *testing.T) {
+	ctx := context.Background()
+	d, err := NewSQLiteDriver(ctx, ":memory:", ConnectOptions{SchemaCacheTTL: time.Minute})
+	if err != nil {
+		t.Fatalf("NewSQLiteDriver: %v", err)
+	}
+	defer d.Close()
+	if _, err := d.db.Exec(`CREATE TABLE t (id INTEGER PRIMARY KEY)`); err != nil {
+		t.Fatal(err)
+	}
+	if cols, err := d.DescribeTable(ctx, "", "t"); err != nil || len(cols) != 1 {
+		t.Fatalf("DescribeTable: %v, %v", cols, err)
+	}
+	if _, err := d.db.Exec(`ALTER TABLE t ADD COLUMN v TEXT`); err != nil {
+		t.Fatal(err)
+	}
+	if cols, _ := d.DescribeTable(ctx, "", "t"); len(cols) != 1 {
+		t.Errorf("expected the cached columns before invalidation, got %d", len(cols))
+	}
+	var inv SchemaInvalidator = d
+	inv.InvalidateSchema("", "t")
+	if cols, _ := d.DescribeTable(ctx, "", "t"); len(cols) != 2 {
+		t.Errorf("expected the new column after invalidation, got %d columns", len(cols))
+	}
+}
@@ -0,0 +1,73 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ScratchSchemas is an optional interface for drivers that can give a
+// client session a schema of its own, to create test tables in without
+// touching the application's, and drop it with everything in it once the
+// session ends. On MySQL the schema is a database, on SQLite an attached
+// temporary database.
+type ScratchSchemas interface {
+	// CreateScratchSchema creates schema name if it does not exist.
+	CreateScratchSchema(ctx context.Context, name string) error
+	// DropScratchSchema drops schema name and every table in it.
+	DropScratchSchema(ctx context.Context, name string) error
+	// CreateTable creates table in schema with the given columns.
+	CreateTable(ctx context.Context, schema, table string, cols []ColumnDef) error
+}
+
+// ColumnDef is a column of a table created with ScratchSchemas.CreateTable.
+type ColumnDef struct {
+	Name string `json:"name"`
+	// Type is the column type in the database's own SQL, e.g. "integer",
+	// "varchar(100)" or "numeric(10, 2)".
+	Type       string `json:"type"`
+	PrimaryKey bool   `json:"primary_key,omitempty"`
+	NotNull    bool   `json:"not_null,omitempty"`
+}
+
+// columnTypePattern is what CreateTable accepts as a column type: words,
+// optionally followed by a length or precision and scale. It keeps a type
+// from carrying anything but a type into the statement.
+var columnTypePattern = regexp.MustCompile(`(?i)^[a-z][a-z0-9_]*( [a-z][a-z0-9_]*)*( ?\( ?(\d+|max) ?(, ?\d+ ?)?\))?$`)
+
+// createTableSQL returns the CREATE TABLE statement for CreateTable, with
+// identifiers quoted by quote and the table name already quoted as name.
+func createTableSQL(name string, cols []ColumnDef, quote func(string) string) (string, error) {
+	if len(cols) == 0 {
+		return "", classify(ErrInvalidInput, "create table: no columns")
+	}
+	defs := make([]string, 0, len(cols)+1)
+	var pk []string
+	seen := make(map[string]bool, len(cols))
+	for _, c := range cols {
+		if c.Name == "" {
+			return "", classify(ErrInvalidInput, "create table: a column has no name")
+		}
+		if seen[strings.ToLower(c.Name)] {
+			return "", classify(ErrInvalidInput, "create table: column %q appears twice", c.Name)
+		}
+		seen[strings.ToLower(c.Name)] = true
+		typ := strings.Join(strings.Fields(c.Type), " ")
+		if !columnTypePattern.MatchString(typ) {
+			return "", classify(ErrInvalidInput, "create table: column %q: %q is not a column type", c.Name, c.Type)
+		}
+		def := quote(c.Name) + " " + typ
+		if c.NotNull || c.PrimaryKey {
+			def += " NOT NULL"
+		}
+		defs = append(defs, def)
+		if c.PrimaryKey {
+			pk = append(pk, quote(c.Name))
+		}
+	}
+	if len(pk) > 0 {
+		defs = append(defs, "PRIMARY KEY ("+strings.Join(pk, ", ")+")")
+	}
+	return fmt.Sprintf("CREATE TABLE %s (%s)", name, strings.Join(defs, ", ")), nil
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"strings"
+	"sync/atomic"
 
 	_ "modernc.org/sqlite"
 )
@@ -13,19 +14,44 @@ import (
 type SQLiteDriver struct {
 	db  *sql.DB
 	uri string
+	// pinned is set once the pool is down to one connection, which holds
+	// the attached scratch databases (see CreateScratchSchema).
+	pinned atomic.Bool
+
+	schemaCache // DescribeTable results
+	masker      *Masker
 }
 
 // NewSQLiteDriver opens a SQLite database at the given path (or URI such as "file:path?mode=...").
-func NewSQLiteDriver(_ context.Context, uri string) (*SQLiteDriver, error) {
-	db, err := sql.Open("sqlite", uri)
+// With opts.ReadOnly every connection sets PRAGMA query_only.
+func NewSQLiteDriver(_ context.Context, uri string, opts ConnectOptions) (*SQLiteDriver, error) {
+	dsn := uri
+	if opts.ReadOnly {
+		sep := "?"
+		if strings.Contains(uri, "?") {
+			sep = "&"
+		}
+		dsn += sep + "_pragma=query_only(1)"
+	}
+	db, err := sql.Open("sqlite", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("sqlite open: %w", err)
 	}
+	// Every connection to an in-memory database gets its own empty
+	// database, so concurrent callers must share a single connection.
+	if isSQLiteMemory(uri) {
+		db.SetMaxOpenConns(1)
+	}
 	if err := db.Ping(); err != nil {
 		db.Close()
 		return nil, fmt.Errorf("sqlite ping: %w", err)
 	}
-	return &SQLiteDriver{db: db, uri: uri}, nil
+	return &SQLiteDriver{db: db, uri: uri, schemaCache: newSchemaCache(opts.SchemaCacheTTL), masker: opts.Masker}, nil
+}
+
+// isSQLiteMemory reports whether uri names an in-memory database.
+func isSQLiteMemory(uri string) bool {
+	return uri == ":memory:" || strings.HasPrefix(uri, "file::memory:") || strings.Contains(uri, "mode=memory")
 }
 
 // Ping implements Driver.
@@ -33,10 +59,26 @@ func (d *SQLiteDriver) Ping(ctx context.Context) error {
 	return d.db.PingContext(ctx)
 }
 
-// ListTables implements Driver. Schema is ignored for SQLite (single schema).
-func (d *SQLiteDriver) ListTables(ctx context.Context, _ string) ([]string, error) {
+// Capabilities implements Driver.
+func (d *SQLiteDriver) Capabilities() Capabilities {
+	caps, _ := CapabilitiesFor(d.engine())
+	return caps
+}
+
+// PoolStats implements PoolStatter.
+func (d *SQLiteDriver) PoolStats() PoolStats {
+	return sqlPoolStats(d.db.Stats())
+}
+
+// ListTables implements Driver. SQLite has a single schema; a non-empty
+// schema names an attached database (see CreateScratchSchema).
+func (d *SQLiteDriver) ListTables(ctx context.Context, schema string) ([]string, error) {
+	master := "sqlite_master"
+	if schema != "" {
+		master = quoteSQLiteIdentifier(schema) + ".sqlite_master"
+	}
 	rows, err := d.db.QueryContext(ctx,
-		`SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name`)
+		`SELECT name FROM `+master+` WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name`)
 	if err != nil {
 		return nil, err
 	}
@@ -52,10 +94,41 @@ func (d *SQLiteDriver) ListTables(ctx context.Context, _ string) ([]string, erro
 	return names, rows.Err()
 }
 
-// DescribeTable implements Driver.
-func (d *SQLiteDriver) DescribeTable(ctx context.Context, _, table string) ([]ColumnInfo, error) {
+// DescribeTable implements Driver. Results are cached per table for the
+// connection's schema cache TTL; see SchemaInvalidator.
+func (d *SQLiteDriver) DescribeTable(ctx context.Context, schema, table string) ([]ColumnInfo, error) {
+	return d.describe(ctx, schema, table, d.describeTable)
+}
+
+// describeTable queries the catalog for DescribeTable.
+func (d *SQLiteDriver) describeTable(ctx context.Context, schema, table string) ([]ColumnInfo, error) {
+	return sqliteCatalog{d.db}.DescribeTable(ctx, d.qualify(schema), table)
+}
+
+// qualify returns the database an unqualified name of a table to describe
+// or write refers to: SQLite looks it up in every attached database, so
+// once scratch databases are attached (see CreateScratchSchema) it is
+// pinned to main, and one session's table never stands in for an
+// application table another session writes to.
+func (d *SQLiteDriver) qualify(schema string) string {
+	if schema == "" && d.pinned.Load() {
+		return "main"
+	}
+	return schema
+}
+
+// sqliteCatalog reads table metadata through q, uncached.
+type sqliteCatalog struct {
+	q sqlQueryer
+}
+
+func (c sqliteCatalog) DescribeTable(ctx context.Context, schema, table string) ([]ColumnInfo, error) {
+	pragma := "PRAGMA "
+	if schema != "" {
+		pragma += quoteSQLiteIdentifier(schema) + "."
+	}
 	// table_info returns: cid, name, type, notnull, dflt_value, pk
-	rows, err := d.db.QueryContext(ctx, fmt.Sprintf("PRAGMA table_info(%s)", quoteSQLiteIdentifier(table)))
+	rows, err := c.q.QueryContext(ctx, fmt.Sprintf("%stable_info(%s)", pragma, quoteSQLiteIdentifier(table)))
 	if err != nil {
 		return nil, err
 	}
@@ -77,7 +150,191 @@ func (d *SQLiteDriver) DescribeTable(ctx context.Context, _, table string) ([]Co
 			IsPK:     pk > 0,
 		})
 	}
-	return cols, rows.Err()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// A lone INTEGER PRIMARY KEY aliases the rowid, which is never NULL,
+	// though table_info reports it nullable unless declared NOT NULL.
+	var pks []int
+	for i, col := range cols {
+		if col.IsPK {
+			pks = append(pks, i)
+		}
+	}
+	if len(pks) == 1 && strings.EqualFold(cols[pks[0]].Type, "INTEGER") {
+		cols[pks[0]].Nullable = false
+	}
+	return cols, nil
+}
+
+// ForeignKeys implements ForeignKeyLister. SQLite constraints have no
+// names, so Name is empty; a reference to the parent's primary key without
+// columns lists those columns as RefColumns.
+func (d *SQLiteDriver) ForeignKeys(ctx context.Context, schema string) ([]ForeignKey, error) {
+	tables, err := d.ListTables(ctx, schema)
+	if err != nil {
+		return nil, err
+	}
+	pragma := "PRAGMA "
+	if s := d.qualify(schema); s != "" {
+		pragma += quoteSQLiteIdentifier(s) + "."
+	}
+	var fks []ForeignKey
+	for _, table := range tables {
+		// foreign_key_list returns: id, seq, table, from, to, on_update,
+		// on_delete, match, a row per column ordered by id and seq.
+		rows, err := d.db.QueryContext(ctx, fmt.Sprintf("%sforeign_key_list(%s)", pragma, quoteSQLiteIdentifier(table)))
+		if err != nil {
+			return nil, err
+		}
+		lastID := -1
+		for rows.Next() {
+			var id, seq int
+			var refTable, col, onUpdate, onDelete, match string
+			var refCol sql.NullString
+			if err := rows.Scan(&id, &seq, &refTable, &col, &refCol, &onUpdate, &onDelete, &match); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			if id != lastID {
+				fks = append(fks, ForeignKey{Table: table, RefTable: refTable, OnDelete: onDelete, OnUpdate: onUpdate})
+				lastID = id
+			}
+			fk := &fks[len(fks)-1]
+			fk.Columns = append(fk.Columns, col)
+			if refCol.Valid {
+				fk.RefColumns = append(fk.RefColumns, refCol.String)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	for i, fk := range fks {
+		if len(fk.RefColumns) > 0 {
+			continue
+		}
+		cols, err := d.DescribeTable(ctx, schema, fk.RefTable)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range cols {
+			if c.IsPK {
+				fks[i].RefColumns = append(fks[i].RefColumns, c.Name)
+			}
+		}
+	}
+	return fks, nil
+}
+
+// Dependents implements DependencyLister. SQLite records no dependencies:
+// triggers on table are found by their table, views and the other
+// triggers by the table's name in their definition. A non-empty schema
+// names an attached database.
+func (d *SQLiteDriver) Dependents(ctx context.Context, schema, table string) ([]Dependent, error) {
+	schema = d.qualify(schema)
+	master := "sqlite_master"
+	if schema != "" {
+		master = quoteSQLiteIdentifier(schema) + ".sqlite_master"
+	}
+	rows, err := d.db.QueryContext(ctx,
+		"SELECT type, name, tbl_name, COALESCE(sql, '') FROM "+master+" WHERE type IN ('trigger', 'view') ORDER BY type, name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	mentions := mentionsTable(table)
+	var deps []Dependent
+	for rows.Next() {
+		var kind, name, on, def string
+		if err := rows.Scan(&kind, &name, &on, &def); err != nil {
+			return nil, err
+		}
+		switch {
+		case kind == DependentTrigger && strings.EqualFold(on, table):
+			deps = append(deps, Dependent{Kind: kind, Schema: schema, Name: name})
+		case mentions.MatchString(def):
+			deps = append(deps, Dependent{Kind: kind, Schema: schema, Name: name, Inferred: true})
+		}
+	}
+	return deps, rows.Err()
+}
+
+// Indexes implements IndexLister. The primary key of a rowid table is not
+// an index in SQLite; it is listed as a primary index without a name.
+func (d *SQLiteDriver) Indexes(ctx context.Context, schema string) ([]Index, error) {
+	tables, err := d.ListTables(ctx, schema)
+	if err != nil {
+		return nil, err
+	}
+	pragma := "PRAGMA "
+	if s := d.qualify(schema); s != "" {
+		pragma += quoteSQLiteIdentifier(s) + "."
+	}
+	var idxs []Index
+	for _, table := range tables {
+		// index_list returns: seq, name, unique, origin (c, u or pk), partial.
+		rows, err := d.db.QueryContext(ctx, fmt.Sprintf("%sindex_list(%s)", pragma, quoteSQLiteIdentifier(table)))
+		if err != nil {
+			return nil, err
+		}
+		var list []Index
+		for rows.Next() {
+			var seq int
+			var origin string
+			ix := Index{Table: table}
+			if err := rows.Scan(&seq, &ix.Name, &ix.Unique, &origin, &ix.Partial); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			ix.Primary = origin == "pk"
+			list = append(list, ix)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		hasPK := false
+		for i := range list {
+			hasPK = hasPK || list[i].Primary
+			// index_info returns: seqno, cid, name (NULL for an expression).
+			rows, err := d.db.QueryContext(ctx, fmt.Sprintf("%sindex_info(%s)", pragma, quoteSQLiteIdentifier(list[i].Name)))
+			if err != nil {
+				return nil, err
+			}
+			for rows.Next() {
+				var seqno, cid int
+				var col sql.NullString
+				if err := rows.Scan(&seqno, &cid, &col); err != nil {
+					rows.Close()
+					return nil, err
+				}
+				list[i].Columns = append(list[i].Columns, col.String)
+			}
+			rows.Close()
+			if err := rows.Err(); err != nil {
+				return nil, err
+			}
+		}
+		if !hasPK {
+			cols, err := d.DescribeTable(ctx, schema, table)
+			if err != nil {
+				return nil, err
+			}
+			pk := Index{Table: table, Unique: true, Primary: true}
+			for _, c := range cols {
+				if c.IsPK {
+					pk.Columns = append(pk.Columns, c.Name)
+				}
+			}
+			if len(pk.Columns) > 0 {
+				list = append(list, pk)
+			}
+		}
+		idxs = append(idxs, list...)
+	}
+	return sortIndexes(idxs), nil
 }
 
 // RunReadOnlyQuery implements Driver. Uses $1, $2 style positional params
@@ -89,7 +346,7 @@ func (d *SQLiteDriver) RunReadOnlyQuery(ctx context.Context, query string, param
 		return nil, err
 	}
 	defer rows.Close()
-	return sqlRowsToMaps(rows)
+	return sqlRowsToMaps(rows, d.masker.forQuery(query))
 }
 
 // convertPlaceholdersToSQLite replaces $1, $2, ... with ?1, ?2, ... for SQLite.
@@ -98,21 +355,21 @@ func convertPlaceholdersToSQLite(s string) string {
 }
 
 // InsertRow implements Driver.
-func (d *SQLiteDriver) InsertRow(ctx context.Context, _, table string, row map[string]any) (any, error) {
+func (d *SQLiteDriver) InsertRow(ctx context.Context, schema, table string, row map[string]any) (any, error) {
+	return d.insertRow(ctx, d.db, d, schema, table, row)
+}
+
+// insertRow runs InsertRow on q, the pool or a transaction.
+func (d *SQLiteDriver) insertRow(ctx context.Context, q sqlConn, desc tableDescriber, schema, table string, row map[string]any) (any, error) {
 	if len(row) == 0 {
-		return nil, fmt.Errorf("insert row: no columns")
+		return nil, classify(ErrInvalidInput, "insert row: no columns")
 	}
-	cols, vals := mapsToColumnsAndValues(row)
-	placeholders := makeSQLitePlaceholders(len(cols))
-	quotedTable := quoteSQLiteIdentifier(table)
-	quotedCols := make([]string, len(cols))
-	for i, c := range cols {
-		quotedCols[i] = quoteSQLiteIdentifier(c)
+	schema = d.qualify(schema)
+	if _, err := checkColumns(ctx, desc, "insert row", schema, table, row); err != nil {
+		return nil, err
 	}
-	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
-		quotedTable, joinQuoted(quotedCols), placeholders)
-
-	result, err := d.db.ExecContext(ctx, query, vals...)
+	query, vals := d.InsertSQL(schema, table, row)
+	result, err := q.ExecContext(ctx, query, vals...)
 	if err != nil {
 		return nil, err
 	}
@@ -124,19 +381,76 @@ func (d *SQLiteDriver) InsertRow(ctx context.Context, _, table string, row map[s
 }
 
 // UpdateRow implements Driver. Validates key matches actual PK, then updates a single row.
-func (d *SQLiteDriver) UpdateRow(ctx context.Context, _, table string, key map[string]any, set map[string]any) (int64, error) {
+func (d *SQLiteDriver) UpdateRow(ctx context.Context, schema, table string, key map[string]any, set map[string]any) (int64, error) {
+	return d.updateRow(ctx, d.db, d, schema, table, key, set)
+}
+
+// updateRow runs UpdateRow on q, the pool or a transaction.
+func (d *SQLiteDriver) updateRow(ctx context.Context, q sqlConn, desc tableDescriber, schema, table string, key map[string]any, set map[string]any) (int64, error) {
 	if len(key) == 0 {
-		return 0, fmt.Errorf("update row: key must contain at least one column")
+		return 0, classify(ErrInvalidInput, "update row: key must contain at least one column")
 	}
 	if len(set) == 0 {
-		return 0, fmt.Errorf("update row: set must contain at least one column")
+		return 0, classify(ErrInvalidInput, "update row: set must contain at least one column")
 	}
 
 	// Fetch actual PK columns and validate the provided key matches.
-	if err := validatePKColumns(ctx, d, "", table, key); err != nil {
+	schema = d.qualify(schema)
+	if err := validatePKColumns(ctx, desc, schema, table, key, set); err != nil {
 		return 0, err
 	}
 
+	query, params := d.UpdateSQL(schema, table, key, set)
+	query = filterUpdate(ctx, query)
+	query, params = expectUpdate(ctx, query, params, quoteSQLiteIdentifier, sqlitePlaceholder)
+	result, err := q.ExecContext(ctx, query, params...)
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if n == 0 && expectedValues(ctx) != nil {
+		return 0, expectedConflict(ctx, sqlRowReader(ctx, q, d.masker), quoteSQLiteTable(schema, table), key, quoteSQLiteIdentifier, sqlitePlaceholder)
+	}
+	if n == 0 {
+		return 0, classify(ErrNotFound, "update row: no row found with the given key")
+	}
+	return n, nil
+}
+
+// BeginTx implements Transactor. The transaction holds one of the pool's
+// connections until it ends; for an in-memory database, or one with
+// scratch databases attached, that is the only one, so other calls wait
+// for it, and columns are validated through the transaction itself.
+func (d *SQLiteDriver) BeginTx(ctx context.Context) (Tx, error) {
+	t, err := beginSQLTx(ctx, d.db, d, d)
+	if err != nil {
+		return nil, err
+	}
+	if isSQLiteMemory(d.uri) || d.pinned.Load() {
+		t.desc = sqliteCatalog{t.tx}
+	}
+	return t, nil
+}
+
+// InsertSQL implements WritePreviewer.
+func (d *SQLiteDriver) InsertSQL(schema, table string, row map[string]any) (string, []any) {
+	cols, vals := mapsToColumnsAndValues(row)
+	placeholders := makeSQLitePlaceholders(len(cols))
+	quotedTable := quoteSQLiteTable(schema, table)
+	quotedCols := make([]string, len(cols))
+	for i, c := range cols {
+		quotedCols[i] = quoteSQLiteIdentifier(c)
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		quotedTable, joinQuoted(quotedCols), placeholders)
+	return query, vals
+}
+
+// UpdateSQL implements WritePreviewer.
+func (d *SQLiteDriver) UpdateSQL(schema, table string, key, set map[string]any) (string, []any) {
 	// Build SET clause: "col1" = ?1, "col2" = ?2, ...
 	setCols, setVals := mapsToColumnsAndValues(set)
 	quotedSets := make([]string, len(setCols))
@@ -151,9 +465,8 @@ func (d *SQLiteDriver) UpdateRow(ctx context.Context, _, table string, key map[s
 		quotedWheres[i] = fmt.Sprintf("%s = ?%d", quoteSQLiteIdentifier(c), len(setCols)+i+1)
 	}
 
-	quotedTable := quoteSQLiteIdentifier(table)
 	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s",
-		quotedTable,
+		quoteSQLiteTable(schema, table),
 		strings.Join(quotedSets, ", "),
 		strings.Join(quotedWheres, " AND "),
 	)
@@ -161,21 +474,11 @@ func (d *SQLiteDriver) UpdateRow(ctx context.Context, _, table string, key map[s
 	params := make([]any, 0, len(setVals)+len(keyVals))
 	params = append(params, setVals...)
 	params = append(params, keyVals...)
-
-	result, err := d.db.ExecContext(ctx, query, params...)
-	if err != nil {
-		return 0, err
-	}
-	n, err := result.RowsAffected()
-	if err != nil {
-		return 0, err
-	}
-	if n == 0 {
-		return 0, fmt.Errorf("update row: no row found with the given key")
-	}
-	return n, nil
+	return query, params
 }
 
+func sqlitePlaceholder(n int) string { return fmt.Sprintf("?%d", n) }
+
 func makeSQLitePlaceholders(n int) string {
 	if n == 0 {
 		return ""
@@ -196,9 +499,65 @@ func quoteSQLiteIdentifier(name string) string {
 	return `"` + sqliteIdentReplacer.Replace(name) + `"`
 }
 
+// quoteSQLiteTable returns "schema"."table" if schema is non-empty,
+// otherwise "table".
+func quoteSQLiteTable(schema, table string) string {
+	if schema == "" {
+		return quoteSQLiteIdentifier(table)
+	}
+	return quoteSQLiteIdentifier(schema) + "." + quoteSQLiteIdentifier(table)
+}
+
+// CreateScratchSchema implements ScratchSchemas with an attached temporary
+// database, which SQLite deletes when it is detached or its connection
+// closes. Attachments belong to a connection, so the pool is first cut
+// down to a single one, as for an in-memory database.
+func (d *SQLiteDriver) CreateScratchSchema(ctx context.Context, name string) error {
+	if !d.pinned.Swap(true) {
+		d.db.SetMaxOpenConns(1)
+	}
+	attached, err := d.attached(ctx, name)
+	if err != nil || attached {
+		return err
+	}
+	_, err = d.db.ExecContext(ctx, "ATTACH DATABASE '' AS "+quoteSQLiteIdentifier(name))
+	return err
+}
+
+// DropScratchSchema implements ScratchSchemas.
+func (d *SQLiteDriver) DropScratchSchema(ctx context.Context, name string) error {
+	defer d.InvalidateSchema(name, "")
+	attached, err := d.attached(ctx, name)
+	if err != nil || !attached {
+		return err
+	}
+	_, err = d.db.ExecContext(ctx, "DETACH DATABASE "+quoteSQLiteIdentifier(name))
+	return err
+}
+
+// attached reports whether a database is attached as name.
+func (d *SQLiteDriver) attached(ctx context.Context, name string) (bool, error) {
+	var n int
+	err := d.db.QueryRowContext(ctx, "SELECT count(*) FROM pragma_database_list WHERE name = ?", name).Scan(&n)
+	return n > 0, err
+}
+
+// CreateTable implements ScratchSchemas.
+func (d *SQLiteDriver) CreateTable(ctx context.Context, schema, table string, cols []ColumnDef) error {
+	query, err := createTableSQL(quoteSQLiteTable(schema, table), cols, quoteSQLiteIdentifier)
+	if err != nil {
+		return err
+	}
+	_, err = d.db.ExecContext(ctx, query)
+	d.InvalidateSchema(schema, table)
+	return err
+}
+
 // Close implements Driver.
 func (d *SQLiteDriver) Close() error {
 	return d.db.Close()
 }
 
 var _ Driver = (*SQLiteDriver)(nil)
+var _ WritePreviewer = (*SQLiteDriver)(nil)
+var _ ScratchSchemas = (*SQLiteDriver)(nil)
@@ -24,12 +24,12 @@ func sqliteFilePath(uri string) (string, error) {
 }
 
 // ExportDatabase dumps the SQLite database to a SQL file using sqlite3 .dump.
-func (d *SQLiteDriver) ExportDatabase(ctx context.Context, path string) error {
-	sqlite3, err := findCLITool("sqlite3")
+func (d *SQLiteDriver) ExportDatabase(ctx context.Context, path string, opts ExportOptions) error {
+	sqlite3, err := FindCLITool("sqlite3")
 	if err != nil {
 		return err
 	}
-	absPath, err := validateExportPath(path)
+	absPath, err := validateExportPath(path, opts.AllowedDirs)
 	if err != nil {
 		return err
 	}
@@ -37,24 +37,32 @@ func (d *SQLiteDriver) ExportDatabase(ctx context.Context, path string) error {
 	if err != nil {
 		return err
 	}
-	// sqlite3 dbpath .dump > outputfile
-	return runCLICaptureStdout(ctx, absPath, sqlite3, dbPath, ".dump")
-}
-
-// ImportDatabase loads a SQL dump file into the SQLite database using sqlite3.
-func (d *SQLiteDriver) ImportDatabase(ctx context.Context, path string) error {
-	sqlite3, err := findCLITool("sqlite3")
+	tmp, err := exportTempFile(absPath)
 	if err != nil {
 		return err
 	}
-	absPath, err := validateImportPath(path)
-	if err != nil {
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+	// sqlite3 dbpath .dump > outputfile
+	if err := runCLICaptureStdout(ctx, tmp.Name(), sqlite3, dbPath, ".dump"); err != nil {
 		return err
 	}
-	dbPath, err := sqliteFilePath(d.uri)
+	return writeManifestedExport(ctx, d, tmp.Name(), absPath, opts)
+}
+
+// ImportDatabase loads a SQL dump file into the SQLite database. The script
+// is executed statement by statement inside a single transaction on the
+// driver's own connection, so a failing statement rolls back everything and
+// leaves the database untouched. The dump's own BEGIN/COMMIT framing (as
+// written by sqlite3 .dump) is skipped.
+func (d *SQLiteDriver) ImportDatabase(ctx context.Context, path string, opts ImportOptions) error {
+	absPath, err := validateImportPath(path, opts.AllowedDirs)
 	if err != nil {
 		return err
 	}
+	if err := checkManifest(absPath, d.engine()); err != nil {
+		return err
+	}
 
 	f, err := os.Open(absPath)
 	if err != nil {
@@ -62,7 +70,53 @@ func (d *SQLiteDriver) ImportDatabase(ctx context.Context, path string) error {
 	}
 	defer f.Close()
 
-	return runCLIWithStdin(ctx, nil, f, sqlite3, dbPath)
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("import: begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Dumps may insert child rows before their parents; check FKs at commit.
+	if _, err := tx.ExecContext(ctx, "PRAGMA defer_foreign_keys = ON"); err != nil {
+		return fmt.Errorf("import: %w", err)
+	}
+
+	sc := newStatementScanner(f)
+	for {
+		stmt, ok := sc.Next()
+		if !ok {
+			break
+		}
+		if isTransactionControl(stmt.Text) {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, stmt.Text); err != nil {
+			return fmt.Errorf("import: statement %d (line %d) failed: %s; no changes were applied",
+				stmt.Num, stmt.Line, truncateMsg(err.Error(), 500))
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("import: read file: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("import: commit failed: %w; no changes were applied", err)
+	}
+	return nil
+}
+
+func (d *SQLiteDriver) engine() string { return "sqlite" }
+
+func (d *SQLiteDriver) serverVersion(ctx context.Context) (string, error) {
+	var v string
+	err := d.db.QueryRowContext(ctx, "SELECT sqlite_version()").Scan(&v)
+	return v, err
+}
+
+func (d *SQLiteDriver) countRows(ctx context.Context, table string) (int64, error) {
+	var n int64
+	err := d.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+quoteSQLiteIdentifier(table)).Scan(&n)
+	return n, err
 }
 
 // Ensure SQLiteDriver implements Exporter.
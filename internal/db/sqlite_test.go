@@ -2,12 +2,18 @@ package db
 
 import (
 	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func newTestSQLiteDriver(t *testing.T) *SQLiteDriver {
 	t.Helper()
-	d, err := NewSQLiteDriver(context.Background(), ":memory:")
+	d, err := NewSQLiteDriver(context.Background(), ":memory:", ConnectOptions{})
 	if err != nil {
 		t.Fatalf("NewSQLiteDriver: %v", err)
 	}
@@ -23,79 +29,49 @@ func newTestSQLiteDriver(t *testing.T) *SQLiteDriver {
 	return d
 }
 
-func TestSQLite_Ping(t *testing.T) {
+func TestSQLite_UpdateRow_expected(t *testing.T) {
 	d := newTestSQLiteDriver(t)
 	defer d.Close()
-	if err := d.Ping(context.Background()); err != nil {
-		t.Errorf("Ping: %v", err)
+	ctx := context.Background()
+	if _, err := d.InsertRow(ctx, "", "users", map[string]any{"name": "Alice"}); err != nil {
+		t.Fatalf("InsertRow: %v", err)
 	}
-}
+	key := map[string]any{"id": int64(1)}
 
-func TestSQLite_ListTables(t *testing.T) {
-	d := newTestSQLiteDriver(t)
-	defer d.Close()
-	tables, err := d.ListTables(context.Background(), "")
-	if err != nil {
-		t.Fatalf("ListTables: %v", err)
+	n, err := d.UpdateRow(WithExpected(ctx, map[string]any{"name": "Alice", "email": nil}), "", "users", key, map[string]any{"email": "a@test.com"})
+	if err != nil || n != 1 {
+		t.Fatalf("update with matching expected values = %d, %v", n, err)
 	}
-	// Should contain "users" (and possibly sqlite_sequence, but we filter sqlite_* out).
-	found := false
-	for _, name := range tables {
-		if name == "users" {
-			found = true
-		}
-	}
-	if !found {
-		t.Errorf("expected 'users' in tables, got %v", tables)
-	}
-}
 
-func TestSQLite_DescribeTable(t *testing.T) {
-	d := newTestSQLiteDriver(t)
-	defer d.Close()
-	cols, err := d.DescribeTable(context.Background(), "", "users")
-	if err != nil {
-		t.Fatalf("DescribeTable: %v", err)
-	}
-	if len(cols) != 3 {
-		t.Fatalf("expected 3 columns, got %d", len(cols))
+	_, err = d.UpdateRow(WithExpected(ctx, map[string]any{"email": nil}), "", "users", key, map[string]any{"name": "Bob"})
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) || !errors.Is(err, ErrConflict) {
+		t.Fatalf("stale expected values: err = %v", err)
 	}
-	// id should be PK
-	if cols[0].Name != "id" || !cols[0].IsPK {
-		t.Errorf("expected id as PK, got %+v", cols[0])
+	if conflict.Current["email"] != "a@test.com" || conflict.Current["name"] != "Alice" {
+		t.Errorf("current = %v", conflict.Current)
 	}
-	// name should be non-nullable
-	if cols[1].Name != "name" || cols[1].Nullable {
-		t.Errorf("expected name as NOT NULL, got %+v", cols[1])
+	rows, _ := d.RunReadOnlyQuery(ctx, "SELECT name FROM users", nil)
+	if rows[0]["name"] != "Alice" {
+		t.Errorf("a conflicting update was applied: %v", rows)
 	}
-}
-
-func TestSQLite_InsertRow(t *testing.T) {
-	d := newTestSQLiteDriver(t)
-	defer d.Close()
-	ctx := context.Background()
 
-	id, err := d.InsertRow(ctx, "", "users", map[string]any{
-		"name":  "Alice",
-		"email": "alice@test.com",
-	})
-	if err != nil {
-		t.Fatalf("InsertRow: %v", err)
+	_, err = d.UpdateRow(WithExpected(ctx, map[string]any{"name": "Alice"}), "", "users", map[string]any{"id": int64(2)}, map[string]any{"name": "Bob"})
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing row: err = %v", err)
 	}
-	if id == nil {
-		t.Fatal("expected non-nil id")
-	}
-	if id.(int64) != 1 {
-		t.Errorf("expected id=1, got %v", id)
+	_, err = d.UpdateRow(WithExpected(ctx, map[string]any{"nope": 1}), "", "users", key, map[string]any{"name": "Bob"})
+	if !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("unknown expected column: err = %v", err)
 	}
 }
 
-func TestSQLite_UpdateRow(t *testing.T) {
+func TestSQLite_UpdateRow_noop(t *testing.T) {
 	d := newTestSQLiteDriver(t)
 	defer d.Close()
 	ctx := context.Background()
 
-	// Insert a row first.
+	// Insert a row.
 	_, err := d.InsertRow(ctx, "", "users", map[string]any{
 		"name":  "Alice",
 		"email": "alice@test.com",
@@ -104,118 +80,310 @@ func TestSQLite_UpdateRow(t *testing.T) {
 		t.Fatalf("InsertRow: %v", err)
 	}
 
-	// Update it.
+	// Update with the same values — SQLite reports matched rows (not
+	// changed rows), so this should succeed with 1 row affected.
 	n, err := d.UpdateRow(ctx, "", "users",
 		map[string]any{"id": int64(1)},
-		map[string]any{"email": "new@test.com"},
+		map[string]any{"email": "alice@test.com"},
 	)
 	if err != nil {
-		t.Fatalf("UpdateRow: %v", err)
+		t.Fatalf("UpdateRow (noop) should not error, got: %v", err)
 	}
 	if n != 1 {
-		t.Errorf("expected 1 row affected, got %d", n)
+		t.Errorf("expected 1 row affected for noop update, got %d", n)
+	}
+}
+
+func TestSQLite_UpdateSQL(t *testing.T) {
+	d := newTestSQLiteDriver(t)
+	defer d.Close()
+
+	query, params := d.UpdateSQL("", "users", map[string]any{"id": 1}, map[string]any{"name": "b", "email": "e"})
+	want := `UPDATE "users" SET "email" = ?1, "name" = ?2 WHERE "id" = ?3`
+	if query != want {
+		t.Errorf("UpdateSQL query = %q, want %q", query, want)
+	}
+	if len(params) != 3 || params[0] != "e" || params[1] != "b" || params[2] != 1 {
+		t.Errorf("UpdateSQL params = %v, want [e b 1]", params)
 	}
+}
 
-	// Verify the update via query.
-	rows, err := d.RunReadOnlyQuery(ctx, "SELECT email FROM users WHERE id = ?1", []any{int64(1)})
+func TestSQLite_Dependents(t *testing.T) {
+	d := newTestSQLiteDriver(t)
+	defer d.Close()
+	if _, err := d.db.Exec(`CREATE TABLE audit (user_id INTEGER, at TEXT);
+		CREATE TABLE usersettings (id INTEGER);
+		CREATE VIEW active_users AS SELECT id, name FROM "users" WHERE email IS NOT NULL;
+		CREATE VIEW settings AS SELECT * FROM usersettings;
+		CREATE TRIGGER users_audit AFTER INSERT ON users BEGIN INSERT INTO audit VALUES (new.id, 'now'); END;
+		CREATE TRIGGER audit_check AFTER INSERT ON audit BEGIN SELECT COUNT(*) FROM users; END`); err != nil {
+		t.Fatal(err)
+	}
+	deps, err := d.Dependents(context.Background(), "", "users")
 	if err != nil {
-		t.Fatalf("RunReadOnlyQuery: %v", err)
+		t.Fatal(err)
 	}
-	if len(rows) != 1 || rows[0]["email"] != "new@test.com" {
-		t.Errorf("expected email=new@test.com, got %v", rows)
+	want := []Dependent{
+		{Kind: DependentTrigger, Name: "audit_check", Inferred: true},
+		{Kind: DependentTrigger, Name: "users_audit"},
+		{Kind: DependentView, Name: "active_users", Inferred: true},
+	}
+	if !reflect.DeepEqual(deps, want) {
+		t.Errorf("Dependents = %+v, want %+v", deps, want)
 	}
 }
 
-func TestSQLite_UpdateRow_wrongKey(t *testing.T) {
+func TestSQLite_RunReadOnlyQuery_cancelled(t *testing.T) {
 	d := newTestSQLiteDriver(t)
 	defer d.Close()
-	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
 
-	// Try to update with a non-PK column as key.
-	_, err := d.UpdateRow(ctx, "", "users",
-		map[string]any{"name": "Alice"},
-		map[string]any{"email": "x"},
-	)
+	// Counts to a billion: runs far longer than the test unless the
+	// statement is interrupted when ctx ends.
+	start := time.Now()
+	_, err := d.RunReadOnlyQuery(ctx, `WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 1000000000)
+		SELECT count(*) FROM n`, nil)
 	if err == nil {
-		t.Fatal("expected error for non-PK key column")
+		t.Fatal("expected an error")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("query stopped after %v, want it interrupted", elapsed)
+	}
+
+	// The single in-memory connection is usable again.
+	if _, err := d.RunReadOnlyQuery(context.Background(), "SELECT 1", nil); err != nil {
+		t.Errorf("after cancel: %v", err)
 	}
 }
 
-func TestSQLite_UpdateRow_noop(t *testing.T) {
+func TestConvertPlaceholdersToSQLite(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"SELECT * FROM t WHERE a = $1", "SELECT * FROM t WHERE a = ?1"},
+		{"$1 AND $2", "?1 AND ?2"},
+		{"$10", "?10"},
+		{"no placeholders", "no placeholders"},
+	}
+	for _, tt := range tests {
+		got := convertPlaceholdersToSQLite(tt.in)
+		if got != tt.want {
+			t.Errorf("convertPlaceholdersToSQLite(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSQLite_ImportDatabase_rollsBackOnFailure(t *testing.T) {
 	d := newTestSQLiteDriver(t)
 	defer d.Close()
 	ctx := context.Background()
 
-	// Insert a row.
-	_, err := d.InsertRow(ctx, "", "users", map[string]any{
-		"name":  "Alice",
-		"email": "alice@test.com",
-	})
-	if err != nil {
-		t.Fatalf("InsertRow: %v", err)
+	path := filepath.Join(t.TempDir(), "dump.sql")
+	script := "BEGIN TRANSACTION;\n" +
+		"INSERT INTO users (name) VALUES ('Alice');\n" +
+		"INSERT INTO users (name) VALUES ('Bob');\n" +
+		"INSERT INTO missing_table VALUES (1);\n" +
+		"COMMIT;\n"
+	if err := os.WriteFile(path, []byte(script), 0o600); err != nil {
+		t.Fatal(err)
 	}
 
-	// Update with the same values — SQLite reports matched rows (not
-	// changed rows), so this should succeed with 1 row affected.
-	n, err := d.UpdateRow(ctx, "", "users",
-		map[string]any{"id": int64(1)},
-		map[string]any{"email": "alice@test.com"},
-	)
+	err := d.ImportDatabase(ctx, path, ImportOptions{AllowedDirs: []string{filepath.Dir(path)}})
+	if err == nil {
+		t.Fatal("expected import error")
+	}
+	if !strings.Contains(err.Error(), "statement 4 (line 4)") {
+		t.Errorf("error should locate the failing statement, got: %v", err)
+	}
+
+	rows, err := d.RunReadOnlyQuery(ctx, "SELECT COUNT(*) AS n FROM users", nil)
 	if err != nil {
-		t.Fatalf("UpdateRow (noop) should not error, got: %v", err)
+		t.Fatalf("RunReadOnlyQuery: %v", err)
 	}
-	if n != 1 {
-		t.Errorf("expected 1 row affected for noop update, got %d", n)
+	if n := rows[0]["n"]; n != int64(0) {
+		t.Errorf("expected rollback to leave 0 rows, got %v", n)
 	}
 }
 
-func TestSQLite_UpdateRow_notFound(t *testing.T) {
+func TestSQLite_ImportDatabase(t *testing.T) {
 	d := newTestSQLiteDriver(t)
 	defer d.Close()
 	ctx := context.Background()
 
-	_, err := d.UpdateRow(ctx, "", "users",
-		map[string]any{"id": int64(999)},
-		map[string]any{"email": "x"},
-	)
-	if err == nil {
-		t.Fatal("expected error for non-existent row")
+	path := filepath.Join(t.TempDir(), "dump.sql")
+	script := "PRAGMA foreign_keys=OFF;\nBEGIN TRANSACTION;\n" +
+		"INSERT INTO users (name, email) VALUES ('Alice', 'a;b@test.com');\n" +
+		"COMMIT;\n"
+	if err := os.WriteFile(path, []byte(script), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.ImportDatabase(ctx, path, ImportOptions{AllowedDirs: []string{filepath.Dir(path)}}); err != nil {
+		t.Fatalf("ImportDatabase: %v", err)
+	}
+	rows, err := d.RunReadOnlyQuery(ctx, "SELECT email FROM users", nil)
+	if err != nil {
+		t.Fatalf("RunReadOnlyQuery: %v", err)
+	}
+	if len(rows) != 1 || rows[0]["email"] != "a;b@test.com" {
+		t.Errorf("unexpected rows after import: %v", rows)
 	}
 }
 
-func TestSQLite_RunReadOnlyQuery(t *testing.T) {
+func TestSQLite_inMemorySingleConnection(t *testing.T) {
 	d := newTestSQLiteDriver(t)
 	defer d.Close()
+	// Each extra connection to ":memory:" would see its own empty database,
+	// so concurrent callers must be funnelled through one connection.
+	if n := d.db.Stats().MaxOpenConnections; n != 1 {
+		t.Errorf("MaxOpenConnections = %d, want 1 for an in-memory database", n)
+	}
+	for _, uri := range []string{":memory:", "file::memory:?cache=shared", "file:x.db?mode=memory"} {
+		if !isSQLiteMemory(uri) {
+			t.Errorf("isSQLiteMemory(%q) = false", uri)
+		}
+	}
+	if isSQLiteMemory("/tmp/app.sqlite") {
+		t.Error("a file path is not an in-memory database")
+	}
+}
+
+func TestSQLite_readOnly(t *testing.T) {
 	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "ro.sqlite")
+	rw, err := NewSQLiteDriver(ctx, path, ConnectOptions{})
+	if err != nil {
+		t.Fatalf("NewSQLiteDriver: %v", err)
+	}
+	if _, err := rw.db.Exec(`CREATE TABLE t (id INTEGER PRIMARY KEY, v TEXT)`); err != nil {
+		t.Fatalf("create table: %v", err)
+	}
+	rw.Close()
+
+	ro, err := NewSQLiteDriver(ctx, path, ConnectOptions{ReadOnly: true})
+	if err != nil {
+		t.Fatalf("NewSQLiteDriver read-only: %v", err)
+	}
+	defer ro.Close()
+	if _, err := ro.InsertRow(ctx, "", "t", map[string]any{"v": "x"}); err == nil {
+		t.Error("insert through a read-only session should fail")
+	}
+	if _, err := ro.RunReadOnlyQuery(ctx, "SELECT * FROM t", nil); err != nil {
+		t.Errorf("read through a read-only session: %v", err)
+	}
+}
 
-	_, _ = d.InsertRow(ctx, "", "users", map[string]any{"name": "Bob", "email": "bob@test.com"})
+func TestSQLite_BeginTx(t *testing.T) {
+	d := newTestSQLiteDriver(t)
+	defer d.Close()
+	count := func() any {
+		t.Helper()
+		rows, err := d.RunReadOnlyQuery(context.Background(), "SELECT COUNT(*) AS n FROM users", nil)
+		if err != nil {
+			t.Fatalf("count: %v", err)
+		}
+		return rows[0]["n"]
+	}
 
-	rows, err := d.RunReadOnlyQuery(ctx, "SELECT name, email FROM users WHERE name = ?1", []any{"Bob"})
+	// The transaction outlives the context it was begun with.
+	beginCtx, cancel := context.WithCancel(context.Background())
+	tx, err := d.BeginTx(beginCtx)
+	cancel()
 	if err != nil {
-		t.Fatalf("RunReadOnlyQuery: %v", err)
+		t.Fatalf("BeginTx: %v", err)
+	}
+	// The in-memory database's only connection belongs to the transaction,
+	// so a key lookup through the pool would wait until the deadline.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	id, err := tx.InsertRow(ctx, "", "users", map[string]any{"name": "alice"})
+	if err != nil {
+		t.Fatalf("InsertRow: %v", err)
+	}
+	if _, err := tx.UpdateRow(ctx, "", "users", map[string]any{"id": id}, map[string]any{"email": "a@example.com"}); err != nil {
+		t.Fatalf("UpdateRow: %v", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if n := count(); n != int64(1) {
+		t.Errorf("after commit: %v rows, want 1", n)
 	}
-	if len(rows) != 1 {
-		t.Fatalf("expected 1 row, got %d", len(rows))
+
+	tx, err = d.BeginTx(ctx)
+	if err != nil {
+		t.Fatalf("BeginTx: %v", err)
 	}
-	if rows[0]["name"] != "Bob" {
-		t.Errorf("expected name=Bob, got %v", rows[0]["name"])
+	if _, err := tx.InsertRow(ctx, "", "users", map[string]any{"name": "bob"}); err != nil {
+		t.Fatalf("InsertRow: %v", err)
+	}
+	if err := tx.Rollback(ctx); err != nil {
+		t.Fatalf("Rollback: %v", err)
+	}
+	if n := count(); n != int64(1) {
+		t.Errorf("after rollback: %v rows, want 1", n)
 	}
 }
 
-func TestConvertPlaceholdersToSQLite(t *testing.T) {
-	tests := []struct {
-		in   string
-		want string
-	}{
-		{"SELECT * FROM t WHERE a = $1", "SELECT * FROM t WHERE a = ?1"},
-		{"$1 AND $2", "?1 AND ?2"},
-		{"$10", "?10"},
-		{"no placeholders", "no placeholders"},
+func TestSQLite_scratchSchema(t *testing.T) {
+	ctx := context.Background()
+	d, err := NewSQLiteDriver(ctx, filepath.Join(t.TempDir(), "app.db"), ConnectOptions{})
+	if err != nil {
+		t.Fatalf("NewSQLiteDriver: %v", err)
 	}
-	for _, tt := range tests {
-		got := convertPlaceholdersToSQLite(tt.in)
-		if got != tt.want {
-			t.Errorf("convertPlaceholdersToSQLite(%q) = %q, want %q", tt.in, got, tt.want)
+	defer d.Close()
+	if err := d.CreateScratchSchema(ctx, "mcp_session_1"); err != nil {
+		t.Fatalf("CreateScratchSchema: %v", err)
+	}
+	if err := d.CreateScratchSchema(ctx, "mcp_session_1"); err != nil {
+		t.Fatalf("CreateScratchSchema again: %v", err)
+	}
+	cols := []ColumnDef{{Name: "id", Type: "integer", PrimaryKey: true}, {Name: "name", Type: "varchar(50)"}}
+	if err := d.CreateTable(ctx, "mcp_session_1", "fixtures", cols); err != nil {
+		t.Fatalf("CreateTable: %v", err)
+	}
+	id, err := d.InsertRow(ctx, "mcp_session_1", "fixtures", map[string]any{"name": "a"})
+	if err != nil || id != int64(1) {
+		t.Fatalf("InsertRow = %v, %v", id, err)
+	}
+	if _, err := d.UpdateRow(ctx, "mcp_session_1", "fixtures", map[string]any{"id": 1}, map[string]any{"name": "b"}); err != nil {
+		t.Fatalf("UpdateRow: %v", err)
+	}
+	if tables, _ := d.ListTables(ctx, ""); len(tables) != 0 {
+		t.Errorf("main database has tables %v", tables)
+	}
+	if tables, err := d.ListTables(ctx, "mcp_session_1"); err != nil || len(tables) != 1 {
+		t.Errorf("scratch tables = %v, %v", tables, err)
+	}
+
+	if err := d.DropScratchSchema(ctx, "mcp_session_1"); err != nil {
+		t.Fatalf("DropScratchSchema: %v", err)
+	}
+	if _, err := d.InsertRow(ctx, "mcp_session_1", "fixtures", map[string]any{"name": "c"}); err == nil {
+		t.Error("insert after drop: expected an error")
+	}
+	if err := d.DropScratchSchema(ctx, "mcp_session_1"); err != nil {
+		t.Errorf("DropScratchSchema again: %v", err)
+	}
+}
+
+func TestCreateTableSQL(t *testing.T) {
+	got, err := createTableSQL(`"s"."t"`, []ColumnDef{
+		{Name: "id", Type: "integer", PrimaryKey: true},
+		{Name: "price", Type: "numeric( 10, 2 )", NotNull: true},
+		{Name: "note", Type: "varchar(max)"},
+	}, quoteSQLiteIdentifier)
+	want := `CREATE TABLE "s"."t" ("id" integer NOT NULL, "price" numeric( 10, 2 ) NOT NULL, "note" varchar(max), PRIMARY KEY ("id"))`
+	if err != nil || got != want {
+		t.Errorf("createTableSQL =\n%s, %v\nwant\n%s", got, err, want)
+	}
+	for _, typ := range []string{"int); DROP TABLE users; --", "text default 'x'", "", "int -- x"} {
+		if _, err := createTableSQL(`"t"`, []ColumnDef{{Name: "c", Type: typ}}, quoteSQLiteIdentifier); err == nil {
+			t.Errorf("type %q: expected an error", typ)
 		}
 	}
+	if _, err := createTableSQL(`"t"`, []ColumnDef{{Name: "c", Type: "int"}, {Name: "C", Type: "int"}}, quoteSQLiteIdentifier); err == nil {
+		t.Error("duplicate column: expected an error")
+	}
 }
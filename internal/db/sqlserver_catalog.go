@@ -0,0 +1,244 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// mssqlColumn is a column definition read from sys.columns, with enough
+// detail to reproduce it in a CREATE TABLE statement.
+type mssqlColumn struct {
+	Name        string
+	TypeName    string
+	MaxLength   int16
+	Precision   uint8
+	Scale       uint8
+	Nullable    bool
+	DefaultName string
+	DefaultDef  string
+}
+
+// typeSQL renders the column type including length, precision, or scale
+// where the type takes one (e.g. nvarchar(50), decimal(10, 2)).
+func (c mssqlColumn) typeSQL() string {
+	switch c.TypeName {
+	case "varchar", "char", "varbinary", "binary":
+		if c.MaxLength == -1 {
+			return c.TypeName + "(max)"
+		}
+		return fmt.Sprintf("%s(%d)", c.TypeName, c.MaxLength)
+	case "nvarchar", "nchar":
+		if c.MaxLength == -1 {
+			return c.TypeName + "(max)"
+		}
+		// max_length is in bytes; n-types store two bytes per character.
+		return fmt.Sprintf("%s(%d)", c.TypeName, c.MaxLength/2)
+	case "decimal", "numeric":
+		return fmt.Sprintf("%s(%d, %d)", c.TypeName, c.Precision, c.Scale)
+	case "datetime2", "datetimeoffset", "time":
+		return fmt.Sprintf("%s(%d)", c.TypeName, c.Scale)
+	default:
+		return c.TypeName
+	}
+}
+
+// mssqlIndex is an index or key constraint read from sys.indexes.
+type mssqlIndex struct {
+	Name             string
+	Table            string
+	TypeDesc         string // CLUSTERED, NONCLUSTERED, ...
+	Unique           bool
+	PrimaryKey       bool
+	UniqueConstraint bool
+	Filter           string
+	Columns          []string // key columns, already quoted with ASC/DESC
+	Included         []string // INCLUDE columns, already quoted
+}
+
+// mssqlView is a view and its original CREATE VIEW definition.
+type mssqlView struct {
+	Name       string
+	Definition string
+}
+
+// exportColumns returns the column definitions of schema.table in ordinal order.
+func (d *SQLServerDriver) exportColumns(ctx context.Context, schema, table string) ([]mssqlColumn, error) {
+	query := `
+	SELECT c.name, t.name, c.max_length, c.precision, c.scale, c.is_nullable,
+	       ISNULL(dc.name, ''), ISNULL(dc.definition, '')
+	FROM sys.columns c
+	JOIN sys.types t ON t.user_type_id = c.user_type_id
+	LEFT JOIN sys.default_constraints dc ON dc.object_id = c.default_object_id
+	WHERE c.object_id = OBJECT_ID(@p1)
+	ORDER BY c.column_id`
+	rows, err := d.db.QueryContext(ctx, query, quoteMSSQLTable(schema, table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cols []mssqlColumn
+	for rows.Next() {
+		var c mssqlColumn
+		if err := rows.Scan(&c.Name, &c.TypeName, &c.MaxLength, &c.Precision, &c.Scale,
+			&c.Nullable, &c.DefaultName, &c.DefaultDef); err != nil {
+			return nil, err
+		}
+		cols = append(cols, c)
+	}
+	return cols, rows.Err()
+}
+
+// foreignKeys returns the foreign keys declared on tables in schema whose
+// referenced table lives in the same schema, one entry per constraint.
+func (d *SQLServerDriver) foreignKeys(ctx context.Context, schema string) ([]ForeignKey, error) {
+	query := `
+	SELECT fk.name, tp.name, cp.name, tr.name, cr.name,
+	       fk.delete_referential_action_desc, fk.update_referential_action_desc
+	FROM sys.foreign_keys fk
+	JOIN sys.foreign_key_columns fkc ON fkc.constraint_object_id = fk.object_id
+	JOIN sys.tables tp ON tp.object_id = fk.parent_object_id
+	JOIN sys.columns cp ON cp.object_id = fkc.parent_object_id AND cp.column_id = fkc.parent_column_id
+	JOIN sys.tables tr ON tr.object_id = fk.referenced_object_id
+	JOIN sys.columns cr ON cr.object_id = fkc.referenced_object_id AND cr.column_id = fkc.referenced_column_id
+	WHERE SCHEMA_NAME(tp.schema_id) = @p1 AND SCHEMA_NAME(tr.schema_id) = @p1
+	ORDER BY fk.name, fkc.constraint_column_id`
+	rows, err := d.db.QueryContext(ctx, query, schema)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var fks []ForeignKey
+	for rows.Next() {
+		var name, table, col, refTable, refCol, onDelete, onUpdate string
+		if err := rows.Scan(&name, &table, &col, &refTable, &refCol, &onDelete, &onUpdate); err != nil {
+			return nil, err
+		}
+		if n := len(fks); n > 0 && fks[n-1].Name == name {
+			fks[n-1].Columns = append(fks[n-1].Columns, col)
+			fks[n-1].RefColumns = append(fks[n-1].RefColumns, refCol)
+			continue
+		}
+		fks = append(fks, ForeignKey{
+			Name:       name,
+			Table:      table,
+			Columns:    []string{col},
+			RefTable:   refTable,
+			RefColumns: []string{refCol},
+			OnDelete:   strings.ReplaceAll(onDelete, "_", " "),
+			OnUpdate:   strings.ReplaceAll(onUpdate, "_", " "),
+		})
+	}
+	return fks, rows.Err()
+}
+
+// indexes returns all indexes (including primary keys and unique
+// constraints) on user tables in schema, grouped per index.
+func (d *SQLServerDriver) indexes(ctx context.Context, schema string) ([]mssqlIndex, error) {
+	query := `
+	SELECT i.name, t.name, i.type_desc, i.is_unique, i.is_primary_key, i.is_unique_constraint,
+	       ISNULL(i.filter_definition, ''), c.name, ic.is_descending_key, ic.is_included_column
+	FROM sys.indexes i
+	JOIN sys.tables t ON t.object_id = i.object_id
+	JOIN sys.index_columns ic ON ic.object_id = i.object_id AND ic.index_id = i.index_id
+	JOIN sys.columns c ON c.object_id = ic.object_id AND c.column_id = ic.column_id
+	WHERE SCHEMA_NAME(t.schema_id) = @p1 AND i.type > 0 AND i.is_hypothetical = 0
+	ORDER BY t.name, i.name, ic.is_included_column, ic.key_ordinal, ic.index_column_id`
+	rows, err := d.db.QueryContext(ctx, query, schema)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var idxs []mssqlIndex
+	for rows.Next() {
+		var ix mssqlIndex
+		var col string
+		var desc, included bool
+		if err := rows.Scan(&ix.Name, &ix.Table, &ix.TypeDesc, &ix.Unique, &ix.PrimaryKey,
+			&ix.UniqueConstraint, &ix.Filter, &col, &desc, &included); err != nil {
+			return nil, err
+		}
+		n := len(idxs)
+		if n == 0 || idxs[n-1].Name != ix.Name || idxs[n-1].Table != ix.Table {
+			idxs = append(idxs, ix)
+			n++
+		}
+		cur := &idxs[n-1]
+		switch {
+		case included:
+			cur.Included = append(cur.Included, quoteMSSQLIdentifier(col))
+		case desc:
+			cur.Columns = append(cur.Columns, quoteMSSQLIdentifier(col)+" DESC")
+		default:
+			cur.Columns = append(cur.Columns, quoteMSSQLIdentifier(col)+" ASC")
+		}
+	}
+	return idxs, rows.Err()
+}
+
+// views returns the views in schema ordered so that views referenced by
+// other views come first.
+func (d *SQLServerDriver) views(ctx context.Context, schema string) ([]mssqlView, error) {
+	rows, err := d.db.QueryContext(ctx, `
+	SELECT v.name, m.definition
+	FROM sys.views v
+	JOIN sys.sql_modules m ON m.object_id = v.object_id
+	WHERE SCHEMA_NAME(v.schema_id) = @p1
+	ORDER BY v.name`, schema)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byName := make(map[string]mssqlView)
+	var names []string
+	for rows.Next() {
+		var v mssqlView
+		if err := rows.Scan(&v.Name, &v.Definition); err != nil {
+			return nil, err
+		}
+		byName[v.Name] = v
+		names = append(names, v.Name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	depRows, err := d.db.QueryContext(ctx, `
+	SELECT OBJECT_NAME(dep.referencing_id), OBJECT_NAME(dep.referenced_id)
+	FROM sys.sql_expression_dependencies dep
+	JOIN sys.views v1 ON v1.object_id = dep.referencing_id
+	JOIN sys.views v2 ON v2.object_id = dep.referenced_id
+	WHERE SCHEMA_NAME(v1.schema_id) = @p1 AND SCHEMA_NAME(v2.schema_id) = @p1`, schema)
+	if err != nil {
+		return nil, err
+	}
+	defer depRows.Close()
+
+	// View-on-view dependencies have the same shape as FK dependencies,
+	// so the table sorter orders them too.
+	var deps []ForeignKey
+	for depRows.Next() {
+		var from, to string
+		if err := depRows.Scan(&from, &to); err != nil {
+			return nil, err
+		}
+		deps = append(deps, ForeignKey{Table: from, RefTable: to})
+	}
+	if err := depRows.Err(); err != nil {
+		return nil, err
+	}
+
+	ordered, _ := sortTablesByDependencies(names, deps)
+	out := make([]mssqlView, len(ordered))
+	for i, name := range ordered {
+		out[i] = byName[name]
+	}
+	return out, nil
+}
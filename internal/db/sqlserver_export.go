@@ -1,6 +1,7 @@
 package db
 
 import (
+	"bufio"
 	"context"
 	"fmt"
 	"net/url"
@@ -41,9 +42,13 @@ func parseSQLServerURI(uri string) (*sqlserverConnInfo, error) {
 }
 
 // ExportDatabase dumps the SQL Server database to a SQL file.
-// Uses pure Go: queries INFORMATION_SCHEMA to generate CREATE TABLE + INSERT statements.
-func (d *SQLServerDriver) ExportDatabase(ctx context.Context, path string) error {
-	absPath, err := validateExportPath(path)
+// Uses pure Go: reads the catalog to generate CREATE TABLE (with defaults and
+// primary keys), FOREIGN KEY constraints, INSERT statements, CREATE INDEX,
+// and CREATE VIEW statements, in that order. Rows are streamed table by
+// table as multi-row INSERTs of opts.BatchSize rows, so memory use does not
+// grow with table size.
+func (d *SQLServerDriver) ExportDatabase(ctx context.Context, path string, opts ExportOptions) (err error) {
+	absPath, err := validateExportPath(path, opts.AllowedDirs)
 	if err != nil {
 		return err
 	}
@@ -52,36 +57,120 @@ func (d *SQLServerDriver) ExportDatabase(ctx context.Context, path string) error
 	if err != nil {
 		return fmt.Errorf("export: list tables: %w", err)
 	}
+	fks, err := d.foreignKeys(ctx, "dbo")
+	if err != nil {
+		return fmt.Errorf("export: list foreign keys: %w", err)
+	}
+	idxs, err := d.indexes(ctx, "dbo")
+	if err != nil {
+		return fmt.Errorf("export: list indexes: %w", err)
+	}
+	views, err := d.views(ctx, "dbo")
+	if err != nil {
+		return fmt.Errorf("export: list views: %w", err)
+	}
+
+	// Tables are written parents-first so the data loads cleanly with the
+	// FK constraints already in place. Cycles can't be ordered, so
+	// constraint checking is switched off around the whole data load instead.
+	tables, cyclic := sortTablesByDependencies(tables, fks)
+
+	pks := make(map[string]mssqlIndex)
+	for _, ix := range idxs {
+		if ix.PrimaryKey {
+			pks[ix.Table] = ix
+		}
+	}
+
+	manifest, err := buildManifest(ctx, d, opts.ToolVersion)
+	if err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	manifestLine, err := manifest.manifestLine()
+	if err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
 
-	f, err := os.Create(absPath)
+	f, err := exportTempFile(absPath)
 	if err != nil {
-		return fmt.Errorf("export: create file: %w", err)
+		return err
 	}
+	defer os.Remove(f.Name())
 	defer f.Close()
+	w := bufio.NewWriter(f)
+	batchSize := opts.batchSize(mssqlMaxInsertRows)
 
-	fmt.Fprintf(f, "-- SQL Server database export\n\n")
+	if _, err := w.WriteString(manifestLine); err != nil {
+		return fmt.Errorf("export: write file: %w", err)
+	}
+	fmt.Fprintf(w, "-- SQL Server database export\n\n")
 
 	for _, table := range tables {
-		// Generate CREATE TABLE
-		createSQL, err := d.generateCreateTable(ctx, "dbo", table)
+		createSQL, err := d.generateCreateTable(ctx, "dbo", table, pks[table])
 		if err != nil {
 			return fmt.Errorf("export: generate DDL for %s: %w", table, err)
 		}
-		fmt.Fprintf(f, "%s\nGO\n\n", createSQL)
+		fmt.Fprintf(w, "%s\nGO\n\n", createSQL)
+	}
+
+	for _, fk := range fks {
+		fmt.Fprintf(w, "%s\nGO\n", generateForeignKey("dbo", fk))
+	}
+	if len(fks) > 0 {
+		fmt.Fprintf(w, "\n")
+	}
+
+	if cyclic {
+		fmt.Fprintf(w, "-- Foreign key cycle detected; constraints are disabled during the data load.\n")
+		for _, table := range tables {
+			fmt.Fprintf(w, "ALTER TABLE %s NOCHECK CONSTRAINT ALL;\n", quoteMSSQLTable("dbo", table))
+		}
+		fmt.Fprintf(w, "GO\n\n")
+	}
 
-		// Generate INSERT statements
-		if err := d.generateInserts(ctx, f, "dbo", table); err != nil {
+	for _, table := range tables {
+		if err := d.generateInserts(ctx, w, "dbo", table, batchSize); err != nil {
 			return fmt.Errorf("export: generate inserts for %s: %w", table, err)
 		}
-		fmt.Fprintf(f, "\n")
+		fmt.Fprintf(w, "\n")
+	}
+
+	if cyclic {
+		for _, table := range tables {
+			fmt.Fprintf(w, "ALTER TABLE %s WITH CHECK CHECK CONSTRAINT ALL;\n", quoteMSSQLTable("dbo", table))
+		}
+		fmt.Fprintf(w, "GO\n\n")
+	}
+
+	for _, ix := range idxs {
+		if ix.PrimaryKey {
+			continue
+		}
+		fmt.Fprintf(w, "%s\nGO\n", generateIndex("dbo", ix))
 	}
 
+	for _, v := range views {
+		// CREATE VIEW must be the only statement in its batch.
+		fmt.Fprintf(w, "\n%s\nGO\n", strings.TrimSpace(v.Definition))
+	}
+
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("export: write file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("export: close file: %w", err)
+	}
+	if err := os.Rename(f.Name(), absPath); err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
 	return nil
 }
 
-// generateCreateTable builds a CREATE TABLE statement from INFORMATION_SCHEMA.
-func (d *SQLServerDriver) generateCreateTable(ctx context.Context, schema, table string) (string, error) {
-	cols, err := d.DescribeTable(ctx, schema, table)
+// generateCreateTable builds a CREATE TABLE statement from the catalog,
+// including column types with length/precision, DEFAULT constraints, and
+// the primary key constraint (pk may be zero if the table has none).
+func (d *SQLServerDriver) generateCreateTable(ctx context.Context, schema, table string, pk mssqlIndex) (string, error) {
+	cols, err := d.exportColumns(ctx, schema, table)
 	if err != nil {
 		return "", err
 	}
@@ -90,10 +179,9 @@ func (d *SQLServerDriver) generateCreateTable(ctx context.Context, schema, table
 	}
 
 	var b strings.Builder
-	quotedTable := quoteMSSQLIdentifier(schema) + "." + quoteMSSQLIdentifier(table)
+	quotedTable := quoteMSSQLTable(schema, table)
 	fmt.Fprintf(&b, "CREATE TABLE %s (\n", quotedTable)
 
-	var pkCols []string
 	for i, c := range cols {
 		if i > 0 {
 			b.WriteString(",\n")
@@ -102,23 +190,78 @@ func (d *SQLServerDriver) generateCreateTable(ctx context.Context, schema, table
 		if c.Nullable {
 			nullable = "NULL"
 		}
-		fmt.Fprintf(&b, "    %s %s %s", quoteMSSQLIdentifier(c.Name), c.Type, nullable)
-		if c.IsPK {
-			pkCols = append(pkCols, quoteMSSQLIdentifier(c.Name))
+		fmt.Fprintf(&b, "    %s %s %s", quoteMSSQLIdentifier(c.Name), c.typeSQL(), nullable)
+		if c.DefaultDef != "" {
+			fmt.Fprintf(&b, " CONSTRAINT %s DEFAULT %s", quoteMSSQLIdentifier(c.DefaultName), c.DefaultDef)
 		}
 	}
 
-	if len(pkCols) > 0 {
-		fmt.Fprintf(&b, ",\n    PRIMARY KEY (%s)", strings.Join(pkCols, ", "))
+	if len(pk.Columns) > 0 {
+		fmt.Fprintf(&b, ",\n    CONSTRAINT %s PRIMARY KEY %s (%s)",
+			quoteMSSQLIdentifier(pk.Name), pk.TypeDesc, strings.Join(pk.Columns, ", "))
 	}
 
 	b.WriteString("\n);")
 	return b.String(), nil
 }
 
-// generateInserts writes INSERT statements for all rows in a table.
-func (d *SQLServerDriver) generateInserts(ctx context.Context, f *os.File, schema, table string) error {
-	quotedTable := quoteMSSQLIdentifier(schema) + "." + quoteMSSQLIdentifier(table)
+// generateForeignKey builds an ALTER TABLE ... ADD CONSTRAINT ... FOREIGN KEY statement.
+func generateForeignKey(schema string, fk ForeignKey) string {
+	cols := make([]string, len(fk.Columns))
+	for i, c := range fk.Columns {
+		cols[i] = quoteMSSQLIdentifier(c)
+	}
+	refCols := make([]string, len(fk.RefColumns))
+	for i, c := range fk.RefColumns {
+		refCols[i] = quoteMSSQLIdentifier(c)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "ALTER TABLE %s ADD CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s (%s)",
+		quoteMSSQLTable(schema, fk.Table), quoteMSSQLIdentifier(fk.Name),
+		strings.Join(cols, ", "), quoteMSSQLTable(schema, fk.RefTable), strings.Join(refCols, ", "))
+	if fk.OnDelete != "" && fk.OnDelete != "NO ACTION" {
+		fmt.Fprintf(&b, " ON DELETE %s", fk.OnDelete)
+	}
+	if fk.OnUpdate != "" && fk.OnUpdate != "NO ACTION" {
+		fmt.Fprintf(&b, " ON UPDATE %s", fk.OnUpdate)
+	}
+	b.WriteString(";")
+	return b.String()
+}
+
+// generateIndex builds a CREATE INDEX statement, or an ALTER TABLE ... ADD
+// CONSTRAINT ... UNIQUE statement for unique constraints.
+func generateIndex(schema string, ix mssqlIndex) string {
+	quotedTable := quoteMSSQLTable(schema, ix.Table)
+	if ix.UniqueConstraint {
+		return fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s UNIQUE %s (%s);",
+			quotedTable, quoteMSSQLIdentifier(ix.Name), ix.TypeDesc, strings.Join(ix.Columns, ", "))
+	}
+	var b strings.Builder
+	b.WriteString("CREATE ")
+	if ix.Unique {
+		b.WriteString("UNIQUE ")
+	}
+	fmt.Fprintf(&b, "%s INDEX %s ON %s (%s)",
+		ix.TypeDesc, quoteMSSQLIdentifier(ix.Name), quotedTable, strings.Join(ix.Columns, ", "))
+	if len(ix.Included) > 0 {
+		fmt.Fprintf(&b, " INCLUDE (%s)", strings.Join(ix.Included, ", "))
+	}
+	if ix.Filter != "" {
+		fmt.Fprintf(&b, " WHERE %s", ix.Filter)
+	}
+	b.WriteString(";")
+	return b.String()
+}
+
+// mssqlMaxInsertRows is SQL Server's limit on row value expressions in a
+// single INSERT ... VALUES statement.
+const mssqlMaxInsertRows = 1000
+
+// generateInserts streams all rows of a table to w as multi-row INSERT
+// statements of batchSize rows, each in its own GO batch.
+func (d *SQLServerDriver) generateInserts(ctx context.Context, w *bufio.Writer, schema, table string, batchSize int) error {
+	quotedTable := quoteMSSQLTable(schema, table)
 	query := fmt.Sprintf("SELECT * FROM %s", quotedTable)
 
 	rows, err := d.db.QueryContext(ctx, query)
@@ -139,31 +282,31 @@ func (d *SQLServerDriver) generateInserts(ctx context.Context, f *os.File, schem
 	for i, c := range colNames {
 		quotedCols[i] = quoteMSSQLIdentifier(c)
 	}
-	colList := strings.Join(quotedCols, ", ")
+	prefix := fmt.Sprintf("INSERT INTO %s (%s) VALUES", quotedTable, strings.Join(quotedCols, ", "))
+	batch := newInsertBatcher(w, prefix, ";\nGO\n", batchSize)
 
 	scan := make([]any, len(colNames))
 	for i := range scan {
 		scan[i] = new(any)
 	}
+	vals := make([]string, len(scan))
 
 	for rows.Next() {
 		if err := rows.Scan(scan...); err != nil {
 			return err
 		}
-		vals := make([]string, len(scan))
 		for i := range scan {
-			v := *(scan[i].(*any))
-			vals[i] = formatSQLValue(v)
+			vals[i] = formatSQLValue(*(scan[i].(*any)))
+		}
+		if err := batch.add(vals); err != nil {
+			return err
 		}
-		fmt.Fprintf(f, "INSERT INTO %s (%s) VALUES (%s);\n",
-			quotedTable, colList, strings.Join(vals, ", "))
 	}
 
 	if err := rows.Err(); err != nil {
 		return err
 	}
-	fmt.Fprintf(f, "GO\n")
-	return nil
+	return batch.flush()
 }
 
 // formatSQLValue formats a Go value as a SQL literal for INSERT statements.
@@ -192,15 +335,18 @@ func formatSQLValue(v any) string {
 }
 
 // ImportDatabase loads a SQL dump file into the SQL Server database using sqlcmd.
-func (d *SQLServerDriver) ImportDatabase(ctx context.Context, path string) error {
-	sqlcmd, err := findCLITool("sqlcmd")
+func (d *SQLServerDriver) ImportDatabase(ctx context.Context, path string, opts ImportOptions) error {
+	sqlcmd, err := FindCLITool("sqlcmd")
 	if err != nil {
 		return err
 	}
-	absPath, err := validateImportPath(path)
+	absPath, err := validateImportPath(path, opts.AllowedDirs)
 	if err != nil {
 		return err
 	}
+	if err := checkManifest(absPath, d.engine()); err != nil {
+		return err
+	}
 	info, err := parseSQLServerURI(d.uri)
 	if err != nil {
 		return fmt.Errorf("import: %w", err)
@@ -214,5 +360,19 @@ func (d *SQLServerDriver) ImportDatabase(ctx context.Context, path string) error
 	)
 }
 
+func (d *SQLServerDriver) engine() string { return "sqlserver" }
+
+func (d *SQLServerDriver) serverVersion(ctx context.Context) (string, error) {
+	var v string
+	err := d.db.QueryRowContext(ctx, "SELECT CAST(SERVERPROPERTY('ProductVersion') AS nvarchar(128))").Scan(&v)
+	return v, err
+}
+
+func (d *SQLServerDriver) countRows(ctx context.Context, table string) (int64, error) {
+	var n int64
+	err := d.db.QueryRowContext(ctx, "SELECT COUNT_BIG(*) FROM "+quoteMSSQLTable("dbo", table)).Scan(&n)
+	return n, err
+}
+
 // Ensure SQLServerDriver implements Exporter.
 var _ Exporter = (*SQLServerDriver)(nil)
@@ -19,3 +19,106 @@ func TestConvertPlaceholdersToMSSQL(t *testing.T) {
 		}
 	}
 }
+
+func TestMSSQLColumnTypeSQL(t *testing.T) {
+	tests := []struct {
+		col  mssqlColumn
+		want string
+	}{
+		{mssqlColumn{TypeName: "int"}, "int"},
+		{mssqlColumn{TypeName: "varchar", MaxLength: 50}, "varchar(50)"},
+		{mssqlColumn{TypeName: "varchar", MaxLength: -1}, "varchar(max)"},
+		{mssqlColumn{TypeName: "nvarchar", MaxLength: 100}, "nvarchar(50)"},
+		{mssqlColumn{TypeName: "nvarchar", MaxLength: -1}, "nvarchar(max)"},
+		{mssqlColumn{TypeName: "decimal", Precision: 10, Scale: 2}, "decimal(10, 2)"},
+		{mssqlColumn{TypeName: "datetime2", Scale: 7}, "datetime2(7)"},
+	}
+	for _, tt := range tests {
+		if got := tt.col.typeSQL(); got != tt.want {
+			t.Errorf("typeSQL(%+v) = %q, want %q", tt.col, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateForeignKey(t *testing.T) {
+	fk := ForeignKey{
+		Name:       "FK_orders_users",
+		Table:      "orders",
+		Columns:    []string{"user_id"},
+		RefTable:   "users",
+		RefColumns: []string{"id"},
+		OnDelete:   "CASCADE",
+		OnUpdate:   "NO ACTION",
+	}
+	want := "ALTER TABLE [dbo].[orders] ADD CONSTRAINT [FK_orders_users] FOREIGN KEY ([user_id]) " +
+		"REFERENCES [dbo].[users] ([id]) ON DELETE CASCADE;"
+	if got := generateForeignKey("dbo", fk); got != want {
+		t.Errorf("generateForeignKey:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestGenerateIndex(t *testing.T) {
+	tests := []struct {
+		name string
+		ix   mssqlIndex
+		want string
+	}{
+		{
+			name: "nonclustered with include and filter",
+			ix: mssqlIndex{
+				Name: "IX_orders_status", Table: "orders", TypeDesc: "NONCLUSTERED",
+				Columns:  []string{"[status] ASC", "[created_at] DESC"},
+				Included: []string{"[total]"},
+				Filter:   "([status]<>'void')",
+			},
+			want: "CREATE NONCLUSTERED INDEX [IX_orders_status] ON [dbo].[orders] ([status] ASC, [created_at] DESC) " +
+				"INCLUDE ([total]) WHERE ([status]<>'void');",
+		},
+		{
+			name: "unique index",
+			ix: mssqlIndex{
+				Name: "UX_users_email", Table: "users", TypeDesc: "NONCLUSTERED", Unique: true,
+				Columns: []string{"[email] ASC"},
+			},
+			want: "CREATE UNIQUE NONCLUSTERED INDEX [UX_users_email] ON [dbo].[users] ([email] ASC);",
+		},
+		{
+			name: "unique constraint",
+			ix: mssqlIndex{
+				Name: "UQ_users_login", Table: "users", TypeDesc: "NONCLUSTERED", Unique: true,
+				UniqueConstraint: true, Columns: []string{"[login] ASC"},
+			},
+			want: "ALTER TABLE [dbo].[users] ADD CONSTRAINT [UQ_users_login] UNIQUE NONCLUSTERED ([login] ASC);",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := generateIndex("dbo", tt.ix); got != tt.want {
+				t.Errorf("generateIndex:\n got %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSQLServerReadOnly(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"sqlserver://sa:pw@localhost?database=app", "sqlserver://sa:pw@localhost?ApplicationIntent=ReadOnly&database=app"},
+		{"server=localhost;user id=sa;", "server=localhost;user id=sa;ApplicationIntent=ReadOnly"},
+	}
+	for _, tt := range tests {
+		if got := sqlServerReadOnly(tt.in); got != tt.want {
+			t.Errorf("sqlServerReadOnly(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestBinaryColumn(t *testing.T) {
+	for typ, want := range map[string]bool{
+		"VARBINARY": true, "BINARY": true, "IMAGE": true, "BLOB": true, "MEDIUMBLOB": true, "bit": true,
+		"VARCHAR": false, "NVARCHAR": false, "TEXT": false, "DECIMAL": false, "JSON": false, "": false,
+	} {
+		if got := binaryColumn(typ); got != want {
+			t.Errorf("binaryColumn(%q) = %t, want %t", typ, got, want)
+		}
+	}
+}
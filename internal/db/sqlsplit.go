@@ -0,0 +1,243 @@
+package db
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// sqlStatement is one statement read from a SQL script.
+type sqlStatement struct {
+	Text string // statement text without the terminating semicolon
+	Line int    // 1-based line on which the statement starts
+	Num  int    // 1-based ordinal of the statement in the script
+}
+
+// statementScanner splits a SQL script into statements on top-level
+// semicolons. It understands '...' strings, "..." / `...` / [...] quoted
+// identifiers, -- and /* */ comments, and CREATE TRIGGER bodies, whose
+// inner semicolons only end the statement after the closing END (the same
+// rule the sqlite3 shell uses). It reads incrementally, so only the current
+// statement is held in memory.
+type statementScanner struct {
+	r    *bufio.Reader
+	line int
+	num  int
+	err  error
+}
+
+func newStatementScanner(r io.Reader) *statementScanner {
+	return &statementScanner{r: bufio.NewReader(r), line: 1}
+}
+
+// Next returns the next non-empty statement. ok is false at end of input or
+// on a read error, which is then available from Err.
+func (s *statementScanner) Next() (stmt sqlStatement, ok bool) {
+	var (
+		buf       strings.Builder
+		word      strings.Builder // current bare word, upper-cased
+		words     []string        // leading words, to detect CREATE TRIGGER
+		lastWord  string          // last complete bare word
+		startLine int
+		inTrigger bool
+		inBody    bool // inside a trigger's BEGIN ... END
+	)
+	endWord := func() {
+		if word.Len() == 0 {
+			return
+		}
+		w := word.String()
+		word.Reset()
+		lastWord = w
+		if len(words) < 4 {
+			words = append(words, w)
+			if isCreateTrigger(words) {
+				inTrigger = true
+			}
+		}
+		if inTrigger && w == "BEGIN" {
+			inBody = true
+		}
+	}
+
+	for {
+		c, _, err := s.r.ReadRune()
+		if err != nil {
+			if err != io.EOF {
+				s.err = err
+				return sqlStatement{}, false
+			}
+			endWord()
+			text := strings.TrimSpace(buf.String())
+			if text == "" {
+				return sqlStatement{}, false
+			}
+			s.num++
+			return sqlStatement{Text: text, Line: startLine, Num: s.num}, true
+		}
+
+		if startLine == 0 && !unicode.IsSpace(c) && !s.commentStart(c) {
+			startLine = s.line
+		}
+
+		switch {
+		case c == '\'' || c == '"' || c == '`' || c == '[':
+			endWord()
+			closing := c
+			if c == '[' {
+				closing = ']'
+			}
+			buf.WriteRune(c)
+			if !s.copyQuoted(&buf, closing) {
+				continue
+			}
+			lastWord = ""
+		case c == '-' && s.peek() == '-':
+			endWord()
+			s.skipLineComment()
+			buf.WriteByte('\n')
+		case c == '/' && s.peek() == '*':
+			endWord()
+			s.skipBlockComment()
+			buf.WriteByte(' ')
+		case c == ';':
+			endWord()
+			if inBody && lastWord != "END" {
+				buf.WriteRune(c)
+				lastWord = ""
+				continue
+			}
+			text := strings.TrimSpace(buf.String())
+			if text == "" {
+				startLine = 0
+				continue
+			}
+			s.num++
+			return sqlStatement{Text: text, Line: startLine, Num: s.num}, true
+		default:
+			if c == '\n' {
+				s.line++
+			}
+			if unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_' {
+				word.WriteRune(unicode.ToUpper(c))
+			} else {
+				endWord()
+				if !unicode.IsSpace(c) {
+					lastWord = ""
+				}
+			}
+			buf.WriteRune(c)
+		}
+	}
+}
+
+// Err returns the first read error encountered, if any.
+func (s *statementScanner) Err() error { return s.err }
+
+// commentStart reports whether c, together with the next rune, opens a comment.
+func (s *statementScanner) commentStart(c rune) bool {
+	return (c == '-' && s.peek() == '-') || (c == '/' && s.peek() == '*')
+}
+
+func (s *statementScanner) peek() rune {
+	b, err := s.r.Peek(1)
+	if err != nil || len(b) == 0 {
+		return 0
+	}
+	return rune(b[0])
+}
+
+// copyQuoted copies a quoted string or identifier up to and including the
+// closing quote; a doubled closing quote is an escaped quote. It returns
+// false if input ended first.
+func (s *statementScanner) copyQuoted(buf *strings.Builder, closing rune) bool {
+	for {
+		c, _, err := s.r.ReadRune()
+		if err != nil {
+			if err != io.EOF {
+				s.err = err
+			}
+			return false
+		}
+		if c == '\n' {
+			s.line++
+		}
+		buf.WriteRune(c)
+		if c == closing {
+			if s.peek() == closing && closing != ']' {
+				next, _, _ := s.r.ReadRune()
+				buf.WriteRune(next)
+				continue
+			}
+			return true
+		}
+	}
+}
+
+func (s *statementScanner) skipLineComment() {
+	for {
+		c, _, err := s.r.ReadRune()
+		if err != nil {
+			return
+		}
+		if c == '\n' {
+			s.line++
+			return
+		}
+	}
+}
+
+func (s *statementScanner) skipBlockComment() {
+	_, _, _ = s.r.ReadRune() // the '*' after '/'
+	prev := rune(0)
+	for {
+		c, _, err := s.r.ReadRune()
+		if err != nil {
+			return
+		}
+		if c == '\n' {
+			s.line++
+		}
+		if prev == '*' && c == '/' {
+			return
+		}
+		prev = c
+	}
+}
+
+// isCreateTrigger reports whether the leading words of a statement are
+// CREATE [TEMP|TEMPORARY] TRIGGER.
+func isCreateTrigger(words []string) bool {
+	if len(words) < 2 || words[0] != "CREATE" {
+		return false
+	}
+	if words[1] == "TRIGGER" {
+		return true
+	}
+	return len(words) >= 3 && (words[1] == "TEMP" || words[1] == "TEMPORARY") && words[2] == "TRIGGER"
+}
+
+// isTransactionControl reports whether stmt is a BEGIN/COMMIT/END
+// statement. Dumps produced by sqlite3 .dump wrap their content in
+// BEGIN TRANSACTION ... COMMIT, which must be skipped when the importer
+// supplies its own transaction.
+func isTransactionControl(stmt string) bool {
+	fields := strings.Fields(strings.ToUpper(stmt))
+	if len(fields) == 0 {
+		return false
+	}
+	switch fields[0] {
+	case "BEGIN":
+		if len(fields) == 1 {
+			return true
+		}
+		switch fields[1] {
+		case "TRANSACTION", "DEFERRED", "IMMEDIATE", "EXCLUSIVE":
+			return true
+		}
+	case "COMMIT", "END":
+		return len(fields) == 1 || fields[1] == "TRANSACTION"
+	}
+	return false
+}
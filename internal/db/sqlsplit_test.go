@@ -0,0 +1,103 @@
+package db
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStatementScanner(t *testing.T) {
+	script := `-- header comment
+PRAGMA foreign_keys=OFF;
+BEGIN TRANSACTION;
+CREATE TABLE t (id INTEGER PRIMARY KEY, note TEXT);
+INSERT INTO t VALUES(1, 'semi;colon');
+INSERT INTO t VALUES(2, 'it''s
+multi-line');
+/* block; comment */ INSERT INTO "odd;name" VALUES(3);
+CREATE TRIGGER trg AFTER INSERT ON t BEGIN
+  UPDATE t SET note = 'x' WHERE id = NEW.id;
+  DELETE FROM t WHERE id < 0;
+END;
+COMMIT;
+SELECT 1`
+
+	type want struct {
+		prefix string
+		line   int
+	}
+	wants := []want{
+		{"PRAGMA foreign_keys=OFF", 2},
+		{"BEGIN TRANSACTION", 3},
+		{"CREATE TABLE t", 4},
+		{"INSERT INTO t VALUES(1, 'semi;colon')", 5},
+		{"INSERT INTO t VALUES(2, 'it''s\nmulti-line')", 6},
+		{`INSERT INTO "odd;name" VALUES(3)`, 8},
+		{"CREATE TRIGGER trg", 9},
+		{"COMMIT", 13},
+		{"SELECT 1", 14},
+	}
+
+	sc := newStatementScanner(strings.NewReader(script))
+	var got []sqlStatement
+	for {
+		stmt, ok := sc.Next()
+		if !ok {
+			break
+		}
+		got = append(got, stmt)
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatalf("Err: %v", err)
+	}
+	if len(got) != len(wants) {
+		for _, g := range got {
+			t.Logf("stmt %d line %d: %q", g.Num, g.Line, g.Text)
+		}
+		t.Fatalf("got %d statements, want %d", len(got), len(wants))
+	}
+	for i, w := range wants {
+		if !strings.HasPrefix(got[i].Text, w.prefix) {
+			t.Errorf("stmt %d: got %q, want prefix %q", i+1, got[i].Text, w.prefix)
+		}
+		if got[i].Line != w.line {
+			t.Errorf("stmt %d: line %d, want %d", i+1, got[i].Line, w.line)
+		}
+		if got[i].Num != i+1 {
+			t.Errorf("stmt %d: num %d", i+1, got[i].Num)
+		}
+	}
+	if trigger := got[6].Text; !strings.HasSuffix(trigger, "END") || !strings.Contains(trigger, "DELETE FROM t") {
+		t.Errorf("trigger body was split: %q", trigger)
+	}
+}
+
+func TestIsTransactionControl(t *testing.T) {
+	tests := []struct {
+		stmt string
+		want bool
+	}{
+		{"BEGIN", true},
+		{"BEGIN TRANSACTION", true},
+		{"begin immediate", true},
+		{"COMMIT", true},
+		{"END TRANSACTION", true},
+		{"CREATE TRIGGER x AFTER INSERT ON t BEGIN SELECT 1; END", false},
+		{"INSERT INTO t VALUES (1)", false},
+	}
+	for _, tt := range tests {
+		if got := isTransactionControl(tt.stmt); got != tt.want {
+			t.Errorf("isTransactionControl(%q) = %v, want %v", tt.stmt, got, tt.want)
+		}
+	}
+}
+
+func TestParsePsqlError(t *testing.T) {
+	out := "psql:/tmp/my:dump.sql:42: ERROR:  relation \"users\" does not exist\n"
+	line, msg, ok := parsePsqlError(out)
+	if !ok || line != 42 || msg != `relation "users" does not exist` {
+		t.Errorf("parsePsqlError = (%d, %q, %v)", line, msg, ok)
+	}
+	if _, _, ok := parsePsqlError("could not connect to server"); ok {
+		t.Error("expected no match for non-script error")
+	}
+}
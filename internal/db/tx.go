@@ -0,0 +1,100 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Transactor is an optional interface for drivers that can run InsertRow
+// and UpdateRow inside an explicit transaction (see
+// Capabilities.Transactions).
+type Transactor interface {
+	// BeginTx starts a transaction. It outlives ctx: the caller must end it
+	// with Commit or Rollback.
+	BeginTx(ctx context.Context) (Tx, error)
+}
+
+// Tx is an open transaction. Its InsertRow and UpdateRow behave like the
+// Driver methods of the same name, but nothing they write is visible to
+// other connections until Commit.
+type Tx interface {
+	InsertRow(ctx context.Context, schema, table string, row map[string]any) (insertedID any, err error)
+	UpdateRow(ctx context.Context, schema, table string, key map[string]any, set map[string]any) (rowsAffected int64, err error)
+	Commit(ctx context.Context) error
+	Rollback(ctx context.Context) error
+}
+
+// tableDescriber is the part of Driver checkColumns needs.
+type tableDescriber interface {
+	DescribeTable(ctx context.Context, schema, table string) ([]ColumnInfo, error)
+}
+
+// sqlConn is what the database/sql drivers write through: the *sql.DB pool
+// or a *sql.Tx.
+type sqlConn interface {
+	sqlQueryer
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// sqlWriter is implemented by the database/sql drivers, whose InsertRow and
+// UpdateRow run on q. desc validates the columns of a write.
+type sqlWriter interface {
+	insertRow(ctx context.Context, q sqlConn, desc tableDescriber, schema, table string, row map[string]any) (any, error)
+	updateRow(ctx context.Context, q sqlConn, desc tableDescriber, schema, table string, key, set map[string]any) (int64, error)
+}
+
+// sqlTx implements Tx for the database/sql drivers.
+type sqlTx struct {
+	tx   *sql.Tx
+	w    sqlWriter
+	desc tableDescriber
+}
+
+// beginSQLTx starts a transaction on db whose writes go through w. Their
+// columns are validated against desc.
+func beginSQLTx(ctx context.Context, db *sql.DB, w sqlWriter, desc tableDescriber) (*sqlTx, error) {
+	// database/sql rolls a transaction back when its context ends, and the
+	// transaction has to outlive the call that started it.
+	tx, err := db.BeginTx(context.WithoutCancel(ctx), nil)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlTx{tx: tx, w: w, desc: desc}, nil
+}
+
+func (t *sqlTx) InsertRow(ctx context.Context, schema, table string, row map[string]any) (any, error) {
+	return t.w.insertRow(ctx, t.tx, t.desc, schema, table, row)
+}
+
+func (t *sqlTx) UpdateRow(ctx context.Context, schema, table string, key, set map[string]any) (int64, error) {
+	return t.w.updateRow(ctx, t.tx, t.desc, schema, table, key, set)
+}
+
+func (t *sqlTx) Commit(context.Context) error   { return t.tx.Commit() }
+func (t *sqlTx) Rollback(context.Context) error { return t.tx.Rollback() }
+
+// pgConn is what PostgresDriver writes through: the pool or a pgx.Tx.
+type pgConn interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// pgTx implements Tx for PostgresDriver.
+type pgTx struct {
+	tx pgx.Tx
+	d  *PostgresDriver
+}
+
+func (t *pgTx) InsertRow(ctx context.Context, schema, table string, row map[string]any) (any, error) {
+	return t.d.insertRow(ctx, t.tx, schema, table, row)
+}
+
+func (t *pgTx) UpdateRow(ctx context.Context, schema, table string, key, set map[string]any) (int64, error) {
+	return t.d.updateRow(ctx, t.tx, schema, table, key, set)
+}
+
+func (t *pgTx) Commit(ctx context.Context) error   { return t.tx.Commit(ctx) }
+func (t *pgTx) Rollback(ctx context.Context) error { return t.tx.Rollback(ctx) }
@@ -2,7 +2,11 @@ package db
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 )
 
 // mockDriver implements Driver with just enough to test validatePKColumns.
@@ -25,6 +29,7 @@ func (m *mockDriver) InsertRow(context.Context, string, string, map[string]any)
 func (m *mockDriver) UpdateRow(context.Context, string, string, map[string]any, map[string]any) (int64, error) {
 	return 0, nil
 }
+func (m *mockDriver) Capabilities() Capabilities { return Capabilities{} }
 func (m *mockDriver) Close() error { return nil }
 
 func TestValidatePKColumns(t *testing.T) {
@@ -101,10 +106,61 @@ func TestValidatePKColumns(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			d := &mockDriver{columns: tt.columns}
-			err := validatePKColumns(ctx, d, "public", "test_table", tt.key)
+			err := validatePKColumns(ctx, d, "public", "test_table", tt.key, nil)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("validatePKColumns() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
 }
+
+func TestCheckColumns(t *testing.T) {
+	ctx := context.Background()
+	d := &mockDriver{columns: []ColumnInfo{{Name: "id", IsPK: true}, {Name: "Email"}, {Name: "name"}}}
+	tests := []struct {
+		name    string
+		fields  []map[string]any
+		wantErr string
+	}{
+		{"known columns", []map[string]any{{"id": 1}, {"name": "x", "Email": "y"}}, ""},
+		{"unknown column", []map[string]any{{"id": 1}, {"nmae": "x"}}, `insert row: unknown column "nmae" in table "app.users" (columns: id, Email, name)`},
+		{"case differs", []map[string]any{{"email": "y"}}, `insert row: unknown column "email" in table "app.users"; did you mean "Email"?`},
+		{"odd identifier", []map[string]any{{`name" = 1; --`: "x"}}, `insert row: unknown column "name\" = 1; --" in table "app.users" (columns: id, Email, name)`},
+	}
+	for _, tt := range tests {
+		_, err := checkColumns(ctx, d, "insert row", "app", "users", tt.fields...)
+		if got := fmt.Sprint(err); (tt.wantErr == "" && err != nil) || (tt.wantErr != "" && got != tt.wantErr) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("%s: err should match ErrInvalidInput", tt.name)
+		}
+	}
+
+	d.columns = nil
+	if _, err := checkColumns(ctx, d, "insert row", "", "userz", map[string]any{"id": 1}); fmt.Sprint(err) != `insert row: unknown table "userz"` {
+		t.Errorf("unknown table: err = %v", err)
+	}
+}
+
+func TestSQLite_InsertRow_refreshesStaleColumns(t *testing.T) {
+	ctx := context.Background()
+	d, err := NewSQLiteDriver(ctx, ":memory:", ConnectOptions{SchemaCacheTTL: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	if _, err := d.db.ExecContext(ctx, `CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT)`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.InsertRow(ctx, "", "items", map[string]any{"nme": "a"}); err == nil || !strings.Contains(err.Error(), `unknown column "nme"`) {
+		t.Fatalf("insert with a typo: err = %v", err)
+	}
+	// The column list is cached now; a column added later is still found.
+	if _, err := d.db.ExecContext(ctx, `ALTER TABLE items ADD COLUMN note TEXT`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.InsertRow(ctx, "", "items", map[string]any{"name": "a", "note": "b"}); err != nil {
+		t.Errorf("insert into a column added after caching: %v", err)
+	}
+}
@@ -0,0 +1,125 @@
+//go:build testcontainers
+
+package integration
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/mssql"
+	"github.com/testcontainers/testcontainers-go/modules/mysql"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"gopkg.in/yaml.v3"
+)
+
+// TestMain starts a container per selected engine, seeded like those of
+// compose.yaml, points the engine's URI at it and removes it after the
+// tests. Only Docker is needed; nothing has to be started beforehand.
+func TestMain(m *testing.M) {
+	os.Exit(runInContainers(m))
+}
+
+func runInContainers(m *testing.M) int {
+	ctx := context.Background()
+	es, err := Selected(os.Getenv)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	images, err := composeImages()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "compose.yaml: %v\n", err)
+		return 2
+	}
+	for _, e := range es {
+		uri, c, err := startContainer(ctx, e.Type, images[e.Type])
+		if c != nil {
+			defer func() {
+				if err := testcontainers.TerminateContainer(c); err != nil {
+					fmt.Fprintf(os.Stderr, "remove %s container: %v\n", e.Type, err)
+				}
+			}()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "start %s container: %v\n", e.Type, err)
+			return 1
+		}
+		os.Setenv(e.Env, uri)
+	}
+	return m.Run()
+}
+
+// composeImages returns the image of each service of compose.yaml, so the
+// containers run the same server versions.
+func composeImages() (map[string]string, error) {
+	b, err := os.ReadFile("compose.yaml")
+	if err != nil {
+		return nil, err
+	}
+	var compose struct {
+		Services map[string]struct {
+			Image string `yaml:"image"`
+		} `yaml:"services"`
+	}
+	if err := yaml.Unmarshal(b, &compose); err != nil {
+		return nil, err
+	}
+	images := make(map[string]string, len(compose.Services))
+	for name, s := range compose.Services {
+		images[name] = s.Image
+	}
+	return images, nil
+}
+
+// startContainer starts a seeded server of connection type typ and returns
+// the URI of its localdb database. c is set if a container was created,
+// even when starting it failed.
+func startContainer(ctx context.Context, typ, image string) (uri string, c testcontainers.Container, err error) {
+	seed := filepath.Join("..", "db", "dbtest", "fixtures", typ+".sql")
+	switch typ {
+	case "postgres":
+		pg, err := postgres.Run(ctx, image,
+			postgres.WithDatabase("localdb"), postgres.WithUsername("localdb"), postgres.WithPassword("localdb-it"),
+			postgres.WithInitScripts(seed), postgres.BasicWaitStrategies())
+		if pg == nil {
+			return "", nil, err
+		}
+		if err != nil {
+			return "", pg, err
+		}
+		uri, err = pg.ConnectionString(ctx, "sslmode=disable")
+		return uri, pg, err
+	case "mysql":
+		my, err := mysql.Run(ctx, image,
+			mysql.WithDatabase("localdb"), mysql.WithUsername("root"), mysql.WithPassword("localdb-it"),
+			mysql.WithScripts(seed))
+		if my == nil {
+			return "", nil, err
+		}
+		if err != nil {
+			return "", my, err
+		}
+		uri, err = my.ConnectionString(ctx)
+		return uri, my, err
+	case "sqlserver":
+		f, err := os.Open(seed)
+		if err != nil {
+			return "", nil, err
+		}
+		defer f.Close()
+		ms, err := mssql.Run(ctx, image, mssql.WithAcceptEULA(), mssql.WithPassword("LocalDB-it-2024"), mssql.WithInitSQL(f))
+		if ms == nil {
+			return "", nil, err
+		}
+		if err != nil {
+			return "", ms, err
+		}
+		uri, err = ms.ConnectionString(ctx, "database=localdb")
+		return uri, ms, err
+	}
+	return "", nil, fmt.Errorf("no container for %s", typ)
+}
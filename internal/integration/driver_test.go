@@ -0,0 +1,24 @@
+//go:build integration || testcontainers
+
+package integration
+
+import (
+	"testing"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
+	"github.com/SedlarDavid/localdb-mcp/internal/db/dbtest"
+)
+
+// TestDriver runs the driver conformance suite against each engine.
+func TestDriver(t *testing.T) {
+	for _, e := range engines(t) {
+		t.Run(e.Type, func(t *testing.T) {
+			dbtest.Run(t, dbtest.Backend{
+				Type:   e.Type,
+				Open:   func(t *testing.T) db.Driver { return driver(t, e) },
+				Schema: e.Schema,
+				Other:  e.Other,
+			})
+		})
+	}
+}
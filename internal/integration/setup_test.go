@@ -0,0 +1,73 @@
+//go:build integration || testcontainers
+
+package integration
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
+)
+
+// engines returns the engines to test.
+func engines(t *testing.T) []Engine {
+	t.Helper()
+	es, err := Selected(os.Getenv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(es) == 0 {
+		t.Skipf("no engines selected by %s", EnvEngines)
+	}
+	return es
+}
+
+// loadConfig writes a config with a connection per engine, named by its
+// type, and extra YAML, and loads it. Connections from the environment are
+// left out, so the tests never touch the developer's own databases.
+func loadConfig(t *testing.T, es []Engine, extra string) *config.Config {
+	t.Helper()
+	for _, env := range []string{config.EnvPostgresURI, config.EnvSQLServerURI, config.EnvSQLiteURI, config.EnvMySQLURI, config.EnvAuditDB, config.EnvRecordFile} {
+		t.Setenv(env, "")
+	}
+	var b strings.Builder
+	b.WriteString("connections:\n")
+	for _, e := range es {
+		fmt.Fprintf(&b, "  %s: %q\n", e.Type, e.URI)
+	}
+	b.WriteString(extra)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(b.String()), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.LoadFrom(path)
+	if err != nil {
+		t.Fatalf("config: %v", err)
+	}
+	return cfg
+}
+
+// driver opens a driver of e through a Manager, as the tools do.
+func driver(t *testing.T, e Engine) db.Driver {
+	t.Helper()
+	mgr := db.NewManager(loadConfig(t, []Engine{e}, ""))
+	t.Cleanup(func() { mgr.Close() })
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	d, err := mgr.Driver(ctx, e.Type)
+	if err != nil {
+		t.Fatalf("connect to %s: %v (is compose.yaml up? see the package doc)", e.Type, err)
+	}
+	return d
+}
+
+// nonce returns a value no earlier run has written.
+func nonce(t *testing.T) string {
+	return fmt.Sprintf("%s %d", t.Name(), time.Now().UnixNano())
+}
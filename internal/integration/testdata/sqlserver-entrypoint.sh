@@ -0,0 +1,17 @@
+#!/bin/bash
+# Starts SQL Server, loads the sqlserver.sql fixture into it once it accepts logins and
+# keeps running in the foreground with the server.
+set -e
+/opt/mssql/bin/sqlservr &
+server=$!
+sqlcmd() { /opt/mssql-tools18/bin/sqlcmd -C -S 127.0.0.1 -U sa -P "$MSSQL_SA_PASSWORD" -b "$@"; }
+for _ in $(seq 90); do
+	if sqlcmd -Q "SELECT 1" >/dev/null 2>&1; then
+		break
+	fi
+	sleep 1
+done
+if ! sqlcmd -Q "IF DB_ID('localdb') IS NULL THROW 50000, 'not seeded', 1" >/dev/null 2>&1; then
+	sqlcmd -i /seed/sqlserver.sql
+fi
+wait "$server"
@@ -0,0 +1,383 @@
+//go:build integration || testcontainers
+
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
+	"github.com/SedlarDavid/localdb-mcp/internal/db/dbtest"
+	internal_server "github.com/SedlarDavid/localdb-mcp/internal/server"
+)
+
+// caller calls tools on an in-process server and notes which it called,
+// so TestTools can check that every tool the server offers was exercised.
+type caller struct {
+	c      *client.Client
+	called map[string]bool
+}
+
+func (k *caller) do(t *testing.T, tool string, args map[string]any) *mcp.CallToolResult {
+	t.Helper()
+	k.called[tool] = true
+	res, err := k.c.CallTool(context.Background(), mcp.CallToolRequest{Params: mcp.CallToolParams{Name: tool, Arguments: args}})
+	if err != nil {
+		t.Fatalf("%s: %v", tool, err)
+	}
+	return res
+}
+
+// call calls tool and decodes its structured result into out; the call
+// must succeed.
+func (k *caller) call(t *testing.T, tool string, args map[string]any, out any) {
+	t.Helper()
+	res := k.do(t, tool, args)
+	if res.IsError {
+		t.Fatalf("%s %v: %s", tool, args, text(res))
+	}
+	b, err := json.Marshal(res.StructuredContent)
+	if err == nil {
+		err = json.Unmarshal(b, out)
+	}
+	if err != nil {
+		t.Fatalf("%s: decode %s: %v", tool, b, err)
+	}
+}
+
+// fail calls tool and returns the code of its error; the call must fail.
+func (k *caller) fail(t *testing.T, tool string, args map[string]any) string {
+	t.Helper()
+	res := k.do(t, tool, args)
+	if !res.IsError {
+		t.Fatalf("%s %v succeeded: %s", tool, args, text(res))
+	}
+	var e internal_server.ToolError
+	b, _ := json.Marshal(res.StructuredContent)
+	_ = json.Unmarshal(b, &e)
+	return e.Code
+}
+
+// count returns the number of notes with body.
+func (k *caller) count(t *testing.T, connID, body string) string {
+	t.Helper()
+	var out internal_server.RunQueryOutput
+	k.call(t, "run_query", map[string]any{"connection_id": connID, "sql": "SELECT COUNT(*) AS n FROM notes WHERE body = $1", "params": []any{body}}, &out)
+	if len(out.Rows) != 1 {
+		t.Fatalf("count of notes: %v", out.Rows)
+	}
+	return fmt.Sprint(out.Rows[0]["n"])
+}
+
+func text(res *mcp.CallToolResult) string {
+	for _, content := range res.Content {
+		if tc, ok := mcp.AsTextContent(content); ok {
+			return tc.Text
+		}
+	}
+	return ""
+}
+
+func TestTools(t *testing.T) {
+	es := engines(t)
+	dir := t.TempDir()
+	var ids []string
+	for _, e := range es {
+		ids = append(ids, e.Type)
+	}
+	cfg := loadConfig(t, es, fmt.Sprintf(`rate_limits: {read: {rate: 0}, write: {rate: 0}, export: {rate: 0}}
+session_schemas: [%s]
+export_dirs: [%q]
+audit_db: %q
+`, strings.Join(ids, ", "), dir, filepath.Join(dir, "audit.db")))
+	s := server.NewMCPServer(internal_server.ServerName, internal_server.ServerVersion)
+	mgr := internal_server.Register(s, cfg)
+	defer mgr.Close()
+	c, err := client.NewInProcessClient(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	ctx := context.Background()
+	initReq := mcp.InitializeRequest{}
+	initReq.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	if _, err := c.Initialize(ctx, initReq); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	k := &caller{c: c, called: make(map[string]bool)}
+
+	for _, e := range es {
+		t.Run(e.Type, func(t *testing.T) { testTools(t, k, e, dir) })
+	}
+
+	var ping internal_server.PingOutput
+	k.call(t, "ping", nil, &ping)
+	var conns internal_server.ListConnectionsOutput
+	k.call(t, "list_connections", nil, &conns)
+	var health internal_server.HealthOutput
+	k.call(t, "health", map[string]any{"connect": true}, &health)
+	var stats internal_server.ConnectionStatsOutput
+	k.call(t, "connection_stats", nil, &stats)
+	for _, e := range es {
+		if !slices.ContainsFunc(conns.Connections, func(ci config.ConnectionInfo) bool { return ci.ID == e.Type && ci.Type == e.Type }) {
+			t.Errorf("list_connections lacks %s: %+v", e.Type, conns.Connections)
+		}
+		if !slices.ContainsFunc(health.Connections, func(h db.ConnectionHealth) bool { return h.ID == e.Type && h.OK }) {
+			t.Errorf("health of %s not ok: %+v", e.Type, health.Connections)
+		}
+		if !slices.ContainsFunc(stats.Connections, func(cs internal_server.ConnectionStats) bool { return cs.ID == e.Type && cs.Queries > 0 }) {
+			t.Errorf("connection_stats counted no calls on %s: %+v", e.Type, stats.Connections)
+		}
+		var audit internal_server.AuditLogOutput
+		k.call(t, "query_audit_log", map[string]any{"connection": e.Type, "tool": "insert_test_row"}, &audit)
+		if len(audit.Entries) == 0 {
+			t.Errorf("query_audit_log has no insert_test_row calls on %s", e.Type)
+		}
+	}
+
+	tools, err := c.ListTools(ctx, mcp.ListToolsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tool := range tools.Tools {
+		if !k.called[tool.Name] {
+			t.Errorf("tool %s is not exercised by the integration tests", tool.Name)
+		}
+	}
+}
+
+// testTools calls every tool that takes a connection on e.
+func testTools(t *testing.T, k *caller, e Engine, dir string) {
+	conn := func(args map[string]any) map[string]any {
+		args["connection_id"] = e.Type
+		return args
+	}
+
+	var tables internal_server.ListTablesOutput
+	k.call(t, "list_tables", conn(map[string]any{}), &tables)
+	if !slices.Contains(tables.Tables, "customers") {
+		t.Errorf("list_tables = %v", tables.Tables)
+	}
+	k.call(t, "list_tables", conn(map[string]any{"prefix": "ORDER"}), &tables)
+	if !slices.Equal(tables.Tables, []string{"order_items", "orders"}) {
+		t.Errorf("list_tables prefix ORDER = %v", tables.Tables)
+	}
+	k.call(t, "list_tables", conn(map[string]any{"schema": e.Other}), &tables)
+	if !slices.Equal(tables.Tables, []string{"products"}) {
+		t.Errorf("list_tables schema %s = %v", e.Other, tables.Tables)
+	}
+
+	var desc internal_server.DescribeTableOutput
+	k.call(t, "describe_table", conn(map[string]any{"table": "order_items"}), &desc)
+	if got := dbtest.Columns(desc.Columns); !slices.Equal(got, []string{"order_id pk", "line pk", "sku", "quantity"}) {
+		t.Errorf("describe_table order_items = %v", got)
+	}
+	var jsonSchema internal_server.TableJSONSchemaOutput
+	k.call(t, "table_json_schema", conn(map[string]any{"table": "kinds"}), &jsonSchema)
+	wantSchema := []string{`"contentEncoding":"base64"`, `"format":"date-time"`}
+	if e.Type != "mysql" {
+		// MySQL's boolean is a tinyint, and it has no UUID type.
+		wantSchema = append(wantSchema, `"flag":{"type":"boolean"}`, `"format":"uuid"`)
+	}
+	for _, want := range wantSchema {
+		if !strings.Contains(string(jsonSchema.Schema), want) {
+			t.Errorf("table_json_schema kinds = %s, lacks %s", jsonSchema.Schema, want)
+		}
+	}
+	var goStruct internal_server.GenerateCodeOutput
+	k.call(t, "generate_go_struct", conn(map[string]any{"table": "customers"}), &goStruct)
+	if !strings.Contains(goStruct.Code, "type Customer struct") || !regexp.MustCompile(`City +sql.NullString`).MatchString(goStruct.Code) {
+		t.Errorf("generate_go_struct customers =\n%s", goStruct.Code)
+	}
+	var gormModel internal_server.GenerateCodeOutput
+	k.call(t, "generate_go_struct", conn(map[string]any{"table": "orders", "style": "gorm"}), &gormModel)
+	if !regexp.MustCompile(`Customer +\*Customer`).MatchString(gormModel.Code) {
+		t.Errorf("generate_go_struct orders gorm =\n%s", gormModel.Code)
+	}
+	var tsTypes internal_server.GenerateCodeOutput
+	k.call(t, "generate_typescript_types", conn(map[string]any{}), &tsTypes)
+	if !strings.Contains(tsTypes.Code, "export interface Customer {") || !strings.Contains(tsTypes.Code, "export interface OrderItem {") {
+		t.Errorf("generate_typescript_types =\n%s", tsTypes.Code)
+	}
+	var prisma internal_server.GenerateCodeOutput
+	k.call(t, "generate_prisma_schema", conn(map[string]any{}), &prisma)
+	for _, want := range []string{"model OrderItem {", "@@id([orderId, line])", "@@index([placedAt]", "customer   Customer"} {
+		if !strings.Contains(prisma.Code, want) {
+			t.Errorf("generate_prisma_schema lacks %q:\n%s", want, prisma.Code)
+		}
+	}
+	var sdl internal_server.GenerateCodeOutput
+	k.call(t, "generate_graphql_sdl", conn(map[string]any{}), &sdl)
+	for _, want := range []string{"type Order {", "  customer: Customer!\n", "  orderItems: [OrderItem!]!\n"} {
+		if !strings.Contains(sdl.Code, want) {
+			t.Errorf("generate_graphql_sdl lacks %q:\n%s", want, sdl.Code)
+		}
+	}
+	var erd internal_server.GenerateCodeOutput
+	k.call(t, "generate_erd", conn(map[string]any{"table": "order_items", "depth": 1}), &erd)
+	if !strings.Contains(erd.Code, `orders ||--o{ order_items : "order_id"`) || strings.Contains(erd.Code, "customers") {
+		t.Errorf("generate_erd order_items =\n%s", erd.Code)
+	}
+	var schemas internal_server.CompareSchemasOutput
+	k.call(t, "compare_schemas", conn(map[string]any{"other_connection_id": e.Type}), &schemas)
+	if !schemas.Identical {
+		t.Errorf("compare_schemas of a connection with itself = %+v", schemas)
+	}
+	var data internal_server.CompareTableDataOutput
+	k.call(t, "compare_table_data", conn(map[string]any{"table": "order_items"}), &data)
+	if !data.Identical || data.Matching != data.RowsA || len(data.Key) != 2 {
+		t.Errorf("compare_table_data of a table with itself = %+v", data)
+	}
+	var profile internal_server.ProfileTableOutput
+	k.call(t, "profile_table", conn(map[string]any{"table": "orders", "columns": []any{"customer_id", "total"}}), &profile)
+	if profile.Rows != 3 || len(profile.Columns) != 2 || profile.Columns[0].Distinct == nil || *profile.Columns[0].Distinct != 2 ||
+		len(profile.Columns[0].TopValues) != 2 || profile.Columns[0].TopValues[0].Count != 2 || profile.Columns[1].Min == nil {
+		t.Errorf("profile_table orders = %+v", profile)
+	}
+	var dups internal_server.FindDuplicatesOutput
+	k.call(t, "find_duplicates", conn(map[string]any{"table": "orders", "columns": []any{"customer_id"}}), &dups)
+	if dups.Groups != 1 || dups.Rows != 2 || len(dups.Duplicates) != 1 || len(dups.Duplicates[0].SampleKeys) != 2 {
+		t.Errorf("find_duplicates orders = %+v", dups)
+	}
+	var integrity internal_server.CheckReferentialIntegrityOutput
+	k.call(t, "check_referential_integrity", conn(map[string]any{}), &integrity)
+	if !integrity.Valid || integrity.Checked == 0 {
+		t.Errorf("check_referential_integrity = %+v", integrity)
+	}
+	var orphans internal_server.FindOrphansOutput
+	k.call(t, "find_orphans", conn(map[string]any{"relationships": []any{
+		map[string]any{"table": "customers", "columns": []any{"id"}, "ref_table": "orders", "ref_columns": []any{"customer_id"}},
+	}}), &orphans)
+	if orphans.Violated != 1 || orphans.Relationships[0].Orphans != 1 || len(orphans.Relationships[0].Sample) != 1 {
+		t.Errorf("find_orphans customers without orders = %+v", orphans)
+	}
+	var indexes internal_server.SuggestIndexesOutput
+	k.call(t, "suggest_indexes", conn(map[string]any{"sql": "SELECT name FROM customers WHERE city = $1 ORDER BY name", "params": []any{"London"}}), &indexes)
+	if len(indexes.Suggestions) != 1 || strings.Join(indexes.Suggestions[0].Columns, ",") != "city,name" {
+		t.Errorf("suggest_indexes customers by city = %+v", indexes)
+	}
+	var plan internal_server.ExplainQueryOutput
+	k.call(t, "explain_query", conn(map[string]any{"sql": "SELECT name FROM customers WHERE city = $1", "params": []any{"London"}}), &plan)
+	if len(plan.Plan) == 0 || plan.Plan[0].Operation == "" {
+		t.Errorf("explain_query = %+v", plan)
+	}
+	var deps internal_server.ListDependenciesOutput
+	k.call(t, "list_dependencies", conn(map[string]any{"table": "orders"}), &deps)
+	if len(deps.ForeignKeys) != 1 || deps.ForeignKeys[0].Table != "order_items" {
+		t.Errorf("list_dependencies orders = %+v", deps)
+	}
+	var refreshed internal_server.RefreshSchemaOutput
+	k.call(t, "refresh_schema", conn(map[string]any{"table": "order_items"}), &refreshed)
+	if refreshed.Invalidated < 1 {
+		t.Errorf("refresh_schema invalidated %d tables, want the 1 described", refreshed.Invalidated)
+	}
+
+	var rows internal_server.RunQueryOutput
+	k.call(t, "run_query", conn(map[string]any{"sql": "SELECT name FROM customers WHERE id = $1", "params": []any{1}}), &rows)
+	if len(rows.Rows) != 1 || rows.Rows[0]["name"] != "Ada Example" {
+		t.Errorf("run_query = %v", rows.Rows)
+	}
+	k.fail(t, "run_query", conn(map[string]any{"sql": "DELETE FROM notes"}))
+
+	body := nonce(t)
+	var inserted internal_server.InsertTestRowOutput
+	k.call(t, "insert_test_row", conn(map[string]any{"table": "notes", "row": map[string]any{"body": body}, "return_id": true}), &inserted)
+	if inserted.InsertedID == nil {
+		t.Fatal("insert_test_row returned no inserted_id")
+	}
+	var updated internal_server.UpdateTestRowOutput
+	k.call(t, "update_test_row", conn(map[string]any{"table": "notes", "key": map[string]any{"id": inserted.InsertedID}, "set": map[string]any{"body": body + " updated"}}), &updated)
+	if updated.RowsAffected != 1 {
+		t.Errorf("update_test_row affected %d rows", updated.RowsAffected)
+	}
+	if code := k.fail(t, "update_test_row", conn(map[string]any{"table": "notes", "key": map[string]any{"id": -1}, "set": map[string]any{"body": "x"}})); code != internal_server.CodeNotFound {
+		t.Errorf("update_test_row of a missing row: code %q", code)
+	}
+
+	// A rolled back transaction leaves nothing behind; a committed one does.
+	for _, commit := range []bool{false, true} {
+		var tx internal_server.BeginTransactionOutput
+		k.call(t, "begin_transaction", conn(map[string]any{}), &tx)
+		txBody := fmt.Sprintf("%s tx commit=%t", body, commit)
+		k.call(t, "insert_test_row", conn(map[string]any{"table": "notes", "row": map[string]any{"body": txBody}, "transaction_id": tx.TransactionID}), &inserted)
+		end := "rollback_transaction"
+		if commit {
+			end = "commit_transaction"
+		}
+		var ended internal_server.EndTransactionOutput
+		k.call(t, end, map[string]any{"transaction_id": tx.TransactionID}, &ended)
+		want := "0"
+		if commit {
+			want = "1"
+		}
+		if n := k.count(t, e.Type, txBody); n != want || ended.Committed != commit {
+			t.Errorf("%s: committed %t, %s notes written, want %s", end, ended.Committed, n, want)
+		}
+	}
+
+	var created internal_server.CreateTestTableOutput
+	k.call(t, "create_test_table", conn(map[string]any{"table": "fixtures", "columns": []any{
+		map[string]any{"name": "id", "type": "integer", "primary_key": true},
+		map[string]any{"name": "label", "type": "varchar(50)", "not_null": true},
+	}}), &created)
+	k.call(t, "insert_test_row", conn(map[string]any{"table": "fixtures", "row": map[string]any{"id": 1, "label": "scratch"}}), &inserted)
+	k.call(t, "run_query", conn(map[string]any{"sql": fmt.Sprintf("SELECT label FROM %s.fixtures", created.Schema)}), &rows)
+	if len(rows.Rows) != 1 || rows.Rows[0]["label"] != "scratch" {
+		t.Errorf("session table %s.fixtures holds %v", created.Schema, rows.Rows)
+	}
+
+	missing := ""
+	for _, tool := range db.CLITools(e.Type) {
+		if _, err := db.FindCLITool(tool); err != nil {
+			missing = tool
+		}
+	}
+
+	var exported internal_server.ExportDatabaseOutput
+	dump := filepath.Join(dir, e.Type+"-export.sql")
+	if missing == "" || e.Type == "sqlserver" {
+		// SQL Server dumps are written by the server itself.
+		k.call(t, "export_database", conn(map[string]any{"path": dump}), &exported)
+		if b, err := os.ReadFile(dump); err != nil || !strings.Contains(string(b), "customers") {
+			t.Errorf("dump %s: %v; want the customers table in it", dump, err)
+		}
+	} else {
+		k.fail(t, "export_database", conn(map[string]any{"path": dump}))
+	}
+
+	importBody := body + " imported"
+	script := filepath.Join(dir, e.Type+"-import.sql")
+	if err := os.WriteFile(script, []byte(fmt.Sprintf("INSERT INTO notes (body) VALUES ('%s');\n", importBody)), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var confirm internal_server.ConfirmationRequiredOutput
+	k.call(t, "import_database", conn(map[string]any{"path": script}), &confirm)
+	if missing != "" {
+		t.Logf("%s not installed: not running import_database", missing)
+	} else {
+		var imported internal_server.ImportDatabaseOutput
+		k.call(t, "import_database", conn(map[string]any{"path": script, "confirmation_token": confirm.ConfirmationToken}), &imported)
+		if n := k.count(t, e.Type, importBody); n != "1" {
+			t.Errorf("import wrote %s notes, want 1", n)
+		}
+	}
+
+	var closed internal_server.CloseConnectionOutput
+	k.call(t, "close_connection", conn(map[string]any{}), &closed)
+	if !closed.Closed {
+		t.Error("close_connection did not close the open connection")
+	}
+	k.call(t, "list_tables", conn(map[string]any{"schema": e.Other}), &tables)
+}
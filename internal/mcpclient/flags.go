@@ -0,0 +1,184 @@
+package mcpclient
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+)
+
+// Env var equivalents of the command-line flags. A flag given on the command
+// line wins over its env var.
+const (
+	envServerCmd  = "LOCALDB_MCP_SERVER"
+	envConnection = "LOCALDB_MCP_CONNECTION"
+)
+
+// defaultTimeout bounds starting the server and the tool call.
+const defaultTimeout = 15 * time.Second
+
+// options is the parsed command line.
+type options struct {
+	// serverCmd is the command that starts the server on stdio, split into
+	// words; nil runs go run ./cmd/server from the repo root.
+	serverCmd []string
+	// url is the endpoint of a running server to connect to instead of
+	// starting one: streamable HTTP, or SSE if the path ends in /sse.
+	url     string
+	token   string // bearer token for url
+	timeout time.Duration
+	output  string // one of outputFormats
+	tool    string
+	args    map[string]any
+	script  string // "run" subcommand: the script file to run instead of tool
+	// envFile and configPath are a .env file and a config file for the
+	// started server, instead of the ones it finds itself.
+	envFile    string
+	configPath string
+	// connection is the connection_id added to the arguments of calls to
+	// tools that take one and were not given one.
+	connection string
+}
+
+// parseFlags parses args (without the program name), taking defaults from
+// the env vars looked up with getenv. Usage and errors are reported on out.
+func parseFlags(name string, args []string, getenv func(string) string, out io.Writer) (*options, error) {
+	fail := func(err error) (*options, error) {
+		fmt.Fprintln(out, err)
+		return nil, err
+	}
+	var o options
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(out)
+	fs.Usage = func() {
+		fmt.Fprintf(out, "usage: %[1]s [flags] <tool_name> [json_arguments]\n       %[1]s [flags] run <script.jsonl|script.yaml>\n", name)
+		fs.PrintDefaults()
+	}
+	serverCmd := fs.String("server-cmd", getenv(envServerCmd), "command that starts the server on stdio, e.g. localdb-mcp or \"docker run -i --rm localdb-mcp\" (env "+envServerCmd+")")
+	fs.StringVar(&o.url, "url", "", "endpoint of a running server to connect to instead of starting one, e.g. http://localhost:8089/mcp (streamable HTTP) or http://localhost:8089/sse")
+	fs.StringVar(&o.token, "token", getenv(config.EnvAuthToken), "bearer token for --url (env "+config.EnvAuthToken+")")
+	fs.StringVar(&o.envFile, "env-file", "", "a .env file whose variables the started server gets, on top of the environment")
+	fs.StringVar(&o.configPath, "config", "", "config file for the started server instead of ~/.localdb-mcp/config.yaml (sets "+config.EnvConfigFile+")")
+	fs.StringVar(&o.connection, "connection", getenv(envConnection), "connection_id to use for calls that do not name one (env "+envConnection+")")
+	fs.DurationVar(&o.timeout, "timeout", defaultTimeout, "how long starting the server and each tool call may take")
+	fs.StringVar(&o.output, "output", outputTable, "output format: table (rows as a table), json (results and errors as JSON), csv (rows as CSV) or raw (as returned)")
+	asJSON := fs.Bool("json", false, "short for --output json")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		fs.Usage()
+		return nil, errors.New("expected a tool name and optional JSON arguments")
+	}
+	if *asJSON {
+		o.output = outputJSON
+	}
+	if !slices.Contains(outputFormats, o.output) {
+		return fail(fmt.Errorf("--output must be one of %s, got %q", strings.Join(outputFormats, ", "), o.output))
+	}
+	if o.timeout <= 0 {
+		return fail(fmt.Errorf("--timeout must be positive, got %s", o.timeout))
+	}
+	if o.url != "" {
+		u, err := url.Parse(o.url)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fail(fmt.Errorf("--url: %q is not an http or https URL", o.url))
+		}
+		var conflict string
+		fs.Visit(func(f *flag.Flag) {
+			if f.Name == "server-cmd" || f.Name == "env-file" || f.Name == "config" {
+				conflict = f.Name
+			}
+		})
+		if conflict != "" {
+			return fail(fmt.Errorf("--url and --%s cannot be used together: they configure a server mcpclient starts", conflict))
+		}
+		*serverCmd = ""
+	}
+	if *serverCmd != "" {
+		words, err := splitCommand(*serverCmd)
+		if err != nil {
+			return fail(fmt.Errorf("--server-cmd: %w", err))
+		}
+		o.serverCmd = words
+	}
+	if fs.Arg(0) == "run" {
+		if fs.NArg() != 2 || fs.Arg(1) == "" {
+			fs.Usage()
+			return nil, errors.New("run expects a script file")
+		}
+		o.script = fs.Arg(1)
+		return &o, nil
+	}
+	o.tool = fs.Arg(0)
+	o.args = make(map[string]any)
+	if fs.NArg() == 2 && fs.Arg(1) != "" {
+		if err := json.Unmarshal([]byte(fs.Arg(1)), &o.args); err != nil {
+			return fail(fmt.Errorf("invalid json arguments: %w", err))
+		}
+	}
+	return &o, nil
+}
+
+// splitCommand splits s into words at unquoted whitespace, the way a shell
+// would for a simple command: single quotes keep everything literally,
+// double quotes allow backslash escapes, and a backslash outside quotes
+// escapes the next character. No expansion is done.
+func splitCommand(s string) ([]string, error) {
+	var words []string
+	var cur strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			cur.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inWord = true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, cur.String())
+				cur.Reset()
+				inWord = false
+			}
+		default:
+			cur.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("unterminated quote or escape in %q", s)
+	}
+	if inWord {
+		words = append(words, cur.String())
+	}
+	if len(words) == 0 {
+		return nil, errors.New("empty command")
+	}
+	return words, nil
+}
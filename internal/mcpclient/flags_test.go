@@ -0,0 +1,142 @@
+package mcpclient
+
+import (
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+)
+
+func getenvFrom(env map[string]string) func(string) string {
+	return func(name string) string { return env[name] }
+}
+
+func TestParseFlags_defaults(t *testing.T) {
+	o, err := parseFlags("mcpclient", []string{"ping"}, getenvFrom(nil), io.Discard)
+	if err != nil {
+		t.Fatalf("parseFlags: %v", err)
+	}
+	if o.serverCmd != nil || o.timeout != defaultTimeout || o.output != outputTable || o.tool != "ping" || len(o.args) != 0 {
+		t.Errorf("unexpected defaults: %+v", o)
+	}
+}
+
+func TestParseFlags_serverCmd(t *testing.T) {
+	env := getenvFrom(map[string]string{envServerCmd: "localdb-mcp --read-only"})
+	o, err := parseFlags("mcpclient", []string{"list_tables", `{"connection_id":"sqlite"}`}, env, io.Discard)
+	if err != nil {
+		t.Fatalf("parseFlags: %v", err)
+	}
+	if !slices.Equal(o.serverCmd, []string{"localdb-mcp", "--read-only"}) || o.args["connection_id"] != "sqlite" {
+		t.Errorf("env: %+v", o)
+	}
+
+	o, err = parseFlags("mcpclient", []string{"--server-cmd", `docker run -i --rm -e "MCP_CONFIG=/etc/a b.yaml" localdb-mcp`, "--timeout", "1m", "ping"}, env, io.Discard)
+	if err != nil {
+		t.Fatalf("parseFlags: %v", err)
+	}
+	want := []string{"docker", "run", "-i", "--rm", "-e", "MCP_CONFIG=/etc/a b.yaml", "localdb-mcp"}
+	if !slices.Equal(o.serverCmd, want) || o.timeout != time.Minute {
+		t.Errorf("flag did not override env: %q, %s", o.serverCmd, o.timeout)
+	}
+}
+
+func TestParseFlags_invalid(t *testing.T) {
+	for _, args := range [][]string{
+		nil,
+		{"ping", "{}", "extra"},
+		{"ping", "not json"},
+		{"--timeout", "0s", "ping"},
+		{"--server-cmd", `"unterminated`, "ping"},
+		{"--server-cmd", "  ", "ping"},
+		{"--output", "xml", "ping"},
+	} {
+		if _, err := parseFlags("mcpclient", args, getenvFrom(nil), io.Discard); err == nil {
+			t.Errorf("%q: no error", args)
+		}
+	}
+}
+
+func TestSplitCommand(t *testing.T) {
+	for in, want := range map[string][]string{
+		"localdb-mcp":                 {"localdb-mcp"},
+		"  a   b ":                    {"a", "b"},
+		`'/opt/my tools/localdb-mcp'`: {"/opt/my tools/localdb-mcp"},
+		`a\ b "c \"d\"" 'e\f'`:        {"a b", `c "d"`, `e\f`},
+		`--x="" y`:                    {"--x=", "y"},
+		`""`:                          {""},
+	} {
+		got, err := splitCommand(in)
+		if err != nil || !slices.Equal(got, want) {
+			t.Errorf("splitCommand(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+}
+
+func TestParseFlags_url(t *testing.T) {
+	env := getenvFrom(map[string]string{envServerCmd: "localdb-mcp", config.EnvAuthToken: "s3cret"})
+	o, err := parseFlags("mcpclient", []string{"--url", "http://localhost:8089/mcp", "ping"}, env, io.Discard)
+	if err != nil {
+		t.Fatalf("parseFlags: %v", err)
+	}
+	if o.url != "http://localhost:8089/mcp" || o.token != "s3cret" || o.serverCmd != nil {
+		t.Errorf("--url: %+v", o)
+	}
+	for _, args := range [][]string{
+		{"--url", "localhost:8089", "ping"},
+		{"--url", "ftp://localhost/mcp", "ping"},
+		{"--url", "http://localhost:8089/mcp", "--server-cmd", "localdb-mcp", "ping"},
+		{"--url", "http://localhost:8089/mcp", "--env-file", ".env.test", "ping"},
+		{"--url", "http://localhost:8089/mcp", "--config", "test.yaml", "ping"},
+	} {
+		if _, err := parseFlags("mcpclient", args, env, io.Discard); err == nil {
+			t.Errorf("%q: no error", args)
+		}
+	}
+}
+
+func TestParseFlags_run(t *testing.T) {
+	o, err := parseFlags("mcpclient", []string{"--timeout", "1m", "run", "fixtures.jsonl"}, getenvFrom(nil), io.Discard)
+	if err != nil {
+		t.Fatalf("parseFlags: %v", err)
+	}
+	if o.script != "fixtures.jsonl" || o.tool != "" || o.timeout != time.Minute {
+		t.Errorf("run: %+v", o)
+	}
+	for _, args := range [][]string{{"run"}, {"run", ""}, {"run", "a.jsonl", "b.jsonl"}} {
+		if _, err := parseFlags("mcpclient", args, getenvFrom(nil), io.Discard); err == nil {
+			t.Errorf("%q: no error", args)
+		}
+	}
+}
+
+func TestParseFlags_output(t *testing.T) {
+	for args, want := range map[string]string{
+		"--output csv":          outputCSV,
+		"--json":                outputJSON,
+		"--output raw":          outputRaw,
+		"--output table --json": outputJSON,
+	} {
+		o, err := parseFlags("mcpclient", append(strings.Fields(args), "ping"), getenvFrom(nil), io.Discard)
+		if err != nil || o.output != want {
+			t.Errorf("%s: got %+v, %v; want output %s", args, o, err, want)
+		}
+	}
+}
+
+func TestParseFlags_connection(t *testing.T) {
+	env := getenvFrom(map[string]string{envConnection: "postgres"})
+	o, err := parseFlags("mcpclient", []string{"--env-file", ".env.test", "--config", "test.yaml", "list_tables"}, env, io.Discard)
+	if err != nil {
+		t.Fatalf("parseFlags: %v", err)
+	}
+	if o.connection != "postgres" || o.envFile != ".env.test" || o.configPath != "test.yaml" {
+		t.Errorf("env: %+v", o)
+	}
+	if o, err = parseFlags("mcpclient", []string{"--connection", "sqlite", "list_tables"}, env, io.Discard); err != nil || o.connection != "sqlite" {
+		t.Errorf("flag did not override env: %+v, %v", o, err)
+	}
+}
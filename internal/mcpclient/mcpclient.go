@@ -0,0 +1,295 @@
+// Package mcpclient is a one-off MCP client: it starts the localdb-mcp
+// server (or connects to a running one), calls one tool with optional JSON
+// arguments, and prints the result. It backs cmd/mcpclient and
+// localdb-mcp call:
+//
+//	mcpclient <tool_name>              # no args, e.g. ping
+//	mcpclient <tool_name> '<json>'    # with arguments
+//	mcpclient --output csv <tool> ...  # table, json, csv or raw
+//	mcpclient run <file>              # the calls in a script
+//
+// --server-cmd (or LOCALDB_MCP_SERVER) runs another server command instead
+// of the default, e.g. an installed binary or a container; --timeout
+// replaces the default 15 seconds for starting the session and for each
+// call. --url connects to a server
+// already running with --transport=http or sse, with --token (or
+// MCP_AUTH_TOKEN) as its bearer token.
+//
+// --env-file and --config give the started server a .env file and a config
+// file of their own. --connection (or LOCALDB_MCP_CONNECTION) fills in
+// connection_id for calls to tools that take one:
+//
+//	mcpclient --connection postgres list_tables
+//
+// A result with a rows array, such as run_query's, is printed as a table,
+// or with --output csv as CSV. --output json (or --json) prints every result
+// as one line of JSON, and a failed call as {"error": {"code": ...}} on
+// stdout; --output raw prints results as the server returned them.
+//
+// A script is a JSONL file with one {"tool": ..., "arguments": {...}} object
+// per line, or a YAML file (.yaml or .yml) with a list of them. Its calls run
+// in order in one session, each reported with its result or error; the exit
+// code is 1 if any of them failed.
+package mcpclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Main runs the client as program name with args (without the program
+// name) and returns the exit code. server is the command that starts the
+// server unless --server-cmd or --url says otherwise; nil runs go run
+// ./cmd/server from the root of the checkout containing the working
+// directory.
+func Main(name string, args []string, server []string) int {
+	o, err := parseFlags(name, args, os.Getenv, os.Stderr)
+	if err != nil {
+		return 1
+	}
+	if o.serverCmd == nil && o.url == "" {
+		o.serverCmd = server
+	}
+
+	var script []scriptCall
+	if o.script != "" {
+		if script, err = loadScript(o.script); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return 1
+		}
+	}
+
+	// Before newClient changes to the repo root, so relative paths work.
+	if o.envFile != "" {
+		if err := config.LoadEnvFile(o.envFile); err != nil {
+			fmt.Fprintf(os.Stderr, "--env-file: %v\n", err)
+			return 1
+		}
+	}
+	if o.configPath != "" {
+		path, err := filepath.Abs(o.configPath)
+		if err == nil {
+			_, err = os.Stat(path)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "--config: %v\n", err)
+			return 1
+		}
+		_ = os.Setenv(config.EnvConfigFile, path)
+	}
+
+	// The session outlives the timeout, which bounds each step.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c, err := newClient(ctx, o)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	defer c.Close()
+
+	initReq := mcp.InitializeRequest{}
+	initReq.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	initReq.Params.ClientInfo = mcp.Implementation{
+		Name:    name,
+		Version: "1.0.0",
+	}
+	initReq.Params.Capabilities = mcp.ClientCapabilities{}
+
+	initCtx, initCancel := context.WithTimeout(ctx, o.timeout)
+	_, err = c.Initialize(initCtx, initReq)
+	initCancel()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "initialize: %v\n", err)
+		return 1
+	}
+
+	if o.connection != "" {
+		if err := withConnection(ctx, c, o, script); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return 1
+		}
+	}
+
+	if o.script != "" {
+		if runScript(ctx, c, script, o, os.Stdout) > 0 {
+			return 1
+		}
+		return 0
+	}
+
+	text, err := callTool(ctx, c, o.tool, o.args, o.timeout)
+	if err != nil {
+		if o.output == outputJSON {
+			b, _ := json.Marshal(errorJSON(err))
+			fmt.Printf("%s\n", b)
+		} else {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+		}
+		return 1
+	}
+	if err := writeResult(os.Stdout, text, o.output); err != nil {
+		fmt.Fprintf(os.Stderr, "write result: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// PrintUsage writes the usage of the client as program name, with its
+// flags, to w.
+func PrintUsage(name string, w io.Writer) {
+	_, _ = parseFlags(name, []string{"-h"}, func(string) string { return "" }, w)
+}
+
+// callTool calls tool with args, waiting at most timeout, and returns the
+// text of its result. A result flagged as an error is returned as a
+// *toolError.
+func callTool(ctx context.Context, c *client.Client, tool string, args map[string]any, timeout time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if args == nil {
+		args = make(map[string]any)
+	}
+	res, err := c.CallTool(ctx, mcp.CallToolRequest{
+		Params: mcp.CallToolParams{
+			Name:      tool,
+			Arguments: args,
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("call tool: %w", err)
+	}
+
+	if res.IsError {
+		msg := "tool failed"
+		for _, content := range res.Content {
+			if tc, ok := mcp.AsTextContent(content); ok {
+				msg = tc.Text
+				break
+			}
+		}
+		return "", &toolError{msg: msg, structured: res.StructuredContent}
+	}
+
+	text := ""
+	for _, content := range res.Content {
+		if tc, ok := mcp.AsTextContent(content); ok {
+			text += tc.Text
+		}
+	}
+	return text, nil
+}
+
+// withConnection adds o.connection as connection_id to the arguments of
+// o's call, or of script's calls, to tools that take a connection_id and
+// were not given one.
+func withConnection(ctx context.Context, c *client.Client, o *options, script []scriptCall) error {
+	ctx, cancel := context.WithTimeout(ctx, o.timeout)
+	defer cancel()
+	tools, err := c.ListTools(ctx, mcp.ListToolsRequest{})
+	if err != nil {
+		return fmt.Errorf("list tools: %w", err)
+	}
+	takes := make(map[string]bool)
+	for _, t := range tools.Tools {
+		_, takes[t.Name] = t.InputSchema.Properties["connection_id"]
+	}
+	add := func(tool string, args map[string]any) map[string]any {
+		if !takes[tool] {
+			return args
+		}
+		if _, ok := args["connection_id"]; ok {
+			return args
+		}
+		if args == nil {
+			args = make(map[string]any)
+		}
+		args["connection_id"] = o.connection
+		return args
+	}
+	o.args = add(o.tool, o.args)
+	for i := range script {
+		script[i].Arguments = add(script[i].Tool, script[i].Arguments)
+	}
+	return nil
+}
+
+// newClient connects to the server o names: the one at o.url, or one
+// started with o.serverCmd (by default go run ./cmd/server from the repo
+// root).
+func newClient(ctx context.Context, o *options) (*client.Client, error) {
+	if o.url != "" {
+		return DialURL(ctx, o.url, o.token)
+	}
+
+	cmd := o.serverCmd
+	if cmd == nil {
+		repoRoot, err := findRepoRoot()
+		if err != nil {
+			return nil, fmt.Errorf("find repo root: %w (set --server-cmd to run an installed server, or --url to connect to a running one)", err)
+		}
+		if err := os.Chdir(repoRoot); err != nil {
+			return nil, fmt.Errorf("chdir: %w", err)
+		}
+		cmd = []string{"go", "run", "./cmd/server"}
+	}
+	c, err := client.NewStdioMCPClient(cmd[0], os.Environ(), cmd[1:]...)
+	if err != nil {
+		return nil, fmt.Errorf("create client: %w", err)
+	}
+	return c, nil
+}
+
+// DialURL connects to the server at url, a running server's streamable
+// HTTP endpoint or, if its path ends in /sse, its SSE endpoint, sending
+// token as the bearer token if set. The session is not initialized yet.
+func DialURL(ctx context.Context, url, token string) (*client.Client, error) {
+	var headers map[string]string
+	if token != "" {
+		headers = map[string]string{"Authorization": "Bearer " + token}
+	}
+	var c *client.Client
+	var err error
+	if strings.HasSuffix(strings.TrimSuffix(url, "/"), "/sse") {
+		c, err = client.NewSSEMCPClient(url, client.WithHeaders(headers))
+	} else {
+		c, err = client.NewStreamableHttpClient(url, transport.WithHTTPHeaders(headers))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create client: %w", err)
+	}
+	if err := c.Start(ctx); err != nil {
+		c.Close()
+		return nil, fmt.Errorf("connect to %s: %w", url, err)
+	}
+	return c, nil
+}
+
+func findRepoRoot() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return dir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("go.mod not found")
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,97 @@
+package mcpclient
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Output formats of --output.
+const (
+	outputTable = "table" // rows as an aligned table, other results as returned
+	outputJSON  = "json"  // one compact JSON value per result, errors included
+	outputCSV   = "csv"   // rows as CSV, other results as returned
+	outputRaw   = "raw"   // results as returned
+)
+
+var outputFormats = []string{outputTable, outputJSON, outputCSV, outputRaw}
+
+// toolError is a tool result flagged as an error.
+type toolError struct {
+	msg string
+	// structured is the result's structured content, for the server's tools
+	// {"code", "message", "hint", "request_id", ...}; nil if it had none.
+	structured any
+}
+
+func (e *toolError) Error() string { return "tool error: " + e.msg }
+
+// writeResult writes text, a successful tool result, to w in format.
+func writeResult(w io.Writer, text, format string) error {
+	switch format {
+	case outputJSON:
+		b, err := json.Marshal(jsonValue(text))
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", b)
+		return err
+	case outputTable:
+		if printTable(w, text) {
+			return nil
+		}
+	case outputCSV:
+		if rs, ok := parseRows(text); ok {
+			return writeCSV(w, rs)
+		}
+	}
+	_, err := fmt.Fprintln(w, text)
+	return err
+}
+
+// jsonValue returns text as a JSON value: itself if it is JSON, otherwise a
+// JSON string.
+func jsonValue(text string) any {
+	var b bytes.Buffer
+	if json.Compact(&b, []byte(text)) == nil {
+		return json.RawMessage(b.Bytes())
+	}
+	return text
+}
+
+// errorJSON returns err, a failed call, as the {"error": ...} object the
+// json format reports it with: the tool's structured error content if it
+// has one, otherwise the message.
+func errorJSON(err error) map[string]any {
+	var te *toolError
+	if errors.As(err, &te) && te.structured != nil {
+		return map[string]any{"error": te.structured}
+	}
+	return map[string]any{"error": map[string]any{"message": err.Error()}}
+}
+
+// writeCSV writes rs to w as CSV, with a header of its columns. NULL is an
+// empty field; the result's fields besides rows are left out.
+func writeCSV(w io.Writer, rs *rowSet) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(rs.cols); err != nil {
+		return err
+	}
+	record := make([]string, len(rs.cols))
+	for _, row := range rs.rows {
+		for i, c := range rs.cols {
+			record[i] = ""
+			if v, ok := row[c]; ok {
+				record[i] = cellText(v)
+			}
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,48 @@
+package mcpclient
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWriteResult(t *testing.T) {
+	text := `{"rows":[{"id":1,"name":"Ada, \"A\"","note":null},{"id":2,"name":"line\nbreak"}],"truncated":true}`
+	for format, want := range map[string]string{
+		outputCSV:  "id,name,note\n1,\"Ada, \"\"A\"\"\",\n2,\"line\nbreak\",\n",
+		outputJSON: `{"rows":[{"id":1,"name":"Ada, \"A\"","note":null},{"id":2,"name":"line\nbreak"}],"truncated":true}` + "\n",
+		outputRaw:  text + "\n",
+	} {
+		var b strings.Builder
+		if err := writeResult(&b, text, format); err != nil || b.String() != want {
+			t.Errorf("%s: got %q, %v; want %q", format, b.String(), err, want)
+		}
+	}
+
+	// Results without rows are printed as returned, except in JSON.
+	for format, want := range map[string]string{
+		outputCSV:   "pong\n",
+		outputTable: "pong\n",
+		outputJSON:  "\"pong\"\n",
+	} {
+		var b strings.Builder
+		if err := writeResult(&b, "pong", format); err != nil || b.String() != want {
+			t.Errorf("%s without rows: got %q, %v; want %q", format, b.String(), err, want)
+		}
+	}
+}
+
+func TestErrorJSON(t *testing.T) {
+	structured := map[string]any{"code": "not_found", "message": "no such table"}
+	for err, want := range map[error]string{
+		&toolError{msg: "no such table", structured: structured}: `{"error":{"code":"not_found","message":"no such table"}}`,
+		&toolError{msg: "tool failed"}:                           `{"error":{"message":"tool error: tool failed"}}`,
+		errors.New("call tool: broken pipe"):                     `{"error":{"message":"call tool: broken pipe"}}`,
+	} {
+		b, _ := json.Marshal(errorJSON(err))
+		if string(b) != want {
+			t.Errorf("%v: got %s, want %s", err, b, want)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package mcpclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/client"
+	"gopkg.in/yaml.v3"
+)
+
+// scriptCall is one tool call of a script run with mcpclient run.
+type scriptCall struct {
+	Tool      string         `json:"tool" yaml:"tool"`
+	Arguments map[string]any `json:"arguments" yaml:"arguments"`
+	// line is where the call starts in the script, for reporting.
+	line int
+}
+
+// loadScript reads the script at path: YAML (a list of calls) if it ends in
+// .yaml or .yml, JSONL (one call per line; blank lines and lines starting
+// with # are skipped) otherwise.
+func loadScript(path string) ([]scriptCall, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var calls []scriptCall
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		calls, err = parseYAMLScript(data)
+	default:
+		calls, err = parseJSONLScript(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(calls) == 0 {
+		return nil, fmt.Errorf("%s: no calls", path)
+	}
+	return calls, nil
+}
+
+func parseJSONLScript(data []byte) ([]scriptCall, error) {
+	var calls []scriptCall
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(nil, 16<<20)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		dec := json.NewDecoder(strings.NewReader(line))
+		dec.DisallowUnknownFields()
+		var c scriptCall
+		if err := dec.Decode(&c); err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		if dec.More() {
+			return nil, fmt.Errorf("line %d: more than one JSON value", n)
+		}
+		if c.Tool == "" {
+			return nil, fmt.Errorf("line %d: no tool", n)
+		}
+		c.line = n
+		calls = append(calls, c)
+	}
+	return calls, sc.Err()
+}
+
+func parseYAMLScript(data []byte) ([]scriptCall, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	list := doc.Content[0]
+	if list.Kind != yaml.SequenceNode {
+		return nil, errors.New("a YAML script must be a list of {tool, arguments} mappings")
+	}
+	calls := make([]scriptCall, len(list.Content))
+	for i, node := range list.Content {
+		var c scriptCall
+		if err := node.Decode(&c); err != nil {
+			return nil, fmt.Errorf("line %d: %w", node.Line, err)
+		}
+		if c.Tool == "" {
+			return nil, fmt.Errorf("line %d: no tool", node.Line)
+		}
+		c.line = node.Line
+		calls[i] = c
+	}
+	return calls, nil
+}
+
+// runScript runs calls in order in c's session, reporting each with its
+// result or error on w, and returns how many failed. A failed call does not
+// stop the ones after it. With --output json each call is reported as one
+// line of JSON, {"call", "line", "tool"} plus "result" or "error".
+func runScript(ctx context.Context, c *client.Client, calls []scriptCall, o *options, w io.Writer) int {
+	failed := 0
+	for i, call := range calls {
+		text, err := callTool(ctx, c, call.Tool, call.Arguments, o.timeout)
+		if err != nil {
+			failed++
+		}
+		if o.output == outputJSON {
+			record := map[string]any{"call": i + 1, "line": call.line, "tool": call.Tool}
+			if err != nil {
+				maps.Copy(record, errorJSON(err))
+			} else {
+				record["result"] = jsonValue(text)
+			}
+			b, _ := json.Marshal(record)
+			fmt.Fprintf(w, "%s\n", b)
+			continue
+		}
+		if err != nil {
+			fmt.Fprintf(w, "[%d/%d] %s (line %d): FAILED: %v\n", i+1, len(calls), call.Tool, call.line, err)
+			continue
+		}
+		fmt.Fprintf(w, "[%d/%d] %s (line %d): ok\n", i+1, len(calls), call.Tool, call.line)
+		_ = writeResult(w, text, o.output)
+	}
+	if o.output != outputJSON {
+		fmt.Fprintf(w, "%d of %d calls succeeded\n", len(calls)-failed, len(calls))
+	}
+	return failed
+}
@@ -0,0 +1,158 @@
+package mcpclient
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	internal_server "github.com/SedlarDavid/localdb-mcp/internal/server"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func writeScript(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadScript(t *testing.T) {
+	jsonl := writeScript(t, "calls.jsonl", `# fixtures
+{"tool":"ping"}
+
+{"tool":"run_query","arguments":{"connection_id":"demo","sql":"SELECT 1"}}
+`)
+	yml := writeScript(t, "calls.yaml", `- tool: ping
+- tool: run_query
+  arguments:
+    connection_id: demo
+    sql: SELECT 1
+`)
+	for _, path := range []string{jsonl, yml} {
+		calls, err := loadScript(path)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		if len(calls) != 2 || calls[0].Tool != "ping" || calls[1].Tool != "run_query" || calls[1].Arguments["sql"] != "SELECT 1" {
+			t.Errorf("%s: %+v", path, calls)
+		}
+		if calls[1].line != 4 && calls[1].line != 2 {
+			t.Errorf("%s: run_query on line %d", path, calls[1].line)
+		}
+	}
+
+	for name, content := range map[string]string{
+		"bad.jsonl":    "{\"tool\":\"ping\"}\n{\"tool\":\n",
+		"unknown.json": `{"tool":"ping","args":{}}`,
+		"notool.jsonl": `{"arguments":{}}`,
+		"empty.jsonl":  "\n# nothing\n",
+		"map.yaml":     "tool: ping\n",
+		"notool.yml":   "- arguments: {}\n",
+	} {
+		if _, err := loadScript(writeScript(t, name, content)); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+// newDemoClient returns a client of an in-process server with only the
+// demo connection.
+func newDemoClient(t *testing.T) *client.Client {
+	t.Helper()
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(cfgPath, []byte("connections:\n  demo: demo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, env := range []string{config.EnvPostgresURI, config.EnvSQLServerURI, config.EnvSQLiteURI, config.EnvMySQLURI} {
+		t.Setenv(env, "")
+	}
+	cfg, err := config.LoadFrom(cfgPath)
+	if err != nil {
+		t.Fatalf("LoadFrom: %v", err)
+	}
+	s := server.NewMCPServer(internal_server.ServerName, internal_server.ServerVersion)
+	mgr := internal_server.Register(s, cfg)
+	t.Cleanup(func() { mgr.Close() })
+	c, err := client.NewInProcessClient(s)
+	if err != nil {
+		t.Fatalf("NewInProcessClient: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	initReq := mcp.InitializeRequest{}
+	initReq.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	if _, err := c.Initialize(context.Background(), initReq); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	return c
+}
+
+func TestRunScript(t *testing.T) {
+	ctx := context.Background()
+	c := newDemoClient(t)
+
+	calls := []scriptCall{
+		{Tool: "ping", line: 1},
+		{Tool: "run_query", Arguments: map[string]any{"connection_id": "nope", "sql": "SELECT 1"}, line: 2},
+		{Tool: "run_query", Arguments: map[string]any{"connection_id": "demo", "sql": "SELECT id FROM customers WHERE id = 1"}, line: 3},
+	}
+	var out strings.Builder
+	if failed := runScript(ctx, c, calls, &options{timeout: 5 * time.Second, output: outputTable}, &out); failed != 1 {
+		t.Errorf("%d calls failed, want 1", failed)
+	}
+	for _, want := range []string{
+		"[1/3] ping (line 1): ok\n",
+		"[2/3] run_query (line 2): FAILED: tool error:",
+		"[3/3] run_query (line 3): ok\n",
+		"| id |",
+		"2 of 3 calls succeeded\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output lacks %q:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	if failed := runScript(ctx, c, calls, &options{timeout: 5 * time.Second, output: outputJSON}, &out); failed != 1 {
+		t.Errorf("json: %d calls failed, want 1", failed)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 ||
+		lines[0] != `{"call":1,"line":1,"result":{"message":"pong"},"tool":"ping"}` ||
+		!strings.HasPrefix(lines[1], `{"call":2,"error":{"code":"unknown_connection",`) ||
+		lines[2] != `{"call":3,"line":3,"result":{"rows":[{"id":1}]},"tool":"run_query"}` {
+		t.Errorf("json output:\n%s", out.String())
+	}
+}
+
+func TestWithConnection(t *testing.T) {
+	c := newDemoClient(t)
+	o := &options{timeout: 5 * time.Second, connection: "demo", tool: "list_tables"}
+	script := []scriptCall{
+		{Tool: "ping"},
+		{Tool: "describe_table", Arguments: map[string]any{"table": "customers"}},
+		{Tool: "run_query", Arguments: map[string]any{"connection_id": "other", "sql": "SELECT 1"}},
+	}
+	if err := withConnection(context.Background(), c, o, script); err != nil {
+		t.Fatal(err)
+	}
+	if o.args["connection_id"] != "demo" {
+		t.Errorf("call: %v", o.args)
+	}
+	if script[0].Arguments != nil {
+		t.Errorf("ping takes no connection_id: %v", script[0].Arguments)
+	}
+	if script[1].Arguments["connection_id"] != "demo" || script[1].Arguments["table"] != "customers" {
+		t.Errorf("describe_table: %v", script[1].Arguments)
+	}
+	if script[2].Arguments["connection_id"] != "other" {
+		t.Errorf("an explicit connection_id was replaced: %v", script[2].Arguments)
+	}
+}
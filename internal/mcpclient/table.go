@@ -0,0 +1,168 @@
+package mcpclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// rowSet is a tool result with a rows array of objects, such as
+// run_query's.
+type rowSet struct {
+	// cols are the columns in the order they first appear in the rows.
+	cols []string
+	rows []map[string]json.RawMessage
+	// others are the result's fields besides rows.
+	others map[string]json.RawMessage
+}
+
+// parseRows parses text, a tool result, as a rowSet and reports whether it
+// is one: a JSON object with a rows array of objects.
+func parseRows(text string) (*rowSet, bool) {
+	var result map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(text), &result); err != nil {
+		return nil, false
+	}
+	var raws []json.RawMessage
+	if err := json.Unmarshal(result["rows"], &raws); err != nil || result["rows"] == nil {
+		return nil, false
+	}
+	rs := &rowSet{rows: make([]map[string]json.RawMessage, len(raws))}
+	seen := make(map[string]bool)
+	for i, raw := range raws {
+		keys, row, ok := decodeRow(raw)
+		if !ok {
+			return nil, false
+		}
+		for _, k := range keys {
+			if !seen[k] {
+				seen[k] = true
+				rs.cols = append(rs.cols, k)
+			}
+		}
+		rs.rows[i] = row
+	}
+	delete(result, "rows")
+	rs.others = result
+	return rs, true
+}
+
+// printTable renders text, a tool result, as an aligned table if it is a
+// rowSet, and reports whether it did. The result's other fields are printed
+// below the table.
+func printTable(w io.Writer, text string) bool {
+	rs, ok := parseRows(text)
+	if !ok {
+		return false
+	}
+	cols := rs.cols
+	rows := make([]map[string]string, len(rs.rows))
+	for i, raw := range rs.rows {
+		rows[i] = make(map[string]string, len(raw))
+		for k, v := range raw {
+			rows[i][k] = formatCell(v)
+		}
+	}
+
+	widths := make([]int, len(cols))
+	for i, c := range cols {
+		widths[i] = utf8.RuneCountInString(c)
+		for _, row := range rows {
+			widths[i] = max(widths[i], utf8.RuneCountInString(row[c]))
+		}
+	}
+	rule := "+"
+	for _, n := range widths {
+		rule += strings.Repeat("-", n+2) + "+"
+	}
+	line := func(cell func(i int) string) {
+		var b strings.Builder
+		b.WriteString("|")
+		for i, n := range widths {
+			v := cell(i)
+			b.WriteString(" " + v + strings.Repeat(" ", n-utf8.RuneCountInString(v)) + " |")
+		}
+		fmt.Fprintln(w, b.String())
+	}
+	if len(cols) > 0 {
+		fmt.Fprintln(w, rule)
+		line(func(i int) string { return cols[i] })
+		fmt.Fprintln(w, rule)
+		for _, row := range rows {
+			line(func(i int) string { return row[cols[i]] })
+		}
+		fmt.Fprintln(w, rule)
+	}
+	if len(rows) == 1 {
+		fmt.Fprintln(w, "(1 row)")
+	} else {
+		fmt.Fprintf(w, "(%d rows)\n", len(rows))
+	}
+
+	others := make([]string, 0, len(rs.others))
+	for k := range rs.others {
+		others = append(others, k)
+	}
+	sort.Strings(others)
+	for _, k := range others {
+		fmt.Fprintf(w, "%s: %s\n", k, formatCell(rs.others[k]))
+	}
+	return true
+}
+
+// decodeRow decodes raw, a JSON object, into its keys in order and its
+// values.
+func decodeRow(raw json.RawMessage) ([]string, map[string]json.RawMessage, bool) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, nil, false
+	}
+	var keys []string
+	row := make(map[string]json.RawMessage)
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, nil, false
+		}
+		key, _ := tok.(string)
+		var v json.RawMessage
+		if err := dec.Decode(&v); err != nil {
+			return nil, nil, false
+		}
+		if _, dup := row[key]; !dup {
+			keys = append(keys, key)
+		}
+		row[key] = v
+	}
+	return keys, row, true
+}
+
+// formatCell formats a JSON value for a table cell: like cellText, with
+// NULL for null, on one line.
+func formatCell(v json.RawMessage) string {
+	if string(v) == "null" {
+		return "NULL"
+	}
+	return strings.NewReplacer("\r", `\r`, "\n", `\n`, "\t", `\t`).Replace(cellText(v))
+}
+
+// cellText returns a JSON value as text: strings without their quotes, null
+// as "", anything else as compact JSON.
+func cellText(v json.RawMessage) string {
+	var s string
+	switch {
+	case string(v) == "null":
+		return ""
+	case json.Unmarshal(v, &s) == nil:
+		return s
+	}
+	var b bytes.Buffer
+	if json.Compact(&b, v) == nil {
+		return b.String()
+	}
+	return string(v)
+}
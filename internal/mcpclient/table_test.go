@@ -0,0 +1,47 @@
+package mcpclient
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPrintTable(t *testing.T) {
+	var b strings.Builder
+	text := `{"rows":[{"id":1,"name":"Ada","note":null},{"id":22,"name":"Zoë\nB","tags":["x"]}],"truncated":true}`
+	if !printTable(&b, text) {
+		t.Fatal("rows were not rendered as a table")
+	}
+	want := `+----+--------+------+-------+
+| id | name   | note | tags  |
++----+--------+------+-------+
+| 1  | Ada    | NULL |       |
+| 22 | Zoë\nB |      | ["x"] |
++----+--------+------+-------+
+(2 rows)
+truncated: true
+`
+	if b.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestPrintTable_notRows(t *testing.T) {
+	for _, text := range []string{
+		`{"status":"ok"}`,
+		`{"rows":[1,2]}`,
+		`{"rows":"x"}`,
+		`not json`,
+	} {
+		var b strings.Builder
+		if printTable(&b, text) || b.Len() > 0 {
+			t.Errorf("%s: rendered as a table: %q", text, b.String())
+		}
+	}
+}
+
+func TestPrintTable_empty(t *testing.T) {
+	var b strings.Builder
+	if !printTable(&b, `{"rows":[]}`) || b.String() != "(0 rows)\n" {
+		t.Errorf("got %q", b.String())
+	}
+}
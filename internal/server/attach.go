@@ -0,0 +1,309 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Reconnect attempts made by Attach when the daemon is unreachable, e.g.
+// while it restarts.
+const (
+	attachRetries    = 5
+	attachRetryDelay = 200 * time.Millisecond
+)
+
+// Attach bridges an MCP client speaking the stdio transport on in and out
+// to a daemon serving TransportUnix on socket (DefaultSocketPath if empty),
+// until in is closed or ctx is cancelled. Each Attach is its own session on
+// the daemon; closing it ends the session but leaves the daemon, and its
+// database connections, running. If the daemon restarts, Attach reconnects
+// and replays the client's initialize handshake on a new session.
+func Attach(ctx context.Context, socket string, in io.Reader, out io.Writer) error {
+	if socket == "" {
+		socket = DefaultSocketPath()
+	}
+	b := &bridge{socket: socket, out: out, pending: make(map[string]chan *transport.JSONRPCResponse)}
+	defer b.close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	r := bufio.NewReader(in)
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			b.dispatch(ctx, line, &wg)
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// bridge relays JSON-RPC messages between a stdio client and the daemon.
+type bridge struct {
+	socket string
+
+	outMu sync.Mutex
+	out   io.Writer
+
+	connMu      sync.Mutex
+	conn        *transport.StreamableHTTP
+	initialize  *transport.JSONRPCRequest // replayed on reconnect
+	initialized *mcp.JSONRPCNotification  // replayed on reconnect
+
+	pendingMu sync.Mutex
+	pending   map[string]chan *transport.JSONRPCResponse // daemon requests awaiting the client
+}
+
+// message is any JSON-RPC message read from the client.
+type message struct {
+	ID     *mcp.RequestId           `json:"id,omitempty"`
+	Method string                   `json:"method"`
+	Params json.RawMessage          `json:"params,omitempty"`
+	Result json.RawMessage          `json:"result,omitempty"`
+	Error  *mcp.JSONRPCErrorDetails `json:"error,omitempty"`
+}
+
+// dispatch handles one line from the client. The handshake and
+// notifications are relayed in order; other requests run concurrently.
+func (b *bridge) dispatch(ctx context.Context, line []byte, wg *sync.WaitGroup) {
+	var msg message
+	if err := json.Unmarshal(line, &msg); err != nil {
+		b.write(mcp.NewJSONRPCError(mcp.NewRequestId(nil), mcp.PARSE_ERROR, "parse error", nil))
+		return
+	}
+	switch {
+	case msg.Method != "" && msg.ID != nil:
+		req := transport.JSONRPCRequest{JSONRPC: mcp.JSONRPC_VERSION, ID: *msg.ID, Method: msg.Method}
+		if len(msg.Params) > 0 {
+			req.Params = msg.Params
+		}
+		if msg.Method == string(mcp.MethodInitialize) {
+			b.forward(ctx, req)
+			b.connMu.Lock()
+			b.initialize = &req
+			b.connMu.Unlock()
+			return
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b.forward(ctx, req)
+		}()
+	case msg.Method != "":
+		var n mcp.JSONRPCNotification
+		if err := json.Unmarshal(line, &n); err != nil {
+			return
+		}
+		if n.Method == "notifications/initialized" {
+			b.connMu.Lock()
+			b.initialized = &n
+			b.connMu.Unlock()
+		}
+		if err := b.notify(ctx, n); err != nil {
+			slog.Warn("attach: forward notification", "method", n.Method, "err", err)
+		}
+	case msg.ID != nil:
+		b.pendingMu.Lock()
+		ch, ok := b.pending[msg.ID.String()]
+		delete(b.pending, msg.ID.String())
+		b.pendingMu.Unlock()
+		if ok {
+			ch <- &transport.JSONRPCResponse{JSONRPC: mcp.JSONRPC_VERSION, ID: *msg.ID, Result: msg.Result, Error: msg.Error}
+		}
+	}
+}
+
+// forward sends req to the daemon and writes its response, or an error
+// response, to the client.
+func (b *bridge) forward(ctx context.Context, req transport.JSONRPCRequest) {
+	var resp *transport.JSONRPCResponse
+	err := b.retry(ctx, req.Method != string(mcp.MethodInitialize), func(c *transport.StreamableHTTP) error {
+		var err error
+		resp, err = c.SendRequest(ctx, req)
+		return err
+	})
+	if err != nil {
+		b.write(mcp.NewJSONRPCError(req.ID, mcp.INTERNAL_ERROR, fmt.Sprintf("localdb-mcp daemon: %v", err), nil))
+		return
+	}
+	b.write(resp)
+}
+
+// notify sends n to the daemon.
+func (b *bridge) notify(ctx context.Context, n mcp.JSONRPCNotification) error {
+	return b.retry(ctx, true, func(c *transport.StreamableHTTP) error {
+		return c.SendNotification(ctx, n)
+	})
+}
+
+// retry runs fn on the daemon connection. If the daemon cannot be reached
+// or has forgotten the session and reconnect is set, the connection is
+// re-established (replaying the handshake) and fn tried again.
+func (b *bridge) retry(ctx context.Context, reconnect bool, fn func(*transport.StreamableHTTP) error) error {
+	var err error
+	for attempt := 0; attempt <= attachRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(attachRetryDelay * time.Duration(attempt)):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		var c *transport.StreamableHTTP
+		if c, err = b.connect(ctx); err == nil {
+			if err = fn(c); err == nil {
+				return nil
+			}
+		}
+		if !reconnect || !isDaemonGone(err) {
+			return err
+		}
+		b.drop(c)
+	}
+	return err
+}
+
+// isDaemonGone reports whether err means the daemon is down or restarted.
+func isDaemonGone(err error) bool {
+	var opErr *net.OpError
+	return errors.Is(err, transport.ErrSessionTerminated) || errors.As(err, &opErr)
+}
+
+// connect returns the daemon connection, opening it and replaying the
+// client's handshake if it was dropped.
+func (b *bridge) connect(ctx context.Context) (*transport.StreamableHTTP, error) {
+	b.connMu.Lock()
+	defer b.connMu.Unlock()
+	if b.conn != nil {
+		return b.conn, nil
+	}
+	socket := b.socket
+	c, err := transport.NewStreamableHTTP("http://localdb-mcp/mcp",
+		transport.WithHTTPBasicClient(&http.Client{Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socket)
+			},
+		}}),
+		transport.WithContinuousListening(),
+		transport.WithHTTPLogger(slogLogger{}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	c.SetNotificationHandler(func(n mcp.JSONRPCNotification) { b.write(n) })
+	c.SetRequestHandler(b.askClient)
+	if err := c.Start(ctx); err != nil {
+		return nil, err
+	}
+	if b.initialize != nil {
+		// Reconnecting: open a new session the way the client opened the
+		// first one. The daemon's answer was already seen by the client.
+		resp, err := c.SendRequest(ctx, *b.initialize)
+		if err != nil {
+			c.Close()
+			return nil, err
+		}
+		if resp.Error != nil {
+			c.Close()
+			return nil, fmt.Errorf("re-initialize: %s", resp.Error.Message)
+		}
+		if b.initialized != nil {
+			if err := c.SendNotification(ctx, *b.initialized); err != nil {
+				c.Close()
+				return nil, err
+			}
+		}
+		slog.Info("attach: reconnected to daemon", "socket", socket)
+	}
+	b.conn = c
+	return c, nil
+}
+
+// drop forgets c so the next call reconnects.
+func (b *bridge) drop(c *transport.StreamableHTTP) {
+	if c == nil {
+		return
+	}
+	b.connMu.Lock()
+	if b.conn == c {
+		b.conn = nil
+	}
+	b.connMu.Unlock()
+	c.Close()
+}
+
+// askClient relays a request from the daemon (e.g. an elicitation) to the
+// client and waits for its response.
+func (b *bridge) askClient(ctx context.Context, req transport.JSONRPCRequest) (*transport.JSONRPCResponse, error) {
+	ch := make(chan *transport.JSONRPCResponse, 1)
+	key := req.ID.String()
+	b.pendingMu.Lock()
+	b.pending[key] = ch
+	b.pendingMu.Unlock()
+	defer func() {
+		b.pendingMu.Lock()
+		delete(b.pending, key)
+		b.pendingMu.Unlock()
+	}()
+	b.write(req)
+	select {
+	case resp := <-ch:
+		return resp, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// write sends one message to the client.
+func (b *bridge) write(v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		slog.Error("attach: encode message", "err", err)
+		return
+	}
+	b.outMu.Lock()
+	defer b.outMu.Unlock()
+	if _, err := b.out.Write(append(data, '\n')); err != nil {
+		slog.Warn("attach: write to client", "err", err)
+	}
+}
+
+// close ends the daemon session.
+func (b *bridge) close() {
+	b.connMu.Lock()
+	c := b.conn
+	b.conn = nil
+	b.connMu.Unlock()
+	if c != nil {
+		c.Close()
+	}
+}
+
+// slogLogger routes the streamable HTTP client's logging to slog.
+type slogLogger struct{}
+
+func (slogLogger) Infof(format string, v ...any)  { slog.Debug(fmt.Sprintf(format, v...)) }
+func (slogLogger) Errorf(format string, v ...any) { slog.Warn(fmt.Sprintf(format, v...)) }
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// startDaemon serves a fresh server on socket until the returned stop
+// function is called.
+func startDaemon(t *testing.T, socket string) (stop func()) {
+	t.Helper()
+	s := server.NewMCPServer(ServerName, ServerVersion)
+	Register(s, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- Serve(ctx, s, ServeOptions{Transport: TransportUnix, Addr: socket, DrainTimeout: time.Second})
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if c, err := net.Dial("unix", socket); err == nil {
+			c.Close()
+			break
+		}
+		if time.Now().After(deadline) {
+			cancel()
+			t.Fatalf("daemon did not start: %v", <-done)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Serve: %v", err)
+		}
+	}
+}
+
+func TestAttach(t *testing.T) {
+	// Unix socket paths are short; t.TempDir can exceed the limit.
+	dir, err := os.MkdirTemp("", "ldb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "d.sock")
+
+	stop := startDaemon(t, socket)
+	if fi, err := os.Stat(socket); err != nil || fi.Mode().Perm() != 0o600 {
+		t.Errorf("socket mode: %v %v, want 0600", fi.Mode(), err)
+	}
+	if err := Serve(context.Background(), server.NewMCPServer(ServerName, ServerVersion),
+		ServeOptions{Transport: TransportUnix, Addr: socket}); err == nil {
+		t.Error("a second daemon on a live socket should fail")
+	}
+
+	clientIn, attachIn := io.Pipe()
+	attachOut, clientOut := io.Pipe()
+	done := make(chan error, 1)
+	go func() { done <- Attach(context.Background(), socket, clientIn, clientOut) }()
+	responses := bufio.NewScanner(attachOut)
+	call := func(id int, method, params string) map[string]any {
+		t.Helper()
+		fmt.Fprintf(attachIn, `{"jsonrpc":"2.0","id":%d,"method":%q,"params":%s}`+"\n", id, method, params)
+		for responses.Scan() {
+			var msg map[string]any
+			if err := json.Unmarshal(responses.Bytes(), &msg); err != nil {
+				t.Fatalf("decode %s: %v", responses.Bytes(), err)
+			}
+			if msg["id"] == float64(id) {
+				return msg
+			}
+		}
+		t.Fatalf("%s: no response: %v", method, responses.Err())
+		return nil
+	}
+	ping := func(id int) {
+		t.Helper()
+		msg := call(id, "tools/call", `{"name":"ping"}`)
+		result, _ := msg["result"].(map[string]any)
+		if result == nil || result["isError"] == true {
+			t.Errorf("ping through the bridge: %v", msg)
+		}
+	}
+
+	if msg := call(1, "initialize", `{"protocolVersion":"2025-06-18","capabilities":{},"clientInfo":{"name":"test","version":"1"}}`); msg["result"] == nil {
+		t.Fatalf("initialize: %v", msg)
+	}
+	fmt.Fprintln(attachIn, `{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+	ping(2)
+
+	// The bridge survives a daemon restart by opening a new session.
+	stop()
+	stop = startDaemon(t, socket)
+	defer stop()
+	ping(3)
+
+	attachIn.Close()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Attach: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Attach did not return after stdin closed")
+	}
+}
@@ -0,0 +1,366 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// auditTimeFormat is how times are stored in the audit database: UTC and
+// fixed width, so they sort and compare as text.
+const auditTimeFormat = "2006-01-02T15:04:05.000000Z"
+
+// Limits on the entries query_audit_log returns.
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+const auditSchema = `
+CREATE TABLE IF NOT EXISTS tool_calls (
+	id            INTEGER PRIMARY KEY,
+	time          TEXT    NOT NULL,
+	request_id    TEXT    NOT NULL,
+	session_id    TEXT    NOT NULL,
+	tool          TEXT    NOT NULL,
+	connection_id TEXT    NOT NULL,
+	sql           TEXT    NOT NULL,
+	duration_ms   INTEGER NOT NULL,
+	error_code    TEXT    NOT NULL
+);
+CREATE INDEX IF NOT EXISTS tool_calls_time ON tool_calls (time);
+CREATE INDEX IF NOT EXISTS tool_calls_tool ON tool_calls (tool, time);
+CREATE INDEX IF NOT EXISTS tool_calls_connection ON tool_calls (connection_id, time);
+CREATE INDEX IF NOT EXISTS tool_calls_failed ON tool_calls (time) WHERE error_code <> '';
+CREATE TABLE IF NOT EXISTS tool_call_tables (
+	call_id INTEGER NOT NULL REFERENCES tool_calls (id),
+	name    TEXT    NOT NULL
+);
+CREATE INDEX IF NOT EXISTS tool_call_tables_name ON tool_call_tables (name, call_id);
+CREATE INDEX IF NOT EXISTS tool_call_tables_call ON tool_call_tables (call_id);
+`
+
+// sqlTableRef matches the table after FROM, JOIN, INTO and UPDATE, with its
+// schema if qualified.
+var sqlTableRef = regexp.MustCompile(`(?i)\b(?:from|join|into|update)\s+((?:["\x60\[]?[\w$]+["\x60\]]?\s*\.\s*)?["\x60\[]?[\w$]+["\x60\]]?)`)
+
+// auditLog writes every tool call to a SQLite database: when it ran, in
+// which session, on which connection and tables, how long it took and the
+// code it failed with. Like connection_stats it stores error codes, not
+// messages, since driver messages may echo the DSN; row values are not
+// stored either.
+type auditLog struct {
+	db *sql.DB
+}
+
+// openAuditLog opens the audit database at path, creating it and its
+// directory if needed.
+func openAuditLog(path string) (*auditLog, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	// One connection serializes the writes; WAL lets other processes, such
+	// as the sqlite3 shell, read the log meanwhile.
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(auditSchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &auditLog{db: db}, nil
+}
+
+func (a *auditLog) close() error {
+	return a.db.Close()
+}
+
+// auditRecord is one tool call to write to the audit log.
+type auditRecord struct {
+	time         time.Time
+	requestID    string
+	sessionID    string
+	tool         string
+	connectionID string
+	sql          string
+	tables       []string
+	duration     time.Duration
+	errorCode    string
+}
+
+// middleware records every tool call, including those refused by the
+// limits further in, once it returns. A call that cannot be recorded is
+// logged but not failed.
+func (a *auditLog) middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Searching the log touches no database and would crowd it out.
+		if request.Params.Name == "query_audit_log" {
+			return next(ctx, request)
+		}
+		start := time.Now()
+		res, err := next(ctx, request)
+		rec := auditRecord{
+			time:         start,
+			requestID:    RequestID(ctx),
+			sessionID:    sessionID(ctx),
+			tool:         request.Params.Name,
+			connectionID: request.GetString("connection_id", ""),
+			sql:          request.GetString("sql", ""),
+			duration:     time.Since(start),
+		}
+		if table := request.GetString("table", ""); table != "" {
+			if schema := request.GetString("schema", ""); schema != "" {
+				table = schema + "." + table
+			}
+			rec.tables = append(rec.tables, table)
+		}
+		if rec.sql != "" {
+			rec.tables = append(rec.tables, sqlTables(rec.sql)...)
+		}
+		switch {
+		case err != nil:
+			rec.errorCode = CodeInternal
+		case res != nil && res.IsError:
+			rec.errorCode = resultCode(res)
+		}
+		if werr := a.record(context.WithoutCancel(ctx), rec); werr != nil {
+			slog.Error("audit log write failed", "request_id", rec.requestID, "tool", rec.tool, "err", werr)
+		}
+		return res, err
+	}
+}
+
+// record writes rec and the tables it touched.
+func (a *auditLog) record(ctx context.Context, rec auditRecord) error {
+	tx, err := a.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO tool_calls (time, request_id, session_id, tool, connection_id, sql, duration_ms, error_code)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		rec.time.UTC().Format(auditTimeFormat), rec.requestID, rec.sessionID, rec.tool,
+		rec.connectionID, rec.sql, rec.duration.Milliseconds(), rec.errorCode)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	seen := make(map[string]bool)
+	for _, t := range rec.tables {
+		t = normalizeTableName(t)
+		if t == "" || seen[t] {
+			continue
+		}
+		seen[t] = true
+		if _, err := tx.ExecContext(ctx, `INSERT INTO tool_call_tables (call_id, name) VALUES (?, ?)`, id, t); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// sqlTables returns the tables statement sql reads or writes, as far as a
+// pattern can tell: comments and string literals are ignored, and tables
+// reached through views or functions are missed.
+func sqlTables(sql string) []string {
+	sql = sqlStringLiteral.ReplaceAllString(stripSQLComments(sql), "''")
+	var tables []string
+	for _, m := range sqlTableRef.FindAllStringSubmatch(sql, -1) {
+		tables = append(tables, m[1])
+	}
+	return tables
+}
+
+// normalizeTableName lower-cases name and drops its identifier quotes and
+// the spaces around a schema dot, so "Sales" . [Orders] is stored as
+// sales.orders.
+func normalizeTableName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch r {
+		case '"', '`', '[', ']', ' ', '\t', '\n', '\r':
+			return -1
+		}
+		return r
+	}, name)
+	return strings.ToLower(name)
+}
+
+// AuditFilter narrows query_audit_log and AuditReader.Query; zero fields
+// match everything.
+type AuditFilter struct {
+	Tool         string
+	ConnectionID string
+	// Table matches calls that touched the table, with or without schema.
+	Table      string
+	FailedOnly bool
+	Since      time.Time
+	Until      time.Time
+	// AfterID matches the entries recorded after the one with that ID.
+	AfterID int64
+	Limit   int
+}
+
+// AuditEntry is one tool call in query_audit_log.
+type AuditEntry struct {
+	ID           int64    `json:"id"`
+	Time         string   `json:"time"`
+	RequestID    string   `json:"request_id"`
+	SessionID    string   `json:"session_id,omitempty"`
+	Tool         string   `json:"tool"`
+	ConnectionID string   `json:"connection_id,omitempty"`
+	Tables       []string `json:"tables,omitempty"`
+	SQL          string   `json:"sql,omitempty"`
+	DurationMS   int64    `json:"duration_ms"`
+	ErrorCode    string   `json:"error_code,omitempty"`
+}
+
+// AuditLogOutput is the result of query_audit_log, newest entry first.
+type AuditLogOutput struct {
+	Entries []AuditEntry `json:"entries"`
+}
+
+// query returns the entries matching f, newest first.
+func (a *auditLog) query(ctx context.Context, f AuditFilter) ([]AuditEntry, error) {
+	var where []string
+	var args []any
+	if f.Tool != "" {
+		where = append(where, "tool = ?")
+		args = append(args, f.Tool)
+	}
+	if f.ConnectionID != "" {
+		where = append(where, "connection_id = ?")
+		args = append(args, f.ConnectionID)
+	}
+	if f.Table != "" {
+		t := normalizeTableName(f.Table)
+		where = append(where, "id IN (SELECT call_id FROM tool_call_tables WHERE name = ? OR name LIKE ? ESCAPE '\\')")
+		args = append(args, t, "%."+escapeLike(t))
+	}
+	if f.FailedOnly {
+		where = append(where, "error_code <> ''")
+	}
+	if !f.Since.IsZero() {
+		where = append(where, "time >= ?")
+		args = append(args, f.Since.UTC().Format(auditTimeFormat))
+	}
+	if !f.Until.IsZero() {
+		where = append(where, "time < ?")
+		args = append(args, f.Until.UTC().Format(auditTimeFormat))
+	}
+	if f.AfterID > 0 {
+		where = append(where, "id > ?")
+		args = append(args, f.AfterID)
+	}
+	q := `SELECT id, time, request_id, session_id, tool, connection_id, sql, duration_ms, error_code,
+		(SELECT group_concat(name, ',') FROM tool_call_tables WHERE call_id = tool_calls.id)
+		FROM tool_calls`
+	if len(where) > 0 {
+		q += " WHERE " + strings.Join(where, " AND ")
+	}
+	q += " ORDER BY id DESC LIMIT ?"
+	args = append(args, f.Limit)
+
+	rows, err := a.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	entries := []AuditEntry{}
+	for rows.Next() {
+		var e AuditEntry
+		var tables sql.NullString
+		if err := rows.Scan(&e.ID, &e.Time, &e.RequestID, &e.SessionID, &e.Tool, &e.ConnectionID,
+			&e.SQL, &e.DurationMS, &e.ErrorCode, &tables); err != nil {
+			return nil, err
+		}
+		if tables.String != "" {
+			e.Tables = strings.Split(tables.String, ",")
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// AuditReader reads an audit database, such as the one a running server
+// writes to, for localdb-mcp audit.
+type AuditReader struct {
+	log *auditLog
+}
+
+// OpenAuditReader opens the audit database at path read-only. The server
+// writing to it may keep running.
+func OpenAuditReader(path string) (*AuditReader, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite", "file:"+path+"?mode=ro&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &AuditReader{log: &auditLog{db: db}}, nil
+}
+
+// Query returns the entries matching f, newest first. f.Limit defaults
+// to query_audit_log's and is not capped.
+func (r *AuditReader) Query(ctx context.Context, f AuditFilter) ([]AuditEntry, error) {
+	if f.Limit <= 0 {
+		f.Limit = defaultAuditLimit
+	}
+	return r.log.query(ctx, f)
+}
+
+// Close closes the database.
+func (r *AuditReader) Close() error {
+	return r.log.close()
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// auditFilterFromArgs reads the arguments of query_audit_log.
+func auditFilterFromArgs(args map[string]any) (AuditFilter, error) {
+	f := AuditFilter{Limit: defaultAuditLimit}
+	f.Tool, _ = args["tool"].(string)
+	f.ConnectionID, _ = args["connection"].(string)
+	f.Table, _ = args["table"].(string)
+	f.FailedOnly, _ = args["failed_only"].(bool)
+	for name, t := range map[string]*time.Time{"since": &f.Since, "until": &f.Until} {
+		s, _ := args[name].(string)
+		if s == "" {
+			continue
+		}
+		v, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return f, fmt.Errorf("%s must be an RFC 3339 time such as 2024-05-01T12:00:00Z", name)
+		}
+		*t = v
+	}
+	if n, ok := args["limit"].(float64); ok {
+		if n < 1 || n > maxAuditLimit {
+			return f, fmt.Errorf("limit must be between 1 and %d", maxAuditLimit)
+		}
+		f.Limit = int(n)
+	}
+	return f, nil
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestSQLTables(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want []string
+	}{
+		{"SELECT * FROM users", []string{"users"}},
+		{`SELECT o.id FROM "Sales" . [Orders] o JOIN customers c ON c.id = o.customer_id`, []string{"sales.orders", "customers"}},
+		{"SELECT * FROM (SELECT id FROM app.items) x", []string{"app.items"}},
+		{"SELECT 'from secrets' AS note -- FROM hidden\nFROM notes", []string{"notes"}},
+		{"SELECT 1", nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, name := range sqlTables(tt.sql) {
+			got = append(got, normalizeTableName(name))
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("sqlTables(%q) = %v, want %v", tt.sql, got, tt.want)
+		}
+	}
+}
+
+func TestQueryAuditLog(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "app.db")
+	sqlDB, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDB.Close()
+	if _, err := sqlDB.Exec("CREATE TABLE orders (id INTEGER PRIMARY KEY, total INTEGER)"); err != nil {
+		t.Fatal(err)
+	}
+	auditPath := filepath.Join(dir, "audit", "audit.db")
+	t.Setenv(config.EnvAuditDB, "")
+	c := newTestClient(t, "connections:\n  sqlite: \""+dbPath+"\"\naudit_db: "+auditPath+"\n")
+	call := func(name string, args map[string]any) *mcp.CallToolResult {
+		t.Helper()
+		res, err := c.CallTool(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{Name: name, Arguments: args}})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		return res
+	}
+	search := func(args map[string]any) []AuditEntry {
+		t.Helper()
+		res := call("query_audit_log", args)
+		var out AuditLogOutput
+		if res.IsError || json.Unmarshal([]byte(textContent(res)), &out) != nil {
+			t.Fatalf("query_audit_log(%v): %s", args, textContent(res))
+		}
+		return out.Entries
+	}
+
+	start := time.Now().Add(-time.Second)
+	call("ping", nil)
+	call("insert_test_row", map[string]any{"connection_id": "sqlite", "table": "orders", "row": map[string]any{"total": 5}})
+	call("run_query", map[string]any{"connection_id": "sqlite", "sql": "SELECT o.total FROM orders o"})
+	if res := call("run_query", map[string]any{"connection_id": "sqlite", "sql": "SELECT * FROM missing"}); !res.IsError {
+		t.Fatal("query of a missing table should fail")
+	}
+
+	all := search(nil)
+	if len(all) != 4 || all[0].Tool != "run_query" || all[3].Tool != "ping" {
+		t.Fatalf("entries = %+v, want the 4 calls newest first", all)
+	}
+	if all[0].RequestID == "" || all[0].ConnectionID != "sqlite" || all[0].ErrorCode == "" {
+		t.Errorf("failed query entry = %+v", all[0])
+	}
+
+	orders := search(map[string]any{"table": "ORDERS"})
+	if len(orders) != 2 || orders[0].SQL != "SELECT o.total FROM orders o" || orders[1].Tool != "insert_test_row" {
+		t.Errorf("calls touching orders = %+v", orders)
+	}
+	if failed := search(map[string]any{"failed_only": true}); len(failed) != 1 || !slices.Equal(failed[0].Tables, []string{"missing"}) {
+		t.Errorf("failed calls = %+v", failed)
+	}
+	if got := search(map[string]any{"tool": "run_query", "connection": "sqlite", "limit": 1}); len(got) != 1 || got[0].ID != all[0].ID {
+		t.Errorf("last run_query = %+v", got)
+	}
+	if got := search(map[string]any{"since": start.UTC().Format(time.RFC3339)}); len(got) != 4 {
+		t.Errorf("%d calls since the start, want 4", len(got))
+	}
+	if got := search(map[string]any{"until": start.UTC().Format(time.RFC3339)}); len(got) != 0 {
+		t.Errorf("%d calls before the start, want 0", len(got))
+	}
+	if res := call("query_audit_log", map[string]any{"since": "yesterday"}); resultCode(res) != CodeValidationFailed {
+		t.Errorf("bad since: %s", textContent(res))
+	}
+}
@@ -0,0 +1,180 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/codegen"
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// codegenTable is a table described for the tools that generate schemas
+// and code from it.
+type codegenTable struct {
+	engine string // connection type, for codegen.MapType
+	schema string
+	name   string
+	cols   []db.ColumnInfo
+}
+
+// describeForCodegen describes the table named by the connection_id, schema
+// and table arguments after the checks describe_table runs. A table without
+// columns does not exist. res is set if the call fails.
+func describeForCodegen(ctx context.Context, cfg *config.Config, mgr *db.Manager, args map[string]any) (t codegenTable, res *mcp.CallToolResult) {
+	if _, ok := args["table"].(string); !ok {
+		return t, invalidArgs("table is required")
+	}
+	tables, res := describeAllForCodegen(ctx, cfg, mgr, args)
+	if res != nil {
+		return t, res
+	}
+	return tables[0], nil
+}
+
+// describeAllForCodegen is describeForCodegen with the table argument
+// optional: without it, every table of the schema is described, in name
+// order, skipping the system tables list_tables would refuse.
+func describeAllForCodegen(ctx context.Context, cfg *config.Config, mgr *db.Manager, args map[string]any) (tables []codegenTable, res *mcp.CallToolResult) {
+	connID, ok := args["connection_id"].(string)
+	if !ok {
+		return nil, invalidArgs("connection_id is required")
+	}
+	table, _ := args["table"].(string)
+	schema, _ := args["schema"].(string)
+	tables, schema, res = describeSchema(ctx, cfg, mgr, connID, schema, table)
+	if res != nil {
+		return nil, res
+	}
+	if len(tables) == 0 {
+		return nil, errorResult(ToolError{
+			Code:    CodeNotFound,
+			Message: fmt.Sprintf("no tables in schema %q on connection %q", schema, connID),
+			Hint:    "pass schema, or check list_tables for the schema",
+		}, nil)
+	}
+	return tables, nil
+}
+
+// describeSchema describes table of schema on connID, or every table of
+// the schema if table is empty, as describeAllForCodegen does, but with no
+// error for a schema without tables. It returns the schema defaulted as
+// the connection says.
+func describeSchema(ctx context.Context, cfg *config.Config, mgr *db.Manager, connID, schema, table string) (tables []codegenTable, resolved string, res *mcp.CallToolResult) {
+	schema = schemaOrDefault(cfg, connID, schema)
+	if res := checkSchema(cfg, connID, schema); res != nil {
+		return nil, schema, res
+	}
+	if res := checkSchemaLock(cfg, connID, schema); res != nil {
+		return nil, schema, res
+	}
+	if res := checkSystemTable(cfg, connID, schema, table); res != nil {
+		return nil, schema, res
+	}
+	driver, err := mgr.Driver(ctx, connID)
+	if err != nil {
+		return nil, schema, toolErrorResult(err)
+	}
+	names := []string{table}
+	if table == "" {
+		if names, err = driver.ListTables(ctx, schema); err != nil {
+			return nil, schema, toolErrorResult(err)
+		}
+		slices.Sort(names)
+	}
+	engine, _ := cfg.Type(connID)
+	for _, name := range names {
+		if table == "" && checkSystemTable(cfg, connID, schema, name) != nil {
+			continue
+		}
+		cols, err := driver.DescribeTable(ctx, schema, name)
+		if err != nil {
+			return nil, schema, toolErrorResult(err)
+		}
+		if len(cols) == 0 {
+			if table == "" {
+				continue // dropped since it was listed
+			}
+			return nil, schema, errorResult(ToolError{
+				Code:    CodeNotFound,
+				Message: fmt.Sprintf("table %q not found on connection %q", table, connID),
+				Hint:    "list_tables shows the tables of a schema",
+			}, nil)
+		}
+		tables = append(tables, codegenTable{engine: engine, schema: schema, name: name, cols: cols})
+	}
+	return tables, schema, nil
+}
+
+// keysForCodegen returns the foreign keys and indexes of schema on connID,
+// for the generators that declare relations. A driver that cannot list
+// them gives none.
+func keysForCodegen(ctx context.Context, mgr *db.Manager, connID, schema string) ([]db.ForeignKey, []db.Index, error) {
+	driver, err := mgr.Driver(ctx, connID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var fks []db.ForeignKey
+	var idxs []db.Index
+	if l, ok := db.Unwrap(driver).(db.ForeignKeyLister); ok {
+		if fks, err = l.ForeignKeys(ctx, schema); err != nil {
+			return nil, nil, err
+		}
+	}
+	if l, ok := db.Unwrap(driver).(db.IndexLister); ok {
+		if idxs, err = l.Indexes(ctx, schema); err != nil {
+			return nil, nil, err
+		}
+	}
+	return fks, idxs, nil
+}
+
+// codegenInput converts described tables for the codegen package.
+func codegenInput(tables []codegenTable) []codegen.Table {
+	out := make([]codegen.Table, len(tables))
+	for i, t := range tables {
+		out[i] = codegen.Table{Name: t.name, Columns: t.cols}
+	}
+	return out
+}
+
+// TableJSONSchemaOutput is the result of table_json_schema.
+type TableJSONSchemaOutput struct {
+	Table string `json:"table"`
+	// Schema is a JSON Schema (draft 2020-12) document for a row of the
+	// table, as run_query returns it.
+	Schema json.RawMessage `json:"schema"`
+}
+
+// GenerateCodeOutput is the result of the tools generating code from a
+// table.
+type GenerateCodeOutput struct {
+	// Table is the table the code is for; empty if it is for all the
+	// tables of a schema.
+	Table string `json:"table,omitempty"`
+	Code  string `json:"code,omitempty"`
+	// Files holds the generated files by name, for the generators that
+	// write several instead of Code.
+	Files map[string]string `json:"files,omitempty"`
+}
+
+// stringList converts a tool argument holding a JSON array of strings.
+func stringList(v any) ([]string, error) {
+	items, ok := v.([]any)
+	if !ok {
+		return nil, errors.New("want an array of strings")
+	}
+	out := make([]string, len(items))
+	for i, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("item %d is not a string", i)
+		}
+		out[i] = s
+	}
+	return out, nil
+}
@@ -0,0 +1,211 @@
+package server
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestTableJSONSchemaTool(t *testing.T) {
+	call := demoCaller(t)
+	res := call("table_json_schema", map[string]any{"table": "customers"})
+	if res.IsError {
+		t.Fatalf("table_json_schema: %s", textContent(res))
+	}
+	var out TableJSONSchemaOutput
+	if err := json.Unmarshal([]byte(textContent(res)), &out); err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Type       string                     `json:"type"`
+		Properties map[string]json.RawMessage `json:"properties"`
+		Required   []string                   `json:"required"`
+	}
+	if err := json.Unmarshal(out.Schema, &doc); err != nil {
+		t.Fatalf("schema: %v", err)
+	}
+	if doc.Type != "object" || string(doc.Properties["id"]) != `{"type":"integer"}` || string(doc.Properties["city"]) != `{"type":["string","null"]}` {
+		t.Errorf("schema = %s", out.Schema)
+	}
+	if !strings.Contains(strings.Join(doc.Required, ","), "email") {
+		t.Errorf("required = %v, lacks email", doc.Required)
+	}
+
+	res = call("table_json_schema", map[string]any{"table": "nope"})
+	if !res.IsError || !strings.Contains(textContent(res), "not found") {
+		t.Errorf("unknown table: %s", textContent(res))
+	}
+}
+
+func TestGenerateGoStructTool(t *testing.T) {
+	call := demoCaller(t)
+	res := call("generate_go_struct", map[string]any{"table": "order_items", "tags": []any{"json", "gorm"}, "nullable": "pointer"})
+	if res.IsError {
+		t.Fatalf("generate_go_struct: %s", textContent(res))
+	}
+	var out GenerateCodeOutput
+	if err := json.Unmarshal([]byte(textContent(res)), &out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"package models", "type OrderItem struct", "OrderID", `gorm:"column:order_id`} {
+		if !strings.Contains(out.Code, want) {
+			t.Errorf("code lacks %q:\n%s", want, out.Code)
+		}
+	}
+
+	res = call("generate_go_struct", map[string]any{"table": "orders", "style": "gorm"})
+	if res.IsError {
+		t.Fatalf("generate_go_struct gorm: %s", textContent(res))
+	}
+	out = GenerateCodeOutput{}
+	if err := json.Unmarshal([]byte(textContent(res)), &out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`gorm:"foreignKey:CustomerID;references:ID"`, "OrderItems []OrderItem", "func (Order) TableName() string"} {
+		if !strings.Contains(out.Code, want) {
+			t.Errorf("gorm code lacks %q:\n%s", want, out.Code)
+		}
+	}
+
+	res = call("generate_go_struct", map[string]any{"table": "orders", "style": "sqlc"})
+	if res.IsError {
+		t.Fatalf("generate_go_struct sqlc: %s", textContent(res))
+	}
+	out = GenerateCodeOutput{}
+	if err := json.Unmarshal([]byte(textContent(res)), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Code != "" || !strings.Contains(out.Files["schema.sql"], "CREATE TABLE orders (") ||
+		!strings.Contains(out.Files["query.sql"], "-- name: GetOrder :one") || !strings.Contains(out.Files["sqlc.yaml"], `engine: "sqlite"`) {
+		t.Errorf("sqlc output = %+v", out)
+	}
+
+	for _, args := range []map[string]any{
+		{"table": "order_items", "tags": "json"},
+		{"table": "order_items", "package": "my-models"},
+		{"table": "order_items", "style": "ent"},
+	} {
+		if res := call("generate_go_struct", args); !res.IsError {
+			t.Errorf("generate_go_struct %v succeeded", args)
+		}
+	}
+}
+
+func TestGenerateTypeScriptTypesTool(t *testing.T) {
+	call := demoCaller(t)
+	res := call("generate_typescript_types", map[string]any{})
+	if res.IsError {
+		t.Fatalf("generate_typescript_types: %s", textContent(res))
+	}
+	var out GenerateCodeOutput
+	if err := json.Unmarshal([]byte(textContent(res)), &out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"export interface Customer {", "export interface OrderItem {", "  city: string | null;"} {
+		if !strings.Contains(out.Code, want) {
+			t.Errorf("code lacks %q:\n%s", want, out.Code)
+		}
+	}
+
+	res = call("generate_typescript_types", map[string]any{"table": "products", "format": "zod"})
+	if res.IsError {
+		t.Fatalf("generate_typescript_types: %s", textContent(res))
+	}
+	out = GenerateCodeOutput{}
+	if err := json.Unmarshal([]byte(textContent(res)), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Table != "products" || !strings.Contains(out.Code, "export const Product = z.object({") || strings.Contains(out.Code, "Customer") {
+		t.Errorf("zod products = %+v", out)
+	}
+
+	if res := call("generate_typescript_types", map[string]any{"format": "flow"}); !res.IsError {
+		t.Error("generate_typescript_types with format flow succeeded")
+	}
+}
+
+func TestGeneratePrismaSchemaTool(t *testing.T) {
+	call := demoCaller(t)
+	res := call("generate_prisma_schema", map[string]any{})
+	if res.IsError {
+		t.Fatalf("generate_prisma_schema: %s", textContent(res))
+	}
+	var out GenerateCodeOutput
+	if err := json.Unmarshal([]byte(textContent(res)), &out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`provider = "sqlite"`,
+		"model OrderItem {",
+		"@@id([orderId, productId])",
+		"@unique\n",
+		"@relation(fields: [customerId], references: [id]",
+		"orderItems OrderItem[]",
+	} {
+		if !strings.Contains(out.Code, want) {
+			t.Errorf("schema lacks %q:\n%s", want, out.Code)
+		}
+	}
+}
+
+func TestGenerateGraphQLSDLTool(t *testing.T) {
+	call := demoCaller(t)
+	res := call("generate_graphql_sdl", map[string]any{"field_case": "snake"})
+	if res.IsError {
+		t.Fatalf("generate_graphql_sdl: %s", textContent(res))
+	}
+	var out GenerateCodeOutput
+	if err := json.Unmarshal([]byte(textContent(res)), &out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"type OrderItem {",
+		"  customer_id: Int!\n",
+		"  customer: Customer!\n",
+		"  order_items: [OrderItem!]!\n",
+		"  order_item(order_id: Int!, product_id: Int!): OrderItem\n",
+	} {
+		if !strings.Contains(out.Code, want) {
+			t.Errorf("SDL lacks %q:\n%s", want, out.Code)
+		}
+	}
+
+	if res := call("generate_graphql_sdl", map[string]any{"field_case": "kebab"}); !res.IsError {
+		t.Error("generate_graphql_sdl with field_case kebab succeeded")
+	}
+}
+
+func TestGenerateERDTool(t *testing.T) {
+	call := demoCaller(t)
+	res := call("generate_erd", map[string]any{})
+	if res.IsError {
+		t.Fatalf("generate_erd: %s", textContent(res))
+	}
+	var out GenerateCodeOutput
+	if err := json.Unmarshal([]byte(textContent(res)), &out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"erDiagram\n", "    products {\n", "INTEGER customer_id FK\n", `customers ||..o{ orders : "customer_id"`, `orders ||--o{ order_items : "order_id"`} {
+		if !strings.Contains(out.Code, want) {
+			t.Errorf("ERD lacks %q:\n%s", want, out.Code)
+		}
+	}
+
+	res = call("generate_erd", map[string]any{"table": "customers", "depth": 1, "columns": false})
+	if res.IsError {
+		t.Fatalf("generate_erd: %s", textContent(res))
+	}
+	out = GenerateCodeOutput{}
+	if err := json.Unmarshal([]byte(textContent(res)), &out); err != nil {
+		t.Fatal(err)
+	}
+	if want := "erDiagram\n    customers\n    orders\n    customers ||..o{ orders : \"customer_id\"\n"; out.Code != want {
+		t.Errorf("ERD of customers =\n%s\nwant\n%s", out.Code, want)
+	}
+
+	for _, args := range []map[string]any{{"table": "nope"}, {"table": "customers", "depth": -1}} {
+		if res := call("generate_erd", args); !res.IsError {
+			t.Errorf("generate_erd %v succeeded", args)
+		}
+	}
+}
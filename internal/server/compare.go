@@ -0,0 +1,171 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/compare"
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// CompareSchemasOutput is the result of compare_schemas.
+type CompareSchemasOutput struct {
+	ConnectionA string `json:"connection_a"`
+	SchemaA     string `json:"schema_a,omitempty"`
+	ConnectionB string `json:"connection_b"`
+	SchemaB     string `json:"schema_b,omitempty"`
+	compare.SchemaDiff
+}
+
+// compareSide describes schema of connID, with its indexes, for
+// compare.Schemas. res is set if the call fails.
+func compareSide(ctx context.Context, cfg *config.Config, mgr *db.Manager, connID, schema string) (side compare.Schema, resolved string, res *mcp.CallToolResult) {
+	tables, resolved, res := describeSchema(ctx, cfg, mgr, connID, schema, "")
+	if res != nil {
+		return side, resolved, res
+	}
+	_, idxs, err := keysForCodegen(ctx, mgr, connID, resolved)
+	if err != nil {
+		return side, resolved, toolErrorResult(err)
+	}
+	side.Engine, _ = cfg.Type(connID)
+	side.Tables, side.Indexes = codegenInput(tables), idxs
+	return side, resolved, nil
+}
+
+// Limits of compare_table_data.
+const (
+	DefaultCompareMaxRows = 10000
+	MaxCompareMaxRows     = 100000
+	DefaultCompareSample  = 10
+	MaxCompareSample      = 100
+)
+
+// CompareTableDataOutput is the result of compare_table_data.
+type CompareTableDataOutput struct {
+	ConnectionA string `json:"connection_a"`
+	TableA      string `json:"table_a"`
+	ConnectionB string `json:"connection_b"`
+	TableB      string `json:"table_b"`
+	// Key are the columns rows are matched by.
+	Key []string `json:"key"`
+	// Columns are the columns compared: those of both tables, as A names
+	// them. ColumnsOnlyInA and ColumnsOnlyInB are left out.
+	Columns        []string `json:"columns"`
+	ColumnsOnlyInA []string `json:"columns_only_in_a,omitempty"`
+	ColumnsOnlyInB []string `json:"columns_only_in_b,omitempty"`
+	compare.DataDiff
+}
+
+// compareTable is a table compare_table_data reads.
+type compareTable struct {
+	connID, schema, name string
+	cols                 []db.ColumnInfo
+}
+
+// describeCompareTable describes table of schema on connID for
+// compare_table_data. res is set if the call fails.
+func describeCompareTable(ctx context.Context, cfg *config.Config, mgr *db.Manager, connID, schema, table string) (t compareTable, res *mcp.CallToolResult) {
+	if res := checkPermission(cfg, connID, config.OpSelect, "", ""); res != nil {
+		return t, res
+	}
+	tables, schema, res := describeSchema(ctx, cfg, mgr, connID, schema, table)
+	if res != nil {
+		return t, res
+	}
+	return compareTable{connID: connID, schema: schema, name: table, cols: tables[0].cols}, nil
+}
+
+// column returns the name of t's column name, matched case-insensitively.
+func (t compareTable) column(name string) (string, bool) {
+	for _, c := range t.cols {
+		if strings.EqualFold(c.Name, name) {
+			return c.Name, true
+		}
+	}
+	return "", false
+}
+
+// readRows reads at most maxRows rows of t ordered by the columns key,
+// through its row filter, if any. More rows are an error.
+func (t compareTable) readRows(ctx context.Context, cfg *config.Config, mgr *db.Manager, key []string, maxRows int) ([]map[string]any, *mcp.CallToolResult) {
+	typ, _ := cfg.Type(t.connID)
+	driver, err := mgr.Driver(ctx, t.connID)
+	if err != nil {
+		return nil, toolErrorResult(err)
+	}
+	rows, res := filteredQuerier(ctx, cfg, driver, t.connID)(db.OrderedQuery(typ, t.schema, t.name, key, maxRows+1), nil)
+	if res != nil {
+		return nil, res
+	}
+	if len(rows) > maxRows {
+		return nil, errorResult(ToolError{
+			Code:    CodeResultTooLarge,
+			Message: fmt.Sprintf("table %q on connection %q has more than %d rows", t.name, t.connID, maxRows),
+			Hint:    fmt.Sprintf("raise max_rows (at most %d), or compare with run_query on a subset", MaxCompareMaxRows),
+		}, nil)
+	}
+	return rows, nil
+}
+
+// compareTableData compares the rows of tables a and b, matched by the
+// columns key (a's primary key if empty).
+func compareTableData(ctx context.Context, cfg *config.Config, mgr *db.Manager, a, b compareTable, key []string, maxRows, sample int) *mcp.CallToolResult {
+	out := CompareTableDataOutput{ConnectionA: a.connID, TableA: a.name, ConnectionB: b.connID, TableB: b.name, Columns: []string{}}
+	for _, c := range a.cols {
+		if _, ok := b.column(c.Name); ok {
+			out.Columns = append(out.Columns, c.Name)
+		} else {
+			out.ColumnsOnlyInA = append(out.ColumnsOnlyInA, c.Name)
+		}
+	}
+	for _, c := range b.cols {
+		if _, ok := a.column(c.Name); !ok {
+			out.ColumnsOnlyInB = append(out.ColumnsOnlyInB, c.Name)
+		}
+	}
+
+	if len(key) == 0 {
+		for _, c := range a.cols {
+			if c.IsPK {
+				key = append(key, c.Name)
+			}
+		}
+		if len(key) == 0 {
+			return invalidArgs(fmt.Sprintf("table %q has no primary key; pass key", a.name))
+		}
+	}
+	keyA := make([]string, len(key))
+	keyB := make([]string, len(key))
+	for i, k := range key {
+		var okA, okB bool
+		keyA[i], okA = a.column(k)
+		keyB[i], okB = b.column(k)
+		if !okA || !okB {
+			return invalidArgs(fmt.Sprintf("key column %q is not in both tables", k))
+		}
+	}
+	out.Key = keyA
+
+	rowsA, res := a.readRows(ctx, cfg, mgr, keyA, maxRows)
+	if res != nil {
+		return res
+	}
+	rowsB, res := b.readRows(ctx, cfg, mgr, keyB, maxRows)
+	if res != nil {
+		return res
+	}
+	diff, err := compare.Rows(rowsA, rowsB, keyA, out.Columns, sample)
+	if err != nil {
+		return invalidArgs(err.Error() + "; pass key naming unique columns")
+	}
+	out.DataDiff = diff
+	res, err = mcp.NewToolResultJSON(out)
+	if err != nil {
+		return toolErrorResult(err)
+	}
+	return res
+}
@@ -0,0 +1,145 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// compareCaller serves a config with a demo connection, a denied "locked"
+// one, and a "sqlite" one holding a customers table that differs from the
+// demo's and a customers_backup table, and calls tool on it.
+func compareCaller(t *testing.T, tool string) func(args map[string]any) *mcp.CallToolResult {
+	t.Helper()
+	ctx := context.Background()
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "app.db")
+	sqlDB, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDB.Close()
+	if _, err := sqlDB.Exec(`CREATE TABLE customers (id INTEGER PRIMARY KEY, name TEXT, email TEXT NOT NULL, phone TEXT);
+		INSERT INTO customers VALUES (1, 'Ada Example', 'ada@example.com', NULL), (2, 'Ben', 'ben@example.com', NULL),
+			(9, 'Zed', 'zed@example.com', '555');
+		CREATE TABLE customers_backup AS SELECT * FROM customers;
+		UPDATE customers_backup SET phone = '556' WHERE id = 9`); err != nil {
+		t.Fatal(err)
+	}
+	c := newTestClient(t, `
+connections:
+  sqlite: "`+dbPath+`"
+  demo: demo
+  locked: ":memory:"
+policies:
+  - {tool: `+tool+`, connection: locked, action: deny}
+`)
+	return func(args map[string]any) *mcp.CallToolResult {
+		t.Helper()
+		res, err := c.CallTool(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{Name: tool, Arguments: args}})
+		if err != nil {
+			t.Fatalf("%s: %v", tool, err)
+		}
+		return res
+	}
+}
+
+func TestCompareSchemasTool(t *testing.T) {
+	call := compareCaller(t, "compare_schemas")
+	compareOut := func(args map[string]any) CompareSchemasOutput {
+		t.Helper()
+		res := call(args)
+		if res.IsError {
+			t.Fatalf("compare_schemas %v: %s", args, textContent(res))
+		}
+		var out CompareSchemasOutput
+		if err := json.Unmarshal([]byte(textContent(res)), &out); err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	if out := compareOut(map[string]any{"connection_id": "demo", "other_connection_id": "demo"}); !out.Identical {
+		t.Errorf("demo against itself = %+v", out)
+	}
+
+	out := compareOut(map[string]any{"connection_id": "sqlite", "other_connection_id": "demo"})
+	if out.Identical || !slices.Equal(out.OnlyInA, []string{"customers_backup"}) ||
+		!slices.Equal(out.OnlyInB, []string{"order_items", "orders", "products"}) {
+		t.Errorf("tables: only in a %v, only in b %v", out.OnlyInA, out.OnlyInB)
+	}
+	if len(out.Tables) != 1 {
+		t.Fatalf("tables = %+v", out.Tables)
+	}
+	td := out.Tables[0]
+	if td.Table != "customers" || !slices.Equal(td.ColumnsOnlyInA, []string{"phone"}) ||
+		!slices.Equal(td.ColumnsOnlyInB, []string{"city", "created_at"}) {
+		t.Errorf("customers columns = %+v", td)
+	}
+	if len(td.Columns) != 1 || td.Columns[0].Column != "name" || !slices.Equal(td.Columns[0].Differences, []string{"nullable"}) {
+		t.Errorf("customers column differences = %+v", td.Columns)
+	}
+	if !slices.Equal(td.IndexesOnlyInB, []string{"unique (email)"}) {
+		t.Errorf("customers indexes only in b = %v", td.IndexesOnlyInB)
+	}
+
+	if res := call(map[string]any{"connection_id": "sqlite", "other_connection_id": "locked"}); resultCode(res) != CodePermissionDenied {
+		t.Errorf("compare against a denied connection: %s", textContent(res))
+	}
+	if res := call(map[string]any{"connection_id": "sqlite", "other_connection_id": "nope"}); !res.IsError {
+		t.Error("compare against an unknown connection succeeded")
+	}
+}
+
+func TestCompareTableDataTool(t *testing.T) {
+	call := compareCaller(t, "compare_table_data")
+	compareOut := func(args map[string]any) CompareTableDataOutput {
+		t.Helper()
+		res := call(args)
+		if res.IsError {
+			t.Fatalf("compare_table_data %v: %s", args, textContent(res))
+		}
+		var out CompareTableDataOutput
+		if err := json.Unmarshal([]byte(textContent(res)), &out); err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	out := compareOut(map[string]any{"connection_id": "sqlite", "table": "customers", "other_connection_id": "demo"})
+	if !slices.Equal(out.Key, []string{"id"}) || !slices.Equal(out.Columns, []string{"id", "name", "email"}) ||
+		!slices.Equal(out.ColumnsOnlyInA, []string{"phone"}) || !slices.Equal(out.ColumnsOnlyInB, []string{"city", "created_at"}) {
+		t.Errorf("key %v, columns %v, only in a %v, only in b %v", out.Key, out.Columns, out.ColumnsOnlyInA, out.ColumnsOnlyInB)
+	}
+	if out.Identical || out.RowsA != 3 || out.RowsB != 5 || out.Matching != 1 || out.OnlyInA != 1 || out.OnlyInB != 3 || out.Differing != 1 {
+		t.Errorf("counts = %+v", out.DataDiff)
+	}
+	if len(out.Sample) != 5 || out.Sample[0].Status != "differs" || !slices.Equal(out.Sample[0].Columns, []string{"name"}) ||
+		out.Sample[0].A["name"] != "Ben" || out.Sample[0].B["name"] != "Ben Sample" {
+		t.Errorf("sample = %+v", out.Sample)
+	}
+
+	out = compareOut(map[string]any{"connection_id": "sqlite", "table": "customers", "other_table": "customers_backup", "sample": 1})
+	if out.Matching != 2 || out.Differing != 1 || len(out.Sample) != 1 || !slices.Equal(out.Sample[0].Columns, []string{"phone"}) {
+		t.Errorf("customers against customers_backup = %+v", out)
+	}
+
+	for _, tt := range []struct {
+		args map[string]any
+		code string
+	}{
+		{map[string]any{"connection_id": "sqlite", "table": "customers", "other_connection_id": "demo", "max_rows": 4}, CodeResultTooLarge},
+		{map[string]any{"connection_id": "sqlite", "table": "customers", "other_connection_id": "locked"}, CodePermissionDenied},
+		{map[string]any{"connection_id": "sqlite", "table": "customers", "key": []any{"phone"}, "other_connection_id": "demo"}, CodeValidationFailed},
+		{map[string]any{"connection_id": "sqlite", "table": "nope"}, CodeNotFound},
+	} {
+		if res := call(tt.args); resultCode(res) != tt.code {
+			t.Errorf("compare_table_data %v: %s, want %s", tt.args, textContent(res), tt.code)
+		}
+	}
+}
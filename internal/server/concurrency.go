@@ -0,0 +1,62 @@
+package server
+
+import (
+	"context"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// concurrencyLimiter caps the database tool calls running at once on each
+// connection, so an agent issuing many parallel calls cannot swamp a small
+// local database (SQLite in particular). Calls over the limit wait for a
+// slot until their deadline.
+type concurrencyLimiter struct {
+	limit func(connID string) int // zero or less means unlimited
+
+	mu    sync.Mutex
+	slots map[string]chan struct{} // by connection ID
+}
+
+func newConcurrencyLimiter(limit func(connID string) int) *concurrencyLimiter {
+	return &concurrencyLimiter{limit: limit, slots: make(map[string]chan struct{})}
+}
+
+// middleware holds a slot of the call's connection while a database tool
+// (one with a rate limit class) runs. A call whose context ends while it
+// waits fails with cancelled, or query_timeout once timeoutMiddleware sees
+// its deadline.
+func (l *concurrencyLimiter) middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if _, ok := toolClasses[request.Params.Name]; !ok {
+			return next(ctx, request)
+		}
+		slots := l.slotsFor(request.GetString("connection_id", ""))
+		if slots == nil {
+			return next(ctx, request)
+		}
+		select {
+		case slots <- struct{}{}:
+			defer func() { <-slots }()
+			return next(ctx, request)
+		case <-ctx.Done():
+			return toolErrorResult(ctx.Err()), nil
+		}
+	}
+}
+
+// slotsFor returns the semaphore of connID, or nil if it is unlimited.
+func (l *concurrencyLimiter) slotsFor(connID string) chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if slots, ok := l.slots[connID]; ok {
+		return slots
+	}
+	var slots chan struct{}
+	if n := l.limit(connID); n > 0 {
+		slots = make(chan struct{}, n)
+	}
+	l.slots[connID] = slots
+	return slots
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestConcurrencyLimiter(t *testing.T) {
+	l := newConcurrencyLimiter(func(connID string) int {
+		if connID == "sqlite" {
+			return 1
+		}
+		return 0
+	})
+	release := make(chan struct{})
+	started := make(chan struct{}, 4)
+	handler := l.middleware(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		started <- struct{}{}
+		<-release
+		return mcp.NewToolResultText("ok"), nil
+	})
+	call := func(ctx context.Context, connID string) *mcp.CallToolResult {
+		req := mcp.CallToolRequest{}
+		req.Params.Name = "run_query"
+		req.Params.Arguments = map[string]any{"connection_id": connID}
+		res, _ := handler(ctx, req)
+		return res
+	}
+
+	first := make(chan *mcp.CallToolResult)
+	go func() { first <- call(context.Background(), "sqlite") }()
+	<-started
+
+	// The only slot is taken: a second call waits until its deadline.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	res := call(ctx, "sqlite")
+	if te, ok := res.StructuredContent.(ToolError); !res.IsError || !ok || te.Code != CodeQueryTimeout {
+		t.Errorf("call over the limit: %+v", res)
+	}
+
+	// Unlimited connections do not wait.
+	other := make(chan *mcp.CallToolResult)
+	go func() { other <- call(context.Background(), "postgres") }()
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("call on an unlimited connection did not start")
+	}
+
+	close(release)
+	if res := <-first; res.IsError {
+		t.Errorf("first call: %+v", res)
+	}
+	<-other
+	if res := call(context.Background(), "sqlite"); res.IsError {
+		t.Errorf("call after the slot was freed: %+v", res)
+	}
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Errors from confirmWrite.
+var (
+	errWriteNotConfirmed       = errors.New("write not confirmed by the user")
+	errConfirmationUnavailable = errors.New("writes require confirmation, but the client could not be asked")
+)
+
+// confirmWrite asks the human behind the client, via MCP elicitation, to
+// approve statement before it runs on connID. It returns nil only when the
+// user explicitly approves; a client without elicitation support cannot
+// approve, so the write is refused rather than run unconfirmed.
+func confirmWrite(ctx context.Context, s *server.MCPServer, connID, statement string) error {
+	err := askUser(ctx, s,
+		fmt.Sprintf("An agent wants to run this on the %q connection:\n\n%s\n\nAllow it?", connID, statement),
+		"Run this statement", "Check to let the write go ahead")
+	if errors.Is(err, errConfirmationUnavailable) {
+		return fmt.Errorf("%w; use a client with MCP elicitation support or turn off confirm_writes", err)
+	}
+	return err
+}
+
+// askUser asks the human behind the client, via MCP elicitation, to approve
+// what message describes, with a checkbox titled title. It returns nil only
+// when the user explicitly approves, errWriteNotConfirmed when they do not,
+// and errConfirmationUnavailable when the client cannot ask them.
+func askUser(ctx context.Context, s *server.MCPServer, message, title, description string) error {
+	res, err := s.RequestElicitation(ctx, mcp.ElicitationRequest{
+		Params: mcp.ElicitationParams{
+			Message: message,
+			RequestedSchema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"approve": map[string]any{
+						"type":        "boolean",
+						"title":       title,
+						"description": description,
+					},
+				},
+				"required": []string{"approve"},
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("%w (%v)", errConfirmationUnavailable, err)
+	}
+	if res.Action != mcp.ElicitationResponseActionAccept {
+		return fmt.Errorf("%w (%s)", errWriteNotConfirmed, res.Action)
+	}
+	if content, ok := res.Content.(map[string]any); !ok || content["approve"] != true {
+		return errWriteNotConfirmed
+	}
+	return nil
+}
+
+// previewInsert describes the statement InsertRow would run on d.
+func previewInsert(d db.Driver, schema, table string, row map[string]any) string {
+	p, ok := db.Unwrap(d).(db.WritePreviewer)
+	if !ok {
+		return fmt.Sprintf("insert one row into %s", table)
+	}
+	return formatStatement(p.InsertSQL(schema, table, row))
+}
+
+// previewUpdate describes the statement UpdateRow would run on d.
+func previewUpdate(d db.Driver, schema, table string, key, set map[string]any) string {
+	p, ok := db.Unwrap(d).(db.WritePreviewer)
+	if !ok {
+		return fmt.Sprintf("update one row of %s", table)
+	}
+	return formatStatement(p.UpdateSQL(schema, table, key, set))
+}
+
+// formatStatement renders query with its positional params listed below.
+func formatStatement(query string, params []any) string {
+	if len(params) == 0 {
+		return query
+	}
+	b, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Sprintf("%s\n-- params: %v", query, params)
+	}
+	return fmt.Sprintf("%s\n-- params: %s", query, b)
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// fakeHuman answers elicitation requests with a fixed response.
+type fakeHuman struct {
+	approve  bool
+	messages []string
+}
+
+func (h *fakeHuman) Elicit(ctx context.Context, request mcp.ElicitationRequest) (*mcp.ElicitationResult, error) {
+	h.messages = append(h.messages, request.Params.Message)
+	res := &mcp.ElicitationResult{}
+	if h.approve {
+		res.Action = mcp.ElicitationResponseActionAccept
+		res.Content = map[string]any{"approve": true}
+	} else {
+		res.Action = mcp.ElicitationResponseActionDecline
+	}
+	return res, nil
+}
+
+func TestConfirmWrites(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "test.db")
+	sqlDB, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sqlDB.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDB.Close()
+
+	t.Setenv(config.EnvConfirmWrites, "true")
+	s, _ := newTestServer(t, "connections:\n  sqlite: \""+path+"\"\n")
+
+	insert := func(c *client.Client, name string) *mcp.CallToolResult {
+		t.Helper()
+		req := mcp.CallToolRequest{}
+		req.Params.Name = "insert_test_row"
+		req.Params.Arguments = map[string]any{"connection_id": "sqlite", "table": "users", "row": map[string]any{"name": name}}
+		res, err := c.CallTool(ctx, req)
+		if err != nil {
+			t.Fatalf("insert_test_row: %v", err)
+		}
+		return res
+	}
+	connect := func(tr transport.Interface) *client.Client {
+		t.Helper()
+		c := client.NewClient(tr)
+		if err := c.Start(ctx); err != nil {
+			t.Fatal(err)
+		}
+		initReq := mcp.InitializeRequest{}
+		initReq.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+		if _, err := c.Initialize(ctx, initReq); err != nil {
+			t.Fatalf("Initialize: %v", err)
+		}
+		return c
+	}
+
+	human := &fakeHuman{approve: true}
+	c := connect(transport.NewInProcessTransportWithOptions(s, transport.WithElicitationHandler(human)))
+	defer c.Close()
+	if res := insert(c, "alice"); res.IsError {
+		t.Fatalf("approved insert failed: %s", textContent(res))
+	}
+	if len(human.messages) != 1 || !strings.Contains(human.messages[0], `INSERT INTO "users" ("name") VALUES (?1)`) ||
+		!strings.Contains(human.messages[0], `["alice"]`) {
+		t.Errorf("confirmation should show the SQL and params, got %q", human.messages)
+	}
+
+	human.approve = false
+	if res := insert(c, "bob"); !res.IsError || !strings.Contains(textContent(res), "not confirmed") {
+		t.Errorf("declined insert should fail, got %s", textContent(res))
+	}
+
+	// A client that cannot be asked cannot approve.
+	plain := connect(transport.NewInProcessTransport(s))
+	defer plain.Close()
+	if res := insert(plain, "carol"); !res.IsError || !strings.Contains(textContent(res), "could not be asked") {
+		t.Errorf("insert without elicitation support should fail, got %s", textContent(res))
+	}
+
+	var n int
+	if err := sqlDB.QueryRow("SELECT COUNT(*) FROM users").Scan(&n); err != nil || n != 1 {
+		t.Errorf("expected only the approved row, got %d (%v)", n, err)
+	}
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ConfirmationTokenTTL is how long a confirmation token returned by a
+// destructive tool stays valid.
+const ConfirmationTokenTTL = 2 * time.Minute
+
+// pendingOp is a destructive operation waiting for its confirmation token.
+type pendingOp struct {
+	session   string
+	operation string // what the operation does, with everything it depends on
+	expires   time.Time
+}
+
+// confirmations holds the confirmation tokens of destructive tools. The
+// first call of such a tool only describes the operation and returns a
+// token; the operation runs when the same session calls the tool again with
+// the same arguments and the token. The two steps show up in the agent's
+// transcript, where the human can see what is about to happen.
+type confirmations struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	pending map[string]pendingOp
+}
+
+func newConfirmations(ttl time.Duration) *confirmations {
+	return &confirmations{ttl: ttl, pending: make(map[string]pendingOp)}
+}
+
+// check returns nil if args carry a valid confirmation_token for operation,
+// consuming the token. Otherwise it returns the result to send instead: a
+// new token for operation, or an error for a token that is unknown,
+// expired, or was issued for a different operation.
+func (c *confirmations) check(ctx context.Context, args map[string]any, operation string) *mcp.CallToolResult {
+	token, _ := args["confirmation_token"].(string)
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for t, op := range c.pending {
+		if now.After(op.expires) {
+			delete(c.pending, t)
+		}
+	}
+
+	if token == "" {
+		b := make([]byte, 6)
+		rand.Read(b)
+		token = "confirm_" + hex.EncodeToString(b)
+		expires := now.Add(c.ttl)
+		c.pending[token] = pendingOp{session: sessionID(ctx), operation: operation, expires: expires}
+		res, err := mcp.NewToolResultJSON(ConfirmationRequiredOutput{
+			ConfirmationRequired: true,
+			Operation:            operation,
+			ConfirmationToken:    token,
+			ExpiresAt:            expires.UTC().Format(time.RFC3339),
+			Hint:                 "nothing was changed yet; after the user agrees, call the tool again with the same arguments and this confirmation_token",
+		})
+		if err != nil {
+			return toolErrorResult(err)
+		}
+		return res
+	}
+
+	op, ok := c.pending[token]
+	if !ok || op.session != sessionID(ctx) {
+		return errorResult(ToolError{
+			Code:    CodePermissionDenied,
+			Message: fmt.Sprintf("unknown or expired confirmation token %q", token),
+			Hint:    fmt.Sprintf("tokens are valid once, for %s; call again without confirmation_token for a new one", c.ttl),
+		}, nil)
+	}
+	delete(c.pending, token)
+	if op.operation != operation {
+		return errorResult(ToolError{
+			Code:    CodePermissionDenied,
+			Message: fmt.Sprintf("confirmation token %q was issued for a different operation: %s", token, op.operation),
+			Hint:    "call again without confirmation_token to confirm this operation",
+		}, nil)
+	}
+	return nil
+}
+
+// ConfirmationRequiredOutput is the result of the first call of a
+// destructive tool: the operation has not run yet.
+type ConfirmationRequiredOutput struct {
+	ConfirmationRequired bool   `json:"confirmation_required"`
+	Operation            string `json:"operation"`
+	ConfirmationToken    string `json:"confirmation_token"`
+	ExpiresAt            string `json:"expires_at"`
+	Hint                 string `json:"hint"`
+}
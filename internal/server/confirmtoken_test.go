@@ -0,0 +1,56 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestConfirmations(t *testing.T) {
+	ctx := context.Background()
+	c := newConfirmations(time.Minute)
+	token := func(op string) string {
+		t.Helper()
+		res := c.check(ctx, map[string]any{}, op)
+		var out ConfirmationRequiredOutput
+		if res == nil || res.IsError || json.Unmarshal([]byte(textContent(res)), &out) != nil {
+			t.Fatalf("first call: want a confirmation token, got %+v", res)
+		}
+		if !out.ConfirmationRequired || out.Operation != op || out.ConfirmationToken == "" {
+			t.Fatalf("first call: %+v", out)
+		}
+		return out.ConfirmationToken
+	}
+	confirm := func(tok, op string) string {
+		if res := c.check(ctx, map[string]any{"confirmation_token": tok}, op); res != nil {
+			return resultCode(res)
+		}
+		return ""
+	}
+
+	tok := token("import a.sql")
+	if code := confirm(tok, "import a.sql"); code != "" {
+		t.Errorf("valid token refused: %s", code)
+	}
+	if code := confirm(tok, "import a.sql"); code != CodePermissionDenied {
+		t.Errorf("reused token: code %q, want %s", code, CodePermissionDenied)
+	}
+
+	tok = token("import a.sql")
+	if code := confirm(tok, "import b.sql"); code != CodePermissionDenied {
+		t.Errorf("token for another operation: code %q, want %s", code, CodePermissionDenied)
+	}
+	if code := confirm(tok, "import a.sql"); code != CodePermissionDenied {
+		t.Error("a token refused for another operation should be used up")
+	}
+
+	c.ttl = -time.Second
+	tok = token("import a.sql")
+	if code := confirm(tok, "import a.sql"); code != CodePermissionDenied {
+		t.Errorf("expired token: code %q, want %s", code, CodePermissionDenied)
+	}
+	if code := confirm("confirm_made_up", "import a.sql"); code != CodePermissionDenied {
+		t.Errorf("unknown token: code %q, want %s", code, CodePermissionDenied)
+	}
+}
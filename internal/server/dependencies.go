@@ -0,0 +1,63 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ListDependenciesOutput is the result of list_dependencies.
+type ListDependenciesOutput struct {
+	Table string `json:"table"`
+	// ForeignKeys are the foreign keys of the schema's tables, the table
+	// itself included, that reference it.
+	ForeignKeys []db.ForeignKey `json:"foreign_keys"`
+	// Dependents are the views, routines and triggers that depend on it.
+	Dependents []db.Dependent `json:"dependents"`
+}
+
+// listDependencies lists what depends on table of schema on connID: what
+// a change to it may break.
+func listDependencies(ctx context.Context, cfg *config.Config, mgr *db.Manager, connID, schema, table string) *mcp.CallToolResult {
+	if res := checkPermission(cfg, connID, config.OpSelect, "", ""); res != nil {
+		return res
+	}
+	tables, schema, res := describeSchema(ctx, cfg, mgr, connID, schema, table)
+	if res != nil {
+		return res
+	}
+	driver, err := mgr.Driver(ctx, connID)
+	if err != nil {
+		return toolErrorResult(err)
+	}
+	lister, ok := db.Unwrap(driver).(db.DependencyLister)
+	if !ok {
+		return toolErrorResult(fmt.Errorf("%w: connection %q cannot list dependencies", db.ErrNotSupported, connID))
+	}
+	out := ListDependenciesOutput{Table: tables[0].name, ForeignKeys: []db.ForeignKey{}, Dependents: []db.Dependent{}}
+	if fkLister, ok := db.Unwrap(driver).(db.ForeignKeyLister); ok {
+		fks, err := fkLister.ForeignKeys(ctx, schema)
+		if err != nil {
+			return toolErrorResult(err)
+		}
+		for _, fk := range fks {
+			if strings.EqualFold(fk.RefTable, out.Table) {
+				out.ForeignKeys = append(out.ForeignKeys, fk)
+			}
+		}
+	}
+	deps, err := lister.Dependents(ctx, schema, out.Table)
+	if err != nil {
+		return toolErrorResult(err)
+	}
+	out.Dependents = append(out.Dependents, deps...)
+	res, err = mcp.NewToolResultJSON(out)
+	if err != nil {
+		return toolErrorResult(err)
+	}
+	return res
+}
@@ -0,0 +1,30 @@
+package server
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestListDependenciesTool(t *testing.T) {
+	call := demoCaller(t)
+	res := call("list_dependencies", map[string]any{"table": "orders"})
+	if res.IsError {
+		t.Fatalf("list_dependencies: %s", textContent(res))
+	}
+	var out ListDependenciesOutput
+	if err := json.Unmarshal([]byte(textContent(res)), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Table != "orders" || len(out.ForeignKeys) != 1 || out.ForeignKeys[0].Table != "order_items" || len(out.Dependents) != 0 {
+		t.Errorf("list_dependencies orders = %+v", out)
+	}
+
+	for _, args := range []map[string]any{
+		{},
+		{"table": "nope"},
+	} {
+		if res := call("list_dependencies", args); !res.IsError {
+			t.Errorf("list_dependencies %v succeeded", args)
+		}
+	}
+}
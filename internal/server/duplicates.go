@@ -0,0 +1,133 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Limits of find_duplicates.
+const (
+	DefaultDuplicateGroups = 20
+	MaxDuplicateGroups     = 100
+	DefaultDuplicateKeys   = 5
+	MaxDuplicateKeys       = 20
+)
+
+// FindDuplicatesOutput is the result of find_duplicates.
+type FindDuplicatesOutput struct {
+	Table   string   `json:"table"`
+	Columns []string `json:"columns"`
+	// Groups counts the groups of rows sharing the values of Columns, and
+	// Rows the rows in them, including those not returned.
+	Groups int64 `json:"groups"`
+	Rows   int64 `json:"rows"`
+	// KeyColumns are the primary key columns of the sample keys; empty if
+	// the table has no primary key, and no keys are sampled.
+	KeyColumns []string `json:"key_columns"`
+	// Duplicates are the biggest groups.
+	Duplicates []DuplicateGroup `json:"duplicates"`
+	// Truncated is set if there are more groups than Duplicates.
+	Truncated bool `json:"truncated"`
+}
+
+// DuplicateGroup is a group of rows sharing the values of the columns.
+type DuplicateGroup struct {
+	Values map[string]any `json:"values"`
+	Count  int64          `json:"count"`
+	// SampleKeys are the primary keys of the first rows of the group. A
+	// group of masked values has none, as the masked values match no row.
+	SampleKeys []map[string]any `json:"sample_keys,omitempty"`
+}
+
+// findDuplicates returns the groups of rows of table of schema on connID
+// sharing the values of columns, none of them NULL: at most limit groups,
+// the biggest first, with the primary keys of up to keys rows of each.
+// Queries read the table through its row filter, if any.
+func findDuplicates(ctx context.Context, cfg *config.Config, mgr *db.Manager, connID, schema, table string, columns []string, limit, keys int) *mcp.CallToolResult {
+	if res := checkPermission(cfg, connID, config.OpSelect, "", ""); res != nil {
+		return res
+	}
+	tables, schema, res := describeSchema(ctx, cfg, mgr, connID, schema, table)
+	if res != nil {
+		return res
+	}
+	engine, _ := cfg.Type(connID)
+	out := FindDuplicatesOutput{Table: table, Columns: columns, KeyColumns: []string{}, Duplicates: []DuplicateGroup{}}
+	for n, name := range columns {
+		if slices.Contains(columns[:n], name) {
+			return invalidArgs(fmt.Sprintf("column %q is listed twice", name))
+		}
+		i := -1
+		for j, c := range tables[0].cols {
+			if c.Name == name {
+				i = j
+			}
+		}
+		if i < 0 {
+			return invalidArgs(fmt.Sprintf("table %q has no column %q", table, name))
+		}
+		if distinct, _, _ := profileStats(engine, tables[0].cols[i].Type); !distinct {
+			return invalidArgs(fmt.Sprintf("column %q of type %s cannot be compared for equality", name, tables[0].cols[i].Type))
+		}
+	}
+	for _, c := range tables[0].cols {
+		if c.IsPK {
+			out.KeyColumns = append(out.KeyColumns, c.Name)
+		}
+	}
+
+	driver, err := mgr.Driver(ctx, connID)
+	if err != nil {
+		return toolErrorResult(err)
+	}
+	query := filteredQuerier(ctx, cfg, driver, connID)
+
+	rows, res := query(db.DuplicatesSummaryQuery(engine, schema, table, columns), nil)
+	if res != nil {
+		return res
+	}
+	if len(rows) > 0 {
+		out.Groups, _ = asInt64(rows[0]["groups"])
+		out.Rows, _ = asInt64(rows[0]["rows"])
+	}
+	if out.Groups == 0 {
+		return duplicatesResult(out)
+	}
+	sql, countColumn := db.DuplicatesQuery(engine, schema, table, columns, limit)
+	if rows, res = query(sql, nil); res != nil {
+		return res
+	}
+	for _, r := range rows {
+		g := DuplicateGroup{Values: make(map[string]any, len(columns))}
+		params := make([]any, len(columns))
+		for i, c := range columns {
+			g.Values[c], params[i] = r[c], r[c]
+		}
+		g.Count, _ = asInt64(r[countColumn])
+		if keys > 0 && len(out.KeyColumns) > 0 {
+			sample, res := query(db.GroupKeysQuery(engine, schema, table, columns, out.KeyColumns, keys), params)
+			if res != nil {
+				return res
+			}
+			if len(sample) > 0 {
+				g.SampleKeys = sample
+			}
+		}
+		out.Duplicates = append(out.Duplicates, g)
+	}
+	out.Truncated = out.Groups > int64(len(out.Duplicates))
+	return duplicatesResult(out)
+}
+
+func duplicatesResult(out FindDuplicatesOutput) *mcp.CallToolResult {
+	res, err := mcp.NewToolResultJSON(out)
+	if err != nil {
+		return toolErrorResult(err)
+	}
+	return res
+}
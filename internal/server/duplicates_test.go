@@ -0,0 +1,58 @@
+package server
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestFindDuplicatesTool(t *testing.T) {
+	call := demoCaller(t)
+	find := func(args map[string]any) FindDuplicatesOutput {
+		t.Helper()
+		res := call("find_duplicates", args)
+		if res.IsError {
+			t.Fatalf("find_duplicates %v: %s", args, textContent(res))
+		}
+		var out FindDuplicatesOutput
+		if err := json.Unmarshal([]byte(textContent(res)), &out); err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	out := find(map[string]any{"table": "customers", "columns": []any{"city"}})
+	want := []DuplicateGroup{{Values: map[string]any{"city": "Prague"}, Count: 2, SampleKeys: []map[string]any{{"id": 2.0}, {"id": 5.0}}}}
+	if out.Groups != 1 || out.Rows != 2 || out.Truncated || !reflect.DeepEqual(out.KeyColumns, []string{"id"}) ||
+		!reflect.DeepEqual(out.Duplicates, want) {
+		t.Errorf("duplicate cities = %+v", out)
+	}
+
+	out = find(map[string]any{"table": "orders", "columns": []any{"status"}, "limit": 1, "sample_keys": 0})
+	want = []DuplicateGroup{{Values: map[string]any{"status": "delivered"}, Count: 2}}
+	if out.Groups != 2 || out.Rows != 4 || !out.Truncated || !reflect.DeepEqual(out.Duplicates, want) {
+		t.Errorf("duplicate statuses = %+v", out)
+	}
+
+	out = find(map[string]any{"table": "order_items", "columns": []any{"product_id"}, "limit": 1, "sample_keys": 1})
+	want = []DuplicateGroup{{Values: map[string]any{"product_id": 1.0}, Count: 2, SampleKeys: []map[string]any{{"order_id": 1.0, "product_id": 1.0}}}}
+	if !reflect.DeepEqual(out.KeyColumns, []string{"order_id", "product_id"}) || !reflect.DeepEqual(out.Duplicates, want) {
+		t.Errorf("duplicate products = %+v", out)
+	}
+
+	if out := find(map[string]any{"table": "customers", "columns": []any{"email"}}); out.Groups != 0 || len(out.Duplicates) != 0 {
+		t.Errorf("duplicate emails = %+v", out)
+	}
+
+	for _, args := range []map[string]any{
+		{"table": "customers"},
+		{"table": "customers", "columns": []any{}},
+		{"table": "customers", "columns": []any{"nope"}},
+		{"table": "customers", "columns": []any{"city", "city"}},
+		{"table": "customers", "columns": []any{"city"}, "limit": 0},
+	} {
+		if res := call("find_duplicates", args); !res.IsError {
+			t.Errorf("find_duplicates %v succeeded", args)
+		}
+	}
+}
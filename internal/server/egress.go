@@ -0,0 +1,164 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// egressTools are the tools whose results count against a session's
+// egress_budget: those that return table data to the client.
+var egressTools = map[string]bool{
+	"run_query":                   true,
+	"compare_table_data":          true,
+	"find_duplicates":             true,
+	"check_referential_integrity": true,
+	"find_orphans":                true,
+	"profile_table":               true,
+}
+
+// egressRowLists are the lists of a JSON result that hold table rows, by
+// key: run_query's rows, compare_table_data's sample of differences and
+// find_duplicates' groups, each standing for a row of values.
+var egressRowLists = []string{"rows", "sample", "duplicates"}
+
+// egressCheckLists are the lists of a JSON result whose entries each hold
+// a sample of rows: check_referential_integrity's foreign keys and
+// find_orphans' relationships.
+var egressCheckLists = []string{"foreign_keys", "relationships"}
+
+// egressValueLists are the lists of a JSON result whose entries each hold
+// values of a column: profile_table's columns, whose min, max and top
+// values each count as a row.
+var egressValueLists = []string{"columns"}
+
+// egressUsage is the data a session has been sent by egressTools.
+type egressUsage struct {
+	Bytes int64 `json:"bytes"`
+	Rows  int64 `json:"rows"`
+}
+
+// BudgetExceededOutput is the structured content of a result withheld
+// because it would take the session over its egress budget (code
+// budget_exceeded).
+type BudgetExceededOutput struct {
+	ToolError
+	Budget config.EgressBudget `json:"budget"`
+	Used   egressUsage         `json:"used"`
+	Result egressUsage         `json:"result"`
+}
+
+// egressMiddleware adds up the bytes and rows each session receives from
+// egressTools and withholds any result that would take the session over
+// budget. Smaller results still go through until the budget is spent, so
+// many small queries cannot copy out more than one large one could. It runs
+// outside the size guard, counting what is actually sent.
+func egressMiddleware(sessions *sessionRegistry, budget config.EgressBudget) server.ToolHandlerMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			res, err := next(ctx, request)
+			if err != nil || res == nil || res.IsError || !egressTools[request.Params.Name] {
+				return res, err
+			}
+			got := egressUsage{Bytes: int64(resultSize(res)), Rows: int64(resultRows(res))}
+			st := sessions.get(ctx)
+			st.mu.Lock()
+			used := st.egress
+			over := budget.Bytes > 0 && used.Bytes+got.Bytes > budget.Bytes ||
+				budget.Rows > 0 && used.Rows+got.Rows > budget.Rows
+			if !over {
+				st.egress.Bytes += got.Bytes
+				st.egress.Rows += got.Rows
+			}
+			st.mu.Unlock()
+			if !over {
+				return res, nil
+			}
+			e := ToolError{
+				Code: CodeBudgetExceeded,
+				Message: fmt.Sprintf("%s result (%d bytes, %d rows) would exceed this session's egress budget; %s left",
+					request.Params.Name, got.Bytes, got.Rows, remaining(budget, used)),
+				Hint: "narrow the query: aggregate, add a WHERE or LIMIT, or select fewer columns",
+			}
+			return errorResult(e, BudgetExceededOutput{ToolError: e, Budget: budget, Used: used, Result: got}), nil
+		}
+	}
+}
+
+// remaining describes what is left of budget after used.
+func remaining(budget config.EgressBudget, used egressUsage) string {
+	switch {
+	case budget.Bytes > 0 && budget.Rows > 0:
+		return fmt.Sprintf("%d bytes and %d rows", max(budget.Bytes-used.Bytes, 0), max(budget.Rows-used.Rows, 0))
+	case budget.Bytes > 0:
+		return fmt.Sprintf("%d bytes", max(budget.Bytes-used.Bytes, 0))
+	default:
+		return fmt.Sprintf("%d rows", max(budget.Rows-used.Rows, 0))
+	}
+}
+
+// resultRows returns the number of table rows in a JSON result: the
+// lengths of its egressRowLists, or 0 if it has none.
+func resultRows(res *mcp.CallToolResult) int {
+	var out map[string]json.RawMessage
+	if res.StructuredContent != nil {
+		b, err := json.Marshal(res.StructuredContent)
+		if err != nil || json.Unmarshal(b, &out) != nil {
+			return 0
+		}
+		return countRows(out)
+	}
+	for _, c := range res.Content {
+		if tc, ok := mcp.AsTextContent(c); ok && json.Unmarshal([]byte(tc.Text), &out) == nil {
+			return countRows(out)
+		}
+	}
+	return 0
+}
+
+// countRows adds up the lengths of the egressRowLists in out and of the
+// samples in its egressCheckLists, and the values in its egressValueLists.
+// A key holding something other than such a list, like find_duplicates'
+// count of rows or compare_table_data's column names, does not count.
+func countRows(out map[string]json.RawMessage) int {
+	n := 0
+	for _, key := range egressRowLists {
+		var list []json.RawMessage
+		if json.Unmarshal(out[key], &list) == nil {
+			n += len(list)
+		}
+	}
+	for _, key := range egressCheckLists {
+		var checks []struct {
+			Sample []json.RawMessage `json:"sample"`
+		}
+		if json.Unmarshal(out[key], &checks) == nil {
+			for _, c := range checks {
+				n += len(c.Sample)
+			}
+		}
+	}
+	for _, key := range egressValueLists {
+		var columns []struct {
+			Min       json.RawMessage   `json:"min"`
+			Max       json.RawMessage   `json:"max"`
+			TopValues []json.RawMessage `json:"top_values"`
+		}
+		if json.Unmarshal(out[key], &columns) == nil {
+			for _, c := range columns {
+				n += len(c.TopValues)
+				if c.Min != nil {
+					n++
+				}
+				if c.Max != nil {
+					n++
+				}
+			}
+		}
+	}
+	return n
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/compare"
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestEgressMiddleware(t *testing.T) {
+	sessions := newSessionRegistry()
+	rows := 2
+	handler := egressMiddleware(sessions, config.EgressBudget{Rows: 5})(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		out := RunQueryOutput{Rows: make([]map[string]any, rows)}
+		for i := range out.Rows {
+			out.Rows[i] = map[string]any{"id": i}
+		}
+		return mcp.NewToolResultJSON(out)
+	})
+	call := func(name string) *mcp.CallToolResult {
+		t.Helper()
+		req := mcp.CallToolRequest{}
+		req.Params.Name = name
+		res, err := handler(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	for i := 0; i < 2; i++ {
+		if res := call("run_query"); res.IsError {
+			t.Fatalf("query %d within budget failed: %s", i+1, textContent(res))
+		}
+	}
+	res := call("run_query")
+	if resultCode(res) != CodeBudgetExceeded || !strings.Contains(textContent(res), "1 rows left") {
+		t.Fatalf("query over budget: %s", textContent(res))
+	}
+	out, ok := res.StructuredContent.(BudgetExceededOutput)
+	if !ok || out.Used.Rows != 4 || out.Result.Rows != 2 || out.Budget.Rows != 5 {
+		t.Errorf("structured content = %#v", res.StructuredContent)
+	}
+
+	rows = 1
+	if res := call("run_query"); res.IsError {
+		t.Errorf("a query that fits the rest of the budget failed: %s", textContent(res))
+	}
+	if res := call("run_query"); resultCode(res) != CodeBudgetExceeded {
+		t.Errorf("spent budget should refuse any rows, got %s", textContent(res))
+	}
+	rows = 10
+	if res := call("describe_table"); res.IsError {
+		t.Error("tools outside egressTools are not metered")
+	}
+
+	sessions.release("")
+	rows = 5
+	if res := call("run_query"); res.IsError {
+		t.Errorf("a new session gets a fresh budget: %s", textContent(res))
+	}
+}
+
+func TestResultRows(t *testing.T) {
+	for _, tc := range []struct {
+		out  any
+		want int
+	}{
+		{RunQueryOutput{Rows: make([]map[string]any, 3)}, 3},
+		{CompareTableDataOutput{DataDiff: compare.DataDiff{RowsA: 100, Sample: make([]compare.RowDiff, 4)}}, 4},
+		{FindDuplicatesOutput{Groups: 40, Rows: 95, Duplicates: make([]DuplicateGroup, 20)}, 20},
+		{CheckReferentialIntegrityOutput{ForeignKeys: []ForeignKeyCheck{
+			{Orphans: 9, Sample: make([]map[string]any, 5)}, {}, {Orphans: 2, Sample: make([]map[string]any, 2)},
+		}}, 7},
+		{FindOrphansOutput{Relationships: []ForeignKeyCheck{{Orphans: 3, Sample: make([]map[string]any, 3)}}}, 3},
+		{ProfileTableOutput{Rows: 1000, Columns: []ColumnProfile{
+			{Name: "id", Min: 1, Max: 1000},
+			{Name: "status", Min: "active", Max: "closed", TopValues: make([]ValueCount, 3)},
+			{Name: "payload"},
+		}}, 7},
+		{map[string]any{"columns": []string{"id", "name"}}, 0},
+		{map[string]any{"rows": 12}, 0},
+	} {
+		res, err := mcp.NewToolResultJSON(tc.out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := resultRows(res); got != tc.want {
+			t.Errorf("resultRows(%T) = %d, want %d", tc.out, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Error codes carried in the structured content of failed tool calls.
+const (
+	CodeValidationFailed  = "validation_failed"  // missing or invalid arguments
+	CodeUnknownConnection = "unknown_connection" // connection_id is not configured
+	CodeConnectionFailed  = "connection_failed"  // the database could not be reached
+	CodePermissionDenied  = "permission_denied"  // refused by the server's safety rules
+	CodeNotFound          = "not_found"          // the targeted row or transaction does not exist
+	CodeConflict          = "conflict"           // the row changed since it was read; see current
+	CodeNotSupported      = "not_supported"      // not available for this connection or server
+	CodeQueryTimeout      = "query_timeout"      // the operation ran past its deadline
+	CodeCancelled         = "cancelled"          // the call was cancelled
+	CodeRateLimited       = "rate_limited"       // too many calls; see retry_after_ms
+	CodeUnavailable       = "unavailable"        // the server is shutting down
+	CodeDatabaseError     = "database_error"     // the database rejected the operation
+	CodeInternal          = "internal"           // a bug in the server; see the log
+	CodeResultTooLarge    = "result_too_large"   // over max_result_bytes with nothing to truncate
+	CodeBudgetExceeded    = "budget_exceeded"    // over the session's egress_budget
+)
+
+// ToolError is the structured content of a failed tool call. Agents can
+// branch on Code; Message is also sent as the text content, followed by
+// Hint when present.
+type ToolError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Hint    string `json:"hint,omitempty"`
+}
+
+func (e ToolError) text() string {
+	if e.Hint == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s (hint: %s)", e.Message, e.Hint)
+}
+
+// errorResult returns a failed tool result carrying structured as its
+// structured content. structured is a ToolError or a type embedding one.
+func errorResult(e ToolError, structured any) *mcp.CallToolResult {
+	if structured == nil {
+		structured = e
+	}
+	res := mcp.NewToolResultStructured(structured, e.text())
+	res.IsError = true
+	return res
+}
+
+// invalidArgs reports a missing or malformed tool argument.
+func invalidArgs(message string) *mcp.CallToolResult {
+	return errorResult(ToolError{Code: CodeValidationFailed, Message: message}, nil)
+}
+
+// ConflictOutput is the structured content of an update_test_row refused
+// because the row no longer has its expected values (code conflict).
+type ConflictOutput struct {
+	ToolError
+	// Current is the row as it is now; omitted when the connection's
+	// permissions do not allow reading it.
+	Current map[string]any `json:"current,omitempty"`
+}
+
+// toolErrorResult reports err, classified by classifyError.
+func toolErrorResult(err error) *mcp.CallToolResult {
+	e := classifyError(err)
+	var conflict *db.ConflictError
+	if errors.As(err, &conflict) {
+		return errorResult(e, ConflictOutput{ToolError: e, Current: conflict.Current})
+	}
+	return errorResult(e, nil)
+}
+
+// classifyError maps an error from the db layer or a safety check to a
+// ToolError with a code and, where useful, a hint on what to do next.
+func classifyError(err error) ToolError {
+	e := ToolError{Code: CodeDatabaseError, Message: err.Error()}
+	switch {
+	case errors.Is(err, db.ErrUnknownConnection):
+		e.Code, e.Hint = CodeUnknownConnection, "call list_connections for the configured connection IDs"
+	case errors.Is(err, db.ErrConnectFailed):
+		e.Code, e.Hint = CodeConnectionFailed, "call health to see which connections are reachable"
+	case errors.Is(err, ErrNotReadOnly):
+		e.Code, e.Hint = CodePermissionDenied, "run_query only runs read-only SQL; use insert_test_row or update_test_row to change data"
+	case errors.Is(err, errWriteNotConfirmed):
+		e.Code, e.Hint = CodePermissionDenied, "the user declined this write; do not retry it unless they ask"
+	case errors.Is(err, errConfirmationUnavailable):
+		e.Code = CodeNotSupported
+	case errors.Is(err, errTooManyRows):
+		e.Code, e.Hint = CodePermissionDenied, "check that key names a single row, or raise max_rows_affected in config.yaml"
+	case errors.Is(err, db.ErrPathNotAllowed):
+		e.Code, e.Hint = CodePermissionDenied, "use a path inside one of the allowed export directories"
+	case errors.Is(err, db.ErrInvalidInput):
+		e.Code = CodeValidationFailed
+	case errors.Is(err, db.ErrNotFound):
+		e.Code = CodeNotFound
+	case errors.Is(err, db.ErrConflict):
+		e.Code, e.Hint = CodeConflict, "the row was changed since it was read; check current, then retry with it as expected if the update still applies"
+	case errors.Is(err, db.ErrNotSupported):
+		e.Code = CodeNotSupported
+	case errors.Is(err, db.ErrManagerClosed):
+		e.Code = CodeUnavailable
+	case errors.Is(err, context.DeadlineExceeded):
+		e.Code, e.Hint = CodeQueryTimeout, "narrow the query, add a LIMIT, or retry later"
+	case errors.Is(err, context.Canceled):
+		e.Code = CodeCancelled
+	}
+	return e
+}
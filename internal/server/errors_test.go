@@ -0,0 +1,84 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		err  error
+		code string
+	}{
+		{fmt.Errorf("unknown connection ID %q: %w", "x", db.ErrUnknownConnection), CodeUnknownConnection},
+		{fmt.Errorf("failed to connect: %w", db.ErrConnectFailed), CodeConnectionFailed},
+		{fmt.Errorf("%w: found %q", ErrNotReadOnly, "DELETE"), CodePermissionDenied},
+		{fmt.Errorf("%w (decline)", errWriteNotConfirmed), CodePermissionDenied},
+		{fmt.Errorf("%w (no session)", errConfirmationUnavailable), CodeNotSupported},
+		{fmt.Errorf("dump path: %w", db.ErrPathNotAllowed), CodePermissionDenied},
+		{fmt.Errorf("update row: %w", db.ErrInvalidInput), CodeValidationFailed},
+		{fmt.Errorf("update row: %w", db.ErrNotFound), CodeNotFound},
+		{&db.ConflictError{Current: map[string]any{"id": 1}}, CodeConflict},
+		{fmt.Errorf("export: %w", db.ErrNotSupported), CodeNotSupported},
+		{fmt.Errorf("query: %w", context.DeadlineExceeded), CodeQueryTimeout},
+		{context.Canceled, CodeCancelled},
+		{errors.New(`relation "nope" does not exist`), CodeDatabaseError},
+	}
+	for _, tt := range tests {
+		got := classifyError(tt.err)
+		if got.Code != tt.code {
+			t.Errorf("classifyError(%q).Code = %q, want %q", tt.err, got.Code, tt.code)
+		}
+		if got.Message != tt.err.Error() {
+			t.Errorf("classifyError(%q).Message = %q", tt.err, got.Message)
+		}
+	}
+}
+
+func TestToolErrors_structured(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t, "connections:\n  sqlite: \":memory:\"\n")
+
+	tests := []struct {
+		tool string
+		args map[string]any
+		code string
+	}{
+		{"list_tables", map[string]any{"connection_id": "nope"}, CodeUnknownConnection},
+		{"list_tables", map[string]any{}, CodeValidationFailed},
+		{"run_query", map[string]any{"connection_id": "sqlite", "sql": "DELETE FROM t"}, CodePermissionDenied},
+	}
+	for _, tt := range tests {
+		res, err := c.CallTool(ctx, mcp.CallToolRequest{
+			Params: mcp.CallToolParams{Name: tt.tool, Arguments: tt.args},
+		})
+		if err != nil {
+			t.Fatalf("CallTool(%s): %v", tt.tool, err)
+		}
+		if !res.IsError {
+			t.Errorf("%s %v: expected an error result", tt.tool, tt.args)
+			continue
+		}
+		b, err := json.Marshal(res.StructuredContent)
+		if err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+		var out ToolError
+		if err := json.Unmarshal(b, &out); err != nil {
+			t.Fatalf("decode %s: %v", b, err)
+		}
+		if out.Code != tt.code {
+			t.Errorf("%s %v: code %q, want %q", tt.tool, tt.args, out.Code, tt.code)
+		}
+		if out.Message == "" || !strings.HasPrefix(textContent(res), out.Message) {
+			t.Errorf("%s: text %q should start with message %q", tt.tool, textContent(res), out.Message)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ExplainQueryOutput is the result of explain_query.
+type ExplainQueryOutput struct {
+	// Plan is the estimated plan, the same fields for every engine.
+	Plan []db.PlanNode `json:"plan"`
+	// FullScans lists the tables the plan reads in full.
+	FullScans []string `json:"full_scans,omitempty"`
+}
+
+// checkReadOnlySQL runs the checks of run_query on sql, a statement for
+// connID: it must be a query, and read only what the connection's policy
+// lets it read.
+func checkReadOnlySQL(cfg *config.Config, connID, sql string) *mcp.CallToolResult {
+	typ, _ := cfg.Type(connID)
+	if err := ValidateReadOnlySQLFor(typ, sql); err != nil {
+		if errors.Is(err, ErrNotReadOnly) {
+			return toolErrorResult(err)
+		}
+		return invalidArgs(err.Error())
+	}
+	if res := checkPermission(cfg, connID, config.OpSelect, "", ""); res != nil {
+		return res
+	}
+	if res := checkSystemSQL(cfg, connID, sql); res != nil {
+		return res
+	}
+	if res := checkDeniedFunctions(cfg, connID, sql); res != nil {
+		return res
+	}
+	return checkSchemaLockSQL(cfg, connID, sql)
+}
+
+// explainPlan returns the estimated plan of sql, a query checked with
+// checkReadOnlySQL, on driver after applying connID's row filters, or
+// false if the connection cannot explain queries. The query is not run.
+func explainPlan(ctx context.Context, cfg *config.Config, driver db.Driver, connID, sql string, params []any) ([]db.PlanNode, bool, *mcp.CallToolResult) {
+	sql, res := applyQueryRowFilters(cfg, connID, sql)
+	if res != nil {
+		return nil, false, res
+	}
+	engine, _ := cfg.Type(connID)
+	var plan []db.PlanNode
+	var err error
+	if explain, ok := db.ExplainQuery(engine, sql); ok {
+		rows, qerr := driver.RunReadOnlyQuery(ctx, explain, params)
+		if qerr != nil {
+			return nil, false, toolErrorResult(qerr)
+		}
+		plan, err = db.ParsePlan(engine, rows)
+	} else if planner, ok := db.Unwrap(driver).(db.ShowPlanner); ok {
+		var showplan string
+		if showplan, err = planner.ShowPlanXML(ctx, sql, params); err == nil {
+			plan, err = db.ParseShowPlanXML(showplan)
+		}
+	} else {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, toolErrorResult(err)
+	}
+	return plan, true, nil
+}
+
+// explainQuery returns the estimated plan of sql, a query checked with
+// checkReadOnlySQL, on connID.
+func explainQuery(ctx context.Context, cfg *config.Config, mgr *db.Manager, connID, sql string, params []any) *mcp.CallToolResult {
+	driver, err := mgr.Driver(ctx, connID)
+	if err != nil {
+		return toolErrorResult(err)
+	}
+	plan, ok, res := explainPlan(ctx, cfg, driver, connID, sql, params)
+	if res != nil {
+		return res
+	}
+	if !ok {
+		return toolErrorResult(fmt.Errorf("%w: connection %q cannot explain queries", db.ErrNotSupported, connID))
+	}
+	if plan == nil {
+		plan = []db.PlanNode{}
+	}
+	res, err = mcp.NewToolResultJSON(ExplainQueryOutput{Plan: plan, FullScans: db.FullScans(plan)})
+	if err != nil {
+		return toolErrorResult(err)
+	}
+	return res
+}
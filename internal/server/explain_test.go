@@ -0,0 +1,42 @@
+package server
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
+)
+
+func TestExplainQueryTool(t *testing.T) {
+	call := demoCaller(t)
+	res := call("explain_query", map[string]any{
+		"sql":    "SELECT o.id FROM orders o JOIN customers c ON c.id = o.customer_id WHERE o.status = $1 ORDER BY o.ordered_at",
+		"params": []any{"pending"},
+	})
+	if res.IsError {
+		t.Fatalf("explain_query: %s", textContent(res))
+	}
+	var out ExplainQueryOutput
+	if err := json.Unmarshal([]byte(textContent(res)), &out); err != nil {
+		t.Fatal(err)
+	}
+	want := []db.PlanNode{
+		{Operation: "Table Scan", Relation: "o", FullScan: true, Detail: "SCAN o"},
+		{Operation: "Index Search", Relation: "c", Index: "PRIMARY KEY", Detail: "SEARCH c USING INTEGER PRIMARY KEY (rowid=?)"},
+		{Operation: "Sort", Detail: "USE TEMP B-TREE FOR ORDER BY"},
+	}
+	if !reflect.DeepEqual(out.Plan, want) || !reflect.DeepEqual(out.FullScans, []string{"o"}) {
+		t.Errorf("explain_query = %+v", out)
+	}
+
+	for _, args := range []map[string]any{
+		{},
+		{"sql": "DELETE FROM orders"},
+		{"sql": "SELECT * FROM nope"},
+	} {
+		if res := call("explain_query", args); !res.IsError {
+			t.Errorf("explain_query %v succeeded", args)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	// exportURIPrefix is the URI scheme for dumps served as MCP resources.
+	exportURIPrefix = "localdb://exports/"
+	// maxInlineExportBytes is the largest dump embedded directly in the
+	// export_database result; larger dumps are only linked.
+	maxInlineExportBytes = 1 << 20
+	// maxRetainedExports bounds how many resource-delivered dumps are kept
+	// on disk; the oldest is deleted when a new one is added.
+	maxRetainedExports = 5
+)
+
+// exportStore keeps dumps produced with delivery "resource" in a private
+// temp directory and exposes each as an MCP resource, so clients that do not
+// share a filesystem with the server can still fetch them. The list of
+// dumps is kept per session (see sessionState); the directory is deleted
+// when the Manager is closed.
+type exportStore struct {
+	mu  sync.Mutex
+	dir string
+}
+
+type exportEntry struct {
+	uri  string
+	path string
+	// sessionScoped is set when the resource was registered on the
+	// session only, rather than server-wide.
+	sessionScoped bool
+}
+
+// newPath returns a fresh file path in the store's directory for a dump of
+// connID, creating the directory on first use.
+func (st *exportStore) newPath(connID string) (string, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.dir == "" {
+		dir, err := os.MkdirTemp("", "localdb-mcp-exports-")
+		if err != nil {
+			return "", fmt.Errorf("create export directory: %w", err)
+		}
+		st.dir = dir
+	}
+	var b [6]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return filepath.Join(st.dir, fmt.Sprintf("%s-%s.sql", connID, hex.EncodeToString(b[:]))), nil
+}
+
+// publish registers the dump at path as a resource of sess and returns its
+// URI. Transports with per-session resources (SSE, streamable HTTP) only
+// show the dump to the session that exported it; otherwise (stdio, a single
+// client) it is registered server-wide. When the session holds more than
+// maxRetainedExports dumps the oldest is removed.
+func (st *exportStore) publish(s *server.MCPServer, sess *sessionState, path string) string {
+	uri := exportURIPrefix + filepath.Base(path)
+	resource := mcp.NewResource(uri, filepath.Base(path),
+		mcp.WithResourceDescription("SQL dump produced by export_database"),
+		mcp.WithMIMEType("application/sql"),
+	)
+	handler := func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("export %s is no longer available", uri)
+		}
+		return []mcp.ResourceContents{mcp.TextResourceContents{
+			URI:      uri,
+			MIMEType: "application/sql",
+			Text:     string(data),
+		}}, nil
+	}
+
+	entry := exportEntry{uri: uri, path: path}
+	if sess.id != "" && s.AddSessionResource(sess.id, resource, handler) == nil {
+		entry.sessionScoped = true
+	} else {
+		s.AddResource(resource, handler)
+	}
+
+	sess.mu.Lock()
+	sess.exports = append(sess.exports, entry)
+	var evicted []exportEntry
+	if n := len(sess.exports) - maxRetainedExports; n > 0 {
+		evicted = append(evicted, sess.exports[:n]...)
+		sess.exports = sess.exports[n:]
+	}
+	sess.mu.Unlock()
+
+	for _, e := range evicted {
+		removeExport(s, sess.id, e)
+	}
+	return uri
+}
+
+// release deletes every dump of a session that has ended.
+func (st *exportStore) release(s *server.MCPServer, sess *sessionState) {
+	sess.mu.Lock()
+	entries := sess.exports
+	sess.exports = nil
+	sess.mu.Unlock()
+	for _, e := range entries {
+		removeExport(s, sess.id, e)
+	}
+}
+
+// close deletes the store's directory with any dumps still in it. A later
+// newPath creates a fresh one.
+func (st *exportStore) close() error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.dir == "" {
+		return nil
+	}
+	err := os.RemoveAll(st.dir)
+	st.dir = ""
+	return err
+}
+
+// removeExport unregisters an export's resource and deletes its file.
+func removeExport(s *server.MCPServer, sessionID string, e exportEntry) {
+	if e.sessionScoped {
+		// Fails harmlessly once the session itself is gone.
+		_ = s.DeleteSessionResources(sessionID, e.uri)
+	} else {
+		s.RemoveResource(e.uri)
+	}
+	_ = os.Remove(e.path)
+}
+
+// exportResourceResult builds the export_database result for a published
+// dump: a JSON summary, a resource link, and the dump itself when small.
+func exportResourceResult(uri, path string) (*mcp.CallToolResult, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("stat export: %w", err)
+	}
+	manifest, err := db.ReadManifest(path)
+	if err != nil {
+		return nil, err
+	}
+	out := ExportDatabaseOutput{
+		Message:     "database exported; read the dump from resource_uri",
+		ResourceURI: uri,
+		SizeBytes:   info.Size(),
+		Manifest:    manifest,
+	}
+	res, err := mcp.NewToolResultJSON(out)
+	if err != nil {
+		return nil, err
+	}
+	res.Content = append(res.Content,
+		mcp.NewResourceLink(uri, filepath.Base(path), "SQL dump produced by export_database", "application/sql"))
+	if info.Size() <= maxInlineExportBytes {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read export: %w", err)
+		}
+		res.Content = append(res.Content, mcp.NewEmbeddedResource(mcp.TextResourceContents{
+			URI:      uri,
+			MIMEType: "application/sql",
+			Text:     string(data),
+		}))
+	}
+	return res, nil
+}
@@ -0,0 +1,163 @@
+package server
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func TestExportStore_publishAndEvict(t *testing.T) {
+	ctx := context.Background()
+	s := server.NewMCPServer(ServerName, ServerVersion)
+	st := &exportStore{}
+	sess := &sessionState{}
+
+	var paths, uris []string
+	for i := 0; i < maxRetainedExports+1; i++ {
+		p, err := st.newPath("sqlite")
+		if err != nil {
+			t.Fatalf("newPath: %v", err)
+		}
+		if err := os.WriteFile(p, []byte("CREATE TABLE t (id INTEGER);\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, p)
+		uris = append(uris, st.publish(s, sess, p))
+	}
+	t.Cleanup(func() { st.close() })
+
+	if _, err := os.Stat(paths[0]); !os.IsNotExist(err) {
+		t.Errorf("oldest export should be deleted, stat err = %v", err)
+	}
+
+	c := connectTestClient(t, s)
+
+	list, err := c.ListResources(ctx, mcp.ListResourcesRequest{})
+	if err != nil {
+		t.Fatalf("ListResources: %v", err)
+	}
+	if len(list.Resources) != maxRetainedExports {
+		t.Errorf("expected %d resources, got %d", maxRetainedExports, len(list.Resources))
+	}
+
+	req := mcp.ReadResourceRequest{}
+	req.Params.URI = uris[len(uris)-1]
+	read, err := c.ReadResource(ctx, req)
+	if err != nil {
+		t.Fatalf("ReadResource: %v", err)
+	}
+	if tc, ok := read.Contents[0].(mcp.TextResourceContents); !ok || tc.Text != "CREATE TABLE t (id INTEGER);\n" {
+		t.Errorf("unexpected resource contents: %+v", read.Contents)
+	}
+
+	res, err := exportResourceResult(uris[len(uris)-1], paths[len(paths)-1])
+	if err != nil {
+		t.Fatalf("exportResourceResult: %v", err)
+	}
+	// JSON summary, resource link, and the embedded (small) dump.
+	if len(res.Content) != 3 {
+		t.Errorf("expected 3 content items, got %d", len(res.Content))
+	}
+}
+
+func TestExportStore_sessionScoped(t *testing.T) {
+	ctx := context.Background()
+	s := server.NewMCPServer(ServerName, ServerVersion)
+	sessions := newSessionRegistry()
+	sessions.install(s, nil)
+	st := &exportStore{}
+	sessions.onSessionRelease(func(sess *sessionState) { st.release(s, sess) })
+	ts := httptest.NewServer(newStreamableHTTPServer(s))
+	defer ts.Close()
+
+	var clients []*client.Client
+	for i := 0; i < 2; i++ {
+		c, err := client.NewStreamableHttpClient(ts.URL)
+		if err != nil {
+			t.Fatalf("NewStreamableHttpClient: %v", err)
+		}
+		defer c.Close()
+		if err := c.Start(ctx); err != nil {
+			t.Fatalf("Start: %v", err)
+		}
+		initReq := mcp.InitializeRequest{}
+		initReq.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+		if _, err := c.Initialize(ctx, initReq); err != nil {
+			t.Fatalf("Initialize: %v", err)
+		}
+		clients = append(clients, c)
+	}
+
+	p, err := st.newPath("sqlite")
+	if err != nil {
+		t.Fatalf("newPath: %v", err)
+	}
+	t.Cleanup(func() { st.close() })
+	if err := os.WriteFile(p, []byte("SELECT 1;\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	owner := &sessionState{id: clients[0].GetSessionId()}
+	uri := st.publish(s, owner, p)
+	if !owner.exports[0].sessionScoped {
+		t.Fatal("export should be registered on the session")
+	}
+
+	req := mcp.ReadResourceRequest{}
+	req.Params.URI = uri
+	if _, err := clients[0].ReadResource(ctx, req); err != nil {
+		t.Errorf("owner ReadResource: %v", err)
+	}
+	if _, err := clients[1].ReadResource(ctx, req); err == nil {
+		t.Error("another session must not be able to read the export")
+	}
+
+	st.release(s, owner)
+	if _, err := os.Stat(p); !os.IsNotExist(err) {
+		t.Errorf("released session's dump should be deleted, stat err = %v", err)
+	}
+}
+
+func TestExportStore_close(t *testing.T) {
+	st := &exportStore{}
+	p, err := st.newPath("sqlite")
+	if err != nil {
+		t.Fatalf("newPath: %v", err)
+	}
+	if err := os.WriteFile(p, []byte("SELECT 1;\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	dir := st.dir
+	if err := st.close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("export directory should be deleted, stat err = %v", err)
+	}
+	if err := st.close(); err != nil {
+		t.Errorf("second close: %v", err)
+	}
+}
+
+func TestSessionRegistry(t *testing.T) {
+	r := newSessionRegistry()
+	var released []string
+	r.onSessionRelease(func(st *sessionState) { released = append(released, st.id) })
+
+	a := r.get(context.Background())
+	if b := r.get(context.Background()); a != b {
+		t.Error("calls without a session should share one state")
+	}
+	r.release("")
+	r.release("unknown")
+	if len(released) != 1 || released[0] != "" {
+		t.Errorf("released = %v, want one release of the empty session", released)
+	}
+	if c := r.get(context.Background()); c == a {
+		t.Error("state should be recreated after release")
+	}
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// deniedFunctions are the functions run_query refuses to call on each
+// connection type, as case-insensitive glob patterns: they let a read-only
+// statement reach outside the database (files, programs, other servers) or
+// undo the read-only session.
+var deniedFunctions = map[string][]string{
+	"postgres": {
+		// Queries and commands on other servers.
+		"dblink*",
+		// Server files and directories, including adminpack and large
+		// objects (lo_import, lo_export).
+		"pg_read_file", "pg_read_binary_file", "pg_ls_*", "pg_stat_file", "pg_file_*", "lo_*",
+		// A statement hidden in a string.
+		"query_to_xml*", "cursor_to_xml*",
+		// Settings, including default_transaction_read_only, and other
+		// sessions.
+		"set_config", "pg_reload_conf", "pg_rotate_logfile", "pg_terminate_backend", "pg_cancel_backend",
+	},
+	"sqlserver": {
+		// Extended and system procedures (xp_cmdshell, ...).
+		"xp_*", "sp_*",
+		// Other servers and files.
+		"openrowset", "opendatasource", "openquery",
+		"fn_xe_file_target_read_file", "fn_get_audit_file", "fn_trace_gettable",
+	},
+	"mysql": {
+		// Server files, and the commands of lib_mysqludf_sys.
+		"load_file", "sys_exec", "sys_eval",
+	},
+	"sqlite": {
+		// Extensions, and the sqlite3 shell's file functions.
+		"load_extension", "readfile", "writefile", "edit", "fts3_tokenizer",
+	},
+}
+
+// functionDenied returns whether run_query may not call a function on
+// connID: one of the built-in entries for its type that allowed_functions
+// does not lift, or one in denied_functions. A connection of unknown type
+// gets every type's entries.
+func functionDenied(cfg *config.Config, connID string) func(name string) bool {
+	typ, _ := cfg.Type(connID)
+	if typ == "demo" {
+		typ = "sqlite"
+	}
+	builtin, ok := deniedFunctions[typ]
+	if !ok {
+		for _, t := range []string{"postgres", "sqlserver", "mysql", "sqlite"} {
+			builtin = append(builtin, deniedFunctions[t]...)
+		}
+	}
+	return func(name string) bool {
+		return policyMatchPatterns(cfg.DeniedFunctions(), name) ||
+			policyMatchPatterns(builtin, name) && !policyMatchPatterns(cfg.AllowedFunctions(), name)
+	}
+}
+
+func policyMatchPatterns(patterns []string, name string) bool {
+	return slices.ContainsFunc(patterns, func(p string) bool { return policyMatch(p, name) })
+}
+
+// deniedCall returns the first function sql calls, on a connection of type
+// typ, for which denied is true, or "". Calls are found in the lexed
+// statement (see lexSQL): a name, bare or quoted and possibly qualified,
+// directly followed by an opening parenthesis.
+func deniedCall(typ, sql string, denied func(name string) bool) string {
+	for _, d := range dialectsFor(typ) {
+		toks := lexSQL(sql, d)
+		for i := 0; i+1 < len(toks); i++ {
+			if (toks[i].word || toks[i].ident) && toks[i+1].text == "(" {
+				if name := strings.ToLower(toks[i].text); denied(name) {
+					return name
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// checkDeniedFunctions refuses a run_query statement on connID that calls a
+// function of its deny-list (see functionDenied).
+func checkDeniedFunctions(cfg *config.Config, connID, sql string) *mcp.CallToolResult {
+	typ, _ := cfg.Type(connID)
+	name := deniedCall(typ, sql, functionDenied(cfg, connID))
+	if name == "" {
+		return nil
+	}
+	return errorResult(ToolError{
+		Code:    CodePermissionDenied,
+		Message: fmt.Sprintf("function %s is not allowed in queries on connection %q: it reaches outside the database", name, connID),
+		Hint:    "query the tables directly, or list the function in allowed_functions in config.yaml",
+	}, nil)
+}
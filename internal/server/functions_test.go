@@ -0,0 +1,72 @@
+package server
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func TestDeniedCall(t *testing.T) {
+	denied := func(typ string) func(string) bool {
+		return func(name string) bool { return policyMatchPatterns(deniedFunctions[typ], name) }
+	}
+	tests := []struct {
+		typ, sql, want string
+	}{
+		{"postgres", "SELECT pg_read_file('/etc/passwd')", "pg_read_file"},
+		{"postgres", "SELECT * FROM DBLINK('host=other', 'SELECT 1') AS t(x int)", "dblink"},
+		{"postgres", `SELECT pg_catalog."lo_import"('/etc/passwd')`, "lo_import"},
+		{"postgres", "SELECT set_config('default_transaction_read_only', 'off', false)", "set_config"},
+		{"postgres", "SELECT 'pg_read_file(x)', lo_id FROM files -- dblink(", ""},
+		{"sqlserver", "SELECT * FROM OPENROWSET('SQLNCLI', 'Server=x;', 'SELECT 1')", "openrowset"},
+		{"sqlserver", "SELECT master.dbo.xp_cmdshell('dir')", "xp_cmdshell"},
+		{"mysql", "SELECT LOAD_FILE('/etc/passwd')", "load_file"},
+		{"mysql", "SELECT load_file # (\n FROM t", ""},
+		{"sqlite", "SELECT load_extension('evil.so')", "load_extension"},
+		{"sqlite", "SELECT edit_count FROM t", ""},
+	}
+	for _, tt := range tests {
+		if got := deniedCall(tt.typ, tt.sql, denied(tt.typ)); got != tt.want {
+			t.Errorf("deniedCall(%s, %q) = %q, want %q", tt.typ, tt.sql, got, tt.want)
+		}
+	}
+}
+
+func TestDeniedFunctions(t *testing.T) {
+	ctx := context.Background()
+	cfg := newTestConfig(t, `
+connections:
+  sqlite: "`+filepath.Join(t.TempDir(), "app.db")+`"
+denied_functions: ["upp*"]
+allowed_functions: [edit]
+`)
+	denied := functionDenied(cfg, "sqlite")
+	if !denied("upper") || !denied("load_extension") || denied("edit") || denied("lower") {
+		t.Errorf("deny-list: upper %v, load_extension %v, edit %v, lower %v",
+			denied("upper"), denied("load_extension"), denied("edit"), denied("lower"))
+	}
+
+	s := server.NewMCPServer(ServerName, ServerVersion)
+	mgr := Register(s, cfg)
+	defer mgr.Close()
+	c := connectTestClient(t, s)
+	query := func(sql string) *mcp.CallToolResult {
+		t.Helper()
+		res, err := c.CallTool(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{Name: "run_query",
+			Arguments: map[string]any{"connection_id": "sqlite", "sql": sql}}})
+		if err != nil {
+			t.Fatalf("run_query: %v", err)
+		}
+		return res
+	}
+	if res := query("SELECT UPPER('a') AS x"); resultCode(res) != CodePermissionDenied || !strings.Contains(textContent(res), "upper") {
+		t.Errorf("denied function: %s", textContent(res))
+	}
+	if res := query("SELECT lower('A') AS x"); res.IsError {
+		t.Errorf("lower: %s", textContent(res))
+	}
+}
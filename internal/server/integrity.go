@@ -0,0 +1,132 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Limits of check_referential_integrity.
+const (
+	DefaultOrphanSample = 5
+	MaxOrphanSample     = 50
+)
+
+// CheckReferentialIntegrityOutput is the result of
+// check_referential_integrity.
+type CheckReferentialIntegrityOutput struct {
+	// Valid is set if every foreign key checked holds.
+	Valid bool `json:"valid"`
+	// Checked counts the foreign keys checked, Violated those with orphans.
+	Checked     int               `json:"checked"`
+	Violated    int               `json:"violated"`
+	ForeignKeys []ForeignKeyCheck `json:"foreign_keys"`
+}
+
+// ForeignKeyCheck is the result of checking a foreign key.
+type ForeignKeyCheck struct {
+	db.ForeignKey
+	// Orphans counts the rows whose key, with no NULL in it, references no
+	// row of RefTable.
+	Orphans int64 `json:"orphans"`
+	// RefTableMissing is set if RefTable does not exist, which SQLite
+	// allows with foreign keys off; every key is then an orphan.
+	RefTableMissing bool `json:"ref_table_missing,omitempty"`
+	// Sample holds the primary key and foreign key columns of the first
+	// orphans.
+	Sample []map[string]any `json:"sample,omitempty"`
+}
+
+// checkReferentialIntegrity checks the foreign keys of schema on connID,
+// or of its table if table is not empty, with an anti-join per foreign
+// key, sampling up to sample orphans of each. Queries read the tables
+// through their row filters, if any.
+func checkReferentialIntegrity(ctx context.Context, cfg *config.Config, mgr *db.Manager, connID, schema, table string, sample int) *mcp.CallToolResult {
+	if res := checkPermission(cfg, connID, config.OpSelect, "", ""); res != nil {
+		return res
+	}
+	tables, schema, res := describeSchema(ctx, cfg, mgr, connID, schema, table)
+	if res != nil {
+		return res
+	}
+	driver, err := mgr.Driver(ctx, connID)
+	if err != nil {
+		return toolErrorResult(err)
+	}
+	lister, ok := db.Unwrap(driver).(db.ForeignKeyLister)
+	if !ok {
+		return toolErrorResult(fmt.Errorf("%w: connection %q cannot list foreign keys", db.ErrNotSupported, connID))
+	}
+	fks, err := lister.ForeignKeys(ctx, schema)
+	if err != nil {
+		return toolErrorResult(err)
+	}
+	existing, err := driver.ListTables(ctx, schema)
+	if err != nil {
+		return toolErrorResult(err)
+	}
+	described := make(map[string][]db.ColumnInfo, len(tables))
+	for _, t := range tables {
+		described[t.name] = t.cols
+	}
+	engine, _ := cfg.Type(connID)
+	query := filteredQuerier(ctx, cfg, driver, connID)
+
+	out := CheckReferentialIntegrityOutput{ForeignKeys: []ForeignKeyCheck{}}
+	for _, fk := range fks {
+		cols, ok := described[fk.Table]
+		if !ok {
+			continue // another table, or a system table describeSchema skipped
+		}
+		refMissing := !slices.ContainsFunc(existing, func(t string) bool { return strings.EqualFold(t, fk.RefTable) })
+		check, res := checkOrphans(query, engine, schema, fk, cols, refMissing, sample)
+		if res != nil {
+			return res
+		}
+		out.Checked++
+		if check.Orphans > 0 {
+			out.Violated++
+		}
+		out.ForeignKeys = append(out.ForeignKeys, check)
+	}
+	out.Valid = out.Violated == 0
+	res, err = mcp.NewToolResultJSON(out)
+	if err != nil {
+		return toolErrorResult(err)
+	}
+	return res
+}
+
+// checkOrphans counts the orphans of fk, whose child table has the columns
+// cols, with query, and samples up to sample of them.
+func checkOrphans(query func(string, []any) ([]map[string]any, *mcp.CallToolResult), engine, schema string, fk db.ForeignKey, cols []db.ColumnInfo, refMissing bool, sample int) (ForeignKeyCheck, *mcp.CallToolResult) {
+	check := ForeignKeyCheck{ForeignKey: fk, RefTableMissing: refMissing}
+	rows, res := query(db.OrphansCountQuery(engine, schema, fk, refMissing), nil)
+	if res != nil {
+		return check, res
+	}
+	if len(rows) > 0 {
+		check.Orphans, _ = asInt64(rows[0]["orphans"])
+	}
+	if check.Orphans == 0 || sample == 0 {
+		return check, nil
+	}
+	var sampled []string
+	for _, c := range cols {
+		if c.IsPK {
+			sampled = append(sampled, c.Name)
+		}
+	}
+	for _, c := range fk.Columns {
+		if !slices.Contains(sampled, c) {
+			sampled = append(sampled, c)
+		}
+	}
+	check.Sample, res = query(db.OrphansQuery(engine, schema, fk, refMissing, sampled, sample), nil)
+	return check, res
+}
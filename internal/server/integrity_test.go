@@ -0,0 +1,90 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestCheckReferentialIntegrityTool(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "app.db")
+	sqlDB, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDB.Close()
+	// Foreign keys are off, as SQLite has them by default.
+	if _, err := sqlDB.Exec(`CREATE TABLE customers (id INTEGER PRIMARY KEY);
+		CREATE TABLE orders (id INTEGER PRIMARY KEY, customer_id INTEGER REFERENCES customers (id));
+		CREATE TABLE notes (id INTEGER PRIMARY KEY, author_id INTEGER REFERENCES authors (id));
+		INSERT INTO customers VALUES (1), (2);
+		INSERT INTO orders VALUES (10, 1), (11, 3), (12, NULL), (13, 4), (14, 2);
+		INSERT INTO notes VALUES (1, 7), (2, NULL)`); err != nil {
+		t.Fatal(err)
+	}
+	c := newTestClient(t, "connections:\n  sqlite: \""+dbPath+"\"\n  demo: demo\n")
+	call := func(args map[string]any) *mcp.CallToolResult {
+		t.Helper()
+		res, err := c.CallTool(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{Name: "check_referential_integrity", Arguments: args}})
+		if err != nil {
+			t.Fatalf("check_referential_integrity: %v", err)
+		}
+		return res
+	}
+	check := func(args map[string]any) CheckReferentialIntegrityOutput {
+		t.Helper()
+		res := call(args)
+		if res.IsError {
+			t.Fatalf("check_referential_integrity %v: %s", args, textContent(res))
+		}
+		var out CheckReferentialIntegrityOutput
+		if err := json.Unmarshal([]byte(textContent(res)), &out); err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	if out := check(map[string]any{"connection_id": "demo"}); !out.Valid || out.Checked != 3 || out.Violated != 0 {
+		t.Errorf("demo = %+v", out)
+	}
+
+	out := check(map[string]any{"connection_id": "sqlite", "sample": 1})
+	if out.Valid || out.Checked != 2 || out.Violated != 2 || len(out.ForeignKeys) != 2 {
+		t.Fatalf("sqlite = %+v", out)
+	}
+	for _, fk := range out.ForeignKeys {
+		switch fk.Table {
+		case "orders":
+			if fk.Orphans != 2 || fk.RefTableMissing || !reflect.DeepEqual(fk.Sample, []map[string]any{{"id": 11.0, "customer_id": 3.0}}) {
+				t.Errorf("orders = %+v", fk)
+			}
+		case "notes":
+			if fk.Orphans != 1 || !fk.RefTableMissing || len(fk.Sample) != 1 {
+				t.Errorf("notes = %+v", fk)
+			}
+		default:
+			t.Errorf("unexpected foreign key %+v", fk)
+		}
+	}
+
+	out = check(map[string]any{"connection_id": "sqlite", "table": "orders", "sample": 0})
+	if out.Checked != 1 || out.ForeignKeys[0].Table != "orders" || out.ForeignKeys[0].Sample != nil {
+		t.Errorf("orders only = %+v", out)
+	}
+
+	for _, args := range []map[string]any{
+		{"connection_id": "sqlite", "table": "nope"},
+		{"connection_id": "sqlite", "sample": MaxOrphanSample + 1},
+	} {
+		if res := call(args); !res.IsError {
+			t.Errorf("check_referential_integrity %v succeeded", args)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// selectListEnd are the words that end the select list of a query.
+var selectListEnd = map[string]bool{
+	"FROM": true, "INTO": true, "WHERE": true, "GROUP": true, "HAVING": true, "WINDOW": true, "ORDER": true,
+	"LIMIT": true, "OFFSET": true, "FETCH": true, "FOR": true, "UNION": true, "EXCEPT": true, "INTERSECT": true,
+}
+
+// checkMaskedSQL refuses sql, a query on connID, if it could read a masked
+// column under another name: masking rules match result columns by name,
+// so a masked column may only appear as a plain column of the outermost
+// select list (alias-free, or aliased to its own name). Anywhere else, in
+// an expression, a condition, a subquery or a later UNION branch, as well
+// as a * outside that list, the statement is refused. So is a whole row of
+// a masked table used as a value (SELECT to_jsonb(u) FROM users u) and SQL
+// Server's FOR JSON and FOR XML, which return the row as one document. It
+// returns nil if no masking rule applies to the statement.
+func checkMaskedSQL(cfg *config.Config, connID, sql string) *mcp.CallToolResult {
+	masker := db.NewMasker(connID, cfg.MaskRules())
+	patterns := masker.ColumnPatterns(sql)
+	if len(patterns) == 0 {
+		return nil
+	}
+	masksTable := func(table string) bool { return masker.MasksTable(sql, table) }
+	typ, _ := cfg.Type(connID)
+	for _, d := range dialectsFor(typ) {
+		if what := maskedColumnLeak(lexSQL(sql, d), patterns, masksTable); what != "" {
+			return errorResult(ToolError{
+				Code:    CodePermissionDenied,
+				Message: fmt.Sprintf("connection %q: %s; masked columns may only be selected as plain columns", connID, what),
+				Hint:    "select the masked column by its own name in the outermost select list, and name the columns instead of using * in subqueries or whole rows",
+			}, nil)
+		}
+	}
+	return nil
+}
+
+// maskedColumnLeak returns a description of the first use in toks of a
+// column matching patterns that masking by result column name would miss,
+// or "" if there is none. masksTable reports whether the masking rules
+// cover a table, whose rows may then not be used as values.
+func maskedColumnLeak(toks []sqlToken, patterns []string, masksTable func(table string) bool) string {
+	at := func(i int) sqlToken {
+		if i >= 0 && i < len(toks) {
+			return toks[i]
+		}
+		return sqlToken{}
+	}
+	// The tokens of the plain items of the outermost select list, and its
+	// bounds.
+	plain := make(map[int]bool)
+	start, end := -1, -1
+	for i, t := range toks {
+		if t.depth == 0 && t.word && t.text == "SELECT" {
+			start = i + 1
+			break
+		}
+	}
+	if start >= 0 {
+		end = start
+		for end < len(toks) && !(toks[end].depth == 0 && (toks[end].word && selectListEnd[toks[end].text] || toks[end].text == ";")) {
+			end++
+		}
+		i := start
+		for i < end && at(i).word && (at(i).text == "DISTINCT" || at(i).text == "ALL") {
+			i++
+		}
+		if at(i).word && at(i).text == "TOP" {
+			if i++; at(i).text == "(" {
+				for i < end && at(i).text != ")" {
+					i++
+				}
+			}
+			i++
+		}
+		for i < end {
+			j := i
+			for j < end && !(at(j).depth == 0 && at(j).text == ",") {
+				j++
+			}
+			if plainSelectItem(toks[i:j]) {
+				for k := i; k < j; k++ {
+					plain[k] = true
+				}
+			}
+			i = j + 1
+		}
+	}
+	// The names a row of a masked table goes by, and the tokens of the
+	// table references and aliases that introduce them.
+	rows := make(map[string]bool)
+	inRef := make(map[int]bool)
+	for _, ref := range sqlRefs(toks) {
+		_, table := ref.schemaTable()
+		if ref.call || !masksTable(table) {
+			continue
+		}
+		rows[table] = true
+		for i := ref.first; i < ref.end; i++ {
+			inRef[i] = true
+		}
+		if ref.alias >= 0 {
+			rows[strings.ToLower(toks[ref.alias].text)] = true
+			inRef[ref.alias] = true
+		}
+	}
+	for i, t := range toks {
+		name := strings.ToLower(t.text)
+		if (t.word || t.ident) && rows[name] && !inRef[i] && at(i+1).text != "." && at(i-1).text != "." {
+			return fmt.Sprintf("a whole row of %s is used as a value, which would return its masked columns unmasked", name)
+		}
+		if plain[i] {
+			continue
+		}
+		if (t.word || t.ident) && db.MatchesColumn(patterns, name) {
+			return fmt.Sprintf("masked column %s is used other than as a plain result column", name)
+		}
+		if t.word && t.text == "FOR" && at(i+1).word && (at(i+1).text == "JSON" || at(i+1).text == "XML") {
+			return fmt.Sprintf("FOR %s returns rows as one document, which masking cannot reach into", at(i+1).text)
+		}
+		if t.text == "*" && isStarSelect(at(i-1), at(i+1)) {
+			return "a * other than an item of the outermost select list may read masked columns"
+		}
+	}
+	return ""
+}
+
+// plainSelectItem reports whether item, the tokens of a select list item,
+// is a column, optionally qualified and aliased to its own name, or a *.
+func plainSelectItem(item []sqlToken) bool {
+	name := func(t sqlToken) bool { return t.word || t.ident }
+	if len(item) == 0 || !name(item[0]) && item[0].text != "*" {
+		return false
+	}
+	i := 1
+	for i+1 < len(item) && item[i].text == "." && (name(item[i+1]) || item[i+1].text == "*") {
+		i += 2
+	}
+	last := item[i-1]
+	rest := item[i:]
+	if len(rest) > 0 && rest[0].word && rest[0].text == "AS" {
+		rest = rest[1:]
+		if len(rest) == 0 {
+			return false
+		}
+	}
+	switch len(rest) {
+	case 0:
+		return true
+	case 1:
+		return name(rest[0]) && rest[0].text == last.text
+	}
+	return false
+}
+
+// isStarSelect reports whether a * between the tokens prev and next selects
+// all columns rather than multiplying or counting.
+func isStarSelect(prev, next sqlToken) bool {
+	if next.text == ")" && prev.text == "(" {
+		return false // COUNT(*)
+	}
+	switch {
+	case prev.text == "." || prev.text == ",", next.word && next.text == "FROM":
+		return true
+	case prev.word && (prev.text == "SELECT" || prev.text == "DISTINCT" || prev.text == "ALL"):
+		return true
+	}
+	return false
+}
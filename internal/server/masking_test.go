@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestMaskedColumnLeak(t *testing.T) {
+	patterns := []string{"email", "password*"}
+	masksTable := func(table string) bool { return table == "customers" || table == "users" }
+	for _, tc := range []struct {
+		sql  string
+		leak bool
+	}{
+		{"SELECT email FROM customers", false},
+		{"SELECT c.email, name FROM customers c", false},
+		{"SELECT email AS email FROM customers", false},
+		{"SELECT DISTINCT email FROM customers", false},
+		{"SELECT TOP 5 email FROM customers", false},
+		{"SELECT * FROM customers", false},
+		{"SELECT c.* FROM customers c", false},
+		{"SELECT COUNT(*) FROM customers", false},
+		{"SELECT name FROM customers WHERE id = 2 * 3", false},
+		{"SELECT 'email' AS label FROM customers", false},
+		{"SELECT email AS x FROM customers", true},
+		{"SELECT email x FROM customers", true},
+		{"SELECT password_hash AS x FROM users", true},
+		{"SELECT upper(email) FROM customers", true},
+		{"SELECT email || '' AS email FROM customers", true},
+		{"SELECT name FROM customers WHERE email LIKE 'a%'", true},
+		{"SELECT name FROM customers ORDER BY email", true},
+		{"SELECT e FROM (SELECT email AS e FROM customers) s", true},
+		{"SELECT * FROM (SELECT * FROM customers) s", true},
+		{"SELECT name FROM customers UNION SELECT email FROM customers", true},
+		{"SELECT c.name FROM customers c WHERE c.id = 1", false},
+		{"SELECT o, to_jsonb(o) FROM orders o", false},
+		{"SELECT customers FROM customers", true},
+		{"SELECT to_jsonb(c) FROM customers c", true},
+		{"SELECT row_to_json(c) FROM orders o JOIN customers AS c ON c.id = o.customer_id", true},
+		{"SELECT to_jsonb(c.*) FROM customers c", true},
+		{"SELECT (c).name FROM customers c", true},
+		{"SELECT * FROM customers FOR JSON AUTO", true},
+		{"SELECT name FROM customers FOR XML PATH", true},
+	} {
+		got := maskedColumnLeak(lexSQL(tc.sql, dialectsFor("sqlite")[0]), patterns, masksTable) != ""
+		if got != tc.leak {
+			t.Errorf("maskedColumnLeak(%q) = %v, want %v", tc.sql, got, tc.leak)
+		}
+	}
+}
+
+func TestRunQuery_maskedColumnUnderOtherName(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t, "connections:\n  demo: demo\nmasking:\n  - {table: customers, column: email, mask: redact}\n")
+	query := func(sql string) *mcp.CallToolResult {
+		t.Helper()
+		res, err := c.CallTool(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{
+			Name:      "run_query",
+			Arguments: map[string]any{"connection_id": "demo", "sql": sql},
+		}})
+		if err != nil {
+			t.Fatalf("run_query %q: %v", sql, err)
+		}
+		return res
+	}
+
+	for _, sql := range []string{
+		"SELECT email AS x FROM customers",
+		"SELECT upper(email) FROM customers",
+		"SELECT name FROM customers WHERE email LIKE 'a%'",
+		"SELECT customers FROM customers",
+		"SELECT * FROM customers FOR JSON AUTO",
+	} {
+		res := query(sql)
+		if !res.IsError || resultCode(res) != CodePermissionDenied {
+			t.Errorf("run_query %q: want %s, got %s", sql, CodePermissionDenied, textContent(res))
+		}
+	}
+	for _, sql := range []string{"SELECT email FROM customers", "SELECT * FROM customers"} {
+		res := query(sql)
+		if res.IsError {
+			t.Fatalf("run_query %q: %s", sql, textContent(res))
+		}
+		if text := textContent(res); !strings.Contains(text, "[REDACTED]") || strings.Contains(text, "@") {
+			t.Errorf("run_query %q: email not masked: %s", sql, text)
+		}
+	}
+	// Without a rule for the table, the column may be used freely.
+	if res := query("SELECT upper(name) FROM customers WHERE city IS NOT NULL"); res.IsError {
+		t.Errorf("unmasked query refused: %s", textContent(res))
+	}
+}
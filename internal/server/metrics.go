@@ -0,0 +1,73 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
+)
+
+// MetricsHandler serves the database call metrics of mgr's connections in
+// the Prometheus text format: a latency histogram and error counts by class
+// per connection, and the pool statistics of open connections. Like
+// connection_stats it never includes connection URIs.
+func MetricsHandler(cfg *config.Config, mgr *db.Manager) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		bw := bufio.NewWriter(w)
+		defer bw.Flush()
+		writeMetrics(bw, cfg, mgr)
+	})
+}
+
+// writeMetrics writes the metrics of every configured connection, sorted by
+// ID.
+func writeMetrics(w *bufio.Writer, cfg *config.Config, mgr *db.Manager) {
+	ids := cfg.ConnectionIDs()
+	sort.Strings(ids)
+
+	fmt.Fprintln(w, "# HELP localdb_db_call_duration_seconds Duration of database calls made by the tools.")
+	fmt.Fprintln(w, "# TYPE localdb_db_call_duration_seconds histogram")
+	for _, id := range ids {
+		m, ok := mgr.CallMetrics(id)
+		if !ok {
+			continue
+		}
+		for _, b := range m.Latency {
+			le := strconv.FormatFloat(b.LEMS/1000, 'g', -1, 64)
+			fmt.Fprintf(w, "localdb_db_call_duration_seconds_bucket{connection=%q,le=%q} %d\n", id, le, b.Count)
+		}
+		fmt.Fprintf(w, "localdb_db_call_duration_seconds_bucket{connection=%q,le=\"+Inf\"} %d\n", id, m.Calls)
+		fmt.Fprintf(w, "localdb_db_call_duration_seconds_sum{connection=%q} %g\n", id, m.TotalMS/1000)
+		fmt.Fprintf(w, "localdb_db_call_duration_seconds_count{connection=%q} %d\n", id, m.Calls)
+	}
+
+	fmt.Fprintln(w, "# HELP localdb_db_call_errors_total Failed database calls by error class.")
+	fmt.Fprintln(w, "# TYPE localdb_db_call_errors_total counter")
+	for _, id := range ids {
+		m, _ := mgr.CallMetrics(id)
+		classes := make([]string, 0, len(m.Errors))
+		for class := range m.Errors {
+			classes = append(classes, class)
+		}
+		sort.Strings(classes)
+		for _, class := range classes {
+			fmt.Fprintf(w, "localdb_db_call_errors_total{connection=%q,class=%q} %d\n", id, class, m.Errors[class])
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP localdb_pool_connections Connections in the pool of an open connection, by state.")
+	fmt.Fprintln(w, "# TYPE localdb_pool_connections gauge")
+	for _, id := range ids {
+		p, ok := mgr.PoolStats(id)
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(w, "localdb_pool_connections{connection=%q,state=\"in_use\"} %d\n", id, p.InUse)
+		fmt.Fprintf(w, "localdb_pool_connections{connection=%q,state=\"idle\"} %d\n", id, p.Idle)
+	}
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// FindOrphansOutput is the result of find_orphans.
+type FindOrphansOutput struct {
+	// Valid is set if no relationship has orphans.
+	Valid         bool              `json:"valid"`
+	Checked       int               `json:"checked"`
+	Violated      int               `json:"violated"`
+	Relationships []ForeignKeyCheck `json:"relationships"`
+}
+
+// parseRelationships converts the relationships argument of find_orphans:
+// objects with a table, its columns, a ref_table and optionally its
+// ref_columns, which default to the parent's primary key.
+func parseRelationships(v any) ([]db.ForeignKey, error) {
+	items, ok := v.([]any)
+	if !ok || len(items) == 0 {
+		return nil, errors.New("relationships is required: an array of {table, columns, ref_table, ref_columns}")
+	}
+	rels := make([]db.ForeignKey, len(items))
+	for i, item := range items {
+		m, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("relationships[%d] is not an object", i)
+		}
+		rel := &rels[i]
+		rel.Table, _ = m["table"].(string)
+		rel.RefTable, _ = m["ref_table"].(string)
+		if rel.Table == "" || rel.RefTable == "" {
+			return nil, fmt.Errorf("relationships[%d]: table and ref_table are required", i)
+		}
+		var err error
+		if rel.Columns, err = stringList(m["columns"]); err != nil || len(rel.Columns) == 0 {
+			return nil, fmt.Errorf("relationships[%d]: columns is required: an array of column names", i)
+		}
+		if v, ok := m["ref_columns"]; ok {
+			if rel.RefColumns, err = stringList(v); err != nil {
+				return nil, fmt.Errorf("relationships[%d]: ref_columns: %v", i, err)
+			}
+		}
+	}
+	return rels, nil
+}
+
+// findOrphans checks the relationships rels between tables of schema on
+// connID as check_referential_integrity checks foreign keys, sampling up
+// to sample orphans of each. Both tables of a relationship must exist.
+func findOrphans(ctx context.Context, cfg *config.Config, mgr *db.Manager, connID, schema string, rels []db.ForeignKey, sample int) *mcp.CallToolResult {
+	if res := checkPermission(cfg, connID, config.OpSelect, "", ""); res != nil {
+		return res
+	}
+	driver, err := mgr.Driver(ctx, connID)
+	if err != nil {
+		return toolErrorResult(err)
+	}
+	engine, _ := cfg.Type(connID)
+	query := filteredQuerier(ctx, cfg, driver, connID)
+	out := FindOrphansOutput{Relationships: []ForeignKeyCheck{}}
+	for i, rel := range rels {
+		child, resolved, res := describeSchema(ctx, cfg, mgr, connID, schema, rel.Table)
+		if res != nil {
+			return res
+		}
+		parent, _, res := describeSchema(ctx, cfg, mgr, connID, schema, rel.RefTable)
+		if res != nil {
+			return res
+		}
+		if len(rel.RefColumns) == 0 {
+			for _, c := range parent[0].cols {
+				if c.IsPK {
+					rel.RefColumns = append(rel.RefColumns, c.Name)
+				}
+			}
+			if len(rel.RefColumns) == 0 {
+				return invalidArgs(fmt.Sprintf("relationships[%d]: table %q has no primary key; pass ref_columns", i, rel.RefTable))
+			}
+		}
+		if len(rel.Columns) != len(rel.RefColumns) {
+			return invalidArgs(fmt.Sprintf("relationships[%d]: %d columns reference %d ref_columns", i, len(rel.Columns), len(rel.RefColumns)))
+		}
+		for _, side := range []struct {
+			table string
+			names []string
+			cols  []db.ColumnInfo
+		}{{rel.Table, rel.Columns, child[0].cols}, {rel.RefTable, rel.RefColumns, parent[0].cols}} {
+			for _, name := range side.names {
+				if !hasColumn(side.cols, name) {
+					return invalidArgs(fmt.Sprintf("relationships[%d]: table %q has no column %q", i, side.table, name))
+				}
+			}
+		}
+		check, res := checkOrphans(query, engine, resolved, rel, child[0].cols, false, sample)
+		if res != nil {
+			return res
+		}
+		out.Checked++
+		if check.Orphans > 0 {
+			out.Violated++
+		}
+		out.Relationships = append(out.Relationships, check)
+	}
+	out.Valid = out.Violated == 0
+	res, err := mcp.NewToolResultJSON(out)
+	if err != nil {
+		return toolErrorResult(err)
+	}
+	return res
+}
+
+// hasColumn reports whether cols has a column named name.
+func hasColumn(cols []db.ColumnInfo, name string) bool {
+	for _, c := range cols {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestFindOrphansTool(t *testing.T) {
+	call := demoCaller(t)
+	out := FindOrphansOutput{}
+	res := call("find_orphans", map[string]any{"relationships": []any{
+		map[string]any{"table": "orders", "columns": []any{"customer_id"}, "ref_table": "customers"},
+		// Customers without orders.
+		map[string]any{"table": "customers", "columns": []any{"id"}, "ref_table": "orders", "ref_columns": []any{"customer_id"}},
+	}})
+	if res.IsError {
+		t.Fatalf("find_orphans: %s", textContent(res))
+	}
+	if err := json.Unmarshal([]byte(textContent(res)), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Valid || out.Checked != 2 || out.Violated != 1 || len(out.Relationships) != 2 {
+		t.Fatalf("find_orphans = %+v", out)
+	}
+	if rel := out.Relationships[0]; rel.Orphans != 0 || !reflect.DeepEqual(rel.RefColumns, []string{"id"}) || rel.Sample != nil {
+		t.Errorf("orders → customers = %+v", rel)
+	}
+	if rel := out.Relationships[1]; rel.Orphans != 1 || !reflect.DeepEqual(rel.Sample, []map[string]any{{"id": 4.0}}) {
+		t.Errorf("customers → orders = %+v", rel)
+	}
+
+	for _, rels := range []any{
+		nil,
+		[]any{},
+		[]any{map[string]any{"table": "orders", "ref_table": "customers"}},
+		[]any{map[string]any{"table": "orders", "columns": []any{"nope"}, "ref_table": "customers"}},
+		[]any{map[string]any{"table": "orders", "columns": []any{"customer_id"}, "ref_table": "nope"}},
+		[]any{map[string]any{"table": "orders", "columns": []any{"customer_id", "id"}, "ref_table": "customers"}},
+	} {
+		args := map[string]any{}
+		if rels != nil {
+			args["relationships"] = rels
+		}
+		if res := call("find_orphans", args); !res.IsError {
+			t.Errorf("find_orphans %v succeeded", rels)
+		}
+	}
+}
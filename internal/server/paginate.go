@@ -0,0 +1,51 @@
+package server
+
+import (
+	"encoding/base64"
+	"errors"
+	"sort"
+	"strings"
+)
+
+// Page sizes for list tools that take limit and cursor arguments.
+const (
+	defaultPageSize = 1000
+	maxPageSize     = 5000
+)
+
+// errBadCursor is returned for a cursor that paginate did not produce.
+var errBadCursor = errors.New("invalid cursor; pass the next_cursor of the previous page unchanged")
+
+// paginate returns the page of names (sorted, filtered to those starting
+// with prefix, case-insensitively) that follows cursor, at most limit long,
+// and the cursor of the next page, or "" on the last page. The cursor
+// encodes the last name returned, so pages stay consistent when names are
+// added or dropped between calls.
+func paginate(names []string, prefix, cursor string, limit int) (page []string, next string, err error) {
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	limit = min(limit, maxPageSize)
+	var after string
+	if cursor != "" {
+		b, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err != nil || len(b) == 0 {
+			return nil, "", errBadCursor
+		}
+		after = string(b)
+	}
+
+	sorted := make([]string, 0, len(names))
+	prefix = strings.ToLower(prefix)
+	for _, n := range names {
+		if strings.HasPrefix(strings.ToLower(n), prefix) && (cursor == "" || n > after) {
+			sorted = append(sorted, n)
+		}
+	}
+	sort.Strings(sorted)
+	if len(sorted) <= limit {
+		return sorted, "", nil
+	}
+	page = sorted[:limit]
+	return page, base64.RawURLEncoding.EncodeToString([]byte(page[limit-1])), nil
+}
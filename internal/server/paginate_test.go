@@ -0,0 +1,40 @@
+package server
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPaginate(t *testing.T) {
+	names := []string{"orders", "Tenant_b", "tenant_a", "users", "tenant_c"}
+
+	var got []string
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatal("too many pages")
+		}
+		page, next, err := paginate(names, "", cursor, 2)
+		if err != nil {
+			t.Fatalf("paginate: %v", err)
+		}
+		got = append(got, page...)
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	want := []string{"Tenant_b", "orders", "tenant_a", "tenant_c", "users"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("all pages = %v, want %v", got, want)
+	}
+
+	page, next, err := paginate(names, "TENANT", "", 0)
+	if err != nil || next != "" || !reflect.DeepEqual(page, []string{"Tenant_b", "tenant_a", "tenant_c"}) {
+		t.Errorf("prefix filter: %v %q %v", page, next, err)
+	}
+
+	if _, _, err := paginate(names, "", "not base64!", 2); err == nil {
+		t.Error("expected error for a malformed cursor")
+	}
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// toolOps maps the tools that run an operation a connection's permissions
+// can withhold to that operation.
+var toolOps = map[string]string{
+	"run_query":       config.OpSelect,
+	"insert_test_row": config.OpInsert,
+	"update_test_row": config.OpUpdate,
+	"export_database": config.OpExport,
+	"import_database": config.OpImport,
+}
+
+// checkPermission refuses op on schema.table (or on the connection, for an
+// empty table) unless connection connID's permissions allow it, before
+// anything is sent to the database. It returns nil if op may run.
+func checkPermission(cfg *config.Config, connID, op, schema, table string) *mcp.CallToolResult {
+	if cfg.Permits(connID, op, schema, table) {
+		return nil
+	}
+	p, _ := cfg.Permission(connID)
+	msg := fmt.Sprintf("connection %q does not allow %s", connID, op)
+	if cfg.Permits(connID, op, "", "") {
+		name := table
+		if schema != "" {
+			name = schema + "." + table
+		}
+		msg = fmt.Sprintf("connection %q allows %s only into tables matching %s, not %q",
+			connID, op, strings.Join(p.Tables, ", "), name)
+	}
+	allowed := "nothing"
+	if len(p.Allow) > 0 {
+		allowed = strings.Join(p.Allow, ", ")
+	}
+	return errorResult(ToolError{
+		Code:    CodePermissionDenied,
+		Message: msg,
+		Hint:    fmt.Sprintf("the connection's permissions in config.yaml allow: %s", allowed),
+	}, nil)
+}
+
+// permitsTransactions reports whether connection connID allows a write that
+// a transaction could hold.
+func permitsTransactions(cfg *config.Config, connID string) bool {
+	return cfg.Permits(connID, config.OpInsert, "", "") || cfg.Permits(connID, config.OpUpdate, "", "")
+}
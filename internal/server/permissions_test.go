@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestPermissions(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "app.db")
+	sqlDB, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDB.Close()
+	if _, err := sqlDB.Exec(`CREATE TABLE orders (id INTEGER PRIMARY KEY, v TEXT);
+		CREATE TABLE orders_test (id INTEGER PRIMARY KEY, v TEXT)`); err != nil {
+		t.Fatal(err)
+	}
+	c := newTestClient(t, `
+connections:
+  sqlite: "`+dbPath+`"
+  demo: demo
+permissions:
+  sqlite:
+    allow: [select, insert]
+    tables: ["*_test"]
+`)
+	call := func(name string, args map[string]any) *mcp.CallToolResult {
+		t.Helper()
+		res, err := c.CallTool(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{Name: name, Arguments: args}})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		return res
+	}
+
+	if res := call("run_query", map[string]any{"connection_id": "sqlite", "sql": "SELECT * FROM orders"}); res.IsError {
+		t.Errorf("select: %s", textContent(res))
+	}
+	if res := call("insert_test_row", map[string]any{"connection_id": "sqlite", "table": "orders_test", "row": map[string]any{"v": "a"}}); res.IsError {
+		t.Errorf("insert into orders_test: %s", textContent(res))
+	}
+	if res := call("insert_test_row", map[string]any{"connection_id": "sqlite", "table": "orders", "row": map[string]any{"v": "a"}}); resultCode(res) != CodePermissionDenied {
+		t.Errorf("insert into orders: %s", textContent(res))
+	}
+	if res := call("update_test_row", map[string]any{"connection_id": "sqlite", "table": "orders_test",
+		"key": map[string]any{"id": 1}, "set": map[string]any{"v": "b"}}); resultCode(res) != CodePermissionDenied {
+		t.Errorf("update: %s", textContent(res))
+	}
+	if res := call("export_database", map[string]any{"connection_id": "sqlite", "delivery": "resource"}); resultCode(res) != CodePermissionDenied {
+		t.Errorf("export: %s", textContent(res))
+	}
+	var n int
+	if err := sqlDB.QueryRow("SELECT COUNT(*) FROM orders").Scan(&n); err != nil || n != 0 {
+		t.Errorf("orders has %d rows (err %v), want 0", n, err)
+	}
+
+	// A connection without permissions allows everything.
+	if res := call("run_query", map[string]any{"connection_id": "demo", "sql": "SELECT 1"}); res.IsError {
+		t.Errorf("select on demo: %s", textContent(res))
+	}
+
+	// Tools only offer the connections that allow their operation.
+	tools, err := c.ListTools(ctx, mcp.ListToolsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	offered := func(name string) []string {
+		for _, tool := range tools.Tools {
+			if tool.Name == name {
+				prop, _ := tool.InputSchema.Properties["connection_id"].(map[string]any)
+				ids, _ := prop["enum"].([]any)
+				var out []string
+				for _, id := range ids {
+					out = append(out, id.(string))
+				}
+				return out
+			}
+		}
+		return nil
+	}
+	if got := offered("update_test_row"); !slices.Equal(got, []string{"demo"}) {
+		t.Errorf("update_test_row offers %v, want [demo]", got)
+	}
+	if got := offered("insert_test_row"); !slices.Equal(got, []string{"demo", "sqlite"}) {
+		t.Errorf("insert_test_row offers %v, want [demo sqlite]", got)
+	}
+}
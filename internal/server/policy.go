@@ -0,0 +1,228 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// PolicyDeniedOutput is the structured content of a call refused by a
+// policy rule (code permission_denied).
+type PolicyDeniedOutput struct {
+	ToolError
+	// Rule is the index of the deciding rule in policies.
+	Rule int `json:"rule"`
+}
+
+// policyCall is what policy rules match a tool call on.
+type policyCall struct {
+	tool, connID string
+	tables       []string // lower case; qualified ones also without schema
+	// mentions are all the names in the call's SQL, lower case, qualified
+	// ones also part by part: the candidates for a table the statement
+	// reads in a way tables misses.
+	mentions []string
+}
+
+// policyTableArgs are the arguments naming a table, with the arguments
+// naming its schema, the first one set applying.
+var policyTableArgs = []struct {
+	table  string
+	schema []string
+}{
+	{"table", []string{"schema"}},
+	{"other_table", []string{"other_schema", "schema"}},
+}
+
+// policyTableListArgs are the arguments holding objects with fields that
+// name tables, in the schema argument: find_orphans' relationships.
+var policyTableListArgs = map[string][]string{"relationships": {"table", "ref_table"}}
+
+// newPolicyCall reads a tool call's name, connection and tables from its
+// arguments. The tables of its SQL are read under the dialects of the
+// connection's type in cfg (see lexSQL).
+func newPolicyCall(cfg *config.Config, request mcp.CallToolRequest) policyCall {
+	c := policyCall{tool: request.Params.Name, connID: request.GetString("connection_id", "")}
+	var names []string
+	add := func(schema, table string) {
+		if table == "" {
+			return
+		}
+		if schema != "" {
+			names = append(names, schema+"."+table)
+		}
+		names = append(names, table)
+	}
+	for _, arg := range policyTableArgs {
+		schema := ""
+		for _, s := range arg.schema {
+			if schema = request.GetString(s, ""); schema != "" {
+				break
+			}
+		}
+		add(schema, request.GetString(arg.table, ""))
+	}
+	for arg, fields := range policyTableListArgs {
+		items, _ := request.GetArguments()[arg].([]any)
+		for _, item := range items {
+			obj, _ := item.(map[string]any)
+			for _, field := range fields {
+				table, _ := obj[field].(string)
+				add(request.GetString("schema", ""), table)
+			}
+		}
+	}
+	if sql := request.GetString("sql", ""); sql != "" {
+		typ, _ := cfg.Type(c.connID)
+		for _, d := range dialectsFor(typ) {
+			toks := lexSQL(sql, d)
+			for _, ref := range sqlRefs(toks) {
+				names = append(names, strings.Join(ref.parts, "."))
+			}
+			for _, n := range sqlNames(toks) {
+				if len(n.parts) > 1 {
+					c.mentions = append(c.mentions, strings.Join(n.parts, "."))
+				}
+				c.mentions = append(c.mentions, n.parts...)
+			}
+		}
+	}
+	for _, name := range names {
+		name = normalizeTableName(name)
+		c.tables = append(c.tables, name)
+		if _, table := splitTableName(name); table != name {
+			c.tables = append(c.tables, table)
+		}
+	}
+	return c
+}
+
+// decide returns the index of the first rule matching c in environment env,
+// or -1 if none does. A deny or confirm rule scoped to tables also matches
+// a call whose SQL mentions a matching name that is not one of its table
+// references (see untied), so a statement the table parsing misreads is
+// refused rather than let through.
+func decide(rules []config.PolicyRule, env string, c policyCall) int {
+	for i, r := range rules {
+		if policyMatch(r.Tool, c.tool) && policyMatch(r.Connection, c.connID) &&
+			policyMatch(r.Environment, env) && (r.Table == "" || policyMatchAny(r.Table, c.tables) ||
+			r.Action != config.PolicyAllow && policyMatchAny(r.Table, c.mentions)) {
+			return i
+		}
+	}
+	return -1
+}
+
+// untied reports whether r, a rule decide matched c with, is scoped to
+// tables that c's SQL mentions but does not reference where its table
+// parsing can see them.
+func (c policyCall) untied(r config.PolicyRule) bool {
+	return r.Table != "" && !policyMatchAny(r.Table, c.tables)
+}
+
+// policyMatch reports whether s matches the case-insensitive glob pattern,
+// which matches anything when empty.
+func policyMatch(pattern, s string) bool {
+	if pattern == "" {
+		return true
+	}
+	ok, _ := filepath.Match(strings.ToLower(pattern), strings.ToLower(s))
+	return ok
+}
+
+func policyMatchAny(pattern string, names []string) bool {
+	for _, name := range names {
+		if policyMatch(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// policyMiddleware evaluates the policies of cfg before each tool call: the
+// first matching rule allows it, denies it with permission_denied, or has
+// the human confirm it through MCP elicitation. Calls no rule matches go
+// ahead. A call also naming an other_connection_id is decided for each
+// connection, and must be let through for both. Every decision by a rule
+// is logged with the rule's index, and refused calls reach the audit log
+// with their error code.
+func policyMiddleware(s *server.MCPServer, cfg *config.Config) server.ToolHandlerMiddleware {
+	rules := cfg.Policies()
+	env := cfg.Environment()
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			call := newPolicyCall(cfg, request)
+			calls := []policyCall{call}
+			// A tool reading a second connection must pass its rules too.
+			if other := request.GetString("other_connection_id", ""); other != "" && other != call.connID {
+				otherCall := call
+				otherCall.connID = other
+				calls = append(calls, otherCall)
+			}
+			for _, call := range calls {
+				i := decide(rules, env, call)
+				if i < 0 {
+					continue
+				}
+				r := rules[i]
+				slog.Debug("policy decision", "request_id", RequestID(ctx), "tool", call.tool,
+					"connection_id", call.connID, "rule", i, "action", r.Action)
+				if call.untied(r) {
+					return policyDenied(i, r, fmt.Sprintf("%s on connection %q mentions a table policy rule %d covers where the table it reads cannot be determined", call.tool, call.connID, i)), nil
+				}
+				switch r.Action {
+				case config.PolicyDeny:
+					return policyDenied(i, r, fmt.Sprintf("%s on connection %q is denied by policy rule %d", call.tool, call.connID, i)), nil
+				case config.PolicyConfirm:
+					if err := askUser(ctx, s, policyPrompt(r, request), "Allow this call",
+						"Check to let the tool call go ahead"); err != nil {
+						if errors.Is(err, errConfirmationUnavailable) {
+							err = fmt.Errorf("%w; policy rule %d requires confirmation, which needs a client with MCP elicitation support", err, i)
+						}
+						return toolErrorResult(err), nil
+					}
+				}
+			}
+			return next(ctx, request)
+		}
+	}
+}
+
+// policyPrompt describes a call a confirm rule matched to the human.
+func policyPrompt(r config.PolicyRule, request mcp.CallToolRequest) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "An agent wants to call %s", request.Params.Name)
+	if connID := request.GetString("connection_id", ""); connID != "" {
+		fmt.Fprintf(&b, " on the %q connection", connID)
+		if other := request.GetString("other_connection_id", ""); other != "" && other != connID {
+			fmt.Fprintf(&b, " and the %q one", other)
+		}
+	}
+	if table := request.GetString("table", ""); table != "" {
+		fmt.Fprintf(&b, ", table %s", table)
+	}
+	b.WriteString(".")
+	if sql := request.GetString("sql", ""); sql != "" {
+		fmt.Fprintf(&b, "\n\n%s", sql)
+	}
+	if r.Reason != "" {
+		fmt.Fprintf(&b, "\n\nPolicy: %s", r.Reason)
+	}
+	b.WriteString("\n\nAllow it?")
+	return b.String()
+}
+
+func policyDenied(i int, r config.PolicyRule, msg string) *mcp.CallToolResult {
+	e := ToolError{Code: CodePermissionDenied, Message: msg, Hint: r.Reason}
+	if e.Hint == "" {
+		e.Hint = "this call is not allowed by the server's policies in config.yaml"
+	}
+	return errorResult(e, PolicyDeniedOutput{ToolError: e, Rule: i})
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestDecide(t *testing.T) {
+	rules := []config.PolicyRule{
+		{Tool: "run_query", Table: "audit_*", Action: config.PolicyAllow},
+		{Tool: "*_test_row", Environment: "prod*", Action: config.PolicyDeny},
+		{Connection: "shared", Table: "billing.*", Action: config.PolicyConfirm},
+		{Table: "secrets", Action: config.PolicyDeny},
+	}
+	cfg := newTestConfig(t, "connections:\n  sqlite: \":memory:\"\n")
+	call := func(tool, connID string, args map[string]any) policyCall {
+		req := mcp.CallToolRequest{}
+		req.Params.Name = tool
+		args["connection_id"] = connID
+		req.Params.Arguments = args
+		return newPolicyCall(cfg, req)
+	}
+	tests := []struct {
+		env  string
+		call policyCall
+		want int
+	}{
+		{"prod", call("run_query", "shared", map[string]any{"sql": "SELECT * FROM Audit_Log"}), 0},
+		{"prod", call("insert_test_row", "local", map[string]any{"table": "users"}), 1},
+		{"dev", call("insert_test_row", "local", map[string]any{"table": "users"}), -1},
+		{"dev", call("update_test_row", "shared", map[string]any{"schema": "billing", "table": "invoices"}), 2},
+		{"dev", call("run_query", "shared", map[string]any{"sql": "SELECT * FROM users u JOIN billing.invoices i ON i.user_id = u.id"}), 2},
+		{"dev", call("list_tables", "shared", map[string]any{}), -1},
+		{"dev", call("run_query", "shared", map[string]any{"sql": "SELECT * FROM users, billing.invoices"}), 2},
+		{"dev", call("run_query", "shared", map[string]any{"sql": "SELECT * FROM users u, (SELECT 1) x, billing.invoices i"}), 2},
+		// A name the table parsing cannot place still matches a confirm rule.
+		{"dev", call("run_query", "shared", map[string]any{"sql": "WITH t AS (SELECT 1) SELECT billing.invoices.id FROM t"}), 2},
+		{"dev", call("run_query", "shared", map[string]any{"sql": "SELECT * FROM users WHERE note = 'billing.invoices'"}), -1},
+		// Every argument naming a table is checked.
+		{"dev", call("compare_table_data", "local", map[string]any{"table": "users", "other_table": "secrets"}), 3},
+		{"dev", call("find_orphans", "local", map[string]any{"relationships": []any{
+			map[string]any{"table": "users", "columns": []any{"id"}, "ref_table": "Secrets"},
+		}}), 3},
+		// String literals and comments are read the way the database does.
+		{"dev", call("run_query", "local", map[string]any{"sql": "SELECT '--', * FROM secrets"}), 3},
+		{"dev", call("run_query", "sqlite", map[string]any{"sql": "SELECT 1 AS ['], * FROM users, (SELECT 1 AS [']) x, secrets"}), 3},
+		{"dev", call("run_query", "local", map[string]any{"sql": "SELECT * FROM users -- secrets"}), -1},
+	}
+	for i, tt := range tests {
+		if got := decide(rules, tt.env, tt.call); got != tt.want {
+			t.Errorf("case %d: decide = %d, want %d", i, got, tt.want)
+		}
+	}
+}
+
+func TestPolicyMiddleware(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "app.db")
+	sqlDB, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDB.Close()
+	if _, err := sqlDB.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT); CREATE TABLE secrets (id INTEGER PRIMARY KEY)"); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(config.EnvEnvironment, "")
+	s, _ := newTestServer(t, `
+connections:
+  sqlite: "`+dbPath+`"
+environment: staging
+policies:
+  - {tool: insert_test_row, environment: prod, action: deny}
+  - {tool: insert_test_row, table: users, action: deny, reason: users are seeded by migrations}
+  - {tool: run_query, table: secrets, action: confirm, reason: secrets holds credentials}
+`)
+
+	human := &fakeHuman{}
+	c := connectTestClient(t, s, transport.WithElicitationHandler(human))
+	call := func(name string, args map[string]any) *mcp.CallToolResult {
+		t.Helper()
+		args["connection_id"] = "sqlite"
+		res, err := c.CallTool(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{Name: name, Arguments: args}})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		return res
+	}
+
+	res := call("insert_test_row", map[string]any{"table": "users", "row": map[string]any{"name": "a"}})
+	if resultCode(res) != CodePermissionDenied || !strings.Contains(textContent(res), "rule 1") ||
+		!strings.Contains(textContent(res), "seeded by migrations") {
+		t.Errorf("insert into users: %s", textContent(res))
+	}
+	if res := call("insert_test_row", map[string]any{"table": "secrets", "row": map[string]any{"id": 1}}); res.IsError {
+		t.Errorf("insert no rule denies: %s", textContent(res))
+	}
+
+	if res := call("run_query", map[string]any{"sql": "SELECT * FROM secrets"}); !res.IsError {
+		t.Error("declined query on secrets should fail")
+	}
+	if len(human.messages) != 1 || !strings.Contains(human.messages[0], "SELECT * FROM secrets") ||
+		!strings.Contains(human.messages[0], "secrets holds credentials") {
+		t.Errorf("prompt = %q", human.messages)
+	}
+	human.approve = true
+	if res := call("run_query", map[string]any{"sql": "SELECT * FROM secrets"}); res.IsError {
+		t.Errorf("approved query on secrets: %s", textContent(res))
+	}
+	if res := call("run_query", map[string]any{"sql": "SELECT * FROM users"}); res.IsError || len(human.messages) != 2 {
+		t.Errorf("query on users should run without asking: %s", textContent(res))
+	}
+	// The second table of a comma join is checked too.
+	if res := call("run_query", map[string]any{"sql": "SELECT * FROM users, secrets"}); res.IsError || len(human.messages) != 3 {
+		t.Errorf("comma join with secrets should ask: %s (%d prompts)", textContent(res), len(human.messages))
+	}
+	// A mention of secrets that is not a table reference is refused.
+	res = call("run_query", map[string]any{"sql": "SELECT (SELECT count(*) FROM users) AS n, secrets.id FROM users AS secrets"})
+	if resultCode(res) != CodePermissionDenied || len(human.messages) != 3 {
+		t.Errorf("untied mention of secrets: %s", textContent(res))
+	}
+}
@@ -0,0 +1,252 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/codegen"
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Limits of profile_table.
+const (
+	DefaultProfileSampleRows = 100000
+	MaxProfileSampleRows     = 1000000
+	DefaultProfileTopK       = 5
+	MaxProfileTopK           = 50
+)
+
+// ProfileTableOutput is the result of profile_table.
+type ProfileTableOutput struct {
+	Table string `json:"table"`
+	// Rows is the number of rows of the table.
+	Rows int64 `json:"rows"`
+	// Sampled is set if the table has more rows than the statistics were
+	// computed over: the first ProfiledRows.
+	Sampled      bool            `json:"sampled"`
+	ProfiledRows int64           `json:"profiled_rows"`
+	Columns      []ColumnProfile `json:"columns"`
+}
+
+// ColumnProfile holds the statistics of a column. Those its type cannot
+// give (min and max of a JSON column, say) are left out.
+type ColumnProfile struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	// Kind is what the type holds, as codegen maps it.
+	Kind string `json:"kind"`
+	// NullFraction is the fraction of the profiled rows that are NULL.
+	NullFraction float64 `json:"null_fraction"`
+	Distinct     *int64  `json:"distinct,omitempty"`
+	Min          any     `json:"min,omitempty"`
+	Max          any     `json:"max,omitempty"`
+	// AvgLength is the average length in characters of its strings.
+	AvgLength *float64 `json:"avg_length,omitempty"`
+	// TopValues are its most common values, most common first. They are
+	// left out if every value is distinct.
+	TopValues []ValueCount `json:"top_values,omitempty"`
+}
+
+// ValueCount is a value of a column and how many profiled rows hold it.
+type ValueCount struct {
+	Value any   `json:"value"`
+	Count int64 `json:"count"`
+}
+
+// profileStats reports which statistics profile_table computes of a column
+// of type typ on a connection of type engine: what its kind supports.
+func profileStats(engine, typ string) (distinct, minMax, length bool) {
+	if engine == "sqlserver" {
+		switch strings.ToLower(typ) {
+		case "text", "ntext", "image":
+			return false, false, false // legacy types that cannot be compared
+		}
+	}
+	switch codegen.MapType(engine, typ).Kind {
+	case codegen.KindString:
+		return true, true, true
+	case codegen.KindInteger, codegen.KindFloat, codegen.KindDecimal, codegen.KindTimestamp, codegen.KindDate, codegen.KindTime:
+		return true, true, false
+	case codegen.KindBool, codegen.KindUUID:
+		return true, false, false
+	}
+	return false, false, false
+}
+
+// profileTable computes the statistics of the columns of table of schema
+// on connID (all if columns is empty) over its first sampleRows rows,
+// with the topK most common values of each. Queries read the table through
+// its row filter, if any, and masking rules apply to the values returned.
+func profileTable(ctx context.Context, cfg *config.Config, mgr *db.Manager, connID, schema, table string, columns []string, sampleRows, topK int) *mcp.CallToolResult {
+	if res := checkPermission(cfg, connID, config.OpSelect, "", ""); res != nil {
+		return res
+	}
+	tables, schema, res := describeSchema(ctx, cfg, mgr, connID, schema, table)
+	if res != nil {
+		return res
+	}
+	cols := tables[0].cols
+	if len(columns) > 0 {
+		picked := make([]db.ColumnInfo, 0, len(columns))
+		for _, name := range columns {
+			i := -1
+			for j, c := range cols {
+				if c.Name == name {
+					i = j
+				}
+			}
+			if i < 0 {
+				return invalidArgs(fmt.Sprintf("table %q has no column %q", table, name))
+			}
+			picked = append(picked, cols[i])
+		}
+		cols = picked
+	}
+	engine, _ := cfg.Type(connID)
+	driver, err := mgr.Driver(ctx, connID)
+	if err != nil {
+		return toolErrorResult(err)
+	}
+	query := filteredQuerier(ctx, cfg, driver, connID)
+
+	rows, res := query(db.ProfileQuery(engine, schema, table, nil, 0), nil)
+	if res != nil {
+		return res
+	}
+	out := ProfileTableOutput{Table: table, Columns: make([]ColumnProfile, len(cols))}
+	if len(rows) > 0 {
+		out.Rows, _ = asInt64(rows[0]["rows"])
+	}
+	sample := 0
+	out.ProfiledRows = out.Rows
+	if out.Rows > int64(sampleRows) {
+		sample, out.Sampled, out.ProfiledRows = sampleRows, true, int64(sampleRows)
+	}
+
+	specs := make([]db.ProfileColumn, len(cols))
+	var minMax []string
+	for i, c := range cols {
+		distinct, hasMinMax, length := profileStats(engine, c.Type)
+		specs[i] = db.ProfileColumn{Name: c.Name, Distinct: distinct, Length: length}
+		if hasMinMax {
+			minMax = append(minMax, c.Name)
+		}
+		out.Columns[i] = ColumnProfile{Name: c.Name, Type: c.Type, Kind: codegen.MapType(engine, c.Type).Kind.String()}
+	}
+	if len(cols) == 0 || out.ProfiledRows == 0 {
+		return profileResult(out)
+	}
+
+	rows, res = query(db.ProfileQuery(engine, schema, table, specs, sample), nil)
+	if res != nil {
+		return res
+	}
+	stats := map[string]any{}
+	if len(rows) > 0 {
+		stats = rows[0]
+	}
+	nonNull := make([]int64, len(cols))
+	for i := range cols {
+		p := &out.Columns[i]
+		nonNull[i], _ = asInt64(stats[fmt.Sprintf("nonnull_%d", i)])
+		p.NullFraction = float64(out.ProfiledRows-nonNull[i]) / float64(out.ProfiledRows)
+		if n, ok := asInt64(stats[fmt.Sprintf("distinct_%d", i)]); ok && specs[i].Distinct {
+			p.Distinct = &n
+		}
+		if f, ok := asFloat64(stats[fmt.Sprintf("length_%d", i)]); ok && specs[i].Length {
+			p.AvgLength = &f
+		}
+	}
+
+	if len(minMax) > 0 {
+		mins, res := query(db.ColumnAggregateQuery(engine, schema, table, "MIN", minMax, sample), nil)
+		if res != nil {
+			return res
+		}
+		maxes, res := query(db.ColumnAggregateQuery(engine, schema, table, "MAX", minMax, sample), nil)
+		if res != nil {
+			return res
+		}
+		for i := range out.Columns {
+			p := &out.Columns[i]
+			if len(mins) > 0 {
+				p.Min = mins[0][p.Name]
+			}
+			if len(maxes) > 0 {
+				p.Max = maxes[0][p.Name]
+			}
+		}
+	}
+
+	for i := range cols {
+		p := &out.Columns[i]
+		if topK == 0 || p.Distinct == nil || *p.Distinct == 0 || *p.Distinct == nonNull[i] {
+			continue
+		}
+		sql, countColumn := db.TopValuesQuery(engine, schema, table, p.Name, topK, sample)
+		rows, res := query(sql, nil)
+		if res != nil {
+			return res
+		}
+		p.TopValues = make([]ValueCount, 0, len(rows))
+		for _, r := range rows {
+			n, _ := asInt64(r[countColumn])
+			p.TopValues = append(p.TopValues, ValueCount{Value: r[p.Name], Count: n})
+		}
+	}
+	return profileResult(out)
+}
+
+func profileResult(out ProfileTableOutput) *mcp.CallToolResult {
+	res, err := mcp.NewToolResultJSON(out)
+	if err != nil {
+		return toolErrorResult(err)
+	}
+	return res
+}
+
+// asInt64 converts a count as a driver returns it; MySQL returns sums as
+// text.
+func asInt64(v any) (int64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return v, true
+	case int32:
+		return int64(v), true
+	case int:
+		return int64(v), true
+	case float64:
+		return int64(v), true
+	case string:
+		n, err := strconv.ParseInt(v, 10, 64)
+		return n, err == nil
+	case []byte:
+		n, err := strconv.ParseInt(string(v), 10, 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// asFloat64 converts an average as a driver returns it; MySQL returns
+// decimals as text.
+func asFloat64(v any) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	case []byte:
+		f, err := strconv.ParseFloat(string(v), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
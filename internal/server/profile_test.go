@@ -0,0 +1,59 @@
+package server
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestProfileTableTool(t *testing.T) {
+	call := demoCaller(t)
+	profile := func(args map[string]any) ProfileTableOutput {
+		t.Helper()
+		res := call("profile_table", args)
+		if res.IsError {
+			t.Fatalf("profile_table %v: %s", args, textContent(res))
+		}
+		var out ProfileTableOutput
+		if err := json.Unmarshal([]byte(textContent(res)), &out); err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	out := profile(map[string]any{"table": "customers"})
+	if out.Rows != 5 || out.Sampled || out.ProfiledRows != 5 || len(out.Columns) != 5 {
+		t.Fatalf("profile = %+v", out)
+	}
+	id, city := out.Columns[0], out.Columns[3]
+	if id.Name != "id" || id.Kind != "integer" || id.NullFraction != 0 || *id.Distinct != 5 || id.Min != 1.0 || id.Max != 5.0 ||
+		id.AvgLength != nil || id.TopValues != nil {
+		t.Errorf("id = %+v", id)
+	}
+	if city.Name != "city" || city.NullFraction != 0.2 || *city.Distinct != 3 || city.Min != "Berlin" || city.Max != "Prague" ||
+		*city.AvgLength != 6 {
+		t.Errorf("city = %+v", city)
+	}
+	if want := []ValueCount{{"Prague", 2}, {"Berlin", 1}, {"London", 1}}; len(city.TopValues) != 3 ||
+		city.TopValues[0] != want[0] || city.TopValues[1] != want[1] || city.TopValues[2] != want[2] {
+		t.Errorf("city top values = %v, want %v", city.TopValues, want)
+	}
+
+	out = profile(map[string]any{"table": "customers", "columns": []any{"city"}, "sample_rows": 2, "top_k": 0})
+	if out.Rows != 5 || !out.Sampled || out.ProfiledRows != 2 || len(out.Columns) != 1 {
+		t.Fatalf("sampled profile = %+v", out)
+	}
+	if city := out.Columns[0]; city.NullFraction != 0 || *city.Distinct != 2 || city.TopValues != nil {
+		t.Errorf("sampled city = %+v", city)
+	}
+
+	for _, args := range []map[string]any{
+		{"table": "nope"},
+		{"table": "customers", "columns": []any{"nope"}},
+		{"table": "customers", "sample_rows": 0},
+		{"table": "customers", "top_k": MaxProfileTopK + 1},
+	} {
+		if res := call("profile_table", args); !res.IsError {
+			t.Errorf("profile_table %v succeeded", args)
+		}
+	}
+}
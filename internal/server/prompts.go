@@ -0,0 +1,118 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// maxPromptTestRows caps the rows argument of the create_test_data prompt.
+const maxPromptTestRows = 50
+
+// registerPrompts adds prompt templates for common database workflows. Each
+// prompt spells out which tools to call, in which order, so clients with
+// prompt support start a task the same way every time.
+func registerPrompts(s *server.MCPServer, cfg *config.Config) {
+	s.AddPrompt(mcp.NewPrompt("explore_schema",
+		mcp.WithPromptDescription("Inspect a connection's schema and propose a read-only query that answers a question."),
+		mcp.WithArgument("connection_id", mcp.RequiredArgument(), mcp.ArgumentDescription("Connection ID (see list_connections)")),
+		mcp.WithArgument("question", mcp.RequiredArgument(), mcp.ArgumentDescription("What you want to find out from the data")),
+		mcp.WithArgument("schema", mcp.ArgumentDescription("Schema to look in (optional)")),
+	), func(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		args := request.Params.Arguments
+		connID, err := promptConnection(cfg, args["connection_id"])
+		if err != nil {
+			return nil, err
+		}
+		question := strings.TrimSpace(args["question"])
+		if question == "" {
+			return nil, fmt.Errorf("question is required")
+		}
+
+		var b strings.Builder
+		fmt.Fprintf(&b, "Answer this question using the %q connection: %s\n\n", connID, question)
+		b.WriteString("Work through these steps:\n")
+		fmt.Fprintf(&b, "1. Call list_tables with connection_id=%q%s to see what exists.\n", connID, schemaArg(args["schema"]))
+		b.WriteString("2. Call describe_table for each table that looks relevant; note primary keys and the columns that join tables together.\n")
+		b.WriteString("3. Propose a single SELECT that answers the question. Use positional params for literal values and add a LIMIT while exploring.\n")
+		fmt.Fprintf(&b, "4. Run it with run_query on connection_id=%q, check the result makes sense, and refine if needed.\n", connID)
+		b.WriteString("5. Reply with the final query, a short explanation of how it answers the question, and the key results.\n\n")
+		b.WriteString("Do not modify data: run_query is read-only, and this task does not need insert_test_row or update_test_row.")
+
+		return mcp.NewGetPromptResult("Inspect schema and propose a query",
+			[]mcp.PromptMessage{mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(b.String()))}), nil
+	})
+
+	if cfg.ReadOnly() {
+		// create_test_data relies on insert_test_row, which is not offered.
+		return
+	}
+	s.AddPrompt(mcp.NewPrompt("create_test_data",
+		mcp.WithPromptDescription("Insert a few realistic, clearly fake rows into a table for local testing."),
+		mcp.WithArgument("connection_id", mcp.RequiredArgument(), mcp.ArgumentDescription("Connection ID (see list_connections)")),
+		mcp.WithArgument("table", mcp.RequiredArgument(), mcp.ArgumentDescription("Table to populate")),
+		mcp.WithArgument("rows", mcp.ArgumentDescription(fmt.Sprintf("Number of rows to insert (default 5, max %d)", maxPromptTestRows))),
+		mcp.WithArgument("schema", mcp.ArgumentDescription("Schema of the table (optional)")),
+	), func(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		args := request.Params.Arguments
+		connID, err := promptConnection(cfg, args["connection_id"])
+		if err != nil {
+			return nil, err
+		}
+		table := strings.TrimSpace(args["table"])
+		if table == "" {
+			return nil, fmt.Errorf("table is required")
+		}
+		rows := 5
+		if v := strings.TrimSpace(args["rows"]); v != "" {
+			rows, err = strconv.Atoi(v)
+			if err != nil || rows < 1 || rows > maxPromptTestRows {
+				return nil, fmt.Errorf("rows must be a number between 1 and %d", maxPromptTestRows)
+			}
+		}
+		sch := schemaArg(args["schema"])
+
+		var b strings.Builder
+		fmt.Fprintf(&b, "Create %d rows of safe test data in table %q on the %q connection.\n\n", rows, table, connID)
+		b.WriteString("Work through these steps:\n")
+		fmt.Fprintf(&b, "1. Call describe_table with connection_id=%q, table=%q%s. Note column types, NOT NULL columns, defaults and the primary key.\n", connID, table, sch)
+		b.WriteString("2. Use run_query to look at a few existing rows (SELECT ... LIMIT 5) so new values match the table's conventions, and to find valid values for any foreign-key columns.\n")
+		b.WriteString("3. Invent values that satisfy every constraint. Make them obviously fake (e.g. example.com e-mail addresses, \"Test\" name prefixes) and never copy real personal data.\n")
+		fmt.Fprintf(&b, "4. Insert the rows one at a time with insert_test_row (connection_id=%q, table=%q%s, return_id=true). Leave generated primary keys out of the row.\n", connID, table, sch)
+		b.WriteString("5. If an insert fails, read the error, fix that row and retry it; do not change or delete existing rows.\n")
+		b.WriteString("6. Reply with the inserted IDs and a short summary of the data created.")
+
+		return mcp.NewGetPromptResult("Create safe test data",
+			[]mcp.PromptMessage{mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(b.String()))}), nil
+	})
+}
+
+// promptConnection validates a connection_id prompt argument. The error
+// lists the configured connections so the client can offer a valid choice.
+func promptConnection(cfg *config.Config, id string) (string, error) {
+	id = strings.TrimSpace(id)
+	if id != "" && cfg.HasConnection(id) {
+		return id, nil
+	}
+	ids := cfg.ConnectionIDs()
+	sort.Strings(ids)
+	if id == "" {
+		return "", fmt.Errorf("connection_id is required; configured connections: %s", strings.Join(ids, ", "))
+	}
+	return "", fmt.Errorf("unknown connection_id %q; configured connections: %s", id, strings.Join(ids, ", "))
+}
+
+// schemaArg renders an optional schema argument for a tool-call hint.
+func schemaArg(schema string) string {
+	schema = strings.TrimSpace(schema)
+	if schema == "" {
+		return ""
+	}
+	return fmt.Sprintf(", schema=%q", schema)
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestPrompts(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t, "connections:\n  sqlite: \":memory:\"\n")
+
+	list, err := c.ListPrompts(ctx, mcp.ListPromptsRequest{})
+	if err != nil {
+		t.Fatalf("ListPrompts: %v", err)
+	}
+	names := map[string]bool{}
+	for _, p := range list.Prompts {
+		names[p.Name] = true
+	}
+	if !names["explore_schema"] || !names["create_test_data"] {
+		t.Fatalf("expected explore_schema and create_test_data, got %v", names)
+	}
+
+	req := mcp.GetPromptRequest{}
+	req.Params.Name = "create_test_data"
+	req.Params.Arguments = map[string]string{"connection_id": "sqlite", "table": "users", "rows": "3"}
+	res, err := c.GetPrompt(ctx, req)
+	if err != nil {
+		t.Fatalf("GetPrompt: %v", err)
+	}
+	if len(res.Messages) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(res.Messages))
+	}
+	text, ok := res.Messages[0].Content.(mcp.TextContent)
+	if !ok {
+		t.Fatalf("expected text content, got %T", res.Messages[0].Content)
+	}
+	for _, want := range []string{"Create 3 rows", `connection_id="sqlite"`, "describe_table", "insert_test_row"} {
+		if !strings.Contains(text.Text, want) {
+			t.Errorf("prompt text missing %q:\n%s", want, text.Text)
+		}
+	}
+
+	req.Params.Arguments = map[string]string{"connection_id": "nope", "table": "users"}
+	if _, err := c.GetPrompt(ctx, req); err == nil || !strings.Contains(err.Error(), "sqlite") {
+		t.Errorf("unknown connection should fail listing configured IDs, got %v", err)
+	}
+}
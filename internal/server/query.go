@@ -1,6 +1,7 @@
 package server
 
 import (
+	"errors"
 	"fmt"
 	"regexp"
 	"strings"
@@ -20,18 +21,26 @@ var (
 	forbiddenWordRe = regexp.MustCompile(`(?i)\b(` + strings.Join(forbiddenSQLWords, "|") + `)\b`)
 )
 
+// ErrNotReadOnly is wrapped by ValidateReadOnlySQL errors for statements
+// that would modify data or schema.
+var ErrNotReadOnly = errors.New("read-only queries only")
+
 // ValidateReadOnlySQL returns an error if sql appears to be non–read-only (INSERT/UPDATE/DELETE/DDL etc).
 // It strips line (--) and block (/* */) comments before checking. Only a simple heuristic; not a full parser.
 func ValidateReadOnlySQL(sql string) error {
-	cleaned := sqlLineComment.ReplaceAllString(sql, " ")
-	cleaned = sqlBlockComment.ReplaceAllString(cleaned, " ")
-	cleaned = strings.TrimSpace(cleaned)
+	cleaned := strings.TrimSpace(stripSQLComments(sql))
 	if cleaned == "" {
 		return fmt.Errorf("empty SQL after removing comments")
 	}
 	if loc := forbiddenWordRe.FindStringIndex(cleaned); loc != nil {
 		word := strings.ToUpper(cleaned[loc[0]:loc[1]])
-		return fmt.Errorf("read-only queries only: found %q", word)
+		return fmt.Errorf("%w: found %q", ErrNotReadOnly, word)
 	}
 	return nil
 }
+
+// stripSQLComments replaces line (--) and block (/* */) comments with spaces.
+func stripSQLComments(sql string) string {
+	cleaned := sqlLineComment.ReplaceAllString(sql, " ")
+	return sqlBlockComment.ReplaceAllString(cleaned, " ")
+}
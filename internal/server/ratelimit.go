@@ -0,0 +1,149 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// toolClasses maps the database tools to the class their rate limit is
+// taken from. Tools not listed (ping, list_connections, health,
+// connection_stats, refresh_schema, close_connection, commit_transaction,
+// rollback_transaction) are never limited.
+var toolClasses = map[string]string{
+	"list_tables":                 config.ToolClassRead,
+	"describe_table":              config.ToolClassRead,
+	"table_json_schema":           config.ToolClassRead,
+	"generate_go_struct":          config.ToolClassRead,
+	"generate_typescript_types":   config.ToolClassRead,
+	"generate_prisma_schema":      config.ToolClassRead,
+	"generate_graphql_sdl":        config.ToolClassRead,
+	"generate_erd":                config.ToolClassRead,
+	"compare_schemas":             config.ToolClassRead,
+	"compare_table_data":          config.ToolClassRead,
+	"profile_table":               config.ToolClassRead,
+	"find_duplicates":             config.ToolClassRead,
+	"check_referential_integrity": config.ToolClassRead,
+	"find_orphans":                config.ToolClassRead,
+	"suggest_indexes":             config.ToolClassRead,
+	"explain_query":               config.ToolClassRead,
+	"list_dependencies":           config.ToolClassRead,
+	"run_query":                   config.ToolClassRead,
+	"insert_test_row":             config.ToolClassWrite,
+	"update_test_row":             config.ToolClassWrite,
+	"begin_transaction":           config.ToolClassWrite,
+	"import_database":             config.ToolClassWrite,
+	"create_test_table":           config.ToolClassWrite,
+	"export_database":             config.ToolClassExport,
+}
+
+// RateLimitedOutput is the structured content of a call refused by the rate
+// limiter (code rate_limited). Clients should wait RetryAfterMS before
+// calling again.
+type RateLimitedOutput struct {
+	ToolError
+	ToolClass    string `json:"tool_class"`
+	ConnectionID string `json:"connection_id,omitempty"`
+	RetryAfterMS int64  `json:"retry_after_ms"`
+}
+
+// tokenBucket holds up to burst tokens and refills at rate tokens per second.
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// take removes one token if available. Otherwise it reports how long until
+// one will be.
+func (b *tokenBucket) take(now time.Time) (ok bool, retryAfter time.Duration) {
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// rateLimiter keeps one token bucket per tool class and connection, so a
+// burst of queries against one database does not block another.
+type rateLimiter struct {
+	limits map[string]config.RateLimit
+	// byConnection replaces limits for a class on a connection, by
+	// connection ID and then class.
+	byConnection map[string]map[string]config.RateLimit
+	// cfg, if set, holds the connections calls are limited on: a call on
+	// any other ID takes no token, so it gets no bucket, and fails in its
+	// handler.
+	cfg *config.Config
+	now func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket // keyed by class + "/" + connection ID
+}
+
+func newRateLimiter(limits map[string]config.RateLimit) *rateLimiter {
+	return &rateLimiter{limits: limits, now: time.Now, buckets: make(map[string]*tokenBucket)}
+}
+
+// allow takes a token for a call of class on connID.
+func (l *rateLimiter) allow(class, connID string) (ok bool, retryAfter time.Duration) {
+	if l.cfg != nil {
+		if _, known := l.cfg.Type(connID); !known {
+			return true, 0
+		}
+	}
+	limit, limited := l.byConnection[connID][class]
+	if !limited {
+		limit, limited = l.limits[class]
+	}
+	if !limited || limit.Rate <= 0 {
+		return true, 0
+	}
+	now := l.now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	key := class + "/" + connID
+	b, exists := l.buckets[key]
+	if !exists {
+		burst := float64(max(limit.Burst, 1))
+		b = &tokenBucket{rate: limit.Rate, burst: burst, tokens: burst, last: now}
+		l.buckets[key] = b
+	}
+	return b.take(now)
+}
+
+// middleware refuses calls that exceed their class's limit with a
+// rate_limited error carrying the retry delay.
+func (l *rateLimiter) middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		class, ok := toolClasses[request.Params.Name]
+		if !ok {
+			return next(ctx, request)
+		}
+		connID := request.GetString("connection_id", "")
+		allowed, retryAfter := l.allow(class, connID)
+		if allowed {
+			return next(ctx, request)
+		}
+		retryMS := retryAfter.Milliseconds() + 1
+		e := ToolError{
+			Code:    CodeRateLimited,
+			Message: fmt.Sprintf("rate limit exceeded for %s tools on connection %q; retry after %dms", class, connID, retryMS),
+		}
+		return errorResult(e, RateLimitedOutput{
+			ToolError:    e,
+			ToolClass:    class,
+			ConnectionID: connID,
+			RetryAfterMS: retryMS,
+		}), nil
+	}
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestRateLimiter_allow(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := newRateLimiter(map[string]config.RateLimit{
+		config.ToolClassWrite: {Rate: 2, Burst: 2},
+		config.ToolClassRead:  {Rate: 0},
+	})
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.allow(config.ToolClassWrite, "pg"); !ok {
+			t.Fatalf("call %d within burst was refused", i+1)
+		}
+	}
+	ok, retry := l.allow(config.ToolClassWrite, "pg")
+	if ok || retry != 500*time.Millisecond {
+		t.Errorf("third call: ok=%v retry=%v, want refused with 500ms", ok, retry)
+	}
+	if ok, _ := l.allow(config.ToolClassWrite, "mysql"); !ok {
+		t.Error("other connections have their own bucket")
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if ok, _ := l.allow(config.ToolClassWrite, "pg"); !ok {
+		t.Error("expected a token after refill")
+	}
+
+	for i := 0; i < 100; i++ {
+		if ok, _ := l.allow(config.ToolClassRead, "pg"); !ok {
+			t.Fatal("rate 0 should disable the limit")
+		}
+	}
+}
+
+func TestRateLimiter_byConnection(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := newRateLimiter(config.DefaultRateLimits)
+	l.byConnection = map[string]map[string]config.RateLimit{
+		"shared": {config.ToolClassWrite: {Rate: 1.0 / 60, Burst: 1}},
+		"local":  {config.ToolClassWrite: {Rate: 0}},
+	}
+	l.now = func() time.Time { return now }
+
+	if ok, _ := l.allow(config.ToolClassWrite, "shared"); !ok {
+		t.Fatal("first write on shared was refused")
+	}
+	if ok, retry := l.allow(config.ToolClassWrite, "shared"); ok || retry != time.Minute {
+		t.Errorf("second write on shared: ok=%v retry=%v, want refused for a minute", ok, retry)
+	}
+	if ok, _ := l.allow(config.ToolClassRead, "shared"); !ok {
+		t.Error("reads on shared keep the default limit")
+	}
+	for i := 0; i < 10; i++ {
+		if ok, _ := l.allow(config.ToolClassWrite, "local"); !ok {
+			t.Fatal("rate 0 on a connection should disable its write limit")
+		}
+	}
+	burst := config.DefaultRateLimits[config.ToolClassWrite].Burst
+	for i := 0; i < burst; i++ {
+		if ok, _ := l.allow(config.ToolClassWrite, "other"); !ok {
+			t.Fatalf("write %d within the default burst was refused", i+1)
+		}
+	}
+	if ok, _ := l.allow(config.ToolClassWrite, "other"); ok {
+		t.Error("other connections should get the default write limit")
+	}
+}
+
+func TestRateLimiter_unknownConnection(t *testing.T) {
+	l := newRateLimiter(map[string]config.RateLimit{config.ToolClassRead: {Rate: 1, Burst: 1}})
+	l.cfg = newTestConfig(t, "connections:\n  sqlite: \":memory:\"\n")
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.allow(config.ToolClassRead, fmt.Sprintf("missing-%d", i)); !ok {
+			t.Fatal("a call on an unknown connection was limited")
+		}
+	}
+	if len(l.buckets) != 0 {
+		t.Errorf("unknown connections got %d buckets, want none", len(l.buckets))
+	}
+	if ok, _ := l.allow(config.ToolClassRead, "sqlite"); !ok || len(l.buckets) != 1 {
+		t.Errorf("first call on sqlite: ok=%v with %d buckets", ok, len(l.buckets))
+	}
+}
+
+func TestRateLimiter_middleware(t *testing.T) {
+	l := newRateLimiter(map[string]config.RateLimit{config.ToolClassRead: {Rate: 1, Burst: 1}})
+	l.now = func() time.Time { return time.Unix(0, 0) }
+	handler := l.middleware(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("ok"), nil
+	})
+	call := func(name string) *mcp.CallToolResult {
+		req := mcp.CallToolRequest{}
+		req.Params.Name = name
+		req.Params.Arguments = map[string]any{"connection_id": "pg"}
+		res, err := handler(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	if res := call("run_query"); res.IsError {
+		t.Fatal("first call should pass")
+	}
+	res := call("run_query")
+	if !res.IsError {
+		t.Fatal("second call should be rate limited")
+	}
+	out, ok := res.StructuredContent.(RateLimitedOutput)
+	if !ok || out.Code != CodeRateLimited || out.ToolClass != config.ToolClassRead || out.ConnectionID != "pg" || out.RetryAfterMS <= 0 {
+		t.Errorf("unexpected structured error: %#v", res.StructuredContent)
+	}
+	for i := 0; i < 3; i++ {
+		if res := call("ping"); res.IsError {
+			t.Error("unclassified tools are not limited")
+		}
+	}
+}
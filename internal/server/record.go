@@ -0,0 +1,108 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// RecordedCall is one line of a recording: a tool call and what the client
+// got back, written when cfg.RecordFile is set and re-sent by localdb-mcp
+// replay.
+type RecordedCall struct {
+	Time      time.Time      `json:"time"`
+	RequestID string         `json:"request_id,omitempty"`
+	SessionID string         `json:"session_id,omitempty"`
+	Tool      string         `json:"tool"`
+	Arguments map[string]any `json:"arguments,omitempty"`
+	// Result is the structured content of a successful call, or its text
+	// when that is not JSON; Error is the structured content of a failed
+	// one (a ToolError).
+	Result     json.RawMessage `json:"result,omitempty"`
+	Error      json.RawMessage `json:"error,omitempty"`
+	DurationMS int64           `json:"duration_ms"`
+}
+
+// recorder appends every tool call to a JSONL file. It runs outside the
+// redaction middleware, so it records failed results as the client sees
+// them, without credentials; arguments and rows are recorded as they are,
+// so the file is created readable by its owner only.
+type recorder struct {
+	mu  sync.Mutex
+	f   *os.File
+	enc *json.Encoder
+}
+
+// openRecorder opens the recording at path for appending, creating it and
+// its directory if needed.
+func openRecorder(path string) (*recorder, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &recorder{f: f, enc: json.NewEncoder(f)}, nil
+}
+
+func (r *recorder) close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.f.Close()
+}
+
+// middleware records every tool call once it returns. A call that cannot
+// be recorded is logged but not failed.
+func (r *recorder) middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		start := time.Now()
+		res, err := next(ctx, request)
+		rec := RecordedCall{
+			Time:       start.UTC(),
+			RequestID:  RequestID(ctx),
+			SessionID:  sessionID(ctx),
+			Tool:       request.Params.Name,
+			Arguments:  request.GetArguments(),
+			DurationMS: time.Since(start).Milliseconds(),
+		}
+		switch {
+		case err != nil:
+			rec.Error, _ = json.Marshal(ToolError{Code: CodeInternal, Message: err.Error()})
+		case res != nil && res.IsError:
+			rec.Error = recordedContent(res)
+		case res != nil:
+			rec.Result = recordedContent(res)
+		}
+		r.mu.Lock()
+		werr := r.enc.Encode(rec)
+		r.mu.Unlock()
+		if werr != nil {
+			slog.Error("recording write failed", "request_id", rec.RequestID, "tool", rec.Tool, "err", werr)
+		}
+		return res, err
+	}
+}
+
+// recordedContent returns the structured content of res, or its text as
+// JSON, or as a JSON string if it is not JSON.
+func recordedContent(res *mcp.CallToolResult) json.RawMessage {
+	if res.StructuredContent != nil {
+		if b, err := json.Marshal(res.StructuredContent); err == nil {
+			return b
+		}
+	}
+	text := errorText(res)
+	if json.Valid([]byte(text)) {
+		return json.RawMessage(text)
+	}
+	b, _ := json.Marshal(text)
+	return b
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestRecordFile(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "app.db")
+	sqlDB, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDB.Close()
+	if _, err := sqlDB.Exec("CREATE TABLE orders (id INTEGER PRIMARY KEY, total INTEGER)"); err != nil {
+		t.Fatal(err)
+	}
+	recPath := filepath.Join(dir, "rec", "session.jsonl")
+	t.Setenv(config.EnvRecordFile, "")
+	s, mgr := newTestServer(t, "connections:\n  sqlite: \""+dbPath+"\"\nrecord_file: "+recPath+"\n")
+
+	c := connectTestClient(t, s)
+	for _, args := range []map[string]any{
+		{"connection_id": "sqlite", "sql": "SELECT 7 AS total"},
+		{"connection_id": "sqlite", "sql": "SELECT * FROM missing"},
+	} {
+		if _, err := c.CallTool(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{Name: "run_query", Arguments: args}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mgr.Close()
+
+	f, err := os.Open(recPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if fi, _ := f.Stat(); fi.Mode().Perm() != 0o600 {
+		t.Errorf("recording mode = %v, want 0600", fi.Mode().Perm())
+	}
+	var calls []RecordedCall
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var rc RecordedCall
+		if err := json.Unmarshal(sc.Bytes(), &rc); err != nil {
+			t.Fatalf("line %q: %v", sc.Text(), err)
+		}
+		calls = append(calls, rc)
+	}
+	if len(calls) != 2 {
+		t.Fatalf("recorded %d calls, want 2", len(calls))
+	}
+	if calls[0].Tool != "run_query" || calls[0].Arguments["sql"] != "SELECT 7 AS total" || string(calls[0].Result) != `{"rows":[{"total":7}]}` || calls[0].Error != nil {
+		t.Errorf("first call = %+v", calls[0])
+	}
+	var e ToolError
+	if calls[1].Result != nil || json.Unmarshal(calls[1].Error, &e) != nil || e.Code != CodeDatabaseError || calls[1].RequestID == "" {
+		t.Errorf("failed call = %+v", calls[1])
+	}
+}
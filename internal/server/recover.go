@@ -0,0 +1,35 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// recoverMiddleware turns a panic in a tool handler (say, a driver
+// dereferencing nil) into an internal error result for that call, so one
+// bad call does not take down the server and every client attached to it.
+// The panic and its stack are logged.
+func recoverMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (res *mcp.CallToolResult, err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				slog.Error("tool call panicked",
+					"request_id", RequestID(ctx),
+					"tool", request.Params.Name,
+					"panic", p,
+					"stack", string(debug.Stack()))
+				res, err = errorResult(ToolError{
+					Code:    CodeInternal,
+					Message: fmt.Sprintf("internal error in %s: %v", request.Params.Name, p),
+					Hint:    "this is a bug in localdb-mcp; the server is still running, so other calls are unaffected",
+				}, nil), nil
+			}
+		}()
+		return next(ctx, request)
+	}
+}
@@ -0,0 +1,39 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func TestRecoverMiddleware(t *testing.T) {
+	ctx := context.Background()
+	s := server.NewMCPServer(ServerName, ServerVersion)
+	Register(s, nil)
+	s.AddTool(mcp.NewTool("boom"), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var d *struct{ name string }
+		return mcp.NewToolResultText(d.name), nil
+	})
+
+	c := connectTestClient(t, s)
+
+	res, err := c.CallTool(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{Name: "boom"}})
+	if err != nil {
+		t.Fatalf("CallTool(boom): %v", err)
+	}
+	m, _ := res.StructuredContent.(map[string]any)
+	if !res.IsError || m["code"] != CodeInternal || m["request_id"] == nil {
+		t.Errorf("panic result: %+v", res)
+	}
+	if text := textContent(res); !strings.Contains(text, "nil pointer dereference") {
+		t.Errorf("text %q should describe the panic", text)
+	}
+
+	res, err = c.CallTool(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{Name: "ping"}})
+	if err != nil || res.IsError {
+		t.Errorf("ping after a panic: %v %+v", err, res)
+	}
+}
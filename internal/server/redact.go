@@ -0,0 +1,130 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// redactMiddleware removes credentials (see config.Config.Redact) from the
+// text and structured content of failed results and from errors, whichever
+// handler or middleware further in produced them: driver errors, dump tool
+// output and panics can all echo a DSN.
+func redactMiddleware(cfg *config.Config) server.ToolHandlerMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			res, err := next(ctx, request)
+			if err != nil {
+				if msg := cfg.Redact(err.Error()); msg != err.Error() {
+					err = errors.New(msg)
+				}
+			}
+			if res != nil && res.IsError {
+				redactResult(cfg, res)
+			}
+			return res, err
+		}
+	}
+}
+
+// redactResult redacts the text content and the strings in the structured
+// content of res.
+func redactResult(cfg *config.Config, res *mcp.CallToolResult) {
+	for i, c := range res.Content {
+		if tc, ok := mcp.AsTextContent(c); ok {
+			tc.Text = cfg.Redact(tc.Text)
+			res.Content[i] = *tc
+		}
+	}
+	if res.StructuredContent == nil {
+		return
+	}
+	b, err := json.Marshal(res.StructuredContent)
+	if err != nil {
+		return
+	}
+	var v any
+	if json.Unmarshal(b, &v) == nil {
+		res.StructuredContent = redactValue(cfg, v)
+	}
+}
+
+// redactValue redacts every string in a decoded JSON value.
+func redactValue(cfg *config.Config, v any) any {
+	switch v := v.(type) {
+	case string:
+		return cfg.Redact(v)
+	case map[string]any:
+		for k, e := range v {
+			v[k] = redactValue(cfg, e)
+		}
+	case []any:
+		for i, e := range v {
+			v[i] = redactValue(cfg, e)
+		}
+	}
+	return v
+}
+
+// RedactingHandler returns a slog.Handler that removes credentials (see
+// config.Config.Redact) from the message and attributes of every record
+// before passing it to h.
+func RedactingHandler(h slog.Handler, cfg *config.Config) slog.Handler {
+	return &redactingHandler{h: h, cfg: cfg}
+}
+
+type redactingHandler struct {
+	h   slog.Handler
+	cfg *config.Config
+}
+
+func (r *redactingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return r.h.Enabled(ctx, level)
+}
+
+func (r *redactingHandler) Handle(ctx context.Context, rec slog.Record) error {
+	out := slog.NewRecord(rec.Time, rec.Level, r.cfg.Redact(rec.Message), rec.PC)
+	rec.Attrs(func(a slog.Attr) bool {
+		out.AddAttrs(r.attr(a))
+		return true
+	})
+	return r.h.Handle(ctx, out)
+}
+
+func (r *redactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redacted[i] = r.attr(a)
+	}
+	return &redactingHandler{h: r.h.WithAttrs(redacted), cfg: r.cfg}
+}
+
+func (r *redactingHandler) WithGroup(name string) slog.Handler {
+	return &redactingHandler{h: r.h.WithGroup(name), cfg: r.cfg}
+}
+
+// attr redacts the value of a, formatting errors and other values that
+// are not numbers, times or booleans as strings first.
+func (r *redactingHandler) attr(a slog.Attr) slog.Attr {
+	v := a.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, r.cfg.Redact(v.String()))
+	case slog.KindAny:
+		return slog.String(a.Key, r.cfg.Redact(fmt.Sprint(v.Any())))
+	case slog.KindGroup:
+		group := v.Group()
+		redacted := make([]any, len(group))
+		for i, ga := range group {
+			redacted[i] = r.attr(ga)
+		}
+		return slog.Group(a.Key, redacted...)
+	}
+	return a
+}
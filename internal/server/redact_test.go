@@ -0,0 +1,70 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const redactTestURI = "root:my-s3cret@tcp(mysql.internal:3306)/app"
+
+func loadRedactConfig(t *testing.T) *config.Config {
+	t.Helper()
+	cfg := newTestConfig(t, "connections:\n  mysql: \""+redactTestURI+"\"\n")
+	return cfg
+}
+
+func TestRedactMiddleware(t *testing.T) {
+	cfg := loadRedactConfig(t)
+	handler := redactMiddleware(cfg)(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return errorResult(ToolError{
+			Code:    CodeDatabaseError,
+			Message: "mysqldump failed: Access denied (using password: my-s3cret)",
+			Hint:    "check " + redactTestURI,
+		}, nil), nil
+	})
+	res, err := handler(context.Background(), mcp.CallToolRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := textContent(res); strings.Contains(text, "my-s3cret") || !strings.Contains(text, config.Redacted) {
+		t.Errorf("text = %q", text)
+	}
+	if resultCode(res) != CodeDatabaseError {
+		t.Errorf("code = %q, want it kept", resultCode(res))
+	}
+	sc := res.StructuredContent.(map[string]any)
+	if strings.Contains(sc["message"].(string), "my-s3cret") || strings.Contains(sc["hint"].(string), "my-s3cret") {
+		t.Errorf("structured content = %v", sc)
+	}
+
+	handler = redactMiddleware(cfg)(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return nil, errors.New("dial " + redactTestURI + ": refused")
+	})
+	if _, err := handler(context.Background(), mcp.CallToolRequest{}); err == nil || strings.Contains(err.Error(), "my-s3cret") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestRedactingHandler(t *testing.T) {
+	cfg := loadRedactConfig(t)
+	var buf bytes.Buffer
+	log := slog.New(RedactingHandler(slog.NewTextHandler(&buf, nil), cfg))
+	log.With("uri", redactTestURI).Warn("connect to "+redactTestURI+" failed",
+		"err", errors.New("password my-s3cret rejected"),
+		slog.Group("conn", "dsn", redactTestURI),
+		"attempts", 3)
+	out := buf.String()
+	if strings.Contains(out, "my-s3cret") {
+		t.Errorf("log line leaks the password: %s", out)
+	}
+	if !strings.Contains(out, "attempts=3") {
+		t.Errorf("log line lost other attributes: %s", out)
+	}
+}
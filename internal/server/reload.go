@@ -0,0 +1,98 @@
+package server
+
+import (
+	"maps"
+	"slices"
+	"sort"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Reload applies the connections of next to a running server. cfg (the
+// Config passed to Register) takes over next's connections, mgr closes the
+// drivers of connections that were added, removed or changed, and every
+// tool's connection_id parameter is re-advertised with the new IDs, which
+// sends notifications/tools/list_changed to connected clients. Reload
+// returns the IDs that changed.
+func Reload(s *server.MCPServer, mgr *db.Manager, cfg, next *config.Config) ([]string, error) {
+	changed := cfg.ReplaceConnections(next)
+	if len(changed) == 0 {
+		return nil, nil
+	}
+	err := mgr.Forget(changed...)
+	advertiseConnections(s, cfg)
+	return changed, err
+}
+
+// advertiseConnections lists the configured connection IDs as the enum of
+// every tool's connection_id parameter, and other_connection_id of the
+// tools reading two connections, so clients can offer valid values.
+// A tool in toolNeeds lists only the connections whose backend supports it,
+// and a write tool only the connections that are not read-only.
+// The tools are re-added with their existing handlers.
+func advertiseConnections(s *server.MCPServer, cfg *config.Config) {
+	all := cfg.ConnectionIDs()
+	sort.Strings(all)
+	var updated []server.ServerTool
+	for _, st := range s.ListTools() {
+		if _, ok := st.Tool.InputSchema.Properties["connection_id"].(map[string]any); !ok {
+			continue
+		}
+		var ids []string
+		for _, id := range all {
+			if offers(cfg, st.Tool.Name, id) {
+				ids = append(ids, id)
+			}
+		}
+		tool := st.Tool
+		tool.InputSchema.Properties = maps.Clone(tool.InputSchema.Properties)
+		for _, name := range []string{"connection_id", "other_connection_id"} {
+			prop, ok := tool.InputSchema.Properties[name].(map[string]any)
+			if !ok {
+				continue
+			}
+			prop = maps.Clone(prop)
+			if len(ids) > 0 {
+				prop["enum"] = ids
+			} else {
+				delete(prop, "enum") // an empty enum would reject every value
+			}
+			tool.InputSchema.Properties[name] = prop
+		}
+		updated = append(updated, server.ServerTool{Tool: tool, Handler: st.Handler})
+	}
+	if len(updated) > 0 {
+		s.AddTools(updated...)
+	}
+}
+
+// offers reports whether tool should list connection id.
+func offers(cfg *config.Config, tool, id string) bool {
+	if (slices.Contains(writeTools, tool) || tool == "enable_writes" || tool == "create_test_table") && cfg.ConnectionReadOnly(id) {
+		return false
+	}
+	if op, ok := toolOps[tool]; ok && !cfg.Permits(id, op, "", "") {
+		return false
+	}
+	if tool == "create_test_table" && !cfg.SessionSchemas(id) {
+		return false
+	}
+	if tool == "begin_transaction" && !permitsTransactions(cfg, id) {
+		return false
+	}
+	if tool == "import_database" && len(cfg.WriteSchemas(id)) > 0 {
+		return false
+	}
+	if (tool == "export_database" || tool == "import_database") && (len(cfg.RowFilters(id)) > 0 || cfg.SchemaLocked(id)) {
+		return false
+	}
+	needs, ok := toolNeeds[tool]
+	if !ok {
+		return true
+	}
+	typ, _ := cfg.Type(id)
+	caps, ok := db.CapabilitiesFor(typ)
+	return ok && needs(caps)
+}
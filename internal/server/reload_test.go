@@ -0,0 +1,103 @@
+package server
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func connectionEnum(t *testing.T, s *server.MCPServer, tool string) any {
+	t.Helper()
+	st := s.GetTool(tool)
+	if st == nil {
+		t.Fatalf("%s not registered", tool)
+	}
+	prop, _ := st.Tool.InputSchema.Properties["connection_id"].(map[string]any)
+	return prop["enum"]
+}
+
+func TestReload(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	write := func(body string) *config.Config {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := config.LoadFrom(path)
+		if err != nil {
+			t.Fatalf("LoadFrom: %v", err)
+		}
+		return cfg
+	}
+	for _, env := range []string{config.EnvPostgresURI, config.EnvSQLServerURI, config.EnvSQLiteURI, config.EnvMySQLURI} {
+		t.Setenv(env, "")
+	}
+
+	cfg := write("connections:\n  sqlite: \":memory:\"\n")
+	s := server.NewMCPServer(ServerName, ServerVersion)
+	mgr := Register(s, cfg)
+	defer mgr.Close()
+	if got := connectionEnum(t, s, "run_query"); !reflect.DeepEqual(got, []string{"sqlite"}) {
+		t.Fatalf("connection_id enum = %v, want [sqlite]", got)
+	}
+
+	ts := httptest.NewServer(newSSEServer(s))
+	defer ts.Close()
+	c, err := client.NewSSEMCPClient(ts.URL + "/sse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err := c.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	notified := make(chan struct{}, 10)
+	c.OnNotification(func(n mcp.JSONRPCNotification) {
+		if n.Method == mcp.MethodNotificationToolsListChanged {
+			notified <- struct{}{}
+		}
+	})
+	initReq := mcp.InitializeRequest{}
+	initReq.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	res, err := c.Initialize(ctx, initReq)
+	if err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	if res.Capabilities.Tools == nil || !res.Capabilities.Tools.ListChanged {
+		t.Error("server should advertise tools.listChanged")
+	}
+
+	next := write("connections:\n  sqlite: \":memory:\"\n  mysql: \"root@tcp(localhost:1)/db\"\n")
+	changed, err := Reload(s, mgr, cfg, next)
+	if err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if !reflect.DeepEqual(changed, []string{"mysql"}) {
+		t.Errorf("changed = %v, want [mysql]", changed)
+	}
+	if got := connectionEnum(t, s, "describe_table"); !reflect.DeepEqual(got, []string{"mysql", "sqlite"}) {
+		t.Errorf("connection_id enum after reload = %v", got)
+	}
+	if !cfg.HasConnection("mysql") {
+		t.Error("cfg should have the reloaded connections")
+	}
+	select {
+	case <-notified:
+	case <-time.After(2 * time.Second):
+		t.Error("expected notifications/tools/list_changed after reload")
+	}
+
+	if changed, _ := Reload(s, mgr, cfg, next); changed != nil {
+		t.Errorf("reloading the same config changed %v", changed)
+	}
+}
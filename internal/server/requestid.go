@@ -0,0 +1,96 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+type requestIDKey struct{}
+
+// RequestID returns the ID of the tool call running on ctx, or "" outside
+// a tool call.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// newRequestID returns a short random ID for a tool call.
+func newRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("t%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// requestIDMiddleware gives every tool call an ID, carried on its context
+// (see RequestID) and in its log line. Failed calls return the ID as
+// request_id in their structured content and at the end of their text, so
+// an error an agent reports can be matched to the server log.
+func requestIDMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		id := newRequestID()
+		ctx = context.WithValue(ctx, requestIDKey{}, id)
+		start := time.Now()
+		res, err := next(ctx, request)
+
+		attrs := []any{
+			"request_id", id,
+			"tool", request.Params.Name,
+			"duration", time.Since(start),
+		}
+		if connID := request.GetString("connection_id", ""); connID != "" {
+			attrs = append(attrs, "connection_id", connID)
+		}
+		switch {
+		case err != nil:
+			slog.Warn("tool call failed", append(attrs, "err", err)...)
+			err = fmt.Errorf("%w (request_id: %s)", err, id)
+		case res != nil && res.IsError:
+			tagRequestID(res, id)
+			slog.Warn("tool call failed", append(attrs, "err", errorText(res))...)
+		default:
+			slog.Debug("tool call", attrs...)
+		}
+		return res, err
+	}
+}
+
+// tagRequestID adds id to the structured content and text of a failed
+// result.
+func tagRequestID(res *mcp.CallToolResult, id string) {
+	if res.StructuredContent != nil {
+		if b, err := json.Marshal(res.StructuredContent); err == nil {
+			var m map[string]any
+			if json.Unmarshal(b, &m) == nil && m != nil {
+				m["request_id"] = id
+				res.StructuredContent = m
+			}
+		}
+	}
+	for i, c := range res.Content {
+		if tc, ok := mcp.AsTextContent(c); ok {
+			tc.Text = fmt.Sprintf("%s (request_id: %s)", tc.Text, id)
+			res.Content[i] = *tc
+			break
+		}
+	}
+}
+
+// errorText returns the first text content of res.
+func errorText(res *mcp.CallToolResult) string {
+	for _, c := range res.Content {
+		if tc, ok := mcp.AsTextContent(c); ok {
+			return tc.Text
+		}
+	}
+	return ""
+}
@@ -0,0 +1,47 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	var seen []string
+	handler := requestIDMiddleware(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		seen = append(seen, RequestID(ctx))
+		if request.Params.Name == "fail" {
+			return invalidArgs("table is required"), nil
+		}
+		return mcp.NewToolResultText("ok"), nil
+	})
+
+	res, err := handler(context.Background(), mcp.CallToolRequest{Params: mcp.CallToolParams{Name: "ok"}})
+	if err != nil || res.IsError || textContent(res) != "ok" {
+		t.Fatalf("successful call changed: %v %+v", err, res)
+	}
+	res, err = handler(context.Background(), mcp.CallToolRequest{Params: mcp.CallToolParams{Name: "fail"}})
+	if err != nil || !res.IsError {
+		t.Fatalf("failed call: %v %+v", err, res)
+	}
+
+	if len(seen) != 2 || seen[0] == "" || seen[0] == seen[1] {
+		t.Fatalf("request IDs %q, want two distinct IDs", seen)
+	}
+	id := seen[1]
+	if want := "table is required (request_id: " + id + ")"; textContent(res) != want {
+		t.Errorf("text %q, want %q", textContent(res), want)
+	}
+	m, ok := res.StructuredContent.(map[string]any)
+	if !ok || m["request_id"] != id || m["code"] != CodeValidationFailed {
+		t.Errorf("structured content %#v, want code and request_id %s", res.StructuredContent, id)
+	}
+	if RequestID(context.Background()) != "" {
+		t.Error("RequestID outside a tool call should be empty")
+	}
+	if strings.ContainsAny(id, " ()") {
+		t.Errorf("request ID %q should be a plain token", id)
+	}
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// TruncatedSummary is added as "truncated" to a result that was cut down to
+// fit the size limit.
+type TruncatedSummary struct {
+	Field    string `json:"field"`    // the list that was cut, e.g. "rows"
+	Returned int    `json:"returned"` // items kept
+	Omitted  int    `json:"omitted"`  // items dropped from the end
+	Hint     string `json:"hint"`
+}
+
+// sizeGuardMiddleware keeps tool results under maxBytes, counting the text
+// and structured content that are both sent. An oversized result is cut to
+// the first items of its largest list with a TruncatedSummary added, rather
+// than sent whole to clients that may silently drop it; one with nothing to
+// cut fails with result_too_large.
+func sizeGuardMiddleware(maxBytes int) server.ToolHandlerMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			res, err := next(ctx, request)
+			if err != nil || res == nil || res.IsError || resultSize(res) <= maxBytes {
+				return res, err
+			}
+			if truncated, ok := truncateResult(res, maxBytes); ok {
+				return truncated, nil
+			}
+			return errorResult(ToolError{
+				Code:    CodeResultTooLarge,
+				Message: fmt.Sprintf("%s result is larger than %d bytes and cannot be truncated", request.Params.Name, maxBytes),
+				Hint:    "ask for less data, or raise max_result_bytes in config.yaml",
+			}, nil), nil
+		}
+	}
+}
+
+// resultSize is the serialized size of res's text and structured content.
+func resultSize(res *mcp.CallToolResult) int {
+	n := 0
+	for _, c := range res.Content {
+		if tc, ok := mcp.AsTextContent(c); ok {
+			n += len(tc.Text)
+		}
+	}
+	if res.StructuredContent != nil {
+		b, _ := json.Marshal(res.StructuredContent)
+		n += len(b)
+	}
+	return n
+}
+
+// truncateResult rebuilds a JSON result with its largest list cut to the
+// longest prefix that fits in maxBytes. It reports false when the result is
+// not a JSON object with a list to cut.
+func truncateResult(res *mcp.CallToolResult, maxBytes int) (*mcp.CallToolResult, bool) {
+	var obj map[string]any
+	if res.StructuredContent != nil {
+		b, err := json.Marshal(res.StructuredContent)
+		if err != nil || json.Unmarshal(b, &obj) != nil {
+			return nil, false
+		}
+	} else if len(res.Content) == 1 {
+		tc, ok := mcp.AsTextContent(res.Content[0])
+		if !ok || json.Unmarshal([]byte(tc.Text), &obj) != nil {
+			return nil, false
+		}
+	}
+
+	field, items := largestList(obj)
+	if items == nil {
+		return nil, false
+	}
+	// The JSON is sent twice when there is structured content.
+	budget := maxBytes
+	if res.StructuredContent != nil {
+		budget /= 2
+	}
+	build := func(n int) []byte {
+		obj[field] = items[:n]
+		obj["truncated"] = TruncatedSummary{
+			Field:    field,
+			Returned: n,
+			Omitted:  len(items) - n,
+			Hint:     "the result was cut to fit max_result_bytes; narrow it (add a LIMIT or WHERE, select fewer columns) or page through it",
+		}
+		b, _ := json.Marshal(obj)
+		return b
+	}
+	n := sort.Search(len(items)+1, func(n int) bool { return len(build(n)) > budget }) - 1
+	if n < 0 {
+		return nil, false
+	}
+	b := build(n)
+
+	out := &mcp.CallToolResult{Content: []mcp.Content{mcp.NewTextContent(string(b))}}
+	if res.StructuredContent != nil {
+		out.StructuredContent = obj
+	}
+	return out, true
+}
+
+// largestList returns the key of the longest non-empty list in obj, the
+// first by name on a tie.
+func largestList(obj map[string]any) (string, []any) {
+	var field string
+	var items []any
+	for k, v := range obj {
+		if list, ok := v.([]any); ok && (len(list) > len(items) || len(list) == len(items) && len(list) > 0 && k < field) {
+			field, items = k, list
+		}
+	}
+	return field, items
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestSizeGuardMiddleware(t *testing.T) {
+	rows := make([]map[string]any, 100)
+	for i := range rows {
+		rows[i] = map[string]any{"id": i, "name": strings.Repeat("x", 100)}
+	}
+	results := map[string]func() (*mcp.CallToolResult, error){
+		"small": func() (*mcp.CallToolResult, error) { return mcp.NewToolResultJSON(RunQueryOutput{Rows: rows[:2]}) },
+		"rows":  func() (*mcp.CallToolResult, error) { return mcp.NewToolResultJSON(RunQueryOutput{Rows: rows}) },
+		"blob":  func() (*mcp.CallToolResult, error) { return mcp.NewToolResultText(strings.Repeat("y", 10000)), nil },
+	}
+	const limit = 4000
+	handler := sizeGuardMiddleware(limit)(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return results[request.Params.Name]()
+	})
+	call := func(name string) *mcp.CallToolResult {
+		t.Helper()
+		res, err := handler(context.Background(), mcp.CallToolRequest{Params: mcp.CallToolParams{Name: name}})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		return res
+	}
+
+	if res := call("small"); res.IsError || resultSize(res) > limit {
+		t.Errorf("small result changed: %+v", res)
+	}
+	if _, ok := call("small").StructuredContent.(RunQueryOutput); !ok {
+		t.Error("a result under the limit should be passed through untouched")
+	}
+
+	res := call("rows")
+	if res.IsError || resultSize(res) > limit {
+		t.Fatalf("truncated result is %d bytes: %+v", resultSize(res), res)
+	}
+	var out struct {
+		Rows      []map[string]any `json:"rows"`
+		Truncated TruncatedSummary `json:"truncated"`
+	}
+	if err := json.Unmarshal([]byte(textContent(res)), &out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if out.Truncated.Field != "rows" || out.Truncated.Returned != len(out.Rows) ||
+		out.Truncated.Returned+out.Truncated.Omitted != len(rows) || len(out.Rows) == 0 {
+		t.Errorf("summary %+v with %d rows", out.Truncated, len(out.Rows))
+	}
+
+	res = call("blob")
+	if e, ok := res.StructuredContent.(ToolError); !res.IsError || !ok || e.Code != CodeResultTooLarge {
+		t.Errorf("oversized text result: %+v", res)
+	}
+}
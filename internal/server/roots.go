@@ -0,0 +1,81 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+	"path/filepath"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// exportDirs returns the directories the dump tools may use for the client
+// of ctx. A client that declares MCP roots is confined to its roots (plus
+// the configured export directories; see config.RootExportDirs); other
+// clients get cfg.ExportDirs.
+func exportDirs(ctx context.Context, s *server.MCPServer, sessions *sessionRegistry, cfg *config.Config) []string {
+	if roots, ok := sessions.clientRoots(ctx, s); ok {
+		return cfg.RootExportDirs(roots)
+	}
+	return cfg.ExportDirs()
+}
+
+// clientRoots returns the local directories among the roots the client of
+// ctx declared, and false if it does not support roots or could not be
+// asked. Roots are fetched once per session and again after the client
+// sends notifications/roots/list_changed.
+func (r *sessionRegistry) clientRoots(ctx context.Context, s *server.MCPServer) ([]string, bool) {
+	cs, ok := server.ClientSessionFromContext(ctx).(server.SessionWithClientInfo)
+	if !ok || cs.GetClientCapabilities().Roots == nil {
+		return nil, false
+	}
+	st := r.get(ctx)
+	st.mu.Lock()
+	roots, known := st.roots, st.rootsKnown
+	st.mu.Unlock()
+	if known {
+		return roots, true
+	}
+
+	res, err := s.RequestRoots(ctx, mcp.ListRootsRequest{})
+	if err != nil {
+		slog.Warn("list client roots; falling back to the configured export directories", "err", err)
+		return nil, false
+	}
+	roots = rootPaths(res.Roots)
+	st.mu.Lock()
+	st.roots, st.rootsKnown = roots, true
+	st.mu.Unlock()
+	return roots, true
+}
+
+// watchRoots makes a session re-fetch its roots after the client reports
+// that they changed.
+func (r *sessionRegistry) watchRoots(s *server.MCPServer) {
+	s.AddNotificationHandler(mcp.MethodNotificationRootsListChanged, func(ctx context.Context, _ mcp.JSONRPCNotification) {
+		st := r.get(ctx)
+		st.mu.Lock()
+		st.roots, st.rootsKnown = nil, false
+		st.mu.Unlock()
+	})
+}
+
+// rootPaths converts file:// root URIs to absolute paths, skipping any
+// other kind of root.
+func rootPaths(roots []mcp.Root) []string {
+	paths := make([]string, 0, len(roots))
+	for _, root := range roots {
+		u, err := url.Parse(root.URI)
+		if err != nil || u.Scheme != "file" || (u.Host != "" && u.Host != "localhost") {
+			continue
+		}
+		p := filepath.FromSlash(u.Path)
+		if !filepath.IsAbs(p) {
+			continue
+		}
+		paths = append(paths, filepath.Clean(p))
+	}
+	return paths
+}
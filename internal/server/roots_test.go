@@ -0,0 +1,121 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// fakeWorkspace answers roots/list with a fixed list of directories.
+type fakeWorkspace struct {
+	mu      sync.Mutex
+	dirs    []string
+	calls   int
+	session string // ID of the session that asked
+}
+
+func (w *fakeWorkspace) ListRoots(ctx context.Context, request mcp.ListRootsRequest) (*mcp.ListRootsResult, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.calls++
+	w.session = sessionID(ctx)
+	res := &mcp.ListRootsResult{}
+	for _, d := range w.dirs {
+		res.Roots = append(res.Roots, mcp.Root{URI: "file://" + filepath.ToSlash(d)})
+	}
+	return res, nil
+}
+
+func TestExportDirs_clientRoots(t *testing.T) {
+	ctx := context.Background()
+	workspace, elsewhere := t.TempDir(), t.TempDir()
+	for _, dir := range []string{workspace, elsewhere} {
+		if err := os.WriteFile(filepath.Join(dir, "dump.sql"), []byte("CREATE TABLE IF NOT EXISTS t (id INTEGER);\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv(config.EnvExportDirs, "")
+	s, _ := newTestServer(t, "connections:\n  sqlite: \""+filepath.Join(t.TempDir(), "test.db")+"\"\nrate_limits:\n  write: {rate: 0}\n")
+
+	ws := &fakeWorkspace{dirs: []string{workspace}}
+	c := client.NewClient(transport.NewInProcessTransportWithOptions(s, transport.WithRootsHandler(ws)), client.WithRootsHandler(ws))
+	if err := c.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	initReq := mcp.InitializeRequest{}
+	initReq.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	if _, err := c.Initialize(ctx, initReq); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+
+	// importFrom confirms the import with the token of the first call.
+	importFrom := func(dir string) *mcp.CallToolResult {
+		t.Helper()
+		args := map[string]any{"connection_id": "sqlite", "path": filepath.Join(dir, "dump.sql")}
+		call := func() *mcp.CallToolResult {
+			res, err := c.CallTool(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{Name: "import_database", Arguments: args}})
+			if err != nil {
+				t.Fatalf("import_database: %v", err)
+			}
+			return res
+		}
+		res := call()
+		var pending ConfirmationRequiredOutput
+		if res.IsError || json.Unmarshal([]byte(textContent(res)), &pending) != nil || pending.ConfirmationToken == "" {
+			return res
+		}
+		args["confirmation_token"] = pending.ConfirmationToken
+		return call()
+	}
+	denied := func(res *mcp.CallToolResult) bool {
+		m, _ := res.StructuredContent.(map[string]any)
+		return res.IsError && m["code"] == CodePermissionDenied
+	}
+
+	if res := importFrom(workspace); res.IsError {
+		t.Errorf("import inside the client's root failed: %s", textContent(res))
+	}
+	if res := importFrom(elsewhere); !denied(res) {
+		t.Errorf("import outside the client's roots should be denied, got %s", textContent(res))
+	}
+	if ws.calls != 1 {
+		t.Errorf("roots fetched %d times, want once per session", ws.calls)
+	}
+
+	ws.mu.Lock()
+	ws.dirs = []string{elsewhere}
+	ws.mu.Unlock()
+	// The in-process transport delivers notifications without their
+	// session, so send this one as a session-bound transport would.
+	notification := []byte(`{"jsonrpc":"2.0","method":"notifications/roots/list_changed"}`)
+	s.HandleMessage(s.WithContext(ctx, server.NewInProcessSession(ws.session, nil)), notification)
+	if res := importFrom(elsewhere); res.IsError {
+		t.Errorf("import inside the changed root failed: %s", textContent(res))
+	}
+	if res := importFrom(workspace); !denied(res) {
+		t.Errorf("import from a dropped root should be denied, got %s", textContent(res))
+	}
+}
+
+func TestRootPaths(t *testing.T) {
+	got := rootPaths([]mcp.Root{
+		{URI: "file:///home/dev/project/"},
+		{URI: "file://localhost/srv/data"},
+		{URI: "https://example.com/repo"},
+		{URI: "file://otherhost/share"},
+	})
+	want := []string{filepath.FromSlash("/home/dev/project"), filepath.FromSlash("/srv/data")}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("rootPaths = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,302 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// rowFilter is a predicate from row_filters forced on one table.
+type rowFilter struct {
+	schema, table string // lower case; schema "" matches any schema
+	predicate     string
+}
+
+// rowFiltersFor returns the row filters of connection connID.
+func rowFiltersFor(cfg *config.Config, connID string) []rowFilter {
+	var filters []rowFilter
+	for name, predicate := range cfg.RowFilters(connID) {
+		schema, table := splitTableName(normalizeTableName(name))
+		filters = append(filters, rowFilter{schema: schema, table: table, predicate: predicate})
+	}
+	return filters
+}
+
+// splitTableName splits a normalized, possibly schema-qualified name.
+func splitTableName(name string) (schema, table string) {
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return "", name
+}
+
+// matches reports whether f applies to schema.table on a connection of
+// type typ; an empty schema stands for the connection's default schema.
+func (f rowFilter) matches(typ, schema, table string) bool {
+	if !strings.EqualFold(f.table, table) {
+		return false
+	}
+	if f.schema == "" {
+		return true
+	}
+	if schema == "" {
+		schema = defaultSchemas[typ]
+	}
+	return strings.EqualFold(f.schema, schema)
+}
+
+// findRowFilter returns the filter of connID on schema.table, if any.
+func findRowFilter(cfg *config.Config, connID, schema, table string) (rowFilter, bool) {
+	typ, _ := cfg.Type(connID)
+	for _, f := range rowFiltersFor(cfg, connID) {
+		if f.matches(typ, schema, table) {
+			return f, true
+		}
+	}
+	return rowFilter{}, false
+}
+
+// sqlWord matches an identifier or keyword, with its quotes if any.
+var sqlWord = regexp.MustCompile(`["\x60\[]?[\w$]+["\x60\]]?`)
+
+// aliasStoppers are the words that may follow a table reference without
+// being its alias.
+var aliasStoppers = map[string]bool{
+	"where": true, "join": true, "inner": true, "left": true, "right": true, "full": true,
+	"outer": true, "cross": true, "natural": true, "on": true, "using": true, "group": true,
+	"order": true, "limit": true, "offset": true, "fetch": true, "union": true, "except": true,
+	"intersect": true, "minus": true, "having": true, "window": true, "for": true, "with": true,
+	"lateral": true, "tablesample": true, "straight_join": true, "set": true, "values": true,
+	"select": true, "returning": true, "output": true, "default": true, "partition": true,
+	"use": true, "force": true, "ignore": true,
+}
+
+// filterQuery rewrites sql, a statement on a connection of type typ, so
+// every table with a filter is read through it: FROM orders o becomes
+// FROM (SELECT * FROM orders WHERE <predicate>) o. It reads the statement
+// under each of the type's dialects (see lexSQL) and fails closed: a
+// filtered table mentioned anywhere else than right after FROM or JOIN,
+// other than to qualify a column, or references the dialects read
+// differently, are an error rather than a query that might read around
+// the filter.
+func filterQuery(typ, sql string, filters []rowFilter) (string, error) {
+	var filtered string
+	for i, d := range dialectsFor(typ) {
+		s, err := filterTokens(typ, sql, lexSQL(sql, d), filters)
+		if err != nil {
+			return "", err
+		}
+		if i > 0 && s != filtered {
+			return "", errors.New("the tables the statement reads depend on how the server parses its strings, so its row filters cannot be applied")
+		}
+		filtered = s
+	}
+	return filtered, nil
+}
+
+// filterTokens is filterQuery for sql lexed into toks.
+func filterTokens(typ, sql string, toks []sqlToken, filters []rowFilter) (string, error) {
+	filterOf := func(n sqlName) (rowFilter, bool) {
+		schema, table := n.schemaTable()
+		for _, f := range filters {
+			if f.matches(typ, schema, table) {
+				return f, true
+			}
+		}
+		return rowFilter{}, false
+	}
+
+	var b strings.Builder
+	last := 0
+	inRef := make(map[int]bool) // the tokens of table references, filtered or not
+	for _, ref := range sqlRefs(toks) {
+		if ref.call {
+			continue
+		}
+		for i := ref.first; i < ref.end; i++ {
+			inRef[i] = true
+		}
+		f, ok := filterOf(ref.sqlName)
+		if !ok {
+			continue
+		}
+		name := sqlSource(sql, toks, ref.first, ref.end)
+		if ref.keyword != "FROM" && ref.keyword != "JOIN" {
+			return "", fmt.Errorf("table %s has a row filter and can only be read", name)
+		}
+		repl := "(SELECT * FROM " + name + " WHERE " + f.predicate + ")"
+		if ref.alias < 0 {
+			repl += " AS " + sqlSource(sql, toks, ref.end-1, ref.end)
+		}
+		b.WriteString(sql[last:toks[ref.first].start])
+		b.WriteString(repl)
+		last = toks[ref.end-1].end
+	}
+	b.WriteString(sql[last:])
+
+	for i, t := range toks {
+		if !t.word && !t.ident || inRef[i] || i+1 < len(toks) && toks[i+1].text == "." {
+			continue
+		}
+		word := strings.ToLower(t.text)
+		for _, f := range filters {
+			if f.table == word {
+				return "", fmt.Errorf("table %s has a row filter, which can only be applied where it follows FROM or JOIN", word)
+			}
+		}
+	}
+	return b.String(), nil
+}
+
+// applyQueryRowFilters rewrites a run_query statement on connID so it
+// reads the tables with row filters through them (see filterQuery).
+func applyQueryRowFilters(cfg *config.Config, connID, sql string) (string, *mcp.CallToolResult) {
+	filters := rowFiltersFor(cfg, connID)
+	if len(filters) == 0 {
+		return sql, nil
+	}
+	typ, _ := cfg.Type(connID)
+	filtered, err := filterQuery(typ, sql, filters)
+	if err != nil {
+		return "", rowFilterDenied(connID, err.Error(), "reference filtered tables only as FROM <table> or JOIN <table>, with an alias if needed")
+	}
+	return filtered, nil
+}
+
+// filteredQuerier returns a function running the read-only statements a
+// tool builds on connID, after applyQueryRowFilters, on driver.
+func filteredQuerier(ctx context.Context, cfg *config.Config, driver db.Driver, connID string) func(sql string, params []any) ([]map[string]any, *mcp.CallToolResult) {
+	return func(sql string, params []any) ([]map[string]any, *mcp.CallToolResult) {
+		sql, res := applyQueryRowFilters(cfg, connID, sql)
+		if res != nil {
+			return nil, res
+		}
+		rows, err := driver.RunReadOnlyQuery(ctx, sql, params)
+		if err != nil {
+			return nil, toolErrorResult(err)
+		}
+		return rows, nil
+	}
+}
+
+var (
+	// rowFilterEquality matches one column = literal condition of a predicate.
+	rowFilterEquality = regexp.MustCompile(`(?i)^\s*["\x60\[]?([\w$]+)["\x60\]]?\s*=\s*('(?:[^']|'')*'|-?\d+(?:\.\d+)?|true|false)\s*$`)
+	// sqlAnd splits a predicate into its AND-ed conditions.
+	sqlAnd = regexp.MustCompile(`(?i)\s+and\s+`)
+)
+
+// equalities returns the columns and values a predicate of the form
+// col = literal [AND col = literal ...] pins, with lower-case column names,
+// or false for any other predicate.
+func equalities(predicate string) (map[string]any, bool) {
+	pinned := make(map[string]any)
+	for _, cond := range sqlAnd.Split(predicate, -1) {
+		m := rowFilterEquality.FindStringSubmatch(cond)
+		if m == nil {
+			return nil, false
+		}
+		var v any
+		switch lit := strings.ToLower(m[2]); {
+		case strings.HasPrefix(lit, "'"):
+			v = strings.ReplaceAll(m[2][1:len(m[2])-1], "''", "'")
+		case lit == "true" || lit == "false":
+			v = lit == "true"
+		default:
+			v, _ = strconv.ParseFloat(lit, 64)
+		}
+		pinned[strings.ToLower(m[1])] = v
+	}
+	return pinned, true
+}
+
+// sameValue reports whether a JSON argument value equals a pinned literal.
+func sameValue(arg, pinned any) bool {
+	if s, ok := pinned.(string); ok {
+		return fmt.Sprint(arg) == s
+	}
+	return arg == pinned
+}
+
+// applyInsertRowFilter makes row, about to be inserted into schema.table
+// on connID, satisfy the table's row filter: the columns the filter pins
+// are filled in when missing and must not differ. Only filters of the form
+// col = literal [AND ...] can be checked this way; other filtered tables
+// refuse inserts.
+func applyInsertRowFilter(cfg *config.Config, connID, schema, table string, row map[string]any) *mcp.CallToolResult {
+	f, ok := findRowFilter(cfg, connID, schema, table)
+	if !ok {
+		return nil
+	}
+	pinned, ok := equalities(f.predicate)
+	if !ok {
+		return rowFilterDenied(connID, fmt.Sprintf("table %s has a row filter (%s) that inserted rows cannot be checked against", table, f.predicate),
+			"only row filters of the form column = value allow inserts")
+	}
+	for col, v := range pinned {
+		found := false
+		for k, arg := range row {
+			if strings.EqualFold(k, col) {
+				found = true
+				if !sameValue(arg, v) {
+					return rowFilterDenied(connID, fmt.Sprintf("row is outside the row filter of table %s (%s)", table, f.predicate),
+						fmt.Sprintf("set %s to %v or leave it out", col, v))
+				}
+			}
+		}
+		if !found {
+			row[col] = v
+		}
+	}
+	return nil
+}
+
+// applyUpdateRowFilter returns ctx carrying the row filter of schema.table
+// on connID for UpdateRow (see db.WithRowFilter), so a row outside it is
+// not found. set may only write a pinned column with its pinned value; a
+// column named in any other filter may not be set at all, so an update
+// cannot move a row out of the filter.
+func applyUpdateRowFilter(ctx context.Context, cfg *config.Config, connID, schema, table string, set map[string]any) (context.Context, *mcp.CallToolResult) {
+	f, ok := findRowFilter(cfg, connID, schema, table)
+	if !ok {
+		return ctx, nil
+	}
+	pinned, _ := equalities(f.predicate)
+	words := make(map[string]bool)
+	for _, w := range sqlWord.FindAllString(sqlStringLiteral.ReplaceAllString(f.predicate, "''"), -1) {
+		words[normalizeTableName(w)] = true
+	}
+	for col, v := range set {
+		c := strings.ToLower(col)
+		if p, ok := pinned[c]; ok && sameValue(v, p) || !words[c] {
+			continue
+		}
+		return ctx, rowFilterDenied(connID, fmt.Sprintf("column %s of table %s is used by its row filter (%s) and cannot be changed", col, table, f.predicate),
+			"leave it out of set")
+	}
+	return db.WithRowFilter(ctx, f.predicate), nil
+}
+
+// checkRowFilterDump refuses export_database and import_database on a
+// connection with row filters: dumps cover whole tables.
+func checkRowFilterDump(cfg *config.Config, connID string) *mcp.CallToolResult {
+	if len(cfg.RowFilters(connID)) == 0 {
+		return nil
+	}
+	return rowFilterDenied(connID, "dumps cannot apply row filters", "use run_query to read filtered tables")
+}
+
+func rowFilterDenied(connID, msg, hint string) *mcp.CallToolResult {
+	return errorResult(ToolError{
+		Code:    CodePermissionDenied,
+		Message: fmt.Sprintf("connection %q: %s", connID, msg),
+		Hint:    hint,
+	}, nil)
+}
@@ -0,0 +1,168 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestFilterQuery(t *testing.T) {
+	filters := []rowFilter{
+		{table: "orders", predicate: "tenant_id = 1"},
+		{schema: "sales", table: "invoices", predicate: "tenant_id = 1"},
+	}
+	tests := []struct {
+		sql, want string
+		wantErr   bool
+	}{
+		{sql: "SELECT * FROM users", want: "SELECT * FROM users"},
+		{
+			sql:  "SELECT orders.id FROM orders WHERE total > 5",
+			want: "SELECT orders.id FROM (SELECT * FROM orders WHERE tenant_id = 1) AS orders WHERE total > 5",
+		},
+		{
+			sql:  "SELECT o.id, u.name FROM users u JOIN \"Orders\" AS o ON o.user_id = u.id",
+			want: "SELECT o.id, u.name FROM users u JOIN (SELECT * FROM \"Orders\" WHERE tenant_id = 1) AS o ON o.user_id = u.id",
+		},
+		{
+			sql:  "SELECT * FROM sales.invoices i WHERE note = 'from orders'",
+			want: "SELECT * FROM (SELECT * FROM sales.invoices WHERE tenant_id = 1) i WHERE note = 'from orders'",
+		},
+		{sql: "SELECT * FROM public.invoices", want: "SELECT * FROM public.invoices"},
+		{
+			sql:  "SELECT COUNT(*) FROM orders -- all of them",
+			want: "SELECT COUNT(*) FROM (SELECT * FROM orders WHERE tenant_id = 1) AS orders -- all of them",
+		},
+		{sql: "SELECT * FROM users, orders", want: "SELECT * FROM users, (SELECT * FROM orders WHERE tenant_id = 1) AS orders"},
+		{
+			sql:  "WITH o AS (SELECT * FROM users) SELECT * FROM o, orders",
+			want: "WITH o AS (SELECT * FROM users) SELECT * FROM o, (SELECT * FROM orders WHERE tenant_id = 1) AS orders",
+		},
+		{
+			sql:  "SELECT $$'$$ AS a, * FROM orders, (SELECT $$'$$) x",
+			want: "SELECT $$'$$ AS a, * FROM (SELECT * FROM orders WHERE tenant_id = 1) AS orders, (SELECT $$'$$) x",
+		},
+		{sql: "SELECT E'\\'', o.* FROM orders o", wantErr: true},
+		{sql: "SELECT 'x' FROM users u WHERE u.id = orders(1)", wantErr: true},
+		{sql: "SELECT * FROM users orders", wantErr: true},
+		{sql: "SELECT * FROM users WHERE id IN (SELECT user_id FROM orders)", want: "SELECT * FROM users WHERE id IN (SELECT user_id FROM (SELECT * FROM orders WHERE tenant_id = 1) AS orders)"},
+	}
+	for _, tt := range tests {
+		got, err := filterQuery("postgres", tt.sql, filters)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("filterQuery(%q) error = %v, want error %v", tt.sql, err, tt.wantErr)
+			continue
+		}
+		if err == nil && got != tt.want {
+			t.Errorf("filterQuery(%q)\n got %q\nwant %q", tt.sql, got, tt.want)
+		}
+	}
+}
+
+func TestFilterQuery_dialects(t *testing.T) {
+	filters := []rowFilter{{table: "orders", predicate: "tenant_id = 42"}}
+	got, err := filterQuery("sqlite", "SELECT 1 AS ['], * FROM orders, (SELECT 1 AS [']) x", filters)
+	want := "SELECT 1 AS ['], * FROM (SELECT * FROM orders WHERE tenant_id = 42) AS orders, (SELECT 1 AS [']) x"
+	if err != nil || got != want {
+		t.Errorf("sqlite: got %q, %v\nwant %q", got, err, want)
+	}
+	// Whether \' ends the string depends on NO_BACKSLASH_ESCAPES.
+	if got, err := filterQuery("mysql", "SELECT 'a\\', (SELECT 1 FROM orders), '' FROM users", filters); err == nil {
+		t.Errorf("mysql: a statement read differently by the dialects was rewritten: %q", got)
+	}
+}
+
+func TestEqualities(t *testing.T) {
+	got, ok := equalities("tenant_id = 42 AND \"region\" = 'eu''s'")
+	if !ok || len(got) != 2 || got["tenant_id"] != 42.0 || got["region"] != "eu's" {
+		t.Errorf("equalities = %v, %v", got, ok)
+	}
+	if _, ok := equalities("tenant_id IN (1, 2)"); ok {
+		t.Error("an IN predicate pins no single value")
+	}
+}
+
+func TestRowFilters(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "app.db")
+	sqlDB, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDB.Close()
+	if _, err := sqlDB.Exec(`CREATE TABLE orders (id INTEGER PRIMARY KEY, tenant_id INTEGER, total INTEGER);
+		INSERT INTO orders VALUES (1, 1, 10), (2, 2, 20), (3, 1, 30)`); err != nil {
+		t.Fatal(err)
+	}
+	c := newTestClient(t, `
+connections:
+  sqlite: "`+dbPath+`"
+row_filters:
+  sqlite: {orders: "tenant_id = 1"}
+rate_limits:
+  write: {rate: 0}
+`)
+	call := func(name string, args map[string]any) *mcp.CallToolResult {
+		t.Helper()
+		args["connection_id"] = "sqlite"
+		res, err := c.CallTool(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{Name: name, Arguments: args}})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		return res
+	}
+	total := func() float64 {
+		t.Helper()
+		res := call("run_query", map[string]any{"sql": "SELECT SUM(o.total) AS total FROM orders o"})
+		var out RunQueryOutput
+		if res.IsError || json.Unmarshal([]byte(textContent(res)), &out) != nil || len(out.Rows) != 1 {
+			t.Fatalf("run_query: %s", textContent(res))
+		}
+		n, _ := out.Rows[0]["total"].(float64)
+		return n
+	}
+
+	if got := total(); got != 40 {
+		t.Errorf("tenant 1 total = %v, want 40", got)
+	}
+	if res := call("run_query", map[string]any{"sql": "SELECT 1 AS ['], * FROM orders, (SELECT 1 AS [']) x"}); res.IsError || strings.Contains(textContent(res), `"tenant_id":2`) {
+		t.Errorf("a comma join read around the filter: %s", textContent(res))
+	}
+	if res := call("run_query", map[string]any{"sql": "SELECT 1 AS orders FROM orders"}); resultCode(res) != CodePermissionDenied {
+		t.Errorf("a query the filter cannot be applied to should be refused: %s", textContent(res))
+	}
+
+	if res := call("insert_test_row", map[string]any{"table": "orders", "row": map[string]any{"total": 5}}); res.IsError {
+		t.Fatalf("insert: %s", textContent(res))
+	}
+	if got := total(); got != 45 {
+		t.Errorf("total after insert = %v, want 45: tenant_id should be filled in", got)
+	}
+	if res := call("insert_test_row", map[string]any{"table": "orders", "row": map[string]any{"tenant_id": 2, "total": 5}}); resultCode(res) != CodePermissionDenied {
+		t.Errorf("insert for another tenant: %s", textContent(res))
+	}
+
+	if res := call("update_test_row", map[string]any{"table": "orders", "key": map[string]any{"id": 2}, "set": map[string]any{"total": 0}}); resultCode(res) != CodeNotFound {
+		t.Errorf("update of another tenant's row: %s", textContent(res))
+	}
+	if res := call("update_test_row", map[string]any{"table": "orders", "key": map[string]any{"id": 1}, "set": map[string]any{"tenant_id": 2}}); resultCode(res) != CodePermissionDenied {
+		t.Errorf("moving a row to another tenant: %s", textContent(res))
+	}
+	if res := call("update_test_row", map[string]any{"table": "orders", "key": map[string]any{"id": 1}, "set": map[string]any{"total": 0}}); res.IsError {
+		t.Errorf("update of own row: %s", textContent(res))
+	}
+	var other int
+	if err := sqlDB.QueryRow("SELECT total FROM orders WHERE id = 2").Scan(&other); err != nil || other != 20 {
+		t.Errorf("other tenant's row total = %d (%v), want 20", other, err)
+	}
+
+	if res := call("export_database", map[string]any{"path": filepath.Join(dir, "dump.sql")}); !strings.Contains(textContent(res), "row filters") {
+		t.Errorf("export of a filtered connection: %s", textContent(res))
+	}
+}
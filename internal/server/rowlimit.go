@@ -0,0 +1,62 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
+)
+
+// errTooManyRows is wrapped by the error of an update that changed more rows
+// than max_rows_affected. The update was rolled back.
+var errTooManyRows = errors.New("update affected too many rows")
+
+// limitedWriter is the rowWriter of a write outside a transaction: each
+// update runs in a transaction of its own and is rolled back if it changes
+// more than limit rows, e.g. through an unexpected key or a trigger.
+type limitedWriter struct {
+	db.Driver
+	mgr    *db.Manager
+	connID string
+	limit  int
+}
+
+func (w limitedWriter) UpdateRow(ctx context.Context, schema, table string, key, set map[string]any) (int64, error) {
+	tx, err := w.mgr.BeginTx(ctx, w.connID)
+	if err != nil {
+		return 0, err
+	}
+	n, err := tx.UpdateRow(ctx, schema, table, key, set)
+	if err == nil && n > int64(w.limit) {
+		err = fmt.Errorf("%w: %d rows, more than max_rows_affected (%d); rolled back", errTooManyRows, n, w.limit)
+	}
+	if err != nil {
+		tx.Rollback(context.WithoutCancel(ctx))
+		return 0, err
+	}
+	return n, tx.Commit(ctx)
+}
+
+// limitedTxWriter is the rowWriter of a write inside an open transaction,
+// held by the caller. An update that changes more than limit rows rolls the
+// whole transaction back, as its other statements cannot be kept apart.
+type limitedTxWriter struct {
+	r     *txRegistry
+	t     *openTx
+	limit int
+}
+
+func (w limitedTxWriter) InsertRow(ctx context.Context, schema, table string, row map[string]any) (any, error) {
+	return w.t.tx.InsertRow(ctx, schema, table, row)
+}
+
+func (w limitedTxWriter) UpdateRow(ctx context.Context, schema, table string, key, set map[string]any) (int64, error) {
+	n, err := w.t.tx.UpdateRow(ctx, schema, table, key, set)
+	if err == nil && n > int64(w.limit) {
+		w.r.endLocked(context.WithoutCancel(ctx), w.t, false)
+		return 0, fmt.Errorf("%w: %d rows, more than max_rows_affected (%d); transaction %s was rolled back",
+			errTooManyRows, n, w.limit, w.t.id)
+	}
+	return n, err
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestUpdateTestRow_maxRowsAffected(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "test.db")
+	sqlDB, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDB.Close()
+	if _, err := sqlDB.Exec("CREATE TABLE items (id INTEGER PRIMARY KEY, v TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+	c := newTestClient(t, "connections:\n  sqlite: \""+path+"\"\nrate_limits:\n  write: {rate: 0}\n")
+	call := func(name string, args map[string]any) *mcp.CallToolResult {
+		t.Helper()
+		res, err := c.CallTool(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{Name: name, Arguments: args}})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		return res
+	}
+	changed := func() int {
+		t.Helper()
+		var n int
+		if err := sqlDB.QueryRow("SELECT COUNT(*) FROM items WHERE v = 'z'").Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	// The cached metadata still says id is the primary key after the table
+	// was recreated without one, so the key matches two rows.
+	if res := call("describe_table", map[string]any{"connection_id": "sqlite", "table": "items"}); res.IsError {
+		t.Fatalf("describe_table: %s", textContent(res))
+	}
+	if _, err := sqlDB.Exec(`DROP TABLE items;
+		CREATE TABLE items (id INTEGER, v TEXT);
+		INSERT INTO items VALUES (1, 'a'), (1, 'b'), (2, 'c')`); err != nil {
+		t.Fatal(err)
+	}
+	update := map[string]any{"connection_id": "sqlite", "table": "items", "key": map[string]any{"id": 1}, "set": map[string]any{"v": "z"}}
+
+	res := call("update_test_row", update)
+	if resultCode(res) != CodePermissionDenied || !strings.Contains(textContent(res), "max_rows_affected") {
+		t.Errorf("update of two rows: %s", textContent(res))
+	}
+	if n := changed(); n != 0 {
+		t.Errorf("%d rows changed, want the update rolled back", n)
+	}
+
+	// Inside a transaction the whole transaction is rolled back.
+	res = call("begin_transaction", map[string]any{"connection_id": "sqlite"})
+	var out BeginTransactionOutput
+	if res.IsError || json.Unmarshal([]byte(textContent(res)), &out) != nil {
+		t.Fatalf("begin_transaction: %s", textContent(res))
+	}
+	if res := call("update_test_row", map[string]any{
+		"connection_id": "sqlite", "table": "items", "key": map[string]any{"id": 2}, "set": map[string]any{"v": "z"},
+		"transaction_id": out.TransactionID,
+	}); res.IsError {
+		t.Fatalf("update of one row: %s", textContent(res))
+	}
+	update["transaction_id"] = out.TransactionID
+	if res := call("update_test_row", update); resultCode(res) != CodePermissionDenied {
+		t.Errorf("update of two rows in a transaction: %s", textContent(res))
+	}
+	if res := call("commit_transaction", map[string]any{"transaction_id": out.TransactionID}); resultCode(res) != CodeNotFound {
+		t.Errorf("commit after the limit was hit: %s", textContent(res))
+	}
+	if n := changed(); n != 0 {
+		t.Errorf("%d rows changed, want the transaction rolled back", n)
+	}
+
+	// Within the limit the update is committed.
+	delete(update, "transaction_id")
+	update["key"] = map[string]any{"id": 2}
+	if res := call("update_test_row", update); res.IsError {
+		t.Fatalf("update of one row: %s", textContent(res))
+	}
+	if n := changed(); n != 1 {
+		t.Errorf("%d rows changed, want 1", n)
+	}
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// defaultSchemas are the schemas a write without a schema argument goes to,
+// by connection type. MySQL writes to the database named in the DSN, which
+// the server does not know, so it has none here.
+var defaultSchemas = map[string]string{
+	"postgres":  "public",
+	"sqlserver": "dbo",
+}
+
+// checkWriteSchema refuses a write to schema on connection connID unless
+// the schema is one of its write_schemas (or it has none). An empty schema
+// stands for the connection's default schema. It returns nil if the write
+// may go ahead.
+func checkWriteSchema(cfg *config.Config, connID, schema string) *mcp.CallToolResult {
+	allowed := cfg.WriteSchemas(connID)
+	if len(allowed) == 0 {
+		return nil
+	}
+	target := schema
+	if target == "" {
+		typ, _ := cfg.Type(connID)
+		target = defaultSchemas[typ]
+	}
+	if target != "" && slices.ContainsFunc(allowed, func(s string) bool { return strings.EqualFold(s, target) }) {
+		return nil
+	}
+	msg := fmt.Sprintf("connection %q only allows writes to schema %s, not %q", connID, strings.Join(allowed, ", "), target)
+	if target == "" {
+		msg = fmt.Sprintf("connection %q only allows writes to schema %s; name the schema", connID, strings.Join(allowed, ", "))
+	}
+	return errorResult(ToolError{
+		Code:    CodePermissionDenied,
+		Message: msg,
+		Hint:    "pass one of these as schema; write_schemas in config.yaml keeps writes out of the application's schemas",
+	}, nil)
+}
+
+// checkSandboxImport refuses import_database on a connection with
+// write_schemas: a dump can write to any schema.
+func checkSandboxImport(cfg *config.Config, connID string) *mcp.CallToolResult {
+	allowed := cfg.WriteSchemas(connID)
+	if len(allowed) == 0 {
+		return nil
+	}
+	return errorResult(ToolError{
+		Code:    CodePermissionDenied,
+		Message: fmt.Sprintf("connection %q only allows writes to schema %s, and a dump may write anywhere", connID, strings.Join(allowed, ", ")),
+		Hint:    "use insert_test_row and update_test_row with one of those schemas",
+	}, nil)
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"testing"
+)
+
+func TestCheckWriteSchema(t *testing.T) {
+	cfg := newTestConfig(t, `
+connections:
+  postgres: "postgres://localhost/app"
+  sqlserver: "sqlserver://localhost"
+  mysql: "root@tcp(localhost:3306)/app"
+  other: "postgres://localhost/other"
+write_schemas:
+  postgres: [test, mcp_sandbox]
+  sqlserver: [dbo]
+  mysql: [app_test]
+`)
+	tests := []struct {
+		conn, schema string
+		allowed      bool
+	}{
+		{"postgres", "test", true},
+		{"postgres", "MCP_Sandbox", true},
+		{"postgres", "public", false},
+		{"postgres", "", false}, // public
+		{"sqlserver", "", true}, // dbo
+		{"sqlserver", "sales", false},
+		{"mysql", "app_test", true},
+		{"mysql", "", false}, // the DSN's database is not known
+		{"other", "public", true},
+		{"other", "", true},
+	}
+	for _, tt := range tests {
+		res := checkWriteSchema(cfg, tt.conn, tt.schema)
+		if allowed := res == nil; allowed != tt.allowed {
+			t.Errorf("checkWriteSchema(%s, %q) allowed = %v, want %v", tt.conn, tt.schema, allowed, tt.allowed)
+		}
+		if res != nil && resultCode(res) != CodePermissionDenied {
+			t.Errorf("checkWriteSchema(%s, %q): code %q", tt.conn, tt.schema, resultCode(res))
+		}
+	}
+	if checkSandboxImport(cfg, "postgres") == nil {
+		t.Error("import into a sandboxed connection should be refused")
+	}
+	if checkSandboxImport(cfg, "other") != nil {
+		t.Error("import into a connection without write_schemas should be allowed")
+	}
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// schemaOrDefault returns schema, or the connection's default_schemas entry
+// when it is empty.
+func schemaOrDefault(cfg *config.Config, connID, schema string) string {
+	if schema == "" {
+		return cfg.DefaultSchema(connID)
+	}
+	return schema
+}
+
+// checkSchemaLock refuses a schema argument other than the one a
+// connection in schema_lock is pinned to. It returns nil if the schema may
+// be used.
+func checkSchemaLock(cfg *config.Config, connID, schema string) *mcp.CallToolResult {
+	if !cfg.SchemaLocked(connID) {
+		return nil
+	}
+	locked := cfg.DefaultSchema(connID)
+	if schema == "" || strings.EqualFold(schema, locked) {
+		return nil
+	}
+	return schemaLockDenied(connID, locked, fmt.Sprintf("schema %q", schema))
+}
+
+// sqlRemoteSources are SQL Server's functions that read other servers or
+// databases.
+var sqlRemoteSources = map[string]bool{"OPENROWSET": true, "OPENQUERY": true, "OPENDATASOURCE": true}
+
+// lockedSchemaViolation returns the first reference in sql, a statement on
+// a connection of type typ locked to schema, that may reach another schema
+// or database, or "" if there is none. It reads the statement under each
+// of the type's dialects (see lexSQL) and errs on the side of refusing:
+//
+//   - a table after FROM, JOIN, INTO, UPDATE or in a FROM list may be
+//     unqualified or qualified by schema; a database-qualified name
+//     (db.schema.table, or a linked server) is refused;
+//   - a name with three or more parts anywhere else, or a qualified function
+//     call, must start with schema;
+//   - OPENROWSET, OPENQUERY and OPENDATASOURCE are refused.
+func lockedSchemaViolation(typ, sql, schema string) string {
+	for _, d := range dialectsFor(typ) {
+		if ref := lockedSchemaRef(sql, lexSQL(sql, d), schema); ref != "" {
+			return ref
+		}
+	}
+	return ""
+}
+
+// lockedSchemaRef is lockedSchemaViolation for sql lexed into toks.
+func lockedSchemaRef(sql string, toks []sqlToken, schema string) string {
+	for i, t := range toks {
+		if t.word && sqlRemoteSources[t.text] && i+1 < len(toks) && toks[i+1].text == "(" {
+			return t.text
+		}
+	}
+	for _, ref := range sqlRefs(toks) {
+		if len(ref.parts) > 2 || len(ref.parts) == 2 && !strings.EqualFold(ref.parts[0], schema) {
+			return sqlSource(sql, toks, ref.first, ref.end)
+		}
+	}
+	for _, n := range sqlNames(toks) {
+		call := n.end < len(toks) && toks[n.end].text == "("
+		if len(n.parts) > 1 && (len(n.parts) > 2 || call) && !strings.EqualFold(n.parts[0], schema) {
+			return sqlSource(sql, toks, n.first, n.end)
+		}
+	}
+	return ""
+}
+
+// checkSchemaLockSQL refuses a query on a connection in schema_lock that
+// references another schema or database (see lockedSchemaViolation).
+func checkSchemaLockSQL(cfg *config.Config, connID, sql string) *mcp.CallToolResult {
+	if !cfg.SchemaLocked(connID) {
+		return nil
+	}
+	locked := cfg.DefaultSchema(connID)
+	typ, _ := cfg.Type(connID)
+	if ref := lockedSchemaViolation(typ, sql, locked); ref != "" {
+		return schemaLockDenied(connID, locked, ref)
+	}
+	return nil
+}
+
+// checkSchemaLockDump refuses export_database and import_database on a
+// connection in schema_lock: dumps cover every schema of the database.
+func checkSchemaLockDump(cfg *config.Config, connID string) *mcp.CallToolResult {
+	if !cfg.SchemaLocked(connID) {
+		return nil
+	}
+	return schemaLockDenied(connID, cfg.DefaultSchema(connID), "a whole-database dump")
+}
+
+func schemaLockDenied(connID, locked, what string) *mcp.CallToolResult {
+	return errorResult(ToolError{
+		Code:    CodePermissionDenied,
+		Message: fmt.Sprintf("connection %q is locked to schema %q; %s is outside it", connID, locked, what),
+		Hint:    fmt.Sprintf("use tables of schema %s only, unqualified or qualified as %s.<table>", locked, locked),
+	}, nil)
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"testing"
+)
+
+func TestLockedSchemaViolation(t *testing.T) {
+	tests := []struct {
+		typ, sql, want string
+	}{
+		{"", "SELECT * FROM users", ""},
+		{"", "SELECT u.id, app.orders.total FROM app.users u JOIN \"App\".\"orders\" ON orders.user_id = u.id", ""},
+		{"", "SELECT * FROM users, (SELECT 1 AS n) x, app.orders o", ""},
+		{"", "SELECT 1.5, 'other.users' AS label -- FROM other.users", ""},
+		{"", "SELECT * FROM other.users", "other.users"},
+		{"", "SELECT * FROM users u, other.secrets s", "other.secrets"},
+		{"", "SELECT * FROM users WHERE id IN (SELECT user_id FROM [other] . [orders])", "[other] . [orders]"},
+		{"", "SELECT * FROM otherdb.app.users", "otherdb.app.users"},
+		{"", "SELECT * FROM app.users WHERE other.t.id = 1", "other.t.id"},
+		{"", "SELECT other.secret_fn(1)", "other.secret_fn"},
+		{"", "SELECT * FROM OPENQUERY(remote, 'SELECT 1')", "OPENQUERY"},
+		{"postgres", "SELECT '--', * FROM other.secrets", "other.secrets"},
+		{"postgres", "SELECT $$'$$ AS a, * FROM other.secrets, (SELECT $$'$$) x", "other.secrets"},
+		{"mysql", "SELECT 'a\\'' , x.* FROM otherdb.t x WHERE ''=''", "otherdb.t"},
+		{"sqlserver", "SELECT 1 AS ['], * FROM other.t, (SELECT 1 AS [']) x", "other.t"},
+	}
+	for _, tt := range tests {
+		if got := lockedSchemaViolation(tt.typ, tt.sql, "app"); got != tt.want {
+			t.Errorf("lockedSchemaViolation(%q, %q) = %q, want %q", tt.typ, tt.sql, got, tt.want)
+		}
+	}
+}
+
+func TestCheckSchemaLock(t *testing.T) {
+	cfg := newTestConfig(t, `
+connections:
+  postgres: "postgres://localhost/app"
+  mysql: "root@tcp(localhost:3306)/shop"
+default_schemas:
+  postgres: app
+  mysql: shop
+schema_lock: [postgres]
+`)
+	if got := schemaOrDefault(cfg, "postgres", ""); got != "app" {
+		t.Errorf("default schema = %q, want app", got)
+	}
+	if got := schemaOrDefault(cfg, "postgres", "other"); got != "other" {
+		t.Errorf("explicit schema = %q, want other", got)
+	}
+	if res := checkSchemaLock(cfg, "postgres", "APP"); res != nil {
+		t.Errorf("the locked schema was refused: %s", textContent(res))
+	}
+	if res := checkSchemaLock(cfg, "postgres", "public"); resultCode(res) != CodePermissionDenied {
+		t.Errorf("another schema: %v", res)
+	}
+	if res := checkSchemaLock(cfg, "mysql", "other"); res != nil {
+		t.Error("a default schema without schema_lock does not lock")
+	}
+	if res := checkSchemaLockSQL(cfg, "postgres", "SELECT * FROM public.users"); resultCode(res) != CodePermissionDenied {
+		t.Errorf("cross-schema query: %v", res)
+	}
+	if res := checkSchemaLockSQL(cfg, "mysql", "SELECT * FROM other.users"); res != nil {
+		t.Error("queries on unlocked connections are not checked")
+	}
+	if checkSchemaLockDump(cfg, "postgres") == nil || checkSchemaLockDump(cfg, "mysql") != nil {
+		t.Error("dumps should be refused only on locked connections")
+	}
+}
@@ -0,0 +1,166 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// scratchSchemaPrefix starts the name of every session's scratch schema,
+// so schemas left behind by a server that was killed are easy to find.
+const scratchSchemaPrefix = "mcp_session_"
+
+// scratchDropTimeout bounds dropping a session's scratch schemas once it
+// has ended.
+const scratchDropTimeout = 30 * time.Second
+
+// scratchSchemaName returns the name of the scratch schema of session id,
+// a hash of the ID, so it is a plain identifier on every database.
+func scratchSchemaName(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return scratchSchemaPrefix + hex.EncodeToString(sum[:6])
+}
+
+// scratchTableSchema returns the scratch schema a write to table on connID
+// lands in: the session's own, if the session created table there with
+// create_test_table and the write names no schema. Otherwise it returns "".
+func (r *sessionRegistry) scratchTableSchema(ctx context.Context, connID, schema, table string) string {
+	if schema != "" {
+		return ""
+	}
+	st := r.get(ctx)
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.scratch[connID][table] {
+		return scratchSchemaName(st.id)
+	}
+	return ""
+}
+
+// createScratchTable creates table in the scratch schema of the session
+// ctx belongs to on connID, creating the schema first if this is the
+// session's first table there, and returns the schema.
+func (r *sessionRegistry) createScratchTable(ctx context.Context, mgr *db.Manager, connID, table string, cols []db.ColumnDef) (string, error) {
+	d, err := mgr.Driver(ctx, connID)
+	if err != nil {
+		return "", err
+	}
+	ss, ok := db.Unwrap(d).(db.ScratchSchemas)
+	if !ok {
+		return "", fmt.Errorf("%w: connection %q cannot create session schemas", db.ErrNotSupported, connID)
+	}
+	st := r.get(ctx)
+	schema := scratchSchemaName(st.id)
+	st.mu.Lock()
+	created := st.scratch[connID] != nil
+	st.mu.Unlock()
+	if !created {
+		// Idempotent, so concurrent first calls may both run it.
+		if err := ss.CreateScratchSchema(ctx, schema); err != nil {
+			return "", fmt.Errorf("create session schema %s: %w", schema, err)
+		}
+		slog.Info("session schema created", "connection_id", connID, "schema", schema)
+	}
+	err = ss.CreateTable(ctx, schema, table, cols)
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.scratch == nil {
+		st.scratch = make(map[string]map[string]bool)
+	}
+	if st.scratch[connID] == nil {
+		st.scratch[connID] = make(map[string]bool)
+	}
+	if err != nil {
+		return "", err
+	}
+	st.scratch[connID][table] = true
+	return schema, nil
+}
+
+// dropScratchSchemas drops the scratch schemas of an ended session.
+func dropScratchSchemas(mgr *db.Manager, st *sessionState) {
+	st.mu.Lock()
+	conns := make([]string, 0, len(st.scratch))
+	for connID := range st.scratch {
+		conns = append(conns, connID)
+	}
+	st.scratch = nil
+	st.mu.Unlock()
+	schema := scratchSchemaName(st.id)
+	for _, connID := range conns {
+		ctx, cancel := context.WithTimeout(context.Background(), scratchDropTimeout)
+		err := func() error {
+			d, err := mgr.Driver(ctx, connID)
+			if err != nil {
+				return err
+			}
+			ss, ok := db.Unwrap(d).(db.ScratchSchemas)
+			if !ok {
+				return db.ErrNotSupported
+			}
+			return ss.DropScratchSchema(ctx, schema)
+		}()
+		cancel()
+		if err != nil {
+			slog.Warn("cannot drop session schema", "connection_id", connID, "schema", schema, "err", err)
+		} else {
+			slog.Info("session schema dropped", "connection_id", connID, "schema", schema)
+		}
+	}
+}
+
+// checkSessionSchemas refuses create_test_table on a connection that is not
+// listed in session_schemas.
+func checkSessionSchemas(cfg *config.Config, connID string) *mcp.CallToolResult {
+	if cfg.SessionSchemas(connID) {
+		return nil
+	}
+	return errorResult(ToolError{
+		Code:    CodePermissionDenied,
+		Message: fmt.Sprintf("connection %q has no session schemas", connID),
+		Hint:    "list it in session_schemas in config.yaml to let sessions create test tables",
+	}, nil)
+}
+
+// parseColumnDefs reads the columns argument of create_test_table.
+func parseColumnDefs(v any) ([]db.ColumnDef, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var cols []db.ColumnDef
+	if err := json.Unmarshal(b, &cols); err != nil || len(cols) == 0 {
+		return nil, fmt.Errorf("columns is required and must be a non-empty array of {name, type, primary_key, not_null} objects")
+	}
+	return cols, nil
+}
+
+// describeColumnDefs describes cols for a write confirmation.
+func describeColumnDefs(cols []db.ColumnDef) string {
+	defs := make([]string, len(cols))
+	for i, c := range cols {
+		defs[i] = c.Name + " " + c.Type
+		if c.PrimaryKey {
+			defs[i] += " primary key"
+		}
+	}
+	return strings.Join(defs, ", ")
+}
+
+// CreateTestTableOutput is the result of create_test_table.
+type CreateTestTableOutput struct {
+	ConnectionID string `json:"connection_id"`
+	// Schema is the session's scratch schema the table was created in (an
+	// attached database on SQLite), for qualifying it in run_query.
+	Schema string `json:"schema"`
+	Table  string `json:"table"`
+}
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestSessionSchemas(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "app.db")
+	sqlDB, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDB.Close()
+	if _, err := sqlDB.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+	s, _ := newTestServer(t, "connections:\n  sqlite: \""+dbPath+"\"\nsession_schemas: [sqlite]\nrate_limits:\n  write: {rate: 0}\n")
+
+	// A client only gets a session of its own with a handler.
+	connect := func() (call func(name string, args map[string]any) *mcp.CallToolResult, close func()) {
+		c := client.NewClient(transport.NewInProcessTransportWithOptions(s, transport.WithElicitationHandler(&fakeHuman{})))
+		if err := c.Start(ctx); err != nil {
+			t.Fatal(err)
+		}
+		initReq := mcp.InitializeRequest{}
+		initReq.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+		if _, err := c.Initialize(ctx, initReq); err != nil {
+			t.Fatalf("Initialize: %v", err)
+		}
+		return func(name string, args map[string]any) *mcp.CallToolResult {
+			t.Helper()
+			res, err := c.CallTool(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{Name: name, Arguments: args}})
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			return res
+		}, func() { c.Close() }
+	}
+	createFixtures := map[string]any{
+		"connection_id": "sqlite",
+		"table":         "fixtures",
+		"columns": []any{
+			map[string]any{"name": "id", "type": "integer", "primary_key": true},
+			map[string]any{"name": "label", "type": "varchar(20)", "not_null": true},
+		},
+	}
+	insert := func(label string) map[string]any {
+		return map[string]any{"connection_id": "sqlite", "table": "fixtures", "row": map[string]any{"label": label}}
+	}
+
+	callA, closeA := connect()
+	callB, closeB := connect()
+	defer closeB()
+
+	res := callA("create_test_table", createFixtures)
+	var outA CreateTestTableOutput
+	if res.IsError || json.Unmarshal([]byte(textContent(res)), &outA) != nil {
+		t.Fatalf("create_test_table: %s", textContent(res))
+	}
+	if res := callA("insert_test_row", insert("a")); res.IsError {
+		t.Fatalf("insert into the session's table: %s", textContent(res))
+	}
+	if res := callA("update_test_row", map[string]any{"connection_id": "sqlite", "table": "fixtures", "key": map[string]any{"id": 1}, "set": map[string]any{"label": "a2"}}); res.IsError {
+		t.Fatalf("update of the session's table: %s", textContent(res))
+	}
+	if res := callB("insert_test_row", insert("b")); !res.IsError {
+		t.Error("another session wrote to the first session's table")
+	}
+	if res := callA("insert_test_row", map[string]any{"connection_id": "sqlite", "table": "users", "row": map[string]any{"name": "alice"}}); res.IsError {
+		t.Errorf("insert into an application table: %s", textContent(res))
+	}
+
+	res = callB("create_test_table", createFixtures)
+	var outB CreateTestTableOutput
+	if res.IsError || json.Unmarshal([]byte(textContent(res)), &outB) != nil {
+		t.Fatalf("second session's create_test_table: %s", textContent(res))
+	}
+	if outA.Schema == outB.Schema {
+		t.Fatalf("both sessions got schema %s", outA.Schema)
+	}
+	if res := callB("insert_test_row", insert("b")); res.IsError {
+		t.Fatalf("second session's insert: %s", textContent(res))
+	}
+
+	count := func(schema string) *mcp.CallToolResult {
+		return callB("run_query", map[string]any{"connection_id": "sqlite", "sql": "SELECT label FROM " + schema + ".fixtures"})
+	}
+	var rows RunQueryOutput
+	if res := count(outA.Schema); res.IsError || json.Unmarshal([]byte(textContent(res)), &rows) != nil || len(rows.Rows) != 1 || rows.Rows[0]["label"] != "a2" {
+		t.Fatalf("first session's table: %s", textContent(res))
+	}
+
+	closeA()
+	if res := count(outA.Schema); !res.IsError {
+		t.Errorf("first session's schema outlived it: %s", textContent(res))
+	}
+	if res := count(outB.Schema); res.IsError {
+		t.Errorf("second session's schema was dropped with the first: %s", textContent(res))
+	}
+	var n int
+	if err := sqlDB.QueryRow("SELECT count(*) FROM users").Scan(&n); err != nil || n != 1 {
+		t.Errorf("application table has %d rows, %v", n, err)
+	}
+
+	if res := callB("create_test_table", map[string]any{"connection_id": "demo", "table": "t", "columns": createFixtures["columns"]}); resultCode(res) != CodePermissionDenied {
+		t.Errorf("connection without session_schemas: %s", textContent(res))
+	}
+}
@@ -3,8 +3,16 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
 
+	"github.com/SedlarDavid/localdb-mcp/internal/codegen"
+	"github.com/SedlarDavid/localdb-mcp/internal/compare"
 	"github.com/SedlarDavid/localdb-mcp/internal/config"
 	"github.com/SedlarDavid/localdb-mcp/internal/db"
 	"github.com/mark3labs/mcp-go/mcp"
@@ -16,11 +24,94 @@ const (
 	ServerVersion = "1.2.0"
 )
 
-// Register registers tools to the MCP server.
-func Register(s *server.MCPServer, cfg *config.Config) {
+// Register registers tools to the MCP server. It returns the Manager backing
+// the database tools (nil when cfg is nil); the caller closes it on shutdown.
+// With cfg.ReadOnly set, the tools that write to a database are left out.
+// Database tool calls are rate limited per tool class and connection, and
+// run under a deadline per tool category, with at most a configured number
+// running at once per connection; oversized results are truncated, and
+// database calls slower than the config's threshold are logged.
+// With cfg.ConfirmWrites set, every write is first shown to the human through
+// MCP elicitation and runs only once they approve it. Tools list the
+// configured connection IDs in their connection_id schema; see Reload. Every
+// tool call gets a request ID (see RequestID) that is logged and returned with errors,
+// and a panicking tool handler fails only its own call. Credentials are
+// removed from every failed result (see config.Config.Redact). With
+// cfg.AuditDB set, every tool call is recorded in that SQLite file, which
+// query_audit_log searches; mgr.Close closes it. With cfg.EgressBudget set,
+// run_query results that would take a session over it are withheld.
+// With cfg.RecordFile set, every tool call and its result are appended to
+// that file as a RecordedCall, for localdb-mcp replay.
+// cfg.Policies are evaluated before every tool call; see policyMiddleware.
+// Register installs session hooks on s to track per-session state, replacing
+// any hooks s was created with; see RegisterWithHooks to keep them.
+func Register(s *server.MCPServer, cfg *config.Config) *db.Manager {
+	return RegisterWithHooks(s, cfg, nil)
+}
+
+// RegisterWithHooks is Register for a server created with
+// server.WithHooks(hooks): the session hooks are added to hooks rather than
+// replacing them. A nil hooks is the same as Register.
+func RegisterWithHooks(s *server.MCPServer, cfg *config.Config, hooks *server.Hooks) *db.Manager {
 	var mgr *db.Manager
 	if cfg != nil {
 		mgr = db.NewManager(cfg)
+		if d := cfg.SlowQueryThreshold(); d > 0 {
+			mgr.Use(slowQueryLogger{threshold: d})
+		}
+	}
+	sessions := newSessionRegistry()
+	stats := newCallStats()
+	sessions.install(s, hooks)
+	sessions.watchRoots(s)
+	server.WithToolHandlerMiddleware(requestIDMiddleware)(s)
+	if cfg != nil && cfg.RecordFile() != "" {
+		if rec, err := openRecorder(cfg.RecordFile()); err != nil {
+			slog.Error("recording disabled: cannot open record file", "path", cfg.RecordFile(), "err", err)
+		} else {
+			mgr.OnClose(rec.close)
+			server.WithToolHandlerMiddleware(rec.middleware)(s)
+		}
+	}
+	if cfg != nil {
+		server.WithToolHandlerMiddleware(redactMiddleware(cfg))(s)
+	}
+	server.WithToolHandlerMiddleware(recoverMiddleware)(s)
+	var audit *auditLog
+	if cfg != nil && cfg.AuditDB() != "" {
+		var err error
+		if audit, err = openAuditLog(cfg.AuditDB()); err != nil {
+			slog.Error("audit log disabled: cannot open audit database", "path", cfg.AuditDB(), "err", err)
+		} else {
+			mgr.OnClose(audit.close)
+			server.WithToolHandlerMiddleware(audit.middleware)(s)
+		}
+	}
+	if cfg != nil && len(cfg.Policies()) > 0 {
+		server.WithToolHandlerMiddleware(policyMiddleware(s, cfg))(s)
+	}
+	if cfg != nil {
+		limiter := newRateLimiter(cfg.RateLimits())
+		limiter.byConnection = cfg.ConnectionRateLimits()
+		limiter.cfg = cfg
+		server.WithToolHandlerMiddleware(limiter.middleware)(s)
+		server.WithToolHandlerMiddleware(timeoutMiddleware(cfg.Timeouts()))(s)
+		server.WithToolHandlerMiddleware(newConcurrencyLimiter(cfg.MaxConcurrentQueries).middleware)(s)
+		server.WithToolHandlerMiddleware(stats.middleware)(s)
+		if b := cfg.EgressBudget(); b.Bytes > 0 || b.Rows > 0 {
+			server.WithToolHandlerMiddleware(egressMiddleware(sessions, b))(s)
+		}
+		if n := cfg.MaxResultBytes(); n > 0 {
+			server.WithToolHandlerMiddleware(sizeGuardMiddleware(n))(s)
+		}
+		server.WithToolCapabilities(true)(s)
+		if cfg.ConfirmWrites() || cfg.WriteUnlock() || slices.ContainsFunc(cfg.Policies(), func(r config.PolicyRule) bool { return r.Action == config.PolicyConfirm }) {
+			server.WithElicitation()(s)
+		}
+	}
+	var locks *writeLock
+	if cfg != nil && cfg.WriteUnlock() {
+		locks = newWriteLock()
 	}
 
 	// Ping
@@ -32,43 +123,175 @@ func Register(s *server.MCPServer, cfg *config.Config) {
 
 	// List Connections
 	s.AddTool(mcp.NewTool("list_connections",
-		mcp.WithDescription("List configured database connection IDs and their types (postgres, sqlserver, sqlite, mysql). No credentials in response."),
+		mcp.WithDescription("List configured database connection IDs and their types (postgres, sqlserver, sqlite, mysql, demo), "+
+			"with what each backend supports (schemas, RETURNING, transactions, read-only sessions, export). No credentials in response."),
 	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		out := ListConnectionsOutput{Connections: nil}
 		if cfg != nil {
 			out.Connections = cfg.ConnectionInfos()
+			out.Capabilities = make(map[string]db.Capabilities, len(out.Connections))
+			for _, c := range out.Connections {
+				caps, _ := db.CapabilitiesFor(c.Type)
+				caps.Export = caps.Export && cfg.ExportEnabled()
+				out.Capabilities[c.ID] = caps
+			}
+			out.WriteLock = locks.status()
 		}
 		return mcp.NewToolResultJSON(out)
 	})
 
 	if mgr != nil {
+		// Health
+		s.AddTool(mcp.NewTool("health",
+			mcp.WithDescription(
+				"Report the status of each configured connection: whether it has an open (cached) connection, "+
+					"whether it answers a ping now, and the time and latency of the last successful ping. "+
+					"Connections that have not been used yet are not opened unless connect=true. "+
+					"Use it to pick a working connection before running queries."),
+			mcp.WithBoolean("connect", mcp.Description("Also connect to connections that are not open yet (default false)")),
+		), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			connect := false
+			if args, ok := request.Params.Arguments.(map[string]any); ok {
+				connect, _ = args["connect"].(bool)
+			}
+			return mcp.NewToolResultJSON(HealthOutput{Connections: mgr.Health(ctx, connect), WriteLock: locks.status()})
+		})
+
+		// Connection Stats
+		s.AddTool(mcp.NewTool("connection_stats",
+			mcp.WithDescription(
+				"Report per connection: the connection pool's open, in-use and idle connections (while it is open), "+
+					"the database tool calls made on it since the server started, how many failed, "+
+					"and the time and error code of the last failure. No credentials or error messages in response."),
+		), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultJSON(ConnectionStatsOutput{Connections: stats.connectionStats(cfg, mgr)})
+		})
+
+		if audit != nil {
+			// Query Audit Log
+			s.AddTool(mcp.NewTool("query_audit_log",
+				mcp.WithDescription(
+					"Search the audit trail of tool calls, newest first: time, request and session IDs, tool, connection, "+
+						"tables touched, the SQL of run_query, duration and the error code of failed calls. "+
+						"Filter by tool, connection, table (with or without schema), failures only, and a time range."),
+				mcp.WithString("tool", mcp.Description("Only calls of this tool")),
+				mcp.WithString("connection", mcp.Description("Only calls on this connection ID")),
+				mcp.WithString("table", mcp.Description("Only calls that touched this table (e.g. orders or sales.orders)")),
+				mcp.WithBoolean("failed_only", mcp.Description("Only failed calls (default false)")),
+				mcp.WithString("since", mcp.Description("Only calls at or after this RFC 3339 time")),
+				mcp.WithString("until", mcp.Description("Only calls before this RFC 3339 time")),
+				mcp.WithNumber("limit", mcp.Description(fmt.Sprintf("Maximum entries to return (default %d, max %d)", defaultAuditLimit, maxAuditLimit))),
+			), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+				args, _ := request.Params.Arguments.(map[string]any)
+				filter, err := auditFilterFromArgs(args)
+				if err != nil {
+					return invalidArgs(err.Error()), nil
+				}
+				entries, err := audit.query(ctx, filter)
+				if err != nil {
+					return toolErrorResult(err), nil
+				}
+				return mcp.NewToolResultJSON(AuditLogOutput{Entries: entries})
+			})
+		}
+
+		// Refresh Schema
+		s.AddTool(mcp.NewTool("refresh_schema",
+			mcp.WithDescription(
+				"Forget the cached table metadata of a connection, so describe_table and update_test_row read the catalog again. "+
+					"Call it after changing tables outside this server (migrations, DDL in another client). "+
+					"Narrow it with schema and table; without them the whole connection is refreshed."),
+			mcp.WithString("connection_id", mcp.Required(), mcp.Description("Connection ID")),
+			mcp.WithString("schema", mcp.Description("Schema (optional)")),
+			mcp.WithString("table", mcp.Description("Table (optional)")),
+		), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			args, ok := request.Params.Arguments.(map[string]any)
+			if !ok {
+				return invalidArgs("invalid arguments"), nil
+			}
+			connID, ok := args["connection_id"].(string)
+			if !ok {
+				return invalidArgs("connection_id is required"), nil
+			}
+			schema, _ := args["schema"].(string)
+			table, _ := args["table"].(string)
+			n, err := mgr.InvalidateSchema(connID, schema, table)
+			if err != nil {
+				return toolErrorResult(err), nil
+			}
+			return mcp.NewToolResultJSON(RefreshSchemaOutput{ConnectionID: connID, Invalidated: n})
+		})
+
+		// Close Connection
+		s.AddTool(mcp.NewTool("close_connection",
+			mcp.WithDescription(
+				"Close the open (cached) connection to a database, so the next tool call on it connects afresh. "+
+					"Use it after restarting a local database when calls keep failing with stale connection errors."),
+			mcp.WithString("connection_id", mcp.Required(), mcp.Description("Connection ID")),
+		), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			args, ok := request.Params.Arguments.(map[string]any)
+			if !ok {
+				return invalidArgs("invalid arguments"), nil
+			}
+			connID, ok := args["connection_id"].(string)
+			if !ok {
+				return invalidArgs("connection_id is required"), nil
+			}
+			closed, err := mgr.Evict(connID)
+			if err != nil {
+				return toolErrorResult(err), nil
+			}
+			return mcp.NewToolResultJSON(CloseConnectionOutput{ConnectionID: connID, Closed: closed})
+		})
+
 		// List Tables
 		s.AddTool(mcp.NewTool("list_tables",
-			mcp.WithDescription("List table names in a given connection and optional schema."),
+			mcp.WithDescription("List table names in a given connection and optional schema, sorted by name. "+
+				"Results are paged: when next_cursor is set, call again with it as cursor for the next page."),
 			mcp.WithString("connection_id", mcp.Required(), mcp.Description("Connection ID")),
 			mcp.WithString("schema", mcp.Description("Schema (optional)")),
+			mcp.WithString("prefix", mcp.Description("Only tables whose name starts with this, case-insensitively (optional)")),
+			mcp.WithNumber("limit", mcp.Description("Maximum tables per page (default 1000, max 5000)")),
+			mcp.WithString("cursor", mcp.Description("next_cursor from the previous page (optional)")),
 		), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 			args, ok := request.Params.Arguments.(map[string]any)
 			if !ok {
-				return mcp.NewToolResultError("invalid arguments"), nil
+				return invalidArgs("invalid arguments"), nil
 			}
 			
 			connID, ok := args["connection_id"].(string)
 			if !ok {
-				return mcp.NewToolResultError("connection_id is required"), nil
+				return invalidArgs("connection_id is required"), nil
 			}
 			schema, _ := args["schema"].(string)
 
+			schema = schemaOrDefault(cfg, connID, schema)
+			if res := checkSchema(cfg, connID, schema); res != nil {
+				return res, nil
+			}
+			if res := checkSchemaLock(cfg, connID, schema); res != nil {
+				return res, nil
+			}
+			if res := checkSystemTable(cfg, connID, schema, ""); res != nil {
+				return res, nil
+			}
 			driver, err := mgr.Driver(ctx, connID)
 			if err != nil {
-				return mcp.NewToolResultError(err.Error()), nil
+				return toolErrorResult(err), nil
 			}
 			tables, err := driver.ListTables(ctx, schema)
 			if err != nil {
-				return mcp.NewToolResultError(err.Error()), nil
+				return toolErrorResult(err), nil
+			}
+			prefix, _ := args["prefix"].(string)
+			cursor, _ := args["cursor"].(string)
+			limit, _ := args["limit"].(float64)
+			page, next, err := paginate(tables, prefix, cursor, int(limit))
+			if err != nil {
+				return invalidArgs(err.Error()), nil
 			}
 
-			return mcp.NewToolResultJSON(ListTablesOutput{Tables: tables})
+			return mcp.NewToolResultJSON(ListTablesOutput{Tables: page, NextCursor: next})
 		})
 
 		// Describe Table
@@ -80,31 +303,644 @@ func Register(s *server.MCPServer, cfg *config.Config) {
 		), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 			args, ok := request.Params.Arguments.(map[string]any)
 			if !ok {
-				return mcp.NewToolResultError("invalid arguments"), nil
+				return invalidArgs("invalid arguments"), nil
 			}
 
 			connID, ok := args["connection_id"].(string)
 			if !ok {
-				return mcp.NewToolResultError("connection_id is required"), nil
+				return invalidArgs("connection_id is required"), nil
 			}
 			table, ok := args["table"].(string)
 			if !ok {
-				return mcp.NewToolResultError("table is required"), nil
+				return invalidArgs("table is required"), nil
 			}
 			schema, _ := args["schema"].(string)
 
+			schema = schemaOrDefault(cfg, connID, schema)
+			if res := checkSchema(cfg, connID, schema); res != nil {
+				return res, nil
+			}
+			if res := checkSchemaLock(cfg, connID, schema); res != nil {
+				return res, nil
+			}
+			if res := checkSystemTable(cfg, connID, schema, table); res != nil {
+				return res, nil
+			}
 			driver, err := mgr.Driver(ctx, connID)
 			if err != nil {
-				return mcp.NewToolResultError(err.Error()), nil
+				return toolErrorResult(err), nil
 			}
 			cols, err := driver.DescribeTable(ctx, schema, table)
 			if err != nil {
-				return mcp.NewToolResultError(err.Error()), nil
+				return toolErrorResult(err), nil
 			}
 
 			return mcp.NewToolResultJSON(DescribeTableOutput{Columns: cols})
 		})
 
+		// Table JSON Schema
+		s.AddTool(mcp.NewTool("table_json_schema",
+			mcp.WithDescription("Describe a row of a table as a JSON Schema (draft 2020-12) document: a property per column "+
+				"with its JSON type, null allowed for nullable columns, and formats for dates, times and UUIDs. "+
+				"Feed it to code generators and request validators."),
+			mcp.WithString("connection_id", mcp.Required(), mcp.Description("Connection ID")),
+			mcp.WithString("table", mcp.Required(), mcp.Description("Table name")),
+			mcp.WithString("schema", mcp.Description("Schema (optional)")),
+		), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			args, ok := request.Params.Arguments.(map[string]any)
+			if !ok {
+				return invalidArgs("invalid arguments"), nil
+			}
+			t, res := describeForCodegen(ctx, cfg, mgr, args)
+			if res != nil {
+				return res, nil
+			}
+			return mcp.NewToolResultJSON(TableJSONSchemaOutput{Table: t.name, Schema: codegen.JSONSchema(t.engine, t.name, t.cols)})
+		})
+
+		// Generate Go Struct
+		s.AddTool(mcp.NewTool("generate_go_struct",
+			mcp.WithDescription("Generate a Go struct for a row of a table: a field per column with an idiomatic name "+
+				"(customer_id → CustomerID), its Go type for the connection's database, sql.Null types or pointers "+
+				"for nullable columns, and struct tags. Returns gofmt-ed source. style gorm makes it a GORM model "+
+				"with associations from the foreign keys; style sqlc returns sqlc's schema.sql, query.sql and sqlc.yaml instead."),
+			mcp.WithString("connection_id", mcp.Required(), mcp.Description("Connection ID")),
+			mcp.WithString("table", mcp.Required(), mcp.Description("Table name")),
+			mcp.WithString("schema", mcp.Description("Schema (optional)")),
+			mcp.WithString("struct_name", mcp.Description("Struct name (default: the table's name in singular, e.g. OrderItem)")),
+			mcp.WithString("package", mcp.Description("Package name (default models)")),
+			mcp.WithArray("tags", mcp.WithStringItems(), mcp.Description("Struct tag keys, e.g. [\"json\", \"db\", \"gorm\"] (default json and db)")),
+			mcp.WithString("nullable", mcp.Enum(codegen.NullableSQL, codegen.NullablePointer), mcp.Description("Nullable columns as sql.Null types (sql, default) or pointers")),
+			mcp.WithString("style", mcp.Enum(codegen.StylePlain, codegen.StyleGORM, codegen.StyleSQLC), mcp.Description("plain struct (default), GORM model, or sqlc input files")),
+		), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			args, ok := request.Params.Arguments.(map[string]any)
+			if !ok {
+				return invalidArgs("invalid arguments"), nil
+			}
+			opts := codegen.GoOptions{}
+			opts.StructName, _ = args["struct_name"].(string)
+			opts.Package, _ = args["package"].(string)
+			opts.Nullable, _ = args["nullable"].(string)
+			if raw, ok := args["tags"]; ok {
+				tags, err := stringList(raw)
+				if err != nil {
+					return invalidArgs("tags: " + err.Error()), nil
+				}
+				opts.Tags = tags
+			}
+			opts.Style, _ = args["style"].(string)
+			t, res := describeForCodegen(ctx, cfg, mgr, args)
+			if res != nil {
+				return res, nil
+			}
+			if opts.Style == codegen.StyleSQLC {
+				files, err := codegen.SQLC(t.engine, t.name, t.cols, opts.Package)
+				if err != nil {
+					return invalidArgs(err.Error()), nil
+				}
+				return mcp.NewToolResultJSON(GenerateCodeOutput{Table: t.name, Files: files})
+			}
+			if opts.Style == codegen.StyleGORM {
+				connID, _ := args["connection_id"].(string)
+				fks, _, err := keysForCodegen(ctx, mgr, connID, t.schema)
+				if err != nil {
+					return toolErrorResult(err), nil
+				}
+				opts.ForeignKeys = fks
+			}
+			code, err := codegen.GoStruct(t.engine, t.name, t.cols, opts)
+			if err != nil {
+				return invalidArgs(err.Error()), nil
+			}
+			return mcp.NewToolResultJSON(GenerateCodeOutput{Table: t.name, Code: code})
+		})
+
+		s.AddTool(mcp.NewTool("generate_typescript_types",
+			mcp.WithDescription("Generate TypeScript types for rows of a table, or of every table of a schema if table is omitted: "+
+				"an interface (or zod schema) per table, typed for the connection's database, with nullable columns as unions with null."),
+			mcp.WithString("connection_id", mcp.Required(), mcp.Description("Connection ID")),
+			mcp.WithString("table", mcp.Description("Table name (optional; all tables of the schema if omitted)")),
+			mcp.WithString("schema", mcp.Description("Schema (optional)")),
+			mcp.WithString("format", mcp.Enum(codegen.TSInterface, codegen.TSZod), mcp.Description("interface (default) or zod schemas with their inferred types")),
+		), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			args, ok := request.Params.Arguments.(map[string]any)
+			if !ok {
+				return invalidArgs("invalid arguments"), nil
+			}
+			format, _ := args["format"].(string)
+			tables, res := describeAllForCodegen(ctx, cfg, mgr, args)
+			if res != nil {
+				return res, nil
+			}
+			code, err := codegen.TypeScript(tables[0].engine, codegenInput(tables), format)
+			if err != nil {
+				return invalidArgs(err.Error()), nil
+			}
+			table, _ := args["table"].(string)
+			return mcp.NewToolResultJSON(GenerateCodeOutput{Table: table, Code: code})
+		})
+
+		s.AddTool(mcp.NewTool("generate_prisma_schema",
+			mcp.WithDescription("Generate a Prisma schema from every table of a schema: a model per table with its columns, "+
+				"primary key, unique constraints and indexes, and relation fields for its foreign keys, "+
+				"to bootstrap a Prisma ORM layer from an existing database."),
+			mcp.WithString("connection_id", mcp.Required(), mcp.Description("Connection ID")),
+			mcp.WithString("schema", mcp.Description("Schema (optional)")),
+		), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			args, ok := request.Params.Arguments.(map[string]any)
+			if !ok {
+				return invalidArgs("invalid arguments"), nil
+			}
+			tables, res := describeAllForCodegen(ctx, cfg, mgr, map[string]any{
+				"connection_id": args["connection_id"], "schema": args["schema"],
+			})
+			if res != nil {
+				return res, nil
+			}
+			connID, _ := args["connection_id"].(string)
+			fks, idxs, err := keysForCodegen(ctx, mgr, connID, tables[0].schema)
+			if err != nil {
+				return toolErrorResult(err), nil
+			}
+			code, err := codegen.Prisma(tables[0].engine, codegenInput(tables), fks, idxs)
+			if err != nil {
+				return invalidArgs(err.Error()), nil
+			}
+			return mcp.NewToolResultJSON(GenerateCodeOutput{Code: code})
+		})
+
+		s.AddTool(mcp.NewTool("generate_graphql_sdl",
+			mcp.WithDescription("Generate a GraphQL schema (SDL) from every table of a schema: an object type per table, "+
+				"relation fields for its foreign keys in both directions, and a Query type listing each table's rows "+
+				"and looking one up by primary key, to scaffold an API layer on an existing database."),
+			mcp.WithString("connection_id", mcp.Required(), mcp.Description("Connection ID")),
+			mcp.WithString("schema", mcp.Description("Schema (optional)")),
+			mcp.WithString("field_case", mcp.Enum(codegen.CaseCamel, codegen.CaseSnake, codegen.CasePreserve),
+				mcp.Description("Field names in camelCase (camel, default), snake_case (snake) or as the columns are named (preserve)")),
+		), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			args, ok := request.Params.Arguments.(map[string]any)
+			if !ok {
+				return invalidArgs("invalid arguments"), nil
+			}
+			fieldCase, _ := args["field_case"].(string)
+			tables, res := describeAllForCodegen(ctx, cfg, mgr, map[string]any{
+				"connection_id": args["connection_id"], "schema": args["schema"],
+			})
+			if res != nil {
+				return res, nil
+			}
+			connID, _ := args["connection_id"].(string)
+			fks, idxs, err := keysForCodegen(ctx, mgr, connID, tables[0].schema)
+			if err != nil {
+				return toolErrorResult(err), nil
+			}
+			code, err := codegen.GraphQL(tables[0].engine, codegenInput(tables), fks, idxs, fieldCase)
+			if err != nil {
+				return invalidArgs(err.Error()), nil
+			}
+			return mcp.NewToolResultJSON(GenerateCodeOutput{Code: code})
+		})
+
+		s.AddTool(mcp.NewTool("generate_erd",
+			mcp.WithDescription("Generate a Mermaid erDiagram of the tables of a schema and the foreign keys between them, "+
+				"with columns and keys. Scope it to a table and the tables within depth foreign keys of it. "+
+				"The code renders in a ```mermaid block."),
+			mcp.WithString("connection_id", mcp.Required(), mcp.Description("Connection ID")),
+			mcp.WithString("schema", mcp.Description("Schema (optional)")),
+			mcp.WithString("table", mcp.Description("Only this table and the tables related to it (optional)")),
+			mcp.WithNumber("depth", mcp.Description("With table: how many foreign keys away related tables may be (default 1; 0 for the table alone)")),
+			mcp.WithBoolean("columns", mcp.Description("List each table's columns (default true)")),
+		), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			args, ok := request.Params.Arguments.(map[string]any)
+			if !ok {
+				return invalidArgs("invalid arguments"), nil
+			}
+			depth := 1
+			if n, ok := args["depth"].(float64); ok {
+				if n < 0 {
+					return invalidArgs("depth must not be negative"), nil
+				}
+				depth = int(n)
+			}
+			columns := true
+			if b, ok := args["columns"].(bool); ok {
+				columns = b
+			}
+			table, _ := args["table"].(string)
+			if table != "" {
+				// Run the checks on the table, and fail if it does not exist.
+				if _, res := describeForCodegen(ctx, cfg, mgr, args); res != nil {
+					return res, nil
+				}
+			}
+			tables, res := describeAllForCodegen(ctx, cfg, mgr, map[string]any{
+				"connection_id": args["connection_id"], "schema": args["schema"],
+			})
+			if res != nil {
+				return res, nil
+			}
+			connID, _ := args["connection_id"].(string)
+			fks, idxs, err := keysForCodegen(ctx, mgr, connID, tables[0].schema)
+			if err != nil {
+				return toolErrorResult(err), nil
+			}
+			if table != "" {
+				related := codegen.Related(fks, table, depth)
+				tables = slices.DeleteFunc(tables, func(t codegenTable) bool { return !slices.Contains(related, t.name) })
+			}
+			code := codegen.ERD(codegenInput(tables), fks, idxs, columns)
+			return mcp.NewToolResultJSON(GenerateCodeOutput{Table: table, Code: code})
+		})
+
+		s.AddTool(mcp.NewTool("compare_schemas",
+			mcp.WithDescription("Compare the schemas of two connections, e.g. a local SQLite database and a staging Postgres: "+
+				"tables missing from either, and for the tables in both, missing columns, type and nullability differences, "+
+				"primary keys and indexes. Side a is connection_id, side b other_connection_id. Names match case-insensitively; "+
+				"types of different engines are compared by what they hold (integer, string, timestamp, ...)."),
+			mcp.WithString("connection_id", mcp.Required(), mcp.Description("Connection ID of side a")),
+			mcp.WithString("other_connection_id", mcp.Required(), mcp.Description("Connection ID of side b")),
+			mcp.WithString("schema", mcp.Description("Schema of side a (optional)")),
+			mcp.WithString("other_schema", mcp.Description("Schema of side b (optional)")),
+		), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			args, ok := request.Params.Arguments.(map[string]any)
+			if !ok {
+				return invalidArgs("invalid arguments"), nil
+			}
+			connA, ok := args["connection_id"].(string)
+			if !ok {
+				return invalidArgs("connection_id is required"), nil
+			}
+			connB, ok := args["other_connection_id"].(string)
+			if !ok {
+				return invalidArgs("other_connection_id is required"), nil
+			}
+			schemaA, _ := args["schema"].(string)
+			schemaB, _ := args["other_schema"].(string)
+			a, schemaA, res := compareSide(ctx, cfg, mgr, connA, schemaA)
+			if res != nil {
+				return res, nil
+			}
+			b, schemaB, res := compareSide(ctx, cfg, mgr, connB, schemaB)
+			if res != nil {
+				return res, nil
+			}
+			return mcp.NewToolResultJSON(CompareSchemasOutput{
+				ConnectionA: connA, SchemaA: schemaA, ConnectionB: connB, SchemaB: schemaB,
+				SchemaDiff: compare.Schemas(a, b),
+			})
+		})
+
+		s.AddTool(mcp.NewTool("compare_table_data",
+			mcp.WithDescription("Compare the rows of a table across two connections, or of two tables on one connection, "+
+				"matched by primary key (or key): counts of the rows only in either table and of the rows whose values differ, "+
+				"with a sample of the differences. Side a is connection_id and table, side b other_connection_id and other_table, "+
+				"which default to them. The columns of both tables are compared; values match when they hold the same thing "+
+				"whatever the engine, so an integer 1 equals 1.0 and a boolean true. Each table may have at most max_rows rows."),
+			mcp.WithString("connection_id", mcp.Required(), mcp.Description("Connection ID of side a")),
+			mcp.WithString("table", mcp.Required(), mcp.Description("Table of side a")),
+			mcp.WithString("schema", mcp.Description("Schema of side a (optional)")),
+			mcp.WithString("other_connection_id", mcp.Description("Connection ID of side b (default connection_id)")),
+			mcp.WithString("other_table", mcp.Description("Table of side b (default table)")),
+			mcp.WithString("other_schema", mcp.Description("Schema of side b (default schema on the same connection)")),
+			mcp.WithArray("key", mcp.WithStringItems(), mcp.Description("Columns to match rows by (default the primary key of side a)")),
+			mcp.WithNumber("sample", mcp.Description(fmt.Sprintf("How many differing rows to return (default %d, max %d)", DefaultCompareSample, MaxCompareSample))),
+			mcp.WithNumber("max_rows", mcp.Description(fmt.Sprintf("Most rows to read from each table (default %d, max %d)", DefaultCompareMaxRows, MaxCompareMaxRows))),
+		), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			args, ok := request.Params.Arguments.(map[string]any)
+			if !ok {
+				return invalidArgs("invalid arguments"), nil
+			}
+			connA, ok := args["connection_id"].(string)
+			if !ok {
+				return invalidArgs("connection_id is required"), nil
+			}
+			tableA, ok := args["table"].(string)
+			if !ok || tableA == "" {
+				return invalidArgs("table is required"), nil
+			}
+			schemaA, _ := args["schema"].(string)
+			connB, _ := args["other_connection_id"].(string)
+			if connB == "" {
+				connB = connA
+			}
+			tableB, _ := args["other_table"].(string)
+			if tableB == "" {
+				tableB = tableA
+			}
+			schemaB, _ := args["other_schema"].(string)
+			if schemaB == "" && connB == connA {
+				schemaB = schemaA
+			}
+			var key []string
+			if v, ok := args["key"]; ok {
+				var err error
+				if key, err = stringList(v); err != nil {
+					return invalidArgs("key: " + err.Error()), nil
+				}
+			}
+			sample := DefaultCompareSample
+			if n, ok := args["sample"].(float64); ok {
+				if n < 0 || n > MaxCompareSample {
+					return invalidArgs(fmt.Sprintf("sample must be between 0 and %d", MaxCompareSample)), nil
+				}
+				sample = int(n)
+			}
+			maxRows := DefaultCompareMaxRows
+			if n, ok := args["max_rows"].(float64); ok {
+				if n < 1 || n > MaxCompareMaxRows {
+					return invalidArgs(fmt.Sprintf("max_rows must be between 1 and %d", MaxCompareMaxRows)), nil
+				}
+				maxRows = int(n)
+			}
+			a, res := describeCompareTable(ctx, cfg, mgr, connA, schemaA, tableA)
+			if res != nil {
+				return res, nil
+			}
+			b, res := describeCompareTable(ctx, cfg, mgr, connB, schemaB, tableB)
+			if res != nil {
+				return res, nil
+			}
+			return compareTableData(ctx, cfg, mgr, a, b, key, maxRows, sample), nil
+		})
+
+		s.AddTool(mcp.NewTool("profile_table",
+			mcp.WithDescription("Profile a table's columns before writing queries against it: the fraction of NULLs, "+
+				"the number of distinct values, min and max, the most common values and the average length of strings, "+
+				"as far as each column's type supports them. Tables with more than sample_rows rows are profiled over their "+
+				"first sample_rows rows (sampled is then set). Row filters and masking apply."),
+			mcp.WithString("connection_id", mcp.Required(), mcp.Description("Connection ID")),
+			mcp.WithString("table", mcp.Required(), mcp.Description("Table name")),
+			mcp.WithString("schema", mcp.Description("Schema (optional)")),
+			mcp.WithArray("columns", mcp.WithStringItems(), mcp.Description("Columns to profile (default all)")),
+			mcp.WithNumber("sample_rows", mcp.Description(fmt.Sprintf("Most rows to profile (default %d, max %d)", DefaultProfileSampleRows, MaxProfileSampleRows))),
+			mcp.WithNumber("top_k", mcp.Description(fmt.Sprintf("How many of the most common values to return per column (default %d, max %d; 0 for none)", DefaultProfileTopK, MaxProfileTopK))),
+		), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			args, ok := request.Params.Arguments.(map[string]any)
+			if !ok {
+				return invalidArgs("invalid arguments"), nil
+			}
+			connID, ok := args["connection_id"].(string)
+			if !ok {
+				return invalidArgs("connection_id is required"), nil
+			}
+			table, ok := args["table"].(string)
+			if !ok || table == "" {
+				return invalidArgs("table is required"), nil
+			}
+			schema, _ := args["schema"].(string)
+			var columns []string
+			if v, ok := args["columns"]; ok {
+				var err error
+				if columns, err = stringList(v); err != nil {
+					return invalidArgs("columns: " + err.Error()), nil
+				}
+			}
+			sampleRows := DefaultProfileSampleRows
+			if n, ok := args["sample_rows"].(float64); ok {
+				if n < 1 || n > MaxProfileSampleRows {
+					return invalidArgs(fmt.Sprintf("sample_rows must be between 1 and %d", MaxProfileSampleRows)), nil
+				}
+				sampleRows = int(n)
+			}
+			topK := DefaultProfileTopK
+			if n, ok := args["top_k"].(float64); ok {
+				if n < 0 || n > MaxProfileTopK {
+					return invalidArgs(fmt.Sprintf("top_k must be between 0 and %d", MaxProfileTopK)), nil
+				}
+				topK = int(n)
+			}
+			return profileTable(ctx, cfg, mgr, connID, schema, table, columns, sampleRows, topK), nil
+		})
+
+		s.AddTool(mcp.NewTool("find_duplicates",
+			mcp.WithDescription("Find the groups of rows of a table that share the values of some columns, e.g. before adding "+
+				"a unique constraint: the number of groups and of rows in them, and the biggest groups with their values, "+
+				"row count and the primary keys of a few of their rows. Rows with a NULL in any of the columns are left out, "+
+				"as unique constraints allow them. Row filters apply."),
+			mcp.WithString("connection_id", mcp.Required(), mcp.Description("Connection ID")),
+			mcp.WithString("table", mcp.Required(), mcp.Description("Table name")),
+			mcp.WithString("schema", mcp.Description("Schema (optional)")),
+			mcp.WithArray("columns", mcp.Required(), mcp.WithStringItems(), mcp.Description("Columns whose values rows must share")),
+			mcp.WithNumber("limit", mcp.Description(fmt.Sprintf("Most groups to return (default %d, max %d)", DefaultDuplicateGroups, MaxDuplicateGroups))),
+			mcp.WithNumber("sample_keys", mcp.Description(fmt.Sprintf("Primary keys to return per group (default %d, max %d)", DefaultDuplicateKeys, MaxDuplicateKeys))),
+		), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			args, ok := request.Params.Arguments.(map[string]any)
+			if !ok {
+				return invalidArgs("invalid arguments"), nil
+			}
+			connID, ok := args["connection_id"].(string)
+			if !ok {
+				return invalidArgs("connection_id is required"), nil
+			}
+			table, ok := args["table"].(string)
+			if !ok || table == "" {
+				return invalidArgs("table is required"), nil
+			}
+			schema, _ := args["schema"].(string)
+			columns, err := stringList(args["columns"])
+			if err != nil || len(columns) == 0 {
+				return invalidArgs("columns is required: an array of column names"), nil
+			}
+			limit := DefaultDuplicateGroups
+			if n, ok := args["limit"].(float64); ok {
+				if n < 1 || n > MaxDuplicateGroups {
+					return invalidArgs(fmt.Sprintf("limit must be between 1 and %d", MaxDuplicateGroups)), nil
+				}
+				limit = int(n)
+			}
+			keys := DefaultDuplicateKeys
+			if n, ok := args["sample_keys"].(float64); ok {
+				if n < 0 || n > MaxDuplicateKeys {
+					return invalidArgs(fmt.Sprintf("sample_keys must be between 0 and %d", MaxDuplicateKeys)), nil
+				}
+				keys = int(n)
+			}
+			return findDuplicates(ctx, cfg, mgr, connID, schema, table, columns, limit, keys), nil
+		})
+
+		s.AddTool(mcp.NewTool("check_referential_integrity",
+			mcp.WithDescription("Verify that the declared foreign keys of a schema, or of one table, hold: for each, the number "+
+				"of orphans, rows whose key references no row of the parent table, with the primary keys of a few of them. "+
+				"Useful after manual imports, or on SQLite with foreign keys off. Keys with a NULL are not checked, as they "+
+				"reference nothing. Row filters apply."),
+			mcp.WithString("connection_id", mcp.Required(), mcp.Description("Connection ID")),
+			mcp.WithString("schema", mcp.Description("Schema (optional)")),
+			mcp.WithString("table", mcp.Description("Check only the foreign keys of this table (optional)")),
+			mcp.WithNumber("sample", mcp.Description(fmt.Sprintf("Orphans to return per foreign key (default %d, max %d)", DefaultOrphanSample, MaxOrphanSample))),
+		), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			args, ok := request.Params.Arguments.(map[string]any)
+			if !ok {
+				return invalidArgs("invalid arguments"), nil
+			}
+			connID, ok := args["connection_id"].(string)
+			if !ok {
+				return invalidArgs("connection_id is required"), nil
+			}
+			schema, _ := args["schema"].(string)
+			table, _ := args["table"].(string)
+			sample := DefaultOrphanSample
+			if n, ok := args["sample"].(float64); ok {
+				if n < 0 || n > MaxOrphanSample {
+					return invalidArgs(fmt.Sprintf("sample must be between 0 and %d", MaxOrphanSample)), nil
+				}
+				sample = int(n)
+			}
+			return checkReferentialIntegrity(ctx, cfg, mgr, connID, schema, table, sample), nil
+		})
+
+		findOrphansTool := mcp.NewTool("find_orphans",
+			mcp.WithDescription("Find child rows whose parent is missing, for relationships the schema does not declare as "+
+				"foreign keys (check_referential_integrity checks the declared ones): for each relationship, the number of "+
+				"orphans, rows whose columns, with no NULL in them, match no row of the parent table, with the primary keys "+
+				"of a few of them. Row filters apply."),
+			mcp.WithString("connection_id", mcp.Required(), mcp.Description("Connection ID")),
+			mcp.WithString("schema", mcp.Description("Schema of the tables (optional)")),
+			mcp.WithNumber("sample", mcp.Description(fmt.Sprintf("Orphans to return per relationship (default %d, max %d)", DefaultOrphanSample, MaxOrphanSample))),
+		)
+		findOrphansTool.InputSchema.Properties["relationships"] = map[string]any{
+			"type": "array",
+			"items": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"table":       map[string]any{"type": "string", "description": "Child table"},
+					"columns":     map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "description": "Columns of the child table referencing the parent"},
+					"ref_table":   map[string]any{"type": "string", "description": "Parent table"},
+					"ref_columns": map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "description": "Columns of the parent table, in the order of columns (default its primary key)"},
+				},
+				"required": []string{"table", "columns", "ref_table"},
+			},
+			"description": "Relationships to check, e.g. [{\"table\": \"orders\", \"columns\": [\"customer_id\"], \"ref_table\": \"customers\"}]",
+		}
+		findOrphansTool.InputSchema.Required = append(findOrphansTool.InputSchema.Required, "relationships")
+
+		s.AddTool(findOrphansTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			args, ok := request.Params.Arguments.(map[string]any)
+			if !ok {
+				return invalidArgs("invalid arguments"), nil
+			}
+			connID, ok := args["connection_id"].(string)
+			if !ok {
+				return invalidArgs("connection_id is required"), nil
+			}
+			schema, _ := args["schema"].(string)
+			rels, err := parseRelationships(args["relationships"])
+			if err != nil {
+				return invalidArgs(err.Error()), nil
+			}
+			sample := DefaultOrphanSample
+			if n, ok := args["sample"].(float64); ok {
+				if n < 0 || n > MaxOrphanSample {
+					return invalidArgs(fmt.Sprintf("sample must be between 0 and %d", MaxOrphanSample)), nil
+				}
+				sample = int(n)
+			}
+			return findOrphans(ctx, cfg, mgr, connID, schema, rels, sample), nil
+		})
+
+		// Suggest Indexes
+		suggestIndexesTool := mcp.NewTool("suggest_indexes",
+			mcp.WithDescription(
+				"Suggest indexes for a read-only query: the columns its WHERE and JOIN conditions compare and its ORDER BY sorts by, "+
+					"on tables its plan (EXPLAIN, where the database has one) reads in full, with the CREATE INDEX statement for each. "+
+					"Candidates an existing index already serves are listed as covered. "+
+					"These are suggestions only: nothing is created, and the statements are for you to review and run."),
+			mcp.WithString("connection_id", mcp.Required(), mcp.Description("Connection ID")),
+			mcp.WithString("sql", mcp.Required(), mcp.Description("SELECT query to analyze; it is explained, not run")),
+			mcp.WithString("schema", mcp.Description("Schema of the tables the query reads (optional)")),
+		)
+		suggestIndexesTool.InputSchema.Properties["params"] = map[string]any{
+			"type": "array",
+			"items": map[string]any{
+				"type": []string{"string", "number", "boolean", "null"},
+			},
+			"description": "Positional parameters for the query, as for run_query",
+		}
+		s.AddTool(suggestIndexesTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			args, ok := request.Params.Arguments.(map[string]any)
+			if !ok {
+				return invalidArgs("invalid arguments"), nil
+			}
+			connID, ok := args["connection_id"].(string)
+			if !ok {
+				return invalidArgs("connection_id is required"), nil
+			}
+			sql, ok := args["sql"].(string)
+			if !ok {
+				return invalidArgs("sql is required"), nil
+			}
+			schema, _ := args["schema"].(string)
+			params, _ := args["params"].([]any)
+			if res := checkReadOnlySQL(cfg, connID, sql); res != nil {
+				return res, nil
+			}
+			return suggestIndexes(ctx, cfg, mgr, connID, schema, sql, params), nil
+		})
+
+		// Explain Query
+		explainQueryTool := mcp.NewTool("explain_query",
+			mcp.WithDescription(
+				"Show the estimated plan of a read-only query without running it, as structured steps with the same fields on every engine: "+
+					"operation (the engine's name for the step), relation, alias, index, full_scan (reads every row of a table), "+
+					"estimated_rows, cost (in the engine's units, including the children), detail and children. "+
+					"From EXPLAIN (FORMAT JSON) on PostgreSQL, EXPLAIN FORMAT=JSON on MySQL, SHOWPLAN_XML on SQL Server and EXPLAIN QUERY PLAN on SQLite, "+
+					"which has no estimates."),
+			mcp.WithString("connection_id", mcp.Required(), mcp.Description("Connection ID")),
+			mcp.WithString("sql", mcp.Required(), mcp.Description("SELECT query to explain")),
+		)
+		explainQueryTool.InputSchema.Properties["params"] = map[string]any{
+			"type": "array",
+			"items": map[string]any{
+				"type": []string{"string", "number", "boolean", "null"},
+			},
+			"description": "Positional parameters for the query, as for run_query",
+		}
+		s.AddTool(explainQueryTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			args, ok := request.Params.Arguments.(map[string]any)
+			if !ok {
+				return invalidArgs("invalid arguments"), nil
+			}
+			connID, ok := args["connection_id"].(string)
+			if !ok {
+				return invalidArgs("connection_id is required"), nil
+			}
+			sql, ok := args["sql"].(string)
+			if !ok {
+				return invalidArgs("sql is required"), nil
+			}
+			params, _ := args["params"].([]any)
+			if res := checkReadOnlySQL(cfg, connID, sql); res != nil {
+				return res, nil
+			}
+			return explainQuery(ctx, cfg, mgr, connID, sql, params), nil
+		})
+
+		// List Dependencies
+		s.AddTool(mcp.NewTool("list_dependencies",
+			mcp.WithDescription(
+				"List what depends on a table, and may break if it changes: the foreign keys referencing it and the views, "+
+					"routines and triggers depending on it, as the database records them (pg_depend, sys.sql_expression_dependencies, "+
+					"INFORMATION_SCHEMA). Where it records none (SQLite views, MySQL routines), objects whose definition names the table "+
+					"are listed as inferred. Check it before proposing a schema change."),
+			mcp.WithString("connection_id", mcp.Required(), mcp.Description("Connection ID")),
+			mcp.WithString("table", mcp.Required(), mcp.Description("Table name")),
+			mcp.WithString("schema", mcp.Description("Schema (optional)")),
+		), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			args, ok := request.Params.Arguments.(map[string]any)
+			if !ok {
+				return invalidArgs("invalid arguments"), nil
+			}
+			connID, ok := args["connection_id"].(string)
+			if !ok {
+				return invalidArgs("connection_id is required"), nil
+			}
+			table, ok := args["table"].(string)
+			if !ok || table == "" {
+				return invalidArgs("table is required"), nil
+			}
+			schema, _ := args["schema"].(string)
+			return listDependencies(ctx, cfg, mgr, connID, schema, table), nil
+		})
+
 		// Run Query
 		runQueryTool := mcp.NewTool("run_query",
 			mcp.WithDescription("Run a read-only SQL query (SELECT only). Rejects INSERT/UPDATE/DELETE/DDL. Params are positional."),
@@ -123,16 +959,16 @@ func Register(s *server.MCPServer, cfg *config.Config) {
 		s.AddTool(runQueryTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 			args, ok := request.Params.Arguments.(map[string]any)
 			if !ok {
-				return mcp.NewToolResultError("invalid arguments"), nil
+				return invalidArgs("invalid arguments"), nil
 			}
 
 			connID, ok := args["connection_id"].(string)
 			if !ok {
-				return mcp.NewToolResultError("connection_id is required"), nil
+				return invalidArgs("connection_id is required"), nil
 			}
 			sql, ok := args["sql"].(string)
 			if !ok {
-				return mcp.NewToolResultError("sql is required"), nil
+				return invalidArgs("sql is required"), nil
 			}
 
 			var params []any
@@ -142,22 +978,80 @@ func Register(s *server.MCPServer, cfg *config.Config) {
 				}
 			}
 
-			if err := ValidateReadOnlySQL(sql); err != nil {
-				return mcp.NewToolResultError(err.Error()), nil
+			if res := checkReadOnlySQL(cfg, connID, sql); res != nil {
+				return res, nil
+			}
+			if res := checkMaskedSQL(cfg, connID, sql); res != nil {
+				return res, nil
+			}
+			sql, res := applyQueryRowFilters(cfg, connID, sql)
+			if res != nil {
+				return res, nil
 			}
 
 			driver, err := mgr.Driver(ctx, connID)
 			if err != nil {
-				return mcp.NewToolResultError(err.Error()), nil
+				return toolErrorResult(err), nil
 			}
 			rows, err := driver.RunReadOnlyQuery(ctx, sql, params)
 			if err != nil {
-				return mcp.NewToolResultError(err.Error()), nil
+				return toolErrorResult(err), nil
 			}
 
 			return mcp.NewToolResultJSON(RunQueryOutput{Rows: rows})
 		})
 
+		// Transactions
+		txs := newTxRegistry(TransactionIdleTimeout)
+		sessions.onSessionRelease(txs.release)
+
+		if locks != nil {
+			// Enable Writes
+			s.AddTool(mcp.NewTool("enable_writes",
+				mcp.WithDescription(
+					"Ask the user, through the client, to enable the write tools (insert_test_row, update_test_row, "+
+						"begin_transaction, import_database) on a connection for a few minutes. "+
+						"Writes are locked until the user approves, and lock again by themselves when the time is up; "+
+						"list_connections and health show until when each connection is unlocked."),
+				mcp.WithString("connection_id", mcp.Required(), mcp.Description("Connection ID")),
+				mcp.WithNumber("minutes", mcp.Description(fmt.Sprintf("How long to enable writes (default %d, max %d)", DefaultWriteUnlockMinutes, MaxWriteUnlockMinutes))),
+				mcp.WithString("reason", mcp.Description("What the writes are for, shown to the user")),
+			), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+				args, ok := request.Params.Arguments.(map[string]any)
+				if !ok {
+					return invalidArgs("invalid arguments"), nil
+				}
+				connID, ok := args["connection_id"].(string)
+				if !ok {
+					return invalidArgs("connection_id is required"), nil
+				}
+				minutes := DefaultWriteUnlockMinutes
+				if n, ok := args["minutes"].(float64); ok {
+					if n < 1 || n > MaxWriteUnlockMinutes {
+						return invalidArgs(fmt.Sprintf("minutes must be between 1 and %d", MaxWriteUnlockMinutes)), nil
+					}
+					minutes = int(n)
+				}
+				reason, _ := args["reason"].(string)
+				if _, ok := cfg.Type(connID); !ok {
+					return toolErrorResult(fmt.Errorf("%w: %q", db.ErrUnknownConnection, connID)), nil
+				}
+				if res := checkWritable(cfg, connID); res != nil {
+					return res, nil
+				}
+				message := fmt.Sprintf("An agent wants to enable writes to the %q connection for %d minutes.", connID, minutes)
+				if reason != "" {
+					message += "\n\nReason given: " + reason
+				}
+				if err := askUser(ctx, s, message+"\n\nAllow it?", "Enable writes", "Check to unlock the write tools for this connection"); err != nil {
+					return toolErrorResult(err), nil
+				}
+				until := locks.unlock(connID, time.Duration(minutes)*time.Minute)
+				slog.Info("writes enabled", "connection_id", connID, "until", until)
+				return mcp.NewToolResultJSON(EnableWritesOutput{ConnectionID: connID, UnlockedUntil: until})
+			})
+		}
+
 		// Insert Test Row
 		insertRowTool := mcp.NewTool("insert_test_row",
 			mcp.WithDescription("Insert a single test row. Optionally return generated ID (e.g. serial/identity)."),
@@ -165,6 +1059,7 @@ func Register(s *server.MCPServer, cfg *config.Config) {
 			mcp.WithString("table", mcp.Required(), mcp.Description("Table name")),
 			mcp.WithBoolean("return_id", mcp.Description("Return generated ID")),
 			mcp.WithString("schema", mcp.Description("Schema (optional)")),
+			mcp.WithString("transaction_id", mcp.Description("Insert inside this transaction from begin_transaction (optional)")),
 		)
 		insertRowTool.InputSchema.Properties["row"] = map[string]any{
 			"type":                 "object",
@@ -176,32 +1071,59 @@ func Register(s *server.MCPServer, cfg *config.Config) {
 		s.AddTool(insertRowTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 			args, ok := request.Params.Arguments.(map[string]any)
 			if !ok {
-				return mcp.NewToolResultError("invalid arguments"), nil
+				return invalidArgs("invalid arguments"), nil
 			}
 
 			connID, ok := args["connection_id"].(string)
 			if !ok {
-				return mcp.NewToolResultError("connection_id is required"), nil
+				return invalidArgs("connection_id is required"), nil
 			}
 			table, ok := args["table"].(string)
 			if !ok {
-				return mcp.NewToolResultError("table is required"), nil
+				return invalidArgs("table is required"), nil
 			}
 			returnID, _ := args["return_id"].(bool)
 			schema, _ := args["schema"].(string)
 
 			rowMap, ok := args["row"].(map[string]any)
 			if !ok || len(rowMap) == 0 {
-				return mcp.NewToolResultError("row is required and must be an object"), nil
+				return invalidArgs("row is required and must be an object"), nil
 			}
 
+			if res := checkWritable(cfg, connID); res != nil {
+				return res, nil
+			}
+			if res := locks.check(connID); res != nil {
+				return res, nil
+			}
+			if scratch := sessions.scratchTableSchema(ctx, connID, schema, table); scratch != "" {
+				schema = scratch
+			} else {
+				schema = schemaOrDefault(cfg, connID, schema)
+				if res := checkWriteTarget(cfg, connID, config.OpInsert, schema, table); res != nil {
+					return res, nil
+				}
+			}
+			if res := applyInsertRowFilter(cfg, connID, schema, table, rowMap); res != nil {
+				return res, nil
+			}
 			driver, err := mgr.Driver(ctx, connID)
 			if err != nil {
-				return mcp.NewToolResultError(err.Error()), nil
+				return toolErrorResult(err), nil
+			}
+			w, done, res := txs.writer(ctx, args, mgr, connID, driver, cfg.MaxRowsAffected())
+			if res != nil {
+				return res, nil
+			}
+			defer done()
+			if cfg.ConfirmWrites() {
+				if err := confirmWrite(ctx, s, connID, previewInsert(driver, schema, table, rowMap)); err != nil {
+					return toolErrorResult(err), nil
+				}
 			}
-			id, err := driver.InsertRow(ctx, schema, table, rowMap)
+			id, err := w.InsertRow(ctx, schema, table, rowMap)
 			if err != nil {
-				return mcp.NewToolResultError(err.Error()), nil
+				return toolErrorResult(err), nil
 			}
 
 			out := InsertTestRowOutput{}
@@ -213,10 +1135,14 @@ func Register(s *server.MCPServer, cfg *config.Config) {
 
 		// Update Test Row
 		updateRowTool := mcp.NewTool("update_test_row",
-			mcp.WithDescription("Update a single row identified by its primary key. Safely enforces PK-only targeting to prevent mass updates."),
+			mcp.WithDescription("Update a single row identified by its primary key. Safely enforces PK-only targeting to prevent mass updates; "+
+				"an update changing more rows than the server allows (default 1) is rolled back. "+
+				"With expected, the update only applies if the row still has those values; otherwise it fails with code conflict "+
+				"and the row's current values, so data changed since it was read is not overwritten."),
 			mcp.WithString("connection_id", mcp.Required(), mcp.Description("Connection ID")),
 			mcp.WithString("table", mcp.Required(), mcp.Description("Table name")),
 			mcp.WithString("schema", mcp.Description("Schema (optional)")),
+			mcp.WithString("transaction_id", mcp.Description("Update inside this transaction from begin_transaction (optional)")),
 		)
 		updateRowTool.InputSchema.Properties["key"] = map[string]any{
 			"type":                 "object",
@@ -228,122 +1154,483 @@ func Register(s *server.MCPServer, cfg *config.Config) {
 			"additionalProperties": true,
 			"description":          "Column names and new values to update",
 		}
+		updateRowTool.InputSchema.Properties["expected"] = map[string]any{
+			"type":                 "object",
+			"additionalProperties": true,
+			"description":          "Column names and the values the row must still have, as last read (optional; null expects NULL)",
+		}
 		updateRowTool.InputSchema.Required = append(updateRowTool.InputSchema.Required, "key", "set")
 
 		s.AddTool(updateRowTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 			args, ok := request.Params.Arguments.(map[string]any)
 			if !ok {
-				return mcp.NewToolResultError("invalid arguments"), nil
+				return invalidArgs("invalid arguments"), nil
 			}
 
 			connID, ok := args["connection_id"].(string)
 			if !ok {
-				return mcp.NewToolResultError("connection_id is required"), nil
+				return invalidArgs("connection_id is required"), nil
 			}
 			table, ok := args["table"].(string)
 			if !ok {
-				return mcp.NewToolResultError("table is required"), nil
+				return invalidArgs("table is required"), nil
 			}
 			schema, _ := args["schema"].(string)
 
 			keyMap, ok := args["key"].(map[string]any)
 			if !ok || len(keyMap) == 0 {
-				return mcp.NewToolResultError("key is required and must be an object with PK column(s)"), nil
+				return invalidArgs("key is required and must be an object with PK column(s)"), nil
 			}
 			setMap, ok := args["set"].(map[string]any)
 			if !ok || len(setMap) == 0 {
-				return mcp.NewToolResultError("set is required and must be an object with column(s) to update"), nil
+				return invalidArgs("set is required and must be an object with column(s) to update"), nil
+			}
+			if v, present := args["expected"]; present && v != nil {
+				expected, ok := v.(map[string]any)
+				if !ok || len(expected) == 0 {
+					return invalidArgs("expected must be an object with the column values the row must still have"), nil
+				}
+				ctx = db.WithExpected(ctx, expected)
 			}
 
+			if res := checkWritable(cfg, connID); res != nil {
+				return res, nil
+			}
+			if res := locks.check(connID); res != nil {
+				return res, nil
+			}
+			if scratch := sessions.scratchTableSchema(ctx, connID, schema, table); scratch != "" {
+				schema = scratch
+			} else {
+				schema = schemaOrDefault(cfg, connID, schema)
+				if res := checkWriteTarget(cfg, connID, config.OpUpdate, schema, table); res != nil {
+					return res, nil
+				}
+			}
+			ctx, res := applyUpdateRowFilter(ctx, cfg, connID, schema, table, setMap)
+			if res != nil {
+				return res, nil
+			}
 			driver, err := mgr.Driver(ctx, connID)
 			if err != nil {
-				return mcp.NewToolResultError(err.Error()), nil
+				return toolErrorResult(err), nil
+			}
+			w, done, res := txs.writer(ctx, args, mgr, connID, driver, cfg.MaxRowsAffected())
+			if res != nil {
+				return res, nil
+			}
+			defer done()
+			if cfg.ConfirmWrites() {
+				if err := confirmWrite(ctx, s, connID, previewUpdate(driver, schema, table, keyMap, setMap)); err != nil {
+					return toolErrorResult(err), nil
+				}
+			}
+			n, err := w.UpdateRow(ctx, schema, table, keyMap, setMap)
+			var conflict *db.ConflictError
+			if errors.As(err, &conflict) && !cfg.Permits(connID, config.OpSelect, schema, table) {
+				conflict.Current = nil
 			}
-			n, err := driver.UpdateRow(ctx, schema, table, keyMap, setMap)
 			if err != nil {
-				return mcp.NewToolResultError(err.Error()), nil
+				return toolErrorResult(err), nil
 			}
 
 			return mcp.NewToolResultJSON(UpdateTestRowOutput{RowsAffected: n})
 		})
 
+		// Create Test Table
+		if cfg.AnySessionSchemas() {
+			sessions.onSessionRelease(func(sess *sessionState) { dropScratchSchemas(mgr, sess) })
+			createTableTool := mcp.NewTool("create_test_table",
+				mcp.WithDescription(
+					"Create a table for test fixtures in this session's own scratch schema (on SQLite, an attached database), "+
+						"which other sessions do not see and which is dropped with its tables when the session ends. "+
+						"insert_test_row and update_test_row calls without a schema write to the session's table of that name; "+
+						"run_query reads it qualified with the returned schema."),
+				mcp.WithString("connection_id", mcp.Required(), mcp.Description("Connection ID")),
+				mcp.WithString("table", mcp.Required(), mcp.Description("Table name")),
+			)
+			createTableTool.InputSchema.Properties["columns"] = map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"name":        map[string]any{"type": "string"},
+						"type":        map[string]any{"type": "string", "description": "Column type in the database's SQL, e.g. integer, varchar(100), numeric(10, 2)"},
+						"primary_key": map[string]any{"type": "boolean"},
+						"not_null":    map[string]any{"type": "boolean"},
+					},
+					"required": []string{"name", "type"},
+				},
+				"description": "Columns of the table, in order",
+			}
+			createTableTool.InputSchema.Required = append(createTableTool.InputSchema.Required, "columns")
+
+			s.AddTool(createTableTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+				args, ok := request.Params.Arguments.(map[string]any)
+				if !ok {
+					return invalidArgs("invalid arguments"), nil
+				}
+				connID, ok := args["connection_id"].(string)
+				if !ok {
+					return invalidArgs("connection_id is required"), nil
+				}
+				table, ok := args["table"].(string)
+				if !ok || table == "" {
+					return invalidArgs("table is required"), nil
+				}
+				cols, err := parseColumnDefs(args["columns"])
+				if err != nil {
+					return invalidArgs(err.Error()), nil
+				}
+				if res := checkSessionSchemas(cfg, connID); res != nil {
+					return res, nil
+				}
+				if res := checkWritable(cfg, connID); res != nil {
+					return res, nil
+				}
+				if res := locks.check(connID); res != nil {
+					return res, nil
+				}
+				if res := checkPermission(cfg, connID, config.OpInsert, "", ""); res != nil {
+					return res, nil
+				}
+				if cfg.ConfirmWrites() {
+					if err := confirmWrite(ctx, s, connID, fmt.Sprintf("create table %s in this session's scratch schema (%s)", table, describeColumnDefs(cols))); err != nil {
+						return toolErrorResult(err), nil
+					}
+				}
+				schema, err := sessions.createScratchTable(ctx, mgr, connID, table, cols)
+				if err != nil {
+					return toolErrorResult(err), nil
+				}
+				return mcp.NewToolResultJSON(CreateTestTableOutput{ConnectionID: connID, Schema: schema, Table: table})
+			})
+		}
+
+		// Begin Transaction
+		s.AddTool(mcp.NewTool("begin_transaction",
+			mcp.WithDescription(
+				"Start a transaction on a connection, so a set of related insert_test_row and update_test_row calls "+
+					"commits or rolls back as a whole: pass the returned transaction_id to them, then call "+
+					"commit_transaction or rollback_transaction. Writes are invisible to other calls until committed. "+
+					fmt.Sprintf("A transaction left without calls for %s, or whose session ends, is rolled back. ", TransactionIdleTimeout)+
+					"On an in-memory SQLite database every other call waits until the transaction ends."),
+			mcp.WithString("connection_id", mcp.Required(), mcp.Description("Connection ID")),
+		), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			args, ok := request.Params.Arguments.(map[string]any)
+			if !ok {
+				return invalidArgs("invalid arguments"), nil
+			}
+			connID, ok := args["connection_id"].(string)
+			if !ok {
+				return invalidArgs("connection_id is required"), nil
+			}
+			if res := checkWritable(cfg, connID); res != nil {
+				return res, nil
+			}
+			if res := locks.check(connID); res != nil {
+				return res, nil
+			}
+			if !permitsTransactions(cfg, connID) {
+				return checkPermission(cfg, connID, config.OpInsert, "", ""), nil
+			}
+			id, err := txs.begin(ctx, mgr, connID)
+			if err != nil {
+				return toolErrorResult(err), nil
+			}
+			return mcp.NewToolResultJSON(BeginTransactionOutput{TransactionID: id, ConnectionID: connID})
+		})
+
+		// Commit / Rollback Transaction
+		endTransaction := func(commit bool) server.ToolHandlerFunc {
+			return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+				args, ok := request.Params.Arguments.(map[string]any)
+				if !ok {
+					return invalidArgs("invalid arguments"), nil
+				}
+				id, ok := args["transaction_id"].(string)
+				if !ok {
+					return invalidArgs("transaction_id is required"), nil
+				}
+				t, res := txs.acquire(ctx, id, "")
+				if res != nil {
+					return res, nil
+				}
+				defer t.mu.Unlock()
+				if err := txs.endLocked(ctx, t, commit); err != nil {
+					return toolErrorResult(err), nil
+				}
+				return mcp.NewToolResultJSON(EndTransactionOutput{TransactionID: id, ConnectionID: t.connID, Committed: commit})
+			}
+		}
+		s.AddTool(mcp.NewTool("commit_transaction",
+			mcp.WithDescription("Commit a transaction from begin_transaction, making its writes visible."),
+			mcp.WithString("transaction_id", mcp.Required(), mcp.Description("Transaction ID from begin_transaction")),
+		), endTransaction(true))
+		s.AddTool(mcp.NewTool("rollback_transaction",
+			mcp.WithDescription("Roll back a transaction from begin_transaction, discarding its writes."),
+			mcp.WithString("transaction_id", mcp.Required(), mcp.Description("Transaction ID from begin_transaction")),
+		), endTransaction(false))
+
 		// Export Database
+		exports := &exportStore{}
+		sessions.onSessionRelease(func(sess *sessionState) { exports.release(s, sess) })
+		mgr.OnClose(exports.close)
 		s.AddTool(mcp.NewTool("export_database",
 			mcp.WithDescription(
 				"Export a database to a SQL dump file using engine-native tools. "+
 					"PostgreSQL uses pg_dump, MySQL uses mysqldump, SQLite uses sqlite3 .dump, "+
 					"SQL Server generates SQL via queries. "+
+					"With delivery=resource the dump is returned through MCP as a resource instead of "+
+					"being written to a caller-chosen path (for clients that don't share the server's filesystem). "+
 					"Requires the CLI tool to be installed on the server for PostgreSQL/MySQL/SQLite."),
 			mcp.WithString("connection_id", mcp.Required(), mcp.Description("Connection ID to export")),
-			mcp.WithString("path", mcp.Required(), mcp.Description("Absolute file path for the output SQL dump file")),
+			mcp.WithString("path", mcp.Description("Absolute file path for the output SQL dump file (required for delivery=file); must be inside an allowed export directory")),
+			mcp.WithString("delivery", mcp.Enum("file", "resource"), mcp.Description("Where the dump goes: file (default) writes to path; resource returns it as an MCP resource")),
+			mcp.WithNumber("batch_size", mcp.Description("Rows per multi-row INSERT for Go-generated dumps (SQL Server); default 100")),
 		), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 			args, ok := request.Params.Arguments.(map[string]any)
 			if !ok {
-				return mcp.NewToolResultError("invalid arguments"), nil
+				return invalidArgs("invalid arguments"), nil
 			}
 			connID, ok := args["connection_id"].(string)
 			if !ok {
-				return mcp.NewToolResultError("connection_id is required"), nil
+				return invalidArgs("connection_id is required"), nil
 			}
-			path, ok := args["path"].(string)
-			if !ok {
-				return mcp.NewToolResultError("path is required"), nil
+			delivery, _ := args["delivery"].(string)
+			if delivery == "" {
+				delivery = "file"
+			}
+			path, _ := args["path"].(string)
+			switch delivery {
+			case "file":
+				if path == "" {
+					return invalidArgs("path is required"), nil
+				}
+			case "resource":
+				if path != "" {
+					return invalidArgs("path must not be set when delivery is resource"), nil
+				}
+			default:
+				return invalidArgs(`delivery must be "file" or "resource"`), nil
+			}
+
+			if res := checkPermission(cfg, connID, config.OpExport, "", ""); res != nil {
+				return res, nil
+			}
+			if res := checkRowFilterDump(cfg, connID); res != nil {
+				return res, nil
+			}
+			if res := checkSchemaLockDump(cfg, connID); res != nil {
+				return res, nil
+			}
+			opts := db.ExportOptions{ToolVersion: ServerVersion, AllowedDirs: exportDirs(ctx, s, sessions, cfg)}
+			if n, ok := args["batch_size"].(float64); ok {
+				if n < 1 {
+					return invalidArgs("batch_size must be at least 1"), nil
+				}
+				opts.BatchSize = int(n)
 			}
 
 			exp, err := mgr.Exporter(ctx, connID)
 			if err != nil {
-				return mcp.NewToolResultError(err.Error()), nil
+				return toolErrorResult(err), nil
 			}
-			if err := exp.ExportDatabase(ctx, path); err != nil {
-				return mcp.NewToolResultError(err.Error()), nil
+			if delivery == "resource" {
+				path, err = exports.newPath(connID)
+				if err != nil {
+					return toolErrorResult(err), nil
+				}
+				// The store's private directory is always a valid target.
+				opts.AllowedDirs = []string{filepath.Dir(path)}
+			}
+			if err := exp.ExportDatabase(ctx, path, opts); err != nil {
+				return toolErrorResult(err), nil
+			}
+			if delivery == "resource" {
+				res, err := exportResourceResult(exports.publish(s, sessions.get(ctx), path), path)
+				if err != nil {
+					return toolErrorResult(err), nil
+				}
+				return res, nil
+			}
+			manifest, err := db.ReadManifest(path)
+			if err != nil {
+				return toolErrorResult(err), nil
 			}
 			return mcp.NewToolResultJSON(ExportDatabaseOutput{
-				Message: fmt.Sprintf("database exported to %s", path),
+				Message:  fmt.Sprintf("database exported to %s", path),
+				Manifest: manifest,
 			})
 		})
 
 		// Import Database
+		confirms := newConfirmations(ConfirmationTokenTTL)
 		s.AddTool(mcp.NewTool("import_database",
 			mcp.WithDescription(
 				"Import a SQL dump file into a database using engine-native tools. "+
 					"WARNING: This is a DESTRUCTIVE operation that may overwrite existing data. "+
-					"PostgreSQL uses psql, MySQL uses mysql CLI, SQLite uses sqlite3, "+
-					"SQL Server uses sqlcmd. Requires the CLI tool to be installed on the server."),
+					"PostgreSQL uses psql, MySQL uses mysql CLI, SQL Server uses sqlcmd; SQLite is imported in-process. "+
+					"PostgreSQL and SQLite imports run in a single transaction, so a failing statement leaves the database untouched. "+
+					"Dumps written by export_database carry a manifest; importing one into a different engine is refused. "+
+					"Requires the CLI tool to be installed on the server for PostgreSQL/MySQL/SQL Server. "+
+					"Runs in two steps: the first call only returns a confirmation_token describing the import; "+
+					"show it to the user, then call again with the same arguments and the token "+
+					fmt.Sprintf("(valid once, for %s) to run it.", ConfirmationTokenTTL)),
 			mcp.WithString("connection_id", mcp.Required(), mcp.Description("Connection ID to import into")),
-			mcp.WithString("path", mcp.Required(), mcp.Description("Absolute file path of the SQL dump file to import")),
-			mcp.WithBoolean("confirm_destructive", mcp.Required(), mcp.Description("Must be set to true to confirm this destructive operation")),
+			mcp.WithString("path", mcp.Required(), mcp.Description("Absolute file path of the SQL dump file to import; must be inside an allowed export directory")),
+			mcp.WithString("confirmation_token", mcp.Description("Token returned by the first call, to run the import (omit it to get one)")),
 		), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 			args, ok := request.Params.Arguments.(map[string]any)
 			if !ok {
-				return mcp.NewToolResultError("invalid arguments"), nil
+				return invalidArgs("invalid arguments"), nil
 			}
 			connID, ok := args["connection_id"].(string)
 			if !ok {
-				return mcp.NewToolResultError("connection_id is required"), nil
+				return invalidArgs("connection_id is required"), nil
 			}
 			path, ok := args["path"].(string)
 			if !ok {
-				return mcp.NewToolResultError("path is required"), nil
-			}
-			confirmed, _ := args["confirm_destructive"].(bool)
-			if !confirmed {
-				return mcp.NewToolResultError(
-					"import_database is destructive and may overwrite existing data; " +
-						"set confirm_destructive=true to proceed"), nil
+				return invalidArgs("path is required"), nil
 			}
 
+			if res := checkWritable(cfg, connID); res != nil {
+				return res, nil
+			}
+			if res := locks.check(connID); res != nil {
+				return res, nil
+			}
+			if res := checkPermission(cfg, connID, config.OpImport, "", ""); res != nil {
+				return res, nil
+			}
+			if res := checkSandboxImport(cfg, connID); res != nil {
+				return res, nil
+			}
+			if res := checkRowFilterDump(cfg, connID); res != nil {
+				return res, nil
+			}
+			if res := checkSchemaLockDump(cfg, connID); res != nil {
+				return res, nil
+			}
 			exp, err := mgr.Exporter(ctx, connID)
 			if err != nil {
-				return mcp.NewToolResultError(err.Error()), nil
+				return toolErrorResult(err), nil
+			}
+			allowed := exportDirs(ctx, s, sessions, cfg)
+			if err := db.CheckImportPath(path, allowed); err != nil {
+				return toolErrorResult(err), nil
+			}
+			// The token is bound to the dump as it is now, so it cannot
+			// confirm a file that was replaced after the user saw it.
+			info, err := os.Stat(path)
+			if err != nil {
+				return toolErrorResult(err), nil
 			}
-			if err := exp.ImportDatabase(ctx, path); err != nil {
-				return mcp.NewToolResultError(err.Error()), nil
+			operation := fmt.Sprintf("import the SQL dump %s (%d bytes, modified %s) into connection %q, overwriting existing data",
+				path, info.Size(), info.ModTime().UTC().Format(time.RFC3339), connID)
+			if res := confirms.check(ctx, args, operation); res != nil {
+				return res, nil
+			}
+			if cfg.ConfirmWrites() {
+				statement := fmt.Sprintf("import the SQL dump %s (destructive: may overwrite existing data)", path)
+				if err := confirmWrite(ctx, s, connID, statement); err != nil {
+					return toolErrorResult(err), nil
+				}
+			}
+			err = exp.ImportDatabase(ctx, path, db.ImportOptions{AllowedDirs: allowed})
+			// Even a failed import may have changed tables.
+			mgr.InvalidateSchema(connID, "", "")
+			if err != nil {
+				return toolErrorResult(err), nil
 			}
 			return mcp.NewToolResultJSON(ImportDatabaseOutput{
 				Message: fmt.Sprintf("database imported from %s", path),
 			})
 		})
 	}
+
+	if cfg != nil && cfg.ReadOnly() {
+		s.DeleteTools(writeTools...)
+		s.DeleteTools("enable_writes", "create_test_table")
+	}
+	if cfg != nil && !cfg.ExportEnabled() {
+		s.DeleteTools(exportTools...)
+	}
+	if cfg != nil {
+		advertiseConnections(s, cfg)
+		registerPrompts(s, cfg)
+	}
+	return mgr
+}
+
+// writeTools are the tools that modify a database. They are not offered when
+// the server runs read-only.
+var writeTools = []string{
+	"insert_test_row", "update_test_row", "import_database",
+	"begin_transaction", "commit_transaction", "rollback_transaction",
+}
+
+// exportTools are the tools that write or read SQL dumps. They are not
+// offered when features.export is off.
+var exportTools = []string{"export_database", "import_database"}
+
+// toolNeeds names the backend capability a tool depends on. Its
+// connection_id only offers connections whose backend has it.
+var toolNeeds = map[string]func(db.Capabilities) bool{
+	"export_database":   func(c db.Capabilities) bool { return c.Export },
+	"import_database":   func(c db.Capabilities) bool { return c.Export },
+	"begin_transaction": func(c db.Capabilities) bool { return c.Transactions },
+}
+
+// checkWritable refuses a write tool on a connection marked read-only in
+// read_only_connections. It returns nil if the connection may be written.
+func checkWritable(cfg *config.Config, connID string) *mcp.CallToolResult {
+	if !cfg.ConnectionReadOnly(connID) {
+		return nil
+	}
+	return errorResult(ToolError{
+		Code:    CodePermissionDenied,
+		Message: fmt.Sprintf("connection %q is read-only", connID),
+		Hint:    "remove it from read_only_connections in config.yaml to allow writes",
+	}, nil)
+}
+
+// checkWriteTarget runs the checks a write of op to schema.table on connID
+// must pass before anything is sent to the database: the schema exists on
+// the backend and is allowed by schema_lock, allow_system_schemas,
+// permissions and write_schemas. It returns nil if the write may go ahead.
+func checkWriteTarget(cfg *config.Config, connID, op, schema, table string) *mcp.CallToolResult {
+	if res := checkSchema(cfg, connID, schema); res != nil {
+		return res
+	}
+	if res := checkSchemaLock(cfg, connID, schema); res != nil {
+		return res
+	}
+	if res := checkSystemTable(cfg, connID, schema, table); res != nil {
+		return res
+	}
+	if res := checkPermission(cfg, connID, op, schema, table); res != nil {
+		return res
+	}
+	return checkWriteSchema(cfg, connID, schema)
+}
+
+// checkSchema rejects a schema argument for a connection whose backend has
+// no schemas, rather than silently ignoring it. It returns nil if schema may
+// be used.
+func checkSchema(cfg *config.Config, connID, schema string) *mcp.CallToolResult {
+	if schema == "" {
+		return nil
+	}
+	typ, _ := cfg.Type(connID)
+	if caps, ok := db.CapabilitiesFor(typ); ok && !caps.Schemas {
+		return errorResult(ToolError{
+			Code:    CodeValidationFailed,
+			Message: fmt.Sprintf("connection %q (%s) has no schemas", connID, typ),
+			Hint:    "omit schema",
+		}, nil)
+	}
+	return nil
 }
 
 // PingOutput is the structured result of the ping tool.
@@ -354,11 +1641,57 @@ type PingOutput struct {
 // ListConnectionsOutput is the result of list_connections.
 type ListConnectionsOutput struct {
 	Connections []config.ConnectionInfo `json:"connections"`
+	// Capabilities of each connection's backend, by connection ID.
+	Capabilities map[string]db.Capabilities `json:"capabilities,omitempty"`
+	// WriteLock is set when the server runs with write_unlock.
+	WriteLock *WriteLockStatus `json:"write_lock,omitempty"`
+}
+
+// HealthOutput is the result of health.
+type HealthOutput struct {
+	Connections []db.ConnectionHealth `json:"connections"`
+	// WriteLock is set when the server runs with write_unlock.
+	WriteLock *WriteLockStatus `json:"write_lock,omitempty"`
+}
+
+// EnableWritesOutput is the result of enable_writes.
+type EnableWritesOutput struct {
+	ConnectionID  string    `json:"connection_id"`
+	UnlockedUntil time.Time `json:"unlocked_until"`
+}
+
+// RefreshSchemaOutput is the result of refresh_schema. Invalidated is the
+// number of cached tables dropped.
+type RefreshSchemaOutput struct {
+	ConnectionID string `json:"connection_id"`
+	Invalidated  int    `json:"invalidated"`
+}
+
+// BeginTransactionOutput is the result of begin_transaction.
+type BeginTransactionOutput struct {
+	TransactionID string `json:"transaction_id"`
+	ConnectionID  string `json:"connection_id"`
+}
+
+// EndTransactionOutput is the result of commit_transaction and
+// rollback_transaction.
+type EndTransactionOutput struct {
+	TransactionID string `json:"transaction_id"`
+	ConnectionID  string `json:"connection_id"`
+	Committed     bool   `json:"committed"`
+}
+
+// CloseConnectionOutput is the result of close_connection. Closed is false
+// when the connection was not open.
+type CloseConnectionOutput struct {
+	ConnectionID string `json:"connection_id"`
+	Closed       bool   `json:"closed"`
 }
 
 // ListTablesOutput is the result of list_tables.
 type ListTablesOutput struct {
-	Tables []string `json:"tables"`
+	Tables     []string `json:"tables"`
+	NextCursor string   `json:"next_cursor,omitempty"` // set when more tables follow
 }
 
 // DescribeTableOutput is the result of describe_table.
@@ -381,9 +1714,14 @@ type UpdateTestRowOutput struct {
 	RowsAffected int64 `json:"rows_affected"`
 }
 
-// ExportDatabaseOutput is the result of export_database.
+// ExportDatabaseOutput is the result of export_database. ResourceURI and
+// SizeBytes are set when the dump was delivered as an MCP resource; Manifest
+// echoes the header embedded in the dump.
 type ExportDatabaseOutput struct {
-	Message string `json:"message"`
+	Message     string       `json:"message"`
+	ResourceURI string       `json:"resource_uri,omitempty"`
+	SizeBytes   int64        `json:"size_bytes,omitempty"`
+	Manifest    *db.Manifest `json:"manifest,omitempty"`
 }
 
 // ImportDatabaseOutput is the result of import_database.
@@ -2,9 +2,17 @@ package server
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
 	"testing"
 
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
 	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 )
@@ -67,6 +75,221 @@ func TestPingTool(t *testing.T) {
 	}
 }
 
+func TestRegister_readOnly(t *testing.T) {
+	cfg := newTestConfig(t, "connections:\n  sqlite: \":memory:\"\n")
+
+	s := server.NewMCPServer(ServerName, ServerVersion)
+	Register(s, cfg)
+	for _, name := range writeTools {
+		if s.GetTool(name) == nil {
+			t.Errorf("expected %s to be registered", name)
+		}
+	}
+
+	cfg.SetReadOnly(true)
+	s = server.NewMCPServer(ServerName, ServerVersion)
+	Register(s, cfg)
+	for _, name := range writeTools {
+		if s.GetTool(name) != nil {
+			t.Errorf("%s should not be registered in read-only mode", name)
+		}
+	}
+	for _, name := range []string{"run_query", "export_database"} {
+		if s.GetTool(name) == nil {
+			t.Errorf("expected %s in read-only mode", name)
+		}
+	}
+}
+
+func TestRegister_exportDisabled(t *testing.T) {
+	ctx := context.Background()
+	s, mgr := newTestServer(t, "connections:\n  sqlite: \":memory:\"\nfeatures: {export: false}\n")
+	for _, name := range exportTools {
+		if s.GetTool(name) != nil {
+			t.Errorf("%s should not be registered with export disabled", name)
+		}
+	}
+	if s.GetTool("run_query") == nil {
+		t.Error("expected run_query with export disabled")
+	}
+	if _, err := mgr.Exporter(ctx, "sqlite"); !errors.Is(err, db.ErrNotSupported) {
+		t.Errorf("Exporter: err = %v, want ErrNotSupported", err)
+	}
+
+	c := connectTestClient(t, s)
+	res, err := c.CallTool(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{Name: "list_connections"}})
+	if err != nil {
+		t.Fatalf("list_connections: %v", err)
+	}
+	var out ListConnectionsOutput
+	if err := json.Unmarshal([]byte(textContent(res)), &out); err != nil || out.Capabilities["sqlite"].Export {
+		t.Errorf("list_connections should not report export: %s", textContent(res))
+	}
+}
+
+func TestReadOnlyConnections(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestServer(t, `
+connections:
+  sqlite: ":memory:"
+  reporting: "postgres://localhost/reporting"
+read_only_connections: [sqlite]
+`)
+
+	enum := func(tool string) []string {
+		prop, _ := s.GetTool(tool).Tool.InputSchema.Properties["connection_id"].(map[string]any)
+		ids, _ := prop["enum"].([]string)
+		return ids
+	}
+	if got := enum("insert_test_row"); !slices.Equal(got, []string{"reporting"}) {
+		t.Errorf("insert_test_row connections = %v, want [reporting]", got)
+	}
+	if got := enum("run_query"); !slices.Equal(got, []string{"reporting", "sqlite"}) {
+		t.Errorf("run_query connections = %v, want both", got)
+	}
+
+	c := connectTestClient(t, s)
+	res, err := c.CallTool(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{
+		Name:      "insert_test_row",
+		Arguments: map[string]any{"connection_id": "sqlite", "table": "t", "row": map[string]any{"v": 1}},
+	}})
+	if err != nil {
+		t.Fatalf("insert_test_row: %v", err)
+	}
+	if te, ok := res.StructuredContent.(map[string]any); !res.IsError || !ok || te["code"] != CodePermissionDenied {
+		t.Errorf("insert_test_row on a read-only connection: %+v", res)
+	}
+}
+
+func TestHealthTool(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t, "connections:\n  sqlite: \":memory:\"\n")
+
+	for _, connect := range []bool{false, true} {
+		res, err := c.CallTool(ctx, mcp.CallToolRequest{
+			Params: mcp.CallToolParams{Name: "health", Arguments: map[string]any{"connect": connect}},
+		})
+		if err != nil || res.IsError {
+			t.Fatalf("health(connect=%v): %v %+v", connect, err, res)
+		}
+		var out HealthOutput
+		if err := json.Unmarshal([]byte(textContent(res)), &out); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		var h *db.ConnectionHealth
+		for i := range out.Connections {
+			if out.Connections[i].ID == "sqlite" {
+				h = &out.Connections[i]
+			}
+		}
+		if h == nil {
+			t.Fatalf("sqlite missing from %+v", out)
+		}
+		if h.OK != connect {
+			t.Errorf("health(connect=%v): ok=%v", connect, h.OK)
+		}
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t, "connections:\n  sqlite: \":memory:\"\n")
+
+	res, err := c.CallTool(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{Name: "list_connections"}})
+	if err != nil || res.IsError {
+		t.Fatalf("list_connections: %v %+v", err, res)
+	}
+	var out ListConnectionsOutput
+	if err := json.Unmarshal([]byte(textContent(res)), &out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if caps, ok := out.Capabilities["sqlite"]; !ok || caps.Schemas || !caps.Export {
+		t.Errorf("sqlite capabilities: %+v", out.Capabilities)
+	}
+
+	// SQLite has no schemas; a schema argument is refused, not ignored.
+	res, err = c.CallTool(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{
+		Name:      "list_tables",
+		Arguments: map[string]any{"connection_id": "sqlite", "schema": "analytics"},
+	}})
+	if err != nil {
+		t.Fatalf("list_tables: %v", err)
+	}
+	if te, ok := res.StructuredContent.(map[string]any); !res.IsError || !ok || te["code"] != CodeValidationFailed {
+		t.Errorf("list_tables with a schema on sqlite: %+v", res)
+	}
+}
+
+func TestCloseConnectionTool(t *testing.T) {
+	ctx := context.Background()
+	s, mgr := newTestServer(t, "connections:\n  sqlite: \":memory:\"\n")
+	c := connectTestClient(t, s)
+	if _, err := mgr.Driver(ctx, "sqlite"); err != nil {
+		t.Fatalf("Driver: %v", err)
+	}
+
+	closeConn := func(id string) *mcp.CallToolResult {
+		t.Helper()
+		res, err := c.CallTool(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{
+			Name:      "close_connection",
+			Arguments: map[string]any{"connection_id": id},
+		}})
+		if err != nil {
+			t.Fatalf("close_connection: %v", err)
+		}
+		return res
+	}
+	for _, want := range []bool{true, false} {
+		res := closeConn("sqlite")
+		var out CloseConnectionOutput
+		if err := json.Unmarshal([]byte(textContent(res)), &out); err != nil || res.IsError {
+			t.Fatalf("close_connection: %v %+v", err, res)
+		}
+		if out.Closed != want {
+			t.Errorf("closed = %v, want %v", out.Closed, want)
+		}
+	}
+	res := closeConn("nonexistent")
+	if te, ok := res.StructuredContent.(map[string]any); !res.IsError || !ok || te["code"] != CodeUnknownConnection {
+		t.Errorf("close_connection on an unknown connection: %+v", res)
+	}
+}
+
+func TestUpdateTestRow_expected(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t, "connections:\n  demo: demo\n")
+	update := func(expected map[string]any, city string) *mcp.CallToolResult {
+		t.Helper()
+		res, err := c.CallTool(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{
+			Name: "update_test_row",
+			Arguments: map[string]any{
+				"connection_id": "demo", "table": "customers",
+				"key": map[string]any{"id": 1}, "set": map[string]any{"city": city}, "expected": expected,
+			},
+		}})
+		if err != nil {
+			t.Fatalf("update_test_row: %v", err)
+		}
+		return res
+	}
+
+	if res := update(map[string]any{"city": "London"}, "Paris"); res.IsError {
+		t.Fatalf("update with current values expected: %s", textContent(res))
+	}
+	res := update(map[string]any{"city": "London"}, "Rome")
+	if resultCode(res) != CodeConflict {
+		t.Fatalf("update with stale values expected: %s", textContent(res))
+	}
+	out, ok := res.StructuredContent.(map[string]any)
+	current, _ := out["current"].(map[string]any)
+	if !ok || current["city"] != "Paris" || current["name"] != "Ada Example" {
+		t.Errorf("structured content = %v", res.StructuredContent)
+	}
+	if res := update(map[string]any{}, "Rome"); resultCode(res) != CodeValidationFailed {
+		t.Errorf("empty expected: %s", textContent(res))
+	}
+}
+
 func textContent(res *mcp.CallToolResult) string {
 	if res == nil || len(res.Content) == 0 {
 		return ""
@@ -76,3 +299,75 @@ func textContent(res *mcp.CallToolResult) string {
 	}
 	return ""
 }
+
+// newTestConfig loads the config file yaml. The MCP_DB_* variables are
+// cleared first, so the connections are yaml's alone.
+func newTestConfig(t *testing.T, yaml string) *config.Config {
+	t.Helper()
+	for _, env := range []string{config.EnvPostgresURI, config.EnvSQLServerURI, config.EnvSQLiteURI, config.EnvMySQLURI, config.EnvGlobalReadOnly} {
+		t.Setenv(env, "")
+	}
+	path := filepath.Join(t.TempDir(), config.ConfigFileName)
+	if err := os.WriteFile(path, []byte(yaml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.LoadFrom(path)
+	if err != nil {
+		t.Fatalf("LoadFrom: %v", err)
+	}
+	return cfg
+}
+
+// newTestServer registers the tools for the config file yaml, loaded by
+// newTestConfig, on a new server. The Manager is closed when the test ends.
+func newTestServer(t *testing.T, yaml string) (*server.MCPServer, *db.Manager) {
+	t.Helper()
+	s := server.NewMCPServer(ServerName, ServerVersion)
+	mgr := Register(s, newTestConfig(t, yaml))
+	t.Cleanup(func() { mgr.Close() })
+	return s, mgr
+}
+
+// newTestClient returns an initialized in-process client of a server set
+// up by newTestServer for the config file yaml.
+func newTestClient(t *testing.T, yaml string) *client.Client {
+	t.Helper()
+	s, _ := newTestServer(t, yaml)
+	return connectTestClient(t, s)
+}
+
+// demoCaller returns a function calling a tool on a server whose only
+// connection is the demo database, with id "demo".
+func demoCaller(t *testing.T) func(name string, args map[string]any) *mcp.CallToolResult {
+	t.Helper()
+	ctx := context.Background()
+	c := newTestClient(t, "connections:\n  demo: demo\n")
+	return func(name string, args map[string]any) *mcp.CallToolResult {
+		t.Helper()
+		args["connection_id"] = "demo"
+		res, err := c.CallTool(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{Name: name, Arguments: args}})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		return res
+	}
+}
+
+// connectTestClient returns an initialized in-process client of s, its
+// transport configured by opts (e.g. an elicitation handler). It is closed
+// when the test ends.
+func connectTestClient(t *testing.T, s *server.MCPServer, opts ...transport.InProcessOption) *client.Client {
+	t.Helper()
+	ctx := context.Background()
+	c := client.NewClient(transport.NewInProcessTransportWithOptions(s, opts...))
+	if err := c.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	initReq := mcp.InitializeRequest{}
+	initReq.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	if _, err := c.Initialize(ctx, initReq); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	return c
+}
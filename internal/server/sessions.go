@@ -0,0 +1,97 @@
+package server
+
+import (
+	"context"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// sessionState is the server-side state belonging to one MCP session.
+// Anything a tool call leaves behind for later calls of the same client is
+// kept here, so concurrent clients of one server never see each other's
+// results.
+type sessionState struct {
+	id string
+
+	mu         sync.Mutex
+	exports    []exportEntry // resource-delivered dumps, oldest first
+	roots      []string      // the client's filesystem roots, once fetched
+	rootsKnown bool          // roots is current; cleared on roots/list_changed
+	egress     egressUsage   // data run_query returned, for egress_budget
+	// scratch holds, by connection ID, the tables create_test_table made in
+	// the session's scratch schema; a connection is present once the
+	// schema exists.
+	scratch map[string]map[string]bool
+}
+
+// sessionRegistry maps MCP session IDs to their state. State is created on
+// first use and dropped when the transport unregisters the session.
+type sessionRegistry struct {
+	mu        sync.Mutex
+	states    map[string]*sessionState
+	onRelease []func(*sessionState)
+}
+
+func newSessionRegistry() *sessionRegistry {
+	return &sessionRegistry{states: make(map[string]*sessionState)}
+}
+
+// sessionID returns the ID of the MCP session ctx belongs to, or "" when
+// the call did not arrive through a session.
+func sessionID(ctx context.Context) string {
+	if cs := server.ClientSessionFromContext(ctx); cs != nil {
+		return cs.SessionID()
+	}
+	return ""
+}
+
+// get returns the state of the session ctx belongs to. Calls without a
+// session share the state stored under "".
+func (r *sessionRegistry) get(ctx context.Context) *sessionState {
+	id := sessionID(ctx)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	st, ok := r.states[id]
+	if !ok {
+		st = &sessionState{id: id}
+		r.states[id] = st
+	}
+	return st
+}
+
+// release drops the state of session id and runs the cleanup callbacks.
+func (r *sessionRegistry) release(id string) {
+	r.mu.Lock()
+	st, ok := r.states[id]
+	delete(r.states, id)
+	callbacks := r.onRelease
+	r.mu.Unlock()
+	if !ok {
+		return
+	}
+	for _, fn := range callbacks {
+		fn(st)
+	}
+}
+
+// onSessionRelease registers fn to run when a session's state is released.
+func (r *sessionRegistry) onSessionRelease(fn func(*sessionState)) {
+	r.mu.Lock()
+	r.onRelease = append(r.onRelease, fn)
+	r.mu.Unlock()
+}
+
+// install hooks the registry into s so a session's state is released when
+// the session ends. The hook is added to hooks, the hooks s was created
+// with, which it installs again; if hooks is nil, it replaces any hooks s
+// has with new ones.
+func (r *sessionRegistry) install(s *server.MCPServer, hooks *server.Hooks) {
+	if hooks == nil {
+		hooks = &server.Hooks{}
+	}
+	hooks.AddOnUnregisterSession(func(ctx context.Context, session server.ClientSession) {
+		r.release(session.SessionID())
+	})
+	server.WithHooks(hooks)(s)
+}
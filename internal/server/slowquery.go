@@ -0,0 +1,44 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
+)
+
+// maxLoggedSQL caps the statement text in a slow-query log line.
+const maxLoggedSQL = 500
+
+// slowQueryLogger is a db.Interceptor that warns about database calls
+// taking threshold or longer, so a slow tool call can be traced to the
+// database rather than the agent.
+type slowQueryLogger struct {
+	threshold time.Duration
+}
+
+func (slowQueryLogger) Before(context.Context, *db.Call) {}
+
+func (l slowQueryLogger) After(ctx context.Context, call *db.Call) {
+	if call.Duration < l.threshold {
+		return
+	}
+	attrs := []any{
+		"request_id", RequestID(ctx),
+		"connection_id", call.ConnectionID,
+		"op", call.Op,
+		"duration", call.Duration,
+	}
+	if call.SQL != "" {
+		sql := call.SQL
+		if len(sql) > maxLoggedSQL {
+			sql = sql[:maxLoggedSQL] + "..."
+		}
+		attrs = append(attrs, "sql", sql, "params", call.Params)
+	}
+	if call.Err != nil {
+		attrs = append(attrs, "err", call.Err)
+	}
+	slog.Warn("slow database call", attrs...)
+}
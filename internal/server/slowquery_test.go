@@ -0,0 +1,40 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
+)
+
+func TestSlowQueryLogger(t *testing.T) {
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	defer slog.SetDefault(prev)
+
+	l := slowQueryLogger{threshold: time.Second}
+	l.After(context.Background(), &db.Call{ConnectionID: "sqlite", Op: db.OpQuery, SQL: "SELECT 1", Duration: time.Millisecond})
+	if buf.Len() != 0 {
+		t.Errorf("fast call logged: %s", buf.String())
+	}
+
+	l.After(context.Background(), &db.Call{
+		ConnectionID: "sqlite", Op: db.OpQuery, SQL: strings.Repeat("x", 2*maxLoggedSQL), Params: 2,
+		Duration: 2 * time.Second, Err: errors.New("boom"),
+	})
+	out := buf.String()
+	for _, want := range []string{"slow database call", "connection_id=sqlite", "op=query", "params=2", "err=boom"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log %q lacks %q", out, want)
+		}
+	}
+	if strings.Contains(out, strings.Repeat("x", maxLoggedSQL+1)) {
+		t.Errorf("SQL not truncated: %s", out)
+	}
+}
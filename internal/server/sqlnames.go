@@ -0,0 +1,135 @@
+package server
+
+import (
+	"slices"
+	"strings"
+)
+
+// sqlName is a possibly qualified name in a statement lexed by lexSQL,
+// such as sales.orders or [dbo].[Orders].
+type sqlName struct {
+	parts      []string // lower case, without quotes
+	first, end int      // the name's tokens are toks[first:end]
+}
+
+// readSQLName reads the name starting at toks[i]. It reports false if
+// toks[i] is neither a word nor a quoted identifier.
+func readSQLName(toks []sqlToken, i int) (sqlName, bool) {
+	isName := func(j int) bool { return j < len(toks) && (toks[j].word || toks[j].ident) }
+	if !isName(i) {
+		return sqlName{}, false
+	}
+	n := sqlName{first: i}
+	for {
+		n.parts = append(n.parts, strings.ToLower(toks[i].text))
+		i++
+		if i+1 >= len(toks) || toks[i].text != "." || !isName(i+1) {
+			break
+		}
+		i++
+	}
+	n.end = i
+	return n, true
+}
+
+// sqlNames returns every name of toks, qualified ones whole: in a.b.c, the
+// name starts at a only.
+func sqlNames(toks []sqlToken) []sqlName {
+	var names []sqlName
+	for i := 0; i < len(toks); i++ {
+		if n, ok := readSQLName(toks, i); ok {
+			names = append(names, n)
+			i = n.end - 1
+		}
+	}
+	return names
+}
+
+// schemaTable returns the table part of n and the schema qualifying it, if
+// any.
+func (n sqlName) schemaTable() (schema, table string) {
+	if len(n.parts) > 1 {
+		schema = n.parts[len(n.parts)-2]
+	}
+	return schema, n.parts[len(n.parts)-1]
+}
+
+// sqlSource returns the text of toks[first:end] in sql, the statement they
+// were lexed from.
+func sqlSource(sql string, toks []sqlToken, first, end int) string {
+	return sql[toks[first].start:toks[end-1].end]
+}
+
+// closingParenToken returns the index of the parenthesis closing the one
+// at toks[i], or len(toks) if it is not closed.
+func closingParenToken(toks []sqlToken, i int) int {
+	for j := i + 1; j < len(toks); j++ {
+		if toks[j].text == ")" && toks[j].depth == toks[i].depth {
+			return j
+		}
+	}
+	return len(toks)
+}
+
+// sqlRef is a table reference of a statement (see sqlRefs).
+type sqlRef struct {
+	sqlName
+	keyword string // FROM, JOIN, INTO or UPDATE
+	// call is set when the name is called like a function, as in
+	// FROM generate_series(1, 3): it may not be a table at all.
+	call bool
+	// alias is the index of the reference's alias token, or -1.
+	alias int
+}
+
+// sqlRefKeywords are the keywords table references follow.
+var sqlRefKeywords = map[string]bool{"FROM": true, "JOIN": true, "INTO": true, "UPDATE": true}
+
+// sqlRefs returns the table references of toks in statement order: the
+// name after JOIN, INTO and UPDATE, and each name of the comma-separated
+// list after FROM, whose parenthesized subqueries are skipped here and
+// read at their own FROM. ONLY and LATERAL before a name are skipped.
+func sqlRefs(toks []sqlToken) []sqlRef {
+	var refs []sqlRef
+	for k, t := range toks {
+		if !t.word || !sqlRefKeywords[t.text] {
+			continue
+		}
+		i := k + 1
+		for i < len(toks) {
+			ref := sqlRef{keyword: t.text, alias: -1}
+			if toks[i].word && (toks[i].text == "ONLY" || toks[i].text == "LATERAL") {
+				i++
+				continue
+			}
+			if toks[i].text == "(" {
+				i = closingParenToken(toks, i) + 1
+			} else if n, ok := readSQLName(toks, i); ok {
+				ref.sqlName = n
+				i = n.end
+				if i < len(toks) && toks[i].text == "(" && (t.text == "FROM" || t.text == "JOIN") {
+					ref.call = true
+					i = closingParenToken(toks, i) + 1
+				}
+			} else {
+				break
+			}
+			if i < len(toks) && toks[i].word && toks[i].text == "AS" {
+				i++
+			}
+			if i < len(toks) && (toks[i].ident || toks[i].word && !aliasStoppers[strings.ToLower(toks[i].text)]) {
+				ref.alias = i
+				i++
+			}
+			if ref.parts != nil {
+				refs = append(refs, ref)
+			}
+			if t.text != "FROM" || i >= len(toks) || toks[i].text != "," {
+				break
+			}
+			i++
+		}
+	}
+	slices.SortFunc(refs, func(a, b sqlRef) int { return a.first - b.first })
+	return refs
+}
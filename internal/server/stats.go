@@ -0,0 +1,132 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// ConnectionStats is one connection's entry in connection_stats. Like
+// config.ConnectionInfo it never contains the connection URI, and failures
+// are reported by code only, since driver messages may echo the DSN.
+type ConnectionStats struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	// Pool is set while the connection is open.
+	Pool *db.PoolStats `json:"pool,omitempty"`
+	// Queries counts the database tool calls made on the connection since
+	// the server started, and Errors those that failed.
+	Queries       int64      `json:"queries"`
+	Errors        int64      `json:"errors"`
+	LastError     *time.Time `json:"last_error,omitempty"`
+	LastErrorCode string     `json:"last_error_code,omitempty"`
+	// DB is the latency histogram and error counts of the database calls
+	// themselves, as timed by the db layer, so slowness can be told apart
+	// from the agent's own. Set once a call reached the database.
+	DB *db.CallMetrics `json:"db,omitempty"`
+}
+
+// ConnectionStatsOutput is the result of connection_stats.
+type ConnectionStatsOutput struct {
+	Connections []ConnectionStats `json:"connections"`
+}
+
+// callStats counts database tool calls per connection.
+type callStats struct {
+	mu     sync.Mutex
+	byConn map[string]*callCounts
+}
+
+type callCounts struct {
+	queries, errors int64
+	lastError       time.Time
+	lastErrorCode   string
+}
+
+func newCallStats() *callStats {
+	return &callStats{byConn: make(map[string]*callCounts)}
+}
+
+// middleware counts the calls of the database tools (those with a rate limit
+// class) that get past the rate limiter, and their failures.
+func (st *callStats) middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if _, ok := toolClasses[request.Params.Name]; !ok {
+			return next(ctx, request)
+		}
+		res, err := next(ctx, request)
+		code := ""
+		switch {
+		case err != nil:
+			code = CodeInternal
+		case res != nil && res.IsError:
+			code = resultCode(res)
+		}
+		st.record(request.GetString("connection_id", ""), code)
+		return res, err
+	}
+}
+
+// record counts a call on connID that failed with code, or succeeded if
+// code is empty.
+func (st *callStats) record(connID, code string) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	c, ok := st.byConn[connID]
+	if !ok {
+		c = &callCounts{}
+		st.byConn[connID] = c
+	}
+	c.queries++
+	if code != "" {
+		c.errors++
+		c.lastError = time.Now()
+		c.lastErrorCode = code
+	}
+}
+
+// resultCode returns the code of a failed result's structured content, or
+// CodeDatabaseError if it has none.
+func resultCode(res *mcp.CallToolResult) string {
+	if b, err := json.Marshal(res.StructuredContent); err == nil {
+		var e ToolError
+		if json.Unmarshal(b, &e) == nil && e.Code != "" {
+			return e.Code
+		}
+	}
+	return CodeDatabaseError
+}
+
+// connectionStats reports every configured connection, sorted by ID.
+func (st *callStats) connectionStats(cfg *config.Config, mgr *db.Manager) []ConnectionStats {
+	infos := cfg.ConnectionInfos()
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ID < infos[j].ID })
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	out := make([]ConnectionStats, 0, len(infos))
+	for _, info := range infos {
+		cs := ConnectionStats{ID: info.ID, Type: info.Type}
+		if pool, ok := mgr.PoolStats(info.ID); ok {
+			cs.Pool = &pool
+		}
+		if m, ok := mgr.CallMetrics(info.ID); ok {
+			cs.DB = &m
+		}
+		if c, ok := st.byConn[info.ID]; ok {
+			cs.Queries, cs.Errors = c.queries, c.errors
+			if !c.lastError.IsZero() {
+				at := c.lastError.UTC()
+				cs.LastError, cs.LastErrorCode = &at, c.lastErrorCode
+			}
+		}
+		out = append(out, cs)
+	}
+	return out
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func TestConnectionStatsTool(t *testing.T) {
+	ctx := context.Background()
+	cfg := newTestConfig(t, "connections:\n  sqlite: \":memory:\"\n")
+	s := server.NewMCPServer(ServerName, ServerVersion)
+	mgr := Register(s, cfg)
+	defer mgr.Close()
+
+	c := connectTestClient(t, s)
+	call := func(name string, args map[string]any) *mcp.CallToolResult {
+		t.Helper()
+		res, err := c.CallTool(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{Name: name, Arguments: args}})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		return res
+	}
+	stats := func() ConnectionStats {
+		t.Helper()
+		var out ConnectionStatsOutput
+		if err := json.Unmarshal([]byte(textContent(call("connection_stats", nil))), &out); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		for _, cs := range out.Connections {
+			if cs.ID == "sqlite" {
+				return cs
+			}
+		}
+		t.Fatalf("sqlite missing from %+v", out)
+		return ConnectionStats{}
+	}
+
+	if cs := stats(); cs.Pool != nil || cs.Queries != 0 {
+		t.Errorf("before any call: %+v", cs)
+	}
+	if res := call("list_tables", map[string]any{"connection_id": "sqlite"}); res.IsError {
+		t.Fatalf("list_tables: %s", textContent(res))
+	}
+	if res := call("run_query", map[string]any{"connection_id": "sqlite", "sql": "SELECT * FROM missing"}); !res.IsError {
+		t.Fatal("run_query on a missing table should fail")
+	}
+	cs := stats()
+	if cs.Pool == nil || cs.Pool.Open < 1 {
+		t.Errorf("pool stats of an open connection: %+v", cs.Pool)
+	}
+	if cs.Queries != 2 || cs.Errors != 1 || cs.LastError == nil || cs.LastErrorCode != CodeDatabaseError {
+		t.Errorf("after one good and one failed call: %+v", cs)
+	}
+	if cs.DB == nil || cs.DB.Calls != 2 || cs.DB.Errors[db.ErrorClassDatabase] != 1 {
+		t.Errorf("database call metrics: %+v", cs.DB)
+	}
+
+	rec := httptest.NewRecorder()
+	MetricsHandler(cfg, mgr).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	for _, want := range []string{
+		`localdb_db_call_duration_seconds_bucket{connection="sqlite",le="+Inf"} 2`,
+		`localdb_db_call_duration_seconds_count{connection="sqlite"} 2`,
+		`localdb_db_call_errors_total{connection="sqlite",class="database"} 1`,
+		`localdb_pool_connections{connection="sqlite",state="idle"}`,
+	} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("metrics lack %s:\n%s", want, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,392 @@
+package server
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// suggestIndexesNote labels the result of suggest_indexes.
+const suggestIndexesNote = "These are suggestions only; no index was created. " +
+	"Check them against the workload, and the plan after creating one, before keeping it."
+
+// SuggestIndexesOutput is the result of suggest_indexes.
+type SuggestIndexesOutput struct {
+	Suggestions []IndexSuggestion `json:"suggestions"`
+	// Covered lists the candidates an existing index already serves.
+	Covered []CoveredIndex `json:"covered,omitempty"`
+	// PlanAvailable is set if the connection could explain the query;
+	// FullScans then lists the tables the plan reads in full.
+	PlanAvailable bool     `json:"plan_available"`
+	FullScans     []string `json:"full_scans,omitempty"`
+	Note          string   `json:"note"`
+}
+
+// IndexSuggestion is a candidate index for the query. DDL creates it; it
+// is never run.
+type IndexSuggestion struct {
+	Table   string   `json:"table"`
+	Columns []string `json:"columns"`
+	Reason  string   `json:"reason"`
+	// FullScan is set if the plan reads the table in full.
+	FullScan bool   `json:"full_scan"`
+	DDL      string `json:"ddl"`
+}
+
+// CoveredIndex is a candidate index whose columns lead an existing index.
+type CoveredIndex struct {
+	Table   string   `json:"table"`
+	Columns []string `json:"columns"`
+	Index   string   `json:"index"`
+}
+
+// columnUse is how a statement uses a column: in an equality or join
+// condition, a range condition or an ORDER BY.
+type columnUse int
+
+const (
+	useEquality columnUse = iota
+	useRange
+	useOrder
+)
+
+// columnRef is a column a statement uses, with the table name or alias
+// qualifying it, if any. Names are upper-cased, as lexSQL returns them.
+type columnRef struct {
+	qualifier string
+	column    string
+	use       columnUse
+}
+
+// tableRef is a table a statement reads, with the schema qualifying it, if
+// any.
+type tableRef struct {
+	schema string
+	table  string
+}
+
+// sqlReservedAliases are the words that may follow a table reference
+// without being its alias.
+var sqlReservedAliases = map[string]bool{
+	"WHERE": true, "JOIN": true, "INNER": true, "LEFT": true, "RIGHT": true, "FULL": true, "CROSS": true,
+	"OUTER": true, "NATURAL": true, "STRAIGHT_JOIN": true, "ON": true, "USING": true, "GROUP": true,
+	"ORDER": true, "HAVING": true, "LIMIT": true, "OFFSET": true, "FETCH": true, "UNION": true,
+	"EXCEPT": true, "INTERSECT": true, "WINDOW": true, "FOR": true, "WITH": true,
+}
+
+// sqlConnectives are the words a parenthesized condition may follow.
+var sqlConnectives = map[string]bool{"WHERE": true, "ON": true, "AND": true, "OR": true, "NOT": true}
+
+// queryColumns returns the tables toks, a lexed SELECT, reads by the names
+// and aliases it reads them under, and the columns it compares in WHERE
+// and ON conditions or sorts by. Columns inside function calls are left
+// out: an index on the bare column does not serve them.
+func queryColumns(toks []sqlToken) (map[string]tableRef, []columnRef) {
+	tables := make(map[string]tableRef)
+	var refs []columnRef
+	clause := map[int]string{} // the clause at each parenthesis depth
+	name := func(t sqlToken) bool { return t.word || t.ident }
+	at := func(i int) sqlToken {
+		if i >= 0 && i < len(toks) {
+			return toks[i]
+		}
+		return sqlToken{}
+	}
+	for i := 0; i < len(toks); i++ {
+		t := toks[i]
+		switch {
+		case t.text == "(":
+			if prev := at(i - 1); prev.word && !sqlConnectives[prev.text] {
+				clause[t.depth+1] = "" // function arguments or a value list
+			} else {
+				clause[t.depth+1] = clause[t.depth]
+			}
+		case t.word && (t.text == "SELECT" || t.text == "WHERE" || t.text == "ON" || t.text == "GROUP" ||
+			t.text == "HAVING" || t.text == "LIMIT" || t.text == "UNION" || t.text == "ORDER" && at(i+1).text == "BY"):
+			clause[t.depth] = t.text
+		case t.word && (t.text == "FROM" || t.text == "JOIN"):
+			clause[t.depth] = "FROM"
+			// Read each table reference of the FROM list or JOIN.
+			for j := i + 1; name(at(j)); {
+				parts := []string{at(j).text}
+				j++
+				for at(j).text == "." && name(at(j+1)) {
+					parts = append(parts, at(j+1).text)
+					j += 2
+				}
+				ref := tableRef{table: parts[len(parts)-1]}
+				if len(parts) > 1 {
+					ref.schema = parts[len(parts)-2]
+				}
+				tables[ref.table] = ref
+				if at(j).text == "AS" {
+					j++
+				}
+				if a := at(j); a.ident || a.word && !sqlReservedAliases[a.text] {
+					tables[a.text] = ref
+					j++
+				}
+				i = j - 1
+				if t.text == "JOIN" || at(j).text != "," || at(j).depth != t.depth {
+					break
+				}
+				j++
+			}
+		case name(t) && at(i+1).text != "(":
+			c := clause[t.depth]
+			if c != "WHERE" && c != "ON" && c != "ORDER" {
+				continue
+			}
+			ref := columnRef{column: t.text}
+			start := i
+			for at(i+1).text == "." && name(at(i+2)) {
+				ref.qualifier, ref.column = ref.column, at(i+2).text
+				i += 2
+			}
+			if c == "ORDER" {
+				ref.use = useOrder
+			} else if use, ok := comparison(at(start-2).text, at(start-1).text, at(i+1).text, at(i+2).text); ok {
+				ref.use = use
+			} else {
+				continue
+			}
+			refs = append(refs, ref)
+		}
+	}
+	return tables, refs
+}
+
+// comparison returns how a column between the tokens before2, before and
+// after, after2 is compared, or false if an index on it cannot serve the
+// comparison.
+func comparison(before2, before, after, after2 string) (columnUse, bool) {
+	switch after {
+	case "=", "IN", "IS":
+		return useEquality, true
+	case "<", ">":
+		return useRange, after+after2 != "<>"
+	case "BETWEEN", "LIKE":
+		return useRange, true
+	}
+	switch before {
+	case "=":
+		switch before2 {
+		case "<", ">":
+			return useRange, true
+		case "!":
+			return 0, false
+		}
+		return useEquality, true
+	case "<", ">":
+		return useRange, before2+before != "<>"
+	}
+	return 0, false
+}
+
+// suggestIndexes proposes indexes for sql, a SELECT on connID, from the
+// columns its conditions compare and sorts by and from the tables its
+// plan reads in full. Tables qualified by a schema other than schema are
+// left out. Candidates an existing index already leads with are reported
+// as covered. Nothing is created.
+func suggestIndexes(ctx context.Context, cfg *config.Config, mgr *db.Manager, connID, schema, sql string, params []any) *mcp.CallToolResult {
+	driver, err := mgr.Driver(ctx, connID)
+	if err != nil {
+		return toolErrorResult(err)
+	}
+	engine, _ := cfg.Type(connID)
+	out := SuggestIndexesOutput{Suggestions: []IndexSuggestion{}, Note: suggestIndexesNote}
+
+	plan, ok, res := explainPlan(ctx, cfg, driver, connID, sql, params)
+	if res != nil {
+		return res
+	}
+	out.PlanAvailable = ok
+	scanned := db.FullScans(plan)
+
+	schema = schemaOrDefault(cfg, connID, schema)
+	existing, err := driver.ListTables(ctx, schema)
+	if err != nil {
+		return toolErrorResult(err)
+	}
+	refs, uses := queryColumns(lexSQL(sql, dialectsFor(engine)[0]))
+	// The tables read, and the tables by the names and aliases they are
+	// read under.
+	var names []string
+	described := make(map[string][]db.ColumnInfo)
+	byRef := make(map[string]string)
+	for ref, tref := range refs {
+		if tref.schema != "" && !strings.EqualFold(tref.schema, schema) {
+			continue
+		}
+		i := slices.IndexFunc(existing, func(t string) bool { return strings.EqualFold(t, tref.table) })
+		if i < 0 {
+			continue // a common table expression, or a table of another schema
+		}
+		table := existing[i]
+		byRef[ref] = table
+		if _, ok := described[table]; ok {
+			continue
+		}
+		tables, _, res := describeSchema(ctx, cfg, mgr, connID, schema, table)
+		if res != nil {
+			return res
+		}
+		described[table] = tables[0].cols
+		names = append(names, table)
+	}
+	slices.Sort(names)
+	for _, name := range scanned {
+		if table, ok := byRef[strings.ToUpper(name)]; ok && !slices.Contains(out.FullScans, table) {
+			out.FullScans = append(out.FullScans, table)
+		}
+	}
+
+	// Gather the columns each table is compared and sorted by.
+	type candidate struct{ equality, ranges, order []string }
+	candidates := make(map[string]*candidate)
+	for _, use := range uses {
+		table, column := resolveColumn(use, byRef, described)
+		if table == "" {
+			continue
+		}
+		c := candidates[table]
+		if c == nil {
+			c = &candidate{}
+			candidates[table] = c
+		}
+		switch use.use {
+		case useEquality:
+			c.equality = appendNew(c.equality, column)
+		case useRange:
+			c.ranges = appendNew(c.ranges, column)
+		default:
+			c.order = appendNew(c.order, column)
+		}
+	}
+
+	var indexes []db.Index
+	if lister, ok := db.Unwrap(driver).(db.IndexLister); ok {
+		if indexes, err = lister.Indexes(ctx, schema); err != nil {
+			return toolErrorResult(err)
+		}
+	}
+	for _, table := range names {
+		c := candidates[table]
+		if c == nil {
+			continue
+		}
+		// Equality columns lead, in any order; then the first range
+		// column, or else the sort columns.
+		columns := slices.Clone(c.equality)
+		var reasons []string
+		if len(c.equality) > 0 {
+			reasons = append(reasons, "equality or join on "+strings.Join(c.equality, ", "))
+		}
+		if r := slices.DeleteFunc(slices.Clone(c.ranges), func(col string) bool { return slices.Contains(columns, col) }); len(r) > 0 {
+			columns = append(columns, r[0])
+			reasons = append(reasons, "range on "+r[0])
+		} else if o := slices.DeleteFunc(slices.Clone(c.order), func(col string) bool { return slices.Contains(columns, col) }); len(o) > 0 {
+			columns = append(columns, o...)
+			reasons = append(reasons, "ORDER BY "+strings.Join(o, ", "))
+		}
+		if len(columns) == 0 {
+			continue
+		}
+		if index, ok := coveringIndex(indexes, table, columns, len(c.equality)); ok {
+			out.Covered = append(out.Covered, CoveredIndex{Table: table, Columns: columns, Index: index})
+			continue
+		}
+		suggestion := IndexSuggestion{
+			Table:    table,
+			Columns:  columns,
+			Reason:   strings.Join(reasons, "; "),
+			FullScan: slices.Contains(out.FullScans, table),
+			DDL:      db.CreateIndexDDL(engine, schema, table, columns),
+		}
+		if suggestion.FullScan {
+			suggestion.Reason += "; the plan reads the table in full"
+		}
+		out.Suggestions = append(out.Suggestions, suggestion)
+	}
+	// Tables read in full first.
+	slices.SortStableFunc(out.Suggestions, func(a, b IndexSuggestion) int {
+		switch {
+		case a.FullScan == b.FullScan:
+			return 0
+		case a.FullScan:
+			return -1
+		}
+		return 1
+	})
+	res, err = mcp.NewToolResultJSON(out)
+	if err != nil {
+		return toolErrorResult(err)
+	}
+	return res
+}
+
+// resolveColumn returns the table and the column use refers to, by its
+// qualifier or, unqualified, as the only table read with such a column,
+// or "" if there is none.
+func resolveColumn(use columnRef, byRef map[string]string, described map[string][]db.ColumnInfo) (table, column string) {
+	find := func(table string) string {
+		for _, c := range described[table] {
+			if strings.EqualFold(c.Name, use.column) {
+				return c.Name
+			}
+		}
+		return ""
+	}
+	if use.qualifier != "" {
+		if table, ok := byRef[use.qualifier]; ok {
+			if column := find(table); column != "" {
+				return table, column
+			}
+		}
+		return "", ""
+	}
+	for candidate := range described {
+		if c := find(candidate); c != "" {
+			if table != "" {
+				return "", "" // ambiguous
+			}
+			table, column = candidate, c
+		}
+	}
+	return table, column
+}
+
+// coveringIndex returns the name of a full index on table that leads with
+// columns, whose first equality columns may come in any order.
+func coveringIndex(indexes []db.Index, table string, columns []string, equality int) (string, bool) {
+	for _, ix := range indexes {
+		if ix.Table != table || ix.Partial || len(ix.Columns) < len(columns) {
+			continue
+		}
+		lead := ix.Columns[:equality]
+		if !slices.Equal(ix.Columns[equality:len(columns)], columns[equality:]) {
+			continue
+		}
+		if slices.ContainsFunc(columns[:equality], func(c string) bool { return !slices.Contains(lead, c) }) {
+			continue
+		}
+		name := ix.Name
+		if name == "" {
+			name = "PRIMARY KEY"
+		}
+		return name, true
+	}
+	return "", false
+}
+
+// appendNew appends s to list unless it is in it already.
+func appendNew(list []string, s string) []string {
+	if slices.Contains(list, s) {
+		return list
+	}
+	return append(list, s)
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestSuggestIndexesTool(t *testing.T) {
+	call := demoCaller(t)
+	suggest := func(args map[string]any) SuggestIndexesOutput {
+		t.Helper()
+		res := call("suggest_indexes", args)
+		if res.IsError {
+			t.Fatalf("suggest_indexes %v: %s", args, textContent(res))
+		}
+		var out SuggestIndexesOutput
+		if err := json.Unmarshal([]byte(textContent(res)), &out); err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	out := suggest(map[string]any{
+		"sql": `SELECT o.id, c.name FROM orders AS o JOIN customers c ON c.id = o.customer_id
+			WHERE o.status = $1 AND o.ordered_at >= '2024-05-01' AND lower(c.city) = 'prague' ORDER BY o.ordered_at`,
+		"params": []any{"pending"},
+	})
+	want := []IndexSuggestion{{
+		Table:    "orders",
+		Columns:  []string{"customer_id", "status", "ordered_at"},
+		Reason:   "equality or join on customer_id, status; range on ordered_at; the plan reads the table in full",
+		FullScan: true,
+		DDL:      `CREATE INDEX "ix_orders_customer_id_status_ordered_at" ON "orders" ("customer_id", "status", "ordered_at")`,
+	}}
+	if !out.PlanAvailable || !reflect.DeepEqual(out.FullScans, []string{"orders"}) || !reflect.DeepEqual(out.Suggestions, want) || out.Note == "" {
+		t.Errorf("orders by customer = %+v", out)
+	}
+	if len(out.Covered) != 1 || out.Covered[0].Table != "customers" || !reflect.DeepEqual(out.Covered[0].Columns, []string{"id"}) {
+		t.Errorf("covered = %+v", out.Covered)
+	}
+
+	out = suggest(map[string]any{"sql": "SELECT * FROM customers WHERE email = 'ada@example.com'"})
+	if len(out.Suggestions) != 0 || len(out.Covered) != 1 || out.FullScans != nil {
+		t.Errorf("customer by email = %+v", out)
+	}
+
+	out = suggest(map[string]any{"sql": "SELECT name FROM products WHERE category IN ('books') ORDER BY price_cents"})
+	if len(out.Suggestions) != 1 || !reflect.DeepEqual(out.Suggestions[0].Columns, []string{"category", "price_cents"}) {
+		t.Errorf("products by category = %+v", out)
+	}
+
+	for _, args := range []map[string]any{
+		{},
+		{"sql": "DELETE FROM orders"},
+		{"sql": "SELECT * FROM nope WHERE id = 1"},
+	} {
+		if res := call("suggest_indexes", args); !res.IsError {
+			t.Errorf("suggest_indexes %v succeeded", args)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// systemSchemas are the catalog schemas of each connection type that the
+// tools refuse unless the connection is listed in allow_system_schemas.
+// Names are lower case; databases compare them case-insensitively.
+var systemSchemas = map[string][]string{
+	"postgres":  {"pg_catalog", "information_schema", "pg_toast"},
+	"sqlserver": {"sys", "information_schema"},
+	"mysql":     {"mysql", "information_schema", "performance_schema", "sys"},
+}
+
+// sqliteSystemTable matches SQLite's internal tables; list_tables already
+// leaves them out.
+var sqliteSystemTable = regexp.MustCompile(`(?i)^sqlite_`)
+
+var (
+	sqlStringLiteral = regexp.MustCompile(`'(?:[^']|'')*'`)
+	// sqliteSystemRef matches the name of an internal SQLite table.
+	sqliteSystemRef = regexp.MustCompile(`(?i)^sqlite_(master|schema|temp_master|temp_schema|sequence|stat\d)$`)
+	// sqlServerCatalogRef matches the name of a SQL Server catalog
+	// compatibility view (sysobjects, syscolumns, sysusers, syslogins,
+	// ...), which resolves without the sys schema. Their names have no
+	// underscore, so system_id and the like are not taken for one.
+	sqlServerCatalogRef = regexp.MustCompile(`(?i)^sys[a-z0-9]+$`)
+)
+
+// isSystemSchema reports whether schema is a system schema of connection
+// type typ.
+func isSystemSchema(typ, schema string) bool {
+	for _, s := range systemSchemas[typ] {
+		if strings.EqualFold(s, schema) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSystemTable refuses a schema or table argument naming a system
+// schema or catalog table of connID, unless it is listed in
+// allow_system_schemas. It returns nil if the table may be used.
+func checkSystemTable(cfg *config.Config, connID, schema, table string) *mcp.CallToolResult {
+	if cfg.SystemSchemasAllowed(connID) {
+		return nil
+	}
+	typ, _ := cfg.Type(connID)
+	switch {
+	case isSystemSchema(typ, schema):
+		return systemSchemaDenied(connID, schema)
+	case (typ == "sqlite" || typ == "demo") && sqliteSystemTable.MatchString(table):
+		return systemSchemaDenied(connID, table)
+	}
+	return nil
+}
+
+// checkSystemSQL refuses a query that reads a system schema or catalog
+// table of connID, unless it is listed in allow_system_schemas. Like
+// smuggledWrite it reads the statement under each of the connection's
+// dialects, so string literals and comments hide nothing from it.
+func checkSystemSQL(cfg *config.Config, connID, sql string) *mcp.CallToolResult {
+	if cfg.SystemSchemasAllowed(connID) {
+		return nil
+	}
+	typ, _ := cfg.Type(connID)
+	for _, d := range dialectsFor(typ) {
+		if name := systemRef(typ, lexSQL(sql, d)); name != "" {
+			return systemSchemaDenied(connID, name)
+		}
+	}
+	return nil
+}
+
+// systemRef returns the first system schema or catalog table of a
+// connection of type typ that toks reference, lower case, or "" if there
+// is none:
+//
+//   - a system schema qualifying a name, e.g. information_schema.tables
+//     or [sys].[objects];
+//   - on PostgreSQL, an unqualified pg_ relation, which resolves to
+//     pg_catalog through the search path;
+//   - on SQL Server, a catalog compatibility view;
+//   - on SQLite, an internal table.
+//
+// Names called like functions (pg_size_pretty(...), SYSDATETIME()) are
+// not catalog tables.
+func systemRef(typ string, toks []sqlToken) string {
+	for i, t := range toks {
+		if !t.word && !t.ident {
+			continue
+		}
+		name := strings.ToLower(t.text)
+		next := ""
+		if i+1 < len(toks) {
+			next = toks[i+1].text
+		}
+		switch {
+		case next == "." && isSystemSchema(typ, name):
+			return name
+		case next == "(":
+		case typ == "postgres" && strings.HasPrefix(name, "pg_"):
+			return "pg_catalog"
+		case typ == "sqlserver" && sqlServerCatalogRef.MatchString(name):
+			return name
+		case (typ == "sqlite" || typ == "demo") && sqliteSystemRef.MatchString(name):
+			return name
+		}
+	}
+	return ""
+}
+
+func systemSchemaDenied(connID, name string) *mcp.CallToolResult {
+	return errorResult(ToolError{
+		Code:    CodePermissionDenied,
+		Message: fmt.Sprintf("access to system schema or table %q of connection %q is blocked", name, connID),
+		Hint:    "use list_tables and describe_table for metadata, or list the connection in allow_system_schemas in config.yaml",
+	}, nil)
+}
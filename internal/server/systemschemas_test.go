@@ -0,0 +1,109 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func loadSystemSchemaConfig(t *testing.T) *config.Config {
+	t.Helper()
+	cfg := newTestConfig(t, `
+connections:
+  postgres: "postgres://localhost/app"
+  sqlserver: "sqlserver://localhost"
+  mysql: "root@tcp(localhost:3306)/app"
+  sqlite: ":memory:"
+  admin: "postgres://localhost/postgres"
+allow_system_schemas: [admin]
+`)
+	return cfg
+}
+
+func TestCheckSystemSQL(t *testing.T) {
+	cfg := loadSystemSchemaConfig(t)
+	tests := []struct {
+		conn    string
+		sql     string
+		blocked bool
+	}{
+		{"postgres", "SELECT * FROM users", false},
+		{"postgres", "SELECT * FROM information_schema.columns", true},
+		{"postgres", `SELECT * FROM "pg_catalog"."pg_class"`, true},
+		{"postgres", "SELECT relname FROM pg_class", true},
+		{"postgres", "SELECT pg_size_pretty(pg_database_size('app'))", false},
+		{"postgres", "SELECT * FROM users WHERE note = 'see pg_class and information_schema.tables'", false},
+		{"postgres", "SELECT 1 -- information_schema.tables", false},
+		{"postgres", "SELECT * FROM app.sys_users", false},
+		{"postgres", "SELECT $$'$$ AS a, * FROM pg_catalog.pg_authid, (SELECT $$'$$) x", true},
+		{"postgres", "SELECT E'\\'', * FROM pg_authid -- '", true},
+		{"sqlserver", "SELECT name FROM sys.objects", true},
+		{"sqlserver", "SELECT name FROM [sys].[tables]", true},
+		{"sqlserver", "SELECT * FROM INFORMATION_SCHEMA.TABLES", true},
+		{"sqlserver", "SELECT * FROM dbo.pg_class", false},
+		{"sqlserver", "SELECT name FROM sysobjects WHERE xtype = 'U'", true},
+		{"sqlserver", "SELECT * FROM SysColumns", true},
+		{"sqlserver", "SELECT name, password FROM [syslogins]", true},
+		{"sqlserver", "SELECT * FROM dbo.sysusers", true},
+		{"sqlserver", "SELECT system_id, SYSDATETIME() FROM dbo.orders", false},
+		{"mysql", "SELECT user FROM mysql.user", true},
+		{"mysql", "SELECT * FROM `performance_schema`.`threads`", true},
+		{"mysql", "SELECT * FROM app.orders", false},
+		{"sqlite", "SELECT sql FROM sqlite_master", true},
+		{"sqlite", "SELECT * FROM SQLITE_SEQUENCE", true},
+		{"sqlite", "SELECT sqlite_version()", false},
+		{"sqlite", "SELECT '--', name FROM sqlite_master", true},
+		{"sqlite", "SELECT 1 AS ['], * FROM [sqlite_master], (SELECT 1 AS [']) x", true},
+		{"mysql", "SELECT 'a\\'' , x.* FROM mysql.user x WHERE ''=''", true},
+		{"admin", "SELECT relname FROM pg_catalog.pg_class", false},
+	}
+	for _, tt := range tests {
+		res := checkSystemSQL(cfg, tt.conn, tt.sql)
+		if blocked := res != nil; blocked != tt.blocked {
+			t.Errorf("checkSystemSQL(%s, %q) blocked = %v, want %v", tt.conn, tt.sql, blocked, tt.blocked)
+		}
+		if res != nil && resultCode(res) != CodePermissionDenied {
+			t.Errorf("checkSystemSQL(%s, %q): code %q, want %q", tt.conn, tt.sql, resultCode(res), CodePermissionDenied)
+		}
+	}
+}
+
+func TestCheckSystemTable(t *testing.T) {
+	cfg := loadSystemSchemaConfig(t)
+	tests := []struct {
+		conn, schema, table string
+		blocked             bool
+	}{
+		{"postgres", "", "users", false},
+		{"postgres", "public", "users", false},
+		{"postgres", "PG_CATALOG", "pg_class", true},
+		{"sqlserver", "sys", "objects", true},
+		{"mysql", "mysql", "user", true},
+		{"mysql", "app", "user", false},
+		{"sqlite", "", "sqlite_sequence", true},
+		{"sqlite", "", "users", false},
+		{"admin", "information_schema", "tables", false},
+	}
+	for _, tt := range tests {
+		if blocked := checkSystemTable(cfg, tt.conn, tt.schema, tt.table) != nil; blocked != tt.blocked {
+			t.Errorf("checkSystemTable(%s, %q, %q) blocked = %v, want %v", tt.conn, tt.schema, tt.table, blocked, tt.blocked)
+		}
+	}
+}
+
+func TestRunQuery_systemSchemaBlocked(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t, "connections:\n  sqlite: \":memory:\"\n")
+	res, err := c.CallTool(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{
+		Name:      "run_query",
+		Arguments: map[string]any{"connection_id": "sqlite", "sql": "SELECT name, sql FROM sqlite_master"},
+	}})
+	if err != nil {
+		t.Fatalf("run_query: %v", err)
+	}
+	if !res.IsError || resultCode(res) != CodePermissionDenied {
+		t.Errorf("run_query on sqlite_master: %s", textContent(res))
+	}
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// toolTimeoutCategories maps the database tools to the category their
+// deadline is taken from. Tools not listed (ping, list_connections,
+// connection_stats, refresh_schema, close_connection, and health, which
+// bounds each ping itself) are never timed out by the server.
+var toolTimeoutCategories = map[string]string{
+	"list_tables":                 config.TimeoutMetadata,
+	"describe_table":              config.TimeoutMetadata,
+	"table_json_schema":           config.TimeoutMetadata,
+	"generate_go_struct":          config.TimeoutMetadata,
+	"generate_typescript_types":   config.TimeoutMetadata,
+	"generate_prisma_schema":      config.TimeoutMetadata,
+	"generate_graphql_sdl":        config.TimeoutMetadata,
+	"generate_erd":                config.TimeoutMetadata,
+	"compare_schemas":             config.TimeoutMetadata,
+	"compare_table_data":          config.TimeoutQuery,
+	"profile_table":               config.TimeoutQuery,
+	"find_duplicates":             config.TimeoutQuery,
+	"check_referential_integrity": config.TimeoutQuery,
+	"find_orphans":                config.TimeoutQuery,
+	"suggest_indexes":             config.TimeoutQuery,
+	"explain_query":               config.TimeoutQuery,
+	"list_dependencies":           config.TimeoutMetadata,
+	"run_query":                   config.TimeoutQuery,
+	"insert_test_row":             config.TimeoutQuery,
+	"update_test_row":             config.TimeoutQuery,
+	"begin_transaction":           config.TimeoutQuery,
+	"commit_transaction":          config.TimeoutQuery,
+	"rollback_transaction":        config.TimeoutQuery,
+	"create_test_table":           config.TimeoutQuery,
+	"export_database":             config.TimeoutExport,
+	"import_database":             config.TimeoutExport,
+}
+
+// timeoutMiddleware runs each tool call under its category's deadline,
+// whatever timeout the client uses. The deadline cancels the database
+// operation or dump tool; the call then fails with query_timeout.
+func timeoutMiddleware(timeouts map[string]time.Duration) server.ToolHandlerMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			d := timeouts[toolTimeoutCategories[request.Params.Name]]
+			if d <= 0 {
+				return next(ctx, request)
+			}
+			callCtx, cancel := context.WithTimeout(ctx, d)
+			defer cancel()
+			res, err := next(callCtx, request)
+			// Only report a timeout the server imposed, not a client
+			// cancellation or an earlier deadline on ctx.
+			if errors.Is(callCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+				return errorResult(ToolError{
+					Code:    CodeQueryTimeout,
+					Message: fmt.Sprintf("%s did not finish within %s and was cancelled", request.Params.Name, d),
+					Hint:    "narrow the query, add a LIMIT, or raise timeouts in config.yaml",
+				}, nil), nil
+			}
+			return res, err
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestTimeoutMiddleware(t *testing.T) {
+	handler := timeoutMiddleware(map[string]time.Duration{
+		config.TimeoutQuery:    10 * time.Millisecond,
+		config.TimeoutMetadata: 0,
+	})(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if _, ok := ctx.Deadline(); !ok {
+			return mcp.NewToolResultText("no deadline"), nil
+		}
+		<-ctx.Done()
+		return toolErrorResult(ctx.Err()), nil
+	})
+	call := func(ctx context.Context, name string) *mcp.CallToolResult {
+		t.Helper()
+		res, err := handler(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{Name: name}})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		return res
+	}
+
+	res := call(context.Background(), "run_query")
+	out, ok := res.StructuredContent.(ToolError)
+	if !res.IsError || !ok || out.Code != CodeQueryTimeout {
+		t.Errorf("run_query past its deadline: %+v", res)
+	}
+	for _, name := range []string{"list_tables", "ping"} {
+		if res := call(context.Background(), name); textContent(res) != "no deadline" {
+			t.Errorf("%s should run without a server deadline, got %q", name, textContent(res))
+		}
+	}
+
+	// A client cancellation is passed through, not reported as a timeout.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	res = call(ctx, "run_query")
+	if out, _ := res.StructuredContent.(ToolError); out.Code != CodeCancelled {
+		t.Errorf("cancelled call: %+v", res.StructuredContent)
+	}
+}
@@ -0,0 +1,156 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// TransactionIdleTimeout is how long a transaction may go without a call
+// before the server rolls it back, so an agent that forgets one does not
+// hold its locks forever.
+const TransactionIdleTimeout = 5 * time.Minute
+
+// rowWriter is what insert_test_row and update_test_row write through: a
+// connection's driver or an open transaction on it.
+type rowWriter interface {
+	InsertRow(ctx context.Context, schema, table string, row map[string]any) (any, error)
+	UpdateRow(ctx context.Context, schema, table string, key, set map[string]any) (int64, error)
+}
+
+// openTx is a transaction begun by begin_transaction. mu serializes the
+// calls using it; done is set once it was committed or rolled back.
+type openTx struct {
+	id, connID, session string
+	tx                  db.Tx
+	timer               *time.Timer
+
+	mu   sync.Mutex
+	done bool
+}
+
+// txRegistry holds the open transactions. A transaction belongs to the
+// session that began it and ends with that session.
+type txRegistry struct {
+	idle time.Duration
+
+	mu  sync.Mutex
+	txs map[string]*openTx
+}
+
+func newTxRegistry(idle time.Duration) *txRegistry {
+	return &txRegistry{idle: idle, txs: make(map[string]*openTx)}
+}
+
+// begin starts a transaction on connID for the session ctx belongs to and
+// returns its ID.
+func (r *txRegistry) begin(ctx context.Context, mgr *db.Manager, connID string) (string, error) {
+	tx, err := mgr.BeginTx(ctx, connID)
+	if err != nil {
+		return "", err
+	}
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		tx.Rollback(ctx)
+		return "", err
+	}
+	t := &openTx{id: "tx_" + hex.EncodeToString(b), connID: connID, session: sessionID(ctx), tx: tx}
+	r.mu.Lock()
+	r.txs[t.id] = t
+	t.timer = time.AfterFunc(r.idle, func() { r.end(context.Background(), t, false) })
+	r.mu.Unlock()
+	return t.id, nil
+}
+
+// acquire returns the open transaction id of the calling session, locked
+// for the caller's use; the caller unlocks t.mu when done. connID, if not
+// empty, must be the transaction's connection. It returns a failed result
+// instead when there is no such transaction.
+func (r *txRegistry) acquire(ctx context.Context, id, connID string) (*openTx, *mcp.CallToolResult) {
+	r.mu.Lock()
+	t, ok := r.txs[id]
+	r.mu.Unlock()
+	if !ok || t.session != sessionID(ctx) {
+		return nil, errorResult(ToolError{
+			Code:    CodeNotFound,
+			Message: fmt.Sprintf("no open transaction %q", id),
+			Hint:    fmt.Sprintf("it was committed, rolled back, or idle for over %s; call begin_transaction again", r.idle),
+		}, nil)
+	}
+	if connID != "" && t.connID != connID {
+		return nil, invalidArgs(fmt.Sprintf("transaction %q belongs to connection %q, not %q", id, t.connID, connID))
+	}
+	t.mu.Lock()
+	if t.done {
+		t.mu.Unlock()
+		return r.acquire(ctx, id, connID)
+	}
+	t.timer.Reset(r.idle)
+	return t, nil
+}
+
+// end commits or rolls back t unless it already ended, and forgets it.
+func (r *txRegistry) end(ctx context.Context, t *openTx, commit bool) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return r.endLocked(ctx, t, commit)
+}
+
+// endLocked is end for a caller holding t.mu.
+func (r *txRegistry) endLocked(ctx context.Context, t *openTx, commit bool) error {
+	if t.done {
+		return nil
+	}
+	t.done = true
+	t.timer.Stop()
+	r.mu.Lock()
+	delete(r.txs, t.id)
+	r.mu.Unlock()
+	if commit {
+		return t.tx.Commit(ctx)
+	}
+	return t.tx.Rollback(ctx)
+}
+
+// release rolls back the transactions of a session that ended.
+func (r *txRegistry) release(sess *sessionState) {
+	r.mu.Lock()
+	var owned []*openTx
+	for _, t := range r.txs {
+		if t.session == sess.id {
+			owned = append(owned, t)
+		}
+	}
+	r.mu.Unlock()
+	for _, t := range owned {
+		r.end(context.Background(), t, false)
+	}
+}
+
+// writer returns what a write tool call writes through: the transaction
+// named by its transaction_id argument, locked until the returned func is
+// called, or driver when there is none. Updates changing more than limit
+// rows are rolled back (see limitedWriter); zero means no limit.
+func (r *txRegistry) writer(ctx context.Context, args map[string]any, mgr *db.Manager, connID string, driver db.Driver, limit int) (rowWriter, func(), *mcp.CallToolResult) {
+	id, _ := args["transaction_id"].(string)
+	if id == "" {
+		if limit > 0 {
+			return limitedWriter{Driver: driver, mgr: mgr, connID: connID, limit: limit}, func() {}, nil
+		}
+		return driver, func() {}, nil
+	}
+	t, res := r.acquire(ctx, id, connID)
+	if res != nil {
+		return nil, nil, res
+	}
+	if limit > 0 {
+		return limitedTxWriter{r: r, t: t, limit: limit}, t.mu.Unlock, nil
+	}
+	return t.tx, t.mu.Unlock, nil
+}
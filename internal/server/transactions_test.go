@@ -0,0 +1,113 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestTransactionTools(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "test.db")
+	sqlDB, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDB.Close()
+	if _, err := sqlDB.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+
+	c := newTestClient(t, "connections:\n  sqlite: \""+path+"\"\nrate_limits:\n  write: {rate: 0}\n")
+	call := func(name string, args map[string]any) *mcp.CallToolResult {
+		t.Helper()
+		res, err := c.CallTool(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{Name: name, Arguments: args}})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		return res
+	}
+	begin := func() string {
+		t.Helper()
+		res := call("begin_transaction", map[string]any{"connection_id": "sqlite"})
+		var out BeginTransactionOutput
+		if res.IsError || json.Unmarshal([]byte(textContent(res)), &out) != nil || out.TransactionID == "" {
+			t.Fatalf("begin_transaction: %s", textContent(res))
+		}
+		return out.TransactionID
+	}
+	count := func() int {
+		t.Helper()
+		var n int
+		if err := sqlDB.QueryRow("SELECT COUNT(*) FROM users").Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	txID := begin()
+	for _, name := range []string{"alice", "bob"} {
+		res := call("insert_test_row", map[string]any{
+			"connection_id": "sqlite", "table": "users", "row": map[string]any{"name": name}, "transaction_id": txID,
+		})
+		if res.IsError {
+			t.Fatalf("insert_test_row: %s", textContent(res))
+		}
+	}
+	res := call("update_test_row", map[string]any{
+		"connection_id": "sqlite", "table": "users", "transaction_id": txID,
+		"key": map[string]any{"id": 1}, "set": map[string]any{"name": "carol"},
+	})
+	if res.IsError {
+		t.Fatalf("update_test_row: %s", textContent(res))
+	}
+	if res := call("rollback_transaction", map[string]any{"transaction_id": txID}); res.IsError {
+		t.Fatalf("rollback_transaction: %s", textContent(res))
+	}
+	if n := count(); n != 0 {
+		t.Errorf("after rollback: %d rows, want 0", n)
+	}
+
+	// An ended transaction is gone.
+	res = call("insert_test_row", map[string]any{
+		"connection_id": "sqlite", "table": "users", "row": map[string]any{"name": "dave"}, "transaction_id": txID,
+	})
+	if got := resultCode(res); got != CodeNotFound {
+		t.Errorf("insert into an ended transaction: code %q, want %q", got, CodeNotFound)
+	}
+
+	txID = begin()
+	res = call("insert_test_row", map[string]any{
+		"connection_id": "sqlite", "table": "users", "row": map[string]any{"name": "erin"}, "transaction_id": txID,
+	})
+	if res.IsError {
+		t.Fatalf("insert_test_row: %s", textContent(res))
+	}
+	if res := call("commit_transaction", map[string]any{"transaction_id": txID}); res.IsError {
+		t.Fatalf("commit_transaction: %s", textContent(res))
+	}
+	if n := count(); n != 1 {
+		t.Errorf("after commit: %d rows, want 1", n)
+	}
+	if got := resultCode(call("commit_transaction", map[string]any{"transaction_id": txID})); got != CodeNotFound {
+		t.Errorf("second commit: code %q, want %q", got, CodeNotFound)
+	}
+}
+
+func TestTxRegistry_release(t *testing.T) {
+	_, mgr := newTestServer(t, "connections:\n  sqlite: \":memory:\"\n")
+
+	r := newTxRegistry(TransactionIdleTimeout)
+	id, err := r.begin(context.Background(), mgr, "sqlite")
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	r.release(&sessionState{id: ""})
+	if _, res := r.acquire(context.Background(), id, ""); res == nil {
+		t.Error("transaction survived the end of its session")
+	}
+}
@@ -0,0 +1,338 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Transport names accepted by Serve.
+const (
+	TransportStdio = "stdio"
+	TransportSSE   = "sse"
+	TransportHTTP  = "http"
+	TransportUnix  = "unix"
+)
+
+// DefaultAddr is the listen address for network transports when none is given.
+const DefaultAddr = ":8089"
+
+// DefaultSocketPath returns the socket TransportUnix listens on, and Attach
+// connects to, when no address is given: ~/.localdb-mcp/localdb-mcp.sock.
+func DefaultSocketPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "localdb-mcp.sock")
+	}
+	return filepath.Join(home, ".localdb-mcp", "localdb-mcp.sock")
+}
+
+// DefaultDrainTimeout is how long in-flight tool calls may keep running after
+// shutdown starts when ServeOptions.DrainTimeout is zero.
+const DefaultDrainTimeout = 10 * time.Second
+
+// ServeOptions selects the transport Serve runs on.
+type ServeOptions struct {
+	// Transport is one of TransportStdio (default), TransportSSE,
+	// TransportHTTP or TransportUnix.
+	Transport string
+	// Addr is the listen address for network transports; DefaultAddr if empty.
+	// For TransportUnix it is the socket path; DefaultSocketPath if empty or
+	// DefaultAddr.
+	Addr string
+	// AllowRemote permits binding to non-loopback interfaces. Without it a
+	// host-less address such as ":8089" binds to 127.0.0.1 and any other
+	// host is rejected, so the database tools are not reachable from the
+	// network by accident.
+	AllowRemote bool
+	// DrainTimeout bounds how long in-flight tool calls may run once ctx is
+	// cancelled; after it they are cancelled too. DefaultDrainTimeout if zero.
+	DrainTimeout time.Duration
+	// AuthToken is the bearer token network transports require on every
+	// request ("Authorization: Bearer <token>"). If empty, Serve generates a
+	// random token and prints it once to stderr.
+	AuthToken string
+	// Metrics, if set, is served on /metrics by the network and Unix socket
+	// transports, behind the same bearer token as MCP (see MetricsHandler).
+	Metrics http.Handler
+}
+
+func (o ServeOptions) drainTimeout() time.Duration {
+	if o.DrainTimeout <= 0 {
+		return DefaultDrainTimeout
+	}
+	return o.DrainTimeout
+}
+
+// handleMetrics mounts o.Metrics on mux, if set.
+func (o ServeOptions) handleMetrics(mux *http.ServeMux) {
+	if o.Metrics != nil {
+		mux.Handle("/metrics", o.Metrics)
+	}
+}
+
+// Serve runs s on the selected transport until the client goes away, an
+// error occurs, or ctx is cancelled.
+//
+//   - stdio talks to a single client over stdin/stdout.
+//   - sse serves any number of clients over HTTP with Server-Sent Events
+//     (GET /sse for the event stream, POST /message for requests).
+//   - http serves the MCP streamable HTTP transport on /mcp, with sessions
+//     identified by the Mcp-Session-Id header and ended by DELETE.
+//   - unix serves the same streamable HTTP transport on a Unix socket, as a
+//     long-running daemon that stdio clients reach through Attach. Database
+//     connections stay open while clients come and go.
+//
+// Network transports require opts.AuthToken as a bearer token on every
+// request, so exposing the port does not expose the databases. With
+// opts.Metrics set they also serve GET /metrics. The Unix
+// socket is instead created readable and writable by its owner only.
+//
+// Cancelling ctx starts a graceful shutdown: no new requests are accepted,
+// and tool calls already running get up to opts.DrainTimeout to finish
+// before their contexts are cancelled. Serve returns once they are done, so
+// the caller can then close the database connections.
+func Serve(ctx context.Context, s *server.MCPServer, opts ServeOptions) error {
+	var addr, token string
+	switch opts.Transport {
+	case "", TransportStdio:
+	case TransportSSE, TransportHTTP:
+		var err error
+		if addr, err = listenAddr(opts.Addr, opts.AllowRemote); err != nil {
+			return err
+		}
+		if token = opts.AuthToken; token == "" {
+			if token, err = generateToken(); err != nil {
+				return fmt.Errorf("generate auth token: %w", err)
+			}
+			fmt.Fprintf(os.Stderr, "localdb-mcp: clients must send \"Authorization: Bearer %s\"\n", token)
+		}
+	case TransportUnix:
+		if addr = opts.Addr; addr == "" || addr == DefaultAddr {
+			addr = DefaultSocketPath()
+		}
+	default:
+		return fmt.Errorf("unknown transport %q (want %q, %q, %q or %q)",
+			opts.Transport, TransportStdio, TransportSSE, TransportHTTP, TransportUnix)
+	}
+
+	// Tool calls run on a context that outlives ctx by the drain timeout.
+	work, cancelWork := context.WithCancel(context.Background())
+	defer cancelWork()
+	stop := context.AfterFunc(ctx, func() {
+		time.AfterFunc(opts.drainTimeout(), cancelWork)
+	})
+	defer stop()
+	server.WithToolHandlerMiddleware(drainMiddleware(ctx, work))(s)
+
+	// The http.Server is created up front so a shutdown that races with
+	// startup still stops it: ListenAndServe after Shutdown returns at once.
+	srv := &http.Server{Addr: addr}
+	switch opts.Transport {
+	case TransportSSE:
+		log.Printf("serving MCP over SSE on %s (endpoint /sse)", addr)
+		sse := newSSEServer(s, server.WithHTTPServer(srv))
+		mux := http.NewServeMux()
+		mux.Handle("/", sse)
+		opts.handleMetrics(mux)
+		srv.Handler = requireBearer(token, mux)
+		return serveHTTP(ctx, opts.drainTimeout(), srv.ListenAndServe, sse.Shutdown)
+	case TransportHTTP:
+		log.Printf("serving MCP over streamable HTTP on %s (endpoint /mcp)", addr)
+		h := newStreamableHTTPServer(s, server.WithStreamableHTTPServer(srv))
+		mux := http.NewServeMux()
+		mux.Handle("/mcp", h)
+		opts.handleMetrics(mux)
+		srv.Handler = requireBearer(token, mux)
+		return serveHTTP(ctx, opts.drainTimeout(), srv.ListenAndServe, h.Shutdown)
+	case TransportUnix:
+		l, err := listenUnix(addr)
+		if err != nil {
+			return err
+		}
+		log.Printf("serving MCP over streamable HTTP on unix socket %s (attach with: localdb-mcp attach)", addr)
+		h := newStreamableHTTPServer(s, server.WithStreamableHTTPServer(srv))
+		mux := http.NewServeMux()
+		mux.Handle("/mcp", h)
+		opts.handleMetrics(mux)
+		srv.Handler = mux
+		return serveHTTP(ctx, opts.drainTimeout(), func() error { return srv.Serve(l) }, h.Shutdown)
+	default:
+		err := server.NewStdioServer(s).Listen(ctx, os.Stdin, os.Stdout)
+		if errors.Is(err, context.Canceled) {
+			return nil
+		}
+		return err
+	}
+}
+
+// serveHTTP runs start until it fails or ctx is cancelled, then calls
+// shutdown with the drain timeout so open requests can complete.
+func serveHTTP(ctx context.Context, drain time.Duration, start func() error, shutdown func(context.Context) error) error {
+	errc := make(chan error, 1)
+	go func() { errc <- start() }()
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), drain)
+	defer cancel()
+	if err := shutdown(shutdownCtx); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	if err := <-errc; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// listenUnix listens on the socket at path, readable and writable by the
+// owner only. A socket left behind by a daemon that died is replaced; one
+// that still accepts connections is an error.
+func listenUnix(path string) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	if fi, err := os.Lstat(path); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if c, err := net.Dial("unix", path); err == nil {
+			c.Close()
+			return nil, fmt.Errorf("a daemon is already listening on %s", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// requireBearer rejects requests that do not carry token as a bearer
+// credential in the Authorization header.
+func requireBearer(token string, next http.Handler) http.Handler {
+	want := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := []byte(r.Header.Get("Authorization"))
+		if subtle.ConstantTimeCompare(got, want) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="localdb-mcp"`)
+			http.Error(w, "missing or invalid bearer token", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// generateToken returns a random 256-bit token, hex encoded.
+func generateToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// drainMiddleware keeps tool calls running through the start of shutdown.
+// A call's context is normally cancelled with its request (e.g. when an HTTP
+// client disconnects), but cancellation caused by shutdown itself is ignored
+// until work is cancelled at the end of the drain timeout. Calls arriving
+// after shutdown has started are refused.
+func drainMiddleware(shutdown, work context.Context) server.ToolHandlerMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			if shutdown.Err() != nil {
+				return errorResult(ToolError{Code: CodeUnavailable, Message: "server is shutting down"}, nil), nil
+			}
+			callCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+			defer cancel()
+			defer context.AfterFunc(work, cancel)()
+			defer context.AfterFunc(ctx, func() {
+				if shutdown.Err() == nil {
+					cancel()
+				}
+			})()
+			return next(callCtx, request)
+		}
+	}
+}
+
+// listenAddr returns the address to listen on. Unless allowRemote is set, an
+// empty host is bound to loopback and a non-loopback host is an error.
+func listenAddr(addr string, allowRemote bool) (string, error) {
+	if addr == "" {
+		addr = DefaultAddr
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", fmt.Errorf("invalid listen address %q: %w", addr, err)
+	}
+	if allowRemote {
+		return addr, nil
+	}
+	if host == "" {
+		return net.JoinHostPort("127.0.0.1", port), nil
+	}
+	if host == "localhost" {
+		return addr, nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return addr, nil
+	}
+	return "", fmt.Errorf("refusing to listen on non-loopback address %q; pass --allow-remote to expose the server to the network", addr)
+}
+
+// newSSEServer wraps s in an SSE transport. The message endpoint is
+// advertised as a relative path so clients reach the server through
+// whichever host name they connected with.
+func newSSEServer(s *server.MCPServer, opts ...server.SSEOption) *server.SSEServer {
+	return server.NewSSEServer(s, append([]server.SSEOption{
+		server.WithKeepAlive(true),
+	}, opts...)...)
+}
+
+// newStreamableHTTPServer wraps s in a stateful streamable HTTP transport:
+// the server issues a session ID on initialize and rejects requests for
+// unknown or terminated sessions.
+func newStreamableHTTPServer(s *server.MCPServer, opts ...server.StreamableHTTPOption) *server.StreamableHTTPServer {
+	return server.NewStreamableHTTPServer(s, append([]server.StreamableHTTPOption{
+		server.WithSessionIdManager(&sessionIDs{}),
+	}, opts...)...)
+}
+
+// sessionIDs tracks streamable HTTP sessions like mcp-go's stateful
+// manager, but answers a session it does not know (say, one from before a
+// restart) with 404 rather than 400, as the MCP spec requires, so clients
+// know to start a new session.
+type sessionIDs struct {
+	server.InsecureStatefulSessionIdManager
+}
+
+func (m *sessionIDs) Validate(sessionID string) (isTerminated bool, err error) {
+	isTerminated, err = m.InsecureStatefulSessionIdManager.Validate(sessionID)
+	if err != nil && strings.HasPrefix(err.Error(), "session not found") {
+		return true, nil
+	}
+	return isTerminated, err
+}
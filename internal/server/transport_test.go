@@ -0,0 +1,230 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// pingOver initializes c and calls the ping tool.
+func pingOver(t *testing.T, c *client.Client) {
+	t.Helper()
+	ctx := context.Background()
+	if err := c.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	initReq := mcp.InitializeRequest{}
+	initReq.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	if _, err := c.Initialize(ctx, initReq); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	req := mcp.CallToolRequest{}
+	req.Params.Name = "ping"
+	res, err := c.CallTool(ctx, req)
+	if err != nil {
+		t.Fatalf("ping: %v", err)
+	}
+	if res.IsError {
+		t.Fatalf("ping returned an error: %+v", res.Content)
+	}
+}
+
+func TestSSETransport_multipleClients(t *testing.T) {
+	s := server.NewMCPServer(ServerName, ServerVersion)
+	Register(s, nil)
+	ts := httptest.NewServer(newSSEServer(s))
+	defer ts.Close()
+
+	for i := 0; i < 2; i++ {
+		c, err := client.NewSSEMCPClient(ts.URL + "/sse")
+		if err != nil {
+			t.Fatalf("NewSSEMCPClient: %v", err)
+		}
+		defer c.Close()
+		pingOver(t, c)
+	}
+}
+
+func TestStreamableHTTPTransport_sessions(t *testing.T) {
+	s := server.NewMCPServer(ServerName, ServerVersion)
+	Register(s, nil)
+	ts := httptest.NewServer(newStreamableHTTPServer(s))
+	defer ts.Close()
+
+	c, err := client.NewStreamableHttpClient(ts.URL)
+	if err != nil {
+		t.Fatalf("NewStreamableHttpClient: %v", err)
+	}
+	defer c.Close()
+	pingOver(t, c)
+
+	// Requests carrying an unknown session ID are rejected.
+	req, _ := http.NewRequest(http.MethodPost, ts.URL,
+		strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Mcp-Session-Id", "mcp-session-00000000-0000-0000-0000-000000000000")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		t.Error("expected unknown session to be rejected")
+	}
+}
+
+func TestRequireBearer(t *testing.T) {
+	s := server.NewMCPServer(ServerName, ServerVersion)
+	Register(s, nil)
+	ts := httptest.NewServer(requireBearer("secret", newSSEServer(s)))
+	defer ts.Close()
+
+	for _, auth := range []string{"", "Bearer wrong", "secret"} {
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+"/sse", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized || resp.Header.Get("WWW-Authenticate") == "" {
+			t.Errorf("Authorization %q: got %d, want 401 with a challenge", auth, resp.StatusCode)
+		}
+	}
+
+	c, err := client.NewSSEMCPClient(ts.URL+"/sse",
+		transport.WithHeaders(map[string]string{"Authorization": "Bearer secret"}))
+	if err != nil {
+		t.Fatalf("NewSSEMCPClient: %v", err)
+	}
+	defer c.Close()
+	pingOver(t, c)
+}
+
+func TestGenerateToken(t *testing.T) {
+	a, err := generateToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := generateToken()
+	if len(a) != 64 || a == b {
+		t.Errorf("expected distinct 64-char tokens, got %q and %q", a, b)
+	}
+}
+
+func TestListenAddr(t *testing.T) {
+	tests := []struct {
+		addr        string
+		allowRemote bool
+		want        string
+		wantErr     bool
+	}{
+		{"", false, "127.0.0.1:8089", false},
+		{":9000", false, "127.0.0.1:9000", false},
+		{"localhost:9000", false, "localhost:9000", false},
+		{"[::1]:9000", false, "[::1]:9000", false},
+		{"0.0.0.0:9000", false, "", true},
+		{"192.168.1.5:9000", false, "", true},
+		{"0.0.0.0:9000", true, "0.0.0.0:9000", false},
+		{":9000", true, ":9000", false},
+		{"no-port", false, "", true},
+	}
+	for _, tt := range tests {
+		got, err := listenAddr(tt.addr, tt.allowRemote)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("listenAddr(%q, %v) = %q, %v; want %q, err=%v", tt.addr, tt.allowRemote, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestServe_unknownTransport(t *testing.T) {
+	s := server.NewMCPServer(ServerName, ServerVersion)
+	if err := Serve(context.Background(), s, ServeOptions{Transport: "carrier-pigeon"}); err == nil {
+		t.Fatal("expected error for unknown transport")
+	}
+}
+
+func TestServe_shutdownOnCancel(t *testing.T) {
+	for _, transport := range []string{TransportSSE, TransportHTTP} {
+		s := server.NewMCPServer(ServerName, ServerVersion)
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() {
+			done <- Serve(ctx, s, ServeOptions{Transport: transport, Addr: "127.0.0.1:0", DrainTimeout: time.Second, AuthToken: "secret"})
+		}()
+		cancel()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("%s: Serve returned %v after cancel", transport, err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: Serve did not return after cancel", transport)
+		}
+	}
+}
+
+func TestDrainMiddleware(t *testing.T) {
+	shutdown, startShutdown := context.WithCancel(context.Background())
+	work, endDrain := context.WithCancel(context.Background())
+	defer endDrain()
+
+	started := make(chan struct{})
+	finished := make(chan error, 1)
+	handler := drainMiddleware(shutdown, work)(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		close(started)
+		select {
+		case <-ctx.Done():
+			finished <- ctx.Err()
+		case <-time.After(50 * time.Millisecond):
+			finished <- nil
+		}
+		return mcp.NewToolResultText("ok"), nil
+	})
+
+	// The request context derives from the transport's, which shutdown cancels.
+	reqCtx, cancelReq := context.WithCancel(shutdown)
+	defer cancelReq()
+	go handler(reqCtx, mcp.CallToolRequest{})
+	<-started
+	startShutdown()
+	if err := <-finished; err != nil {
+		t.Errorf("in-flight call should survive the start of shutdown, got %v", err)
+	}
+
+	res, _ := handler(context.Background(), mcp.CallToolRequest{})
+	if res == nil || !res.IsError {
+		t.Error("calls after shutdown started should be refused")
+	}
+}
+
+func TestDrainMiddleware_cancelAfterDrain(t *testing.T) {
+	shutdown := context.Background()
+	work, endDrain := context.WithCancel(context.Background())
+
+	handler := drainMiddleware(shutdown, work)(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		endDrain()
+		<-ctx.Done()
+		return mcp.NewToolResultText("cancelled"), nil
+	})
+	done := make(chan struct{})
+	go func() {
+		handler(context.Background(), mcp.CallToolRequest{})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("call was not cancelled when the drain timeout expired")
+	}
+}
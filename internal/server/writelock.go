@@ -0,0 +1,83 @@
+package server
+
+import (
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Durations enable_writes accepts, in minutes.
+const (
+	DefaultWriteUnlockMinutes = 15
+	MaxWriteUnlockMinutes     = 60
+)
+
+// WriteLockStatus reports which connections' write tools are enabled while
+// the server runs with write_unlock, and until when.
+type WriteLockStatus struct {
+	UnlockedUntil map[string]time.Time `json:"unlocked_until"`
+}
+
+// writeLock keeps the write tools locked per connection except during the
+// windows the human opened with enable_writes. A window ends by itself:
+// every write checks the time, so nothing has to relock.
+type writeLock struct {
+	now func() time.Time
+
+	mu    sync.Mutex
+	until map[string]time.Time // by connection ID
+}
+
+func newWriteLock() *writeLock {
+	return &writeLock{now: time.Now, until: make(map[string]time.Time)}
+}
+
+// unlock enables writes on connID for d, replacing any window it had, and
+// returns when they lock again.
+func (l *writeLock) unlock(connID string, d time.Duration) time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	until := l.now().Add(d)
+	l.until[connID] = until
+	return until
+}
+
+// status returns the connections with writes enabled now, or nil for a nil
+// *writeLock.
+func (l *writeLock) status() *WriteLockStatus {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	maps.DeleteFunc(l.until, func(_ string, until time.Time) bool { return !now.Before(until) })
+	return &WriteLockStatus{UnlockedUntil: maps.Clone(l.until)}
+}
+
+// check refuses a write on connID unless its writes are enabled now. It
+// returns nil if the write may go ahead; a nil *writeLock never locks.
+func (l *writeLock) check(connID string) *mcp.CallToolResult {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	until, ok := l.until[connID]
+	now := l.now()
+	l.mu.Unlock()
+	if ok && now.Before(until) {
+		return nil
+	}
+	msg := fmt.Sprintf("writes to connection %q are locked", connID)
+	if ok {
+		msg = fmt.Sprintf("writes to connection %q locked again at %s", connID, until.UTC().Format(time.RFC3339))
+	}
+	return errorResult(ToolError{
+		Code:    CodePermissionDenied,
+		Message: msg,
+		Hint:    "call enable_writes to ask the user to enable writes for a few minutes",
+	}, nil)
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestWriteLock(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	l := newWriteLock()
+	l.now = func() time.Time { return now }
+
+	if res := l.check("sqlite"); resultCode(res) != CodePermissionDenied {
+		t.Fatalf("writes should start locked, got %v", res)
+	}
+	until := l.unlock("sqlite", 10*time.Minute)
+	if !until.Equal(now.Add(10 * time.Minute)) {
+		t.Errorf("unlock returned %v", until)
+	}
+	if res := l.check("sqlite"); res != nil {
+		t.Errorf("unlocked connection refused: %s", textContent(res))
+	}
+	if res := l.check("demo"); res == nil {
+		t.Error("unlocking one connection unlocked another")
+	}
+	if st := l.status(); len(st.UnlockedUntil) != 1 || !st.UnlockedUntil["sqlite"].Equal(until) {
+		t.Errorf("status = %+v", st)
+	}
+
+	now = until
+	if res := l.check("sqlite"); !strings.Contains(textContent(res), "locked again") {
+		t.Errorf("expired window should relock, got %v", res)
+	}
+	if st := l.status(); len(st.UnlockedUntil) != 0 {
+		t.Errorf("status after expiry = %+v", st)
+	}
+
+	var nilLock *writeLock
+	if nilLock.check("sqlite") != nil || nilLock.status() != nil {
+		t.Error("a nil writeLock should never lock")
+	}
+}
+
+func TestEnableWrites(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "test.db")
+	sqlDB, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDB.Close()
+	if _, err := sqlDB.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(config.EnvWriteUnlock, "true")
+	s, _ := newTestServer(t, "connections:\n  sqlite: \""+path+"\"\n")
+
+	human := &fakeHuman{}
+	c := connectTestClient(t, s, transport.WithElicitationHandler(human))
+	call := func(name string, args map[string]any) *mcp.CallToolResult {
+		t.Helper()
+		res, err := c.CallTool(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{Name: name, Arguments: args}})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		return res
+	}
+	insert := map[string]any{"connection_id": "sqlite", "table": "users", "row": map[string]any{"name": "alice"}}
+
+	if res := call("insert_test_row", insert); resultCode(res) != CodePermissionDenied {
+		t.Fatalf("insert before enable_writes: %s", textContent(res))
+	}
+	if res := call("enable_writes", map[string]any{"connection_id": "sqlite", "minutes": 90}); resultCode(res) != CodeValidationFailed {
+		t.Errorf("too long a window: %s", textContent(res))
+	}
+	if res := call("enable_writes", map[string]any{"connection_id": "sqlite"}); !res.IsError {
+		t.Fatal("declined enable_writes should fail")
+	}
+
+	human.approve = true
+	res := call("enable_writes", map[string]any{"connection_id": "sqlite", "minutes": 5, "reason": "seed a user"})
+	var out EnableWritesOutput
+	if res.IsError || json.Unmarshal([]byte(textContent(res)), &out) != nil {
+		t.Fatalf("enable_writes: %s", textContent(res))
+	}
+	if d := time.Until(out.UnlockedUntil); d <= 4*time.Minute || d > 5*time.Minute {
+		t.Errorf("unlocked until %v, want about 5 minutes from now", out.UnlockedUntil)
+	}
+	if last := human.messages[len(human.messages)-1]; !strings.Contains(last, "5 minutes") || !strings.Contains(last, "seed a user") {
+		t.Errorf("prompt = %q", last)
+	}
+	if res := call("insert_test_row", insert); res.IsError {
+		t.Fatalf("insert after enable_writes: %s", textContent(res))
+	}
+
+	var health HealthOutput
+	if err := json.Unmarshal([]byte(textContent(call("health", nil))), &health); err != nil {
+		t.Fatal(err)
+	}
+	if health.WriteLock == nil || !health.WriteLock.UnlockedUntil["sqlite"].Equal(out.UnlockedUntil) {
+		t.Errorf("health write_lock = %+v", health.WriteLock)
+	}
+}
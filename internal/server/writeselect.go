@@ -0,0 +1,254 @@
+package server
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// sqlToken is a word or punctuation character of a statement, as read by
+// lexSQL. Words and quoted identifiers are upper-cased, the latter without
+// their quotes; a string literal becomes a single token of two quotes.
+type sqlToken struct {
+	text       string
+	word       bool // a bare keyword or identifier
+	ident      bool // a quoted identifier
+	depth      int  // parenthesis depth
+	start, end int  // byte offsets of the token in the statement
+}
+
+// sqlDialect says how a database lexes statements, where it matters for
+// finding the words outside string literals and comments.
+type sqlDialect struct {
+	backslash   bool // '...' and "..." strings use backslash escapes (MySQL)
+	eStrings    bool // E'...' strings use backslash escapes (PostgreSQL)
+	dollar      bool // $tag$...$tag$ strings (PostgreSQL)
+	brackets    bool // [...] quotes identifiers (SQL Server, SQLite)
+	hashComment bool // # starts a line comment (MySQL)
+	dashSpace   bool // -- only starts a comment before whitespace (MySQL)
+}
+
+// sqlDialects are the ways each connection type may lex a statement,
+// depending on server settings (MySQL's NO_BACKSLASH_ESCAPES, PostgreSQL's
+// E-prefixed strings next to an identifier). A statement is checked under each,
+// so one of them always matches the server. A type without an entry is
+// checked under all of them.
+var sqlDialects = map[string][]sqlDialect{
+	"postgres":  {{eStrings: true, dollar: true}, {dollar: true}},
+	"mysql":     {{backslash: true, hashComment: true, dashSpace: true}, {hashComment: true, dashSpace: true}},
+	"sqlserver": {{brackets: true}},
+	"sqlite":    {{brackets: true}},
+	"demo":      {{brackets: true}},
+}
+
+// dialectsFor returns the dialects a statement for a connection of type typ
+// is lexed under.
+func dialectsFor(typ string) []sqlDialect {
+	if dialects, ok := sqlDialects[typ]; ok {
+		return dialects
+	}
+	var all []sqlDialect
+	for _, t := range []string{"postgres", "mysql", "sqlserver"} {
+		all = append(all, sqlDialects[t]...)
+	}
+	return all
+}
+
+// lexSQL splits sql into tokens under dialect d, dropping comments and
+// whitespace. It is not a validating parser: it only needs to agree with
+// the database about what is code, so unterminated strings and comments
+// simply run to the end.
+func lexSQL(sql string, d sqlDialect) []sqlToken {
+	var toks []sqlToken
+	depth := 0
+	rs := []rune(sql)
+	at := func(i int) rune {
+		if i >= 0 && i < len(rs) {
+			return rs[i]
+		}
+		return 0
+	}
+	// skipQuoted returns the index after the quote closing the one at i,
+	// with a doubled quote (and with escapes, a backslash) escaping it.
+	skipQuoted := func(i int, q rune, escapes bool) int {
+		for j := i + 1; j < len(rs); j++ {
+			switch {
+			case escapes && rs[j] == '\\':
+				j++
+			case rs[j] == q && at(j+1) == q:
+				j++
+			case rs[j] == q:
+				return j + 1
+			}
+		}
+		return len(rs)
+	}
+	// skipPast returns the index after the first end at or after i.
+	skipPast := func(i int, end string) int {
+		if k := strings.Index(string(rs[i:]), end); k >= 0 {
+			return i + len([]rune(string(rs[i:])[:k])) + len([]rune(end))
+		}
+		return len(rs)
+	}
+	// offset holds the byte offset of each rune, and of the end.
+	offset := make([]int, 0, len(rs)+1)
+	for i := range sql {
+		offset = append(offset, i)
+	}
+	offset = append(offset, len(sql))
+	for i := 0; i < len(rs); {
+		c, next := rs[i], at(i+1)
+		n, start := len(toks), i
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '-' && next == '-' && (!d.dashSpace || unicode.IsSpace(at(i+2)) || at(i+2) == 0),
+			d.hashComment && c == '#':
+			for i < len(rs) && rs[i] != '\n' {
+				i++
+			}
+		case c == '/' && next == '*':
+			i = skipPast(i+2, "*/")
+		case c == '\'' || c == '"' && d.backslash:
+			// MySQL reads "..." as a string unless ANSI_QUOTES is set;
+			// as an identifier it hides nothing more.
+			escapes := d.backslash || d.eStrings && (at(i-1) == 'E' || at(i-1) == 'e')
+			i = skipQuoted(i, c, escapes)
+			toks = append(toks, sqlToken{text: "''", depth: depth})
+		case c == '"' || c == '`' || c == '[' && d.brackets:
+			q := c
+			if c == '[' {
+				q = ']'
+			}
+			start := i
+			i = skipQuoted(i, q, false)
+			name := strings.TrimSuffix(string(rs[start+1:i]), string(q))
+			name = strings.ReplaceAll(name, string(q)+string(q), string(q))
+			toks = append(toks, sqlToken{text: strings.ToUpper(name), ident: true, depth: depth})
+		case d.dollar && c == '$' && !isSQLWordRune(at(i-1)):
+			j := i + 1
+			for j < len(rs) && rs[j] != '$' && isSQLWordRune(rs[j]) {
+				j++
+			}
+			if at(j) != '$' || unicode.IsDigit(next) {
+				i++ // a $1 parameter
+				continue
+			}
+			i = skipPast(j+1, string(rs[i:j+1]))
+			toks = append(toks, sqlToken{text: "''", depth: depth})
+		case isSQLWordRune(c):
+			start := i
+			for i < len(rs) && isSQLWordRune(rs[i]) {
+				i++
+			}
+			toks = append(toks, sqlToken{text: strings.ToUpper(string(rs[start:i])), word: true, depth: depth})
+		default:
+			if c == ')' {
+				depth--
+			}
+			toks = append(toks, sqlToken{text: string(c), depth: depth})
+			if c == '(' {
+				depth++
+			}
+			i++
+		}
+		if len(toks) > n {
+			toks[n].start, toks[n].end = offset[start], offset[i]
+		}
+	}
+	return toks
+}
+
+func isSQLWordRune(r rune) bool {
+	return r == '_' || r == '$' || r == '@' || r == '#' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// smuggledWrite returns a description of the first construct in sql, a
+// statement for a connection of type typ, that writes although the
+// statement may look like a query, or "" if there is none:
+//
+//   - SELECT ... INTO, which creates a table on SQL Server and PostgreSQL
+//     and writes a file (INTO OUTFILE/DUMPFILE) or variables on MySQL;
+//   - CREATE TABLE/VIEW ... AS SELECT;
+//   - COPY ... TO/FROM, in particular COPY ... PROGRAM, which runs a shell
+//     command on the PostgreSQL server;
+//   - INSERT, UPDATE, DELETE or MERGE inside a WITH clause;
+//   - any keyword of forbiddenSQLWords outside string literals and comments.
+//
+// Unlike ValidateReadOnlySQL it lexes the statement the way the database
+// does, so string literals cannot hide a construct, nor comment markers
+// inside them cut a statement short.
+func smuggledWrite(typ, sql string) string {
+	for _, d := range dialectsFor(typ) {
+		if msg := smuggledWriteIn(lexSQL(sql, d), typ); msg != "" {
+			return msg
+		}
+	}
+	return ""
+}
+
+func smuggledWriteIn(toks []sqlToken, typ string) string {
+	word := func(i int) string {
+		if i >= 0 && i < len(toks) && toks[i].word {
+			return toks[i].text
+		}
+		return ""
+	}
+	// ahead returns the index of the first of words after i at depth, up to
+	// the end of the statement, or -1.
+	ahead := func(i int, words ...string) int {
+		for j := i + 1; j < len(toks) && toks[j].text != ";"; j++ {
+			if toks[j].depth == toks[i].depth && toks[j].word && slices.Contains(words, toks[j].text) {
+				return j
+			}
+		}
+		return -1
+	}
+	for i, t := range toks {
+		switch {
+		case t.text == "(" && (word(i-1) == "AS" || word(i-1) == "MATERIALIZED"):
+			// WITH name AS [NOT] [MATERIALIZED] (INSERT ...)
+			if w := word(i + 1); w == "INSERT" || w == "UPDATE" || w == "DELETE" || w == "MERGE" {
+				return fmt.Sprintf("%s inside a WITH clause modifies data", w)
+			}
+		case !t.word:
+		case t.text == "INTO":
+			target := word(i + 1)
+			switch {
+			case target == "OUTFILE" || target == "DUMPFILE":
+				return fmt.Sprintf("SELECT ... INTO %s writes a file on the database server", target)
+			case typ == "mysql" || strings.HasPrefix(target, "@"):
+				return "SELECT ... INTO stores the result in variables instead of returning it"
+			default:
+				return "SELECT ... INTO creates a table from the result"
+			}
+		case t.text == "CREATE":
+			if j := ahead(i, "TABLE", "VIEW"); j >= 0 && ahead(j, "AS") >= 0 {
+				return fmt.Sprintf("CREATE %s ... AS writes the result of a query to a new %s", toks[j].text, strings.ToLower(toks[j].text))
+			}
+			return "found \"CREATE\""
+		case t.text == "COPY":
+			if ahead(i, "PROGRAM") >= 0 {
+				return "COPY ... PROGRAM runs a shell command on the database server"
+			}
+			if ahead(i, "TO") >= 0 {
+				return "COPY ... TO writes data out of the database"
+			}
+			return "COPY ... FROM loads data into a table"
+		case slices.Contains(forbiddenSQLWords, t.text):
+			return fmt.Sprintf("found %q", t.text)
+		}
+	}
+	return ""
+}
+
+// ValidateReadOnlySQLFor is ValidateReadOnlySQL for a statement on a
+// connection of type typ (see smuggledWrite), naming the write construct
+// it refuses.
+func ValidateReadOnlySQLFor(typ, sql string) error {
+	if msg := smuggledWrite(typ, sql); msg != "" {
+		return fmt.Errorf("%w: %s", ErrNotReadOnly, msg)
+	}
+	return ValidateReadOnlySQL(sql)
+}
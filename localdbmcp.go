@@ -0,0 +1,192 @@
+// Package localdbmcp embeds localdb-mcp's database tools in other Go
+// programs: register them on your own MCP server instead of running the
+// localdb-mcp binary next to it, or use the drivers directly.
+//
+// Connections are configured as for the binary, from MCP_DB_* environment
+// variables and a config file (see LoadConfig). Register adds every tool to
+// an MCP server with the same safety rules the binary applies:
+//
+//	cfg, err := localdbmcp.LoadConfig()
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	s := server.NewMCPServer("my-server", "1.0.0")
+//	mgr := localdbmcp.Register(s, cfg, nil)
+//	defer mgr.Close()
+//
+// NewServer does both in one call, for a server of localdb-mcp's name that
+// your own tools are added to.
+//
+// This package is the supported API; the packages under internal may change
+// between releases.
+package localdbmcp
+
+import (
+	"context"
+	"io/fs"
+	"net/http"
+
+	"github.com/SedlarDavid/localdb-mcp/internal/config"
+	"github.com/SedlarDavid/localdb-mcp/internal/db"
+	lserver "github.com/SedlarDavid/localdb-mcp/internal/server"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Configuration.
+type (
+	// Config holds the connections and server settings. Connection URIs are
+	// never included in logs or tool output.
+	Config = config.Config
+	// ConnectionInfo is a connection's ID and type, safe to show.
+	ConnectionInfo = config.ConnectionInfo
+)
+
+// LoadConfig reads the configuration from the environment, a .env file in
+// the current directory and ~/.localdb-mcp/config.yaml (or the file named
+// by MCP_CONFIG), like the localdb-mcp binary.
+func LoadConfig() (*Config, error) {
+	return config.Load()
+}
+
+// LoadConfigFrom is like LoadConfig but reads the config file at path, which
+// must exist.
+func LoadConfigFrom(path string) (*Config, error) {
+	return config.LoadFrom(path)
+}
+
+// Drivers.
+type (
+	// Manager opens and caches a Driver per connection ID. Close it when
+	// done.
+	Manager = db.Manager
+	// Driver runs the database operations behind the tools on one
+	// connection.
+	Driver = db.Driver
+	// ColumnInfo describes a column, as returned by Driver.DescribeTable.
+	ColumnInfo = db.ColumnInfo
+	// Capabilities reports what a connection's backend supports.
+	Capabilities = db.Capabilities
+	// Tx is a write transaction from Manager.BeginTx.
+	Tx = db.Tx
+	// Interceptor observes the driver calls of a Manager; see Manager.Use.
+	Interceptor = db.Interceptor
+	// Call describes one driver call for an Interceptor.
+	Call = db.Call
+	// CallMetrics is a connection's latency histogram and error counts; see
+	// Manager.CallMetrics.
+	CallMetrics = db.CallMetrics
+	// ForeignKey is a foreign-key constraint, as listed by a driver that
+	// implements ForeignKeyLister.
+	ForeignKey = db.ForeignKey
+	// ForeignKeyLister is implemented by the drivers that can list a
+	// schema's foreign keys.
+	ForeignKeyLister = db.ForeignKeyLister
+	// Index is an index, as listed by a driver that implements IndexLister.
+	Index = db.Index
+	// IndexLister is implemented by the drivers that can list a schema's
+	// indexes.
+	IndexLister = db.IndexLister
+)
+
+// Errors returned by Manager and Driver, for errors.Is. Any other error
+// comes from the database itself.
+var (
+	ErrUnknownConnection = db.ErrUnknownConnection
+	ErrConnectFailed     = db.ErrConnectFailed
+	ErrNotSupported      = db.ErrNotSupported
+	ErrInvalidInput      = db.ErrInvalidInput
+	ErrNotFound          = db.ErrNotFound
+	ErrManagerClosed     = db.ErrManagerClosed
+)
+
+// NewManager returns a Manager for cfg's connections, for programs that use
+// the drivers without the MCP tools.
+func NewManager(cfg *Config) *Manager {
+	return db.NewManager(cfg)
+}
+
+// LoadFixtures seeds the database d is connected to from the YAML or JSON
+// fixture files at the root of fsys, one per table, parents before the
+// tables that reference them; see the README for the file format. Use it
+// in your own tests:
+//
+//	d, err := mgr.Driver(ctx, "postgres")
+//	...
+//	err = localdbmcp.LoadFixtures(ctx, d, os.DirFS("testdata/fixtures"))
+func LoadFixtures(ctx context.Context, d Driver, fsys fs.FS) error {
+	return db.LoadFixtures(ctx, d, fsys)
+}
+
+// MCP server.
+type (
+	// ServeOptions selects the transport Serve runs on.
+	ServeOptions = lserver.ServeOptions
+	// ToolError is the structured content of a failed tool call.
+	ToolError = lserver.ToolError
+)
+
+// Transport names accepted by Serve.
+const (
+	TransportStdio = lserver.TransportStdio
+	TransportSSE   = lserver.TransportSSE
+	TransportHTTP  = lserver.TransportHTTP
+	TransportUnix  = lserver.TransportUnix
+)
+
+// Register adds localdb-mcp's tools and prompts to s and returns the
+// Manager behind them (nil if cfg is nil); close it on shutdown. It
+// installs tool middleware and session hooks on s. Pass the hooks s was
+// created with (server.WithHooks) as hooks, and localdb-mcp's session hooks
+// are added to them; with nil, they replace any hooks s has.
+func Register(s *server.MCPServer, cfg *Config, hooks *server.Hooks) *Manager {
+	return lserver.RegisterWithHooks(s, cfg, hooks)
+}
+
+// NewServer returns an MCP server named and versioned like the localdb-mcp
+// binary, with its tools and prompts registered for cfg, and a function
+// that closes its connections on shutdown. Add your own tools to it before
+// serving. hooks, if not nil, are the server's hooks, with localdb-mcp's
+// session hooks added as Register describes; opts are passed to
+// server.NewMCPServer and must not include server.WithHooks.
+//
+//	hooks := &server.Hooks{}
+//	hooks.AddOnRegisterSession(onSession)
+//	s, closeDB := localdbmcp.NewServer(cfg, hooks, server.WithLogging())
+//	defer closeDB()
+//	s.AddTool(myTool, myHandler)
+//	err := localdbmcp.Serve(ctx, s, localdbmcp.ServeOptions{Transport: localdbmcp.TransportStdio})
+func NewServer(cfg *Config, hooks *server.Hooks, opts ...server.ServerOption) (s *server.MCPServer, close func() error) {
+	s = server.NewMCPServer(lserver.ServerName, lserver.ServerVersion, opts...)
+	mgr := lserver.RegisterWithHooks(s, cfg, hooks)
+	return s, func() error {
+		if mgr == nil {
+			return nil
+		}
+		return mgr.Close()
+	}
+}
+
+// Reload applies a newly loaded configuration to a server set up by
+// Register; see the SIGHUP handling of the localdb-mcp binary. It returns
+// the IDs of the connections that changed.
+func Reload(s *server.MCPServer, mgr *Manager, cfg, next *Config) ([]string, error) {
+	return lserver.Reload(s, mgr, cfg, next)
+}
+
+// Serve runs s on the transport selected by opts until ctx is cancelled or
+// the client goes away.
+func Serve(ctx context.Context, s *server.MCPServer, opts ServeOptions) error {
+	return lserver.Serve(ctx, s, opts)
+}
+
+// MetricsHandler serves mgr's database call metrics in the Prometheus text
+// format; set it as ServeOptions.Metrics or mount it on your own mux.
+func MetricsHandler(cfg *Config, mgr *Manager) http.Handler {
+	return lserver.MetricsHandler(cfg, mgr)
+}
+
+// RequestID returns the ID of the tool call ctx belongs to, for correlating
+// your own logs with the server's.
+func RequestID(ctx context.Context) string {
+	return lserver.RequestID(ctx)
+}
@@ -0,0 +1,105 @@
+package localdbmcp_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	localdbmcp "github.com/SedlarDavid/localdb-mcp"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func TestRegister(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("MCP_DB_SQLITE_URI", ":memory:")
+	cfg, err := localdbmcp.LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	hooks := &server.Hooks{}
+	var initialized int
+	hooks.AddAfterInitialize(func(context.Context, any, *mcp.InitializeRequest, *mcp.InitializeResult) { initialized++ })
+	s := server.NewMCPServer("embedder", "0.0.1", server.WithHooks(hooks))
+	mgr := localdbmcp.Register(s, cfg, hooks)
+	defer mgr.Close()
+
+	c, err := client.NewInProcessClient(s)
+	if err != nil {
+		t.Fatalf("NewInProcessClient: %v", err)
+	}
+	defer c.Close()
+	initReq := mcp.InitializeRequest{}
+	initReq.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	if _, err := c.Initialize(ctx, initReq); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	res, err := c.CallTool(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{
+		Name:      "run_query",
+		Arguments: map[string]any{"connection_id": "sqlite", "sql": "SELECT 1 AS one"},
+	}})
+	if err != nil || res.IsError {
+		t.Fatalf("run_query: %v %+v", err, res)
+	}
+	if tc, ok := mcp.AsTextContent(res.Content[0]); !ok || !strings.Contains(tc.Text, `"one":1`) {
+		t.Errorf("run_query result: %+v", res.Content)
+	}
+	if initialized != 1 {
+		t.Errorf("the embedder's hook ran %d times, want 1", initialized)
+	}
+
+	if _, err := mgr.Driver(ctx, "nope"); !errors.Is(err, localdbmcp.ErrUnknownConnection) {
+		t.Errorf("Driver(nope): got %v, want ErrUnknownConnection", err)
+	}
+}
+
+func TestNewServer(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("MCP_DB_SQLITE_URI", ":memory:")
+	cfg, err := localdbmcp.LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	s, closeDB := localdbmcp.NewServer(cfg, nil)
+	defer closeDB()
+	s.AddTool(mcp.NewTool("hello"), func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("hi"), nil
+	})
+
+	c, err := client.NewInProcessClient(s)
+	if err != nil {
+		t.Fatalf("NewInProcessClient: %v", err)
+	}
+	defer c.Close()
+	initReq := mcp.InitializeRequest{}
+	initReq.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	init, err := c.Initialize(ctx, initReq)
+	if err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	if init.ServerInfo.Name != "localdb-mcp" {
+		t.Errorf("server name = %q", init.ServerInfo.Name)
+	}
+	tools, err := c.ListTools(ctx, mcp.ListToolsRequest{})
+	if err != nil {
+		t.Fatalf("ListTools: %v", err)
+	}
+	var own, db bool
+	for _, tool := range tools.Tools {
+		own = own || tool.Name == "hello"
+		db = db || tool.Name == "run_query"
+	}
+	if !own || !db {
+		t.Errorf("tools lack hello (%t) or run_query (%t)", own, db)
+	}
+	res, err := c.CallTool(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{Name: "hello"}})
+	if err != nil || res.IsError {
+		t.Errorf("hello: %v %+v", err, res)
+	}
+
+	if err := closeDB(); err != nil {
+		t.Errorf("close: %v", err)
+	}
+}